@@ -0,0 +1,335 @@
+/*
+ * Tencent is pleased to support the open source community by making
+ * 蓝鲸智云 - 配置平台 (BlueKing - Configuration System) available.
+ * Copyright (C) 2017 THL A29 Limited,
+ * a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on
+ * an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+package filter
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"configcenter/src/common"
+	"configcenter/src/common/mapstr"
+	"configcenter/src/common/util"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// LookupFieldValues get the values of the field in the document with the same semantics as mongodb. the field can be
+// a dotted path of the embedded documents, the embedded documents in an array are traversed to get the field, and
+// a numeric path element can also be used to get the array element by index.
+// missing is true if the field does not exist in any of the traversed paths.
+func LookupFieldValues(doc mapstr.MapStr, field string) (values []interface{}, missing bool) {
+	if len(field) == 0 {
+		return nil, true
+	}
+
+	return lookupValues(map[string]interface{}(doc), strings.Split(field, "."))
+}
+
+func lookupValues(data interface{}, paths []string) ([]interface{}, bool) {
+	if len(paths) == 0 {
+		return []interface{}{data}, false
+	}
+
+	if doc, ok := convToMap(data); ok {
+		val, exist := doc[paths[0]]
+		if !exist {
+			return nil, true
+		}
+		return lookupValues(val, paths[1:])
+	}
+
+	arr, ok := convToSlice(data)
+	if !ok {
+		return nil, true
+	}
+
+	values := make([]interface{}, 0)
+	missing := false
+	if idx, err := strconv.Atoi(paths[0]); err == nil && idx >= 0 && idx < len(arr) {
+		vals, miss := lookupValues(arr[idx], paths[1:])
+		values = append(values, vals...)
+		missing = missing || miss
+	}
+
+	// only the embedded documents in the array are traversed, just like mongodb
+	for _, elem := range arr {
+		if _, ok := convToMap(elem); !ok {
+			continue
+		}
+
+		vals, miss := lookupValues(elem, paths)
+		values = append(values, vals...)
+		missing = missing || miss
+	}
+
+	if len(values) == 0 {
+		missing = true
+	}
+
+	return values, missing
+}
+
+func convToMap(data interface{}) (map[string]interface{}, bool) {
+	switch val := data.(type) {
+	case map[string]interface{}:
+		return val, true
+	case mapstr.MapStr:
+		return val, true
+	case primitive.M:
+		return val, true
+	case primitive.D:
+		return val.Map(), true
+	default:
+		return nil, false
+	}
+}
+
+func convToSlice(data interface{}) ([]interface{}, bool) {
+	switch val := data.(type) {
+	case []interface{}:
+		return val, true
+	case primitive.A:
+		return val, true
+	case nil:
+		return nil, false
+	}
+
+	value := reflect.ValueOf(data)
+	if value.Kind() != reflect.Slice && value.Kind() != reflect.Array {
+		return nil, false
+	}
+
+	arr := make([]interface{}, value.Len())
+	for i := 0; i < value.Len(); i++ {
+		arr[i] = value.Index(i).Interface()
+	}
+	return arr, true
+}
+
+// matchElements check if any of the values matches, an array value is matched by its elements like mongodb does.
+func matchElements(values []interface{}, match func(v interface{}) bool) bool {
+	for _, value := range values {
+		if arr, ok := convToSlice(value); ok {
+			for _, elem := range arr {
+				if match(elem) {
+					return true
+				}
+			}
+			continue
+		}
+
+		if match(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchArrays check if any of the values is an array that matches, the array value is not expanded.
+func matchArrays(values []interface{}, match func(arr []interface{}) bool) bool {
+	for _, value := range values {
+		if arr, ok := convToSlice(value); ok && match(arr) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchEqual check if the field value equals to the value, a missing field matches the nil value like mongodb.
+func matchEqual(doc mapstr.MapStr, field string, value interface{}) bool {
+	values, missing := LookupFieldValues(doc, field)
+	if value == nil && missing {
+		return true
+	}
+
+	return matchElements(values, func(v interface{}) bool {
+		return equalValue(v, value)
+	})
+}
+
+// matchIn check if the field value equals to any of the value's elements
+func matchIn(doc mapstr.MapStr, field string, value interface{}) (bool, error) {
+	arr, ok := convToSlice(value)
+	if !ok {
+		return false, fmt.Errorf("value(%+v) is not an array", value)
+	}
+
+	for _, elem := range arr {
+		if matchEqual(doc, field, elem) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func equalValue(a, b interface{}) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	if util.IsNumeric(a) && util.IsNumeric(b) {
+		cmp, ok := compareNumeric(a, b)
+		return ok && cmp == 0
+	}
+
+	if timeA, ok := convToMatchTime(a); ok {
+		timeB, ok := convToMatchTime(b)
+		return ok && timeA.Equal(timeB)
+	}
+
+	valA, valB := reflect.ValueOf(a), reflect.ValueOf(b)
+	switch {
+	case valA.Kind() == reflect.String && valB.Kind() == reflect.String:
+		return valA.String() == valB.String()
+	case valA.Kind() == reflect.Bool && valB.Kind() == reflect.Bool:
+		return valA.Bool() == valB.Bool()
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+// compareNumeric compare two numeric values, returns false if any of them is not numeric,
+// since mongodb only compares the values of the same type.
+func compareNumeric(a, b interface{}) (int, bool) {
+	if !util.IsNumeric(a) || !util.IsNumeric(b) {
+		return 0, false
+	}
+
+	floatA, err := util.GetFloat64ByInterface(a)
+	if err != nil {
+		return 0, false
+	}
+
+	floatB, err := util.GetFloat64ByInterface(b)
+	if err != nil {
+		return 0, false
+	}
+
+	switch {
+	case floatA < floatB:
+		return -1, true
+	case floatA > floatB:
+		return 1, true
+	default:
+		return 0, true
+	}
+}
+
+// matchNumeric check if any of the field's numeric values satisfies the compare result with the value
+func matchNumeric(doc mapstr.MapStr, field string, value interface{}, match func(cmp int) bool) bool {
+	values, _ := LookupFieldValues(doc, field)
+	return matchElements(values, func(v interface{}) bool {
+		cmp, ok := compareNumeric(v, value)
+		return ok && match(cmp)
+	})
+}
+
+// matchTimeLayouts are the layouts of the datetime strings in the document, the documents like the event details are
+// decoded from json, so their datetime values are strings.
+var matchTimeLayouts = []string{time.RFC3339Nano, common.TimeTransferModel}
+
+// convToMatchTime convert the document's datetime value to time, the datetime type and the datetime string in
+// RFC3339 or cmdb time layout can be compared with the datetime operator's value, timestamp is not compared as
+// the mongodb does.
+func convToMatchTime(v interface{}) (time.Time, bool) {
+	switch val := v.(type) {
+	case time.Time:
+		return val, true
+	case *time.Time:
+		if val == nil {
+			return time.Time{}, false
+		}
+		return *val, true
+	case primitive.DateTime:
+		return val.Time(), true
+	case string:
+		for _, layout := range matchTimeLayouts {
+			t, err := time.ParseInLocation(layout, val, time.Local)
+			if err == nil {
+				return t, true
+			}
+		}
+		return time.Time{}, false
+	default:
+		return time.Time{}, false
+	}
+}
+
+// matchDatetime check if any of the field's datetime values satisfies the compare result with the value
+func matchDatetime(doc mapstr.MapStr, field string, value interface{}, match func(cmp int) bool) (bool, error) {
+	t, err := util.ConvToTime(value)
+	if err != nil {
+		return false, fmt.Errorf("convert value to time failed, err: %v", err)
+	}
+
+	return matchTime(doc, field, t, match), nil
+}
+
+func matchTime(doc mapstr.MapStr, field string, t time.Time, match func(cmp int) bool) bool {
+	values, _ := LookupFieldValues(doc, field)
+	return matchElements(values, func(v interface{}) bool {
+		fieldTime, ok := convToMatchTime(v)
+		if !ok {
+			return false
+		}
+
+		// mongodb saves the datetime in milliseconds
+		fieldMs, valMs := fieldTime.UnixNano()/int64(time.Millisecond), t.UnixNano()/int64(time.Millisecond)
+		switch {
+		case fieldMs < valMs:
+			return match(-1)
+		case fieldMs > valMs:
+			return match(1)
+		default:
+			return match(0)
+		}
+	})
+}
+
+// matchRegex check if any of the field's string values matches the regex, insensitive is the same as the "i" option.
+func matchRegex(doc mapstr.MapStr, field string, pattern string, insensitive bool) (bool, error) {
+	if insensitive {
+		pattern = "(?i)" + pattern
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return false, fmt.Errorf("regex %s is invalid, err: %v", pattern, err)
+	}
+
+	values, _ := LookupFieldValues(doc, field)
+	return matchElements(values, func(v interface{}) bool {
+		val := reflect.ValueOf(v)
+		return val.Kind() == reflect.String && re.MatchString(val.String())
+	}), nil
+}
+
+// regexPattern get the regex pattern with the string value's prefix and suffix
+func regexPattern(value interface{}, prefix, suffix string) (string, error) {
+	val := reflect.ValueOf(value)
+	if val.Kind() != reflect.String {
+		return "", errors.New("value should be a string")
+	}
+
+	return prefix + val.String() + suffix, nil
+}
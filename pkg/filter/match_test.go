@@ -0,0 +1,287 @@
+/*
+ * Tencent is pleased to support the open source community by making
+ * 蓝鲸智云 - 配置平台 (BlueKing - Configuration System) available.
+ * Copyright (C) 2017 THL A29 Limited,
+ * a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on
+ * an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+package filter
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"configcenter/src/common/mapstr"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/connstring"
+)
+
+var matchBaseTime = time.Unix(1600000000, 0)
+
+// matchTestDocs is the documents used to test that the Match and ToMgo results are the same
+var matchTestDocs = []mapstr.MapStr{
+	{
+		"id":      1,
+		"name":    "host-a",
+		"ip":      []interface{}{"10.0.0.1", "192.168.1.1"},
+		"cpu":     4,
+		"mem":     8.5,
+		"enabled": true,
+		"created": matchBaseTime,
+		"tags":    []interface{}{"a", "b"},
+		"labels":  map[string]interface{}{"env": "prod", "zone": "sz"},
+		"disks": []interface{}{
+			map[string]interface{}{"size": 100, "type": "ssd"},
+			map[string]interface{}{"size": 500, "type": "hdd"},
+		},
+	},
+	{
+		"id":      2,
+		"name":    "Host-B",
+		"ip":      []interface{}{"10.0.0.2"},
+		"cpu":     8,
+		"enabled": false,
+		"created": matchBaseTime.Add(time.Hour),
+		"tags":    []interface{}{},
+		"labels":  map[string]interface{}{"env": "test"},
+		"disks":   []interface{}{map[string]interface{}{"size": 200, "type": "ssd"}},
+	},
+	{
+		"id":      3,
+		"name":    "db-c",
+		"ip":      []interface{}{"172.16.0.1"},
+		"cpu":     16,
+		"mem":     nil,
+		"created": matchBaseTime.Add(2 * time.Hour),
+		"labels":  map[string]interface{}{},
+		"disks":   []interface{}{},
+	},
+	{
+		"id":   4,
+		"name": "web-d",
+		"cpu":  int64(2),
+		"tags": []interface{}{"c"},
+	},
+	{
+		"id":   5,
+		"name": "cache",
+		"cpu":  "8",
+		"mem":  32,
+		"tags": nil,
+	},
+}
+
+type matchTestCase struct {
+	name     string
+	rule     RuleFactory
+	expected []int
+}
+
+func atom(field string, op OpType, value interface{}) *AtomRule {
+	return &AtomRule{Field: field, Operator: op.Factory(), Value: value}
+}
+
+var matchTestCases = []matchTestCase{
+	{"equal string", atom("name", Equal, "host-a"), []int{1}},
+	{"equal int", atom("cpu", Equal, 8), []int{2}},
+	{"equal float", atom("cpu", Equal, 8.0), []int{2}},
+	{"equal array element", atom("ip", Equal, "10.0.0.2"), []int{2}},
+	{"equal bool", atom("enabled", Equal, false), []int{2}},
+	{"equal embedded field", atom("labels.env", Equal, "prod"), []int{1}},
+	{"not equal array element", atom("ip", NotEqual, "10.0.0.2"), []int{1, 3, 4, 5}},
+	{"not equal bool", atom("enabled", NotEqual, false), []int{1, 3, 4, 5}},
+	{"in", atom("cpu", In, []interface{}{2, 16}), []int{3, 4}},
+	{"in array element", atom("tags", In, []interface{}{"b", "c"}), []int{1, 4}},
+	{"not in array element", atom("tags", NotIn, []interface{}{"b", "c"}), []int{2, 3, 5}},
+	{"less", atom("cpu", Less, 8), []int{1, 4}},
+	{"less or equal", atom("cpu", LessOrEqual, 8), []int{1, 2, 4}},
+	{"greater", atom("mem", Greater, 8.5), []int{5}},
+	{"greater or equal", atom("mem", GreaterOrEqual, 8.5), []int{1, 5}},
+	{"datetime less", atom("created", DatetimeLess, matchBaseTime.Add(time.Hour).Unix()), []int{1}},
+	{"datetime less or equal", atom("created", DatetimeLessOrEqual, matchBaseTime.Add(time.Hour).Unix()),
+		[]int{1, 2}},
+	{"datetime greater", atom("created", DatetimeGreater, matchBaseTime.Unix()), []int{2, 3}},
+	{"datetime greater or equal", atom("created", DatetimeGreaterOrEqual,
+		matchBaseTime.Add(time.Hour).Local().Format("2006-01-02 15:04:05")),
+		[]int{2, 3}},
+	{"begins with", atom("name", BeginsWith, "host"), []int{1}},
+	{"begins with insensitive", atom("name", BeginsWithInsensitive, "host"), []int{1, 2}},
+	{"not begins with", atom("name", NotBeginsWith, "host"), []int{2, 3, 4, 5}},
+	{"not begins with insensitive", atom("name", NotBeginsWithInsensitive, "host"), []int{3, 4, 5}},
+	{"contains", atom("name", Contains, "B"), []int{2, 3, 4}},
+	{"contains sensitive", atom("name", ContainsSensitive, "B"), []int{2}},
+	{"not contains", atom("name", NotContains, "b"), []int{1, 2, 5}},
+	{"not contains insensitive", atom("name", NotContainsInsensitive, "b"), []int{1, 5}},
+	{"ends with", atom("name", EndsWith, "-a"), []int{1}},
+	{"ends with insensitive", atom("name", EndsWithInsensitive, "-b"), []int{2}},
+	{"not ends with", atom("name", NotEndsWith, "-a"), []int{2, 3, 4, 5}},
+	{"not ends with insensitive", atom("name", NotEndsWithInsensitive, "-B"), []int{1, 3, 4, 5}},
+	{"is empty", atom("tags", IsEmpty, nil), []int{2}},
+	{"is not empty", atom("tags", IsNotEmpty, nil), []int{1, 4}},
+	{"size", atom("tags", Size, 2), []int{1}},
+	{"is null", atom("mem", IsNull, nil), []int{2, 3, 4}},
+	{"is null embedded field", atom("labels.zone", IsNull, nil), []int{2, 3, 4, 5}},
+	{"is not null", atom("mem", IsNotNull, nil), []int{1, 5}},
+	{"exist", atom("ip", Exist, nil), []int{1, 2, 3}},
+	{"not exist", atom("ip", NotExist, nil), []int{4, 5}},
+	{"regex", atom("name", Regex, "^[a-z]+-[a-d]$"), []int{1, 3, 4}},
+	{"not regex", atom("name", NotRegex, "^h"), []int{2, 3, 4, 5}},
+	{"between", atom("cpu", Between, []interface{}{4, 8}), []int{1, 2}},
+	{"datetime between", atom("created", DatetimeBetween,
+		[]interface{}{matchBaseTime.Add(30 * time.Minute).Unix(), matchBaseTime.Add(3 * time.Hour).Unix()}),
+		[]int{2, 3}},
+	{"filter object", atom("labels", Object, atom("env", Equal, "prod")), []int{1}},
+	{"filter object with combined rule", atom("labels", Object, &CombinedRule{
+		Condition: Or,
+		Rules:     []RuleFactory{atom("env", Equal, "test"), atom("zone", Exist, nil)},
+	}), []int{1, 2}},
+	{"filter object in array", atom("disks", Object, atom("size", Greater, 300)), []int{1}},
+	// the range conditions can be satisfied by different array elements, just like mongodb
+	{"between array elements", atom("disks", Object, atom("size", Between, []interface{}{250, 300})), []int{1}},
+	{"filter array", atom("tags", Array, atom(ArrayElement, Equal, "a")), []int{1}},
+	{"filter array with string operator", atom("ip", Array, atom(ArrayElement, BeginsWith, "10.")), []int{1, 2}},
+	{"custom operator", atom("ip", ipInCidr, "10.0.0.0/8"), []int{1, 2}},
+	{"and", &CombinedRule{
+		Condition: And,
+		Rules:     []RuleFactory{atom("cpu", Greater, 2), atom("enabled", Equal, true)},
+	}, []int{1}},
+	{"or", &CombinedRule{
+		Condition: Or,
+		Rules:     []RuleFactory{atom("name", Equal, "cache"), atom("ip", Equal, "172.16.0.1")},
+	}, []int{3, 5}},
+}
+
+// TestMatch test that the document matches the rule as expected
+func TestMatch(t *testing.T) {
+	for _, c := range matchTestCases {
+		matched := make([]int, 0)
+		for _, doc := range matchTestDocs {
+			ok, err := c.rule.Match(doc)
+			if err != nil {
+				t.Errorf("case %s match failed, err: %v", c.name, err)
+				return
+			}
+
+			if ok {
+				matched = append(matched, doc["id"].(int))
+			}
+		}
+
+		if !reflect.DeepEqual(matched, c.expected) {
+			t.Errorf("case %s matched %v, but expected %v", c.name, matched, c.expected)
+		}
+	}
+}
+
+func TestMatchInvalidRule(t *testing.T) {
+	invalidRules := []RuleFactory{
+		atom("name", Unknown, "a"),
+		atom("", Equal, "a"),
+		atom("cpu", In, 1),
+		atom("name", Regex, "(a"),
+		atom("labels", Object, "a"),
+		atom("tags", Array, atom("a", Equal, "a")),
+		&CombinedRule{Condition: And},
+	}
+
+	for _, rule := range invalidRules {
+		if _, err := rule.Match(matchTestDocs[0]); err == nil {
+			t.Errorf("match rule %+v should return error", rule)
+		}
+	}
+}
+
+// TestMatchConformance test that the Match result is the same as the mongodb query result of the ToMgo condition,
+// it needs a mongodb instance specified by the MONGOURI environment variable, e.g. mongodb://127.0.0.1:27017/cmdb
+func TestMatchConformance(t *testing.T) {
+	uri := os.Getenv("MONGOURI")
+	if uri == "" {
+		t.Skip("MONGOURI is not set, skip the mongodb conformance test")
+	}
+
+	connStr, err := connstring.ParseAndValidate(uri)
+	if err != nil {
+		t.Fatalf("parse mongodb uri failed, err: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("connect mongodb failed, err: %v", err)
+	}
+	defer client.Disconnect(context.Background())
+
+	table := client.Database(connStr.Database).Collection(fmt.Sprintf("cc_filter_match_test_%d", time.Now().UnixNano()))
+	defer table.Drop(context.Background())
+
+	docs := make([]interface{}, len(matchTestDocs))
+	for i, doc := range matchTestDocs {
+		docs[i] = doc
+	}
+	if _, err := table.InsertMany(ctx, docs); err != nil {
+		t.Fatalf("insert test documents failed, err: %v", err)
+	}
+
+	for _, c := range matchTestCases {
+		cond, err := c.rule.ToMgo()
+		if err != nil {
+			t.Errorf("case %s to mongo failed, err: %v", c.name, err)
+			continue
+		}
+
+		cursor, err := table.Find(ctx, cond)
+		if err != nil {
+			t.Errorf("case %s find by %+v failed, err: %v", c.name, cond, err)
+			continue
+		}
+
+		result := make([]struct {
+			ID int `bson:"id"`
+		}, 0)
+		if err := cursor.All(ctx, &result); err != nil {
+			t.Errorf("case %s decode result failed, err: %v", c.name, err)
+			continue
+		}
+
+		dbMatched := make([]int, 0)
+		for _, one := range result {
+			dbMatched = append(dbMatched, one.ID)
+		}
+		sort.Ints(dbMatched)
+
+		matched := make([]int, 0)
+		for _, doc := range matchTestDocs {
+			ok, err := c.rule.Match(doc)
+			if err != nil {
+				t.Errorf("case %s match failed, err: %v", c.name, err)
+				break
+			}
+			if ok {
+				matched = append(matched, doc["id"].(int))
+			}
+		}
+
+		if !reflect.DeepEqual(matched, dbMatched) {
+			t.Errorf("case %s matched %v, but mongodb matched %v by %+v", c.name, matched, dbMatched, cond)
+		}
+	}
+}
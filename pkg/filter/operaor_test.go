@@ -18,12 +18,17 @@
 package filter
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
 	"reflect"
 	"testing"
 	"time"
 
 	"configcenter/src/common"
 	"configcenter/src/common/criteria/enumor"
+	"configcenter/src/common/mapstr"
 )
 
 func TestEqualValidate(t *testing.T) {
@@ -2172,7 +2177,7 @@ func TestIsNotEmptyMongoCond(t *testing.T) {
 	}
 
 	if !reflect.DeepEqual(cond, map[string]interface{}{"test": map[string]interface{}{
-		common.BKDBSize: map[string]interface{}{common.BKDBGT: 0}}}) {
+		common.BKDBType: "array", common.BKDBNot: map[string]interface{}{common.BKDBSize: 0}}}) {
 		t.Errorf("cond %+v is invalid", cond)
 		return
 	}
@@ -2678,3 +2683,274 @@ func TestArrayMongoCond(t *testing.T) {
 		return
 	}
 }
+
+func TestRegexValidate(t *testing.T) {
+	op := Regex.Factory().Operator()
+
+	// test valid regex
+	for _, v := range []interface{}{"^a.*b$", "[0-9]{1,3}(\\.[0-9]{1,3}){3}", "a|b|c", "(ab)?c+"} {
+		if err := op.ValidateValue(v, nil); err != nil {
+			t.Errorf("validate %v failed, err: %v", v, err)
+			return
+		}
+	}
+
+	// test invalid regex, including the unsafe ones
+	for _, v := range []interface{}{"", "(a", "(a)\\1", "(?=a)b", "(a+)+", "(a*)*b", "(a|b+){2,}", 1, nil,
+		[]string{"a"}, string(make([]byte, MaxRegexLength+1))} {
+		if err := op.ValidateValue(v, nil); err == nil {
+			t.Errorf("validate %v should return error", v)
+			return
+		}
+	}
+}
+
+func TestRegexMongoCond(t *testing.T) {
+	op := Regex.Factory().Operator()
+
+	cond, err := op.ToMgo("test", "^a.*b$")
+	if err != nil {
+		t.Errorf("to mongo failed, err: %v", err)
+		return
+	}
+
+	if !reflect.DeepEqual(cond, map[string]interface{}{"test": map[string]interface{}{common.BKDBLIKE: "^a.*b$"}}) {
+		t.Errorf("cond %+v is invalid", cond)
+		return
+	}
+}
+
+func TestNotRegexValidate(t *testing.T) {
+	op := NotRegex.Factory().Operator()
+
+	if err := op.ValidateValue("^a.*b$", nil); err != nil {
+		t.Errorf("validate failed, err: %v", err)
+		return
+	}
+
+	for _, v := range []interface{}{"", "(a+)+", 1, nil} {
+		if err := op.ValidateValue(v, nil); err == nil {
+			t.Errorf("validate %v should return error", v)
+			return
+		}
+	}
+}
+
+func TestNotRegexMongoCond(t *testing.T) {
+	op := NotRegex.Factory().Operator()
+
+	cond, err := op.ToMgo("test", "^a")
+	if err != nil {
+		t.Errorf("to mongo failed, err: %v", err)
+		return
+	}
+
+	expected := map[string]interface{}{
+		"test": map[string]interface{}{common.BKDBNot: map[string]interface{}{common.BKDBLIKE: "^a"}},
+	}
+	if !reflect.DeepEqual(cond, expected) {
+		t.Errorf("cond %+v is invalid", cond)
+		return
+	}
+}
+
+func TestBetweenValidate(t *testing.T) {
+	op := Between.Factory().Operator()
+
+	for _, v := range []interface{}{[]interface{}{1, 2}, []int64{1, 1}, []interface{}{1.5, json.Number("3")}} {
+		if err := op.ValidateValue(v, nil); err != nil {
+			t.Errorf("validate %v failed, err: %v", v, err)
+			return
+		}
+	}
+
+	for _, v := range []interface{}{[]interface{}{2, 1}, []interface{}{1}, []interface{}{1, 2, 3}, []interface{}{"a", 1},
+		1, "a", nil} {
+		if err := op.ValidateValue(v, nil); err == nil {
+			t.Errorf("validate %v should return error", v)
+			return
+		}
+	}
+}
+
+func TestBetweenMongoCond(t *testing.T) {
+	op := Between.Factory().Operator()
+
+	cond, err := op.ToMgo("test", []interface{}{1, 2})
+	if err != nil {
+		t.Errorf("to mongo failed, err: %v", err)
+		return
+	}
+
+	expected := map[string]interface{}{"test": map[string]interface{}{common.BKDBGTE: 1, common.BKDBLTE: 2}}
+	if !reflect.DeepEqual(cond, expected) {
+		t.Errorf("cond %+v is invalid", cond)
+		return
+	}
+}
+
+func TestDatetimeBetweenValidate(t *testing.T) {
+	op := DatetimeBetween.Factory().Operator()
+
+	for _, v := range []interface{}{[]interface{}{1, 2}, []interface{}{"2006-01-02 15:04:05", "2006-01-02T16:04:05+08:00"},
+		[]interface{}{time.Unix(1, 0), 1}} {
+		if err := op.ValidateValue(v, nil); err != nil {
+			t.Errorf("validate %v failed, err: %v", v, err)
+			return
+		}
+	}
+
+	for _, v := range []interface{}{[]interface{}{2, 1}, []interface{}{1}, []interface{}{"a", 1}, 1, "a", nil} {
+		if err := op.ValidateValue(v, nil); err == nil {
+			t.Errorf("validate %v should return error", v)
+			return
+		}
+	}
+}
+
+func TestDatetimeBetweenMongoCond(t *testing.T) {
+	op := DatetimeBetween.Factory().Operator()
+
+	cond, err := op.ToMgo("test", []interface{}{1, 2})
+	if err != nil {
+		t.Errorf("to mongo failed, err: %v", err)
+		return
+	}
+
+	expected := map[string]interface{}{
+		"test": map[string]interface{}{common.BKDBGTE: time.Unix(1, 0), common.BKDBLTE: time.Unix(2, 0)},
+	}
+	if !reflect.DeepEqual(cond, expected) {
+		t.Errorf("cond %+v is invalid", cond)
+		return
+	}
+}
+
+// ipInCidrOp is an example of the custom operator, it matches the ip in the cidr whose prefix length is 8, 16 or 24.
+type ipInCidrOp OpType
+
+const ipInCidr OpType = "ip_in_cidr"
+
+func init() {
+	op := ipInCidrOp(ipInCidr)
+	if err := RegisterOperator(&op); err != nil {
+		panic(err)
+	}
+}
+
+func (o ipInCidrOp) Name() OpType {
+	return ipInCidr
+}
+
+func (o ipInCidrOp) ValidateValue(v interface{}, opt *ExprOption) error {
+	cidr, ok := v.(string)
+	if !ok {
+		return errors.New("value should be a cidr string")
+	}
+
+	ip, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return err
+	}
+
+	ones, _ := ipNet.Mask.Size()
+	if ip.To4() == nil || ones%8 != 0 || ones == 0 {
+		return errors.New("only ipv4 cidr with prefix length of 8, 16 or 24 is supported")
+	}
+	return nil
+}
+
+func (o ipInCidrOp) ToMgo(field string, value interface{}) (map[string]interface{}, error) {
+	_, ipNet, err := net.ParseCIDR(value.(string))
+	if err != nil {
+		return nil, err
+	}
+
+	ones, _ := ipNet.Mask.Size()
+	prefix := ""
+	for i := 0; i < ones/8; i++ {
+		prefix += fmt.Sprintf("%d\\.", ipNet.IP[i])
+	}
+
+	return mapstr.MapStr{field: map[string]interface{}{common.BKDBLIKE: "^" + prefix}}, nil
+}
+
+func (o ipInCidrOp) Match(doc mapstr.MapStr, field string, value interface{}) (bool, error) {
+	_, ipNet, err := net.ParseCIDR(value.(string))
+	if err != nil {
+		return false, err
+	}
+
+	values, _ := LookupFieldValues(doc, field)
+	for _, val := range values {
+		ips, ok := val.([]interface{})
+		if !ok {
+			ips = []interface{}{val}
+		}
+
+		for _, ip := range ips {
+			str, ok := ip.(string)
+			if ok && net.ParseIP(str) != nil && ipNet.Contains(net.ParseIP(str)) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+func TestRegisterOperator(t *testing.T) {
+	// test register duplicate or invalid operators, ip_in_cidr operator is registered in init
+	op := ipInCidrOp(ipInCidr)
+	if err := RegisterOperator(&op); err == nil {
+		t.Errorf("register duplicate operator should return error")
+		return
+	}
+
+	equal := EqualOp(Equal)
+	if err := RegisterOperator(&equal); err == nil {
+		t.Errorf("register built-in operator should return error")
+		return
+	}
+
+	if err := RegisterOperator(nil); err == nil {
+		t.Errorf("register nil operator should return error")
+		return
+	}
+
+	// test the registered operator works in the expression like the built-in ones
+	exprJson := `{"condition":"AND","rules":[{"field":"bk_host_innerip","operator":"ip_in_cidr","value":"10.0.0.0/8"},
+{"field":"bk_cpu","operator":"between","value":[1,8]}]}`
+	expr := new(Expression)
+	if err := json.Unmarshal([]byte(exprJson), expr); err != nil {
+		t.Errorf("unmarshal expression failed, err: %v", err)
+		return
+	}
+
+	opt := NewDefaultExprOpt(map[string]enumor.FieldType{"bk_host_innerip": enumor.String, "bk_cpu": enumor.Numeric})
+	if err := expr.Validate(opt); err != nil {
+		t.Errorf("validate expression failed, err: %v", err)
+		return
+	}
+
+	cond, err := expr.ToMgo()
+	if err != nil {
+		t.Errorf("to mongo failed, err: %v", err)
+		return
+	}
+
+	expected := map[string]interface{}{common.BKDBAND: []map[string]interface{}{
+		{"bk_host_innerip": map[string]interface{}{common.BKDBLIKE: "^10\\."}},
+		{"bk_cpu": map[string]interface{}{common.BKDBGTE: float64(1), common.BKDBLTE: float64(8)}},
+	}}
+	if !reflect.DeepEqual(cond, expected) {
+		t.Errorf("cond %+v is invalid", cond)
+		return
+	}
+
+	invalidExpr := &Expression{RuleFactory: &AtomRule{Field: "bk_host_innerip", Operator: "ip_in_cidr",
+		Value: "10.1.0.0/12"}}
+	if err := invalidExpr.Validate(opt); err == nil {
+		t.Errorf("validate invalid expression should return error")
+		return
+	}
+}
@@ -20,6 +20,10 @@ package filter
 import (
 	"errors"
 	"fmt"
+	"reflect"
+	"regexp/syntax"
+	"sync"
+	"time"
 
 	"configcenter/src/common"
 	"configcenter/src/common/criteria/enumor"
@@ -27,7 +31,11 @@ import (
 	"configcenter/src/common/util"
 )
 
-var opFactory map[OpFactory]Operator
+var (
+	opFactory map[OpFactory]Operator
+	// opLock protects the opFactory, since custom operators can be registered by RegisterOperator
+	opLock sync.RWMutex
+)
 
 func init() {
 	opFactory = make(map[OpFactory]Operator)
@@ -98,6 +106,38 @@ func init() {
 	opFactory[OpFactory(obj.Name())] = &obj
 	filterArr := ArrayOp(Array)
 	opFactory[OpFactory(filterArr.Name())] = &filterArr
+	regex := RegexOp(Regex)
+	opFactory[OpFactory(regex.Name())] = &regex
+	notRegex := NotRegexOp(NotRegex)
+	opFactory[OpFactory(notRegex.Name())] = &notRegex
+	between := BetweenOp(Between)
+	opFactory[OpFactory(between.Name())] = &between
+	datetimeBetween := DatetimeBetweenOp(DatetimeBetween)
+	opFactory[OpFactory(datetimeBetween.Name())] = &datetimeBetween
+}
+
+// RegisterOperator register a custom operator, so that the services can use their domain operators in the expression
+// just like the built-in ones. the operator's name can not be empty or conflict with the registered operators.
+// it should be called before the expression is used, e.g. in the init function.
+func RegisterOperator(op Operator) error {
+	if op == nil {
+		return errors.New("operator is nil")
+	}
+
+	name := op.Name()
+	if len(name) == 0 || name == Unknown {
+		return fmt.Errorf("operator name %s is invalid", name)
+	}
+
+	opLock.Lock()
+	defer opLock.Unlock()
+
+	if _, exist := opFactory[OpFactory(name)]; exist {
+		return fmt.Errorf("operator %s is already registered", name)
+	}
+
+	opFactory[OpFactory(name)] = op
+	return nil
 }
 
 const (
@@ -127,7 +167,9 @@ type OpFactory string
 
 // Operator return this operator factory's Operator
 func (of OpFactory) Operator() Operator {
+	opLock.RLock()
 	op, exist := opFactory[of]
+	opLock.RUnlock()
 	if !exist {
 		unknown := UnknownOp(Unknown)
 		return &unknown
@@ -238,20 +280,38 @@ const (
 	Object OpType = "filter_object"
 	// Array filter array elements operator
 	Array OpType = "filter_array"
+
+	// regular expression operator, the expression is validated to be safe for the mongodb
+
+	// Regex operator
+	Regex OpType = "regex"
+	// NotRegex operator
+	NotRegex OpType = "not_regex"
+
+	// range operator that matches the value in the closed interval [min, max]
+
+	// Between operator for numeric value
+	Between OpType = "between"
+	// DatetimeBetween operator for datetime value
+	DatetimeBetween OpType = "datetime_between"
+)
+
+const (
+	// MaxRegexLength defines the maximum length of the regex operator's value
+	MaxRegexLength = 256
 )
 
 // OpType defines the operators supported by cc.
 type OpType string
 
 // Validate test the operator is valid or not.
+// both the built-in operators and the operators registered by RegisterOperator are valid.
 func (op OpType) Validate() error {
-	switch op {
-	case Equal, NotEqual, In, NotIn, Less, LessOrEqual, Greater, GreaterOrEqual, DatetimeLess, DatetimeLessOrEqual,
-		DatetimeGreater, DatetimeGreaterOrEqual, BeginsWith, BeginsWithInsensitive, NotBeginsWith,
-		NotBeginsWithInsensitive, Contains, ContainsSensitive, NotContains, NotContainsInsensitive, EndsWith,
-		EndsWithInsensitive, NotEndsWith, NotEndsWithInsensitive, IsEmpty, IsNotEmpty, Size, IsNull,
-		IsNotNull, Exist, NotExist, Object, Array:
-	default:
+	opLock.RLock()
+	_, exist := opFactory[OpFactory(op)]
+	opLock.RUnlock()
+
+	if !exist {
 		return fmt.Errorf("unsupported operator: %s", op)
 	}
 
@@ -271,6 +331,9 @@ type Operator interface {
 	ValidateValue(v interface{}, opt *ExprOption) error
 	// ToMgo generate an operator's mongo condition with its field and value.
 	ToMgo(field string, value interface{}) (map[string]interface{}, error)
+	// Match checks if the document matches the operator's field and value in memory, it must have the same
+	// semantics as the mongo condition generated by ToMgo.
+	Match(doc mapstr.MapStr, field string, value interface{}) (bool, error)
 }
 
 // UnknownOp is unknown operator
@@ -291,6 +354,11 @@ func (o UnknownOp) ToMgo(_ string, _ interface{}) (map[string]interface{}, error
 	return nil, errors.New("unknown operator, can not gen mongo expression")
 }
 
+// Match checks if the document matches this operator's field and value.
+func (o UnknownOp) Match(_ mapstr.MapStr, _ string, _ interface{}) (bool, error) {
+	return false, errors.New("unknown operator, can not match the document")
+}
+
 // EqualOp is equal operator type
 type EqualOp OpType
 
@@ -318,6 +386,15 @@ func (o EqualOp) ToMgo(field string, value interface{}) (map[string]interface{},
 	}, nil
 }
 
+// Match checks if the document matches the equal operator's field and value.
+func (o EqualOp) Match(doc mapstr.MapStr, field string, value interface{}) (bool, error) {
+	if len(field) == 0 {
+		return false, errors.New("field is empty")
+	}
+
+	return matchEqual(doc, field, value), nil
+}
+
 // NotEqualOp is not equal operator type
 type NotEqualOp OpType
 
@@ -345,6 +422,15 @@ func (ne NotEqualOp) ToMgo(field string, value interface{}) (map[string]interfac
 	}, nil
 }
 
+// Match checks if the document matches the not equal operator's field and value.
+func (ne NotEqualOp) Match(doc mapstr.MapStr, field string, value interface{}) (bool, error) {
+	if len(field) == 0 {
+		return false, errors.New("field is empty")
+	}
+
+	return !matchEqual(doc, field, value), nil
+}
+
 // InOp is in operator
 type InOp OpType
 
@@ -378,6 +464,15 @@ func (o InOp) ToMgo(field string, value interface{}) (map[string]interface{}, er
 	}, nil
 }
 
+// Match checks if the document matches the in operator's field and value.
+func (o InOp) Match(doc mapstr.MapStr, field string, value interface{}) (bool, error) {
+	if len(field) == 0 {
+		return false, errors.New("field is empty")
+	}
+
+	return matchIn(doc, field, value)
+}
+
 // NotInOp is not in operator
 type NotInOp OpType
 
@@ -411,6 +506,19 @@ func (o NotInOp) ToMgo(field string, value interface{}) (map[string]interface{},
 	}, nil
 }
 
+// Match checks if the document matches the not in operator's field and value.
+func (o NotInOp) Match(doc mapstr.MapStr, field string, value interface{}) (bool, error) {
+	if len(field) == 0 {
+		return false, errors.New("field is empty")
+	}
+
+	matched, err := matchIn(doc, field, value)
+	if err != nil {
+		return false, err
+	}
+	return !matched, nil
+}
+
 // LessOp is less than operator
 type LessOp OpType
 
@@ -438,6 +546,15 @@ func (o LessOp) ToMgo(field string, value interface{}) (map[string]interface{},
 	}, nil
 }
 
+// Match checks if the document matches the less than operator's field and value.
+func (o LessOp) Match(doc mapstr.MapStr, field string, value interface{}) (bool, error) {
+	if len(field) == 0 {
+		return false, errors.New("field is empty")
+	}
+
+	return matchNumeric(doc, field, value, func(cmp int) bool { return cmp < 0 }), nil
+}
+
 // LessOrEqualOp is less than or equal operator
 type LessOrEqualOp OpType
 
@@ -465,6 +582,15 @@ func (o LessOrEqualOp) ToMgo(field string, value interface{}) (map[string]interf
 	}, nil
 }
 
+// Match checks if the document matches the less than or equal operator's field and value.
+func (o LessOrEqualOp) Match(doc mapstr.MapStr, field string, value interface{}) (bool, error) {
+	if len(field) == 0 {
+		return false, errors.New("field is empty")
+	}
+
+	return matchNumeric(doc, field, value, func(cmp int) bool { return cmp <= 0 }), nil
+}
+
 // GreaterOp is greater than operator
 type GreaterOp OpType
 
@@ -492,6 +618,15 @@ func (o GreaterOp) ToMgo(field string, value interface{}) (map[string]interface{
 	}, nil
 }
 
+// Match checks if the document matches the greater than operator's field and value.
+func (o GreaterOp) Match(doc mapstr.MapStr, field string, value interface{}) (bool, error) {
+	if len(field) == 0 {
+		return false, errors.New("field is empty")
+	}
+
+	return matchNumeric(doc, field, value, func(cmp int) bool { return cmp > 0 }), nil
+}
+
 // GreaterOrEqualOp is greater than or equal operator
 type GreaterOrEqualOp OpType
 
@@ -519,6 +654,15 @@ func (o GreaterOrEqualOp) ToMgo(field string, value interface{}) (map[string]int
 	}, nil
 }
 
+// Match checks if the document matches the greater than or equal operator's field and value.
+func (o GreaterOrEqualOp) Match(doc mapstr.MapStr, field string, value interface{}) (bool, error) {
+	if len(field) == 0 {
+		return false, errors.New("field is empty")
+	}
+
+	return matchNumeric(doc, field, value, func(cmp int) bool { return cmp >= 0 }), nil
+}
+
 // DatetimeLessOp is datetime less than operator
 type DatetimeLessOp OpType
 
@@ -552,6 +696,15 @@ func (o DatetimeLessOp) ToMgo(field string, value interface{}) (map[string]inter
 	}, nil
 }
 
+// Match checks if the document matches the datetime less than operator's field and value.
+func (o DatetimeLessOp) Match(doc mapstr.MapStr, field string, value interface{}) (bool, error) {
+	if len(field) == 0 {
+		return false, errors.New("field is empty")
+	}
+
+	return matchDatetime(doc, field, value, func(cmp int) bool { return cmp < 0 })
+}
+
 // DatetimeLessOrEqualOp is datetime less than or equal operator
 type DatetimeLessOrEqualOp OpType
 
@@ -585,6 +738,15 @@ func (o DatetimeLessOrEqualOp) ToMgo(field string, value interface{}) (map[strin
 	}, nil
 }
 
+// Match checks if the document matches the datetime less than or equal operator's field and value.
+func (o DatetimeLessOrEqualOp) Match(doc mapstr.MapStr, field string, value interface{}) (bool, error) {
+	if len(field) == 0 {
+		return false, errors.New("field is empty")
+	}
+
+	return matchDatetime(doc, field, value, func(cmp int) bool { return cmp <= 0 })
+}
+
 // DatetimeGreaterOp is datetime greater than operator
 type DatetimeGreaterOp OpType
 
@@ -619,6 +781,15 @@ func (o DatetimeGreaterOp) ToMgo(field string, value interface{}) (map[string]in
 	}, nil
 }
 
+// Match checks if the document matches the datetime greater than operator's field and value.
+func (o DatetimeGreaterOp) Match(doc mapstr.MapStr, field string, value interface{}) (bool, error) {
+	if len(field) == 0 {
+		return false, errors.New("field is empty")
+	}
+
+	return matchDatetime(doc, field, value, func(cmp int) bool { return cmp > 0 })
+}
+
 // DatetimeGreaterOrEqualOp is datetime greater than or equal operator
 type DatetimeGreaterOrEqualOp OpType
 
@@ -652,6 +823,15 @@ func (o DatetimeGreaterOrEqualOp) ToMgo(field string, value interface{}) (map[st
 	}, nil
 }
 
+// Match checks if the document matches the datetime greater than or equal operator's field and value.
+func (o DatetimeGreaterOrEqualOp) Match(doc mapstr.MapStr, field string, value interface{}) (bool, error) {
+	if len(field) == 0 {
+		return false, errors.New("field is empty")
+	}
+
+	return matchDatetime(doc, field, value, func(cmp int) bool { return cmp >= 0 })
+}
+
 // BeginsWithOp is begins with operator
 type BeginsWithOp OpType
 
@@ -683,6 +863,24 @@ func (o BeginsWithOp) ToMgo(field string, value interface{}) (map[string]interfa
 	}, nil
 }
 
+// Match checks if the document matches the begins with operator's field and value.
+func (o BeginsWithOp) Match(doc mapstr.MapStr, field string, value interface{}) (bool, error) {
+	if len(field) == 0 {
+		return false, errors.New("field is empty")
+	}
+
+	pattern, err := regexPattern(value, "^", "")
+	if err != nil {
+		return false, err
+	}
+
+	matched, err := matchRegex(doc, field, pattern, false)
+	if err != nil {
+		return false, err
+	}
+	return matched, nil
+}
+
 // BeginsWithInsensitiveOp is begins with insensitive operator
 type BeginsWithInsensitiveOp OpType
 
@@ -715,6 +913,24 @@ func (o BeginsWithInsensitiveOp) ToMgo(field string, value interface{}) (map[str
 	}, nil
 }
 
+// Match checks if the document matches the begins with insensitive operator's field and value.
+func (o BeginsWithInsensitiveOp) Match(doc mapstr.MapStr, field string, value interface{}) (bool, error) {
+	if len(field) == 0 {
+		return false, errors.New("field is empty")
+	}
+
+	pattern, err := regexPattern(value, "^", "")
+	if err != nil {
+		return false, err
+	}
+
+	matched, err := matchRegex(doc, field, pattern, true)
+	if err != nil {
+		return false, err
+	}
+	return matched, nil
+}
+
 // NotBeginsWithOp is not begins with operator
 type NotBeginsWithOp OpType
 
@@ -746,6 +962,24 @@ func (o NotBeginsWithOp) ToMgo(field string, value interface{}) (map[string]inte
 	}, nil
 }
 
+// Match checks if the document matches the not begins with operator's field and value.
+func (o NotBeginsWithOp) Match(doc mapstr.MapStr, field string, value interface{}) (bool, error) {
+	if len(field) == 0 {
+		return false, errors.New("field is empty")
+	}
+
+	pattern, err := regexPattern(value, "^", "")
+	if err != nil {
+		return false, err
+	}
+
+	matched, err := matchRegex(doc, field, pattern, false)
+	if err != nil {
+		return false, err
+	}
+	return !matched, nil
+}
+
 // NotBeginsWithInsensitiveOp is not begins with insensitive operator
 type NotBeginsWithInsensitiveOp OpType
 
@@ -780,6 +1014,24 @@ func (o NotBeginsWithInsensitiveOp) ToMgo(field string, value interface{}) (map[
 	}, nil
 }
 
+// Match checks if the document matches the not begins with insensitive operator's field and value.
+func (o NotBeginsWithInsensitiveOp) Match(doc mapstr.MapStr, field string, value interface{}) (bool, error) {
+	if len(field) == 0 {
+		return false, errors.New("field is empty")
+	}
+
+	pattern, err := regexPattern(value, "^", "")
+	if err != nil {
+		return false, err
+	}
+
+	matched, err := matchRegex(doc, field, pattern, true)
+	if err != nil {
+		return false, err
+	}
+	return !matched, nil
+}
+
 // ContainsOp is contains operator
 type ContainsOp OpType
 
@@ -812,6 +1064,24 @@ func (o ContainsOp) ToMgo(field string, value interface{}) (map[string]interface
 	}, nil
 }
 
+// Match checks if the document matches the contains operator's field and value.
+func (o ContainsOp) Match(doc mapstr.MapStr, field string, value interface{}) (bool, error) {
+	if len(field) == 0 {
+		return false, errors.New("field is empty")
+	}
+
+	pattern, err := regexPattern(value, "", "")
+	if err != nil {
+		return false, err
+	}
+
+	matched, err := matchRegex(doc, field, pattern, true)
+	if err != nil {
+		return false, err
+	}
+	return matched, nil
+}
+
 // ContainsSensitiveOp is contains sensitive operator
 type ContainsSensitiveOp OpType
 
@@ -843,6 +1113,24 @@ func (o ContainsSensitiveOp) ToMgo(field string, value interface{}) (map[string]
 	}, nil
 }
 
+// Match checks if the document matches the contains sensitive operator's field and value.
+func (o ContainsSensitiveOp) Match(doc mapstr.MapStr, field string, value interface{}) (bool, error) {
+	if len(field) == 0 {
+		return false, errors.New("field is empty")
+	}
+
+	pattern, err := regexPattern(value, "", "")
+	if err != nil {
+		return false, err
+	}
+
+	matched, err := matchRegex(doc, field, pattern, false)
+	if err != nil {
+		return false, err
+	}
+	return matched, nil
+}
+
 // NotContainsOp is not contains operator
 type NotContainsOp OpType
 
@@ -874,6 +1162,24 @@ func (o NotContainsOp) ToMgo(field string, value interface{}) (map[string]interf
 	}, nil
 }
 
+// Match checks if the document matches the not contains operator's field and value.
+func (o NotContainsOp) Match(doc mapstr.MapStr, field string, value interface{}) (bool, error) {
+	if len(field) == 0 {
+		return false, errors.New("field is empty")
+	}
+
+	pattern, err := regexPattern(value, "", "")
+	if err != nil {
+		return false, err
+	}
+
+	matched, err := matchRegex(doc, field, pattern, false)
+	if err != nil {
+		return false, err
+	}
+	return !matched, nil
+}
+
 // NotContainsInsensitiveOp is not contains insensitive operator
 type NotContainsInsensitiveOp OpType
 
@@ -908,6 +1214,24 @@ func (o NotContainsInsensitiveOp) ToMgo(field string, value interface{}) (map[st
 	}, nil
 }
 
+// Match checks if the document matches the not contains insensitive operator's field and value.
+func (o NotContainsInsensitiveOp) Match(doc mapstr.MapStr, field string, value interface{}) (bool, error) {
+	if len(field) == 0 {
+		return false, errors.New("field is empty")
+	}
+
+	pattern, err := regexPattern(value, "", "")
+	if err != nil {
+		return false, err
+	}
+
+	matched, err := matchRegex(doc, field, pattern, true)
+	if err != nil {
+		return false, err
+	}
+	return !matched, nil
+}
+
 // EndsWithOp is ends with operator
 type EndsWithOp OpType
 
@@ -939,6 +1263,24 @@ func (o EndsWithOp) ToMgo(field string, value interface{}) (map[string]interface
 	}, nil
 }
 
+// Match checks if the document matches the ends with operator's field and value.
+func (o EndsWithOp) Match(doc mapstr.MapStr, field string, value interface{}) (bool, error) {
+	if len(field) == 0 {
+		return false, errors.New("field is empty")
+	}
+
+	pattern, err := regexPattern(value, "", "$")
+	if err != nil {
+		return false, err
+	}
+
+	matched, err := matchRegex(doc, field, pattern, false)
+	if err != nil {
+		return false, err
+	}
+	return matched, nil
+}
+
 // EndsWithInsensitiveOp is ends with insensitive operator
 type EndsWithInsensitiveOp OpType
 
@@ -971,6 +1313,24 @@ func (o EndsWithInsensitiveOp) ToMgo(field string, value interface{}) (map[strin
 	}, nil
 }
 
+// Match checks if the document matches the ends with insensitive operator's field and value.
+func (o EndsWithInsensitiveOp) Match(doc mapstr.MapStr, field string, value interface{}) (bool, error) {
+	if len(field) == 0 {
+		return false, errors.New("field is empty")
+	}
+
+	pattern, err := regexPattern(value, "", "$")
+	if err != nil {
+		return false, err
+	}
+
+	matched, err := matchRegex(doc, field, pattern, true)
+	if err != nil {
+		return false, err
+	}
+	return matched, nil
+}
+
 // NotEndsWithOp is not ends with operator
 type NotEndsWithOp OpType
 
@@ -1002,6 +1362,24 @@ func (o NotEndsWithOp) ToMgo(field string, value interface{}) (map[string]interf
 	}, nil
 }
 
+// Match checks if the document matches the not ends with operator's field and value.
+func (o NotEndsWithOp) Match(doc mapstr.MapStr, field string, value interface{}) (bool, error) {
+	if len(field) == 0 {
+		return false, errors.New("field is empty")
+	}
+
+	pattern, err := regexPattern(value, "", "$")
+	if err != nil {
+		return false, err
+	}
+
+	matched, err := matchRegex(doc, field, pattern, false)
+	if err != nil {
+		return false, err
+	}
+	return !matched, nil
+}
+
 // NotEndsWithInsensitiveOp is not ends with insensitive operator
 type NotEndsWithInsensitiveOp OpType
 
@@ -1036,6 +1414,24 @@ func (o NotEndsWithInsensitiveOp) ToMgo(field string, value interface{}) (map[st
 	}, nil
 }
 
+// Match checks if the document matches the not ends with insensitive operator's field and value.
+func (o NotEndsWithInsensitiveOp) Match(doc mapstr.MapStr, field string, value interface{}) (bool, error) {
+	if len(field) == 0 {
+		return false, errors.New("field is empty")
+	}
+
+	pattern, err := regexPattern(value, "", "$")
+	if err != nil {
+		return false, err
+	}
+
+	matched, err := matchRegex(doc, field, pattern, true)
+	if err != nil {
+		return false, err
+	}
+	return !matched, nil
+}
+
 // IsEmptyOp is empty operator
 type IsEmptyOp OpType
 
@@ -1062,6 +1458,16 @@ func (o IsEmptyOp) ToMgo(field string, value interface{}) (map[string]interface{
 	}, nil
 }
 
+// Match checks if the document matches the is empty operator's field and value.
+func (o IsEmptyOp) Match(doc mapstr.MapStr, field string, value interface{}) (bool, error) {
+	if len(field) == 0 {
+		return false, errors.New("field is empty")
+	}
+
+	values, _ := LookupFieldValues(doc, field)
+	return matchArrays(values, func(arr []interface{}) bool { return len(arr) == 0 }), nil
+}
+
 // IsNotEmptyOp is not empty operator
 type IsNotEmptyOp OpType
 
@@ -1083,11 +1489,22 @@ func (o IsNotEmptyOp) ToMgo(field string, value interface{}) (map[string]interfa
 
 	return mapstr.MapStr{
 		field: map[string]interface{}{
-			common.BKDBSize: map[string]interface{}{common.BKDBGT: 0},
+			common.BKDBType: "array",
+			common.BKDBNot:  map[string]interface{}{common.BKDBSize: 0},
 		},
 	}, nil
 }
 
+// Match checks if the document matches the is not empty operator's field and value.
+func (o IsNotEmptyOp) Match(doc mapstr.MapStr, field string, value interface{}) (bool, error) {
+	if len(field) == 0 {
+		return false, errors.New("field is empty")
+	}
+
+	values, _ := LookupFieldValues(doc, field)
+	return matchArrays(values, func(arr []interface{}) bool { return len(arr) > 0 }), nil
+}
+
 // SizeOp size operator
 type SizeOp OpType
 
@@ -1122,6 +1539,21 @@ func (o SizeOp) ToMgo(field string, value interface{}) (map[string]interface{},
 	}, nil
 }
 
+// Match checks if the document matches the size operator's field and value.
+func (o SizeOp) Match(doc mapstr.MapStr, field string, value interface{}) (bool, error) {
+	if len(field) == 0 {
+		return false, errors.New("field is empty")
+	}
+
+	size, err := util.GetInt64ByInterface(value)
+	if err != nil {
+		return false, fmt.Errorf("size operator's value is invalid, err: %v", err)
+	}
+
+	values, _ := LookupFieldValues(doc, field)
+	return matchArrays(values, func(arr []interface{}) bool { return int64(len(arr)) == size }), nil
+}
+
 // IsNullOp is null operator
 type IsNullOp OpType
 
@@ -1148,6 +1580,15 @@ func (o IsNullOp) ToMgo(field string, value interface{}) (map[string]interface{}
 	}, nil
 }
 
+// Match checks if the document matches the is null operator's field and value.
+func (o IsNullOp) Match(doc mapstr.MapStr, field string, value interface{}) (bool, error) {
+	if len(field) == 0 {
+		return false, errors.New("field is empty")
+	}
+
+	return matchEqual(doc, field, nil), nil
+}
+
 // IsNotNullOp is not null operator
 type IsNotNullOp OpType
 
@@ -1174,6 +1615,15 @@ func (o IsNotNullOp) ToMgo(field string, value interface{}) (map[string]interfac
 	}, nil
 }
 
+// Match checks if the document matches the is not null operator's field and value.
+func (o IsNotNullOp) Match(doc mapstr.MapStr, field string, value interface{}) (bool, error) {
+	if len(field) == 0 {
+		return false, errors.New("field is empty")
+	}
+
+	return !matchEqual(doc, field, nil), nil
+}
+
 // ExistOp is 'exist' operator
 type ExistOp OpType
 
@@ -1200,6 +1650,16 @@ func (o ExistOp) ToMgo(field string, value interface{}) (map[string]interface{},
 	}, nil
 }
 
+// Match checks if the document matches the exist operator's field and value.
+func (o ExistOp) Match(doc mapstr.MapStr, field string, value interface{}) (bool, error) {
+	if len(field) == 0 {
+		return false, errors.New("field is empty")
+	}
+
+	values, _ := LookupFieldValues(doc, field)
+	return len(values) > 0, nil
+}
+
 // NotExistOp is not exist operator
 type NotExistOp OpType
 
@@ -1226,6 +1686,16 @@ func (o NotExistOp) ToMgo(field string, value interface{}) (map[string]interface
 	}, nil
 }
 
+// Match checks if the document matches the not exist operator's field and value.
+func (o NotExistOp) Match(doc mapstr.MapStr, field string, value interface{}) (bool, error) {
+	if len(field) == 0 {
+		return false, errors.New("field is empty")
+	}
+
+	values, _ := LookupFieldValues(doc, field)
+	return len(values) == 0, nil
+}
+
 // ObjectOp is filter object operator
 type ObjectOp OpType
 
@@ -1279,6 +1749,25 @@ func (o ObjectOp) ToMgo(field string, value interface{}) (map[string]interface{}
 	return subRule.ToMgo(parentOpt)
 }
 
+// Match checks if the document matches the filter object operator's field and value.
+func (o ObjectOp) Match(doc mapstr.MapStr, field string, value interface{}) (bool, error) {
+	if len(field) == 0 {
+		return false, errors.New("field is empty")
+	}
+
+	subRule, ok := value.(RuleFactory)
+	if !ok {
+		return false, fmt.Errorf("filter object operator's value(%+v) is not a rule type", value)
+	}
+
+	parentOpt := &RuleOption{
+		Parent:     field,
+		ParentType: enumor.Object,
+	}
+
+	return subRule.Match(doc, parentOpt)
+}
+
 const (
 	ArrayElement = "element"
 )
@@ -1337,3 +1826,324 @@ func (o ArrayOp) ToMgo(field string, value interface{}) (map[string]interface{},
 
 	return subRule.ToMgo(parentOpt)
 }
+
+// Match checks if the document matches the filter array operator's field and value.
+func (o ArrayOp) Match(doc mapstr.MapStr, field string, value interface{}) (bool, error) {
+	if len(field) == 0 {
+		return false, errors.New("field is empty")
+	}
+
+	subRule, ok := value.(RuleFactory)
+	if !ok {
+		return false, fmt.Errorf("filter array operator's value(%+v) is not a rule type", value)
+	}
+
+	parentOpt := &RuleOption{
+		Parent:     field,
+		ParentType: enumor.Array,
+	}
+
+	return subRule.Match(doc, parentOpt)
+}
+
+// RegexOp is regular expression operator
+type RegexOp OpType
+
+// Name is regular expression operator name
+func (o RegexOp) Name() OpType {
+	return Regex
+}
+
+// ValidateValue validate regular expression operator's value
+func (o RegexOp) ValidateValue(v interface{}, opt *ExprOption) error {
+	if err := validateSafeRegex(v); err != nil {
+		return fmt.Errorf("regex operator's value is invalid, err: %v", err)
+	}
+
+	return nil
+}
+
+// ToMgo convert the regular expression operator's field and value to a mongo query condition.
+func (o RegexOp) ToMgo(field string, value interface{}) (map[string]interface{}, error) {
+	if len(field) == 0 {
+		return nil, errors.New("field is empty")
+	}
+
+	return mapstr.MapStr{
+		field: map[string]interface{}{common.BKDBLIKE: value},
+	}, nil
+}
+
+// Match checks if the document matches the regex operator's field and value.
+func (o RegexOp) Match(doc mapstr.MapStr, field string, value interface{}) (bool, error) {
+	if len(field) == 0 {
+		return false, errors.New("field is empty")
+	}
+
+	pattern, err := regexPattern(value, "", "")
+	if err != nil {
+		return false, err
+	}
+
+	matched, err := matchRegex(doc, field, pattern, false)
+	if err != nil {
+		return false, err
+	}
+	return matched, nil
+}
+
+// NotRegexOp is not regular expression operator
+type NotRegexOp OpType
+
+// Name is not regular expression operator name
+func (o NotRegexOp) Name() OpType {
+	return NotRegex
+}
+
+// ValidateValue validate not regular expression operator's value
+func (o NotRegexOp) ValidateValue(v interface{}, opt *ExprOption) error {
+	if err := validateSafeRegex(v); err != nil {
+		return fmt.Errorf("not regex operator's value is invalid, err: %v", err)
+	}
+
+	return nil
+}
+
+// ToMgo convert the not regular expression operator's field and value to a mongo query condition.
+func (o NotRegexOp) ToMgo(field string, value interface{}) (map[string]interface{}, error) {
+	if len(field) == 0 {
+		return nil, errors.New("field is empty")
+	}
+
+	return mapstr.MapStr{
+		field: map[string]interface{}{
+			common.BKDBNot: map[string]interface{}{common.BKDBLIKE: value},
+		},
+	}, nil
+}
+
+// Match checks if the document matches the not regex operator's field and value.
+func (o NotRegexOp) Match(doc mapstr.MapStr, field string, value interface{}) (bool, error) {
+	if len(field) == 0 {
+		return false, errors.New("field is empty")
+	}
+
+	pattern, err := regexPattern(value, "", "")
+	if err != nil {
+		return false, err
+	}
+
+	matched, err := matchRegex(doc, field, pattern, false)
+	if err != nil {
+		return false, err
+	}
+	return !matched, nil
+}
+
+// validateSafeRegex validate the regular expression is a safe one. mongodb uses a backtracking regex engine, so
+// only the RE2 syntax is allowed(no back reference and look around), and the nested unbounded repetitions like
+// "(a+)+" that may cause catastrophic backtracking are rejected.
+func validateSafeRegex(v interface{}) error {
+	if err := util.ValidateNotEmptyStringType(v); err != nil {
+		return err
+	}
+
+	expr := v.(string)
+	if len(expr) > MaxRegexLength {
+		return fmt.Errorf("regex length exceeds maximum limit: %d", MaxRegexLength)
+	}
+
+	re, err := syntax.Parse(expr, syntax.Perl)
+	if err != nil {
+		return fmt.Errorf("invalid regex, err: %v", err)
+	}
+
+	if hasNestedRepeat(re, false, false) {
+		return errors.New("nested repetition is not allowed in regex")
+	}
+
+	return nil
+}
+
+// hasNestedRepeat check if the regex has a repetition inside another repetition, and at least one of them is
+// unbounded, like "(a+)+" or "(a{1,3})*". bounded repetitions like "(a{1,3}){3}" are allowed.
+func hasNestedRepeat(re *syntax.Regexp, inRepeat, inUnbounded bool) bool {
+	isRepeat, isUnbounded := false, false
+	switch re.Op {
+	case syntax.OpStar, syntax.OpPlus:
+		isRepeat, isUnbounded = true, true
+	case syntax.OpRepeat:
+		isRepeat, isUnbounded = re.Max == -1 || re.Max > 1, re.Max == -1
+	}
+
+	if isRepeat && (inUnbounded || (inRepeat && isUnbounded)) {
+		return true
+	}
+
+	for _, sub := range re.Sub {
+		if hasNestedRepeat(sub, inRepeat || isRepeat, inUnbounded || isUnbounded) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// BetweenOp is between operator, it matches the numeric value in the closed interval [min, max]
+type BetweenOp OpType
+
+// Name is between operator name
+func (o BetweenOp) Name() OpType {
+	return Between
+}
+
+// ValidateValue validate between operator's value
+func (o BetweenOp) ValidateValue(v interface{}, opt *ExprOption) error {
+	min, max, err := parseRangeValue(v)
+	if err != nil {
+		return fmt.Errorf("between operator's value is invalid, err: %v", err)
+	}
+
+	if !util.IsNumeric(min) || !util.IsNumeric(max) {
+		return errors.New("between operator's value is invalid, should be an array of two numeric values")
+	}
+
+	minVal, err := util.GetFloat64ByInterface(min)
+	if err != nil {
+		return fmt.Errorf("between operator's min value is invalid, err: %v", err)
+	}
+
+	maxVal, err := util.GetFloat64ByInterface(max)
+	if err != nil {
+		return fmt.Errorf("between operator's max value is invalid, err: %v", err)
+	}
+
+	if minVal > maxVal {
+		return errors.New("between operator's min value should not be greater than the max value")
+	}
+
+	return nil
+}
+
+// ToMgo convert the between operator's field and value to a mongo query condition.
+func (o BetweenOp) ToMgo(field string, value interface{}) (map[string]interface{}, error) {
+	if len(field) == 0 {
+		return nil, errors.New("field is empty")
+	}
+
+	min, max, err := parseRangeValue(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return mapstr.MapStr{
+		field: map[string]interface{}{common.BKDBGTE: min, common.BKDBLTE: max},
+	}, nil
+}
+
+// Match checks if the document matches the between operator's field and value.
+func (o BetweenOp) Match(doc mapstr.MapStr, field string, value interface{}) (bool, error) {
+	if len(field) == 0 {
+		return false, errors.New("field is empty")
+	}
+
+	min, max, err := parseRangeValue(value)
+	if err != nil {
+		return false, err
+	}
+
+	// like mongodb, the conditions can be satisfied by different elements if the field is an array
+	return matchNumeric(doc, field, min, func(cmp int) bool { return cmp >= 0 }) &&
+		matchNumeric(doc, field, max, func(cmp int) bool { return cmp <= 0 }), nil
+}
+
+// DatetimeBetweenOp is datetime between operator, it matches the time in the closed interval [start, end]
+type DatetimeBetweenOp OpType
+
+// Name is datetime between operator name
+func (o DatetimeBetweenOp) Name() OpType {
+	return DatetimeBetween
+}
+
+// ValidateValue validate datetime between operator's value
+func (o DatetimeBetweenOp) ValidateValue(v interface{}, opt *ExprOption) error {
+	start, end, err := parseDatetimeRangeValue(v)
+	if err != nil {
+		return fmt.Errorf("datetime between operator's value is invalid, err: %v", err)
+	}
+
+	if start.After(end) {
+		return errors.New("datetime between operator's start time should not be after the end time")
+	}
+
+	return nil
+}
+
+// ToMgo convert the datetime between operator's field and value to a mongo query condition.
+func (o DatetimeBetweenOp) ToMgo(field string, value interface{}) (map[string]interface{}, error) {
+	if len(field) == 0 {
+		return nil, errors.New("field is empty")
+	}
+
+	start, end, err := parseDatetimeRangeValue(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return mapstr.MapStr{
+		field: map[string]interface{}{common.BKDBGTE: start, common.BKDBLTE: end},
+	}, nil
+}
+
+// Match checks if the document matches the datetime between operator's field and value.
+func (o DatetimeBetweenOp) Match(doc mapstr.MapStr, field string, value interface{}) (bool, error) {
+	if len(field) == 0 {
+		return false, errors.New("field is empty")
+	}
+
+	start, end, err := parseDatetimeRangeValue(value)
+	if err != nil {
+		return false, err
+	}
+
+	// like mongodb, the conditions can be satisfied by different elements if the field is an array
+	return matchTime(doc, field, start, func(cmp int) bool { return cmp >= 0 }) &&
+		matchTime(doc, field, end, func(cmp int) bool { return cmp <= 0 }), nil
+}
+
+// parseRangeValue parse the range operator's value, which should be an array of two elements [min, max]
+func parseRangeValue(v interface{}) (interface{}, interface{}, error) {
+	if v == nil {
+		return nil, nil, errors.New("value is nil")
+	}
+
+	value := reflect.ValueOf(v)
+	if value.Kind() != reflect.Array && value.Kind() != reflect.Slice {
+		return nil, nil, errors.New("value should be an array of two elements")
+	}
+
+	if value.Len() != 2 {
+		return nil, nil, fmt.Errorf("value should have two elements, but got %d", value.Len())
+	}
+
+	return value.Index(0).Interface(), value.Index(1).Interface(), nil
+}
+
+func parseDatetimeRangeValue(v interface{}) (time.Time, time.Time, error) {
+	start, end, err := parseRangeValue(v)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	startTime, err := util.ConvToTime(start)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("convert start value to time failed, err: %v", err)
+	}
+
+	endTime, err := util.ConvToTime(end)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("convert end value to time failed, err: %v", err)
+	}
+
+	return startTime, endTime, nil
+}
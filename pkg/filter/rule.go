@@ -27,6 +27,7 @@ import (
 	"configcenter/src/common"
 	"configcenter/src/common/blog"
 	"configcenter/src/common/criteria/enumor"
+	"configcenter/src/common/mapstr"
 	"configcenter/src/common/util"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -42,6 +43,8 @@ type RuleFactory interface {
 	RuleFields() []string
 	// ToMgo convert this rule to a mongo condition
 	ToMgo(opt ...*RuleOption) (map[string]interface{}, error)
+	// Match checks if the document matches this rule in memory with the same semantics as the mongo condition
+	Match(doc mapstr.MapStr, opt ...*RuleOption) (bool, error)
 }
 
 // RuleType is the expression rule's rule type.
@@ -235,30 +238,50 @@ func (ar *AtomRule) RuleFields() []string {
 
 // ToMgo convert this atom rule to a mongo query condition.
 func (ar *AtomRule) ToMgo(opts ...*RuleOption) (map[string]interface{}, error) {
-	if len(opts) > 0 && opts[0] != nil {
-		opt := opts[0]
-		if len(opt.Parent) == 0 {
-			return nil, errors.New("parent is empty")
-		}
+	field, err := ar.fieldWithOption(opts...)
+	if err != nil {
+		return nil, err
+	}
 
-		switch opt.ParentType {
-		case enumor.Object:
-			// add object parent field as prefix to generate object filter rules
-			return ar.Operator.Operator().ToMgo(opt.Parent+"."+ar.Field, ar.Value)
-		case enumor.Array:
-			switch ar.Field {
-			case ArrayElement:
-				// filter array element, matches if any of the elements matches the filter
-				return ar.Operator.Operator().ToMgo(opt.Parent, ar.Value)
-			default:
-				return nil, fmt.Errorf("filter array field %s is invalid", ar.Field)
-			}
+	return ar.Operator.Operator().ToMgo(field, ar.Value)
+}
+
+// Match checks if the document matches this atom rule.
+func (ar *AtomRule) Match(doc mapstr.MapStr, opts ...*RuleOption) (bool, error) {
+	field, err := ar.fieldWithOption(opts...)
+	if err != nil {
+		return false, err
+	}
+
+	return ar.Operator.Operator().Match(doc, field, ar.Value)
+}
+
+// fieldWithOption get the actual field of the atom rule with its parent option.
+func (ar *AtomRule) fieldWithOption(opts ...*RuleOption) (string, error) {
+	if len(opts) == 0 || opts[0] == nil {
+		return ar.Field, nil
+	}
+
+	opt := opts[0]
+	if len(opt.Parent) == 0 {
+		return "", errors.New("parent is empty")
+	}
+
+	switch opt.ParentType {
+	case enumor.Object:
+		// add object parent field as prefix to generate object filter rules
+		return opt.Parent + "." + ar.Field, nil
+	case enumor.Array:
+		switch ar.Field {
+		case ArrayElement:
+			// filter array element, matches if any of the elements matches the filter
+			return opt.Parent, nil
 		default:
-			return nil, fmt.Errorf("parent type %s is invalid", opt.ParentType)
+			return "", fmt.Errorf("filter array field %s is invalid", ar.Field)
 		}
+	default:
+		return "", fmt.Errorf("parent type %s is invalid", opt.ParentType)
 	}
-
-	return ar.Operator.Operator().ToMgo(ar.Field, ar.Value)
 }
 
 type jsonAtomRuleBroker struct {
@@ -278,8 +301,8 @@ func (ar *AtomRule) UnmarshalJSON(raw []byte) error {
 	ar.Field = br.Field
 	ar.Operator = br.Operator
 	switch br.Operator {
-	case OpFactory(In), OpFactory(NotIn):
-		// in and nin operator's value should be an array.
+	case OpFactory(In), OpFactory(NotIn), OpFactory(Between), OpFactory(DatetimeBetween):
+		// in, nin and range operator's value should be an array.
 		array := make([]interface{}, 0)
 		if err := json.Unmarshal(br.Value, &array); err != nil {
 			return err
@@ -345,8 +368,8 @@ func (ar *AtomRule) UnmarshalBSON(raw []byte) error {
 	ar.Field = br.Field
 	ar.Operator = br.Operator
 	switch br.Operator {
-	case OpFactory(In), OpFactory(NotIn):
-		// in and nin operator's value should be an array.
+	case OpFactory(In), OpFactory(NotIn), OpFactory(Between), OpFactory(DatetimeBetween):
+		// in, nin and range operator's value should be an array.
 		array := make([]interface{}, 0)
 		if err := br.Value.Unmarshal(&array); err != nil {
 			return err
@@ -459,6 +482,39 @@ func (cr *CombinedRule) ToMgo(opt ...*RuleOption) (map[string]interface{}, error
 	}
 }
 
+// Match checks if the document matches the combined rule.
+func (cr *CombinedRule) Match(doc mapstr.MapStr, opt ...*RuleOption) (bool, error) {
+	if err := cr.Condition.Validate(); err != nil {
+		return false, err
+	}
+
+	if len(cr.Rules) == 0 {
+		return false, errors.New("combined rules shouldn't be empty")
+	}
+
+	for idx, rule := range cr.Rules {
+		matched, err := rule.Match(doc, opt...)
+		if err != nil {
+			return false, fmt.Errorf("rules[%d] is invalid, err: %v", idx, err)
+		}
+
+		switch cr.Condition {
+		case Or:
+			if matched {
+				return true, nil
+			}
+		case And:
+			if !matched {
+				return false, nil
+			}
+		default:
+			return false, fmt.Errorf("unexpected operator %s", cr.Condition)
+		}
+	}
+
+	return cr.Condition == And, nil
+}
+
 type jsonCombinedRuleBroker struct {
 	Condition LogicOperator     `json:"condition"`
 	Rules     []json.RawMessage `json:"rules"`
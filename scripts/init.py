@@ -318,8 +318,56 @@ webServer:
     #权限模式，web页面使用，可选值: internal, iam
     authscheme: $auth_scheme
   login:
-    #登录模式
+    #登录模式，可选值: opensource, blueking, skip-login, ldap, oidc
     version: $loginVersion
+  # ldap登录模式相关配置，仅在login.version为ldap时生效
+  ldap:
+    # ldap服务地址，如ldap://127.0.0.1:389或ldaps://127.0.0.1:636
+    url:
+    # 使用ldaps时客户端是否跳过校验服务端证书，bool值, true为不校验, false为校验
+    insecureSkipVerify: false
+    # 用于查询用户的服务账号DN及密码，为空时匿名查询
+    bindDN:
+    bindPassword:
+    # 查询用户的根DN，如ou=people,dc=example,dc=com
+    baseDN:
+    # 查询登录用户的过滤条件，%s会被替换为转义后的用户名
+    userFilter: (uid=%s)
+    # 查询用户列表的过滤条件，这些用户会在人员选择器中展示
+    userListFilter: (objectClass=person)
+    # 用户名、显示名、邮箱、电话及所属用户组对应的ldap属性
+    userAttr: uid
+    displayNameAttr: cn
+    emailAttr: mail
+    phoneAttr: telephoneNumber
+    groupAttr: memberOf
+    # 用户组到cmdb角色的映射，格式为 用户组DN或CN:角色，如 admin:admin
+    roleMapping: []
+    # 连接及每次请求ldap的超时时间，单位为秒
+    timeoutSeconds: 10
+    # 分页查询用户列表时每页的数量
+    pageSize: 500
+  # oidc登录模式相关配置，仅在login.version为oidc时生效
+  oidc:
+    # 身份提供方的issuer地址，会从{issuer}/.well-known/openid-configuration获取授权、令牌及jwks地址
+    issuer:
+    # 在身份提供方注册的客户端ID及密钥
+    clientID:
+    clientSecret:
+    # 在身份提供方注册的回调地址，应为cmdb网址加/login/callback，如http://127.0.0.1/login/callback
+    redirectUrl:
+    # 申请的scope，需要包含openid，包含offline_access时会获取refresh token用于续期会话
+    scopes: [openid, profile, email, offline_access]
+    # 用户名、显示名、邮箱、语言及开发商对应的id token声明，开发商声明为空时使用默认开发商
+    usernameClaim: preferred_username
+    displayNameClaim: name
+    emailClaim: email
+    languageClaim: locale
+    ownerClaim:
+    # 客户端是否跳过校验身份提供方的证书，bool值, true为不校验, false为校验
+    insecureSkipVerify: false
+    # 请求身份提供方的超时时间，单位为秒
+    timeoutSeconds: 10
   #cmdb版本日志存放路径配置
   changelogPath:
     #中文版版本日志存放路径
@@ -371,6 +419,14 @@ cloudServer:
   syncTask:
     # 同步周期,最小为5分钟
     syncPeriodMinutes: 5
+  # openstack兼容私有云，云账户的ID和Key为keystone应用凭证(application credential)的id和secret
+  openstack:
+    # keystone v3认证地址，如http://127.0.0.1:5000/v3，不配置时无法使用openstack云账户
+    authUrl:
+    # 使用的服务目录中的endpoint类型，可选值为public、internal、admin，默认为public
+    interface: public
+    # 请求openstack接口的超时时间，单位为秒，默认为30秒
+    timeoutSeconds: 30
 
 #datacollection专属配置
 datacollection:
@@ -460,6 +516,22 @@ eventServer:
       fileOwner: "root"
       # 下发主机身份文件权限值
       filePrivilege: 644
+  # 事件订阅推送相关配置
+  subscription:
+    # 是否开启事件订阅推送功能, 当处于true时，会将订阅的资源事件推送到订阅的回调地址
+    startUp: true
+    # 推送失败时的最大重试次数，超过后会将该批事件记录到死信列表中，并继续推送后续的事件
+    maxRetry: 5
+    # 推送失败时首次重试的间隔，后续每次重试间隔翻倍，单位为秒
+    retryIntervalSeconds: 1
+    # 推送事件到回调地址的请求超时时间，单位为秒
+    timeoutSeconds: 10
+
+# taskServer相关配置
+taskServer:
+  # 各任务队列同时执行的最大任务数，可以按任务队列名称单独配置，如set_template_sync: 2，未配置的任务队列使用default的值
+  concurrency:
+    default: 1
 
 # 直接调用gse服务相关配置
 gse:
@@ -496,6 +568,18 @@ kafka:
     # 安全协议SASL_PLAINTEXT，SASL机制SCRAM-SHA-512的账号、密码信息
     user:
     password:
+  # cacheservice将资源变更事件同步到kafka的相关配置，每种资源的事件写入单独的topic，消息的key为事件的bk_cursor
+  eventSink:
+    # 是否开启将资源变更事件同步到kafka的功能
+    enabled: false
+    brokers:
+    # 事件topic的前缀，每种资源的topic为前缀加资源名，如bk_cmdb_event_host，topic的partition数量建议为1，保证事件的顺序性
+    topicPrefix: bk_cmdb_event_
+    # 需要同步的资源，不配置时同步所有资源的事件，可选值与watch接口的资源相同，如host、biz、set、module、object_instance等
+    resources:
+    # 安全协议SASL_PLAINTEXT，SASL机制SCRAM-SHA-512的账号、密码信息
+    user:
+    password:
 
 # cmdb服务tls配置
 tls:
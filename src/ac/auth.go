@@ -19,7 +19,9 @@ import (
 	"net/http"
 
 	"configcenter/src/ac/iam"
+	"configcenter/src/ac/local"
 	"configcenter/src/ac/meta"
+	"configcenter/src/apimachinery"
 	"configcenter/src/common/metadata"
 	"configcenter/src/scene_server/auth_server/sdk/types"
 )
@@ -64,3 +66,12 @@ type AuthorizeInterface interface {
 	BatchRegisterResourceCreatorAction(ctx context.Context, h http.Header, input metadata.IamInstancesWithCreator) (
 		[]metadata.IamCreatorActionPolicy, error)
 }
+
+// NewAuthorizer new authorizer by the auth mode, returns the local rbac authorizer in local mode, otherwise returns
+// the iam authorizer
+func NewAuthorizer(clientSet apimachinery.ClientSetInterface) AuthorizeInterface {
+	if iam.IsLocalAuthMode() {
+		return local.NewAuthorizer(clientSet)
+	}
+	return iam.NewAuthorizer(clientSet)
+}
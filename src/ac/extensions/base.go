@@ -99,3 +99,28 @@ func (am *AuthManager) batchAuthorize(ctx context.Context, header http.Header, r
 func (am *AuthManager) Enabled() bool {
 	return auth.EnableAuthorize()
 }
+
+// AuthorizeResources authorize if the user has the permissions of all the resources
+func (am *AuthManager) AuthorizeResources(ctx context.Context, header http.Header,
+	resources ...meta.ResourceAttribute) error {
+
+	if !am.Enabled() || len(resources) == 0 {
+		return nil
+	}
+
+	return am.batchAuthorize(ctx, header, resources...)
+}
+
+// GenResourcesNoPermissionResp generate the no permission response of the resources
+func (am *AuthManager) GenResourcesNoPermissionResp(ctx context.Context, header http.Header,
+	resources []meta.ResourceAttribute) (*metadata.BaseResp, error) {
+
+	rid := util.ExtractRequestIDFromContext(ctx)
+	permission, err := am.Authorizer.GetPermissionToApply(ctx, header, resources)
+	if err != nil {
+		blog.Errorf("get permission to apply failed, resources: %#v, err: %v, rid: %s", resources, err, rid)
+		return nil, err
+	}
+	resp := metadata.NewNoPermissionResp(permission)
+	return &resp, nil
+}
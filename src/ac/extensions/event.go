@@ -0,0 +1,73 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.,
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the ",License",); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an ",AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package extensions
+
+import (
+	"context"
+	"net/http"
+
+	"configcenter/src/ac/meta"
+	"configcenter/src/ac/parser"
+	"configcenter/src/common/blog"
+	"configcenter/src/common/metadata"
+	"configcenter/src/common/util"
+	"configcenter/src/common/watch"
+)
+
+func (am *AuthManager) makeWatchResource(ctx context.Context, header http.Header, resource watch.CursorType,
+	subResource string) (meta.ResourceAttribute, error) {
+
+	return parser.GenWatchResourceAttribute(string(resource), subResource, func(objID string) (int64, error) {
+		objects, err := am.collectObjectsByObjectIDs(ctx, header, 0, objID)
+		if err != nil {
+			return 0, err
+		}
+		return objects[0].ID, nil
+	})
+}
+
+// AuthorizeWatchResource authorize if the user can watch the resource, subResource is the sub resource of the watch
+// filter, the resource with all sub resources is authorized if it is not set.
+func (am *AuthManager) AuthorizeWatchResource(ctx context.Context, header http.Header, resource watch.CursorType,
+	subResource string) error {
+
+	if !am.Enabled() {
+		return nil
+	}
+
+	authResource, err := am.makeWatchResource(ctx, header, resource, subResource)
+	if err != nil {
+		return err
+	}
+
+	return am.batchAuthorize(ctx, header, authResource)
+}
+
+// GenWatchResourceNoPermissionResp generate the no permission response of watching the resource
+func (am *AuthManager) GenWatchResourceNoPermissionResp(ctx context.Context, header http.Header,
+	resource watch.CursorType, subResource string) (*metadata.BaseResp, error) {
+
+	authResource, err := am.makeWatchResource(ctx, header, resource, subResource)
+	if err != nil {
+		return nil, err
+	}
+
+	rid := util.ExtractRequestIDFromContext(ctx)
+	permission, err := am.Authorizer.GetPermissionToApply(ctx, header, []meta.ResourceAttribute{authResource})
+	if err != nil {
+		blog.Errorf("get permission to apply failed, err: %v, rid: %s", err, rid)
+		return nil, err
+	}
+	resp := metadata.NewNoPermissionResp(permission)
+	return &resp, nil
+}
@@ -42,7 +42,7 @@ type IAM struct {
 // NewIAM new iam client
 func NewIAM(cfg AuthConfig, reg prometheus.Registerer) (*IAM, error) {
 	blog.V(5).Infof("new iam with parameters cfg: %+v", cfg)
-	if !auth.EnableAuthorize() {
+	if !auth.EnableAuthorize() || IsLocalAuthMode() {
 		return new(IAM), nil
 	}
 
@@ -106,7 +106,7 @@ func NewIAM(cfg AuthConfig, reg prometheus.Registerer) (*IAM, error) {
 
 // Register cc auth resources to iam
 func (i IAM) Register(ctx context.Context, host string, objects []metadata.Object, rid string) error {
-	if !auth.EnableAuthorize() {
+	if !auth.EnableAuthorize() || IsLocalAuthMode() {
 		return nil
 	}
 
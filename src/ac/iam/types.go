@@ -41,6 +41,22 @@ const (
 	SystemIDIAM = "bk_iam"
 )
 
+const (
+	// AuthModeIAM authorize with blueking iam, it is the default auth mode
+	AuthModeIAM = "iam"
+	// AuthModeLocal authorize with the rbac roles stored in cmdb db, blueking iam is not needed in this mode
+	AuthModeLocal = "local"
+)
+
+// IsLocalAuthMode returns if the local rbac authorizer is used instead of iam, it is configured by authServer.mode
+func IsLocalAuthMode() bool {
+	mode, err := cc.String("authServer.mode")
+	if err != nil {
+		return false
+	}
+	return mode == AuthModeLocal
+}
+
 // AuthConfig TODO
 type AuthConfig struct {
 	// blueking's auth center addresses
@@ -57,7 +73,7 @@ type AuthConfig struct {
 // ParseConfigFromKV TODO
 func ParseConfigFromKV(prefix string, configMap map[string]string) (AuthConfig, error) {
 	var cfg AuthConfig
-	if !auth.EnableAuthorize() {
+	if !auth.EnableAuthorize() || IsLocalAuthMode() {
 		return AuthConfig{}, nil
 	}
 	address, err := cc.String(prefix + ".address")
@@ -39,7 +39,7 @@ func NewViewer(client apimachinery.ClientSetInterface, iam *IAM) *viewer {
 
 // CreateView create iam view for objects
 func (v *viewer) CreateView(ctx context.Context, header http.Header, objects []metadata.Object) error {
-	if !auth.EnableAuthorize() {
+	if !auth.EnableAuthorize() || IsLocalAuthMode() {
 		return nil
 	}
 
@@ -65,7 +65,7 @@ func (v *viewer) CreateView(ctx context.Context, header http.Header, objects []m
 
 // DeleteView delete iam view for objects
 func (v *viewer) DeleteView(ctx context.Context, header http.Header, objects []metadata.Object) error {
-	if !auth.EnableAuthorize() {
+	if !auth.EnableAuthorize() || IsLocalAuthMode() {
 		return nil
 	}
 
@@ -91,7 +91,7 @@ func (v *viewer) DeleteView(ctx context.Context, header http.Header, objects []m
 
 // UpdateView update iam view for objects
 func (v *viewer) UpdateView(ctx context.Context, header http.Header, objects []metadata.Object) error {
-	if !auth.EnableAuthorize() {
+	if !auth.EnableAuthorize() || IsLocalAuthMode() {
 		return nil
 	}
 
@@ -0,0 +1,205 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package local is the client of the local rbac authorizer in auth server, it is used instead of iam authorizer
+// when authServer.mode is local
+package local
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"configcenter/src/ac/meta"
+	"configcenter/src/apimachinery"
+	"configcenter/src/apimachinery/authserver"
+	"configcenter/src/common/auth"
+	"configcenter/src/common/blog"
+	"configcenter/src/common/metadata"
+	"configcenter/src/common/util"
+	"configcenter/src/scene_server/auth_server/sdk/types"
+)
+
+type authorizer struct {
+	authClientSet authserver.AuthServerClientInterface
+}
+
+// NewAuthorizer new local rbac authorizer
+func NewAuthorizer(clientSet apimachinery.ClientSetInterface) *authorizer {
+	return &authorizer{authClientSet: clientSet.AuthServer()}
+}
+
+// AuthorizeBatch batch authorization will not pass if one of them does not have permission
+func (a *authorizer) AuthorizeBatch(ctx context.Context, h http.Header, user meta.UserInfo,
+	resources ...meta.ResourceAttribute) ([]types.Decision, error) {
+	return a.authorizeBatch(ctx, h, true, user, resources...)
+}
+
+// AuthorizeAnyBatch batch authorization will pass if one of them has permission
+func (a *authorizer) AuthorizeAnyBatch(ctx context.Context, h http.Header, user meta.UserInfo,
+	resources ...meta.ResourceAttribute) ([]types.Decision, error) {
+	return a.authorizeBatch(ctx, h, false, user, resources...)
+}
+
+func (a *authorizer) authorizeBatch(ctx context.Context, h http.Header, exact bool, user meta.UserInfo,
+	resources ...meta.ResourceAttribute) ([]types.Decision, error) {
+
+	rid := util.GetHTTPCCRequestID(h)
+
+	decisions := make([]types.Decision, len(resources))
+	if !auth.EnableAuthorize() {
+		for i := range decisions {
+			decisions[i].Authorized = true
+		}
+		return decisions, nil
+	}
+
+	// resources with skip action do not need to be authorized, authIndexes are the indexes of the other resources
+	opts := &meta.LocalAuthBatchOption{User: user, Resources: make([]meta.ResourceAttribute, 0)}
+	authIndexes := make([]int, 0)
+	for index, resource := range resources {
+		if resource.Action == meta.SkipAction {
+			decisions[index].Authorized = true
+			continue
+		}
+		opts.Resources = append(opts.Resources, resource)
+		authIndexes = append(authIndexes, index)
+	}
+
+	if len(opts.Resources) == 0 {
+		return decisions, nil
+	}
+
+	var authDecisions []types.Decision
+	var err error
+	if exact {
+		authDecisions, err = a.authClientSet.LocalAuthorizeBatch(ctx, h, opts)
+	} else {
+		authDecisions, err = a.authClientSet.LocalAuthorizeAnyBatch(ctx, h, opts)
+	}
+	if err != nil {
+		blog.ErrorJSON("local authorize batch failed, err: %s, exact: %s, ops: %s, rid: %s", err, exact, opts, rid)
+		return nil, err
+	}
+
+	if len(authDecisions) != len(opts.Resources) {
+		blog.Errorf("local authorize decisions count %d mismatches resources count %d, rid: %s",
+			len(authDecisions), len(opts.Resources), rid)
+		return nil, fmt.Errorf("local authorize decisions count %d mismatches resources count %d",
+			len(authDecisions), len(opts.Resources))
+	}
+
+	for idx, decision := range authDecisions {
+		decisions[authIndexes[idx]].Authorized = decision.Authorized
+	}
+
+	return decisions, nil
+}
+
+// ListAuthorizedResources list the user's authorized resources
+func (a *authorizer) ListAuthorizedResources(ctx context.Context, h http.Header,
+	input meta.ListAuthorizedResourcesParam) (*types.AuthorizeList, error) {
+	return a.authClientSet.LocalListAuthorizedResources(ctx, h, input)
+}
+
+// GetNoAuthSkipUrl there is no permission center to apply for permissions in local mode, returns empty url
+func (a *authorizer) GetNoAuthSkipUrl(ctx context.Context, h http.Header,
+	input *metadata.IamPermission) (string, error) {
+	return "", nil
+}
+
+// GetPermissionToApply get permission to apply, it is only used to show the permissions that the user lacks.
+// there is no permission center in local mode, so the permission is generated from the resources directly.
+func (a *authorizer) GetPermissionToApply(ctx context.Context, h http.Header,
+	input []meta.ResourceAttribute) (*metadata.IamPermission, error) {
+	return genPermissionToApply(input), nil
+}
+
+// genPermissionToApply generate the permission of the resources, the resources with the same action and type are
+// merged into one related resource type
+func genPermissionToApply(resources []meta.ResourceAttribute) *metadata.IamPermission {
+	permission := &metadata.IamPermission{Actions: make([]metadata.IamAction, 0)}
+	actionIndexes := make(map[meta.Action]int)
+	for _, resource := range resources {
+		if resource.Action == meta.SkipAction {
+			continue
+		}
+
+		idx, exists := actionIndexes[resource.Action]
+		if !exists {
+			idx = len(permission.Actions)
+			actionIndexes[resource.Action] = idx
+			permission.Actions = append(permission.Actions, metadata.IamAction{
+				ID:                   string(resource.Action),
+				Name:                 string(resource.Action),
+				RelatedResourceTypes: make([]metadata.IamResourceType, 0),
+			})
+		}
+		action := &permission.Actions[idx]
+
+		typeIdx := -1
+		for i, resType := range action.RelatedResourceTypes {
+			if resType.Type == string(resource.Type) {
+				typeIdx = i
+				break
+			}
+		}
+		if typeIdx == -1 {
+			typeIdx = len(action.RelatedResourceTypes)
+			action.RelatedResourceTypes = append(action.RelatedResourceTypes, metadata.IamResourceType{
+				Type:     string(resource.Type),
+				TypeName: string(resource.Type),
+			})
+		}
+
+		if resource.InstanceID > 0 || resource.InstanceIDEx != "" {
+			resType := &action.RelatedResourceTypes[typeIdx]
+			resType.Instances = append(resType.Instances, genResourceInstances(&resource))
+		}
+	}
+	return permission
+}
+
+// genResourceInstances generate the topology path of the resource instance with its business and layers
+func genResourceInstances(resource *meta.ResourceAttribute) []metadata.IamResourceInstance {
+	instances := make([]metadata.IamResourceInstance, 0)
+	if resource.BusinessID > 0 {
+		instances = append(instances, metadata.IamResourceInstance{Type: string(meta.Business),
+			TypeName: string(meta.Business), ID: strconv.FormatInt(resource.BusinessID, 10)})
+	}
+
+	for _, layer := range resource.Layers {
+		instances = append(instances, metadata.IamResourceInstance{Type: string(layer.Type),
+			TypeName: string(layer.Type), ID: strconv.FormatInt(layer.InstanceID, 10), Name: layer.Name})
+	}
+
+	id := resource.InstanceIDEx
+	if id == "" {
+		id = strconv.FormatInt(resource.InstanceID, 10)
+	}
+	instances = append(instances, metadata.IamResourceInstance{Type: string(resource.Type),
+		TypeName: string(resource.Type), ID: id, Name: resource.Name})
+	return instances
+}
+
+// RegisterResourceCreatorAction local mode do not grant creator permissions, the permissions are granted by roles
+func (a *authorizer) RegisterResourceCreatorAction(ctx context.Context, h http.Header,
+	input metadata.IamInstanceWithCreator) ([]metadata.IamCreatorActionPolicy, error) {
+	return make([]metadata.IamCreatorActionPolicy, 0), nil
+}
+
+// BatchRegisterResourceCreatorAction local mode do not grant creator permissions, the permissions are granted by roles
+func (a *authorizer) BatchRegisterResourceCreatorAction(ctx context.Context, h http.Header,
+	input metadata.IamInstancesWithCreator) ([]metadata.IamCreatorActionPolicy, error) {
+	return make([]metadata.IamCreatorActionPolicy, 0), nil
+}
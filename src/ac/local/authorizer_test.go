@@ -0,0 +1,192 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package local
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"configcenter/src/ac/meta"
+	"configcenter/src/apimachinery/authserver"
+	"configcenter/src/common"
+	"configcenter/src/common/metadata"
+	"configcenter/src/scene_server/auth_server/sdk/types"
+)
+
+// fakeAuthClient authorizes the resources whose instance id is odd, and records the authorized resources
+type fakeAuthClient struct {
+	authserver.AuthServerClientInterface
+	exact           bool
+	resources       []meta.ResourceAttribute
+	dropOneDecision bool
+}
+
+func (f *fakeAuthClient) authorize(opts *meta.LocalAuthBatchOption) []types.Decision {
+	f.resources = opts.Resources
+	decisions := make([]types.Decision, len(opts.Resources))
+	for idx, resource := range opts.Resources {
+		decisions[idx].Authorized = resource.InstanceID%2 == 1
+	}
+	if f.dropOneDecision {
+		return decisions[1:]
+	}
+	return decisions
+}
+
+func (f *fakeAuthClient) LocalAuthorizeBatch(_ context.Context, _ http.Header, opts *meta.LocalAuthBatchOption) (
+	[]types.Decision, error) {
+	f.exact = true
+	return f.authorize(opts), nil
+}
+
+func (f *fakeAuthClient) LocalAuthorizeAnyBatch(_ context.Context, _ http.Header, opts *meta.LocalAuthBatchOption) (
+	[]types.Decision, error) {
+	f.exact = false
+	return f.authorize(opts), nil
+}
+
+func TestAuthorizeBatch(t *testing.T) {
+	resources := []meta.ResourceAttribute{
+		{Basic: meta.Basic{Type: meta.HostInstance, Action: meta.Update, InstanceID: 1}},
+		{Basic: meta.Basic{Type: meta.HostInstance, Action: meta.SkipAction, InstanceID: 2}},
+		{Basic: meta.Basic{Type: meta.HostInstance, Action: meta.Update, InstanceID: 4}},
+		{Basic: meta.Basic{Type: meta.HostInstance, Action: meta.Update, InstanceID: 5}},
+	}
+	want := []bool{true, true, false, true}
+
+	for _, exact := range []bool{true, false} {
+		client := &fakeAuthClient{exact: !exact}
+		a := &authorizer{authClientSet: client}
+
+		var decisions []types.Decision
+		var err error
+		if exact {
+			decisions, err = a.AuthorizeBatch(context.Background(), make(http.Header), meta.UserInfo{}, resources...)
+		} else {
+			decisions, err = a.AuthorizeAnyBatch(context.Background(), make(http.Header), meta.UserInfo{},
+				resources...)
+		}
+		if err != nil {
+			t.Fatalf("authorize batch failed, exact: %v, err: %v", exact, err)
+		}
+
+		if client.exact != exact {
+			t.Errorf("auth server exact = %v, want %v", client.exact, exact)
+		}
+		if len(client.resources) != 3 {
+			t.Errorf("auth server got %d resources, want the 3 resources without skip action",
+				len(client.resources))
+		}
+		if len(decisions) != len(resources) {
+			t.Fatalf("got %d decisions, want %d", len(decisions), len(resources))
+		}
+		for idx, decision := range decisions {
+			if decision.Authorized != want[idx] {
+				t.Errorf("exact: %v, resource %d authorized = %v, want %v", exact, idx, decision.Authorized,
+					want[idx])
+			}
+		}
+	}
+}
+
+func TestAuthorizeBatchAllSkipped(t *testing.T) {
+	client := new(fakeAuthClient)
+	a := &authorizer{authClientSet: client}
+
+	resources := []meta.ResourceAttribute{{Basic: meta.Basic{Type: meta.HostInstance, Action: meta.SkipAction}}}
+	decisions, err := a.AuthorizeBatch(context.Background(), make(http.Header), meta.UserInfo{}, resources...)
+	if err != nil {
+		t.Fatalf("authorize batch failed, err: %v", err)
+	}
+	if client.resources != nil {
+		t.Errorf("auth server should not be called when all resources are skipped")
+	}
+	if len(decisions) != 1 || !decisions[0].Authorized {
+		t.Errorf("skipped resource should be authorized, decisions: %v", decisions)
+	}
+}
+
+func TestAuthorizeBatchDecisionsMismatch(t *testing.T) {
+	a := &authorizer{authClientSet: &fakeAuthClient{dropOneDecision: true}}
+
+	resources := []meta.ResourceAttribute{
+		{Basic: meta.Basic{Type: meta.HostInstance, Action: meta.Update, InstanceID: 1}},
+		{Basic: meta.Basic{Type: meta.HostInstance, Action: meta.Update, InstanceID: 3}},
+	}
+	decisions, err := a.AuthorizeBatch(context.Background(), make(http.Header), meta.UserInfo{}, resources...)
+	if err == nil {
+		t.Fatalf("authorize batch should fail when decisions count mismatches, decisions: %v", decisions)
+	}
+}
+
+func TestGetPermissionToApplyDenied(t *testing.T) {
+	// the fake client panics if the permission is fetched from the auth server, which does not support it in local mode
+	a := &authorizer{authClientSet: new(fakeAuthClient)}
+
+	resources := []meta.ResourceAttribute{
+		{Basic: meta.Basic{Type: meta.HostInstance, Action: meta.Update, InstanceID: 2}, BusinessID: 3,
+			Layers: []meta.Item{{Type: meta.ModelSet, InstanceID: 4}}},
+		{Basic: meta.Basic{Type: meta.HostInstance, Action: meta.Update, InstanceID: 6}, BusinessID: 3},
+		{Basic: meta.Basic{Type: meta.Business, Action: meta.Create}},
+		{Basic: meta.Basic{Type: meta.HostInstance, Action: meta.SkipAction, InstanceID: 8}},
+	}
+	decisions, err := a.AuthorizeBatch(context.Background(), make(http.Header), meta.UserInfo{}, resources...)
+	if err != nil {
+		t.Fatalf("authorize batch failed, err: %v", err)
+	}
+
+	denied := make([]meta.ResourceAttribute, 0)
+	for idx, decision := range decisions {
+		if !decision.Authorized {
+			denied = append(denied, resources[idx])
+		}
+	}
+	if len(denied) != 3 {
+		t.Fatalf("got %d denied resources, want 3", len(denied))
+	}
+
+	permission, err := a.GetPermissionToApply(context.Background(), make(http.Header), denied)
+	if err != nil {
+		t.Fatalf("get permission to apply failed, err: %v", err)
+	}
+
+	if resp := metadata.NewNoPermissionResp(permission); resp.Code != common.CCNoPermission {
+		t.Errorf("no permission response code = %d, want %d", resp.Code, common.CCNoPermission)
+	}
+
+	if len(permission.Actions) != 2 {
+		t.Fatalf("got %d actions, want the update and create actions, permission: %+v", len(permission.Actions),
+			permission)
+	}
+
+	update := permission.Actions[0]
+	if update.ID != string(meta.Update) || len(update.RelatedResourceTypes) != 1 {
+		t.Fatalf("update action is invalid: %+v", update)
+	}
+	instances := update.RelatedResourceTypes[0].Instances
+	if len(instances) != 2 {
+		t.Fatalf("got %d update instances, want 2", len(instances))
+	}
+	path := instances[0]
+	if len(path) != 3 || path[0].ID != "3" || path[1].ID != "4" || path[2].ID != "2" ||
+		path[2].Type != string(meta.HostInstance) {
+		t.Errorf("update instance path is invalid: %+v", path)
+	}
+
+	create := permission.Actions[1]
+	if create.ID != string(meta.Create) || len(create.RelatedResourceTypes) != 1 ||
+		len(create.RelatedResourceTypes[0].Instances) != 0 {
+		t.Errorf("create action is invalid: %+v", create)
+	}
+}
@@ -0,0 +1,89 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package meta
+
+import (
+	"encoding/json"
+	"errors"
+
+	"configcenter/src/scene_server/auth_server/sdk/types"
+)
+
+// AuthExplainOption is the option to explain why the user is authorized or denied to operate the resources
+type AuthExplainOption struct {
+	User UserInfo `json:"user"`
+	// Request is the api request whose resources are parsed by the ac parser to be explained, it is optional
+	Request *AuthExplainRequest `json:"request"`
+	// Resources are the resources to be explained, they are explained after the resources parsed from the request
+	Resources []ResourceAttribute `json:"resources"`
+}
+
+// AuthExplainRequest is an api request of cmdb, like: POST /api/v3/create/instance/object/switch
+type AuthExplainRequest struct {
+	Method string          `json:"method"`
+	URL    string          `json:"url"`
+	Body   json.RawMessage `json:"body"`
+}
+
+// Validate validates the auth explain option
+func (o *AuthExplainOption) Validate() error {
+	if o.User.UserName == "" {
+		return errors.New("user name is not set")
+	}
+
+	if o.Request != nil && (o.Request.Method == "" || o.Request.URL == "") {
+		return errors.New("request method and url must be set")
+	}
+
+	if o.Request == nil && len(o.Resources) == 0 {
+		return errors.New("request and resources are both not set")
+	}
+	return nil
+}
+
+// AuthExplainResult explains the authorize decision of a resource
+type AuthExplainResult struct {
+	// Resource is the resource that is authorized
+	Resource ResourceAttribute `json:"resource"`
+	// FromRequest defines if the resource is parsed from the request
+	FromRequest bool `json:"from_request"`
+	// Skipped defines if the resource does not need to be authorized
+	Skipped bool `json:"skipped"`
+	// ActionID is the action id that the resource is authorized with
+	ActionID string `json:"action_id"`
+	// ResourceType is the resource type that the resource is authorized with
+	ResourceType string `json:"resource_type"`
+	// ResourcePath is the topology path of the resource that is checked, like: /biz,1/set,2/
+	ResourcePath []string `json:"resource_path"`
+	// Resources are the iam resources that are checked, only used in iam auth mode
+	Resources  []types.Resource `json:"resources,omitempty"`
+	Authorized bool             `json:"authorized"`
+	// Policy is the user's policy of the action that causes the decision, nil means the policy is missing.
+	// it is the iam policy in iam auth mode, and the matched local roles in local auth mode.
+	Policy interface{} `json:"policy"`
+	// Reason is the readable reason of the decision
+	Reason string `json:"reason"`
+}
+
+// LocalAuthExplainPolicy is a local role bound to the user that allows the action of the resource type
+type LocalAuthExplainPolicy struct {
+	RoleID      int64                `json:"role_id"`
+	RoleName    string               `json:"role_name"`
+	BindingID   int64                `json:"binding_id"`
+	SubjectType LocalAuthSubjectType `json:"subject_type"`
+	Subject     string               `json:"subject"`
+	ScopeType   LocalAuthScopeType   `json:"scope_type"`
+	ScopeID     int64                `json:"scope_id"`
+	// InScope defines if the resource is in the scope of the role binding
+	InScope bool `json:"in_scope"`
+}
@@ -0,0 +1,232 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package meta
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"configcenter/src/common/metadata"
+)
+
+// LocalAuthAny matches any resource type or action in the local role permission
+const LocalAuthAny = "*"
+
+// LocalAuthScopeType is the scope type of the local role binding
+type LocalAuthScopeType string
+
+const (
+	// LocalAuthScopeGlobal the bound role takes effect on all resources
+	LocalAuthScopeGlobal LocalAuthScopeType = "global"
+	// LocalAuthScopeBiz the bound role takes effect on the resources of the business
+	LocalAuthScopeBiz LocalAuthScopeType = "biz"
+	// LocalAuthScopeBizSet the bound role takes effect on the business set
+	LocalAuthScopeBizSet LocalAuthScopeType = "biz_set"
+	// LocalAuthScopeModel the bound role takes effect on the model and its instances
+	LocalAuthScopeModel LocalAuthScopeType = "model"
+)
+
+// LocalAuthSubjectType is the subject type of the local role binding
+type LocalAuthSubjectType string
+
+const (
+	// LocalAuthSubjectUser the role is bound to a user
+	LocalAuthSubjectUser LocalAuthSubjectType = "user"
+	// LocalAuthSubjectGroup the role is bound to a user group
+	LocalAuthSubjectGroup LocalAuthSubjectType = "group"
+)
+
+// LocalAuthPermission is a permission of the local role, which allows the actions on the resource type
+type LocalAuthPermission struct {
+	// ResourceType is the resource type, "*" means all resource types
+	ResourceType ResourceType `json:"resource_type" bson:"resource_type"`
+	// Actions are the allowed actions of the resource type, "*" means all actions
+	Actions []Action `json:"actions" bson:"actions"`
+}
+
+// IsMatched returns if the permission allows the action on the resource type
+func (p LocalAuthPermission) IsMatched(resourceType ResourceType, action Action) bool {
+	if p.ResourceType != LocalAuthAny && p.ResourceType != resourceType {
+		return false
+	}
+
+	for _, one := range p.Actions {
+		if one == LocalAuthAny || one == action {
+			return true
+		}
+	}
+	return false
+}
+
+// LocalAuthRole is a role of the local authorizer, which is a set of permissions
+type LocalAuthRole struct {
+	ID              int64                 `json:"id" bson:"id"`
+	Name            string                `json:"name" bson:"name"`
+	Description     string                `json:"description" bson:"description"`
+	Permissions     []LocalAuthPermission `json:"permissions" bson:"permissions"`
+	SupplierAccount string                `json:"bk_supplier_account" bson:"bk_supplier_account"`
+	Creator         string                `json:"creator" bson:"creator"`
+	Modifier        string                `json:"modifier" bson:"modifier"`
+	CreateTime      time.Time             `json:"create_time" bson:"create_time"`
+	LastTime        time.Time             `json:"last_time" bson:"last_time"`
+}
+
+// Validate validates the local role
+func (r *LocalAuthRole) Validate() error {
+	if r.Name == "" {
+		return errors.New("name is not set")
+	}
+
+	if len(r.Permissions) == 0 {
+		return errors.New("permissions are not set")
+	}
+
+	for idx, perm := range r.Permissions {
+		if perm.ResourceType == "" {
+			return fmt.Errorf("permissions[%d].resource_type is not set", idx)
+		}
+
+		if len(perm.Actions) == 0 {
+			return fmt.Errorf("permissions[%d].actions are not set", idx)
+		}
+	}
+	return nil
+}
+
+// LocalAuthGroup is a user group of the local authorizer, roles can be bound to the group for all its members
+type LocalAuthGroup struct {
+	ID              int64     `json:"id" bson:"id"`
+	Name            string    `json:"name" bson:"name"`
+	Description     string    `json:"description" bson:"description"`
+	Members         []string  `json:"members" bson:"members"`
+	SupplierAccount string    `json:"bk_supplier_account" bson:"bk_supplier_account"`
+	Creator         string    `json:"creator" bson:"creator"`
+	Modifier        string    `json:"modifier" bson:"modifier"`
+	CreateTime      time.Time `json:"create_time" bson:"create_time"`
+	LastTime        time.Time `json:"last_time" bson:"last_time"`
+}
+
+// Validate validates the local user group
+func (g *LocalAuthGroup) Validate() error {
+	if g.Name == "" {
+		return errors.New("name is not set")
+	}
+	return nil
+}
+
+// LocalAuthRoleBinding binds a role to a user or a user group in a scope
+type LocalAuthRoleBinding struct {
+	ID          int64                `json:"id" bson:"id"`
+	RoleID      int64                `json:"role_id" bson:"role_id"`
+	SubjectType LocalAuthSubjectType `json:"subject_type" bson:"subject_type"`
+	// Subject is the user name if subject type is user, or the group id if subject type is group
+	Subject   string             `json:"subject" bson:"subject"`
+	ScopeType LocalAuthScopeType `json:"scope_type" bson:"scope_type"`
+	// ScopeID is the business id, business set id or model id of the scope, it is not used in global scope
+	ScopeID         int64     `json:"scope_id" bson:"scope_id"`
+	SupplierAccount string    `json:"bk_supplier_account" bson:"bk_supplier_account"`
+	Creator         string    `json:"creator" bson:"creator"`
+	CreateTime      time.Time `json:"create_time" bson:"create_time"`
+}
+
+// Validate validates the local role binding
+func (b *LocalAuthRoleBinding) Validate() error {
+	if b.RoleID <= 0 {
+		return errors.New("role_id is invalid")
+	}
+
+	switch b.SubjectType {
+	case LocalAuthSubjectUser, LocalAuthSubjectGroup:
+	default:
+		return fmt.Errorf("subject_type %s is invalid", b.SubjectType)
+	}
+
+	if b.Subject == "" {
+		return errors.New("subject is not set")
+	}
+
+	switch b.ScopeType {
+	case LocalAuthScopeGlobal:
+		if b.ScopeID != 0 {
+			return errors.New("scope_id can not be set in global scope")
+		}
+	case LocalAuthScopeBiz, LocalAuthScopeBizSet, LocalAuthScopeModel:
+		if b.ScopeID <= 0 {
+			return errors.New("scope_id is invalid")
+		}
+	default:
+		return fmt.Errorf("scope_type %s is invalid", b.ScopeType)
+	}
+	return nil
+}
+
+// IsResourceInScope returns if the resource is in the scope of the role binding
+func (b *LocalAuthRoleBinding) IsResourceInScope(resource *ResourceAttribute) bool {
+	switch b.ScopeType {
+	case LocalAuthScopeGlobal:
+		return true
+	case LocalAuthScopeBiz:
+		if resource.BusinessID == b.ScopeID {
+			return true
+		}
+		return resource.Type == Business && resource.InstanceID == b.ScopeID
+	case LocalAuthScopeBizSet:
+		return resource.Type == BizSet && resource.InstanceID == b.ScopeID
+	case LocalAuthScopeModel:
+		if resource.Type == Model && resource.InstanceID == b.ScopeID {
+			return true
+		}
+		for _, layer := range resource.Layers {
+			if layer.Type == Model && layer.InstanceID == b.ScopeID {
+				return true
+			}
+		}
+		return false
+	default:
+		return false
+	}
+}
+
+// LocalAuthBatchOption is the option to authorize the resources of the user with the local authorizer
+type LocalAuthBatchOption struct {
+	User      UserInfo            `json:"user"`
+	Resources []ResourceAttribute `json:"resources"`
+}
+
+// LocalAuthListOption is the option to list the local roles, user groups or role bindings
+type LocalAuthListOption struct {
+	IDs []int64 `json:"ids"`
+	// RoleID and Subject are only used to list the role bindings
+	RoleID  int64             `json:"role_id"`
+	Subject string            `json:"subject"`
+	Page    metadata.BasePage `json:"page"`
+}
+
+// LocalAuthRoleListResult is the result of listing the local roles
+type LocalAuthRoleListResult struct {
+	Count uint64          `json:"count"`
+	Info  []LocalAuthRole `json:"info"`
+}
+
+// LocalAuthGroupListResult is the result of listing the local user groups
+type LocalAuthGroupListResult struct {
+	Count uint64           `json:"count"`
+	Info  []LocalAuthGroup `json:"info"`
+}
+
+// LocalAuthRoleBindingListResult is the result of listing the local role bindings
+type LocalAuthRoleBindingListResult struct {
+	Count uint64                 `json:"count"`
+	Info  []LocalAuthRoleBinding `json:"info"`
+}
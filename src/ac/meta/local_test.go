@@ -0,0 +1,111 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package meta
+
+import "testing"
+
+func TestIsResourceInScope(t *testing.T) {
+	bizResource := &ResourceAttribute{Basic: Basic{Type: HostInstance, Action: Update, InstanceID: 10}, BusinessID: 2}
+	modelInst := &ResourceAttribute{
+		Basic:  Basic{Type: MainlineInstance, Action: Update, InstanceID: 10},
+		Layers: []Item{{Type: Model, InstanceID: 3}},
+	}
+
+	tests := []struct {
+		name     string
+		binding  LocalAuthRoleBinding
+		resource *ResourceAttribute
+		want     bool
+	}{
+		{
+			name:     "global scope matches any resource",
+			binding:  LocalAuthRoleBinding{ScopeType: LocalAuthScopeGlobal},
+			resource: &ResourceAttribute{Basic: Basic{Type: CloudAccount, Action: Create}},
+			want:     true,
+		},
+		{
+			name:     "biz scope matches resource in the business",
+			binding:  LocalAuthRoleBinding{ScopeType: LocalAuthScopeBiz, ScopeID: 2},
+			resource: bizResource,
+			want:     true,
+		},
+		{
+			name:     "biz scope does not match resource in other business",
+			binding:  LocalAuthRoleBinding{ScopeType: LocalAuthScopeBiz, ScopeID: 3},
+			resource: bizResource,
+			want:     false,
+		},
+		{
+			name:     "biz scope matches the business itself",
+			binding:  LocalAuthRoleBinding{ScopeType: LocalAuthScopeBiz, ScopeID: 2},
+			resource: &ResourceAttribute{Basic: Basic{Type: Business, Action: Update, InstanceID: 2}},
+			want:     true,
+		},
+		{
+			name:     "biz scope does not match other resource with the same instance id",
+			binding:  LocalAuthRoleBinding{ScopeType: LocalAuthScopeBiz, ScopeID: 2},
+			resource: &ResourceAttribute{Basic: Basic{Type: BizSet, Action: Update, InstanceID: 2}},
+			want:     false,
+		},
+		{
+			name:     "biz scope does not match resource without business",
+			binding:  LocalAuthRoleBinding{ScopeType: LocalAuthScopeBiz, ScopeID: 2},
+			resource: &ResourceAttribute{Basic: Basic{Type: CloudAccount, Action: Create}},
+			want:     false,
+		},
+		{
+			name:     "biz set scope matches the biz set",
+			binding:  LocalAuthRoleBinding{ScopeType: LocalAuthScopeBizSet, ScopeID: 5},
+			resource: &ResourceAttribute{Basic: Basic{Type: BizSet, Action: Update, InstanceID: 5}},
+			want:     true,
+		},
+		{
+			name:     "biz set scope does not match the business",
+			binding:  LocalAuthRoleBinding{ScopeType: LocalAuthScopeBizSet, ScopeID: 5},
+			resource: &ResourceAttribute{Basic: Basic{Type: Business, Action: Update, InstanceID: 5}},
+			want:     false,
+		},
+		{
+			name:     "model scope matches the model",
+			binding:  LocalAuthRoleBinding{ScopeType: LocalAuthScopeModel, ScopeID: 3},
+			resource: &ResourceAttribute{Basic: Basic{Type: Model, Action: Update, InstanceID: 3}},
+			want:     true,
+		},
+		{
+			name:     "model scope matches the instance of the model",
+			binding:  LocalAuthRoleBinding{ScopeType: LocalAuthScopeModel, ScopeID: 3},
+			resource: modelInst,
+			want:     true,
+		},
+		{
+			name:     "model scope does not match the instance of other model",
+			binding:  LocalAuthRoleBinding{ScopeType: LocalAuthScopeModel, ScopeID: 4},
+			resource: modelInst,
+			want:     false,
+		},
+		{
+			name:     "unknown scope matches nothing",
+			binding:  LocalAuthRoleBinding{ScopeType: "unknown", ScopeID: 2},
+			resource: bizResource,
+			want:     false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.binding.IsResourceInScope(tt.resource); got != tt.want {
+				t.Errorf("IsResourceInScope() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -21,6 +21,13 @@ import (
 
 var findSystemConfigRegexp = regexp.MustCompile(`^/api/v3/admin/find/system_config/platform_setting/[^\s/]+/?$`)
 
+var (
+	updateLocalAuthRegexp = regexp.MustCompile(`^/api/v3/update/auth/(role|group)/[0-9]+/?$`)
+	deleteLocalAuthRegexp = regexp.MustCompile(`^/api/v3/delete/auth/(role|group|role_binding)/[0-9]+/?$`)
+	createLocalAuthRegexp = regexp.MustCompile(`^/api/v3/create/auth/(role|group|role_binding)/?$`)
+	findLocalAuthRegexp   = regexp.MustCompile(`^/api/v3/findmany/auth/(role|group|role_binding)/?$`)
+)
+
 func (ps *parseStream) adminRelated() *parseStream {
 	if ps.shouldReturn() {
 		return ps
@@ -28,6 +35,7 @@ func (ps *parseStream) adminRelated() *parseStream {
 
 	ps.ConfigAdmin()
 	ps.PlatformSettingConfigAuth()
+	ps.LocalAuthAdmin()
 
 	return ps
 }
@@ -80,3 +88,49 @@ func (ps *parseStream) PlatformSettingConfigAuth() *parseStream {
 	return ParseStreamWithFramework(ps, PlatformSettingConfig)
 
 }
+
+// LocalAuthAdminConfigs the roles, user groups and role bindings of the local authorizer are managed as config admin,
+// and the auth explain api is used by the config admin to debug the access problems
+var LocalAuthAdminConfigs = []AuthConfig{
+	{
+		Name:           "createLocalAuthData",
+		Description:    "创建本地权限角色、用户组或角色授权",
+		Regex:          createLocalAuthRegexp,
+		HTTPMethod:     http.MethodPost,
+		ResourceType:   meta.ConfigAdmin,
+		ResourceAction: meta.Update,
+	}, {
+		Name:           "updateLocalAuthData",
+		Description:    "更新本地权限角色或用户组",
+		Regex:          updateLocalAuthRegexp,
+		HTTPMethod:     http.MethodPut,
+		ResourceType:   meta.ConfigAdmin,
+		ResourceAction: meta.Update,
+	}, {
+		Name:           "deleteLocalAuthData",
+		Description:    "删除本地权限角色、用户组或角色授权",
+		Regex:          deleteLocalAuthRegexp,
+		HTTPMethod:     http.MethodDelete,
+		ResourceType:   meta.ConfigAdmin,
+		ResourceAction: meta.Update,
+	}, {
+		Name:           "findLocalAuthData",
+		Description:    "查询本地权限角色、用户组或角色授权",
+		Regex:          findLocalAuthRegexp,
+		HTTPMethod:     http.MethodPost,
+		ResourceType:   meta.ConfigAdmin,
+		ResourceAction: meta.Find,
+	}, {
+		Name:           "explainAuthorize",
+		Description:    "查询用户鉴权结果的原因",
+		Pattern:        "/api/v3/find/auth/explain",
+		HTTPMethod:     http.MethodPost,
+		ResourceType:   meta.ConfigAdmin,
+		ResourceAction: meta.Update,
+	},
+}
+
+// LocalAuthAdmin local authorizer management auth
+func (ps *parseStream) LocalAuthAdmin() *parseStream {
+	return ParseStreamWithFramework(ps, LocalAuthAdminConfigs)
+}
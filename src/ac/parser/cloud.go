@@ -130,6 +130,13 @@ var cloudResourceTaskConfigs = []AuthConfig{
 		HTTPMethod:     http.MethodPost,
 		ResourceType:   meta.CloudResourceTask,
 		ResourceAction: meta.Create,
+	}, {
+		Name:           "previewCloudResourceTaskPattern",
+		Description:    "预览云资源同步任务",
+		Pattern:        "/api/v3/find/cloud/sync/task/preview",
+		HTTPMethod:     http.MethodPost,
+		ResourceType:   meta.CloudResourceTask,
+		ResourceAction: meta.SkipAction,
 	}, {
 		Name:           "updateCloudResourceTaskRegex",
 		Description:    "更新云资源同步任务",
@@ -31,6 +31,7 @@ func (ps *parseStream) eventRelated() *parseStream {
 	}
 
 	ps.watch().
+		subscription().
 		syncHostIdentifier().
 		pushHostIdentifier().
 		findHostIdentifierPushResult()
@@ -54,61 +55,146 @@ func (ps *parseStream) watch() *parseStream {
 			return ps
 		}
 
-		if resource == string(watch.HostIdentifier) {
-			// redirect host identity resource to host resource in iam.
-			resource = string(watch.Host)
+		body, err := ps.RequestCtx.getRequestBody()
+		if err != nil {
+			ps.err = err
+			return ps
 		}
 
-		if resource == string(watch.BizSetRelation) {
-			// redirect biz set relation resource to biz set resource in iam.
-			resource = string(watch.BizSet)
+		authResource, err := ps.watchResourceAttribute(resource, body)
+		if err != nil {
+			ps.err = err
+			return ps
 		}
 
-		authResource := meta.ResourceAttribute{
-			Basic: meta.Basic{
-				Type:   meta.EventWatch,
-				Action: meta.Action(resource),
-			},
+		ps.Attribute.Resources = append(ps.Attribute.Resources, authResource)
+		return ps
+	}
+
+	return ps
+}
+
+// watchResourceAttribute returns the auth attribute of watching the resource, the request body is used to get the
+// watch filter of the resource.
+func (ps *parseStream) watchResourceAttribute(resource string, body []byte) (meta.ResourceAttribute, error) {
+	subResource := gjson.GetBytes(body, "bk_filter."+common.BKSubResourceField).String()
+
+	return GenWatchResourceAttribute(resource, subResource, func(objID string) (int64, error) {
+		model, err := ps.getOneModel(mapstr.MapStr{common.BKObjIDField: objID})
+		if err != nil {
+			return 0, err
 		}
+		return model.ID, nil
+	})
+}
+
+// GenWatchResourceAttribute returns the auth attribute of watching the resource, subResource is the sub resource of
+// the watch filter, getModelID is used to get the id of the model when the sub resource is an object id.
+func GenWatchResourceAttribute(resource string, subResource string, getModelID func(objID string) (int64, error)) (
+	meta.ResourceAttribute, error) {
+
+	if resource == string(watch.HostIdentifier) {
+		// redirect host identity resource to host resource in iam.
+		resource = string(watch.Host)
+	}
 
-		switch watch.CursorType(resource) {
-		case watch.ObjectBase, watch.MainlineInstance, watch.InstAsst:
-			body, err := ps.RequestCtx.getRequestBody()
-			if err != nil {
-				ps.err = err
-				return ps
-			}
-
-			// use sub resource(corresponding to the bk_obj_id of the object) for authorization if it is set
-			// if sub resource is not set, verify authorization of the resource(which means all sub resources)
-			subResource := gjson.GetBytes(body, "bk_filter."+common.BKSubResourceField)
-			if subResource.Exists() {
-				model, err := ps.getOneModel(mapstr.MapStr{common.BKObjIDField: subResource.String()})
-				if err != nil {
-					ps.err = err
-					return ps
-				}
-				authResource.InstanceID = model.ID
-			}
-		case watch.KubeWorkload:
-			body, err := ps.RequestCtx.getRequestBody()
-			if err != nil {
-				ps.err = err
-				return ps
-			}
-
-			// use sub resource(corresponding to the kind of the workload) for authorization if it is set
-			// if sub resource is not set, verify authorization of the resource(which means all sub resources)
-			subResource := gjson.GetBytes(body, "bk_filter."+common.BKSubResourceField)
-			if subResource.Exists() {
-				authResource.InstanceIDEx = subResource.String()
-			}
+	if resource == string(watch.BizSetRelation) {
+		// redirect biz set relation resource to biz set resource in iam.
+		resource = string(watch.BizSet)
+	}
+
+	if resource == string(watch.DynamicGroupMember) {
+		// redirect dynamic group member resource to host resource in iam.
+		resource = string(watch.Host)
+	}
+
+	authResource := meta.ResourceAttribute{
+		Basic: meta.Basic{
+			Type:   meta.EventWatch,
+			Action: meta.Action(resource),
+		},
+	}
+
+	// if sub resource is not set, verify authorization of the resource(which means all sub resources)
+	if len(subResource) == 0 {
+		return authResource, nil
+	}
+
+	switch watch.CursorType(resource) {
+	case watch.ObjectBase, watch.MainlineInstance, watch.InstAsst:
+		// use sub resource(corresponding to the bk_obj_id of the object) for authorization if it is set
+		modelID, err := getModelID(subResource)
+		if err != nil {
+			return authResource, err
+		}
+		authResource.InstanceID = modelID
+	case watch.KubeWorkload:
+		// use sub resource(corresponding to the kind of the workload) for authorization if it is set
+		authResource.InstanceIDEx = subResource
+	}
+
+	return authResource, nil
+}
+
+const (
+	createSubscriptionPattern       = "/api/v3/event/create/subscription"
+	findManySubscriptionPattern     = "/api/v3/event/findmany/subscription"
+	findSubscriptionDeliveryPattern = "/api/v3/event/find/subscription/delivery_status"
+)
+
+var (
+	updateSubscriptionRegexp         = regexp.MustCompile(`^/api/v3/event/update/subscription/[0-9]+/?$`)
+	deleteSubscriptionRegexp         = regexp.MustCompile(`^/api/v3/event/delete/subscription/[0-9]+/?$`)
+	findSubscriptionDeadLetterRegexp = regexp.MustCompile(`^/api/v3/event/findmany/subscription/[0-9]+/dead_letter/?$`)
+)
+
+func (ps *parseStream) subscription() *parseStream {
+	if ps.shouldReturn() {
+		return ps
+	}
+
+	// create subscription, the subscriber should have the authority to watch the subscribed resource.
+	if ps.hitPattern(createSubscriptionPattern, http.MethodPost) {
+		body, err := ps.RequestCtx.getRequestBody()
+		if err != nil {
+			ps.err = err
+			return ps
+		}
+
+		resource := gjson.GetBytes(body, "bk_resource").String()
+		if len(resource) == 0 {
+			ps.err = fmt.Errorf("create subscription, but got empty resource")
+			return ps
+		}
+
+		authResource, err := ps.watchResourceAttribute(resource, body)
+		if err != nil {
+			ps.err = err
+			return ps
 		}
 
 		ps.Attribute.Resources = append(ps.Attribute.Resources, authResource)
 		return ps
 	}
 
+	// the other subscription operations are authorized by event server with the subscribed resource and the creator
+	// of the subscription, because the subscription is only stored in event server.
+	if ps.hitRegexp(updateSubscriptionRegexp, http.MethodPut) ||
+		ps.hitRegexp(deleteSubscriptionRegexp, http.MethodDelete) ||
+		ps.hitPattern(findManySubscriptionPattern, http.MethodPost) ||
+		ps.hitPattern(findSubscriptionDeliveryPattern, http.MethodPost) ||
+		ps.hitRegexp(findSubscriptionDeadLetterRegexp, http.MethodPost) {
+
+		ps.Attribute.Resources = []meta.ResourceAttribute{
+			{
+				Basic: meta.Basic{
+					Action: meta.SkipAction,
+				},
+			},
+		}
+		return ps
+	}
+
 	return ps
 }
 
@@ -1742,6 +1742,8 @@ const (
 	findObjectBatchLatestPattern         = "/api/v3/findmany/object"
 	findObjectWithTotalInfoLatestPattern = "/api/v3/findmany/object/total/info"
 	findObjectTopologyLatestPattern      = "/api/v3/find/objecttopology"
+	diffModelSchemaLatestPattern         = "/api/v3/find/object/schema/diff"
+	applyModelSchemaLatestPattern        = "/api/v3/update/object/schema/apply"
 )
 
 var (
@@ -1954,6 +1956,32 @@ func (ps *parseStream) objectLatest() *parseStream {
 		return ps
 	}
 
+	// diff model schema with the live models.
+	if ps.hitPattern(diffModelSchemaLatestPattern, http.MethodPost) {
+		ps.Attribute.Resources = []meta.ResourceAttribute{
+			{
+				Basic: meta.Basic{
+					Type:   meta.Model,
+					Action: meta.FindMany,
+				},
+			},
+		}
+		return ps
+	}
+
+	// apply model schema, it is authorized in topo server by the resolved changes of the schema.
+	if ps.hitPattern(applyModelSchemaLatestPattern, http.MethodPost) {
+		ps.Attribute.Resources = []meta.ResourceAttribute{
+			{
+				Basic: meta.Basic{
+					Type:   meta.Model,
+					Action: meta.SkipAction,
+				},
+			},
+		}
+		return ps
+	}
+
 	// find object's topology operation.
 	if ps.hitPattern(findObjectTopologyLatestPattern, http.MethodPost) {
 		bizID, err := ps.RequestCtx.getBizIDFromBody()
@@ -23,6 +23,7 @@ import (
 	"configcenter/src/ac/meta"
 	"configcenter/src/common"
 	"configcenter/src/common/json"
+	"configcenter/src/common/mapstr"
 	"configcenter/src/common/metadata"
 	"configcenter/src/kube/types"
 )
@@ -1347,6 +1348,12 @@ var (
 	searchInstAudit   = `/api/v3/find/inst_audit`
 )
 
+var (
+	findInstHistoryRegexp = regexp.MustCompile(
+		`^/api/v3/find/inst/(history|history_diff)/object/[^\s/]+/inst/[0-9]+/?$`)
+	revertInstRegexp = regexp.MustCompile(`^/api/v3/update/inst/revert/object/[^\s/]+/inst/[0-9]+/?$`)
+)
+
 func (ps *parseStream) audit() *parseStream {
 	if ps.shouldReturn() {
 		return ps
@@ -1464,6 +1471,66 @@ func (ps *parseStream) audit() *parseStream {
 		return ps
 	}
 
+	// the instance history is reconstructed from the audit log details, so it needs the audit log find permission
+	if ps.hitRegexp(findInstHistoryRegexp, http.MethodPost) {
+		ps.Attribute.Resources = []meta.ResourceAttribute{
+			{
+				Basic: meta.Basic{
+					Type:   meta.AuditLog,
+					Action: meta.Find,
+				},
+			},
+		}
+		return ps
+	}
+
+	// revert instance is authorized as updating the instance
+	if ps.hitRegexp(revertInstRegexp, http.MethodPut) {
+		if len(ps.RequestCtx.Elements) != 9 {
+			ps.err = errors.New("revert instance, but got invalid url")
+			return ps
+		}
+
+		instID, err := strconv.ParseInt(ps.RequestCtx.Elements[8], 10, 64)
+		if err != nil {
+			ps.err = fmt.Errorf("revert instance, but got invalid instance id %s", ps.RequestCtx.Elements[8])
+			return ps
+		}
+
+		model, err := ps.getOneModel(mapstr.MapStr{common.BKObjIDField: ps.RequestCtx.Elements[6]})
+		if err != nil {
+			ps.err = err
+			return ps
+		}
+
+		instanceType, err := ps.getInstanceTypeByObject(model.ObjectID, model.ID)
+		if err != nil {
+			ps.err = err
+			return ps
+		}
+
+		var bizID int64
+		if model.ObjectID == common.BKInnerObjIDHost {
+			bizID, err = ps.getBizIDByHostID(instID)
+			if err != nil {
+				ps.err = err
+				return ps
+			}
+		}
+
+		ps.Attribute.Resources = []meta.ResourceAttribute{
+			{
+				BusinessID: bizID,
+				Basic: meta.Basic{
+					Type:       instanceType,
+					Action:     meta.Update,
+					InstanceID: instID,
+				},
+			},
+		}
+		return ps
+	}
+
 	return ps
 }
 
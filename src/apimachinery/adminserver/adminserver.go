@@ -29,6 +29,17 @@ type AdminServerClientInterface interface {
 	Set(ctx context.Context, ownerID string, h http.Header) (resp *metadata.Response, err error)
 	Migrate(ctx context.Context, ownerID string, distribution string, h http.Header) (resp *metadata.Response, err error)
 	RunSyncDBIndex(ctx context.Context, h http.Header) (*metadata.Response, error)
+	MigrateDryRun(ctx context.Context, ownerID string, distribution string, h http.Header) (
+		*metadata.MigrationDryRunResponse, error)
+	ListMigrationRecords(ctx context.Context, h http.Header, opt *metadata.ListMigrationRecordOption) (
+		*metadata.ListMigrationRecordResponse, error)
+	FindDBIndexDrift(ctx context.Context, h http.Header, opt *metadata.IndexDriftOption) (
+		*metadata.IndexDriftResponse, error)
+	ReconcileDBIndex(ctx context.Context, h http.Header, opt *metadata.IndexDriftOption) (
+		*metadata.IndexReconcileResponse, error)
+	FindDBIndexReconcileProgress(ctx context.Context, h http.Header) (*metadata.IndexReconcileResponse, error)
+	VerifyAuditLogChain(ctx context.Context, h http.Header, opt *metadata.VerifyAuditLogChainOption) (
+		*metadata.VerifyAuditLogChainResponse, error)
 }
 
 // NewAdminServerClientInterface TODO
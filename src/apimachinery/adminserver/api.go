@@ -78,3 +78,104 @@ func (a *adminServer) RunSyncDBIndex(ctx context.Context, h http.Header) (*metad
 		Into(resp)
 	return resp, err
 }
+
+// MigrateDryRun list the pending upgraders and the db operations that each of them would do
+func (a *adminServer) MigrateDryRun(ctx context.Context, ownerID string, distribution string, h http.Header) (
+	*metadata.MigrationDryRunResponse, error) {
+
+	resp := new(metadata.MigrationDryRunResponse)
+	subPath := "/migrate/dryrun/%s/%s"
+
+	err := a.client.Post().
+		WithContext(ctx).
+		Body(nil).
+		SubResourcef(subPath, distribution, ownerID).
+		WithHeaders(h).
+		Do().
+		Into(resp)
+	return resp, err
+}
+
+// ListMigrationRecords list the execution records of the upgraders
+func (a *adminServer) ListMigrationRecords(ctx context.Context, h http.Header,
+	opt *metadata.ListMigrationRecordOption) (*metadata.ListMigrationRecordResponse, error) {
+
+	resp := new(metadata.ListMigrationRecordResponse)
+	subPath := "/find/migrate/records"
+
+	err := a.client.Post().
+		WithContext(ctx).
+		Body(opt).
+		SubResourcef(subPath).
+		WithHeaders(h).
+		Do().
+		Into(resp)
+	return resp, err
+}
+
+// FindDBIndexDrift find the difference between the defined indexes and the indexes that exist in db
+func (a *adminServer) FindDBIndexDrift(ctx context.Context, h http.Header, opt *metadata.IndexDriftOption) (
+	*metadata.IndexDriftResponse, error) {
+
+	resp := new(metadata.IndexDriftResponse)
+	subPath := "/find/db/index/drift"
+
+	err := a.client.Post().
+		WithContext(ctx).
+		Body(opt).
+		SubResourcef(subPath).
+		WithHeaders(h).
+		Do().
+		Into(resp)
+	return resp, err
+}
+
+// ReconcileDBIndex start a background task to create the missing indexes in db one at a time
+func (a *adminServer) ReconcileDBIndex(ctx context.Context, h http.Header, opt *metadata.IndexDriftOption) (
+	*metadata.IndexReconcileResponse, error) {
+
+	resp := new(metadata.IndexReconcileResponse)
+	subPath := "/migrate/reconcile/db/index"
+
+	err := a.client.Post().
+		WithContext(ctx).
+		Body(opt).
+		SubResourcef(subPath).
+		WithHeaders(h).
+		Do().
+		Into(resp)
+	return resp, err
+}
+
+// FindDBIndexReconcileProgress find the progress of the latest db index reconcile task
+func (a *adminServer) FindDBIndexReconcileProgress(ctx context.Context, h http.Header) (
+	*metadata.IndexReconcileResponse, error) {
+
+	resp := new(metadata.IndexReconcileResponse)
+	subPath := "/find/db/index/reconcile/progress"
+
+	err := a.client.Get().
+		WithContext(ctx).
+		SubResourcef(subPath).
+		WithHeaders(h).
+		Do().
+		Into(resp)
+	return resp, err
+}
+
+// VerifyAuditLogChain verify the audit log hash chain of a supplier account
+func (a *adminServer) VerifyAuditLogChain(ctx context.Context, h http.Header,
+	opt *metadata.VerifyAuditLogChainOption) (*metadata.VerifyAuditLogChainResponse, error) {
+
+	resp := new(metadata.VerifyAuditLogChainResponse)
+	subPath := "/find/auditlog/chain/verify"
+
+	err := a.client.Post().
+		WithContext(ctx).
+		Body(opt).
+		SubResourcef(subPath).
+		WithHeaders(h).
+		Do().
+		Into(resp)
+	return resp, err
+}
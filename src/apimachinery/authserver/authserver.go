@@ -37,6 +37,17 @@ type AuthServerClientInterface interface {
 		[]metadata.IamCreatorActionPolicy, error)
 	BatchRegisterResourceCreatorAction(ctx context.Context, h http.Header, input metadata.IamInstancesWithCreator) (
 		[]metadata.IamCreatorActionPolicy, error)
+
+	// LocalAuthorizeBatch authorize the resources with the local rbac authorizer, all of them must be authorized
+	LocalAuthorizeBatch(ctx context.Context, h http.Header, input *meta.LocalAuthBatchOption) ([]types.Decision,
+		error)
+	// LocalAuthorizeAnyBatch authorize the resources with the local rbac authorizer, the resource is authorized if
+	// the user has the authority of its action on any instance
+	LocalAuthorizeAnyBatch(ctx context.Context, h http.Header, input *meta.LocalAuthBatchOption) ([]types.Decision,
+		error)
+	// LocalListAuthorizedResources list the authorized resources with the local rbac authorizer
+	LocalListAuthorizedResources(ctx context.Context, h http.Header, input meta.ListAuthorizedResourcesParam) (
+		*types.AuthorizeList, error)
 }
 
 // NewAuthServerClientInterface TODO
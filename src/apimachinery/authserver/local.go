@@ -0,0 +1,101 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2019 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package authserver
+
+import (
+	"context"
+	"net/http"
+
+	"configcenter/src/ac/meta"
+	"configcenter/src/common/errors"
+	"configcenter/src/common/metadata"
+	"configcenter/src/scene_server/auth_server/sdk/types"
+)
+
+// LocalAuthorizeBatch authorize the resources with the local rbac authorizer
+func (a *authServer) LocalAuthorizeBatch(ctx context.Context, h http.Header, input *meta.LocalAuthBatchOption) (
+	[]types.Decision, error) {
+
+	subPath := "/local/authorize/batch"
+	response := new(authorizeBatchResp)
+
+	err := a.client.Post().
+		WithContext(ctx).
+		Body(input).
+		SubResourcef(subPath).
+		WithHeaders(h).
+		Do().
+		Into(response)
+
+	if err != nil {
+		return nil, errors.CCHttpError
+	}
+	if response.Code != 0 {
+		return nil, response.CCError()
+	}
+
+	return response.Data, nil
+}
+
+// LocalAuthorizeAnyBatch authorize the resources' actions on any instance with the local rbac authorizer
+func (a *authServer) LocalAuthorizeAnyBatch(ctx context.Context, h http.Header, input *meta.LocalAuthBatchOption) (
+	[]types.Decision, error) {
+
+	subPath := "/local/authorize/any/batch"
+	response := new(authorizeBatchResp)
+
+	err := a.client.Post().
+		WithContext(ctx).
+		Body(input).
+		SubResourcef(subPath).
+		WithHeaders(h).
+		Do().
+		Into(response)
+
+	if err != nil {
+		return nil, errors.CCHttpError
+	}
+	if response.Code != 0 {
+		return nil, response.CCError()
+	}
+
+	return response.Data, nil
+}
+
+// LocalListAuthorizedResources list the authorized resources with the local rbac authorizer
+func (a *authServer) LocalListAuthorizedResources(ctx context.Context, h http.Header,
+	input meta.ListAuthorizedResourcesParam) (*types.AuthorizeList, error) {
+
+	response := new(struct {
+		metadata.BaseResp `json:",inline"`
+		Data              *types.AuthorizeList `json:"data"`
+	})
+	subPath := "/local/findmany/authorized_resource"
+
+	err := a.client.Post().
+		WithContext(ctx).
+		Body(input).
+		SubResourcef(subPath).
+		WithHeaders(h).
+		Do().
+		Into(response)
+
+	if err != nil {
+		return nil, errors.CCHttpError
+	}
+	if response.Code != 0 {
+		return nil, response.CCError()
+	}
+
+	return response.Data, nil
+}
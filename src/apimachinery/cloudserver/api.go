@@ -156,3 +156,18 @@ func (c *cloudserver) SearchSyncRegion(ctx context.Context, h http.Header, data
 		Into(resp)
 	return
 }
+
+// PreviewSyncTask preview the changes of the cloud sync task without writing any data
+func (c *cloudserver) PreviewSyncTask(ctx context.Context, h http.Header, data map[string]interface{}) (resp *metadata.Response, err error) {
+	resp = new(metadata.Response)
+	subPath := "/find/cloud/sync/task/preview"
+
+	err = c.client.Post().
+		WithContext(ctx).
+		Body(data).
+		SubResourcef(subPath).
+		WithHeaders(h).
+		Do().
+		Into(resp)
+	return
+}
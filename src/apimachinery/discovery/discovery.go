@@ -17,7 +17,7 @@ import (
 	"fmt"
 
 	"configcenter/src/common"
-	"configcenter/src/common/backbone/service_mange/zk"
+	"configcenter/src/common/backbone/service_mange/regdiscv"
 	"configcenter/src/common/blog"
 	"configcenter/src/common/registerdiscover"
 	"configcenter/src/common/types"
@@ -58,8 +58,8 @@ type Interface interface {
 }
 
 // NewServiceDiscovery new a simple discovery module which can be used to get alive server address
-func NewServiceDiscovery(client *zk.ZkClient) (DiscoveryInterface, error) {
-	disc := registerdiscover.NewRegDiscoverEx(client)
+func NewServiceDiscovery(client *regdiscv.Client) (DiscoveryInterface, error) {
+	disc := registerdiscover.NewRegDiscoverWithClient(client)
 
 	d := &discover{
 		servers: make(map[string]*server),
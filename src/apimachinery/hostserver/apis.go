@@ -774,3 +774,57 @@ func (hs *hostServer) DeleteCloudHostFromBiz(ctx context.Context, header http.He
 
 	return nil
 }
+
+// ExecServiceTemplateApplyPlan update the hosts of service templates by the apply plan whose conflicts are resolved
+// by the conflict policies
+func (hs *hostServer) ExecServiceTemplateApplyPlan(ctx context.Context, header http.Header,
+	option *metadata.HostApplyServiceTemplateOption) errors.CCErrorCoder {
+
+	resp := new(metadata.BaseResp)
+	subPath := "/updatemany/service_template/host_apply_plan/exec"
+
+	err := hs.client.Post().
+		WithContext(ctx).
+		Body(option).
+		SubResourcef(subPath).
+		WithHeaders(header).
+		Do().
+		Into(resp)
+
+	if err != nil {
+		return errors.CCHttpError
+	}
+
+	if err := resp.CCError(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetModuleFinalRules get the host apply rules that finally take effect on the modules, the rules of the service
+// template take precedence over the rules of the module if the service template enables host apply
+func (hs *hostServer) GetModuleFinalRules(ctx context.Context, header http.Header,
+	option *metadata.ModuleFinalRulesParam) ([]metadata.HostApplyRule, errors.CCErrorCoder) {
+
+	resp := new(metadata.ModuleFinalRulesResponse)
+	subPath := "/host/findmany/module/get_module_final_rules"
+
+	err := hs.client.Post().
+		WithContext(ctx).
+		Body(option).
+		SubResourcef(subPath).
+		WithHeaders(header).
+		Do().
+		Into(resp)
+
+	if err != nil {
+		return nil, errors.CCHttpError
+	}
+
+	if err := resp.CCError(); err != nil {
+		return nil, err
+	}
+
+	return resp.Data, nil
+}
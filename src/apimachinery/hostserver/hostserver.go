@@ -89,6 +89,10 @@ type HostServerClientInterface interface {
 		*metadata.RspIDs, errors.CCErrorCoder)
 	DeleteCloudHostFromBiz(ctx context.Context, header http.Header,
 		option *metadata.DeleteCloudHostFromBizParam) errors.CCErrorCoder
+	ExecServiceTemplateApplyPlan(ctx context.Context, header http.Header,
+		option *metadata.HostApplyServiceTemplateOption) errors.CCErrorCoder
+	GetModuleFinalRules(ctx context.Context, header http.Header, option *metadata.ModuleFinalRulesParam) (
+		[]metadata.HostApplyRule, errors.CCErrorCoder)
 }
 
 // NewHostServerClientInterface TODO
@@ -37,6 +37,14 @@ type TaskClientInterface interface {
 	TaskDetail(ctx context.Context, header http.Header, taskID string) (resp *metadata.TaskDetailResponse, err error)
 
 	DeleteTask(ctx context.Context, header http.Header, taskCond *metadata.DeleteOption) error
+
+	// CancelTask cancel a task that is not finished
+	CancelTask(ctx context.Context, header http.Header, taskID string) errors.CCErrorCoder
+	// PauseTask pause a task that is not finished, it can be resumed later
+	PauseTask(ctx context.Context, header http.Header, taskID string) errors.CCErrorCoder
+	// ResumeTask resume a paused task
+	ResumeTask(ctx context.Context, header http.Header, taskID string) errors.CCErrorCoder
+
 	// TaskStatusToSuccess(ctx context.Context, header http.Header, taskID, subTaskID string) (resp *metadata.Response, err error)
 	// TaskStatusToFailure(ctx context.Context, header http.Header, taskID, subTaskID string, errResponse *metadata.Response) (resp *metadata.Response, err error)
 
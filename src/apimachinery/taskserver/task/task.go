@@ -150,6 +150,44 @@ func (t *task) DeleteTask(ctx context.Context, header http.Header, taskCond *met
 	return nil
 }
 
+// CancelTask cancel a task that is not finished
+func (t *task) CancelTask(ctx context.Context, header http.Header, taskID string) errors.CCErrorCoder {
+	return t.changeTaskStatus(ctx, header, "/task/cancel/%s", taskID)
+}
+
+// PauseTask pause a task that is not finished
+func (t *task) PauseTask(ctx context.Context, header http.Header, taskID string) errors.CCErrorCoder {
+	return t.changeTaskStatus(ctx, header, "/task/pause/%s", taskID)
+}
+
+// ResumeTask resume a paused task
+func (t *task) ResumeTask(ctx context.Context, header http.Header, taskID string) errors.CCErrorCoder {
+	return t.changeTaskStatus(ctx, header, "/task/resume/%s", taskID)
+}
+
+func (t *task) changeTaskStatus(ctx context.Context, header http.Header, subPath string,
+	taskID string) errors.CCErrorCoder {
+
+	resp := new(metadata.Response)
+	err := t.client.Post().
+		WithContext(ctx).
+		Body(nil).
+		SubResourcef(subPath, taskID).
+		WithHeaders(header).
+		Do().
+		Into(resp)
+
+	if err != nil {
+		blog.Errorf("change task %s status failed, http request failed, err: %v", taskID, err)
+		return errors.CCHttpError
+	}
+	if err := resp.CCError(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
 // ListLatestSyncStatus list latest sync status by condition
 func (t *task) ListLatestSyncStatus(ctx context.Context, header http.Header,
 	option *metadata.ListLatestSyncStatusRequest) ([]metadata.APITaskSyncStatus, errors.CCErrorCoder) {
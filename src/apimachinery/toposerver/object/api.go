@@ -17,6 +17,7 @@ import (
 	"net/http"
 
 	"configcenter/src/apimachinery/rest"
+	"configcenter/src/common/errors"
 	"configcenter/src/common/metadata"
 )
 
@@ -79,6 +80,10 @@ type ObjectInterface interface {
 		data *metadata.UpdateUniqueRequest) (resp *metadata.Response, err error)
 	DeleteObjectUnique(ctx context.Context, objID string, h http.Header, uniqueID uint64) (resp *metadata.Response,
 		err error)
+	DiffModelSchema(ctx context.Context, h http.Header, opt *metadata.ModelSchemaOption) (
+		*metadata.ModelSchemaResult, errors.CCErrorCoder)
+	ApplyModelSchema(ctx context.Context, h http.Header, opt *metadata.ModelSchemaOption) (
+		*metadata.ModelSchemaResult, errors.CCErrorCoder)
 }
 
 // NewObjectInterface TODO
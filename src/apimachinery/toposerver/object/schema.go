@@ -0,0 +1,73 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package object
+
+import (
+	"context"
+	"net/http"
+
+	"configcenter/src/common/errors"
+	"configcenter/src/common/metadata"
+)
+
+// DiffModelSchema compare model schema with the live models
+func (t *object) DiffModelSchema(ctx context.Context, h http.Header, opt *metadata.ModelSchemaOption) (
+	*metadata.ModelSchemaResult, errors.CCErrorCoder) {
+
+	resp := new(metadata.ModelSchemaResponse)
+	subPath := "/find/object/schema/diff"
+
+	err := t.client.Post().
+		WithContext(ctx).
+		Body(opt).
+		SubResourcef(subPath).
+		WithHeaders(h).
+		Do().
+		Into(resp)
+
+	if err != nil {
+		return nil, errors.CCHttpError
+	}
+
+	if err := resp.CCError(); err != nil {
+		return nil, err
+	}
+
+	return &resp.Data, nil
+}
+
+// ApplyModelSchema apply model schema to the live models
+func (t *object) ApplyModelSchema(ctx context.Context, h http.Header, opt *metadata.ModelSchemaOption) (
+	*metadata.ModelSchemaResult, errors.CCErrorCoder) {
+
+	resp := new(metadata.ModelSchemaResponse)
+	subPath := "/update/object/schema/apply"
+
+	err := t.client.Post().
+		WithContext(ctx).
+		Body(opt).
+		SubResourcef(subPath).
+		WithHeaders(h).
+		Do().
+		Into(resp)
+
+	if err != nil {
+		return nil, errors.CCHttpError
+	}
+
+	if err := resp.CCError(); err != nil {
+		return nil, err
+	}
+
+	return &resp.Data, nil
+}
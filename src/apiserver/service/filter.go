@@ -14,9 +14,13 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"configcenter/src/ac/parser"
 	"configcenter/src/common"
@@ -58,6 +62,8 @@ const (
 	CloudType RequestType = "cloud"
 	// CacheType TODO
 	CacheType RequestType = "cache"
+	// AuthType is the request type of the local auth management apis
+	AuthType RequestType = "auth"
 )
 
 // URLFilterChan url filter chan
@@ -127,6 +133,9 @@ func (s *service) URLFilterChan(req *restful.Request, resp *restful.Response, ch
 	case CacheType:
 		servers, err = s.discovery.CacheService().GetServers()
 
+	case AuthType:
+		servers, err = s.discovery.AuthServer().GetServers()
+
 	default:
 		name := string(kind)
 		if name != "" {
@@ -260,6 +269,7 @@ func (s *service) verifyAuthorizeStatus(req *restful.Request,
 
 // KEYS[1] is the redis key to incr and expire
 // ARGV[1] is the ttl
+// returns the request count and the ttl of the window
 const setRequestCntTTLScript = `
 local cnt = redis.pcall('INCR', KEYS[1]);
 if type(cnt) ~= "number"
@@ -275,16 +285,78 @@ end
 
 if rs == -1
 then
-	rs = redis.pcall('EXPIRE', KEYS[1], ARGV[1]);
-	if type(rs) ~= "number"
+	local ok = redis.pcall('EXPIRE', KEYS[1], ARGV[1]);
+	if type(ok) ~= "number"
 	then
-		return rs
+		return ok
 	end
+	rs = tonumber(ARGV[1])
 end
 
-return cnt
+return {cnt, rs}
 `
 
+// KEYS[1] is the redis key of the token bucket, which stores the tokens and the last refill time in milliseconds
+// ARGV[1] is the capacity of the bucket
+// ARGV[2] is the number of tokens refilled in every millisecond
+// ARGV[3] is the current time in milliseconds
+// ARGV[4] is the ttl of the bucket, which is the time to refill the bucket to full
+// returns whether the request is allowed, the remaining tokens and the milliseconds to wait for the next token
+const takeBucketTokenScript = `
+local capacity = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(bucket[1])
+local ts = tonumber(bucket[2])
+if tokens == nil or ts == nil
+then
+	tokens = capacity
+	ts = now
+end
+
+if now > ts
+then
+	tokens = math.min(capacity, tokens + (now - ts) * rate)
+	ts = now
+end
+
+local allowed = 0
+local wait = 0
+if tokens >= 1
+then
+	tokens = tokens - 1
+	allowed = 1
+else
+	wait = math.ceil((1 - tokens) / rate)
+end
+
+redis.call('HMSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(ts))
+redis.call('EXPIRE', KEYS[1], ARGV[4])
+
+return {allowed, math.floor(tokens), wait}
+`
+
+const (
+	labelLimiterRule   = "rule"
+	labelLimiterDryRun = "dry_run"
+
+	// limiterStatsTTL is the ttl of the limited callers' stats of the limiter rules
+	limiterStatsTTL = 24 * time.Hour
+)
+
+// limiterResult is the result of taking the quota of a limiter rule
+type limiterResult struct {
+	allowed bool
+	// limit is the max request count in a window, or the capacity of the token bucket
+	limit int64
+	// remaining is the remaining request count in the window, or the remaining tokens
+	remaining int64
+	// reset is the seconds to wait for the quota to be reset or refilled
+	reset int64
+}
+
 // LimiterFilter limit on a api request according to limiter rules
 func (s *service) LimiterFilter() func(req *restful.Request, resp *restful.Response, fchain *restful.FilterChain) {
 	return func(req *restful.Request, resp *restful.Response, fchain *restful.FilterChain) {
@@ -294,6 +366,18 @@ func (s *service) LimiterFilter() func(req *restful.Request, resp *restful.Respo
 			return
 		}
 
+		// dry run rules only log and record the requests that should be limited
+		for _, rule := range s.limiter.GetMatchedDryRunRules(req) {
+			if !rule.DenyAll {
+				result, err := s.takeLimiterQuota(req, rule, rid)
+				if err != nil || result.allowed {
+					continue
+				}
+			}
+			blog.Warnf("request should be limited by dry run rule %#v, rid: %s", *rule, rid)
+			s.recordLimitedRequest(req, rule, rid)
+		}
+
 		rule := s.limiter.GetMatchedRule(req)
 		if rule == nil {
 			fchain.ProcessFilter(req, resp)
@@ -302,38 +386,26 @@ func (s *service) LimiterFilter() func(req *restful.Request, resp *restful.Respo
 
 		if rule.DenyAll {
 			blog.Errorf("too many requests, matched rule is %#v, rid: %s", *rule, rid)
-			rsp := metadata.BaseResp{
-				Code:   common.CCErrTooManyRequestErr,
-				ErrMsg: "too many requests",
-				Result: false,
-			}
-			resp.WriteAsJson(rsp)
+			s.recordLimitedRequest(req, rule, rid)
+			writeTooManyRequests(resp)
 			return
 		}
 
-		key := common.ApiCacheLimiterRulePrefix + rule.RuleName
-		result, err := s.cache.Eval(context.Background(), setRequestCntTTLScript, []string{key}, rule.TTL).Result()
+		result, err := s.takeLimiterQuota(req, rule, rid)
 		if err != nil {
-			blog.Errorf("redis Eval failed, key:%s, rule:%#v, err: %v, rid: %s", key, *rule, err, rid)
-			fchain.ProcessFilter(req, resp)
-			return
-		}
-		cnt, ok := result.(int64)
-		if !ok {
-			blog.Errorf("execute setRequestCntTTLScript failed, key:%s, rule:%#v, err: %v, rid: %s",
-				key, *rule, result, rid)
 			fchain.ProcessFilter(req, resp)
 			return
 		}
 
-		if cnt > rule.Limit {
+		resp.AddHeader("X-RateLimit-Limit", strconv.FormatInt(result.limit, 10))
+		resp.AddHeader("X-RateLimit-Remaining", strconv.FormatInt(result.remaining, 10))
+		resp.AddHeader("X-RateLimit-Reset", strconv.FormatInt(result.reset, 10))
+
+		if !result.allowed {
 			blog.Errorf("too many requests, matched rule is %#v, rid: %s", *rule, rid)
-			rsp := metadata.BaseResp{
-				Code:   common.CCErrTooManyRequestErr,
-				ErrMsg: "too many requests",
-				Result: false,
-			}
-			resp.WriteAsJson(rsp)
+			s.recordLimitedRequest(req, rule, rid)
+			resp.AddHeader("Retry-After", strconv.FormatInt(result.reset, 10))
+			writeTooManyRequests(resp)
 			return
 		}
 
@@ -342,6 +414,156 @@ func (s *service) LimiterFilter() func(req *restful.Request, resp *restful.Respo
 	}
 }
 
+func writeTooManyRequests(resp *restful.Response) {
+	rsp := metadata.BaseResp{
+		Code:   common.CCErrTooManyRequestErr,
+		ErrMsg: "too many requests",
+		Result: false,
+	}
+	resp.WriteAsJson(rsp)
+}
+
+// takeLimiterQuota takes one request quota of the limiter rule, returns error if redis failed, then the request
+// is not limited
+func (s *service) takeLimiterQuota(req *restful.Request, rule *metadata.LimiterRule, rid string) (*limiterResult,
+	error) {
+
+	key := common.ApiCacheLimiterRulePrefix + rule.RuleName
+	if rule.PerTenant {
+		key += ":" + util.GetOwnerID(req.Request.Header)
+	}
+
+	if rule.GetType() == metadata.LimiterTypeTokenBucket {
+		now := time.Now().UnixNano() / int64(time.Millisecond)
+		return s.takeBucketToken(key+":"+metadata.LimiterTypeTokenBucket, rule, now, rid)
+	}
+
+	result, err := s.cache.Eval(context.Background(), setRequestCntTTLScript, []string{key}, rule.TTL).Result()
+	if err != nil {
+		blog.Errorf("redis Eval failed, key:%s, rule:%#v, err: %v, rid: %s", key, *rule, err, rid)
+		return nil, err
+	}
+
+	values, ok := result.([]interface{})
+	if !ok || len(values) != 2 {
+		blog.Errorf("execute setRequestCntTTLScript failed, key:%s, rule:%#v, err: %v, rid: %s",
+			key, *rule, result, rid)
+		return nil, fmt.Errorf("invalid request count result %v", result)
+	}
+	cnt, cntOk := values[0].(int64)
+	ttl, ttlOk := values[1].(int64)
+	if !cntOk || !ttlOk {
+		blog.Errorf("execute setRequestCntTTLScript failed, key:%s, rule:%#v, err: %v, rid: %s",
+			key, *rule, result, rid)
+		return nil, fmt.Errorf("invalid request count result %v", result)
+	}
+
+	remaining := rule.Limit - cnt
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	return &limiterResult{
+		allowed:   cnt <= rule.Limit,
+		limit:     rule.Limit,
+		remaining: remaining,
+		reset:     ttl,
+	}, nil
+}
+
+// takeBucketToken takes one token from the token bucket of the limiter rule, now is the current time in milliseconds
+func (s *service) takeBucketToken(key string, rule *metadata.LimiterRule, now int64, rid string) (*limiterResult,
+	error) {
+
+	burst := rule.GetBurst()
+	// tokens refilled in every millisecond
+	rate := float64(rule.Limit) / float64(rule.TTL*1000)
+	// the bucket expires after it is refilled to full, since a full bucket is the same as a not existing one
+	ttl := int64(math.Ceil(float64(burst)/rate/1000)) + 1
+
+	result, err := s.cache.Eval(context.Background(), takeBucketTokenScript, []string{key}, burst,
+		strconv.FormatFloat(rate, 'f', -1, 64), now, ttl).Result()
+	if err != nil {
+		blog.Errorf("redis Eval failed, key:%s, rule:%#v, err: %v, rid: %s", key, *rule, err, rid)
+		return nil, err
+	}
+
+	values, ok := result.([]interface{})
+	if !ok || len(values) != 3 {
+		blog.Errorf("execute takeBucketTokenScript failed, key:%s, rule:%#v, err: %v, rid: %s", key, *rule,
+			result, rid)
+		return nil, fmt.Errorf("invalid token bucket result %v", result)
+	}
+	allowed, allowedOk := values[0].(int64)
+	remaining, remainingOk := values[1].(int64)
+	wait, waitOk := values[2].(int64)
+	if !allowedOk || !remainingOk || !waitOk {
+		blog.Errorf("execute takeBucketTokenScript failed, key:%s, rule:%#v, err: %v, rid: %s", key, *rule,
+			result, rid)
+		return nil, fmt.Errorf("invalid token bucket result %v", result)
+	}
+
+	return &limiterResult{
+		allowed:   allowed == 1,
+		limit:     burst,
+		remaining: remaining,
+		reset:     getBucketReset(allowed == 1, burst, remaining, wait, rate),
+	}, nil
+}
+
+// getBucketReset returns the seconds to wait for the next token if the request is limited, which is at least one
+// second, otherwise it is the seconds to refill the bucket to full. wait is in milliseconds, and rate is the tokens
+// refilled in every millisecond.
+func getBucketReset(allowed bool, burst, remaining, wait int64, rate float64) int64 {
+	if allowed {
+		return int64(math.Ceil(float64(burst-remaining) / rate / 1000))
+	}
+
+	reset := int64(math.Ceil(float64(wait) / 1000))
+	if reset < 1 {
+		reset = 1
+	}
+	return reset
+}
+
+// recordLimitedRequest records the caller of the request limited by the rule into metrics and redis stats, which
+// can be viewed with the cmdb_ctl limiter stats command
+func (s *service) recordLimitedRequest(req *restful.Request, rule *metadata.LimiterRule, rid string) {
+	header := req.Request.Header
+	caller := metadata.LimiterCaller{
+		AppCode:         header.Get(common.BKHTTPRequestAppCode),
+		User:            header.Get(common.BKHTTPHeaderUser),
+		IP:              header.Get(common.BKHTTPRequestRealIP),
+		SupplierAccount: util.GetOwnerID(header),
+		DryRun:          rule.DryRun,
+	}
+
+	if s.limitedRequestTotal != nil {
+		// the users and supplier accounts are only recorded in the stats, since they make too many label values
+		s.limitedRequestTotal.With(prometheus.Labels{
+			labelLimiterRule:     rule.RuleName,
+			metrics.LabelAppCode: caller.AppCode,
+			labelLimiterDryRun:   strconv.FormatBool(caller.DryRun),
+		}).Inc()
+	}
+
+	field, err := json.Marshal(caller)
+	if err != nil {
+		blog.Errorf("marshal limited caller %#v failed, err: %v, rid: %s", caller, err, rid)
+		return
+	}
+
+	key := common.ApiCacheLimiterStatsPrefix + rule.RuleName
+	if err := s.cache.HIncrBy(context.Background(), key, string(field), 1).Err(); err != nil {
+		blog.Errorf("record limited caller %s for rule %s failed, err: %v, rid: %s", field, rule.RuleName, err, rid)
+		return
+	}
+
+	if err := s.cache.Expire(context.Background(), key, limiterStatsTTL).Err(); err != nil {
+		blog.Errorf("set limiter stats %s ttl failed, err: %v, rid: %s", key, err, rid)
+	}
+}
+
 // JwtFilter the filter that handles the source of the jwt request
 func (s *service) JwtFilter() func(req *restful.Request, resp *restful.Response, fchain *restful.FilterChain) {
 	return func(req *restful.Request, resp *restful.Response, fchain *restful.FilterChain) {
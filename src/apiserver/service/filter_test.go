@@ -0,0 +1,173 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"configcenter/src/common"
+	"configcenter/src/common/metadata"
+	"configcenter/src/storage/dal/redis"
+
+	"github.com/alicebob/miniredis"
+	"github.com/emicklei/go-restful/v3"
+	goredis "github.com/go-redis/redis/v7"
+)
+
+func newLimiterTestService(t *testing.T, rules ...*metadata.LimiterRule) (*service, *miniredis.Miniredis) {
+	redisMock, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("run redis mock failed, err: %v", err)
+	}
+	t.Cleanup(redisMock.Close)
+
+	limiter := new(Limiter)
+	ruleMap := make(map[string]*metadata.LimiterRule)
+	for _, rule := range rules {
+		ruleMap[rule.RuleName] = rule
+	}
+	limiter.setRules(ruleMap)
+
+	return &service{
+		cache:   redis.NewClient(&goredis.Options{Addr: redisMock.Addr()}),
+		limiter: limiter,
+	}, redisMock
+}
+
+func TestTakeBucketToken(t *testing.T) {
+	// 2 tokens in every second with the capacity of 2, which is 0.002 tokens in every millisecond
+	rule := &metadata.LimiterRule{RuleName: "bucket", Limit: 2, TTL: 1, Type: metadata.LimiterTypeTokenBucket}
+	s, redisMock := newLimiterTestService(t, rule)
+	key := "test_bucket"
+
+	steps := []struct {
+		now  int64
+		want limiterResult
+	}{
+		// the bucket is full at first
+		{now: 1000, want: limiterResult{allowed: true, limit: 2, remaining: 1, reset: 1}},
+		{now: 1000, want: limiterResult{allowed: true, limit: 2, remaining: 0, reset: 1}},
+		// the next token is refilled after 500 milliseconds
+		{now: 1000, want: limiterResult{allowed: false, limit: 2, remaining: 0, reset: 1}},
+		{now: 1250, want: limiterResult{allowed: false, limit: 2, remaining: 0, reset: 1}},
+		{now: 1500, want: limiterResult{allowed: true, limit: 2, remaining: 0, reset: 1}},
+		// the bucket is refilled to full, and the tokens are no more than the capacity
+		{now: 5000, want: limiterResult{allowed: true, limit: 2, remaining: 1, reset: 1}},
+		// the time goes back will not refill the bucket
+		{now: 4000, want: limiterResult{allowed: true, limit: 2, remaining: 0, reset: 1}},
+		{now: 4000, want: limiterResult{allowed: false, limit: 2, remaining: 0, reset: 1}},
+	}
+
+	for index, step := range steps {
+		result, err := s.takeBucketToken(key, rule, step.now, "test")
+		if err != nil {
+			t.Fatalf("step %d take bucket token failed, err: %v", index, err)
+		}
+		if *result != step.want {
+			t.Errorf("step %d take bucket token result %+v, want %+v", index, *result, step.want)
+		}
+	}
+
+	// the bucket expires after it is refilled to full
+	if ttl := redisMock.TTL(key); ttl != 2*time.Second {
+		t.Errorf("bucket ttl is %v, want 2s", ttl)
+	}
+}
+
+func TestTakeLimiterQuotaFixedWindow(t *testing.T) {
+	rule := &metadata.LimiterRule{RuleName: "window", AppCode: "app", Limit: 2, TTL: 10}
+	s, _ := newLimiterTestService(t, rule)
+	req := restful.NewRequest(httptest.NewRequest(http.MethodGet, "/api/v3/test", nil))
+
+	wants := []limiterResult{
+		{allowed: true, limit: 2, remaining: 1, reset: 10},
+		{allowed: true, limit: 2, remaining: 0, reset: 10},
+		{allowed: false, limit: 2, remaining: 0, reset: 10},
+	}
+	for index, want := range wants {
+		result, err := s.takeLimiterQuota(req, rule, "test")
+		if err != nil {
+			t.Fatalf("request %d take quota failed, err: %v", index, err)
+		}
+		if *result != want {
+			t.Errorf("request %d take quota result %+v, want %+v", index, *result, want)
+		}
+	}
+}
+
+func TestGetBucketReset(t *testing.T) {
+	tests := []struct {
+		name      string
+		allowed   bool
+		burst     int64
+		remaining int64
+		wait      int64
+		rate      float64
+		want      int64
+	}{
+		{name: "allowed with full bucket", allowed: true, burst: 10, remaining: 10, rate: 0.01, want: 0},
+		{name: "allowed refill to full", allowed: true, burst: 10, remaining: 5, rate: 0.001, want: 5},
+		{name: "allowed refill in part of second", allowed: true, burst: 10, remaining: 9, rate: 0.002, want: 1},
+		{name: "limited wait for seconds", allowed: false, burst: 10, wait: 2500, rate: 0.001, want: 3},
+		{name: "limited wait less than one second", allowed: false, burst: 10, wait: 1, rate: 0.5, want: 1},
+		{name: "limited without wait", allowed: false, burst: 10, wait: 0, rate: 0.5, want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getBucketReset(tt.allowed, tt.burst, tt.remaining, tt.wait, tt.rate); got != tt.want {
+				t.Errorf("getBucketReset() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLimiterFilterHeaders(t *testing.T) {
+	rule := &metadata.LimiterRule{RuleName: "app", AppCode: "app", Limit: 1, TTL: 60,
+		Type: metadata.LimiterTypeTokenBucket}
+	s, _ := newLimiterTestService(t, rule)
+	filter := s.LimiterFilter()
+
+	doRequest := func() (*httptest.ResponseRecorder, bool) {
+		httpReq := httptest.NewRequest(http.MethodGet, "/api/v3/test", nil)
+		httpReq.Header.Set(common.BKHTTPRequestAppCode, "app")
+		recorder := httptest.NewRecorder()
+		resp := restful.NewResponse(recorder)
+
+		processed := false
+		chain := &restful.FilterChain{Target: func(*restful.Request, *restful.Response) { processed = true }}
+		filter(restful.NewRequest(httpReq), resp, chain)
+		return recorder, processed
+	}
+
+	recorder, processed := doRequest()
+	if !processed || recorder.Header().Get("Retry-After") != "" {
+		t.Fatalf("first request should be processed without Retry-After, header: %v", recorder.Header())
+	}
+	if recorder.Header().Get("X-RateLimit-Limit") != "1" || recorder.Header().Get("X-RateLimit-Remaining") != "0" {
+		t.Errorf("unexpected rate limit headers: %v", recorder.Header())
+	}
+
+	// one token is refilled in every 60 seconds
+	recorder, processed = doRequest()
+	if processed {
+		t.Fatalf("second request should be limited")
+	}
+	retryAfter := recorder.Header().Get("Retry-After")
+	if retryAfter == "" || retryAfter == "0" || retryAfter != recorder.Header().Get("X-RateLimit-Reset") {
+		t.Errorf("unexpected Retry-After %s, header: %v", retryAfter, recorder.Header())
+	}
+}
@@ -21,32 +21,32 @@ import (
 	"time"
 
 	"configcenter/src/common"
+	"configcenter/src/common/backbone/service_mange/regdiscv"
 	"configcenter/src/common/blog"
 	"configcenter/src/common/metadata"
 	"configcenter/src/common/types"
 	"configcenter/src/common/util"
-	"configcenter/src/common/zkclient"
 
 	"github.com/emicklei/go-restful/v3"
 )
 
 // Limiter TODO
 type Limiter struct {
-	zkCli        *zkclient.ZkClient
+	client       *regdiscv.Client
 	rules        map[string]*metadata.LimiterRule
 	lock         sync.RWMutex
 	syncDuration time.Duration
 }
 
 // NewLimiter TODO
-func NewLimiter(zkCli *zkclient.ZkClient) *Limiter {
+func NewLimiter(client *regdiscv.Client) *Limiter {
 	return &Limiter{
-		zkCli:        zkCli,
+		client:       client,
 		syncDuration: 5 * time.Second,
 	}
 }
 
-// SyncLimiterRules sync the api limiter rules from zk
+// SyncLimiterRules sync the api limiter rules from register and discover backend
 func (l *Limiter) SyncLimiterRules() error {
 	blog.Info("begin SyncLimiterRules")
 	path := types.CC_SERVLIMITER_BASEPATH
@@ -64,9 +64,9 @@ func (l *Limiter) SyncLimiterRules() error {
 
 func (l *Limiter) syncLimiterRules(path string) error {
 	blog.V(5).Infof("syncing limiter rules for path:%s", path)
-	children, err := l.zkCli.GetChildren(path)
+	children, err := l.client.KV().GetChildren(path)
 	if err != nil {
-		if err == zkclient.ErrNoNode {
+		if l.client.IsNoNodeErr(err) {
 			// if no rules, set rules to be empty
 			l.setRules(make(map[string]*metadata.LimiterRule))
 			return nil
@@ -77,7 +77,7 @@ func (l *Limiter) syncLimiterRules(path string) error {
 
 	rules := make(map[string]*metadata.LimiterRule)
 	for _, child := range children {
-		data, err := l.zkCli.Get(path + "/" + child)
+		data, err := l.client.KV().Get(path + "/" + child)
 		if err != nil {
 			blog.Errorf("fail to Get for path:%s, err:%s", path, err.Error())
 			continue
@@ -134,55 +134,15 @@ func (l *Limiter) LenOfRules() int {
 	return len(l.rules)
 }
 
-// GetMatchedRule get the matched limiter rule according request
+// GetMatchedRule get the matched limiter rule according request, dry run rules are not included
 func (l *Limiter) GetMatchedRule(req *restful.Request) *metadata.LimiterRule {
-	header := req.Request.Header
 	var matchedRule *metadata.LimiterRule
 	var min int64 = 999999
 	rules := l.GetRules()
 	for _, r := range rules {
-		if r.AppCode == "" && r.User == "" && r.IP == "" && r.Url == "" && r.Method == "" {
-			blog.Errorf("wrong rule format, one of appcode, user, ip, url, method must be set, r:%#v", *r)
+		if r.DryRun || !isRuleMatched(r, req) {
 			continue
 		}
-		if r.AppCode != "" {
-			if r.AppCode != header.Get(common.BKHTTPRequestAppCode) {
-				continue
-			}
-		}
-		if r.User != "" {
-			if r.User != header.Get(common.BKHTTPHeaderUser) {
-				continue
-			}
-		}
-		if r.IP != "" {
-			hit := false
-			ips := strings.Split(r.IP, ",")
-			for _, ip := range ips {
-				if strings.TrimSpace(ip) == strings.TrimSpace(header.Get(common.BKHTTPRequestRealIP)) {
-					hit = true
-					break
-				}
-			}
-			if hit == false {
-				continue
-			}
-		}
-		if r.Method != "" {
-			if util.Normalize(r.Method) != util.Normalize(req.Request.Method) {
-				continue
-			}
-		}
-		if r.Url != "" {
-			match, err := regexp.MatchString(r.Url, req.Request.RequestURI)
-			if err != nil {
-				blog.Errorf("MatchString failed, r.Url:%s, reqURI:%s, err:%s", r.Url, req.Request.RequestURI, err.Error())
-				continue
-			}
-			if !match {
-				continue
-			}
-		}
 
 		if r.DenyAll == true {
 			matchedRule = r
@@ -195,3 +155,68 @@ func (l *Limiter) GetMatchedRule(req *restful.Request) *metadata.LimiterRule {
 	}
 	return matchedRule
 }
+
+// GetMatchedDryRunRules get all the matched dry run limiter rules according request
+func (l *Limiter) GetMatchedDryRunRules(req *restful.Request) []*metadata.LimiterRule {
+	matchedRules := make([]*metadata.LimiterRule, 0)
+	rules := l.GetRules()
+	for _, r := range rules {
+		if r.DryRun && isRuleMatched(r, req) {
+			matchedRules = append(matchedRules, r)
+		}
+	}
+	return matchedRules
+}
+
+func isRuleMatched(r *metadata.LimiterRule, req *restful.Request) bool {
+	header := req.Request.Header
+	if !r.HasCondition() {
+		blog.Errorf("wrong rule format, one of appcode, user, ip, url, method, bk_supplier_account, pertenant must "+
+			"be set, r:%#v", *r)
+		return false
+	}
+	if r.AppCode != "" {
+		if r.AppCode != header.Get(common.BKHTTPRequestAppCode) {
+			return false
+		}
+	}
+	if r.User != "" {
+		if r.User != header.Get(common.BKHTTPHeaderUser) {
+			return false
+		}
+	}
+	if r.SupplierAccount != "" {
+		if r.SupplierAccount != util.GetOwnerID(header) {
+			return false
+		}
+	}
+	if r.IP != "" {
+		hit := false
+		ips := strings.Split(r.IP, ",")
+		for _, ip := range ips {
+			if strings.TrimSpace(ip) == strings.TrimSpace(header.Get(common.BKHTTPRequestRealIP)) {
+				hit = true
+				break
+			}
+		}
+		if hit == false {
+			return false
+		}
+	}
+	if r.Method != "" {
+		if util.Normalize(r.Method) != util.Normalize(req.Request.Method) {
+			return false
+		}
+	}
+	if r.Url != "" {
+		match, err := regexp.MatchString(r.Url, req.Request.RequestURI)
+		if err != nil {
+			blog.Errorf("MatchString failed, r.Url:%s, reqURI:%s, err:%s", r.Url, req.Request.RequestURI, err.Error())
+			return false
+		}
+		if !match {
+			return false
+		}
+	}
+	return true
+}
@@ -15,7 +15,6 @@ package service
 
 import (
 	"configcenter/src/ac"
-	"configcenter/src/ac/iam"
 	"configcenter/src/apimachinery"
 	"configcenter/src/apimachinery/discovery"
 	"configcenter/src/common/auth"
@@ -52,6 +51,8 @@ type service struct {
 	limiter    *Limiter
 	// noPermissionRequestTotal is the total number of request without permission
 	noPermissionRequestTotal *prometheus.CounterVec
+	// limitedRequestTotal is the total number of request limited by the limiter rules
+	limitedRequestTotal *prometheus.CounterVec
 }
 
 // SetConfig set config
@@ -63,7 +64,7 @@ func (s *service) SetConfig(engine *backbone.Engine, httpClient HTTPClient, disc
 	s.clientSet = clientSet
 	s.cache = cache
 	s.limiter = limiter
-	s.authorizer = iam.NewAuthorizer(clientSet)
+	s.authorizer = ac.NewAuthorizer(clientSet)
 }
 
 // WebServices TODO
@@ -78,6 +79,14 @@ func (s *service) WebServices() []*restful.WebService {
 	ws.Filter(s.engine.Metric().RestfulMiddleWare)
 	ws.Filter(rdapi.AllGlobalFilter(getErrFun))
 	ws.Filter(rdapi.RequestLogFilter())
+	s.limitedRequestTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cmdb_limited_request_total",
+			Help: "total number of request limited by the api limiter rules.",
+		},
+		[]string{labelLimiterRule, metrics.LabelAppCode, labelLimiterDryRun},
+	)
+	s.engine.Metric().Registry().MustRegister(s.limitedRequestTotal)
 	ws.Filter(s.LimiterFilter())
 	ws.Produces(restful.MIME_JSON)
 	if auth.EnableAuthorize() {
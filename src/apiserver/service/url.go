@@ -52,6 +52,8 @@ func (u URLPath) FilterChain(req *restful.Request) (RequestType, error) {
 		return AdminType, nil
 	case u.WithCloud(req):
 		return CloudType, nil
+	case u.WithAuth(req):
+		return AuthType, nil
 	default:
 		if server, isHit := match.FilterMatch(req); isHit {
 			return RequestType(server), nil
@@ -410,6 +412,28 @@ func (u *URLPath) WithCloud(req *restful.Request) (isHit bool) {
 	return false
 }
 
+var authURLRegexp = regexp.MustCompile(fmt.Sprintf(
+	"^/api/v3/(%s)/auth/(role|group|role_binding|explain)([/?].*)?$", verbs))
+
+// WithAuth transform the local auth management and auth explain url to auth server
+func (u *URLPath) WithAuth(req *restful.Request) (isHit bool) {
+	authRoot := "/ac/v3"
+	from, to := rootPath, authRoot
+
+	switch {
+	case authURLRegexp.MatchString(string(*u)):
+		from, to, isHit = rootPath, authRoot, true
+	default:
+		isHit = false
+	}
+
+	if isHit {
+		u.revise(req, from, to)
+		return true
+	}
+	return false
+}
+
 func (u URLPath) revise(req *restful.Request, from, to string) {
 	req.Request.RequestURI = to + req.Request.RequestURI[len(from):]
 	req.Request.URL.Path = to + req.Request.URL.Path[len(from):]
@@ -0,0 +1,195 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package exporter
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"time"
+
+	"configcenter/src/apimachinery/util"
+	cc "configcenter/src/common/backbone/configcenter"
+)
+
+// Format is the format of the exported audit log records
+type Format string
+
+const (
+	// FormatJSON exports each audit log as a line of json
+	FormatJSON Format = "json"
+	// FormatCEF exports each audit log as an ArcSight common event format record
+	FormatCEF Format = "cef"
+)
+
+// SinkType is the type of the destination that the audit logs are exported to
+type SinkType string
+
+const (
+	// SinkSyslog sends the audit logs as RFC5424 syslog messages over tcp or tls
+	SinkSyslog SinkType = "syslog"
+	// SinkFile appends the audit logs to a local file
+	SinkFile SinkType = "file"
+	// SinkHTTP posts the audit logs to a http endpoint in batches
+	SinkHTTP SinkType = "http"
+)
+
+const (
+	defaultCursorName     = "default"
+	defaultBatchSize      = 200
+	defaultDelay          = time.Minute
+	defaultHTTPTimeout    = 10 * time.Second
+	defaultSyslogFacility = 13
+	defaultSyslogAppName  = "bk-cmdb"
+)
+
+// Config is the config of the audit log exporter
+type Config struct {
+	Enabled bool
+	// Name is the name of the exporter, the export progress is saved with this name
+	Name   string
+	Format Format
+	Sink   SinkType
+	Syslog SyslogConfig
+	File   FileConfig
+	HTTP   HTTPConfig
+	// BatchSize is the max number of the audit logs that are exported in one batch
+	BatchSize int
+	// Delay is the time to wait before the audit log is exported, so that the logs with smaller ids that are
+	// committed later can be exported in order, it defaults to the delay of the audit log chain sealer
+	Delay time.Duration
+}
+
+// SyslogConfig is the config of the syslog sink
+type SyslogConfig struct {
+	// Network is tcp or tls
+	Network  string
+	Address  string
+	Facility int
+	AppName  string
+	TLS      *tls.Config
+}
+
+// FileConfig is the config of the file sink
+type FileConfig struct {
+	Path string
+}
+
+// HTTPConfig is the config of the http sink
+type HTTPConfig struct {
+	URL string
+	// Token is sent as the bearer token in the Authorization header if it is set
+	Token   string
+	Timeout time.Duration
+	TLS     *tls.Config
+}
+
+// ParseConfig parse the audit log exporter config with the prefix, such as auditLog.export
+func ParseConfig(prefix string) (*Config, error) {
+	conf := &Config{
+		Name:      defaultCursorName,
+		Format:    FormatJSON,
+		BatchSize: defaultBatchSize,
+		Delay:     defaultDelay,
+	}
+
+	conf.Enabled, _ = cc.Bool(prefix + ".enabled")
+	if !conf.Enabled {
+		return conf, nil
+	}
+
+	if name, _ := cc.String(prefix + ".name"); name != "" {
+		conf.Name = name
+	}
+	if format, _ := cc.String(prefix + ".format"); format != "" {
+		conf.Format = Format(format)
+	}
+	sink, _ := cc.String(prefix + ".sink")
+	conf.Sink = SinkType(sink)
+	if batchSize, _ := cc.Int(prefix + ".batchSize"); batchSize > 0 {
+		conf.BatchSize = batchSize
+	}
+	if delay, _ := cc.Int(prefix + ".delaySeconds"); delay > 0 {
+		conf.Delay = time.Duration(delay) * time.Second
+	}
+
+	var err error
+	switch conf.Sink {
+	case SinkSyslog:
+		conf.Syslog.Network, _ = cc.String(prefix + ".syslog.network")
+		conf.Syslog.Address, _ = cc.String(prefix + ".syslog.address")
+		conf.Syslog.AppName, _ = cc.String(prefix + ".syslog.appName")
+		if conf.Syslog.AppName == "" {
+			conf.Syslog.AppName = defaultSyslogAppName
+		}
+		conf.Syslog.Facility = defaultSyslogFacility
+		if cc.IsExist(prefix + ".syslog.facility") {
+			conf.Syslog.Facility, _ = cc.Int(prefix + ".syslog.facility")
+		}
+		if conf.Syslog.Network == "tls" {
+			conf.Syslog.TLS, err = util.GetClientTLSConfig(prefix + ".syslog.tls")
+			if err != nil {
+				return nil, fmt.Errorf("parse syslog tls config failed, err: %v", err)
+			}
+		}
+	case SinkFile:
+		conf.File.Path, _ = cc.String(prefix + ".file.path")
+	case SinkHTTP:
+		conf.HTTP.URL, _ = cc.String(prefix + ".http.url")
+		conf.HTTP.Token, _ = cc.String(prefix + ".http.token")
+		conf.HTTP.Timeout = defaultHTTPTimeout
+		if timeout, _ := cc.Int(prefix + ".http.timeoutSeconds"); timeout > 0 {
+			conf.HTTP.Timeout = time.Duration(timeout) * time.Second
+		}
+		conf.HTTP.TLS, err = util.GetClientTLSConfig(prefix + ".http.tls")
+		if err != nil {
+			return nil, fmt.Errorf("parse http tls config failed, err: %v", err)
+		}
+	}
+
+	if err := conf.Validate(); err != nil {
+		return nil, err
+	}
+	return conf, nil
+}
+
+// Validate validates the audit log exporter config
+func (c *Config) Validate() error {
+	if c.Format != FormatJSON && c.Format != FormatCEF {
+		return fmt.Errorf("audit log export format %s is invalid, must be json or cef", c.Format)
+	}
+
+	switch c.Sink {
+	case SinkSyslog:
+		if c.Syslog.Network != "tcp" && c.Syslog.Network != "tls" {
+			return fmt.Errorf("syslog network %s is invalid, must be tcp or tls", c.Syslog.Network)
+		}
+		if c.Syslog.Address == "" {
+			return errors.New("syslog address is not set")
+		}
+		if c.Syslog.Facility < 0 || c.Syslog.Facility > 23 {
+			return fmt.Errorf("syslog facility %d is invalid, must be in 0-23", c.Syslog.Facility)
+		}
+	case SinkFile:
+		if c.File.Path == "" {
+			return errors.New("audit log export file path is not set")
+		}
+	case SinkHTTP:
+		if c.HTTP.URL == "" {
+			return errors.New("audit log export http url is not set")
+		}
+	default:
+		return fmt.Errorf("audit log export sink %s is invalid, must be syslog, file or http", c.Sink)
+	}
+	return nil
+}
@@ -0,0 +1,364 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package exporter exports the audit logs to the external collectors such as syslog server or SIEM in real time.
+// The audit logs in db are used as the durable buffer, the id of the last exported audit log is saved in db after
+// each batch is delivered, so the export continues from where it stopped after restart, and the audit logs are
+// delivered at least once. The audit logs that become visible after the cursor passed their ids are checked for a
+// while and exported out of order, they are counted by the late metric.
+package exporter
+
+import (
+	"context"
+	"time"
+
+	"configcenter/src/common"
+	"configcenter/src/common/blog"
+	"configcenter/src/common/mapstr"
+	"configcenter/src/common/metadata"
+	"configcenter/src/common/util"
+	"configcenter/src/storage/dal"
+)
+
+const (
+	// exportInterval is the interval to check the new audit logs
+	exportInterval = time.Second
+	// maxRetryInterval is the max interval to retry after the export fails
+	maxRetryInterval = time.Minute
+	// skippedCheckDuration is the duration to check whether the ids skipped by the cursor have audit logs, the audit
+	// log ids are generated before the transaction commits, so a log may become visible after the cursor passed it
+	skippedCheckDuration = 10 * time.Minute
+	// maxSkippedIDs is the max number of the skipped ids that are checked
+	maxSkippedIDs = 10000
+)
+
+// exportCursor is the export progress of an exporter
+type exportCursor struct {
+	Name       string    `bson:"name"`
+	LastID     int64     `bson:"last_id"`
+	UpdateTime time.Time `bson:"update_time"`
+}
+
+// Exporter exports the audit logs in the order of their ids, it only runs when isMaster returns true so that
+// there is only one exporter of the same name that is running.
+type Exporter struct {
+	conf      *Config
+	db        dal.RDB
+	isMaster  func() bool
+	formatter formatter
+	sink      sink
+	// skipped is the map of the ids that have no audit log when the cursor passed them to the time they are skipped
+	skipped map[int64]time.Time
+}
+
+// NewExporter new audit log exporter
+func NewExporter(conf *Config, db dal.RDB, isMaster func() bool) (*Exporter, error) {
+	if err := conf.Validate(); err != nil {
+		return nil, err
+	}
+
+	f, err := newFormatter(conf.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := newSink(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	initMetric()
+
+	return &Exporter{
+		conf:      conf,
+		db:        db,
+		isMaster:  isMaster,
+		formatter: f,
+		sink:      s,
+		skipped:   make(map[int64]time.Time),
+	}, nil
+}
+
+// Run export the audit logs until the context is done
+func (e *Exporter) Run(ctx context.Context) {
+	blog.Infof("start audit log exporter %s, sink: %s, format: %s", e.conf.Name, e.conf.Sink, e.conf.Format)
+	defer e.sink.Close()
+
+	retryInterval := exportInterval
+	timer := time.NewTimer(exportInterval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		if !e.isMaster() {
+			// release the connection to the collector, the master will take over the export
+			_ = e.sink.Close()
+			timer.Reset(exportInterval)
+			continue
+		}
+
+		rid := util.GenerateRID()
+		kitCtx := context.WithValue(ctx, common.ContextRequestIDField, rid)
+
+		if err := e.checkSkipped(kitCtx, rid); err != nil {
+			blog.Errorf("check the audit logs skipped by the export cursor failed, err: %v, rid: %s", err, rid)
+		}
+
+		var err error
+		for {
+			var exported int
+			exported, err = e.export(kitCtx, rid)
+			if err != nil || exported < e.conf.BatchSize {
+				break
+			}
+		}
+
+		if err != nil {
+			blog.Errorf("export audit logs failed, retry after %s, err: %v, rid: %s", retryInterval, err, rid)
+			timer.Reset(retryInterval)
+			if retryInterval *= 2; retryInterval > maxRetryInterval {
+				retryInterval = maxRetryInterval
+			}
+			continue
+		}
+
+		retryInterval = exportInterval
+		timer.Reset(exportInterval)
+	}
+}
+
+// export one batch of the audit logs after the cursor, returns the number of the exported logs
+func (e *Exporter) export(ctx context.Context, rid string) (int, error) {
+	cursor, err := e.getCursor(ctx, rid)
+	if err != nil {
+		return 0, err
+	}
+
+	cond := mapstr.MapStr{common.BKFieldID: mapstr.MapStr{common.BKDBGT: cursor.LastID}}
+	logs := make([]metadata.AuditLog, 0)
+	err = e.db.Table(common.BKTableNameAuditLog).Find(cond).Sort(common.BKFieldID).Limit(uint64(e.conf.BatchSize)).
+		All(ctx, &logs)
+	if err != nil {
+		blog.Errorf("get audit logs to export failed, cond: %+v, err: %v, rid: %s", cond, err, rid)
+		return 0, err
+	}
+
+	deadline := time.Now().Add(-e.conf.Delay)
+	records := make([]record, 0, len(logs))
+	lastID := cursor.LastID
+	for idx := range logs {
+		log := &logs[idx]
+		// stop at the first log that is too new, so that the logs are always exported in the order of their ids
+		if log.OperationTime.After(deadline) {
+			break
+		}
+		lastID = log.ID
+
+		data, err := e.formatter.Format(log)
+		if err != nil {
+			// the log can never be formatted, skip it so that it does not block the export
+			blog.Errorf("format audit log %d failed, skip it, err: %v, rid: %s", log.ID, err, rid)
+			continue
+		}
+		records = append(records, record{log: log, data: data})
+	}
+
+	if lastID == cursor.LastID {
+		return 0, nil
+	}
+
+	if len(records) > 0 {
+		if err := e.sink.Write(ctx, records); err != nil {
+			blog.Errorf("write %d audit logs to %s failed, err: %v, rid: %s", len(records), e.conf.Sink, err, rid)
+			return 0, err
+		}
+	}
+
+	fromID := cursor.LastID
+	cursor.LastID = lastID
+	cursor.UpdateTime = time.Now()
+	cursorCond := mapstr.MapStr{"name": e.conf.Name}
+	if err := e.db.Table(common.BKTableNameAuditLogExportCursor).Upsert(ctx, cursorCond, cursor); err != nil {
+		blog.Errorf("save audit log export cursor %+v failed, err: %v, rid: %s", cursor, err, rid)
+		return 0, err
+	}
+
+	now := time.Now()
+	for _, id := range findSkippedIDs(logs, fromID, lastID) {
+		if len(e.skipped) >= maxSkippedIDs {
+			break
+		}
+		e.skipped[id] = now
+	}
+
+	exported := 0
+	for _, log := range logs {
+		if log.ID > lastID {
+			break
+		}
+		exported++
+	}
+	return exported, nil
+}
+
+// findSkippedIDs find the ids in the range of (from, to] that have no audit log in the logs sorted by id
+func findSkippedIDs(logs []metadata.AuditLog, from, to int64) []int64 {
+	skipped := make([]int64, 0)
+	next := from + 1
+	for idx := range logs {
+		if logs[idx].ID > to {
+			break
+		}
+		for ; next < logs[idx].ID && len(skipped) < maxSkippedIDs; next++ {
+			skipped = append(skipped, next)
+		}
+		next = logs[idx].ID + 1
+	}
+	for ; next <= to && len(skipped) < maxSkippedIDs; next++ {
+		skipped = append(skipped, next)
+	}
+	return skipped
+}
+
+// checkSkipped check if the ids skipped by the export cursor have audit logs now, these audit logs are behind the
+// cursor, so they are exported out of order and counted by the late metric. The ids are kept to be checked again if
+// the export fails, so that the audit logs are still delivered at least once.
+func (e *Exporter) checkSkipped(ctx context.Context, rid string) error {
+	if len(e.skipped) == 0 {
+		return nil
+	}
+
+	deadline := time.Now().Add(-skippedCheckDuration)
+	ids := make([]int64, 0, len(e.skipped))
+	for id, skipTime := range e.skipped {
+		if skipTime.Before(deadline) {
+			delete(e.skipped, id)
+			continue
+		}
+		ids = append(ids, id)
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	cond := mapstr.MapStr{common.BKFieldID: mapstr.MapStr{common.BKDBIN: ids}}
+	logs := make([]metadata.AuditLog, 0)
+	err := e.db.Table(common.BKTableNameAuditLog).Find(cond).Sort(common.BKFieldID).All(ctx, &logs)
+	if err != nil {
+		blog.Errorf("get the audit logs skipped by the export cursor failed, err: %v, rid: %s", err, rid)
+		return err
+	}
+
+	if len(logs) == 0 {
+		return nil
+	}
+
+	records := make([]record, 0, len(logs))
+	lateIDs := make([]int64, 0, len(logs))
+	for idx := range logs {
+		log := &logs[idx]
+		data, err := e.formatter.Format(log)
+		if err != nil {
+			blog.Errorf("format audit log %d failed, skip it, err: %v, rid: %s", log.ID, err, rid)
+			delete(e.skipped, log.ID)
+			continue
+		}
+		records = append(records, record{log: log, data: data})
+		lateIDs = append(lateIDs, log.ID)
+	}
+
+	if len(records) == 0 {
+		return nil
+	}
+
+	if err := e.sink.Write(ctx, records); err != nil {
+		blog.Errorf("write %d late audit logs to %s failed, err: %v, rid: %s", len(records), e.conf.Sink, err, rid)
+		return err
+	}
+
+	for _, id := range lateIDs {
+		delete(e.skipped, id)
+	}
+	lateTotal.WithLabelValues(e.conf.Name).Add(float64(len(lateIDs)))
+	blog.Warnf("audit logs %v are behind the cursor of exporter %s, exported them out of order, rid: %s", lateIDs,
+		e.conf.Name, rid)
+	return nil
+}
+
+// getCursor get the export cursor, the export starts from the current last audit log if it is not exist, so that
+// the history audit logs are not flushed to the collector
+func (e *Exporter) getCursor(ctx context.Context, rid string) (*exportCursor, error) {
+	cursors := make([]exportCursor, 0)
+	cond := mapstr.MapStr{"name": e.conf.Name}
+	if err := e.db.Table(common.BKTableNameAuditLogExportCursor).Find(cond).All(ctx, &cursors); err != nil {
+		blog.Errorf("get audit log export cursor failed, cond: %+v, err: %v, rid: %s", cond, err, rid)
+		return nil, err
+	}
+
+	if len(cursors) > 0 {
+		return &cursors[0], nil
+	}
+
+	lastLogs := make([]metadata.AuditLog, 0)
+	err := e.db.Table(common.BKTableNameAuditLog).Find(mapstr.MapStr{}).Fields(common.BKFieldID).
+		Sort("-"+common.BKFieldID).Limit(1).All(ctx, &lastLogs)
+	if err != nil {
+		blog.Errorf("get the last audit log failed, err: %v, rid: %s", err, rid)
+		return nil, err
+	}
+
+	cursor := &exportCursor{Name: e.conf.Name, UpdateTime: time.Now()}
+	if len(lastLogs) > 0 {
+		cursor.LastID = lastLogs[0].ID
+	}
+
+	if err := e.db.Table(common.BKTableNameAuditLogExportCursor).Insert(ctx, cursor); err != nil {
+		blog.Errorf("init audit log export cursor %+v failed, err: %v, rid: %s", cursor, err, rid)
+		return nil, err
+	}
+	blog.Infof("init audit log export cursor %+v, rid: %s", cursor, rid)
+	return cursor, nil
+}
+
+// CountUnexported count the audit logs that match the condition but are not exported by the exporters yet,
+// returns the map of the exporter name to the count of its unexported audit logs, the exporters whose audit logs
+// are all exported are not returned.
+func CountUnexported(ctx context.Context, db dal.RDB, cond mapstr.MapStr, rid string) (map[string]uint64, error) {
+	cursors := make([]exportCursor, 0)
+	if err := db.Table(common.BKTableNameAuditLogExportCursor).Find(mapstr.MapStr{}).All(ctx, &cursors); err != nil {
+		blog.Errorf("get audit log export cursors failed, err: %v, rid: %s", err, rid)
+		return nil, err
+	}
+
+	unexported := make(map[string]uint64)
+	for _, cursor := range cursors {
+		countCond := cond.Clone()
+		countCond[common.BKFieldID] = mapstr.MapStr{common.BKDBGT: cursor.LastID}
+		cnt, err := db.Table(common.BKTableNameAuditLog).Find(countCond).Count(ctx)
+		if err != nil {
+			blog.Errorf("count audit logs unexported by %s failed, cond: %+v, err: %v, rid: %s", cursor.Name,
+				countCond, err, rid)
+			return nil, err
+		}
+
+		if cnt > 0 {
+			unexported[cursor.Name] = cnt
+		}
+	}
+	return unexported, nil
+}
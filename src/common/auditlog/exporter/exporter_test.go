@@ -0,0 +1,313 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package exporter
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"configcenter/src/common"
+	"configcenter/src/common/mapstr"
+	"configcenter/src/common/metadata"
+	"configcenter/src/storage/dal"
+	"configcenter/src/storage/dal/types"
+)
+
+func TestFindSkippedIDs(t *testing.T) {
+	newLogs := func(ids ...int64) []metadata.AuditLog {
+		logs := make([]metadata.AuditLog, len(ids))
+		for idx, id := range ids {
+			logs[idx].ID = id
+		}
+		return logs
+	}
+
+	tests := []struct {
+		name     string
+		logs     []metadata.AuditLog
+		from, to int64
+		want     []int64
+	}{
+		{
+			name: "no skipped ids",
+			logs: newLogs(11, 12, 13),
+			from: 10,
+			to:   13,
+			want: []int64{},
+		},
+		{
+			name: "skipped ids between logs",
+			logs: newLogs(12, 13, 16),
+			from: 10,
+			to:   16,
+			want: []int64{11, 14, 15},
+		},
+		{
+			name: "logs after the end are not exported",
+			logs: newLogs(11, 14, 18),
+			from: 10,
+			to:   14,
+			want: []int64{12, 13},
+		},
+		{
+			name: "no logs",
+			from: 10,
+			to:   10,
+			want: []int64{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := findSkippedIDs(tt.logs, tt.from, tt.to); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findSkippedIDs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if got := findSkippedIDs(nil, 0, maxSkippedIDs*2); len(got) != maxSkippedIDs {
+		t.Errorf("findSkippedIDs() returns %d ids, want at most %d", len(got), maxSkippedIDs)
+	}
+}
+
+// fakeDB is an in-memory db with the audit log and the export cursor tables, it only supports the operations
+// used by the exporter
+type fakeDB struct {
+	dal.DB
+	logs   []metadata.AuditLog
+	cursor *exportCursor
+}
+
+// Table returns the fake table of the collection
+func (db *fakeDB) Table(collection string) types.Table {
+	return &fakeTable{db: db, collection: collection}
+}
+
+type fakeTable struct {
+	types.Table
+	db         *fakeDB
+	collection string
+}
+
+// Find returns the fake find of the filter
+func (t *fakeTable) Find(filter types.Filter, _ ...*types.FindOpts) types.Find {
+	return &fakeFind{table: t, filter: filter.(mapstr.MapStr)}
+}
+
+// Insert inserts the export cursor
+func (t *fakeTable) Insert(_ context.Context, doc interface{}) error {
+	cursor := *doc.(*exportCursor)
+	t.db.cursor = &cursor
+	return nil
+}
+
+// Upsert saves the export cursor
+func (t *fakeTable) Upsert(ctx context.Context, _ types.Filter, doc interface{}) error {
+	return t.Insert(ctx, doc)
+}
+
+type fakeFind struct {
+	types.Find
+	table  *fakeTable
+	filter mapstr.MapStr
+	sort   string
+	limit  uint64
+}
+
+// Fields is ignored by the fake find
+func (f *fakeFind) Fields(_ ...string) types.Find {
+	return f
+}
+
+// Sort sets the sort of the audit logs, only the id field is supported
+func (f *fakeFind) Sort(sort string) types.Find {
+	f.sort = sort
+	return f
+}
+
+// Limit sets the limit of the audit logs
+func (f *fakeFind) Limit(limit uint64) types.Find {
+	f.limit = limit
+	return f
+}
+
+// All finds the audit logs by the id filter or the export cursor
+func (f *fakeFind) All(_ context.Context, result interface{}) error {
+	if f.table.collection == common.BKTableNameAuditLogExportCursor {
+		cursors := result.(*[]exportCursor)
+		if f.table.db.cursor != nil {
+			*cursors = append(*cursors, *f.table.db.cursor)
+		}
+		return nil
+	}
+
+	logs := make([]metadata.AuditLog, 0)
+	idCond, _ := f.filter[common.BKFieldID].(mapstr.MapStr)
+	for _, log := range f.table.db.logs {
+		if gt, ok := idCond[common.BKDBGT].(int64); ok && log.ID <= gt {
+			continue
+		}
+		if in, ok := idCond[common.BKDBIN].([]int64); ok && !containsID(in, log.ID) {
+			continue
+		}
+		logs = append(logs, log)
+	}
+
+	sort.Slice(logs, func(i, j int) bool {
+		if f.sort == "-"+common.BKFieldID {
+			return logs[i].ID > logs[j].ID
+		}
+		return logs[i].ID < logs[j].ID
+	})
+	if f.limit > 0 && uint64(len(logs)) > f.limit {
+		logs = logs[:f.limit]
+	}
+
+	*result.(*[]metadata.AuditLog) = logs
+	return nil
+}
+
+func containsID(ids []int64, id int64) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+	return false
+}
+
+// fakeSink records the ids of the written audit logs, it fails when err is set
+type fakeSink struct {
+	ids []int64
+	err error
+}
+
+// Write records the ids of the audit logs
+func (s *fakeSink) Write(_ context.Context, records []record) error {
+	if s.err != nil {
+		return s.err
+	}
+	for _, r := range records {
+		s.ids = append(s.ids, r.log.ID)
+	}
+	return nil
+}
+
+// Close closes the fake sink
+func (s *fakeSink) Close() error {
+	return nil
+}
+
+func newTestExporter(db *fakeDB, s sink) *Exporter {
+	initMetric()
+	return &Exporter{
+		conf:      &Config{Name: defaultCursorName, BatchSize: 3, Delay: time.Minute},
+		db:        db,
+		isMaster:  func() bool { return true },
+		formatter: new(jsonFormatter),
+		sink:      s,
+		skipped:   make(map[int64]time.Time),
+	}
+}
+
+func newTestLog(id int64, operationTime time.Time) metadata.AuditLog {
+	return metadata.AuditLog{ID: id, OperationTime: metadata.Time{Time: operationTime}}
+}
+
+func TestExporterExport(t *testing.T) {
+	ctx := context.Background()
+	old := time.Now().Add(-time.Hour)
+	db := &fakeDB{logs: []metadata.AuditLog{newTestLog(1, old)}}
+	s := new(fakeSink)
+	e := newTestExporter(db, s)
+
+	// the export starts from the last audit log when the cursor is not exist
+	exported, err := e.export(ctx, "test")
+	if err != nil || exported != 0 || db.cursor == nil || db.cursor.LastID != 1 {
+		t.Fatalf("init export cursor failed, exported: %d, cursor: %+v, err: %v", exported, db.cursor, err)
+	}
+
+	// log 3 is not visible yet, log 6 is too new to be exported, log 5 is not in this batch
+	db.logs = append(db.logs, newTestLog(2, old), newTestLog(4, old), newTestLog(5, old),
+		newTestLog(6, time.Now()))
+	exported, err = e.export(ctx, "test")
+	if err != nil {
+		t.Fatalf("export audit logs failed, err: %v", err)
+	}
+	if exported != 3 || db.cursor.LastID != 5 || !reflect.DeepEqual(s.ids, []int64{2, 4, 5}) {
+		t.Errorf("exported %d logs %v, cursor: %d, want 3 logs [2 4 5] and cursor 5", exported, s.ids,
+			db.cursor.LastID)
+	}
+	if _, ok := e.skipped[3]; !ok || len(e.skipped) != 1 {
+		t.Errorf("skipped ids are %v, want [3]", e.skipped)
+	}
+
+	// the new log is not exported before the delay
+	exported, err = e.export(ctx, "test")
+	if err != nil || exported != 0 || db.cursor.LastID != 5 {
+		t.Errorf("export the new audit log before the delay, exported: %d, cursor: %d, err: %v", exported,
+			db.cursor.LastID, err)
+	}
+
+	// the cursor is not moved when the sink fails
+	db.logs[len(db.logs)-1].OperationTime = metadata.Time{Time: old}
+	s.err = errors.New("sink failed")
+	if _, err = e.export(ctx, "test"); err == nil || db.cursor.LastID != 5 {
+		t.Errorf("export should fail without moving the cursor, cursor: %d, err: %v", db.cursor.LastID, err)
+	}
+
+	s.err = nil
+	exported, err = e.export(ctx, "test")
+	if err != nil || exported != 1 || db.cursor.LastID != 6 || !reflect.DeepEqual(s.ids, []int64{2, 4, 5, 6}) {
+		t.Errorf("exported %d logs %v, cursor: %d, err: %v, want log 6 to be exported", exported, s.ids,
+			db.cursor.LastID, err)
+	}
+}
+
+func TestExporterCheckSkipped(t *testing.T) {
+	ctx := context.Background()
+	old := time.Now().Add(-time.Hour)
+	db := &fakeDB{logs: []metadata.AuditLog{newTestLog(1, old), newTestLog(3, old)}}
+	s := new(fakeSink)
+	e := newTestExporter(db, s)
+	e.skipped = map[int64]time.Time{
+		2: time.Now(),
+		3: time.Now(),
+		// the skipped id that is expired is not checked any more
+		4: time.Now().Add(-2 * skippedCheckDuration),
+	}
+
+	// the late log is kept to be checked again when the sink fails
+	s.err = errors.New("sink failed")
+	if err := e.checkSkipped(ctx, "test"); err == nil {
+		t.Errorf("check skipped should fail when the sink fails")
+	}
+	if _, ok := e.skipped[3]; !ok || len(e.skipped) != 2 {
+		t.Errorf("skipped ids are %v after the sink fails, want [2 3]", e.skipped)
+	}
+
+	s.err = nil
+	if err := e.checkSkipped(ctx, "test"); err != nil {
+		t.Fatalf("check skipped failed, err: %v", err)
+	}
+	if !reflect.DeepEqual(s.ids, []int64{3}) {
+		t.Errorf("late audit logs %v are exported, want [3]", s.ids)
+	}
+	if _, ok := e.skipped[2]; !ok || len(e.skipped) != 1 {
+		t.Errorf("skipped ids are %v, want [2]", e.skipped)
+	}
+}
@@ -0,0 +1,115 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package exporter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"configcenter/src/common/json"
+	"configcenter/src/common/metadata"
+	"configcenter/src/common/version"
+)
+
+// formatter converts the audit log to an exported record
+type formatter interface {
+	Format(log *metadata.AuditLog) ([]byte, error)
+}
+
+func newFormatter(format Format) (formatter, error) {
+	switch format {
+	case FormatJSON:
+		return new(jsonFormatter), nil
+	case FormatCEF:
+		return new(cefFormatter), nil
+	default:
+		return nil, fmt.Errorf("audit log export format %s is not supported", format)
+	}
+}
+
+// jsonFormatter formats the audit log with its resolved operation detail as a json object
+type jsonFormatter struct{}
+
+// Format formats the audit log as json
+func (f *jsonFormatter) Format(log *metadata.AuditLog) ([]byte, error) {
+	return json.Marshal(log)
+}
+
+const (
+	cefVendor  = "Tencent"
+	cefProduct = "BlueKing CMDB"
+)
+
+var (
+	cefHeaderEscaper    = strings.NewReplacer(`\`, `\\`, `|`, `\|`, "\r", " ", "\n", " ")
+	cefExtensionEscaper = strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\r", `\r`, "\n", `\n`)
+)
+
+// cefFormatter formats the audit log as an ArcSight common event format record, the operation detail is put in
+// the msg extension as json
+type cefFormatter struct{}
+
+// Format formats the audit log as a cef record
+func (f *cefFormatter) Format(log *metadata.AuditLog) ([]byte, error) {
+	detail, err := json.Marshal(log.OperationDetail)
+	if err != nil {
+		return nil, err
+	}
+
+	severity := 3
+	if log.Action == metadata.AuditDelete {
+		severity = 6
+	}
+
+	var b strings.Builder
+	b.WriteString("CEF:0|")
+	for _, field := range []string{cefVendor, cefProduct, version.CCVersion,
+		fmt.Sprintf("%s:%s", log.ResourceType, log.Action),
+		fmt.Sprintf("%s %s %s", log.AuditType, log.Action, log.ResourceType)} {
+		b.WriteString(cefHeaderEscaper.Replace(field))
+		b.WriteByte('|')
+	}
+	b.WriteString(strconv.Itoa(severity))
+	b.WriteByte('|')
+
+	extensions := [][2]string{
+		{"rt", strconv.FormatInt(log.OperationTime.UnixNano()/1e6, 10)},
+		{"externalId", strconv.FormatInt(log.ID, 10)},
+		{"suser", log.User},
+		{"act", string(log.Action)},
+		{"cs1Label", "bk_supplier_account"},
+		{"cs1", log.SupplierAccount},
+		{"cs2Label", "resource_id"},
+		{"cs2", fmt.Sprint(log.ResourceID)},
+		{"cs3Label", "resource_name"},
+		{"cs3", log.ResourceName},
+		{"cs4Label", "operate_from"},
+		{"cs4", string(log.OperateFrom)},
+		{"cs5Label", "rid"},
+		{"cs5", log.RequestID},
+		{"cn1Label", "bk_biz_id"},
+		{"cn1", strconv.FormatInt(log.BusinessID, 10)},
+		{"requestClientApplication", log.AppCode},
+		{"msg", string(detail)},
+	}
+	for idx, ext := range extensions {
+		if idx > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(ext[0])
+		b.WriteByte('=')
+		b.WriteString(cefExtensionEscaper.Replace(ext[1]))
+	}
+	return []byte(b.String()), nil
+}
@@ -0,0 +1,72 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package exporter
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"configcenter/src/common/metadata"
+)
+
+func newTestAuditLog() *metadata.AuditLog {
+	return &metadata.AuditLog{
+		ID:              10,
+		AuditType:       metadata.HostType,
+		SupplierAccount: "0",
+		User:            "admin",
+		ResourceType:    metadata.HostRes,
+		Action:          metadata.AuditDelete,
+		OperateFrom:     metadata.FromUser,
+		OperationTime:   metadata.Time{Time: time.Date(2022, 10, 23, 10, 30, 0, 0, time.UTC)},
+		ResourceID:      1,
+		ResourceName:    "127.0.0.1|a=b",
+		OperationDetail: &metadata.BasicOpDetail{Details: &metadata.BasicContent{
+			PreData: map[string]interface{}{"bk_host_innerip": "127.0.0.1"},
+		}},
+	}
+}
+
+func TestCEFFormatter(t *testing.T) {
+	data, err := new(cefFormatter).Format(newTestAuditLog())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	record := string(data)
+	if !strings.HasPrefix(record, "CEF:0|Tencent|BlueKing CMDB|") {
+		t.Errorf("invalid cef header: %s", record)
+	}
+
+	for _, expected := range []string{"|host:delete|", "|6|", "externalId=10 ", "suser=admin ",
+		`cs3=127.0.0.1|a\=b `, `msg={"details":{"pre_data":{"bk_host_innerip":"127.0.0.1"}`} {
+		if !strings.Contains(record, expected) {
+			t.Errorf("cef record %s does not contain %s", record, expected)
+		}
+	}
+}
+
+func TestSyslogMessage(t *testing.T) {
+	s := &syslogSink{
+		conf:     SyslogConfig{Facility: 13, AppName: "bk-cmdb"},
+		hostname: "cmdb",
+		procID:   "100",
+	}
+
+	msg := string(s.message(record{log: newTestAuditLog(), data: []byte(`{"id":10}`)}))
+	expected := `<109>1 2022-10-23T10:30:00.000Z cmdb bk-cmdb 100 AUDIT - {"id":10}`
+	if msg != expected {
+		t.Errorf("expected syslog message: %s, actual: %s", expected, msg)
+	}
+}
@@ -0,0 +1,41 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package exporter
+
+import (
+	"sync"
+
+	"configcenter/src/common/metrics"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// lateTotal is the total count of the audit logs that become visible behind the export cursor after the
+	// cursor has passed them, these logs are exported out of order
+	lateTotal  *prometheus.CounterVec
+	metricOnce sync.Once
+)
+
+// initMetric initialize the exporter metrics, it is safe to be called multiple times
+func initMetric() {
+	metricOnce.Do(func() {
+		lateTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: "audit_log_exporter",
+			Name:      "late_total",
+			Help:      "the total count of the audit logs that are behind the export cursor and are exported out of order",
+		}, []string{"exporter"})
+		metrics.Register().MustRegister(lateTotal)
+	})
+}
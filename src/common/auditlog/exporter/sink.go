@@ -0,0 +1,148 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+
+	"configcenter/src/common/metadata"
+)
+
+// record is an exported audit log record
+type record struct {
+	log  *metadata.AuditLog
+	data []byte
+}
+
+// sink is the destination that the audit log records are exported to, the records must be all delivered or
+// an error is returned so that they are exported again
+type sink interface {
+	Write(ctx context.Context, records []record) error
+	Close() error
+}
+
+func newSink(conf *Config) (sink, error) {
+	switch conf.Sink {
+	case SinkSyslog:
+		return newSyslogSink(conf.Syslog), nil
+	case SinkFile:
+		return &fileSink{path: conf.File.Path}, nil
+	case SinkHTTP:
+		return newHTTPSink(conf.HTTP, conf.Format), nil
+	default:
+		return nil, fmt.Errorf("audit log export sink %s is not supported", conf.Sink)
+	}
+}
+
+// fileSink appends the records to the file as lines, the file is opened for each batch so that it can be rotated
+type fileSink struct {
+	path string
+}
+
+// Write appends the records to the file and syncs it to the disk
+func (f *fileSink) Write(_ context.Context, records []record) error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		return err
+	}
+
+	buf := new(bytes.Buffer)
+	for _, r := range records {
+		buf.Write(r.data)
+		buf.WriteByte('\n')
+	}
+
+	if _, err := file.Write(buf.Bytes()); err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	if err := file.Sync(); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Close closes the file sink
+func (f *fileSink) Close() error {
+	return nil
+}
+
+// httpSink posts the records to the http endpoint in batches, one record per line
+type httpSink struct {
+	url         string
+	token       string
+	contentType string
+	client      *http.Client
+}
+
+func newHTTPSink(conf HTTPConfig, format Format) *httpSink {
+	contentType := "application/x-ndjson"
+	if format == FormatCEF {
+		contentType = "text/plain"
+	}
+
+	return &httpSink{
+		url:         conf.URL,
+		token:       conf.Token,
+		contentType: contentType,
+		client: &http.Client{
+			Timeout:   conf.Timeout,
+			Transport: &http.Transport{TLSClientConfig: conf.TLS},
+		},
+	}
+}
+
+// Write posts the records to the http endpoint
+func (h *httpSink) Write(ctx context.Context, records []record) error {
+	buf := new(bytes.Buffer)
+	for _, r := range records {
+		buf.Write(r.data)
+		buf.WriteByte('\n')
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, buf)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", h.contentType)
+	if h.token != "" {
+		req.Header.Set("Authorization", "Bearer "+h.token)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("post audit logs to %s failed, status: %d, body: %s", h.url, resp.StatusCode, body)
+	}
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	return nil
+}
+
+// Close closes the idle connections of the http sink
+func (h *httpSink) Close() error {
+	h.client.CloseIdleConnections()
+	return nil
+}
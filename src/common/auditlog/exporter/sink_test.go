@@ -0,0 +1,189 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package exporter
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"configcenter/src/common/metadata"
+)
+
+// newTestRecords format the audit logs of the ids as json records
+func newTestRecords(t *testing.T, ids ...int64) []record {
+	records := make([]record, len(ids))
+	for idx, id := range ids {
+		log := &metadata.AuditLog{ID: id, Action: metadata.AuditUpdate,
+			OperationTime: metadata.Time{Time: time.Unix(id, 0)}}
+		data, err := new(jsonFormatter).Format(log)
+		if err != nil {
+			t.Fatalf("format audit log %d failed, err: %v", id, err)
+		}
+		records[idx] = record{log: log, data: data}
+	}
+	return records
+}
+
+func TestFileSink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit_export")
+	if err != nil {
+		t.Fatalf("create temp dir failed, err: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := &fileSink{path: filepath.Join(dir, "audit.log")}
+	defer s.Close()
+
+	ctx := context.Background()
+	if err := s.Write(ctx, newTestRecords(t, 1, 2)); err != nil {
+		t.Fatalf("write the first batch failed, err: %v", err)
+	}
+	if err := s.Write(ctx, newTestRecords(t, 3)); err != nil {
+		t.Fatalf("write the second batch failed, err: %v", err)
+	}
+
+	file, err := os.Open(s.path)
+	if err != nil {
+		t.Fatalf("open exported file failed, err: %v", err)
+	}
+	defer file.Close()
+
+	ids := make([]int64, 0)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		log := new(metadata.AuditLog)
+		if err := json.Unmarshal(scanner.Bytes(), log); err != nil {
+			t.Fatalf("unmarshal exported line %s failed, err: %v", scanner.Text(), err)
+		}
+		if log.Action != metadata.AuditUpdate || log.OperationTime.Unix() != log.ID {
+			t.Errorf("exported audit log %+v is not the same as the written one", log)
+		}
+		ids = append(ids, log.ID)
+	}
+
+	if len(ids) != 3 || ids[0] != 1 || ids[1] != 2 || ids[2] != 3 {
+		t.Errorf("exported audit log ids are %v, want [1 2 3]", ids)
+	}
+}
+
+func TestHTTPSink(t *testing.T) {
+	var body, contentType, auth string
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		body, contentType, auth = string(data), r.Header.Get("Content-Type"), r.Header.Get("Authorization")
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	s := newHTTPSink(HTTPConfig{URL: server.URL, Token: "token", Timeout: time.Second}, FormatJSON)
+	defer s.Close()
+
+	records := newTestRecords(t, 1, 2)
+	if err := s.Write(context.Background(), records); err != nil {
+		t.Fatalf("write records failed, err: %v", err)
+	}
+
+	if want := string(records[0].data) + "\n" + string(records[1].data) + "\n"; body != want {
+		t.Errorf("posted body is %s, want %s", body, want)
+	}
+	if contentType != "application/x-ndjson" {
+		t.Errorf("posted content type is %s, want application/x-ndjson", contentType)
+	}
+	if auth != "Bearer token" {
+		t.Errorf("posted authorization is %s, want Bearer token", auth)
+	}
+
+	status = http.StatusServiceUnavailable
+	if err := s.Write(context.Background(), records); err == nil {
+		t.Errorf("write records should fail when the collector responds %d", status)
+	}
+}
+
+func TestSyslogSink(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed, err: %v", err)
+	}
+	defer listener.Close()
+
+	received := make(chan []string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		// read the octet counting framed messages
+		messages := make([]string, 0)
+		reader := bufio.NewReader(conn)
+		for len(messages) < 2 {
+			length, err := reader.ReadString(' ')
+			if err != nil {
+				break
+			}
+			size, err := strconv.Atoi(strings.TrimSpace(length))
+			if err != nil {
+				break
+			}
+			msg := make([]byte, size)
+			if _, err := io.ReadFull(reader, msg); err != nil {
+				break
+			}
+			messages = append(messages, string(msg))
+		}
+		received <- messages
+	}()
+
+	s := newSyslogSink(SyslogConfig{Network: "tcp", Address: listener.Addr().String(), Facility: 13,
+		AppName: "bk-cmdb"})
+	defer s.Close()
+
+	records := newTestRecords(t, 1, 2)
+	if err := s.Write(context.Background(), records); err != nil {
+		t.Fatalf("write records failed, err: %v", err)
+	}
+
+	var messages []string
+	select {
+	case messages = <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("receive syslog messages timeout")
+	}
+
+	if len(messages) != len(records) {
+		t.Fatalf("received %d syslog messages, want %d", len(messages), len(records))
+	}
+	for idx, msg := range messages {
+		// facility 13 with the info severity
+		if !strings.HasPrefix(msg, "<110>1 ") {
+			t.Errorf("syslog message %s has wrong header", msg)
+		}
+		if !strings.HasSuffix(msg, " "+syslogMsgID+" - "+string(records[idx].data)) {
+			t.Errorf("syslog message %s does not end with the record %s", msg, records[idx].data)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"configcenter/src/common/metadata"
+)
+
+const (
+	syslogDialTimeout  = 10 * time.Second
+	syslogWriteTimeout = 30 * time.Second
+	// syslogTimeFormat is the RFC5424 timestamp format with milliseconds
+	syslogTimeFormat = "2006-01-02T15:04:05.000Z07:00"
+	// syslogMsgID is the MSGID of the audit log syslog messages
+	syslogMsgID = "AUDIT"
+	// severities of the syslog messages
+	syslogSeverityNotice = 5
+	syslogSeverityInfo   = 6
+)
+
+// syslogSink sends the records as RFC5424 syslog messages over tcp or tls with the octet counting framing
+// defined by RFC6587, the connection is re-established after it fails.
+type syslogSink struct {
+	conf     SyslogConfig
+	hostname string
+	procID   string
+	conn     net.Conn
+}
+
+func newSyslogSink(conf SyslogConfig) *syslogSink {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+
+	return &syslogSink{
+		conf:     conf,
+		hostname: hostname,
+		procID:   strconv.Itoa(os.Getpid()),
+	}
+}
+
+// Write sends the records to the syslog server
+func (s *syslogSink) Write(ctx context.Context, records []record) error {
+	if s.conn == nil {
+		conn, err := s.dial(ctx)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+
+	buf := new(bytes.Buffer)
+	for _, r := range records {
+		msg := s.message(r)
+		buf.WriteString(strconv.Itoa(len(msg)))
+		buf.WriteByte(' ')
+		buf.Write(msg)
+	}
+
+	if err := s.conn.SetWriteDeadline(time.Now().Add(syslogWriteTimeout)); err != nil {
+		s.reset()
+		return err
+	}
+
+	if _, err := s.conn.Write(buf.Bytes()); err != nil {
+		s.reset()
+		return err
+	}
+	return nil
+}
+
+// message generate the RFC5424 syslog message of the record
+func (s *syslogSink) message(r record) []byte {
+	severity := syslogSeverityInfo
+	if r.log.Action == metadata.AuditDelete {
+		severity = syslogSeverityNotice
+	}
+
+	buf := new(bytes.Buffer)
+	_, _ = fmt.Fprintf(buf, "<%d>1 %s %s %s %s %s - ", s.conf.Facility*8+severity,
+		r.log.OperationTime.Format(syslogTimeFormat), s.hostname, s.conf.AppName, s.procID, syslogMsgID)
+	buf.Write(r.data)
+	return buf.Bytes()
+}
+
+func (s *syslogSink) dial(ctx context.Context) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: syslogDialTimeout}
+	if s.conf.Network == "tls" {
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: s.conf.TLS}
+		return tlsDialer.DialContext(ctx, "tcp", s.conf.Address)
+	}
+	return dialer.DialContext(ctx, "tcp", s.conf.Address)
+}
+
+func (s *syslogSink) reset() {
+	if s.conn != nil {
+		_ = s.conn.Close()
+		s.conn = nil
+	}
+}
+
+// Close closes the connection to the syslog server
+func (s *syslogSink) Close() error {
+	s.reset()
+	return nil
+}
@@ -0,0 +1,76 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auditlog
+
+import (
+	"configcenter/src/apimachinery/coreservice"
+	"configcenter/src/common"
+	"configcenter/src/common/metadata"
+	"configcenter/src/common/util"
+)
+
+// HostApplyAuditLog is audit log handler for host attributes applied by host apply rules.
+type HostApplyAuditLog struct {
+	audit
+}
+
+// NewHostApplyAuditLog creates a new HostApplyAuditLog object.
+func NewHostApplyAuditLog(clientSet coreservice.CoreServiceClientInterface) *HostApplyAuditLog {
+	return &HostApplyAuditLog{audit: audit{clientSet: clientSet}}
+}
+
+// GenerateAuditLog generate audit logs of the executed host apply plans, the conflicts resolved by the conflict
+// policies are recorded with the source rules, so that where the applied value comes from is traceable.
+func (h *HostApplyAuditLog) GenerateAuditLog(param *generateAuditCommonParameter, bizID int64,
+	plans []metadata.OneHostApplyPlan) []metadata.AuditLog {
+
+	auditLogs := make([]metadata.AuditLog, 0)
+	for _, plan := range plans {
+		if len(plan.UpdateFields) == 0 {
+			continue
+		}
+
+		updateData := plan.GetUpdateData()
+		resolvers := make([]metadata.HostApplyConflictResolver, 0)
+		for _, field := range plan.ConflictFields {
+			if field.UnresolvedConflictExist || field.Strategy == "" {
+				continue
+			}
+			resolvers = append(resolvers, metadata.HostApplyConflictResolver{
+				HostID: plan.HostID,
+				HostAttribute: metadata.HostAttribute{
+					AttributeID:   field.AttributeID,
+					PropertyValue: updateData[field.PropertyID],
+				},
+				Strategy:      field.Strategy,
+				SourceRuleIDs: field.SourceRuleIDs,
+			})
+		}
+
+		auditLogs = append(auditLogs, metadata.AuditLog{
+			AuditType:    metadata.HostType,
+			ResourceType: metadata.HostApplyRes,
+			Action:       param.action,
+			BusinessID:   bizID,
+			ResourceID:   plan.HostID,
+			ResourceName: util.GetStrByInterface(plan.ExpectHost[common.BKHostInnerIPField]),
+			OperateFrom:  param.operateFrom,
+			OperationDetail: &metadata.HostApplyOpDetail{
+				ModuleIDs:         plan.ModuleIDs,
+				UpdateFields:      updateData,
+				ConflictResolvers: resolvers,
+			},
+		})
+	}
+	return auditLogs
+}
@@ -25,7 +25,7 @@ import (
 	"configcenter/src/apimachinery/util"
 	"configcenter/src/common"
 	cc "configcenter/src/common/backbone/configcenter"
-	"configcenter/src/common/backbone/service_mange/zk"
+	"configcenter/src/common/backbone/service_mange/regdiscv"
 	"configcenter/src/common/blog"
 	crd "configcenter/src/common/confregdiscover"
 	"configcenter/src/common/errors"
@@ -58,10 +58,13 @@ type BackboneParameter struct {
 	SrvInfo *types.ServerInfo
 }
 
-func newSvcManagerClient(ctx context.Context, svcManagerAddr string) (*zk.ZkClient, error) {
-	var err error
+func newSvcManagerClient(ctx context.Context, svcManagerAddr string) (*regdiscv.Client, error) {
+	client, err := regdiscv.NewClient(svcManagerAddr, 40*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
 	for retry := 0; retry < maxRetry; retry++ {
-		client := zk.NewZkClient(svcManagerAddr, 40*time.Second)
 		if err = client.Start(); err != nil {
 			blog.Errorf("connect regdiscv [%s] failed: %v", svcManagerAddr, err)
 			time.Sleep(time.Second * 2)
@@ -176,10 +179,9 @@ func NewBackbone(ctx context.Context, input *BackboneParameter) (*Engine, error)
 	}
 
 	// add default configcenter
-	zkdisc := crd.NewZkRegDiscover(client)
 	configCenter := &cc.ConfigCenter{
 		Type:               common.BKDefaultConfigCenter,
-		ConfigCenterDetail: zkdisc,
+		ConfigCenterDetail: crd.NewConfRegDiscvIf(client),
 	}
 	cc.AddConfigCenter(configCenter)
 
@@ -191,9 +193,12 @@ func NewBackbone(ctx context.Context, input *BackboneParameter) (*Engine, error)
 		return nil, fmt.Errorf("new config center failed, err: %v", err)
 	}
 
-	err = handleNotice(ctx, client.Client(), input.SrvInfo.Instance())
-	if err != nil {
-		return nil, fmt.Errorf("handle notice failed, err: %v", err)
+	// the notice is only supported by zookeeper for now
+	if client.Type() == regdiscv.Zookeeper {
+		err = handleNotice(ctx, client.Zk().Client(), input.SrvInfo.Instance())
+		if err != nil {
+			return nil, fmt.Errorf("handle notice failed, err: %v", err)
+		}
 	}
 
 	if err := monitor.InitMonitor(); err != nil {
@@ -275,7 +280,7 @@ type Engine struct {
 	CoreAPI            apimachinery.ClientSetInterface
 	apiMachineryConfig *util.APIMachineryConfig
 
-	client                 *zk.ZkClient
+	client                 *regdiscv.Client
 	ServiceManageInterface discovery.ServiceManageInterface
 	SvcDisc                ServiceRegisterInterface
 	discovery              discovery.DiscoveryInterface
@@ -302,8 +307,8 @@ func (e *Engine) ApiMachineryConfig() *util.APIMachineryConfig {
 	return e.apiMachineryConfig
 }
 
-// ServiceManageClient TODO
-func (e *Engine) ServiceManageClient() *zk.ZkClient {
+// ServiceManageClient returns the client of the register and discover backend
+func (e *Engine) ServiceManageClient() *regdiscv.Client {
 	return e.client
 }
 
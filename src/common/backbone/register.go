@@ -15,7 +15,7 @@ package backbone
 import (
 	"encoding/json"
 
-	"configcenter/src/common/backbone/service_mange/zk"
+	"configcenter/src/common/backbone/service_mange/regdiscv"
 	"configcenter/src/common/registerdiscover"
 	"configcenter/src/common/types"
 	"configcenter/src/framework/core/errors"
@@ -34,9 +34,9 @@ type ServiceRegisterInterface interface {
 }
 
 // NewServiceRegister TODO
-func NewServiceRegister(client *zk.ZkClient) (ServiceRegisterInterface, error) {
+func NewServiceRegister(client *regdiscv.Client) (ServiceRegisterInterface, error) {
 	s := new(serviceRegister)
-	s.client = registerdiscover.NewRegDiscoverEx(client)
+	s.client = registerdiscover.NewRegDiscoverWithClient(client)
 	return s, nil
 }
 
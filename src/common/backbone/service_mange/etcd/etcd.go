@@ -0,0 +1,169 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package etcd do service register and discover by etcd v3
+package etcd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// ErrNoNode the key is not exist in etcd
+var ErrNoNode = errors.New("etcd: key does not exist")
+
+// EtcdClient do service register and discover by etcd
+type EtcdClient struct {
+	endpoints      []string
+	etcdCli        *clientv3.Client
+	cancel         context.CancelFunc
+	rootCxt        context.Context
+	sessionTimeOut time.Duration
+}
+
+// NewEtcdClient create a object of EtcdClient
+func NewEtcdClient(etcdAddress string, timeOut time.Duration) *EtcdClient {
+	return &EtcdClient{
+		endpoints:      strings.Split(etcdAddress, ","),
+		sessionTimeOut: timeOut,
+	}
+}
+
+// Start used to run register and discover server
+func (e *EtcdClient) Start() error {
+	cli, err := clientv3.New(clientv3.Config{
+		Endpoints:   e.endpoints,
+		DialTimeout: 5 * time.Second,
+	})
+	if err != nil {
+		return fmt.Errorf("fail to connect etcd, err: %+v", err)
+	}
+	e.etcdCli = cli
+
+	// create root context
+	e.rootCxt, e.cancel = context.WithCancel(context.Background())
+
+	return nil
+}
+
+// Stop used to stop register and discover server
+func (e *EtcdClient) Stop() error {
+	e.cancel()
+	return e.etcdCli.Close()
+}
+
+// Ping to ping server
+func (e *EtcdClient) Ping() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for _, endpoint := range e.endpoints {
+		if _, err := e.etcdCli.Status(ctx, endpoint); err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("all the etcd endpoints %v are unreachable", e.endpoints)
+}
+
+// Client return etcd client
+func (e *EtcdClient) Client() *clientv3.Client {
+	return e.etcdCli
+}
+
+// SessionTimeOut client session time out, it is used as the ttl of the register lease
+func (e *EtcdClient) SessionTimeOut() time.Duration {
+	return e.sessionTimeOut
+}
+
+// WithCancel context with cancel
+func (e *EtcdClient) WithCancel() (context.Context, context.CancelFunc) {
+	return context.WithCancel(e.rootCxt)
+}
+
+// Get the value of the key, returns ErrNoNode if the key is not exist
+func (e *EtcdClient) Get(key string) (string, error) {
+	resp, err := e.etcdCli.Get(e.rootCxt, key)
+	if err != nil {
+		return "", err
+	}
+
+	if len(resp.Kvs) == 0 {
+		return "", ErrNoNode
+	}
+	return string(resp.Kvs[0].Value), nil
+}
+
+// GetChildren get the direct children name of the path, sorted by their create revision,
+// so that the first child is the earliest created one just like zookeeper sequence nodes.
+func (e *EtcdClient) GetChildren(path string) ([]string, error) {
+	resp, err := e.etcdCli.Get(e.rootCxt, childPrefix(path), clientv3.WithPrefix(), clientv3.WithKeysOnly(),
+		clientv3.WithSort(clientv3.SortByCreateRevision, clientv3.SortAscend))
+	if err != nil {
+		return nil, err
+	}
+
+	children := make([]string, 0)
+	exists := make(map[string]struct{})
+	for _, kv := range resp.Kvs {
+		child := strings.SplitN(strings.TrimPrefix(string(kv.Key), childPrefix(path)), "/", 2)[0]
+		if _, ok := exists[child]; ok || child == "" {
+			continue
+		}
+		exists[child] = struct{}{}
+		children = append(children, child)
+	}
+
+	return children, nil
+}
+
+// GetChildrenData get the value of the direct children of the path, sorted by their create revision
+func (e *EtcdClient) GetChildrenData(path string) ([]string, []string, error) {
+	resp, err := e.etcdCli.Get(e.rootCxt, childPrefix(path), clientv3.WithPrefix(),
+		clientv3.WithSort(clientv3.SortByCreateRevision, clientv3.SortAscend))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	nodes := make([]string, 0)
+	values := make([]string, 0)
+	for _, kv := range resp.Kvs {
+		child := strings.TrimPrefix(string(kv.Key), childPrefix(path))
+		if strings.Contains(child, "/") {
+			continue
+		}
+		nodes = append(nodes, child)
+		values = append(values, string(kv.Value))
+	}
+
+	return nodes, values, nil
+}
+
+// Put set the value of the key
+func (e *EtcdClient) Put(key, value string) error {
+	_, err := e.etcdCli.Put(e.rootCxt, key, value)
+	return err
+}
+
+// Del delete the key
+func (e *EtcdClient) Del(key string) error {
+	_, err := e.etcdCli.Delete(e.rootCxt, key)
+	return err
+}
+
+func childPrefix(path string) string {
+	return strings.TrimSuffix(path, "/") + "/"
+}
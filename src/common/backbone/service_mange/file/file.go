@@ -0,0 +1,172 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package file do service register and discover by a local directory, it is used for single node
+// deployment and tests, every node is a directory and the data of the node is saved in its data file.
+package file
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// ErrNoNode the node is not exist in the directory
+var ErrNoNode = errors.New("file: node does not exist")
+
+// dataFileName is the file name that stores the data of a node, it is hidden so that it is not
+// listed as a child of the node.
+const dataFileName = ".data"
+
+var sequence uint32
+
+// FileClient do service register and discover by local directory
+type FileClient struct {
+	root    string
+	cancel  context.CancelFunc
+	rootCxt context.Context
+}
+
+// NewFileClient create a object of FileClient
+func NewFileClient(root string) *FileClient {
+	return &FileClient{
+		root: root,
+	}
+}
+
+// Start used to run register and discover server
+func (f *FileClient) Start() error {
+	if err := os.MkdirAll(f.root, os.ModePerm); err != nil {
+		return fmt.Errorf("fail to create register discover directory %s, err: %v", f.root, err)
+	}
+
+	// create root context
+	f.rootCxt, f.cancel = context.WithCancel(context.Background())
+
+	return nil
+}
+
+// Stop used to stop register and discover server
+func (f *FileClient) Stop() error {
+	f.cancel()
+	return nil
+}
+
+// Ping to ping server
+func (f *FileClient) Ping() error {
+	info, err := os.Stat(f.root)
+	if err != nil {
+		return err
+	}
+
+	if !info.IsDir() {
+		return fmt.Errorf("register discover path %s is not a directory", f.root)
+	}
+	return nil
+}
+
+// Root return the root directory
+func (f *FileClient) Root() string {
+	return f.root
+}
+
+// WithCancel context with cancel
+func (f *FileClient) WithCancel() (context.Context, context.CancelFunc) {
+	return context.WithCancel(f.rootCxt)
+}
+
+// Get the data of the node, returns ErrNoNode if the node is not exist
+func (f *FileClient) Get(path string) (string, error) {
+	data, err := ioutil.ReadFile(filepath.Join(f.dir(path), dataFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", ErrNoNode
+		}
+		return "", err
+	}
+	return string(data), nil
+}
+
+// GetChildren get the children name of the path, sorted by name, returns ErrNoNode if the node is not exist
+func (f *FileClient) GetChildren(path string) ([]string, error) {
+	infos, err := ioutil.ReadDir(f.dir(path))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNoNode
+		}
+		return nil, err
+	}
+
+	children := make([]string, 0)
+	for _, info := range infos {
+		if !info.IsDir() || strings.HasPrefix(info.Name(), ".") {
+			continue
+		}
+		children = append(children, info.Name())
+	}
+	sort.Strings(children)
+	return children, nil
+}
+
+// Put set the data of the node, the node is created with its parents if it does not exist.
+func (f *FileClient) Put(path string, data []byte) error {
+	dir := f.dir(path)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+
+	// write to a temporary file and rename it, so that readers never get half written data
+	tmp := filepath.Join(dir, fmt.Sprintf("%s.%d", dataFileName, os.Getpid()))
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(dir, dataFileName))
+}
+
+// CreateSeq create a sequence child node of the path, returns the path of the created node.
+// the node name starts with the create time, so that the sorted children are in the create order.
+func (f *FileClient) CreateSeq(path string, data []byte) (string, error) {
+	seq := atomic.AddUint32(&sequence, 1)
+	node := fmt.Sprintf("%s/%020d_%d_%d", strings.TrimSuffix(path, "/"), time.Now().UnixNano(), os.Getpid(), seq)
+	if err := f.Put(node, data); err != nil {
+		return "", err
+	}
+	return node, nil
+}
+
+// ModTime returns the last modify time of the node data, returns ErrNoNode if the node is not exist
+func (f *FileClient) ModTime(path string) (time.Time, error) {
+	info, err := os.Stat(filepath.Join(f.dir(path), dataFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return time.Time{}, ErrNoNode
+		}
+		return time.Time{}, err
+	}
+	return info.ModTime(), nil
+}
+
+// Del delete the node and all of its children
+func (f *FileClient) Del(path string) error {
+	return os.RemoveAll(f.dir(path))
+}
+
+func (f *FileClient) dir(path string) string {
+	return filepath.Join(f.root, filepath.FromSlash(path))
+}
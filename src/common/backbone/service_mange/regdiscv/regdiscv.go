@@ -0,0 +1,207 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package regdiscv choose the register and discover backend by the regdiscv address.
+// the address can be one of the following format:
+// 1. zookeeper: 127.0.0.1:2181,127.0.0.2:2181 or zk://127.0.0.1:2181,127.0.0.2:2181
+// 2. etcd: etcd://127.0.0.1:2379,127.0.0.2:2379
+// 3. local directory: file:///data/cmdb/regdiscv, only for single node deployment and tests
+package regdiscv
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"configcenter/src/common/backbone/service_mange/etcd"
+	"configcenter/src/common/backbone/service_mange/file"
+	"configcenter/src/common/backbone/service_mange/zk"
+	"configcenter/src/common/zkclient"
+)
+
+// Type is the register and discover backend type
+type Type string
+
+const (
+	// Zookeeper register and discover by zookeeper, it is the default type
+	Zookeeper Type = "zk"
+	// Etcd register and discover by etcd v3
+	Etcd Type = "etcd"
+	// File register and discover by local directory
+	File Type = "file"
+)
+
+const schemeSeparator = "://"
+
+// KV is used to read the data and children of the register and discover backend path
+type KV interface {
+	// Get the data of the path
+	Get(path string) (string, error)
+	// GetChildren get the children name of the path
+	GetChildren(path string) ([]string, error)
+}
+
+// Client is the client of the register and discover backend
+type Client struct {
+	typ  Type
+	zk   *zk.ZkClient
+	etcd *etcd.EtcdClient
+	file *file.FileClient
+}
+
+// ParseAddress parse the regdiscv address to backend type and the address of the backend
+func ParseAddress(address string) (Type, string, error) {
+	idx := strings.Index(address, schemeSeparator)
+	if idx < 0 {
+		return Zookeeper, address, nil
+	}
+
+	typ, addr := Type(address[:idx]), address[idx+len(schemeSeparator):]
+	switch typ {
+	case Zookeeper, Etcd, File:
+	default:
+		return "", "", fmt.Errorf("unsupported register and discover type %s", typ)
+	}
+
+	if addr == "" {
+		return "", "", fmt.Errorf("register and discover address %s is invalid", address)
+	}
+	return typ, addr, nil
+}
+
+// NewClient create a register and discover client by the regdiscv address
+func NewClient(address string, timeOut time.Duration) (*Client, error) {
+	typ, addr, err := ParseAddress(address)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &Client{typ: typ}
+	switch typ {
+	case Zookeeper:
+		client.zk = zk.NewZkClient(addr, timeOut)
+	case Etcd:
+		client.etcd = etcd.NewEtcdClient(addr, timeOut)
+	case File:
+		client.file = file.NewFileClient(addr)
+	}
+	return client, nil
+}
+
+// NewZkClientWrapper wrap a zookeeper client
+func NewZkClientWrapper(client *zk.ZkClient) *Client {
+	return &Client{typ: Zookeeper, zk: client}
+}
+
+// Type returns the backend type
+func (c *Client) Type() Type {
+	return c.typ
+}
+
+// Start used to run register and discover server
+func (c *Client) Start() error {
+	switch c.typ {
+	case Etcd:
+		return c.etcd.Start()
+	case File:
+		return c.file.Start()
+	default:
+		return c.zk.Start()
+	}
+}
+
+// Stop used to stop register and discover server
+func (c *Client) Stop() error {
+	switch c.typ {
+	case Etcd:
+		return c.etcd.Stop()
+	case File:
+		return c.file.Stop()
+	default:
+		return c.zk.Stop()
+	}
+}
+
+// Ping to ping server
+func (c *Client) Ping() error {
+	switch c.typ {
+	case Etcd:
+		return c.etcd.Ping()
+	case File:
+		return c.file.Ping()
+	default:
+		return c.zk.Ping()
+	}
+}
+
+// KV returns the data reader of the backend
+func (c *Client) KV() KV {
+	switch c.typ {
+	case Etcd:
+		return c.etcd
+	case File:
+		return c.file
+	default:
+		return c.zk.Client()
+	}
+}
+
+// Put set the data of the path, the path is created with its parents if it does not exist
+func (c *Client) Put(path string, data []byte) error {
+	switch c.typ {
+	case Etcd:
+		return c.etcd.Put(path, string(data))
+	case File:
+		return c.file.Put(path, data)
+	default:
+		exist, err := c.zk.Client().Exist(path)
+		if err != nil {
+			return err
+		}
+		if exist {
+			return c.zk.Client().Set(path, string(data), -1)
+		}
+		return c.zk.Client().CreateDeepNode(path, data)
+	}
+}
+
+// Del delete the path
+func (c *Client) Del(path string) error {
+	switch c.typ {
+	case Etcd:
+		return c.etcd.Del(path)
+	case File:
+		return c.file.Del(path)
+	default:
+		return c.zk.Client().Del(path, -1)
+	}
+}
+
+// IsNoNodeErr check if the error means that the path is not exist
+func (c *Client) IsNoNodeErr(err error) bool {
+	return err == zkclient.ErrNoNode || err == etcd.ErrNoNode || err == file.ErrNoNode
+}
+
+// Zk returns the zookeeper client, returns nil if the backend is not zookeeper
+func (c *Client) Zk() *zk.ZkClient {
+	return c.zk
+}
+
+// Etcd returns the etcd client, returns nil if the backend is not etcd
+func (c *Client) Etcd() *etcd.EtcdClient {
+	return c.etcd
+}
+
+// File returns the local directory client, returns nil if the backend is not file
+func (c *Client) File() *file.FileClient {
+	return c.file
+}
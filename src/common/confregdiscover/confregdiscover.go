@@ -14,6 +14,7 @@
 package confregdiscover
 
 import (
+	"configcenter/src/common/backbone/service_mange/regdiscv"
 	"configcenter/src/common/backbone/service_mange/zk"
 )
 
@@ -40,6 +41,18 @@ func NewConfRegDiscover(client *zk.ZkClient) *ConfRegDiscover {
 	return confRD
 }
 
+// NewConfRegDiscvIf create the config register and discover implementation of the backend that client is connected to
+func NewConfRegDiscvIf(client *regdiscv.Client) ConfRegDiscvIf {
+	switch client.Type() {
+	case regdiscv.Etcd:
+		return NewEtcdRegDiscover(client.Etcd())
+	case regdiscv.File:
+		return NewFileRegDiscover(client.File())
+	default:
+		return NewZkRegDiscover(client.Zk())
+	}
+}
+
 // NewConfRegDiscoverWithTimeOut used to create a object
 func NewConfRegDiscoverWithTimeOut(client *zk.ZkClient) *ConfRegDiscover {
 	confRD := &ConfRegDiscover{
@@ -0,0 +1,119 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package confregdiscover
+
+import (
+	"context"
+	"time"
+
+	"configcenter/src/common/backbone/service_mange/etcd"
+	"configcenter/src/common/blog"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// EtcdRegDiscover config register and discover by etcd
+type EtcdRegDiscover struct {
+	client  *etcd.EtcdClient
+	cancel  context.CancelFunc
+	rootCtx context.Context
+}
+
+// NewEtcdRegDiscover create a object of EtcdRegDiscover
+func NewEtcdRegDiscover(client *etcd.EtcdClient) *EtcdRegDiscover {
+	ctx, ctxCancel := client.WithCancel()
+	return &EtcdRegDiscover{
+		client:  client,
+		rootCtx: ctx,
+		cancel:  ctxCancel,
+	}
+}
+
+// Ping to ping server
+func (e *EtcdRegDiscover) Ping() error {
+	return e.client.Ping()
+}
+
+// Write to save config data into etcd
+func (e *EtcdRegDiscover) Write(key string, data []byte) error {
+	return e.client.Put(key, string(data))
+}
+
+// Read the config data from etcd
+func (e *EtcdRegDiscover) Read(key string) (string, error) {
+	return e.client.Get(key)
+}
+
+// Discover watch the config data of the key
+func (e *EtcdRegDiscover) Discover(key string) (<-chan *DiscoverEvent, error) {
+	env := make(chan *DiscoverEvent, 1)
+
+	go e.loopDiscover(key, env)
+
+	return env, nil
+}
+
+func (e *EtcdRegDiscover) loopDiscover(key string, env chan *DiscoverEvent) {
+	for {
+		resp, err := e.client.Client().Get(e.rootCtx, key)
+		if err != nil {
+			blog.Errorf("fail to get config of key(%s), will retry after 5s, err: %v", key, err)
+			env <- &DiscoverEvent{Key: key, Err: err}
+			if !e.sleep(5 * time.Second) {
+				return
+			}
+			continue
+		}
+
+		if len(resp.Kvs) == 0 {
+			blog.Warnf("config key(%s) is not exist, will watch after 5s", key)
+			if !e.sleep(5 * time.Second) {
+				return
+			}
+			continue
+		}
+
+		env <- &DiscoverEvent{Key: key, Data: resp.Kvs[0].Value}
+
+		// watch the changes after the revision that we have read
+		watchCh := e.client.Client().Watch(e.rootCtx, key, clientv3.WithRev(resp.Header.Revision+1))
+		for watchResp := range watchCh {
+			if err := watchResp.Err(); err != nil {
+				blog.Errorf("watch config key(%s) failed, err: %v", key, err)
+				break
+			}
+
+			for _, event := range watchResp.Events {
+				if event.Type != clientv3.EventTypePut {
+					continue
+				}
+				env <- &DiscoverEvent{Key: key, Data: event.Kv.Value}
+			}
+		}
+
+		if !e.sleep(time.Second) {
+			return
+		}
+	}
+}
+
+// sleep for a while, returns false if the discover is canceled
+func (e *EtcdRegDiscover) sleep(duration time.Duration) bool {
+	select {
+	case <-e.rootCtx.Done():
+		blog.Infof("discover config is done")
+		return false
+	case <-time.After(duration):
+		return true
+	}
+}
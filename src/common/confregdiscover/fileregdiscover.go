@@ -0,0 +1,90 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package confregdiscover
+
+import (
+	"context"
+	"time"
+
+	"configcenter/src/common/backbone/service_mange/file"
+	"configcenter/src/common/blog"
+)
+
+// FileRegDiscover config register and discover by local directory
+type FileRegDiscover struct {
+	client  *file.FileClient
+	cancel  context.CancelFunc
+	rootCtx context.Context
+}
+
+// NewFileRegDiscover create a object of FileRegDiscover
+func NewFileRegDiscover(client *file.FileClient) *FileRegDiscover {
+	ctx, ctxCancel := client.WithCancel()
+	return &FileRegDiscover{
+		client:  client,
+		rootCtx: ctx,
+		cancel:  ctxCancel,
+	}
+}
+
+// Ping to ping server
+func (f *FileRegDiscover) Ping() error {
+	return f.client.Ping()
+}
+
+// Write to save config data into the directory
+func (f *FileRegDiscover) Write(key string, data []byte) error {
+	return f.client.Put(key, data)
+}
+
+// Read the config data from the directory
+func (f *FileRegDiscover) Read(key string) (string, error) {
+	return f.client.Get(key)
+}
+
+// Discover loop check the config data of the key, and send the event when it is modified
+func (f *FileRegDiscover) Discover(key string) (<-chan *DiscoverEvent, error) {
+	env := make(chan *DiscoverEvent, 1)
+
+	go f.loopDiscover(key, env)
+
+	return env, nil
+}
+
+func (f *FileRegDiscover) loopDiscover(key string, env chan *DiscoverEvent) {
+	var previous time.Time
+	for {
+		modTime, err := f.client.ModTime(key)
+		switch {
+		case err == file.ErrNoNode:
+			blog.V(4).Infof("config key(%s) is not exist, will check after 5s", key)
+		case err != nil:
+			env <- &DiscoverEvent{Key: key, Err: err}
+		case !modTime.Equal(previous):
+			data, err := f.client.Get(key)
+			if err != nil {
+				env <- &DiscoverEvent{Key: key, Err: err}
+				break
+			}
+			previous = modTime
+			env <- &DiscoverEvent{Key: key, Data: []byte(data)}
+		}
+
+		select {
+		case <-f.rootCtx.Done():
+			blog.Infof("discover config of key(%s) is done", key)
+			return
+		case <-time.After(5 * time.Second):
+		}
+	}
+}
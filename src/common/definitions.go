@@ -334,6 +334,9 @@ const (
 	// HostApplyEnabledField TODO
 	HostApplyEnabledField = "host_apply_enabled"
 
+	// HostApplyConflictPolicyField the conflict policy field of host apply rule
+	HostApplyConflictPolicyField = "conflict_policy"
+
 	// BKOSTypeField the os type field
 	BKOSTypeField = "bk_os_type"
 
@@ -692,6 +695,12 @@ const (
 	// BKAttributeIDField TODO
 	BKAttributeIDField = "bk_attribute_id"
 
+	// BKDynamicGroupIDField the dynamic group id field of the dynamic group member
+	BKDynamicGroupIDField = "dynamic_group_id"
+
+	// BKWatchMemberField the field of the dynamic group that indicates whether to watch its members
+	BKWatchMemberField = "watch_member"
+
 	// BKTokenField TODO
 	BKTokenField = "token"
 	// BKCursorField TODO
@@ -1064,6 +1073,8 @@ const (
 // api cache keys
 const (
 	ApiCacheLimiterRulePrefix = BKCacheKeyV3Prefix + "api:limiter_rule:"
+	// ApiCacheLimiterStatsPrefix is the prefix of the limiter rule stats key, which records the limited callers
+	ApiCacheLimiterStatsPrefix = BKCacheKeyV3Prefix + "api:limiter_stats:"
 )
 
 const (
@@ -1241,6 +1252,10 @@ const (
 	BKOpenSourceLoginPluginVersion = "opensource"
 	// BKSkipLoginPluginVersion TODO
 	BKSkipLoginPluginVersion = "skip-login"
+	// BKLDAPLoginPluginVersion ldap login plugin version, authenticate the user with the ldap directory
+	BKLDAPLoginPluginVersion = "ldap"
+	// BKOIDCLoginPluginVersion openid connect login plugin version, authenticate the user with an oidc provider
+	BKOIDCLoginPluginVersion = "oidc"
 
 	// BKNoopMonitorPlugin TODO
 	// monitor plugin type
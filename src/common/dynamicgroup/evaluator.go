@@ -0,0 +1,240 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package dynamicgroup defines the evaluator of the host dynamic group members, it is shared by the services that
+// evaluate the host dynamic groups, so that the members are the same wherever they are evaluated.
+package dynamicgroup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"configcenter/src/common"
+	"configcenter/src/common/metadata"
+	parse "configcenter/src/common/paraparse"
+)
+
+// ErrInvalidSubGroup is returned when the sub group of the dynamic group is cyclic referenced, too deep, not exists
+// or not a host dynamic group.
+var ErrInvalidSubGroup = errors.New("invalid dynamic group sub group")
+
+// Getter gets the data that is needed to evaluate the host dynamic group.
+type Getter interface {
+	// GetGroup returns the dynamic group in the business, returns nil if it does not exist.
+	GetGroup(ctx context.Context, bizID int64, id string) (*metadata.DynamicGroup, error)
+	// GetInstIDs returns the ids of the set or module instances in the business that match the condition.
+	GetInstIDs(ctx context.Context, bizID int64, objID string, cond map[string]interface{}) ([]int64, error)
+	// GetRelationHostIDs returns the distinct ids of the hosts that have relations matching the option.
+	GetRelationHostIDs(ctx context.Context, opt *metadata.DistinctHostIDByTopoRelationRequest) ([]int64, error)
+	// GetHostIDs returns the ids of the hosts in hostIDs that match the condition, cond can be nil.
+	GetHostIDs(ctx context.Context, hostIDs []int64, cond map[string]interface{}) ([]int64, error)
+}
+
+// Evaluator evaluates the members of the host dynamic groups.
+type Evaluator struct {
+	getter Getter
+}
+
+// NewEvaluator new host dynamic group evaluator.
+func NewEvaluator(getter Getter) *Evaluator {
+	return &Evaluator{getter: getter}
+}
+
+// MatchHosts returns the hosts that are members of the host dynamic group, only the hosts in hostIDs are evaluated,
+// or all the hosts in the group's business if hostIDs is nil.
+func (e *Evaluator) MatchHosts(ctx context.Context, group *metadata.DynamicGroup, hostIDs []int64) ([]int64,
+	error) {
+
+	return e.matchHosts(ctx, group, hostIDs, map[string]struct{}{group.ID: {}})
+}
+
+// MatchSubGroups returns the hosts that are members of all the sub groups of the host dynamic group, only the hosts
+// in hostIDs are evaluated, or all the hosts in the group's business if hostIDs is nil.
+func (e *Evaluator) MatchSubGroups(ctx context.Context, group *metadata.DynamicGroup, hostIDs []int64) ([]int64,
+	error) {
+
+	return e.matchSubGroups(ctx, group, hostIDs, map[string]struct{}{group.ID: {}})
+}
+
+// matchHosts evaluates the group, path is the group ids in the current nesting path.
+func (e *Evaluator) matchHosts(ctx context.Context, group *metadata.DynamicGroup, hostIDs []int64,
+	path map[string]struct{}) ([]int64, error) {
+
+	if hostIDs != nil && len(hostIDs) == 0 {
+		return make([]int64, 0), nil
+	}
+
+	relationOpt := &metadata.DistinctHostIDByTopoRelationRequest{
+		ApplicationIDArr: []int64{group.AppID},
+		HostIDArr:        hostIDs,
+	}
+
+	hostConds := make([]map[string]interface{}, 0)
+	for _, cond := range group.Info.Condition {
+		instCond, err := ParseCondition(&cond)
+		if err != nil {
+			return nil, fmt.Errorf("parse dynamic group %s condition failed, err: %v", group.ID, err)
+		}
+
+		switch cond.ObjID {
+		case common.BKInnerObjIDHost:
+			hostConds = append(hostConds, instCond)
+
+		case common.BKInnerObjIDSet, common.BKInnerObjIDModule:
+			ids, err := e.getter.GetInstIDs(ctx, group.AppID, cond.ObjID, instCond)
+			if err != nil {
+				return nil, err
+			}
+			if len(ids) == 0 {
+				return make([]int64, 0), nil
+			}
+
+			if cond.ObjID == common.BKInnerObjIDSet {
+				relationOpt.SetIDArr = intersectIDs(relationOpt.SetIDArr, ids)
+				if len(relationOpt.SetIDArr) == 0 {
+					return make([]int64, 0), nil
+				}
+				continue
+			}
+			relationOpt.ModuleIDArr = intersectIDs(relationOpt.ModuleIDArr, ids)
+			if len(relationOpt.ModuleIDArr) == 0 {
+				return make([]int64, 0), nil
+			}
+
+		default:
+			return nil, fmt.Errorf("dynamic group %s has unsupported condition object %s", group.ID, cond.ObjID)
+		}
+	}
+
+	relationHostIDs, err := e.getter.GetRelationHostIDs(ctx, relationOpt)
+	if err != nil {
+		return nil, err
+	}
+	if len(relationHostIDs) == 0 {
+		return make([]int64, 0), nil
+	}
+
+	var hostCond map[string]interface{}
+	if len(hostConds) > 0 {
+		hostCond = map[string]interface{}{common.BKDBAND: hostConds}
+	}
+	matched, err := e.getter.GetHostIDs(ctx, relationHostIDs, hostCond)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.matchSubGroups(ctx, group, matched, path)
+}
+
+// matchSubGroups returns the hosts that are members of all the sub groups of the group.
+func (e *Evaluator) matchSubGroups(ctx context.Context, group *metadata.DynamicGroup, hostIDs []int64,
+	path map[string]struct{}) ([]int64, error) {
+
+	matched := hostIDs
+	for _, subGroupID := range group.Info.SubGroups {
+		if matched != nil && len(matched) == 0 {
+			break
+		}
+
+		if _, exists := path[subGroupID]; exists {
+			return nil, fmt.Errorf("%w: dynamic group %s sub group %s is cyclic referenced", ErrInvalidSubGroup,
+				group.ID, subGroupID)
+		}
+		if len(path) >= metadata.DynamicGroupMaxDepth {
+			return nil, fmt.Errorf("%w: dynamic group %s sub groups exceeds max depth %d", ErrInvalidSubGroup,
+				group.ID, metadata.DynamicGroupMaxDepth)
+		}
+
+		subGroup, err := e.getter.GetGroup(ctx, group.AppID, subGroupID)
+		if err != nil {
+			return nil, err
+		}
+		if subGroup == nil {
+			return nil, fmt.Errorf("%w: dynamic group %s sub group %s not exists", ErrInvalidSubGroup, group.ID,
+				subGroupID)
+		}
+		if subGroup.ObjID != common.BKInnerObjIDHost {
+			return nil, fmt.Errorf("%w: dynamic group %s sub group %s object %s is not host", ErrInvalidSubGroup,
+				group.ID, subGroupID, subGroup.ObjID)
+		}
+
+		path[subGroupID] = struct{}{}
+		matched, err = e.matchHosts(ctx, subGroup, matched, path)
+		delete(path, subGroupID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if matched == nil {
+		return make([]int64, 0), nil
+	}
+	return matched, nil
+}
+
+// intersectIDs returns the ids in both of the id lists, ids is returned if prev is nil.
+func intersectIDs(prev, ids []int64) []int64 {
+	if prev == nil {
+		return ids
+	}
+
+	idMap := make(map[int64]struct{}, len(ids))
+	for _, id := range ids {
+		idMap[id] = struct{}{}
+	}
+
+	result := make([]int64, 0)
+	for _, id := range prev {
+		if _, exists := idMap[id]; exists {
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
+// ParseCondition parses the dynamic group condition into the db condition of the condition's object.
+func ParseCondition(cond *metadata.DynamicGroupInfoCondition) (map[string]interface{}, error) {
+	conditions := make([]metadata.ConditionItem, 0, len(cond.Condition))
+	for _, item := range cond.Condition {
+		conditions = append(conditions, metadata.ConditionItem{Field: item.Field, Operator: item.Operator,
+			Value: item.Value})
+	}
+
+	instCond := make(map[string]interface{})
+	if cond.ObjID == common.BKInnerObjIDHost {
+		if err := parse.ParseHostParams(conditions, instCond); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := parse.ParseCommonParams(conditions, instCond); err != nil {
+			return nil, err
+		}
+	}
+
+	if cond.TimeCondition != nil {
+		var err error
+		if instCond, err = cond.TimeCondition.MergeTimeCondition(instCond); err != nil {
+			return nil, err
+		}
+	}
+
+	if cond.Filter == nil {
+		return instCond, nil
+	}
+
+	filterCond, err := cond.Filter.ToMgo()
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{common.BKDBAND: []map[string]interface{}{instCond, filterCond}}, nil
+}
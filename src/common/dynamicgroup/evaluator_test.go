@@ -0,0 +1,315 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dynamicgroup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+
+	"configcenter/src/common"
+	"configcenter/src/common/metadata"
+)
+
+// fakeGetter gets the data from memory, only supports the equal and $in condition combined with $and
+type fakeGetter struct {
+	groups    map[string]*metadata.DynamicGroup
+	sets      []map[string]interface{}
+	modules   []map[string]interface{}
+	relations []metadata.ModuleHost
+	hosts     []map[string]interface{}
+}
+
+func (f *fakeGetter) GetGroup(_ context.Context, bizID int64, id string) (*metadata.DynamicGroup, error) {
+	group, exists := f.groups[id]
+	if !exists || group.AppID != bizID {
+		return nil, nil
+	}
+	return group, nil
+}
+
+func (f *fakeGetter) GetInstIDs(_ context.Context, bizID int64, objID string, cond map[string]interface{}) (
+	[]int64, error) {
+
+	insts := f.sets
+	if objID == common.BKInnerObjIDModule {
+		insts = f.modules
+	}
+
+	ids := make([]int64, 0)
+	for _, inst := range insts {
+		if inst[common.BKAppIDField] == bizID && matchFakeCond(inst, cond) {
+			ids = append(ids, inst[common.GetInstIDField(objID)].(int64))
+		}
+	}
+	return ids, nil
+}
+
+func (f *fakeGetter) GetRelationHostIDs(_ context.Context, opt *metadata.DistinctHostIDByTopoRelationRequest) (
+	[]int64, error) {
+
+	ids := make([]int64, 0)
+	exists := make(map[int64]struct{})
+	for _, relation := range f.relations {
+		if !containsID(opt.ApplicationIDArr, relation.AppID) || !containsID(opt.SetIDArr, relation.SetID) ||
+			!containsID(opt.ModuleIDArr, relation.ModuleID) || !containsID(opt.HostIDArr, relation.HostID) {
+			continue
+		}
+		if _, ok := exists[relation.HostID]; ok {
+			continue
+		}
+		exists[relation.HostID] = struct{}{}
+		ids = append(ids, relation.HostID)
+	}
+	return ids, nil
+}
+
+func (f *fakeGetter) GetHostIDs(_ context.Context, hostIDs []int64, cond map[string]interface{}) ([]int64, error) {
+	ids := make([]int64, 0)
+	for _, host := range f.hosts {
+		hostID := host[common.BKHostIDField].(int64)
+		if containsID(hostIDs, hostID) && matchFakeCond(host, cond) {
+			ids = append(ids, hostID)
+		}
+	}
+	return ids, nil
+}
+
+// containsID returns if the id is in ids, empty ids contains all ids
+func containsID(ids []int64, id int64) bool {
+	if len(ids) == 0 {
+		return true
+	}
+	for _, one := range ids {
+		if one == id {
+			return true
+		}
+	}
+	return false
+}
+
+func matchFakeCond(doc map[string]interface{}, cond map[string]interface{}) bool {
+	for field, value := range cond {
+		if field == common.BKDBAND {
+			for _, sub := range value.([]map[string]interface{}) {
+				if !matchFakeCond(doc, sub) {
+					return false
+				}
+			}
+			continue
+		}
+
+		op, ok := value.(map[string]interface{})
+		if !ok {
+			if fmt.Sprint(doc[field]) != fmt.Sprint(value) {
+				return false
+			}
+			continue
+		}
+
+		matched := false
+		for _, item := range op[common.BKDBIN].([]interface{}) {
+			if fmt.Sprint(doc[field]) == fmt.Sprint(item) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+func newFakeGetter() *fakeGetter {
+	getter := &fakeGetter{
+		groups: make(map[string]*metadata.DynamicGroup),
+		sets: []map[string]interface{}{
+			{common.BKAppIDField: int64(1), common.BKSetIDField: int64(1), common.BKSetNameField: "set1"},
+			{common.BKAppIDField: int64(1), common.BKSetIDField: int64(2), common.BKSetNameField: "set2"},
+			{common.BKAppIDField: int64(2), common.BKSetIDField: int64(3), common.BKSetNameField: "set1"},
+		},
+		modules: []map[string]interface{}{
+			{common.BKAppIDField: int64(1), common.BKModuleIDField: int64(1), common.BKModuleNameField: "mod1"},
+			{common.BKAppIDField: int64(1), common.BKModuleIDField: int64(2), common.BKModuleNameField: "mod2"},
+			{common.BKAppIDField: int64(2), common.BKModuleIDField: int64(3), common.BKModuleNameField: "mod1"},
+		},
+		relations: []metadata.ModuleHost{
+			{AppID: 1, SetID: 1, ModuleID: 1, HostID: 1},
+			{AppID: 1, SetID: 1, ModuleID: 1, HostID: 2},
+			{AppID: 1, SetID: 2, ModuleID: 2, HostID: 3},
+			{AppID: 1, SetID: 2, ModuleID: 2, HostID: 4},
+			{AppID: 2, SetID: 3, ModuleID: 3, HostID: 5},
+		},
+	}
+
+	for id := int64(1); id <= 5; id++ {
+		getter.hosts = append(getter.hosts, map[string]interface{}{
+			common.BKHostIDField:   id,
+			common.BKHostNameField: fmt.Sprintf("host%d", id%2),
+		})
+	}
+	return getter
+}
+
+func (f *fakeGetter) addGroup(id string, bizID int64, subGroups []string,
+	conds ...metadata.DynamicGroupInfoCondition) {
+
+	f.groups[id] = &metadata.DynamicGroup{
+		AppID: bizID,
+		ID:    id,
+		ObjID: common.BKInnerObjIDHost,
+		Info:  metadata.DynamicGroupInfo{Condition: conds, SubGroups: subGroups},
+	}
+}
+
+func eqCond(objID, field string, value interface{}) metadata.DynamicGroupInfoCondition {
+	return metadata.DynamicGroupInfoCondition{
+		ObjID:     objID,
+		Condition: []metadata.DynamicGroupCondition{{Field: field, Operator: common.BKDBEQ, Value: value}},
+	}
+}
+
+func TestMatchHosts(t *testing.T) {
+	getter := newFakeGetter()
+	getter.addGroup("all", 1, nil)
+	getter.addGroup("host1", 1, nil, eqCond(common.BKInnerObjIDHost, common.BKHostNameField, "host1"))
+	getter.addGroup("set2", 1, nil, eqCond(common.BKInnerObjIDSet, common.BKSetNameField, "set2"))
+	getter.addGroup("set2host1", 1, nil, eqCond(common.BKInnerObjIDSet, common.BKSetNameField, "set2"),
+		eqCond(common.BKInnerObjIDHost, common.BKHostNameField, "host1"))
+	getter.addGroup("set1mod2", 1, nil, eqCond(common.BKInnerObjIDSet, common.BKSetNameField, "set1"),
+		eqCond(common.BKInnerObjIDModule, common.BKModuleNameField, "mod2"))
+	getter.addGroup("mod1", 2, nil, eqCond(common.BKInnerObjIDModule, common.BKModuleNameField, "mod1"))
+	getter.addGroup("subHost1", 1, []string{"host1"})
+	getter.addGroup("set2SubHost1", 1, []string{"host1"},
+		eqCond(common.BKInnerObjIDSet, common.BKSetNameField, "set2"))
+	getter.addGroup("nested", 1, []string{"subHost1", "set2"})
+	getter.addGroup("other", 2, nil)
+	getter.addGroup("subOtherBiz", 1, []string{"other"})
+
+	tests := []struct {
+		name    string
+		group   string
+		hostIDs []int64
+		want    []int64
+	}{
+		{name: "no condition", group: "all", want: []int64{1, 2, 3, 4}},
+		{name: "host condition", group: "host1", want: []int64{1, 3}},
+		{name: "set condition", group: "set2", want: []int64{3, 4}},
+		{name: "set and host condition", group: "set2host1", want: []int64{3}},
+		{name: "set and module have no common host", group: "set1mod2", want: []int64{}},
+		{name: "module condition in other business", group: "mod1", want: []int64{5}},
+		{name: "only evaluate specified hosts", group: "host1", hostIDs: []int64{2, 3, 5}, want: []int64{3}},
+		{name: "empty specified hosts", group: "all", hostIDs: []int64{}, want: []int64{}},
+		{name: "sub group", group: "subHost1", want: []int64{1, 3}},
+		{name: "sub group and condition", group: "set2SubHost1", want: []int64{3}},
+		{name: "nested sub groups", group: "nested", want: []int64{3}},
+		{name: "sub group not in the same business", group: "subOtherBiz", want: nil},
+	}
+
+	evaluator := NewEvaluator(getter)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := evaluator.MatchHosts(context.Background(), getter.groups[tt.group], tt.hostIDs)
+			if tt.want == nil {
+				if !errors.Is(err, ErrInvalidSubGroup) {
+					t.Fatalf("expect invalid sub group error, got: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("match hosts failed, err: %v", err)
+			}
+
+			sort.Slice(got, func(i, j int) bool { return got[i] < got[j] })
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MatchHosts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchSubGroups(t *testing.T) {
+	getter := newFakeGetter()
+	getter.addGroup("host1", 1, nil, eqCond(common.BKInnerObjIDHost, common.BKHostNameField, "host1"))
+	getter.addGroup("set1", 1, nil, eqCond(common.BKInnerObjIDSet, common.BKSetNameField, "set1"))
+	getter.addGroup("parent", 1, []string{"host1", "set1"},
+		eqCond(common.BKInnerObjIDSet, common.BKSetNameField, "set2"))
+	getter.addGroup("noSub", 1, nil)
+
+	evaluator := NewEvaluator(getter)
+
+	// the conditions of the group itself are not evaluated
+	got, err := evaluator.MatchSubGroups(context.Background(), getter.groups["parent"], nil)
+	if err != nil {
+		t.Fatalf("match sub groups failed, err: %v", err)
+	}
+	if !reflect.DeepEqual(got, []int64{1}) {
+		t.Errorf("MatchSubGroups() = %v, want [1]", got)
+	}
+
+	got, err = evaluator.MatchSubGroups(context.Background(), getter.groups["noSub"], nil)
+	if err != nil || len(got) != 0 {
+		t.Errorf("MatchSubGroups() without sub groups = %v, err: %v, want empty", got, err)
+	}
+}
+
+func TestMatchHostsInvalidSubGroup(t *testing.T) {
+	getter := newFakeGetter()
+	getter.addGroup("self", 1, []string{"self"})
+	getter.addGroup("cycle1", 1, []string{"cycle2"})
+	getter.addGroup("cycle2", 1, []string{"cycle3"})
+	getter.addGroup("cycle3", 1, []string{"cycle1"})
+	getter.addGroup("notExists", 1, []string{"unknown"})
+	getter.addGroup("setGroup", 1, nil)
+	getter.groups["setGroup"].ObjID = common.BKInnerObjIDSet
+	getter.addGroup("mismatch", 1, []string{"setGroup"})
+
+	// depth0 -> depth1 -> ... -> depth(max), the nesting of depth(max) exceeds the max depth
+	for depth := 0; depth <= metadata.DynamicGroupMaxDepth; depth++ {
+		var subGroups []string
+		if depth < metadata.DynamicGroupMaxDepth {
+			subGroups = []string{fmt.Sprintf("depth%d", depth+1)}
+		}
+		getter.addGroup(fmt.Sprintf("depth%d", depth), 1, subGroups)
+	}
+
+	tests := []struct {
+		name    string
+		group   string
+		wantErr bool
+	}{
+		{name: "reference itself", group: "self", wantErr: true},
+		{name: "indirect cycle", group: "cycle1", wantErr: true},
+		{name: "sub group not exists", group: "notExists", wantErr: true},
+		{name: "sub group is not host group", group: "mismatch", wantErr: true},
+		{name: "exceeds max depth", group: "depth0", wantErr: true},
+		{name: "reaches max depth", group: "depth1", wantErr: false},
+	}
+
+	evaluator := NewEvaluator(getter)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := evaluator.MatchHosts(context.Background(), getter.groups[tt.group], nil)
+			if tt.wantErr != errors.Is(err, ErrInvalidSubGroup) {
+				t.Errorf("MatchHosts() err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -552,6 +552,8 @@ const (
 	//  CCErrCommMigrateFailed failed to migrate
 	CCErrCommMigrateFailed        = 1105000
 	CCErrCommInitAuthCenterFailed = 1105001
+	// CCErrAdminIndexReconcileRunning db index reconcile task is already running
+	CCErrAdminIndexReconcileRunning = 1105002
 
 	// host controller 1106XXX
 	CCErrHostSelectInst                  = 1106000
@@ -752,6 +754,8 @@ const (
 	CCErrWebVerifyYamlFail              = 1111022
 	CCErrWebVerifyYamlPwdFail           = 1111023
 	CCErrWebBuildZipFail                = 1111024
+	CCErrWebLDAPRequestFail             = 1111025
+	CCErrWebOIDCRequestFail             = 1111026
 
 	// datacollection 1112xxx
 	CCErrCollectNetDeviceCreateFail            = 1112000
@@ -29,7 +29,18 @@ func init() {
 
 // TODO:
 //  新加和修改后的索引,索引名字一定要用对应的前缀，CCLogicUniqueIdxNamePrefix|common.CCLogicIndexNamePrefix
-var commAPITaskIndexes = []types.Index{}
+var commAPITaskIndexes = []types.Index{
+	{
+		Name: common.CCLogicIndexNamePrefix + "task_type_status_priority_create_time",
+		Keys: bson.D{
+			{common.BKTaskTypeField, 1},
+			{common.BKStatusField, 1},
+			{"priority", -1},
+			{common.CreateTimeField, 1},
+		},
+		Background: true,
+	},
+}
 
 // deprecated 未规范化前的索引，只允许删除不允许新加和修改，
 var deprecatedAPITaskIndexes = []types.Index{
@@ -0,0 +1,99 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package collections
+
+import (
+	"configcenter/src/common"
+	"configcenter/src/storage/dal/types"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func init() {
+	registerIndexes(common.BKTableNameLocalAuthRole, commLocalAuthRoleIndexes)
+	registerIndexes(common.BKTableNameLocalAuthGroup, commLocalAuthGroupIndexes)
+	registerIndexes(common.BKTableNameLocalAuthRoleBinding, commLocalAuthRoleBindingIndexes)
+}
+
+var commLocalAuthRoleIndexes = []types.Index{
+	{
+		Name: common.CCLogicUniqueIdxNamePrefix + "id",
+		Keys: bson.D{
+			{common.BKFieldID, 1},
+		},
+		Background: true,
+		Unique:     true,
+	},
+	{
+		Name: common.CCLogicUniqueIdxNamePrefix + "name_bk_supplier_account",
+		Keys: bson.D{
+			{common.BKFieldName, 1},
+			{common.BkSupplierAccount, 1},
+		},
+		Background: true,
+		Unique:     true,
+	},
+}
+
+var commLocalAuthGroupIndexes = []types.Index{
+	{
+		Name: common.CCLogicUniqueIdxNamePrefix + "id",
+		Keys: bson.D{
+			{common.BKFieldID, 1},
+		},
+		Background: true,
+		Unique:     true,
+	},
+	{
+		Name: common.CCLogicUniqueIdxNamePrefix + "name_bk_supplier_account",
+		Keys: bson.D{
+			{common.BKFieldName, 1},
+			{common.BkSupplierAccount, 1},
+		},
+		Background: true,
+		Unique:     true,
+	},
+	{
+		Name: common.CCLogicIndexNamePrefix + "members",
+		Keys: bson.D{
+			{"members", 1},
+		},
+		Background: true,
+	},
+}
+
+var commLocalAuthRoleBindingIndexes = []types.Index{
+	{
+		Name: common.CCLogicUniqueIdxNamePrefix + "id",
+		Keys: bson.D{
+			{common.BKFieldID, 1},
+		},
+		Background: true,
+		Unique:     true,
+	},
+	{
+		Name: common.CCLogicIndexNamePrefix + "subject_type_subject",
+		Keys: bson.D{
+			{"subject_type", 1},
+			{"subject", 1},
+		},
+		Background: true,
+	},
+	{
+		Name: common.CCLogicIndexNamePrefix + "role_id",
+		Keys: bson.D{
+			{"role_id", 1},
+		},
+		Background: true,
+	},
+}
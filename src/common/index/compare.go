@@ -13,9 +13,6 @@
 package index
 
 import (
-	"reflect"
-
-	"configcenter/src/common/util"
 	"configcenter/src/storage/dal/types"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -49,60 +46,5 @@ func FindIndexByIndexFields(keys bson.D, indexList []types.Index) (dbIndex types
 
 // IndexEqual 索引对比， 索引名字不参与对比
 func IndexEqual(toDBIndex, dbIndex types.Index) bool {
-	if toDBIndex.Background != dbIndex.Background {
-		return false
-	}
-	if toDBIndex.Unique != dbIndex.Unique {
-		return false
-	}
-	if toDBIndex.ExpireAfterSeconds != dbIndex.ExpireAfterSeconds {
-		return false
-	}
-
-	toDBIdxMap := toDBIndex.Keys.Map()
-
-	dbIdxMap := dbIndex.Keys.Map()
-
-	if len(toDBIdxMap) != len(dbIdxMap) {
-		return false
-	}
-
-	if len(toDBIndex.PartialFilterExpression) != len(dbIndex.PartialFilterExpression) {
-		return false
-	}
-
-	for key, val := range toDBIdxMap {
-		dbVal, exists := dbIdxMap[key]
-		if !exists {
-			return false
-		}
-
-		valInt, err := util.GetIntByInterface(val)
-		if err != nil {
-			return false
-		}
-
-		dbValInt, err := util.GetIntByInterface(dbVal)
-		if err != nil {
-			return false
-		}
-
-		if valInt != dbValInt {
-			return false
-		}
-	}
-
-	// NOTICE: 对比逻辑不严谨, 如果是cc 代码产生的唯一索引，不存在问题
-	for key, val := range toDBIndex.PartialFilterExpression {
-		dbVal, exists := dbIndex.PartialFilterExpression[key]
-		if !exists {
-			return false
-		}
-		if !reflect.DeepEqual(val, dbVal) {
-			return false
-		}
-	}
-
-	return true
-
+	return len(IndexDiffFields(toDBIndex, dbIndex)) == 0
 }
@@ -0,0 +1,164 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package index
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"configcenter/src/common"
+	"configcenter/src/common/metadata"
+	"configcenter/src/common/util"
+	"configcenter/src/storage/dal/types"
+)
+
+// idIndexName is the name of the default _id index that mongodb creates for all collections
+const idIndexName = "_id_"
+
+// CompareTableIndexes 对比表中定义的索引和db中实际存在的索引，返回缺少、多余、废弃以及不一致的索引
+// 注意： 索引的使用情况需要从db中统计，这里不处理
+func CompareTableIndexes(table string, logicIndexes, dbIndexes []types.Index,
+	deprecatedNames []string) metadata.TableIndexDrift {
+
+	drift := metadata.TableIndexDrift{Table: table}
+
+	logicIdxMap := make(map[string]types.Index, len(logicIndexes))
+	for _, logicIndex := range logicIndexes {
+		logicIdxMap[logicIndex.Name] = logicIndex
+	}
+
+	deprecatedMap := make(map[string]struct{}, len(deprecatedNames))
+	for _, name := range deprecatedNames {
+		deprecatedMap[name] = struct{}{}
+	}
+
+	dbIdxMap := make(map[string]types.Index, len(dbIndexes))
+	for _, dbIndex := range dbIndexes {
+		dbIdxMap[dbIndex.Name] = dbIndex
+		if dbIndex.Name == idIndexName {
+			continue
+		}
+
+		logicIndex, exists := logicIdxMap[dbIndex.Name]
+		if exists {
+			if fields := IndexDiffFields(logicIndex, dbIndex); len(fields) > 0 {
+				drift.Differing = append(drift.Differing, metadata.IndexDifference{
+					Name:     dbIndex.Name,
+					Expected: logicIndex,
+					Actual:   dbIndex,
+					Fields:   fields,
+				})
+			}
+			continue
+		}
+
+		// 和同步索引的逻辑保持一致，cc 规范命名的索引和规范化前的索引在同步时会被删除
+		_, deprecated := deprecatedMap[dbIndex.Name]
+		if deprecated || strings.HasPrefix(dbIndex.Name, common.CCLogicIndexNamePrefix) ||
+			strings.HasPrefix(dbIndex.Name, common.CCLogicUniqueIdxNamePrefix) {
+			drift.Deprecated = append(drift.Deprecated, dbIndex)
+			continue
+		}
+		drift.Extra = append(drift.Extra, dbIndex)
+	}
+
+	for _, logicIndex := range logicIndexes {
+		if _, exists := dbIdxMap[logicIndex.Name]; !exists {
+			drift.Missing = append(drift.Missing, logicIndex)
+		}
+	}
+
+	sort.Slice(drift.Differing, func(i, j int) bool {
+		return drift.Differing[i].Name < drift.Differing[j].Name
+	})
+	sortIndexesByName(drift.Extra)
+	sortIndexesByName(drift.Deprecated)
+
+	return drift
+}
+
+// IndexDiffFields 返回两个索引中不一致的字段， 索引名字不参与对比，IndexEqual 也使用它对比索引
+func IndexDiffFields(toDBIndex, dbIndex types.Index) []string {
+	fields := make([]string, 0)
+	if !indexKeysEqual(toDBIndex, dbIndex) {
+		fields = append(fields, "keys")
+	}
+	if toDBIndex.Unique != dbIndex.Unique {
+		fields = append(fields, "unique")
+	}
+	if toDBIndex.Background != dbIndex.Background {
+		fields = append(fields, "background")
+	}
+	if toDBIndex.ExpireAfterSeconds != dbIndex.ExpireAfterSeconds {
+		fields = append(fields, "expire_after_seconds")
+	}
+	if !partialFilterEqual(toDBIndex, dbIndex) {
+		fields = append(fields, "partialFilterExpression")
+	}
+	return fields
+}
+
+func indexKeysEqual(toDBIndex, dbIndex types.Index) bool {
+	toDBIdxMap := toDBIndex.Keys.Map()
+	dbIdxMap := dbIndex.Keys.Map()
+	if len(toDBIdxMap) != len(dbIdxMap) {
+		return false
+	}
+
+	for key, val := range toDBIdxMap {
+		dbVal, exists := dbIdxMap[key]
+		if !exists {
+			return false
+		}
+
+		valInt, err := util.GetIntByInterface(val)
+		if err != nil {
+			return false
+		}
+
+		dbValInt, err := util.GetIntByInterface(dbVal)
+		if err != nil {
+			return false
+		}
+
+		if valInt != dbValInt {
+			return false
+		}
+	}
+	return true
+}
+
+func partialFilterEqual(toDBIndex, dbIndex types.Index) bool {
+	if len(toDBIndex.PartialFilterExpression) != len(dbIndex.PartialFilterExpression) {
+		return false
+	}
+
+	// NOTICE: 对比逻辑不严谨, 如果是cc 代码产生的唯一索引，不存在问题
+	for key, val := range toDBIndex.PartialFilterExpression {
+		dbVal, exists := dbIndex.PartialFilterExpression[key]
+		if !exists {
+			return false
+		}
+		if !reflect.DeepEqual(val, dbVal) {
+			return false
+		}
+	}
+	return true
+}
+
+func sortIndexesByName(indexes []types.Index) {
+	sort.Slice(indexes, func(i, j int) bool {
+		return indexes[i].Name < indexes[j].Name
+	})
+}
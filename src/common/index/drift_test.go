@@ -0,0 +1,111 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package index
+
+import (
+	"reflect"
+	"testing"
+
+	"configcenter/src/storage/dal/types"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestCompareTableIndexes(t *testing.T) {
+	logicIndexes := []types.Index{
+		{Name: "bkcc_idx_a", Keys: bson.D{{"a", 1}}, Background: true},
+		{Name: "bkcc_idx_b", Keys: bson.D{{"b", 1}}, Background: true},
+		{Name: "bkcc_unique_c", Keys: bson.D{{"c", 1}}, Unique: true, Background: true},
+	}
+	dbIndexes := []types.Index{
+		{Name: "_id_", Keys: bson.D{{"_id", 1}}},
+		{Name: "bkcc_idx_a", Keys: bson.D{{"a", int32(1)}}, Background: true},
+		{Name: "bkcc_unique_c", Keys: bson.D{{"c", -1}}, Background: true},
+		{Name: "bkcc_idx_old", Keys: bson.D{{"old", 1}}, Background: true},
+		{Name: "old_name", Keys: bson.D{{"d", 1}}},
+		{Name: "manual", Keys: bson.D{{"e", 1}}},
+	}
+
+	drift := CompareTableIndexes("cc_Test", logicIndexes, dbIndexes, []string{"old_name"})
+
+	if len(drift.Missing) != 1 || drift.Missing[0].Name != "bkcc_idx_b" {
+		t.Errorf("unexpected missing indexes: %+v", drift.Missing)
+	}
+
+	if len(drift.Extra) != 1 || drift.Extra[0].Name != "manual" {
+		t.Errorf("unexpected extra indexes: %+v", drift.Extra)
+	}
+
+	if len(drift.Deprecated) != 2 || drift.Deprecated[0].Name != "bkcc_idx_old" ||
+		drift.Deprecated[1].Name != "old_name" {
+		t.Errorf("unexpected deprecated indexes: %+v", drift.Deprecated)
+	}
+
+	if len(drift.Differing) != 1 || drift.Differing[0].Name != "bkcc_unique_c" {
+		t.Fatalf("unexpected differing indexes: %+v", drift.Differing)
+	}
+	if !reflect.DeepEqual(drift.Differing[0].Fields, []string{"keys", "unique"}) {
+		t.Errorf("unexpected differing fields: %v", drift.Differing[0].Fields)
+	}
+
+	if !drift.HasDrift() {
+		t.Errorf("table should have drift")
+	}
+}
+
+func TestCompareTableIndexesNoDrift(t *testing.T) {
+	indexes := []types.Index{
+		{Name: "bkcc_idx_a", Keys: bson.D{{"a", 1}, {"b", -1}}, Background: true,
+			PartialFilterExpression: map[string]interface{}{"a": map[string]interface{}{"$type": "string"}}},
+	}
+
+	drift := CompareTableIndexes("cc_Test", indexes, indexes, nil)
+	if drift.HasDrift() {
+		t.Errorf("table should not have drift, drift: %+v", drift)
+	}
+}
+
+func TestIndexDiffFields(t *testing.T) {
+	base := func() types.Index {
+		return types.Index{Name: "bkcc_idx_a", Keys: bson.D{{"a", 1}}, Background: true,
+			PartialFilterExpression: map[string]interface{}{"a": map[string]interface{}{"$type": "string"}}}
+	}
+
+	testCases := []struct {
+		modify func(idx *types.Index)
+		fields []string
+	}{
+		{modify: func(idx *types.Index) { idx.Name = "bkcc_idx_b" }, fields: []string{}},
+		{modify: func(idx *types.Index) { idx.Keys = bson.D{{"a", int64(1)}} }, fields: []string{}},
+		{modify: func(idx *types.Index) { idx.Keys = bson.D{{"a", -1}} }, fields: []string{"keys"}},
+		{modify: func(idx *types.Index) { idx.Unique = true }, fields: []string{"unique"}},
+		{modify: func(idx *types.Index) { idx.Background = false }, fields: []string{"background"}},
+		{modify: func(idx *types.Index) { idx.ExpireAfterSeconds = 60 }, fields: []string{"expire_after_seconds"}},
+		{modify: func(idx *types.Index) { idx.PartialFilterExpression = nil },
+			fields: []string{"partialFilterExpression"}},
+	}
+
+	for i, tc := range testCases {
+		dbIndex := base()
+		tc.modify(&dbIndex)
+
+		fields := IndexDiffFields(base(), dbIndex)
+		if !reflect.DeepEqual(fields, tc.fields) {
+			t.Errorf("case %d: diff fields %v, expect %v", i, fields, tc.fields)
+		}
+
+		if equal := IndexEqual(base(), dbIndex); equal != (len(tc.fields) == 0) {
+			t.Errorf("case %d: index equal %v is not consistent with diff fields %v", i, equal, fields)
+		}
+	}
+}
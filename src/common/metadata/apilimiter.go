@@ -19,6 +19,14 @@ import (
 	"configcenter/src/common/util"
 )
 
+const (
+	// LimiterTypeFixedWindow limits the request count in a fixed window of ttl seconds, it is the default type
+	LimiterTypeFixedWindow = "fixed_window"
+	// LimiterTypeTokenBucket refills limit tokens in every ttl seconds into a bucket with a capacity of burst tokens,
+	// each request takes one token, so that a burst of requests is allowed after an idle period
+	LimiterTypeTokenBucket = "token_bucket"
+)
+
 // LimiterRule is a rule for api limiter
 type LimiterRule struct {
 	RuleName string `json:"rulename"`
@@ -30,6 +38,38 @@ type LimiterRule struct {
 	Limit    int64  `json:"limit"`
 	TTL      int64  `json:"ttl"`
 	DenyAll  bool   `json:"denyall"`
+	// Type is the limiter type of the rule, default is fixed window
+	Type string `json:"type,omitempty"`
+	// Burst is the capacity of the token bucket, default is the limit
+	Burst int64 `json:"burst,omitempty"`
+	// SupplierAccount matches the requests of the supplier account
+	SupplierAccount string `json:"bk_supplier_account,omitempty"`
+	// PerTenant counts the requests of each supplier account separately, so each tenant has its own quota
+	PerTenant bool `json:"pertenant,omitempty"`
+	// DryRun only logs and records the requests that should be limited by the rule, they are not rejected
+	DryRun bool `json:"dryrun,omitempty"`
+}
+
+// HasCondition returns if the rule has any condition to match the requests
+func (r LimiterRule) HasCondition() bool {
+	return r.AppCode != "" || r.User != "" || r.IP != "" || r.Url != "" || r.Method != "" ||
+		r.SupplierAccount != "" || r.PerTenant
+}
+
+// GetType returns the limiter type of the rule
+func (r LimiterRule) GetType() string {
+	if r.Type == "" {
+		return LimiterTypeFixedWindow
+	}
+	return r.Type
+}
+
+// GetBurst returns the capacity of the token bucket
+func (r LimiterRule) GetBurst() int64 {
+	if r.Burst <= 0 {
+		return r.Limit
+	}
+	return r.Burst
 }
 
 // Verify to check the fields of LimiterRule
@@ -37,8 +77,8 @@ func (r LimiterRule) Verify() error {
 	if r.RuleName == "" {
 		return fmt.Errorf("rulename must be set")
 	}
-	if r.AppCode == "" && r.User == "" && r.IP == "" && r.Url == "" && r.Method == "" {
-		return fmt.Errorf("one of appcode, user, ip, url, method must be set")
+	if !r.HasCondition() {
+		return fmt.Errorf("one of appcode, user, ip, url, method, bk_supplier_account, pertenant must be set")
 	}
 	if r.Method != "" {
 		if util.Normalize(r.Method) != "POST" && util.Normalize(r.Method) != "GET" && util.Normalize(r.Method) != "PUT" && util.Normalize(r.Method) != "DELETE" {
@@ -55,5 +95,20 @@ func (r LimiterRule) Verify() error {
 			return fmt.Errorf("both limit and ttl must be set and bigger than 0 when denyall is false")
 		}
 	}
+	if r.Type != "" && r.Type != LimiterTypeFixedWindow && r.Type != LimiterTypeTokenBucket {
+		return fmt.Errorf("type must be one of %s,%s", LimiterTypeFixedWindow, LimiterTypeTokenBucket)
+	}
+	if r.Burst < 0 {
+		return fmt.Errorf("burst can not be negative")
+	}
 	return nil
 }
+
+// LimiterCaller is the caller of the requests that are limited by a limiter rule
+type LimiterCaller struct {
+	AppCode         string `json:"appcode"`
+	User            string `json:"user"`
+	IP              string `json:"ip"`
+	SupplierAccount string `json:"bk_supplier_account"`
+	DryRun          bool   `json:"dryrun"`
+}
@@ -201,6 +201,12 @@ type AuditLog struct {
 	AppCode string `json:"code,omitempty" bson:"code,omitempty"`
 	// RequestID is the request id of the request
 	RequestID string `json:"rid,omitempty" bson:"rid,omitempty"`
+	// ChainSeq is the sequence of the audit log in the hash chain of the supplier account, it is set when sealed
+	ChainSeq int64 `json:"chain_seq,omitempty" bson:"chain_seq,omitempty"`
+	// PrevHash is the hash of the previous audit log in the hash chain
+	PrevHash string `json:"prev_hash,omitempty" bson:"prev_hash,omitempty"`
+	// Hash is the hash of this audit log which is calculated with the previous hash and the content of this log
+	Hash string `json:"hash,omitempty" bson:"hash,omitempty"`
 }
 
 type bsonAuditLog struct {
@@ -218,6 +224,9 @@ type bsonAuditLog struct {
 	ResourceName    string          `json:"resource_name" bson:"resource_name"`
 	AppCode         string          `json:"code" bson:"code"`
 	RequestID       string          `json:"rid" bson:"rid"`
+	ChainSeq        int64           `json:"chain_seq" bson:"chain_seq"`
+	PrevHash        string          `json:"prev_hash" bson:"prev_hash"`
+	Hash            string          `json:"hash" bson:"hash"`
 }
 
 type jsonAuditLog struct {
@@ -235,6 +244,9 @@ type jsonAuditLog struct {
 	ResourceName    string          `json:"resource_name" bson:"resource_name"`
 	AppCode         string          `json:"code" bson:"code"`
 	RequestID       string          `json:"rid" bson:"rid"`
+	ChainSeq        int64           `json:"chain_seq" bson:"chain_seq"`
+	PrevHash        string          `json:"prev_hash" bson:"prev_hash"`
+	Hash            string          `json:"hash" bson:"hash"`
 }
 
 // DetailFactory TODO
@@ -261,6 +273,9 @@ func (auditLog *AuditLog) UnmarshalJSON(data []byte) error {
 	auditLog.ResourceName = audit.ResourceName
 	auditLog.AppCode = audit.AppCode
 	auditLog.RequestID = audit.RequestID
+	auditLog.ChainSeq = audit.ChainSeq
+	auditLog.PrevHash = audit.PrevHash
+	auditLog.Hash = audit.Hash
 
 	if audit.OperationDetail == nil {
 		return nil
@@ -311,6 +326,12 @@ func (auditLog *AuditLog) UnmarshalJSON(data []byte) error {
 			return err
 		}
 		auditLog.OperationDetail = operationDetail
+	case HostApplyRes:
+		operationDetail := new(HostApplyOpDetail)
+		if err := json.Unmarshal(audit.OperationDetail, &operationDetail); err != nil {
+			return err
+		}
+		auditLog.OperationDetail = operationDetail
 	default:
 		operationDetail := new(BasicOpDetail)
 		if err := json.Unmarshal(audit.OperationDetail, &operationDetail); err != nil {
@@ -340,6 +361,9 @@ func (auditLog *AuditLog) UnmarshalBSON(data []byte) error {
 	auditLog.ResourceName = audit.ResourceName
 	auditLog.AppCode = audit.AppCode
 	auditLog.RequestID = audit.RequestID
+	auditLog.ChainSeq = audit.ChainSeq
+	auditLog.PrevHash = audit.PrevHash
+	auditLog.Hash = audit.Hash
 
 	if audit.OperationDetail == nil {
 		return nil
@@ -390,6 +414,12 @@ func (auditLog *AuditLog) UnmarshalBSON(data []byte) error {
 			return err
 		}
 		auditLog.OperationDetail = operationDetail
+	case HostApplyRes:
+		operationDetail := new(HostApplyOpDetail)
+		if err := bson.Unmarshal(audit.OperationDetail, &operationDetail); err != nil {
+			return err
+		}
+		auditLog.OperationDetail = operationDetail
 	default:
 		operationDetail := new(BasicOpDetail)
 		if err := bson.Unmarshal(audit.OperationDetail, &operationDetail); err != nil {
@@ -581,6 +611,21 @@ func (op *GenericOpDetail) WithName() string {
 	return "GenericOpDetail"
 }
 
+// HostApplyOpDetail host apply operation detail, records the host attributes applied by the host apply rules and
+// how the conflicts between the rules are resolved
+type HostApplyOpDetail struct {
+	ModuleIDs []int64 `json:"bk_module_ids" bson:"bk_module_ids"`
+	// UpdateFields the host attributes that are applied to the host
+	UpdateFields map[string]interface{} `json:"update_fields" bson:"update_fields"`
+	// ConflictResolvers the conflicts resolved by the conflict policies, including the chosen source rules
+	ConflictResolvers []HostApplyConflictResolver `json:"conflict_resolvers" bson:"conflict_resolvers"`
+}
+
+// WithName returns the host apply operation detail name
+func (op *HostApplyOpDetail) WithName() string {
+	return "HostApplyOpDetail"
+}
+
 // AuditType TODO
 type AuditType string
 
@@ -664,7 +709,7 @@ const (
 	ModuleRes ResourceType = "module"
 	// ProcessRes TODO
 	ProcessRes ResourceType = "process"
-	// HostApplyRes TODO
+	// HostApplyRes host attributes applied by host apply rules
 	HostApplyRes ResourceType = "host_apply"
 	// CustomFieldRes TODO
 	CustomFieldRes ResourceType = "custom_field"
@@ -909,6 +954,13 @@ var auditDict = []resourceTypeInfo{
 			actionInfoMap[AuditTransferHostModule],
 		},
 	},
+	{
+		ID:   HostApplyRes,
+		Name: "主机属性自动应用",
+		Operations: []actionTypeInfo{
+			actionInfoMap[AuditUpdate],
+		},
+	},
 	{
 		ID:   BusinessRes,
 		Name: "业务",
@@ -1136,6 +1188,13 @@ var auditEnDict = []resourceTypeInfo{
 			actionInfoEnMap[AuditTransferHostModule],
 		},
 	},
+	{
+		ID:   HostApplyRes,
+		Name: "Host Auto Apply",
+		Operations: []actionTypeInfo{
+			actionInfoEnMap[AuditUpdate],
+		},
+	},
 	{
 		ID:   BusinessRes,
 		Name: "Business",
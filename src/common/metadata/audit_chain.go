@@ -0,0 +1,138 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metadata
+
+import (
+	"errors"
+	"time"
+)
+
+// AuditLogChainDocType is the document type in the audit log chain table
+type AuditLogChainDocType string
+
+const (
+	// AuditLogChainCursor is the cursor of the audit log sealer, which records the last sealed audit log id
+	AuditLogChainCursor AuditLogChainDocType = "cursor"
+	// AuditLogChainHead is the head of the hash chain of a supplier account
+	AuditLogChainHead AuditLogChainDocType = "head"
+)
+
+// AuditLogChainCursorDoc is the cursor of the audit log sealer, the audit logs whose id is in the range of
+// (StartID, LastID] are sealed into the hash chains.
+type AuditLogChainCursorDoc struct {
+	Type AuditLogChainDocType `json:"type" bson:"type"`
+	// StartID is the max audit log id when the hash chain is enabled, the former logs are not chained
+	StartID int64 `json:"start_id" bson:"start_id"`
+	// LastID is the id of the last audit log that is sealed
+	LastID int64 `json:"last_id" bson:"last_id"`
+}
+
+// AuditLogChainHeadDoc is the head of the audit log hash chain of a supplier account
+type AuditLogChainHeadDoc struct {
+	Type            AuditLogChainDocType `json:"type" bson:"type"`
+	SupplierAccount string               `json:"bk_supplier_account" bson:"bk_supplier_account"`
+	// ChainSeq is the sequence of the last audit log in the chain
+	ChainSeq int64 `json:"chain_seq" bson:"chain_seq"`
+	// AuditID is the id of the last audit log in the chain
+	AuditID int64 `json:"audit_id" bson:"audit_id"`
+	// Hash is the hash of the last audit log in the chain
+	Hash string `json:"hash" bson:"hash"`
+	// Signature is the hmac-sha256 signature of the head with the configured secret, so that the tail of the chain
+	// can not be deleted by rolling back the head
+	Signature string `json:"signature" bson:"signature"`
+}
+
+// AuditLogCheckpointType is the type of the audit log checkpoint
+type AuditLogCheckpointType string
+
+const (
+	// AuditLogCheckpointPeriodic is the checkpoint that is generated periodically at the head of the chain
+	AuditLogCheckpointPeriodic AuditLogCheckpointType = "periodic"
+	// AuditLogCheckpointTruncate is the checkpoint that is generated at the last deleted audit log before the
+	// audit logs are deleted by the retention policy, so that the remaining chain can still be verified
+	AuditLogCheckpointTruncate AuditLogCheckpointType = "truncate"
+)
+
+// AuditLogCheckpoint is the signed checkpoint of the audit log hash chain, it is never deleted with the audit logs
+type AuditLogCheckpoint struct {
+	ID              int64                  `json:"id" bson:"id"`
+	Type            AuditLogCheckpointType `json:"type" bson:"type"`
+	SupplierAccount string                 `json:"bk_supplier_account" bson:"bk_supplier_account"`
+	ChainSeq        int64                  `json:"chain_seq" bson:"chain_seq"`
+	AuditID         int64                  `json:"audit_id" bson:"audit_id"`
+	Hash            string                 `json:"hash" bson:"hash"`
+	CreateTime      time.Time              `json:"create_time" bson:"create_time"`
+	// Signature is the hmac-sha256 signature of the checkpoint with the configured secret
+	Signature string `json:"signature" bson:"signature"`
+}
+
+// VerifyAuditLogChainOption is the option to verify the audit log hash chain of a supplier account
+type VerifyAuditLogChainOption struct {
+	SupplierAccount string `json:"bk_supplier_account"`
+	// StartSeq and EndSeq are the chain sequence range to verify, verify the whole chain if they are not set
+	StartSeq int64 `json:"start_seq"`
+	EndSeq   int64 `json:"end_seq"`
+}
+
+// Validate validates the verify audit log chain option
+func (v *VerifyAuditLogChainOption) Validate() error {
+	if v.StartSeq < 0 || v.EndSeq < 0 {
+		return errors.New("start_seq and end_seq can not be negative")
+	}
+
+	if v.EndSeq > 0 && v.StartSeq > v.EndSeq {
+		return errors.New("start_seq can not be greater than end_seq")
+	}
+	return nil
+}
+
+// AuditLogChainGap is a range of the chain sequences whose audit logs are missing
+type AuditLogChainGap struct {
+	FromSeq int64 `json:"from_seq"`
+	ToSeq   int64 `json:"to_seq"`
+}
+
+// AuditLogChainIssue is an audit log or checkpoint that fails the verification
+type AuditLogChainIssue struct {
+	AuditID      int64  `json:"audit_id,omitempty"`
+	CheckpointID int64  `json:"checkpoint_id,omitempty"`
+	ChainSeq     int64  `json:"chain_seq"`
+	Reason       string `json:"reason"`
+}
+
+// VerifyAuditLogChainResult is the result of verifying the audit log hash chain
+type VerifyAuditLogChainResult struct {
+	SupplierAccount string `json:"bk_supplier_account"`
+	// Valid is true if there is no gap and issue in the verified chain
+	Valid bool `json:"valid"`
+	// Checked is the number of the verified audit logs
+	Checked  int64 `json:"checked"`
+	FirstSeq int64 `json:"first_seq"`
+	LastSeq  int64 `json:"last_seq"`
+	// HeadSeq is the chain sequence of the head of the chain
+	HeadSeq int64 `json:"head_seq"`
+	// Unsealed is the number of audit logs that should have been sealed but are not in the chain
+	Unsealed int64 `json:"unsealed"`
+	// Checkpoints is the number of the verified checkpoints
+	Checkpoints int64                `json:"checkpoints"`
+	Gaps        []AuditLogChainGap   `json:"gaps"`
+	Issues      []AuditLogChainIssue `json:"issues"`
+	// Truncated is true if the number of gaps or issues exceeds the limit and the rest are not returned
+	Truncated bool `json:"truncated"`
+}
+
+// VerifyAuditLogChainResponse is the response of verifying the audit log hash chain
+type VerifyAuditLogChainResponse struct {
+	BaseResp `json:",inline"`
+	Data     VerifyAuditLogChainResult `json:"data"`
+}
@@ -92,11 +92,13 @@ type CloudAccountWithExtraInfo struct {
 const (
 	AWS          string = "1"
 	TencentCloud string = "2"
+	// OpenStack openstack兼容的私有云
+	OpenStack string = "17"
 )
 
 // SupportedCloudVendors 支持的云厂商
 // 实现了相应的云厂商插件
-var SupportedCloudVendors = []string{AWS, TencentCloud}
+var SupportedCloudVendors = []string{AWS, TencentCloud, OpenStack}
 
 // 云同步任务同步状态
 const (
@@ -440,3 +442,69 @@ type SecretKeyInfo struct {
 type SecretContent struct {
 	SecretKey string `json:"secret_key"`
 }
+
+// PreviewSyncTaskOption 预览云同步任务的条件，即同步任务的账号和需要同步的vpc
+type PreviewSyncTaskOption struct {
+	AccountID int64         `json:"bk_account_id"`
+	SyncVpcs  []VpcSyncInfo `json:"bk_sync_vpcs"`
+}
+
+// Validate 校验预览云同步任务的条件
+func (p *PreviewSyncTaskOption) Validate() (rawError errors.RawErrorInfo) {
+	if p.AccountID <= 0 {
+		return errors.RawErrorInfo{
+			ErrCode: common.CCErrCommParamsInvalid,
+			Args:    []interface{}{common.BKCloudAccountID},
+		}
+	}
+
+	if len(p.SyncVpcs) == 0 {
+		return errors.RawErrorInfo{
+			ErrCode: common.CCErrCommParamsNeedSet,
+			Args:    []interface{}{common.BKCloudSyncVpcs},
+		}
+	}
+
+	return errors.RawErrorInfo{}
+}
+
+// SyncTaskPreview 云同步任务的预览结果，即执行同步任务时将会产生的变更，预览不会写入任何数据
+type SyncTaskPreview struct {
+	// AddHosts 将要新增的云主机
+	AddHosts []CloudHostPreview `json:"add_hosts"`
+	// UpdateHosts 将要更新的云主机，包含字段变更前后的值。和同步结果一致，云端已销毁和在已销毁vpc下的主机
+	// 也是将要被更新为已销毁状态的主机
+	UpdateHosts []CloudHostPreview `json:"update_hosts"`
+	// CreateCloudAreas 还没有对应云区域的vpc，需要先为其创建云区域，否则同步任务会失败
+	CreateCloudAreas []CloudAreaPreview `json:"create_cloud_areas"`
+	// DestroyedCloudAreas 云端已销毁的vpc对应的云区域，将要被置为异常状态
+	DestroyedCloudAreas []CloudAreaPreview `json:"destroyed_cloud_areas"`
+}
+
+// CloudHostPreview 预览结果中的云主机，新增和更新时为云端的数据，被置为已销毁状态时为本地的数据
+type CloudHostPreview struct {
+	HostID        int64  `json:"bk_host_id,omitempty"`
+	InstanceID    string `json:"bk_cloud_inst_id"`
+	CloudID       int64  `json:"bk_cloud_id"`
+	PrivateIp     string `json:"bk_host_innerip"`
+	PublicIp      string `json:"bk_host_outerip"`
+	InstanceState string `json:"bk_cloud_host_status"`
+	// Changes 更新云主机时有变化的字段
+	Changes []CloudHostFieldChange `json:"changes,omitempty"`
+}
+
+// CloudHostFieldChange 云主机字段变更前后的值
+type CloudHostFieldChange struct {
+	Field  string      `json:"bk_property_id"`
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// CloudAreaPreview 预览结果中的云区域
+type CloudAreaPreview struct {
+	CloudID   int64  `json:"bk_cloud_id,omitempty"`
+	CloudName string `json:"bk_cloud_name"`
+	VpcID     string `json:"bk_vpc_id"`
+	VpcName   string `json:"bk_vpc_name"`
+	Region    string `json:"bk_region"`
+}
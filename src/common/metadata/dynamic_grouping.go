@@ -17,8 +17,10 @@ import (
 	"fmt"
 	"time"
 
+	"configcenter/pkg/filter"
 	"configcenter/src/common"
 	"configcenter/src/common/blog"
+	"configcenter/src/common/criteria/enumor"
 	"configcenter/src/common/util"
 
 	"github.com/google/uuid"
@@ -182,6 +184,27 @@ func getAttributeType(attributeType string) (string, error) {
 	}
 }
 
+// getFilterFieldType returns the filter field type of the attribute type, which is used to validate filter expression.
+func getFilterFieldType(attributeType string) (enumor.FieldType, bool) {
+	switch attributeType {
+	case common.FieldTypeSingleChar, common.FieldTypeLongChar, common.FieldTypeDate, common.FieldTypeTimeZone,
+		common.FieldTypeUser, common.FieldTypeList:
+		return enumor.String, true
+	case common.FieldTypeEnum:
+		return enumor.Enum, true
+	case common.FieldTypeInt, common.FieldTypeFloat, common.FieldTypeOrganization:
+		return enumor.Numeric, true
+	case common.FieldTypeBool:
+		return enumor.Boolean, true
+	case common.FieldTypeTime:
+		return enumor.Time, true
+	case common.FieldObject:
+		return enumor.MapString, true
+	default:
+		return "", false
+	}
+}
+
 // DynamicGroupInfoCondition is condition for dynamic grouping, user could search
 // target source base on the conditions.
 type DynamicGroupInfoCondition struct {
@@ -194,6 +217,10 @@ type DynamicGroupInfoCondition struct {
 
 	// 非必填，只能用来查时间，且与Condition是与关系
 	TimeCondition *TimeCondition `json:"time_condition,omitempty" bson:"time_condition,omitempty"`
+
+	// Filter is an optional filter expression on the object's fields, which supports AND/OR nesting and all the
+	// operators of the filter package, it is combined with Condition by AND.
+	Filter *filter.Expression `json:"filter,omitempty" bson:"filter,omitempty"`
 }
 
 // Validate validates dynamic group info conditions format.
@@ -227,13 +254,39 @@ func (c *DynamicGroupInfoCondition) Validate(validatefunc Validatefunc) error {
 			return err
 		}
 	}
+
+	if c.Filter == nil {
+		return nil
+	}
+
+	ruleFields := make(map[string]enumor.FieldType)
+	for field, attributeType := range attributeMap {
+		if fieldType, isSupport := getFilterFieldType(attributeType); isSupport {
+			ruleFields[field] = fieldType
+		}
+	}
+	if err := c.Filter.Validate(filter.NewDefaultExprOpt(ruleFields)); err != nil {
+		return fmt.Errorf("invalid %s filter, %v", c.ObjID, err)
+	}
 	return nil
 }
 
+const (
+	// DynamicGroupMaxSubGroups is the maximum number of sub groups a dynamic group can reference.
+	DynamicGroupMaxSubGroups = 10
+
+	// DynamicGroupMaxDepth is the maximum depth of the nested dynamic groups, including the top group.
+	DynamicGroupMaxDepth = 5
+)
+
 // DynamicGroupInfo is info field in DynamicGroup struct.
 type DynamicGroupInfo struct {
 	// Condition is dynamic group index conditions set.
 	Condition []DynamicGroupInfoCondition `json:"condition" bson:"condition"`
+
+	// SubGroups is the referenced dynamic group IDs in the same business with the same object type, target
+	// resources must be the members of all the sub groups besides matching the conditions.
+	SubGroups []string `json:"sub_groups,omitempty" bson:"sub_groups,omitempty"`
 }
 
 // Validate validates dynamic group info format, it's OK if conditions empty in this level.
@@ -252,6 +305,21 @@ func (c *DynamicGroupInfo) Validate(objectID string, validatefunc Validatefunc)
 			return err
 		}
 	}
+
+	if len(c.SubGroups) > DynamicGroupMaxSubGroups {
+		return fmt.Errorf("sub groups exceeds maximum limit: %d", DynamicGroupMaxSubGroups)
+	}
+
+	subGroups := make(map[string]struct{})
+	for _, id := range c.SubGroups {
+		if len(id) == 0 {
+			return errors.New("empty sub group id")
+		}
+		if _, exists := subGroups[id]; exists {
+			return fmt.Errorf("duplicate sub group %s", id)
+		}
+		subGroups[id] = struct{}{}
+	}
 	return nil
 }
 
@@ -272,6 +340,10 @@ type DynamicGroup struct {
 	// Info is dynamic group core conditions information.
 	Info DynamicGroupInfo `json:"info" bson:"info"`
 
+	// WatchMember is whether to materialize the members of the host dynamic group, the membership changes can be
+	// watched as dynamic group member events if it's enabled.
+	WatchMember bool `json:"watch_member" bson:"watch_member"`
+
 	// CreateUser create user name.
 	CreateUser string `json:"create_user" bson:"create_user"`
 
@@ -300,14 +372,31 @@ func (g *DynamicGroup) Validate(validatefunc Validatefunc) error {
 		return errors.New("empty bk_obj_id")
 	}
 
+	if g.WatchMember && g.ObjID != common.BKInnerObjIDHost {
+		return fmt.Errorf("watch_member is not supported for %s dynamic group", g.ObjID)
+	}
+
 	// check conditions format.
-	if len(g.Info.Condition) == 0 {
-		// it's not OK if conditions empty in this level.
+	if len(g.Info.Condition) == 0 && len(g.Info.SubGroups) == 0 {
+		// it's not OK if both conditions and sub groups are empty in this level.
 		return errors.New("empty info.condition")
 	}
 	return g.Info.Validate(g.ObjID, validatefunc)
 }
 
+// DynamicGroupMember is the materialized member of the watched host dynamic group, it's also the detail of the
+// dynamic group member event.
+type DynamicGroupMember struct {
+	// BizID is the business id of the dynamic group.
+	BizID int64 `json:"bk_biz_id" bson:"bk_biz_id"`
+
+	// GroupID is the dynamic group id.
+	GroupID string `json:"dynamic_group_id" bson:"dynamic_group_id"`
+
+	// HostID is the member host id.
+	HostID int64 `json:"bk_host_id" bson:"bk_host_id"`
+}
+
 // DynamicGroupBatch is batch result struct of dynamic group.
 type DynamicGroupBatch struct {
 	// Count batch count.
@@ -48,6 +48,10 @@ type HostApplyRule struct {
 	CreateTime      time.Time `field:"create_time" json:"create_time" bson:"create_time" mapstructure:"create_time"`
 	LastTime        time.Time `field:"last_time" json:"last_time" bson:"last_time" mapstructure:"last_time"`
 	SupplierAccount string    `field:"bk_supplier_account" json:"bk_supplier_account" bson:"bk_supplier_account" mapstructure:"bk_supplier_account"`
+
+	// ConflictPolicy the conflict policy of the attribute, all rules of the same attribute in a business share it
+	// NOCC:tosa/linelength(忽略长度)
+	ConflictPolicy *HostApplyConflictPolicy `field:"conflict_policy" json:"conflict_policy,omitempty" bson:"conflict_policy,omitempty" mapstructure:"conflict_policy"`
 }
 
 // Validate TODO
@@ -168,6 +172,8 @@ func (option *DeleteHostApplyRuleOption) ValidateModuleOption() (rawError errors
 // BatchCreateOrUpdateApplyRuleOption TODO
 type BatchCreateOrUpdateApplyRuleOption struct {
 	Rules []CreateOrUpdateApplyRuleOption `field:"host_apply_rules" json:"host_apply_rules" bson:"host_apply_rules" mapstructure:"host_apply_rules"`
+	// ConflictPolicies the conflict policies that are saved to all rules of their attributes in the business
+	ConflictPolicies []HostApplyConflictPolicy `json:"conflict_policies,omitempty" bson:"conflict_policies,omitempty"`
 }
 
 // UpdateHostByHostApplyRuleOption TODO
@@ -188,6 +194,16 @@ type BatchCreateOrUpdateHostApplyRuleResult struct {
 	Items []CreateOrUpdateHostApplyRuleResult `json:"items" mapstructure:"items"`
 }
 
+// HasConflictPolicy check if any of the saved rules has conflict policy
+func (r *BatchCreateOrUpdateHostApplyRuleResult) HasConflictPolicy() bool {
+	for _, item := range r.Items {
+		if item.Rule.ConflictPolicy != nil {
+			return true
+		}
+	}
+	return false
+}
+
 // CreateOrUpdateHostApplyRuleResult TODO
 type CreateOrUpdateHostApplyRuleResult struct {
 	ErrorContainer `json:",inline"`
@@ -204,7 +220,67 @@ type HostAttribute struct {
 // HostApplyConflictResolver define a resolution to a single conflict.
 type HostApplyConflictResolver struct {
 	HostID        int64 `json:"bk_host_id" bson:"bk_host_id"`
-	HostAttribute `json:",inline"`
+	HostAttribute `json:",inline" bson:",inline"`
+	// Strategy the conflict strategy that generates this resolver, empty if it is specified by user
+	Strategy HostApplyConflictStrategy `json:"strategy,omitempty" bson:"strategy,omitempty"`
+	// SourceRuleIDs the host apply rules that the resolved value comes from
+	SourceRuleIDs []int64 `json:"source_rule_ids,omitempty" bson:"source_rule_ids,omitempty"`
+}
+
+// HostApplyConflictStrategy the strategy to resolve the conflict of host apply rules, which happens when a host
+// belongs to multiple modules whose rules of the same attribute have different values.
+type HostApplyConflictStrategy string
+
+const (
+	// HostApplyModulePriority use the rule of the module that comes first in the module priority order
+	HostApplyModulePriority HostApplyConflictStrategy = "module_priority"
+	// HostApplyFirstMatch use the rule of the module with the smallest module id
+	HostApplyFirstMatch HostApplyConflictStrategy = "first_match"
+	// HostApplyLastUpdated use the rule that is updated last
+	HostApplyLastUpdated HostApplyConflictStrategy = "last_updated"
+	// HostApplyUnion merge the values of all rules, only applies to multi-value fields like organization
+	HostApplyUnion HostApplyConflictStrategy = "union"
+)
+
+// HostApplyConflictPolicy the conflict resolution policy of a host attribute.
+type HostApplyConflictPolicy struct {
+	AttributeID int64                     `json:"bk_attribute_id" bson:"bk_attribute_id"`
+	Strategy    HostApplyConflictStrategy `json:"strategy" bson:"strategy"`
+	// ModulePriority module ids in descending order of priority, only used by module_priority strategy
+	ModulePriority []int64 `json:"module_priority,omitempty" bson:"module_priority,omitempty"`
+}
+
+// Validate validate HostApplyConflictPolicy
+func (p *HostApplyConflictPolicy) Validate() errors.RawErrorInfo {
+	if p.AttributeID <= 0 {
+		return errors.RawErrorInfo{
+			ErrCode: common.CCErrCommParamsInvalid,
+			Args:    []interface{}{"conflict_policies.bk_attribute_id"},
+		}
+	}
+
+	switch p.Strategy {
+	case HostApplyModulePriority:
+		if len(p.ModulePriority) == 0 {
+			return errors.RawErrorInfo{
+				ErrCode: common.CCErrCommParamsNeedSet,
+				Args:    []interface{}{"conflict_policies.module_priority"},
+			}
+		}
+	case HostApplyFirstMatch, HostApplyLastUpdated, HostApplyUnion:
+		if len(p.ModulePriority) > 0 {
+			return errors.RawErrorInfo{
+				ErrCode: common.CCErrCommParamsInvalid,
+				Args:    []interface{}{"conflict_policies.module_priority"},
+			}
+		}
+	default:
+		return errors.RawErrorInfo{
+			ErrCode: common.CCErrCommParamsInvalid,
+			Args:    []interface{}{"conflict_policies.strategy"},
+		}
+	}
+	return errors.RawErrorInfo{}
 }
 
 // HostApplyTransRules module attribute value setting in the host transfer scenario.
@@ -244,6 +320,9 @@ type HostApplyConflictField struct {
 	// UnresolvedConflictExist show whether conflict still exist after use possible conflict resolver
 	// if there is a conflict, but has a resolver for it, ConflictedStillExist will be false
 	UnresolvedConflictExist bool `field:"unresolved_conflict_exist" json:"unresolved_conflict_exist" mapstructure:"unresolved_conflict_exist"`
+	// Strategy and SourceRuleIDs show how the conflict is resolved when it is resolved by a conflict policy
+	Strategy      HostApplyConflictStrategy `field:"strategy" json:"strategy,omitempty" mapstructure:"strategy"`
+	SourceRuleIDs []int64                   `field:"source_rule_ids" json:"source_rule_ids,omitempty" mapstructure:"source_rule_ids"`
 }
 
 // HostApplyUpdateField TODO
@@ -311,6 +390,27 @@ type HostApplyPlanBase struct {
 	AdditionalRules []CreateHostApplyRuleOption `json:"additional_rules"`
 	// optional, if set, only hostID in HostIDs will be used
 	HostIDs []int64 `json:"bk_host_ids" bson:"bk_host_ids"`
+	// ConflictPolicies optional, the policies used to resolve the conflicts of the specified attributes
+	ConflictPolicies []HostApplyConflictPolicy `json:"conflict_policies,omitempty" bson:"conflict_policies,omitempty"`
+}
+
+// ValidateConflictPolicies validate the conflict policies of HostApplyPlanBase
+func (op *HostApplyPlanBase) ValidateConflictPolicies() errors.RawErrorInfo {
+	attrIDs := make(map[int64]struct{})
+	for _, policy := range op.ConflictPolicies {
+		if err := policy.Validate(); err.ErrCode != 0 {
+			return err
+		}
+
+		if _, exists := attrIDs[policy.AttributeID]; exists {
+			return errors.RawErrorInfo{
+				ErrCode: common.CCErrCommParamsInvalid,
+				Args:    []interface{}{"conflict_policies.bk_attribute_id"},
+			}
+		}
+		attrIDs[policy.AttributeID] = struct{}{}
+	}
+	return errors.RawErrorInfo{}
 }
 
 // HostApplyTaskStatusOption get task status.
@@ -391,6 +491,9 @@ func (op *HostApplyModulesOption) Validate() (rawError errors.RawErrorInfo) {
 	if err := hostApplyBaseValidate(op.BizID, op.AdditionalRules, op.RemoveRuleIDs); err.ErrCode != 0 {
 		return err
 	}
+	if err := op.ValidateConflictPolicies(); err.ErrCode != 0 {
+		return err
+	}
 	return errors.RawErrorInfo{}
 }
 
@@ -412,6 +515,9 @@ func (op *HostApplyServiceTemplateOption) Validate() (rawError errors.RawErrorIn
 	if err := hostApplyBaseValidate(op.BizID, op.AdditionalRules, op.RemoveRuleIDs); err.ErrCode != 0 {
 		return err
 	}
+	if err := op.ValidateConflictPolicies(); err.ErrCode != 0 {
+		return err
+	}
 	return errors.RawErrorInfo{}
 }
 
@@ -550,6 +656,12 @@ type ModuleFinalRulesParam struct {
 	ModuleIDs     []int64 `json:"bk_module_ids"`
 }
 
+// ModuleFinalRulesResponse module final rules response
+type ModuleFinalRulesResponse struct {
+	BaseResp `json:",inline"`
+	Data     []HostApplyRule `json:"data"`
+}
+
 // ServiceTemplatesResponse service template response
 type ServiceTemplatesResponse struct {
 	BaseResp `json:",inline"`
@@ -0,0 +1,121 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metadata
+
+import (
+	"time"
+
+	"configcenter/src/storage/dal/types"
+)
+
+// IndexDriftOption is the option to check the db index drift or reconcile the missing indexes
+type IndexDriftOption struct {
+	// Tables is the tables to check, all the tables that have defined indexes are checked if it is empty
+	Tables []string `json:"tables"`
+}
+
+// IndexDriftReport is the difference between the defined indexes and the indexes that exist in db
+type IndexDriftReport struct {
+	CheckTime time.Time         `json:"check_time"`
+	Tables    []TableIndexDrift `json:"tables"`
+}
+
+// TableIndexDrift is the index drift of a table, only the tables that have drift are returned
+type TableIndexDrift struct {
+	Table string `json:"table"`
+	// TableMissing defines if the table does not exist in db
+	TableMissing bool `json:"table_missing,omitempty"`
+	// Missing is the defined indexes that do not exist in db
+	Missing []types.Index `json:"missing,omitempty"`
+	// Extra is the db indexes that are not defined and not deprecated
+	Extra []types.Index `json:"extra,omitempty"`
+	// Deprecated is the db indexes that are deprecated, they are removed in the next index sync
+	Deprecated []types.Index `json:"deprecated,omitempty"`
+	// Differing is the db indexes whose keys or options are different from the defined ones with the same name
+	Differing []IndexDifference `json:"differing,omitempty"`
+	// Unused is the db indexes that are not accessed since the statistics of the db server are reset
+	Unused []IndexUsage `json:"unused,omitempty"`
+	// Error is the error that occurs when checking the table, the report of the table is incomplete if it is set
+	Error string `json:"error,omitempty"`
+}
+
+// HasDrift returns if the table has any index drift
+func (t *TableIndexDrift) HasDrift() bool {
+	return t.TableMissing || len(t.Missing) > 0 || len(t.Extra) > 0 || len(t.Deprecated) > 0 ||
+		len(t.Differing) > 0 || len(t.Unused) > 0 || t.Error != ""
+}
+
+// IndexDifference is the difference between a defined index and the db index with the same name
+type IndexDifference struct {
+	Name     string      `json:"name"`
+	Expected types.Index `json:"expected"`
+	Actual   types.Index `json:"actual"`
+	// Fields is the different fields, like keys, unique, background, expire_after_seconds, partialFilterExpression
+	Fields []string `json:"fields"`
+}
+
+// IndexUsage is the access statistics of a db index
+type IndexUsage struct {
+	Name  string    `json:"name" bson:"name"`
+	Ops   int64     `json:"ops" bson:"ops"`
+	Since time.Time `json:"since" bson:"since"`
+}
+
+// IndexDriftResponse is the response of checking the db index drift
+type IndexDriftResponse struct {
+	BaseResp `json:",inline"`
+	Data     IndexDriftReport `json:"data"`
+}
+
+// IndexReconcileStatus is the status of the db index reconcile task or one of its items
+type IndexReconcileStatus string
+
+const (
+	// IndexReconcileStatusPending the index is waiting to be created
+	IndexReconcileStatusPending IndexReconcileStatus = "pending"
+	// IndexReconcileStatusRunning the task is running or the index is being created
+	IndexReconcileStatusRunning IndexReconcileStatus = "running"
+	// IndexReconcileStatusSuccess the task is finished without error or the index is created
+	IndexReconcileStatusSuccess IndexReconcileStatus = "success"
+	// IndexReconcileStatusFailed the task is finished with some indexes failed or the index creation is failed
+	IndexReconcileStatusFailed IndexReconcileStatus = "failed"
+)
+
+// IndexReconcileProgress is the progress of the db index reconcile task, which creates the missing indexes
+// in the background one at a time
+type IndexReconcileProgress struct {
+	Status    IndexReconcileStatus `json:"status"`
+	StartTime time.Time            `json:"start_time"`
+	EndTime   *time.Time           `json:"end_time,omitempty"`
+	Total     int                  `json:"total"`
+	Succeeded int                  `json:"succeeded"`
+	Failed    int                  `json:"failed"`
+	Items     []IndexReconcileItem `json:"items"`
+}
+
+// IndexReconcileItem is a missing index that the reconcile task creates
+type IndexReconcileItem struct {
+	Table     string               `json:"table"`
+	Index     types.Index          `json:"index"`
+	Status    IndexReconcileStatus `json:"status"`
+	StartTime *time.Time           `json:"start_time,omitempty"`
+	EndTime   *time.Time           `json:"end_time,omitempty"`
+	Error     string               `json:"error,omitempty"`
+}
+
+// IndexReconcileResponse is the response of the db index reconcile task progress, data is nil if the task
+// has never been started
+type IndexReconcileResponse struct {
+	BaseResp `json:",inline"`
+	Data     *IndexReconcileProgress `json:"data"`
+}
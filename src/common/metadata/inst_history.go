@@ -0,0 +1,132 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metadata
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"configcenter/src/common/mapstr"
+)
+
+// instHistoryTimeLayouts are the supported layouts of the time in instance history options, the time without time
+// zone is parsed in local time zone, which is the same with the operation time condition of the audit log
+var instHistoryTimeLayouts = []string{"2006-01-02 15:04:05", time.RFC3339}
+
+// ParseInstHistoryTime parse the time in instance history options
+func ParseInstHistoryTime(t string) (time.Time, error) {
+	for _, layout := range instHistoryTimeLayouts {
+		parsed, err := time.ParseInLocation(layout, t, time.Local)
+		if err == nil {
+			return parsed, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("time %s is invalid, must be in format 2006-01-02 15:04:05 or RFC3339", t)
+}
+
+// InstHistoryOption is the option to get the attribute state of an instance at a point in time
+type InstHistoryOption struct {
+	Time string `json:"time"`
+}
+
+// Validate validates the instance history option, returns the parsed time
+func (o *InstHistoryOption) Validate() (time.Time, error) {
+	if o.Time == "" {
+		return time.Time{}, errors.New("time is not set")
+	}
+	return ParseInstHistoryTime(o.Time)
+}
+
+// InstSnapshot is the attribute state of an instance at a point in time
+type InstSnapshot struct {
+	ObjID  string `json:"bk_obj_id"`
+	InstID int64  `json:"bk_inst_id"`
+	Time   string `json:"time"`
+	// Exists is false if the instance is not created yet or already deleted at the time
+	Exists bool `json:"exists"`
+	// AuditID is the id of the audit log that the snapshot is reconstructed from,
+	// it is 0 if the snapshot is the current state of the instance
+	AuditID int64         `json:"audit_id"`
+	Data    mapstr.MapStr `json:"data"`
+}
+
+// InstHistoryDiffOption is the option to compare the attribute state of an instance between two points in time
+type InstHistoryDiffOption struct {
+	FromTime string `json:"from_time"`
+	// ToTime is the end time to compare, compare with the current state of the instance if it is not set
+	ToTime string `json:"to_time"`
+	// Fields are the fields to compare, compare all the fields if it is not set
+	Fields []string `json:"fields"`
+}
+
+// Validate validates the instance history diff option, returns the parsed from time and to time
+func (o *InstHistoryDiffOption) Validate() (time.Time, time.Time, error) {
+	if o.FromTime == "" {
+		return time.Time{}, time.Time{}, errors.New("from_time is not set")
+	}
+
+	from, err := ParseInstHistoryTime(o.FromTime)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	to := time.Now()
+	if o.ToTime != "" {
+		if to, err = ParseInstHistoryTime(o.ToTime); err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+	}
+
+	if from.After(to) {
+		return time.Time{}, time.Time{}, errors.New("from_time can not be after to_time")
+	}
+	return from, to, nil
+}
+
+// InstFieldDiff is the difference of a field between two points in time
+type InstFieldDiff struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// InstHistoryDiffResult is the result of comparing the attribute state of an instance between two points in time
+type InstHistoryDiffResult struct {
+	From  *InstSnapshot   `json:"from"`
+	To    *InstSnapshot   `json:"to"`
+	Diffs []InstFieldDiff `json:"diffs"`
+}
+
+// InstRevertOption is the option to revert the selected fields of an instance to their values at a point in time
+type InstRevertOption struct {
+	Time   string   `json:"time"`
+	Fields []string `json:"fields"`
+}
+
+// Validate validates the instance revert option, returns the parsed time
+func (o *InstRevertOption) Validate() (time.Time, error) {
+	if len(o.Fields) == 0 {
+		return time.Time{}, errors.New("fields are not set")
+	}
+
+	if o.Time == "" {
+		return time.Time{}, errors.New("time is not set")
+	}
+	return ParseInstHistoryTime(o.Time)
+}
+
+// InstRevertResult is the result of reverting an instance, contains the fields that are actually changed
+type InstRevertResult struct {
+	Diffs []InstFieldDiff `json:"diffs"`
+}
@@ -0,0 +1,124 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metadata
+
+import (
+	"errors"
+	"time"
+
+	"configcenter/src/common"
+)
+
+// MigrationMode is the mode that an upgrader is executed in
+type MigrationMode string
+
+const (
+	// MigrationModeUpgrade the upgrader is executed by migrate to the newest version
+	MigrationModeUpgrade MigrationMode = "upgrade"
+	// MigrationModeSpecify the upgrader is executed by migrate the specified version
+	MigrationModeSpecify MigrationMode = "specify"
+)
+
+// MigrationStatus is the execution status of an upgrader
+type MigrationStatus string
+
+const (
+	// MigrationStatusRunning the upgrader is running, or the admin server exits while it is running
+	MigrationStatusRunning MigrationStatus = "running"
+	// MigrationStatusSuccess the upgrader is executed successfully
+	MigrationStatusSuccess MigrationStatus = "success"
+	// MigrationStatusFailed the upgrader is failed
+	MigrationStatusFailed MigrationStatus = "failed"
+)
+
+// MigrationRecord is the execution record of an upgrader
+type MigrationRecord struct {
+	ID        int64           `json:"id" bson:"id"`
+	Version   string          `json:"version" bson:"version"`
+	Mode      MigrationMode   `json:"mode" bson:"mode"`
+	Status    MigrationStatus `json:"status" bson:"status"`
+	StartTime time.Time       `json:"start_time" bson:"start_time"`
+	EndTime   *time.Time      `json:"end_time,omitempty" bson:"end_time,omitempty"`
+	// Duration is the execution time of the upgrader in milliseconds
+	Duration int64  `json:"duration" bson:"duration"`
+	Error    string `json:"error" bson:"error"`
+}
+
+// ListMigrationRecordOption is the option to list the upgrader execution records
+type ListMigrationRecordOption struct {
+	Versions []string        `json:"versions"`
+	Status   MigrationStatus `json:"status"`
+	Page     BasePage        `json:"page"`
+}
+
+// Validate list migration record option
+func (l *ListMigrationRecordOption) Validate() error {
+	switch l.Status {
+	case "", MigrationStatusRunning, MigrationStatusSuccess, MigrationStatusFailed:
+	default:
+		return errors.New("status is invalid")
+	}
+
+	if len(l.Versions) > common.BKMaxPageSize {
+		return errors.New("versions exceed max length")
+	}
+
+	return l.Page.ValidateLimit(common.BKMaxPageSize)
+}
+
+// ListMigrationRecordResult is the result of list the upgrader execution records
+type ListMigrationRecordResult struct {
+	Count uint64            `json:"count"`
+	Info  []MigrationRecord `json:"info"`
+}
+
+// ListMigrationRecordResponse is the response of list the upgrader execution records
+type ListMigrationRecordResponse struct {
+	BaseResp `json:",inline"`
+	Data     ListMigrationRecordResult `json:"data"`
+}
+
+// MigrationDryRunResult is the result of the migration dry run, which lists the pending upgraders and the db
+// operations that each of them would do.
+type MigrationDryRunResult struct {
+	CurrentVersion string                `json:"current_version"`
+	Pending        []MigrationDryRunItem `json:"pending"`
+}
+
+// MigrationDryRunItem is the dry run result of a pending upgrader
+type MigrationDryRunItem struct {
+	Version     string               `json:"version"`
+	Collections []string             `json:"collections"`
+	Operations  []MigrationOperation `json:"operations"`
+	// Error is the error that the upgrader returns in dry run, the operations after it is returned are unknown.
+	Error string `json:"error,omitempty"`
+}
+
+// MigrationOperation is a kind of db operation that an upgrader would do on a collection
+type MigrationOperation struct {
+	Collection string `json:"collection"`
+	// Operation is the db operation, like insert, update, delete, create_index
+	Operation string `json:"operation"`
+	// Index is the name of the index that is created or dropped
+	Index string `json:"index,omitempty"`
+	// Times is the times that the operation is called
+	Times int64 `json:"times"`
+	// Documents is the estimated number of documents that are touched, it is counted by the current data
+	Documents int64 `json:"documents"`
+}
+
+// MigrationDryRunResponse is the response of the migration dry run
+type MigrationDryRunResponse struct {
+	BaseResp `json:",inline"`
+	Data     MigrationDryRunResult `json:"data"`
+}
@@ -0,0 +1,233 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metadata
+
+import (
+	"fmt"
+
+	"configcenter/src/common"
+	"configcenter/src/common/errors"
+)
+
+// ModelSchema declarative definition of models, it is usually kept in yaml files and used to diff with and apply to
+// the live models. models, groups, attributes and associations are identified by their string ids, not by db ids.
+type ModelSchema struct {
+	Classifications []SchemaClassification `json:"classifications"`
+	Models          []SchemaModel          `json:"models"`
+	Associations    []SchemaAssociation    `json:"associations"`
+}
+
+// SchemaClassification model classification definition in model schema
+type SchemaClassification struct {
+	ClassificationID   string `json:"bk_classification_id"`
+	ClassificationName string `json:"bk_classification_name"`
+	ClassificationIcon string `json:"bk_classification_icon"`
+}
+
+// SchemaModel model definition in model schema, with its groups, attributes and uniques
+type SchemaModel struct {
+	ObjectID   string            `json:"bk_obj_id"`
+	ObjectName string            `json:"bk_obj_name"`
+	ObjIcon    string            `json:"bk_obj_icon"`
+	ObjCls     string            `json:"bk_classification_id"`
+	Groups     []SchemaGroup     `json:"groups"`
+	Attributes []SchemaAttribute `json:"attributes"`
+	// Uniques every unique is a list of property ids
+	Uniques [][]string `json:"uniques"`
+}
+
+// SchemaGroup attribute group definition in model schema, nil fields are not managed by schema
+type SchemaGroup struct {
+	GroupID    string `json:"bk_group_id"`
+	GroupName  string `json:"bk_group_name"`
+	GroupIndex *int64 `json:"bk_group_index,omitempty"`
+	IsCollapse *bool  `json:"is_collapse,omitempty"`
+}
+
+// SchemaAttribute attribute definition in model schema, nil fields are not managed by schema
+type SchemaAttribute struct {
+	PropertyID    string      `json:"bk_property_id"`
+	PropertyName  string      `json:"bk_property_name"`
+	PropertyGroup string      `json:"bk_property_group"`
+	PropertyType  string      `json:"bk_property_type"`
+	Unit          string      `json:"unit"`
+	Placeholder   string      `json:"placeholder"`
+	Description   string      `json:"description"`
+	IsEditable    *bool       `json:"editable,omitempty"`
+	IsRequired    *bool       `json:"isrequired,omitempty"`
+	IsReadOnly    *bool       `json:"isreadonly,omitempty"`
+	Option        interface{} `json:"option,omitempty"`
+}
+
+// SchemaAssociation model association definition in model schema
+type SchemaAssociation struct {
+	AssociationName      string                    `json:"bk_obj_asst_id"`
+	AssociationAliasName string                    `json:"bk_obj_asst_name"`
+	ObjectID             string                    `json:"bk_obj_id"`
+	AsstObjID            string                    `json:"bk_asst_obj_id"`
+	AsstKindID           string                    `json:"bk_asst_id"`
+	Mapping              AssociationMapping        `json:"mapping"`
+	OnDelete             AssociationOnDeleteAction `json:"on_delete"`
+}
+
+// Validate validate model schema, check required fields and duplicate ids
+func (s *ModelSchema) Validate() errors.RawErrorInfo {
+	clsIDs := make(map[string]struct{})
+	for _, cls := range s.Classifications {
+		if len(cls.ClassificationID) == 0 {
+			return errors.RawErrorInfo{ErrCode: common.CCErrCommParamsNeedSet,
+				Args: []interface{}{common.BKClassificationIDField}}
+		}
+		if _, exists := clsIDs[cls.ClassificationID]; exists {
+			return errors.RawErrorInfo{ErrCode: common.CCErrCommDuplicateItem, Args: []interface{}{cls.ClassificationID}}
+		}
+		clsIDs[cls.ClassificationID] = struct{}{}
+	}
+
+	objIDs := make(map[string]struct{})
+	for _, model := range s.Models {
+		if len(model.ObjectID) == 0 {
+			return errors.RawErrorInfo{ErrCode: common.CCErrCommParamsNeedSet, Args: []interface{}{common.BKObjIDField}}
+		}
+		if _, exists := objIDs[model.ObjectID]; exists {
+			return errors.RawErrorInfo{ErrCode: common.CCErrCommDuplicateItem, Args: []interface{}{model.ObjectID}}
+		}
+		objIDs[model.ObjectID] = struct{}{}
+
+		if err := model.validate(); err.ErrCode != 0 {
+			return err
+		}
+	}
+
+	asstIDs := make(map[string]struct{})
+	for _, asst := range s.Associations {
+		if len(asst.AssociationName) == 0 || len(asst.ObjectID) == 0 || len(asst.AsstObjID) == 0 ||
+			len(asst.AsstKindID) == 0 {
+			return errors.RawErrorInfo{ErrCode: common.CCErrCommParamsNeedSet, Args: []interface{}{fmt.Sprintf(
+				"association %s: %s, %s, %s, %s", asst.AssociationName, common.AssociationObjAsstIDField,
+				common.BKObjIDField, common.BKAsstObjIDField, common.AssociationKindIDField)}}
+		}
+		if asst.AsstKindID == common.AssociationKindMainline {
+			return errors.RawErrorInfo{ErrCode: common.CCErrCommParamsInvalid, Args: []interface{}{
+				fmt.Sprintf("association %s: %s", asst.AssociationName, common.AssociationKindIDField)}}
+		}
+		if _, exists := asstIDs[asst.AssociationName]; exists {
+			return errors.RawErrorInfo{ErrCode: common.CCErrCommDuplicateItem,
+				Args: []interface{}{asst.AssociationName}}
+		}
+		asstIDs[asst.AssociationName] = struct{}{}
+	}
+
+	return errors.RawErrorInfo{}
+}
+
+func (m *SchemaModel) validate() errors.RawErrorInfo {
+	groupIDs := make(map[string]struct{})
+	for _, group := range m.Groups {
+		if len(group.GroupID) == 0 || len(group.GroupName) == 0 {
+			return errors.RawErrorInfo{ErrCode: common.CCErrCommParamsNeedSet, Args: []interface{}{
+				fmt.Sprintf("%s group: %s, %s", m.ObjectID, common.BKPropertyGroupIDField,
+					common.BKPropertyGroupNameField)}}
+		}
+		if _, exists := groupIDs[group.GroupID]; exists {
+			return errors.RawErrorInfo{ErrCode: common.CCErrCommDuplicateItem,
+				Args: []interface{}{m.ObjectID + "." + group.GroupID}}
+		}
+		groupIDs[group.GroupID] = struct{}{}
+	}
+
+	propertyIDs := make(map[string]struct{})
+	for _, attr := range m.Attributes {
+		if len(attr.PropertyID) == 0 || len(attr.PropertyName) == 0 || len(attr.PropertyType) == 0 {
+			return errors.RawErrorInfo{ErrCode: common.CCErrCommParamsNeedSet, Args: []interface{}{
+				fmt.Sprintf("%s attribute: %s, %s, %s", m.ObjectID, common.BKPropertyIDField,
+					common.BKPropertyNameField, common.BKPropertyTypeField)}}
+		}
+		if _, exists := propertyIDs[attr.PropertyID]; exists {
+			return errors.RawErrorInfo{ErrCode: common.CCErrCommDuplicateItem,
+				Args: []interface{}{m.ObjectID + "." + attr.PropertyID}}
+		}
+		propertyIDs[attr.PropertyID] = struct{}{}
+	}
+
+	for _, keys := range m.Uniques {
+		if len(keys) == 0 {
+			return errors.RawErrorInfo{ErrCode: common.CCErrCommParamsNeedSet,
+				Args: []interface{}{m.ObjectID + " unique keys"}}
+		}
+	}
+
+	return errors.RawErrorInfo{}
+}
+
+// ModelSchemaAction the action of a model schema change
+type ModelSchemaAction string
+
+const (
+	// ModelSchemaCreate the resource is declared in schema but not exists
+	ModelSchemaCreate ModelSchemaAction = "create"
+	// ModelSchemaUpdate the resource exists but some of its fields are different from schema
+	ModelSchemaUpdate ModelSchemaAction = "update"
+	// ModelSchemaDelete the resource exists but is not declared in schema
+	ModelSchemaDelete ModelSchemaAction = "delete"
+)
+
+// ModelSchemaKind the resource kind of a model schema change
+type ModelSchemaKind string
+
+const (
+	// ModelSchemaKindClassification model classification
+	ModelSchemaKindClassification ModelSchemaKind = "classification"
+	// ModelSchemaKindModel model
+	ModelSchemaKindModel ModelSchemaKind = "model"
+	// ModelSchemaKindGroup model attribute group
+	ModelSchemaKindGroup ModelSchemaKind = "group"
+	// ModelSchemaKindAttribute model attribute
+	ModelSchemaKindAttribute ModelSchemaKind = "attribute"
+	// ModelSchemaKindUnique model unique
+	ModelSchemaKindUnique ModelSchemaKind = "unique"
+	// ModelSchemaKindAssociation model association
+	ModelSchemaKindAssociation ModelSchemaKind = "association"
+)
+
+// ModelSchemaChange a change that is needed to make the live models consistent with the schema
+type ModelSchemaChange struct {
+	Action   ModelSchemaAction `json:"action"`
+	Kind     ModelSchemaKind   `json:"kind"`
+	ObjectID string            `json:"bk_obj_id,omitempty"`
+	// Key the string id of the changed resource, unique key is its property ids joined by comma
+	Key string `json:"key"`
+	// ID the db id of the changed resource, it is empty for create action
+	ID int64 `json:"id,omitempty"`
+	// Fields the changed fields of update action
+	Fields []string `json:"fields,omitempty"`
+}
+
+// ModelSchemaOption diff or apply model schema option
+type ModelSchemaOption struct {
+	Schema ModelSchema `json:"schema"`
+	// AllowDelete whether to delete the groups, attributes and uniques of the declared models and the associations
+	// of the declared models that are not declared in schema, preset ones and models are never deleted.
+	AllowDelete bool `json:"allow_delete"`
+}
+
+// ModelSchemaResult model schema diff or apply result
+type ModelSchemaResult struct {
+	Changes []ModelSchemaChange `json:"changes"`
+}
+
+// ModelSchemaResponse model schema diff or apply response
+type ModelSchemaResponse struct {
+	BaseResp `json:",inline"`
+	Data     ModelSchemaResult `json:"data"`
+}
@@ -0,0 +1,202 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metadata
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron"
+)
+
+// TaskCronJobOverlapPolicy defines how to handle a cron job run when the task created by its previous run,
+// or any other task of the same task type and instance, is not finished yet.
+type TaskCronJobOverlapPolicy string
+
+const (
+	// CronJobOverlapSkip skip this run and wait for the next scheduled time, it is the default policy
+	CronJobOverlapSkip TaskCronJobOverlapPolicy = "skip"
+	// CronJobOverlapDelay delay this run until the unfinished task is finished, the delayed runs are merged into one
+	CronJobOverlapDelay TaskCronJobOverlapPolicy = "delay"
+)
+
+// Validate cron job overlap policy
+func (p TaskCronJobOverlapPolicy) Validate() error {
+	switch p {
+	case CronJobOverlapSkip, CronJobOverlapDelay:
+		return nil
+	default:
+		return fmt.Errorf("unsupported overlap_policy %s", p)
+	}
+}
+
+// TaskCronJobRunStatus is the result status of a cron job run
+type TaskCronJobRunStatus string
+
+const (
+	// CronJobRunCreated the task of the run is created successfully
+	CronJobRunCreated TaskCronJobRunStatus = "created"
+	// CronJobRunSkipped the run is skipped because of the overlap policy
+	CronJobRunSkipped TaskCronJobRunStatus = "skipped"
+	// CronJobRunFailed the task of the run is failed to create
+	CronJobRunFailed TaskCronJobRunStatus = "failed"
+)
+
+// TaskCronJob is a named job that creates an api task periodically by the cron expression
+type TaskCronJob struct {
+	ID   int64  `json:"id" bson:"id"`
+	Name string `json:"name" bson:"name"`
+	// Spec is the standard cron expression with 5 fields: minute, hour, day of month, month and day of week.
+	// predefined schedules like @daily and intervals like @every 1h30m are also supported.
+	Spec string `json:"spec" bson:"spec"`
+	// TaskType is the task queue name that the created task belongs to, must be a task type defined in code
+	TaskType string `json:"task_type" bson:"task_type"`
+	// InstID is the instance id that the created task is related to
+	InstID int64 `json:"bk_inst_id" bson:"bk_inst_id"`
+	// Data is the sub task data of the created task
+	Data          []interface{}            `json:"data" bson:"data"`
+	Enabled       bool                     `json:"enabled" bson:"enabled"`
+	OverlapPolicy TaskCronJobOverlapPolicy `json:"overlap_policy" bson:"overlap_policy"`
+	// Header is the http header of the user who saves the job, the tasks are created with it
+	Header http.Header `json:"-" bson:"header"`
+	// NextRunTime is the next time that the job is scheduled to run
+	NextRunTime time.Time `json:"next_run_time" bson:"next_run_time"`
+	// LastRun is the result of the last run of the job
+	LastRun         *TaskCronJobRun `json:"last_run,omitempty" bson:"last_run,omitempty"`
+	SupplierAccount string          `json:"bk_supplier_account" bson:"bk_supplier_account"`
+	Creator         string          `json:"creator" bson:"creator"`
+	Modifier        string          `json:"modifier" bson:"modifier"`
+	CreateTime      time.Time       `json:"create_time" bson:"create_time"`
+	LastTime        time.Time       `json:"last_time" bson:"last_time"`
+}
+
+// TaskCronJobRun is the run history of a cron job
+type TaskCronJobRun struct {
+	JobID  int64                `json:"job_id" bson:"job_id"`
+	Status TaskCronJobRunStatus `json:"status" bson:"status"`
+	// TaskID is the id of the created task, it is empty if the task is not created
+	TaskID  string `json:"task_id" bson:"task_id"`
+	Message string `json:"message" bson:"message"`
+	// ScheduleTime is the time that the run is scheduled, CreateTime is the time that the run actually happens
+	ScheduleTime    time.Time `json:"schedule_time" bson:"schedule_time"`
+	SupplierAccount string    `json:"bk_supplier_account" bson:"bk_supplier_account"`
+	CreateTime      time.Time `json:"create_time" bson:"create_time"`
+}
+
+// ParseCronJobSpec parse the cron expression of the cron job
+func ParseCronJobSpec(spec string) (cron.Schedule, error) {
+	if len(strings.TrimSpace(spec)) == 0 {
+		return nil, errors.New("spec is not set")
+	}
+
+	schedule, err := cron.ParseStandard(spec)
+	if err != nil {
+		return nil, fmt.Errorf("spec %s is invalid, err: %v", spec, err)
+	}
+	return schedule, nil
+}
+
+// CreateTaskCronJobOption is the option to create a cron job
+type CreateTaskCronJobOption struct {
+	Name          string                   `json:"name"`
+	Spec          string                   `json:"spec"`
+	TaskType      string                   `json:"task_type"`
+	InstID        int64                    `json:"bk_inst_id"`
+	Data          []interface{}            `json:"data"`
+	OverlapPolicy TaskCronJobOverlapPolicy `json:"overlap_policy"`
+	// Disabled create the job without scheduling it
+	Disabled bool `json:"disabled"`
+}
+
+// Validate create cron job option
+func (c *CreateTaskCronJobOption) Validate() error {
+	if len(c.Name) == 0 {
+		return errors.New("name is not set")
+	}
+
+	if _, err := ParseCronJobSpec(c.Spec); err != nil {
+		return err
+	}
+
+	if len(c.TaskType) == 0 {
+		return errors.New("task_type is not set")
+	}
+
+	if len(c.Data) == 0 {
+		return errors.New("data is not set")
+	}
+
+	if len(c.OverlapPolicy) == 0 {
+		c.OverlapPolicy = CronJobOverlapSkip
+	}
+	return c.OverlapPolicy.Validate()
+}
+
+// UpdateTaskCronJobOption is the option to update a cron job, the task type of the job can not be changed.
+type UpdateTaskCronJobOption struct {
+	Name          *string                   `json:"name"`
+	Spec          *string                   `json:"spec"`
+	InstID        *int64                    `json:"bk_inst_id"`
+	Data          []interface{}             `json:"data"`
+	OverlapPolicy *TaskCronJobOverlapPolicy `json:"overlap_policy"`
+	Enabled       *bool                     `json:"enabled"`
+}
+
+// Validate update cron job option
+func (u *UpdateTaskCronJobOption) Validate() error {
+	if u.Name != nil && len(*u.Name) == 0 {
+		return errors.New("name can not be empty")
+	}
+
+	if u.Spec != nil {
+		if _, err := ParseCronJobSpec(*u.Spec); err != nil {
+			return err
+		}
+	}
+
+	if u.Data != nil && len(u.Data) == 0 {
+		return errors.New("data can not be empty")
+	}
+
+	if u.OverlapPolicy != nil {
+		return u.OverlapPolicy.Validate()
+	}
+	return nil
+}
+
+// ListTaskCronJobOption is the option to list cron jobs
+type ListTaskCronJobOption struct {
+	IDs      []int64  `json:"ids"`
+	TaskType string   `json:"task_type"`
+	Page     BasePage `json:"page"`
+}
+
+// ListTaskCronJobResult is the result of list cron jobs
+type ListTaskCronJobResult struct {
+	Count uint64        `json:"count"`
+	Info  []TaskCronJob `json:"info"`
+}
+
+// ListTaskCronJobHistoryOption is the option to list the run history of a cron job
+type ListTaskCronJobHistoryOption struct {
+	Page BasePage `json:"page"`
+}
+
+// ListTaskCronJobHistoryResult is the result of list the run history of a cron job
+type ListTaskCronJobHistoryResult struct {
+	Count uint64           `json:"count"`
+	Info  []TaskCronJobRun `json:"info"`
+}
@@ -27,6 +27,9 @@ type CreateTaskRequest struct {
 	// bk_inst_id 实例id，该任务关联的实例id
 	InstID int64 `json:"bk_inst_id"`
 
+	// Priority 任务优先级，优先级高的任务优先执行，相同优先级的任务按创建时间顺序执行，默认为0
+	Priority int `json:"priority"`
+
 	Data []interface{} `json:"data"`
 }
 
@@ -44,6 +47,8 @@ type APITaskDetail struct {
 	Header http.Header `json:"header,omitempty" bson:"header"`
 	// Status 任务执行状态
 	Status APITaskStatus `json:"status,omitempty" bson:"status"`
+	// Priority 任务优先级，优先级高的任务优先执行
+	Priority int `json:"priority" bson:"priority"`
 	// Detail 子任务详情列表
 	Detail []APISubTaskDetail `json:"detail,omitempty" bson:"detail"`
 
@@ -86,7 +91,7 @@ type APITaskStatus string
 
 // IsFinished TODO
 func (s APITaskStatus) IsFinished() bool {
-	if s == APITaskStatusSuccess || s == APITAskStatusFail {
+	if s == APITaskStatusSuccess || s == APITAskStatusFail || s == APITaskStatusCanceled {
 		return true
 	}
 	return false
@@ -125,8 +130,29 @@ const (
 
 	// APITAskStatusNeedSync only used for instance with all tasks finished but actual status is not finished
 	APITAskStatusNeedSync APITaskStatus = "need_sync"
+
+	// APITaskStatusCanceled task is canceled, the sub tasks that are not executed will not be executed
+	APITaskStatusCanceled APITaskStatus = "canceled"
+
+	// APITaskStatusPaused task is paused, the sub tasks that are not executed will be executed after it is resumed
+	APITaskStatusPaused APITaskStatus = "paused"
 )
 
+// CanChangeTo returns if the task status can be changed to the target status by the user
+func (s APITaskStatus) CanChangeTo(target APITaskStatus) bool {
+	switch target {
+	case APITaskStatusCanceled:
+		return s == APITaskStatusNew || s == APITaskStatusWaitExecute || s == APITaskStatusExecute ||
+			s == APITaskStatusPaused
+	case APITaskStatusPaused:
+		return s == APITaskStatusNew || s == APITaskStatusWaitExecute || s == APITaskStatusExecute
+	case APITaskStatusWaitExecute:
+		return s == APITaskStatusPaused
+	default:
+		return false
+	}
+}
+
 // ListAPITaskRequest TODO
 type ListAPITaskRequest struct {
 	Condition mapstr.MapStr `json:"condition"`
@@ -68,6 +68,21 @@ type LoginUserPluginInerface interface {
 	GetUserList(c *gin.Context, config map[string]string) ([]*LoginSystemUserInfo, *errors.RawErrorInfo)
 }
 
+// LoginUserAuthenticator is implemented by the login plugins that verify the username and password submitted by the
+// login page with their own user system instead of the webServer.session.userInfo config.
+type LoginUserAuthenticator interface {
+	// AuthenticateUser returns false if the username or password is wrong, returns error if the user system fails.
+	// the returned login name is the name of the user in the user system, it is used as the login user.
+	AuthenticateUser(c *gin.Context, userName, password string) (string, bool, *errors.RawErrorInfo)
+}
+
+// LoginCallbackHandler is implemented by the login plugins that redirect the user to an external identity provider,
+// the provider redirects the user back to /login/callback after the user is authenticated.
+type LoginCallbackHandler interface {
+	// LoginCallback handles the callback request and saves the login user, returns the url to redirect to
+	LoginCallback(c *gin.Context) (string, *errors.RawErrorInfo)
+}
+
 // LoginSystemUserInfo TODO
 type LoginSystemUserInfo struct {
 	CnName string `json:"chinese_name"`
@@ -0,0 +1,205 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package registerdiscover
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"configcenter/src/common/backbone/service_mange/etcd"
+	"configcenter/src/common/blog"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// minEtcdLeaseTTL is the minimum ttl of the register lease in seconds
+const minEtcdLeaseTTL = 5
+
+// EtcdRegDiscv do register and discover by etcd
+type EtcdRegDiscv struct {
+	client       *etcd.EtcdClient
+	cancel       context.CancelFunc
+	rootCxt      context.Context
+	leaseTTL     int64
+	leaseID      clientv3.LeaseID
+	registerPath string
+	sync.Mutex
+}
+
+// NewEtcdRegDiscv create a object of EtcdRegDiscv
+func NewEtcdRegDiscv(client *etcd.EtcdClient) *EtcdRegDiscv {
+	ctx, ctxCancel := client.WithCancel()
+	ttl := int64(client.SessionTimeOut() / time.Second)
+	if ttl < minEtcdLeaseTTL {
+		ttl = minEtcdLeaseTTL
+	}
+
+	return &EtcdRegDiscv{
+		client:   client,
+		cancel:   ctxCancel,
+		rootCxt:  ctx,
+		leaseTTL: ttl,
+	}
+}
+
+// RegisterAndWatch put the server info with a lease and keep the lease alive. if the lease is lost, register again
+func (e *EtcdRegDiscv) RegisterAndWatch(path string, data []byte) error {
+	blog.Infof("register server and watch it. path(%s), data(%s)", path, string(data))
+	go func() {
+		for {
+			keepAlive, err := e.register(path, data)
+			if err != nil {
+				blog.Errorf("fail to register server node(%s), err: %v", path, err)
+				select {
+				case <-e.rootCxt.Done():
+					blog.Infof("register node(%s) is canceled, now exist service register.", path)
+					return
+				case <-time.After(time.Second):
+				}
+				continue
+			}
+
+			// consume the keep alive response until the lease is expired or the register is canceled
+			for range keepAlive {
+			}
+
+			select {
+			case <-e.rootCxt.Done():
+				blog.Infof("watch register node(%s) done, now exist service register.", path)
+				return
+			case <-time.After(time.Second):
+				blog.Errorf("register node(%s) lease is lost, try to register again", e.registerPath)
+			}
+		}
+	}()
+
+	blog.Infof("finish register server node(%s) and watch it", path)
+	return nil
+}
+
+func (e *EtcdRegDiscv) register(path string, data []byte) (<-chan *clientv3.LeaseKeepAliveResponse, error) {
+	cli := e.client.Client()
+	lease, err := cli.Grant(e.rootCxt, e.leaseTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	// use the lease id as the node name, so that it is unique just like the zookeeper sequence node.
+	registerPath := fmt.Sprintf("%s/%016x", strings.TrimSuffix(path, "/"), int64(lease.ID))
+	if _, err := cli.Put(e.rootCxt, registerPath, string(data), clientv3.WithLease(lease.ID)); err != nil {
+		return nil, err
+	}
+
+	keepAlive, err := cli.KeepAlive(e.rootCxt, lease.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	e.Lock()
+	e.leaseID = lease.ID
+	e.registerPath = registerPath
+	e.Unlock()
+	return keepAlive, nil
+}
+
+// GetServNodes get server nodes by path
+func (e *EtcdRegDiscv) GetServNodes(path string) ([]string, error) {
+	return e.client.GetChildren(path)
+}
+
+// Ping to ping server
+func (e *EtcdRegDiscv) Ping() error {
+	return e.client.Ping()
+}
+
+// Discover watch the children of the path
+func (e *EtcdRegDiscv) Discover(path string) (<-chan *DiscoverEvent, error) {
+	blog.Infof("begin to discover by watch children of path(%s)", path)
+	env := make(chan *DiscoverEvent, 1)
+
+	go func() {
+		for {
+			env <- e.getServerInfoByPath(path)
+
+			watchCh := e.client.Client().Watch(e.rootCxt, strings.TrimSuffix(path, "/")+"/", clientv3.WithPrefix())
+			for resp := range watchCh {
+				if err := resp.Err(); err != nil {
+					blog.Errorf("watch children of path(%s) failed, err: %v", path, err)
+					break
+				}
+				env <- e.getServerInfoByPath(path)
+			}
+
+			select {
+			case <-e.rootCxt.Done():
+				blog.Infof("discover path(%s) done", path)
+				return
+			default:
+				// the watch channel is closed unexpectedly, watch again after a while
+				time.Sleep(time.Second)
+			}
+		}
+	}()
+
+	return env, nil
+}
+
+func (e *EtcdRegDiscv) getServerInfoByPath(path string) *DiscoverEvent {
+	for {
+		nodes, servers, err := e.client.GetChildrenData(path)
+		if err != nil {
+			blog.Errorf("get children of path(%s) failed, will retry after 5s, err: %v", path, err)
+			select {
+			case <-e.rootCxt.Done():
+				return &DiscoverEvent{Key: path, Err: err}
+			case <-time.After(5 * time.Second):
+			}
+			continue
+		}
+
+		return &DiscoverEvent{
+			Key:    path,
+			Nodes:  nodes,
+			Server: servers,
+		}
+	}
+}
+
+// Cancel to stop server register and discover
+func (e *EtcdRegDiscv) Cancel() {
+	e.Lock()
+	leaseID := e.leaseID
+	e.Unlock()
+
+	if leaseID != clientv3.NoLease {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if _, err := e.client.Client().Revoke(ctx, leaseID); err != nil {
+			blog.Errorf("revoke register lease %x failed, err: %v", int64(leaseID), err)
+		}
+	}
+	e.cancel()
+}
+
+// ClearRegisterPath to delete server register path from etcd
+func (e *EtcdRegDiscv) ClearRegisterPath() error {
+	e.Lock()
+	defer e.Unlock()
+	if e.registerPath == "" {
+		return nil
+	}
+	return e.client.Del(e.registerPath)
+}
@@ -0,0 +1,194 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package registerdiscover
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"configcenter/src/common/backbone/service_mange/file"
+	"configcenter/src/common/blog"
+)
+
+const (
+	// fileHeartbeatInterval is the interval that the registered node data is refreshed
+	fileHeartbeatInterval = 2 * time.Second
+	// fileNodeTTL is the time after which a node without heartbeat is regarded as dead
+	fileNodeTTL = 5 * fileHeartbeatInterval
+)
+
+// FileRegDiscv do register and discover by local directory. since the file node is not ephemeral,
+// the registered node is refreshed by heartbeat, and the node that is not refreshed in time is ignored.
+type FileRegDiscv struct {
+	client       *file.FileClient
+	cancel       context.CancelFunc
+	rootCxt      context.Context
+	registerPath string
+	sync.Mutex
+}
+
+// NewFileRegDiscv create a object of FileRegDiscv
+func NewFileRegDiscv(client *file.FileClient) *FileRegDiscv {
+	ctx, ctxCancel := client.WithCancel()
+	return &FileRegDiscv{
+		client:  client,
+		cancel:  ctxCancel,
+		rootCxt: ctx,
+	}
+}
+
+// RegisterAndWatch create the node for the service and refresh it by heartbeat. if it is removed, register again
+func (f *FileRegDiscv) RegisterAndWatch(path string, data []byte) error {
+	blog.Infof("register server and watch it. path(%s), data(%s)", path, string(data))
+	go func() {
+		ticker := time.NewTicker(fileHeartbeatInterval)
+		defer ticker.Stop()
+
+		for {
+			f.Lock()
+			// check if the register is canceled with lock, so that the cleared node will not be registered again
+			if f.rootCxt.Err() != nil {
+				f.Unlock()
+				blog.Infof("watch register node(%s) done, now exist service register.", path)
+				return
+			}
+
+			if f.registerPath == "" {
+				registerPath, err := f.client.CreateSeq(path, data)
+				if err != nil {
+					blog.Errorf("fail to register server node(%s), err: %v", path, err)
+				}
+				f.registerPath = registerPath
+			} else {
+				if _, err := f.client.Get(f.registerPath); err != nil {
+					blog.Errorf("node %s doesn't exist, try to create a new one, err: %v", f.registerPath, err)
+					f.registerPath = ""
+					f.Unlock()
+					continue
+				}
+
+				// refresh the node as heartbeat
+				if err := f.client.Put(f.registerPath, data); err != nil {
+					blog.Errorf("refresh register node(%s) failed, err: %v", f.registerPath, err)
+				}
+			}
+			f.Unlock()
+
+			select {
+			case <-f.rootCxt.Done():
+				blog.Infof("watch register node(%s) done, now exist service register.", path)
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	blog.Infof("finish register server node(%s) and watch it", path)
+	return nil
+}
+
+// GetServNodes get the alive server nodes by path
+func (f *FileRegDiscv) GetServNodes(path string) ([]string, error) {
+	event, err := f.getServerInfoByPath(path)
+	if err != nil {
+		return nil, err
+	}
+	return event.Nodes, nil
+}
+
+// Ping to ping server
+func (f *FileRegDiscv) Ping() error {
+	return f.client.Ping()
+}
+
+// Discover loop compare the children of the path, and send the event when it is changed
+func (f *FileRegDiscv) Discover(path string) (<-chan *DiscoverEvent, error) {
+	blog.Infof("begin to discover by loop compare children of path(%s)", path)
+	env := make(chan *DiscoverEvent, 1)
+
+	go func() {
+		var previous []string
+		for {
+			event, err := f.getServerInfoByPath(path)
+			if err != nil && err != file.ErrNoNode {
+				blog.Errorf("get children of path(%s) failed, err: %v", path, err)
+			} else if previous == nil || !reflect.DeepEqual(previous, event.Server) {
+				previous = event.Server
+				env <- event
+			}
+
+			select {
+			case <-f.rootCxt.Done():
+				blog.Infof("discover path(%s) done", path)
+				return
+			case <-time.After(time.Second):
+			}
+		}
+	}()
+
+	return env, nil
+}
+
+func (f *FileRegDiscv) getServerInfoByPath(path string) (*DiscoverEvent, error) {
+	event := &DiscoverEvent{
+		Key:    path,
+		Nodes:  make([]string, 0),
+		Server: make([]string, 0),
+	}
+
+	children, err := f.client.GetChildren(path)
+	if err != nil {
+		return event, err
+	}
+
+	now := time.Now()
+	for _, child := range children {
+		nodePath := strings.TrimSuffix(path, "/") + "/" + child
+		modTime, err := f.client.ModTime(nodePath)
+		if err != nil {
+			continue
+		}
+
+		if now.Sub(modTime) > fileNodeTTL {
+			blog.V(4).Infof("node %s has no heartbeat since %s, skip it", nodePath, modTime)
+			continue
+		}
+
+		data, err := f.client.Get(nodePath)
+		if err != nil {
+			continue
+		}
+		event.Nodes = append(event.Nodes, child)
+		event.Server = append(event.Server, data)
+	}
+
+	return event, nil
+}
+
+// Cancel to stop server register and discover
+func (f *FileRegDiscv) Cancel() {
+	f.cancel()
+}
+
+// ClearRegisterPath to delete server register path from the directory
+func (f *FileRegDiscv) ClearRegisterPath() error {
+	f.Lock()
+	defer f.Unlock()
+	if f.registerPath == "" {
+		return nil
+	}
+	return f.client.Del(f.registerPath)
+}
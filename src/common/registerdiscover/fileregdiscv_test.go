@@ -0,0 +1,82 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package registerdiscover
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"configcenter/src/common/backbone/service_mange/regdiscv"
+)
+
+func TestFileRegDiscv(t *testing.T) {
+	dir, err := ioutil.TempDir("", "regdiscv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	client, err := regdiscv.NewClient("file://"+dir, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Stop()
+
+	first := NewRegDiscoverWithClient(client)
+	second := NewRegDiscoverWithClient(client)
+	defer first.Cancel()
+	defer second.Cancel()
+
+	path := "/cc/services/endpoints/test"
+	if err := first.RegisterAndWatchService(path, []byte("first")); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if err := second.RegisterAndWatchService(path, []byte("second")); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	events, err := first.DiscoverService(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case event := <-events:
+		// the earliest registered server must be the first one, so that it is elected as master
+		if len(event.Server) != 2 || event.Server[0] != "first" || event.Server[1] != "second" {
+			t.Fatalf("discover servers %v are not the registered ones", event.Server)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("discover servers timeout")
+	}
+
+	first.Cancel()
+	if err := first.ClearRegisterPath(); err != nil {
+		t.Fatal(err)
+	}
+
+	nodes, err := second.GetServNodes(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != 1 {
+		t.Fatalf("the cleared server is still discovered, nodes: %v", nodes)
+	}
+}
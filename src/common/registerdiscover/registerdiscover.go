@@ -16,6 +16,7 @@ package registerdiscover
 import (
 	"time"
 
+	"configcenter/src/common/backbone/service_mange/regdiscv"
 	"configcenter/src/common/backbone/service_mange/zk"
 )
 
@@ -54,6 +55,24 @@ func NewRegDiscoverEx(client *zk.ZkClient) *RegDiscover {
 	return regDiscv
 }
 
+// NewRegDiscoverWithClient used to create a object of RegDiscover with the backend that client is connected to
+func NewRegDiscoverWithClient(client *regdiscv.Client) *RegDiscover {
+	regDiscv := &RegDiscover{
+		rdServer: nil,
+	}
+
+	switch client.Type() {
+	case regdiscv.Etcd:
+		regDiscv.rdServer = RegDiscvServer(NewEtcdRegDiscv(client.Etcd()))
+	case regdiscv.File:
+		regDiscv.rdServer = RegDiscvServer(NewFileRegDiscv(client.File()))
+	default:
+		regDiscv.rdServer = RegDiscvServer(NewZkRegDiscv(client.Zk()))
+	}
+
+	return regDiscv
+}
+
 // RegisterAndWatchService register service info into register-discover platform
 // and then watch the service info, if not exist, then register again
 // key is the index of registered service
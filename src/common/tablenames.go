@@ -105,6 +105,42 @@ const (
 
 	// BKTableNameMainlineInstance is a virtual collection name which represent for mainline instance events
 	BKTableNameMainlineInstance = "cc_MainlineInstance"
+
+	// BKTableNameEventSubscription the table to store the webhook subscriptions of the watch events
+	BKTableNameEventSubscription = "cc_EventSubscription"
+
+	// BKTableNameSubscriptionDeadLetter the table to store the events that are failed to push to the subscriptions
+	BKTableNameSubscriptionDeadLetter = "cc_EventSubscriptionDeadLetter"
+
+	// BKTableNameAPITaskCronJob the table to store the cron jobs that create api tasks periodically
+	BKTableNameAPITaskCronJob = "cc_APITaskCronJob"
+
+	// BKTableNameAPITaskCronJobHistory the table to store the run history of the api task cron jobs
+	BKTableNameAPITaskCronJobHistory = "cc_APITaskCronJobHistory"
+
+	// BKTableNameMigrationRecord the table to store the execution records of the admin server upgraders
+	BKTableNameMigrationRecord = "cc_MigrationRecord"
+
+	// BKTableNameDynamicGroupMember the table to store the materialized members of the watched dynamic groups
+	BKTableNameDynamicGroupMember = "cc_DynamicGroupMember"
+
+	// BKTableNameLocalAuthRole the table to store the roles of the local rbac authorizer
+	BKTableNameLocalAuthRole = "cc_LocalAuthRole"
+
+	// BKTableNameLocalAuthGroup the table to store the user groups of the local rbac authorizer
+	BKTableNameLocalAuthGroup = "cc_LocalAuthGroup"
+
+	// BKTableNameLocalAuthRoleBinding the table to store the role bindings of the local rbac authorizer
+	BKTableNameLocalAuthRoleBinding = "cc_LocalAuthRoleBinding"
+
+	// BKTableNameAuditLogChain the table to store the sealer cursor and the hash chain heads of the audit logs
+	BKTableNameAuditLogChain = "cc_AuditLogChain"
+
+	// BKTableNameAuditLogCheckpoint the table to store the signed checkpoints of the audit log hash chains
+	BKTableNameAuditLogCheckpoint = "cc_AuditLogCheckpoint"
+
+	// BKTableNameAuditLogExportCursor the table to store the progress of the audit log exporters
+	BKTableNameAuditLogExportCursor = "cc_AuditLogExportCursor"
 )
 
 // AllTables is all table names, not include the sharding tables which is created dynamically,
@@ -101,6 +101,9 @@ const (
 	KubeWorkload CursorType = "kube_workload"
 	// KubePod cursor type, its event detail is pod info with containers in it
 	KubePod CursorType = "kube_pod"
+	// DynamicGroupMember a mixed event type containing host, host relation & dynamic group events, which are converted
+	// to the membership change events of the watched host dynamic groups
+	DynamicGroupMember CursorType = "dynamic_group_member"
 )
 
 // ToInt TODO
@@ -146,6 +149,8 @@ func (ct CursorType) ToInt() int {
 		return 20
 	case KubePod:
 		return 21
+	case DynamicGroupMember:
+		return 22
 	default:
 		return -1
 	}
@@ -194,6 +199,8 @@ func (ct *CursorType) ParseInt(typ int) {
 		*ct = KubeWorkload
 	case 21:
 		*ct = KubePod
+	case 22:
+		*ct = DynamicGroupMember
 	default:
 		*ct = UnknownType
 	}
@@ -203,7 +210,7 @@ func (ct *CursorType) ParseInt(typ int) {
 func ListCursorTypes() []CursorType {
 	return []CursorType{Host, ModuleHostRelation, Biz, Set, Module, ObjectBase, Process, ProcessInstanceRelation,
 		HostIdentifier, MainlineInstance, InstAsst, BizSet, BizSetRelation, Plat, KubeCluster, KubeNode, KubeNamespace,
-		KubeWorkload, KubePod}
+		KubeWorkload, KubePod, DynamicGroupMember}
 }
 
 // Cursor is a self-defined token which is corresponding to the mongodb's resume token.
@@ -0,0 +1,296 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package watch
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+
+	"configcenter/src/common"
+	"configcenter/src/common/metadata"
+)
+
+const (
+	// DefaultSubscriptionBatchSize is the default max number of events that is pushed to the callback in one request
+	DefaultSubscriptionBatchSize = 100
+	// MaxSubscriptionBatchSize is the max number of events that is pushed to the callback in one request
+	MaxSubscriptionBatchSize = 200
+
+	// SubscriptionSignatureHeader is the http header that contains the hmac-sha256 signature of the pushed payload,
+	// the signature is calculated with the subscription secret by "{timestamp}.{request body}" in hex format.
+	SubscriptionSignatureHeader = "X-Bkcmdb-Signature"
+	// SubscriptionTimestampHeader is the http header that contains the unix timestamp of the push request
+	SubscriptionTimestampHeader = "X-Bkcmdb-Timestamp"
+	// SubscriptionIDHeader is the http header that contains the id of the subscription
+	SubscriptionIDHeader = "X-Bkcmdb-Subscription"
+)
+
+// Subscription is the webhook subscription of the resource watch events. event server watches the resource events
+// with the persisted cursor, and pushes them to the callback url in batches.
+type Subscription struct {
+	ID   int64  `json:"id" bson:"id"`
+	Name string `json:"name" bson:"name"`
+	// the resource kind you want to subscribe
+	Resource CursorType `json:"bk_resource" bson:"bk_resource"`
+	// event types you want to care, empty means all.
+	EventTypes []EventType `json:"bk_event_types" bson:"bk_event_types"`
+	// the fields you only care, if nil, means all.
+	Fields []string         `json:"bk_fields" bson:"bk_fields"`
+	Filter WatchEventFilter `json:"bk_filter" bson:"bk_filter"`
+	// CallbackURL is the http(s) url that the events are pushed to with POST method
+	CallbackURL string `json:"callback_url" bson:"callback_url"`
+	// Secret is used to sign the pushed payload, it is never returned by the query api
+	Secret string `json:"secret,omitempty" bson:"secret"`
+	// BatchSize is the max number of events that is pushed in one request
+	BatchSize int  `json:"batch_size" bson:"batch_size"`
+	Enabled   bool `json:"enabled" bson:"enabled"`
+	// StartFrom is the unix seconds time to where the subscription start to push events, it is only used
+	// when the cursor is not set, default is now.
+	StartFrom int64 `json:"bk_start_from" bson:"bk_start_from"`
+	// Cursor is the cursor of the last event that has been handled, events after it will be pushed
+	Cursor          string         `json:"bk_cursor" bson:"bk_cursor"`
+	Status          DeliveryStatus `json:"delivery_status" bson:"delivery_status"`
+	SupplierAccount string         `json:"bk_supplier_account" bson:"bk_supplier_account"`
+	Creator         string         `json:"creator" bson:"creator"`
+	Modifier        string         `json:"modifier" bson:"modifier"`
+	CreateTime      metadata.Time  `json:"create_time" bson:"create_time"`
+	LastTime        metadata.Time  `json:"last_time" bson:"last_time"`
+}
+
+// WatchOptions returns the watch options that is used to watch the events of the subscription
+func (s *Subscription) WatchOptions() *WatchEventOptions {
+	opts := &WatchEventOptions{
+		EventTypes: s.EventTypes,
+		Fields:     s.Fields,
+		Cursor:     s.Cursor,
+		Resource:   s.Resource,
+		Filter:     s.Filter,
+	}
+
+	if len(s.Cursor) == 0 {
+		opts.StartFrom = s.StartFrom
+	}
+	return opts
+}
+
+// DeliveryStatus is the delivery status of a subscription
+type DeliveryStatus struct {
+	// LastDeliveryTime is the last time that events are pushed to the callback, no matter it succeeds or not
+	LastDeliveryTime *metadata.Time `json:"last_delivery_time,omitempty" bson:"last_delivery_time,omitempty"`
+	// LastSuccessTime is the last time that events are pushed to the callback successfully
+	LastSuccessTime *metadata.Time `json:"last_success_time,omitempty" bson:"last_success_time,omitempty"`
+	// LastError is the error of the last failed delivery, it is reset when a delivery succeeds
+	LastError string `json:"last_error" bson:"last_error"`
+	// FailedTimes is the times that the events are failed to push continuously
+	FailedTimes int64 `json:"failed_times" bson:"failed_times"`
+	// DeliveredCount is the total number of events that has been pushed successfully
+	DeliveredCount int64 `json:"delivered_count" bson:"delivered_count"`
+	// DeadLetterCount is the total number of events that are failed to push after all retries
+	DeadLetterCount int64 `json:"dead_letter_count" bson:"dead_letter_count"`
+}
+
+// SubscriptionDeadLetter is the batch of events that are failed to push after all retries
+type SubscriptionDeadLetter struct {
+	ID             int64      `json:"id" bson:"id"`
+	SubscriptionID int64      `json:"subscription_id" bson:"subscription_id"`
+	Resource       CursorType `json:"bk_resource" bson:"bk_resource"`
+	// StartCursor and EndCursor is the cursor of the first and the last event in the payload
+	StartCursor string `json:"start_cursor" bson:"start_cursor"`
+	EndCursor   string `json:"end_cursor" bson:"end_cursor"`
+	// Payload is the json payload that is failed to push
+	Payload         string        `json:"payload" bson:"payload"`
+	Error           string        `json:"error" bson:"error"`
+	RetryTimes      int           `json:"retry_times" bson:"retry_times"`
+	SupplierAccount string        `json:"bk_supplier_account" bson:"bk_supplier_account"`
+	CreateTime      metadata.Time `json:"create_time" bson:"create_time"`
+}
+
+// SubscriptionPayload is the request body that is pushed to the subscription callback
+type SubscriptionPayload struct {
+	SubscriptionID int64               `json:"subscription_id"`
+	Resource       CursorType          `json:"bk_resource"`
+	Events         []*WatchEventDetail `json:"bk_events"`
+}
+
+// CreateSubscriptionOption is the option to create a subscription
+type CreateSubscriptionOption struct {
+	Name        string           `json:"name"`
+	Resource    CursorType       `json:"bk_resource"`
+	EventTypes  []EventType      `json:"bk_event_types"`
+	Fields      []string         `json:"bk_fields"`
+	Filter      WatchEventFilter `json:"bk_filter"`
+	CallbackURL string           `json:"callback_url"`
+	Secret      string           `json:"secret"`
+	BatchSize   int              `json:"batch_size"`
+	// StartFrom is the unix seconds time to where the subscription start to push events, default is now.
+	StartFrom int64 `json:"bk_start_from"`
+	// Disabled create the subscription without pushing events
+	Disabled bool `json:"disabled"`
+}
+
+// Validate create subscription option
+func (c *CreateSubscriptionOption) Validate() error {
+	if len(c.Name) == 0 {
+		return errors.New("name is not set")
+	}
+
+	if err := validateCallbackURL(c.CallbackURL); err != nil {
+		return err
+	}
+
+	if len(c.Secret) == 0 {
+		return errors.New("secret is not set")
+	}
+
+	if c.BatchSize < 0 || c.BatchSize > MaxSubscriptionBatchSize {
+		return fmt.Errorf("batch_size should be in range [1, %d], or not set to use the default value %d",
+			MaxSubscriptionBatchSize, DefaultSubscriptionBatchSize)
+	}
+
+	if c.StartFrom < 0 {
+		return errors.New("bk_start_from is invalid")
+	}
+
+	return validateSubscriptionResource(c.Resource, c.EventTypes, c.Fields, c.Filter)
+}
+
+// UpdateSubscriptionOption is the option to update a subscription, the subscribed resource can not be changed.
+type UpdateSubscriptionOption struct {
+	Name        *string     `json:"name"`
+	EventTypes  []EventType `json:"bk_event_types"`
+	Fields      []string    `json:"bk_fields"`
+	CallbackURL *string     `json:"callback_url"`
+	Secret      *string     `json:"secret"`
+	BatchSize   *int        `json:"batch_size"`
+	Enabled     *bool       `json:"enabled"`
+}
+
+// Validate update subscription option, the updated event types and fields are validated with the subscribed
+// resource of the subscription to update just like they are created.
+func (u *UpdateSubscriptionOption) Validate(sub *Subscription) error {
+	if u.Name != nil && len(*u.Name) == 0 {
+		return errors.New("name can not be empty")
+	}
+
+	if u.CallbackURL != nil {
+		if err := validateCallbackURL(*u.CallbackURL); err != nil {
+			return err
+		}
+	}
+
+	if u.Secret != nil && len(*u.Secret) == 0 {
+		return errors.New("secret can not be empty")
+	}
+
+	if u.BatchSize != nil && (*u.BatchSize <= 0 || *u.BatchSize > MaxSubscriptionBatchSize) {
+		return fmt.Errorf("batch_size should be in range [1, %d]", MaxSubscriptionBatchSize)
+	}
+
+	eventTypes, fields := sub.EventTypes, sub.Fields
+	if u.EventTypes != nil {
+		eventTypes = u.EventTypes
+	}
+	if u.Fields != nil {
+		fields = u.Fields
+	}
+	return validateSubscriptionResource(sub.Resource, eventTypes, fields, sub.Filter)
+}
+
+// ListSubscriptionOption is the option to list subscriptions
+type ListSubscriptionOption struct {
+	IDs      []int64           `json:"ids"`
+	Resource CursorType        `json:"bk_resource"`
+	Page     metadata.BasePage `json:"page"`
+}
+
+// ListSubscriptionResult is the result of list subscriptions
+type ListSubscriptionResult struct {
+	Count uint64         `json:"count"`
+	Info  []Subscription `json:"info"`
+}
+
+// ListDeadLetterOption is the option to list dead letters of a subscription
+type ListDeadLetterOption struct {
+	Page metadata.BasePage `json:"page"`
+}
+
+// ListDeadLetterResult is the result of list dead letters of a subscription
+type ListDeadLetterResult struct {
+	Count uint64                   `json:"count"`
+	Info  []SubscriptionDeadLetter `json:"info"`
+}
+
+// SubscriptionStatusOption is the option to get the delivery status of subscriptions
+type SubscriptionStatusOption struct {
+	IDs []int64 `json:"ids"`
+}
+
+// Validate subscription status option
+func (s *SubscriptionStatusOption) Validate() error {
+	if len(s.IDs) == 0 {
+		return errors.New("ids is not set")
+	}
+
+	if len(s.IDs) > common.BKMaxPageSize {
+		return fmt.Errorf("ids exceed max length %d", common.BKMaxPageSize)
+	}
+	return nil
+}
+
+// SubscriptionStatus is the delivery status of a subscription
+type SubscriptionStatus struct {
+	ID      int64          `json:"id"`
+	Enabled bool           `json:"enabled"`
+	Cursor  string         `json:"bk_cursor"`
+	Status  DeliveryStatus `json:"delivery_status"`
+}
+
+func validateCallbackURL(callback string) error {
+	if len(callback) == 0 {
+		return errors.New("callback_url is not set")
+	}
+
+	u, err := url.Parse(callback)
+	if err != nil {
+		return fmt.Errorf("callback_url is invalid, err: %v", err)
+	}
+
+	if (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+		return errors.New("callback_url must be a http or https url")
+	}
+	return nil
+}
+
+func validateSubscriptionResource(resource CursorType, eventTypes []EventType, fields []string,
+	filter WatchEventFilter) error {
+
+	valid := false
+	for _, typ := range ListCursorTypes() {
+		if typ == resource {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return fmt.Errorf("unsupported resource %s", resource)
+	}
+
+	opts := &WatchEventOptions{
+		EventTypes: eventTypes,
+		Fields:     fields,
+		Resource:   resource,
+		Filter:     filter,
+	}
+	return opts.Validate()
+}
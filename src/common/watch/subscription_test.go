@@ -0,0 +1,47 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package watch
+
+import (
+	"testing"
+)
+
+func TestUpdateSubscriptionOptionValidate(t *testing.T) {
+	strPtr := func(s string) *string { return &s }
+	hostSub := &Subscription{Resource: Host, Fields: []string{"bk_host_id"}}
+	bizSetSub := &Subscription{Resource: BizSet}
+
+	cases := []struct {
+		name   string
+		opt    UpdateSubscriptionOption
+		sub    *Subscription
+		hasErr bool
+	}{
+		{name: "keep the fields", opt: UpdateSubscriptionOption{Name: strPtr("demo")}, sub: hostSub},
+		{name: "update the fields", opt: UpdateSubscriptionOption{Fields: []string{"bk_host_innerip"}}, sub: hostSub},
+		{name: "clear the required fields", opt: UpdateSubscriptionOption{Fields: []string{}}, sub: hostSub,
+			hasErr: true},
+		{name: "clear the optional fields", opt: UpdateSubscriptionOption{Fields: []string{}}, sub: bizSetSub},
+		{name: "invalid event type", opt: UpdateSubscriptionOption{EventTypes: []EventType{"unknown"}},
+			sub: hostSub, hasErr: true},
+		{name: "empty name", opt: UpdateSubscriptionOption{Name: strPtr("")}, sub: hostSub, hasErr: true},
+		{name: "invalid callback", opt: UpdateSubscriptionOption{CallbackURL: strPtr("ftp://127.0.0.1")},
+			sub: hostSub, hasErr: true},
+	}
+
+	for _, c := range cases {
+		if err := c.opt.Validate(c.sub); c.hasErr != (err != nil) {
+			t.Errorf("%s: unexpected err: %v", c.name, err)
+		}
+	}
+}
@@ -18,6 +18,7 @@ import (
 	"errors"
 	"fmt"
 
+	"configcenter/pkg/filter"
 	"configcenter/src/common/metadata"
 )
 
@@ -40,7 +41,10 @@ type WatchEventOptions struct {
 // WatchEventFilter TODO
 type WatchEventFilter struct {
 	// SubResource the sub resource you want to watch, eg. object ID of the instance resource, watch all if not set
-	SubResource string `json:"bk_sub_resource,omitempty"`
+	SubResource string `json:"bk_sub_resource,omitempty" bson:"bk_sub_resource,omitempty"`
+	// Expression is the filter expression that the event detail must match, e.g. bk_biz_id in [1, 2],
+	// it is evaluated on the whole event detail before the fields are cut, watch all if not set.
+	Expression *filter.Expression `json:"bk_expression,omitempty" bson:"bk_expression,omitempty"`
 }
 
 // Validate TODO
@@ -67,12 +71,21 @@ func (w *WatchEventOptions) Validate() error {
 
 	if len(w.Filter.SubResource) > 0 {
 		switch w.Resource {
-		case ObjectBase, MainlineInstance, InstAsst, KubeWorkload:
+		case ObjectBase, MainlineInstance, InstAsst, KubeWorkload, DynamicGroupMember:
 		default:
 			return fmt.Errorf("%s event cannot have sub resource", w.Resource)
 		}
 	}
 
+	if w.Filter.Expression != nil {
+		// the fields of the event detail is not restricted, since the custom fields of the instances are different
+		opt := filter.NewDefaultExprOpt(nil)
+		opt.IgnoreRuleFields = true
+		if err := w.Filter.Expression.Validate(opt); err != nil {
+			return fmt.Errorf("bk_filter.bk_expression is invalid, err: %v", err)
+		}
+	}
+
 	return nil
 }
 
@@ -63,6 +63,7 @@ type Config struct {
 	ShardingTable  ShardingTableConfig
 	// SyncIAMPeriodMinutes the period for sync IAM resources
 	SyncIAMPeriodMinutes int
+	AuditLogChain        AuditLogChainConfig
 }
 
 // LanguageConfig TODO
@@ -85,6 +86,14 @@ type RegisterConfig struct {
 	Address string
 }
 
+// AuditLogChainConfig is the config of the audit log hash chain
+type AuditLogChainConfig struct {
+	// Secret is the secret to sign the audit log chain heads and checkpoints
+	Secret string
+	// CheckpointIntervalMinutes is the interval of the periodic checkpoints, default is 60 minutes
+	CheckpointIntervalMinutes int
+}
+
 // ShardingTableConfig TODO
 type ShardingTableConfig struct {
 	// 表中同步索引间隔时间，单位分钟， 最小30分钟， 默认60分钟， 最大720分钟
@@ -18,6 +18,7 @@ import (
 	"time"
 
 	iamcli "configcenter/src/ac/iam"
+	"configcenter/src/common/auditlog/exporter"
 	"configcenter/src/common/auth"
 	"configcenter/src/common/backbone"
 	cc "configcenter/src/common/backbone/configcenter"
@@ -26,6 +27,7 @@ import (
 	"configcenter/src/common/resource/esb"
 	"configcenter/src/common/types"
 	"configcenter/src/scene_server/admin_server/app/options"
+	"configcenter/src/scene_server/admin_server/auditchain"
 	"configcenter/src/scene_server/admin_server/configures"
 	"configcenter/src/scene_server/admin_server/iam"
 	"configcenter/src/scene_server/admin_server/logics"
@@ -75,6 +77,8 @@ func Run(ctx context.Context, cancel context.CancelFunc, op *options.ServerOptio
 
 	process.Config.SnapReportMode, _ = cc.String("datacollection.hostsnap.reportMode")
 	process.Config.SnapKafka, _ = cc.Kafka("kafka.snap")
+	process.Config.AuditLogChain.Secret, _ = cc.String("auditLog.chain.secret")
+	process.Config.AuditLogChain.CheckpointIntervalMinutes, _ = cc.Int("auditLog.chain.checkpointIntervalMinutes")
 
 	if err := monitor.InitMonitor(); err != nil {
 		return fmt.Errorf("init monitor failed, err: %v", err)
@@ -210,6 +214,22 @@ func Run(ctx context.Context, cancel context.CancelFunc, op *options.ServerOptio
 	syncor.SetSyncIAMPeriod(process.Config.SyncIAMPeriodMinutes)
 	go syncor.SyncIAM(iamCli, service.Logics)
 
+	sealer := auditchain.NewSealer(engine, mongodb.Client(), process.Config.AuditLogChain.Secret,
+		process.Config.AuditLogChain.CheckpointIntervalMinutes)
+	go sealer.Run(ctx)
+
+	exportConf, err := exporter.ParseConfig("auditLog.export")
+	if err != nil {
+		return fmt.Errorf("parse audit log export config failed, err: %v", err)
+	}
+	if exportConf.Enabled {
+		auditExporter, err := exporter.NewExporter(exportConf, mongodb.Client(), engine.ServiceManageInterface.IsMaster)
+		if err != nil {
+			return fmt.Errorf("new audit log exporter failed, err: %v", err)
+		}
+		go auditExporter.Run(ctx)
+	}
+
 	select {
 	case <-ctx.Done():
 	}
@@ -0,0 +1,97 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auditchain
+
+import (
+	"context"
+	"time"
+
+	"configcenter/src/common"
+	"configcenter/src/common/blog"
+	"configcenter/src/common/mapstr"
+	"configcenter/src/common/metadata"
+	"configcenter/src/storage/dal"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// addCheckpoint sign and save the audit log checkpoint
+func addCheckpoint(ctx context.Context, db dal.RDB, secret string, typ metadata.AuditLogCheckpointType,
+	supplier string, seq, auditID int64, hash string) (*metadata.AuditLogCheckpoint, error) {
+
+	id, err := db.NextSequence(ctx, common.BKTableNameAuditLogCheckpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	cp := &metadata.AuditLogCheckpoint{
+		ID:              int64(id),
+		Type:            typ,
+		SupplierAccount: supplier,
+		ChainSeq:        seq,
+		AuditID:         auditID,
+		Hash:            hash,
+		CreateTime:      time.Now().UTC().Truncate(time.Second),
+	}
+	cp.Signature = signCheckpoint(secret, cp)
+
+	if err := db.Table(common.BKTableNameAuditLogCheckpoint).Insert(ctx, cp); err != nil {
+		return nil, err
+	}
+	return cp, nil
+}
+
+// CheckpointBeforeTruncate generate the truncate checkpoints at the last sealed audit logs of each chain whose
+// mongo object id is less than the given one, it must be called before these audit logs are deleted, so that the
+// remaining part of the chains can still be verified.
+func CheckpointBeforeTruncate(ctx context.Context, db dal.RDB, secret string, before primitive.ObjectID,
+	rid string) error {
+
+	if secret == "" {
+		blog.Warnf("audit log chain secret is not set, skip generating truncate checkpoints, rid: %s", rid)
+		return nil
+	}
+
+	heads, err := getHeads(ctx, db)
+	if err != nil {
+		blog.Errorf("get audit log chain heads failed, err: %v, rid: %s", err, rid)
+		return err
+	}
+
+	for supplier := range heads {
+		cond := mapstr.MapStr{
+			"_id":                    mapstr.MapStr{common.BKDBLT: before},
+			common.BkSupplierAccount: supplier,
+			chainSeqField:            mapstr.MapStr{common.BKDBGT: 0},
+		}
+		logs := make([]sealInfo, 0)
+		err := db.Table(common.BKTableNameAuditLog).Find(cond).Sort("-"+chainSeqField).Limit(1).All(ctx, &logs)
+		if err != nil {
+			blog.Errorf("get the last audit log to truncate failed, cond: %+v, err: %v, rid: %s", cond, err, rid)
+			return err
+		}
+
+		if len(logs) == 0 {
+			continue
+		}
+
+		last := logs[0]
+		_, err = addCheckpoint(ctx, db, secret, metadata.AuditLogCheckpointTruncate, supplier, last.ChainSeq,
+			last.ID, last.Hash)
+		if err != nil {
+			blog.Errorf("add truncate checkpoint for audit log %d failed, err: %v, rid: %s", last.ID, err, rid)
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package auditchain seals the audit logs into per supplier account hash chains, generates the signed
+// checkpoints of the chains and verifies the chains to find out the modified or deleted audit logs.
+package auditchain
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+
+	"configcenter/src/common/metadata"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	chainSeqField = "chain_seq"
+	prevHashField = "prev_hash"
+	hashField     = "hash"
+)
+
+// chainFields are the fields that are set when the audit log is sealed, they are not a part of the log content
+var chainFields = map[string]struct{}{
+	"_id":         {},
+	chainSeqField: {},
+	prevHashField: {},
+	hashField:     {},
+}
+
+// sealInfo is the info of the audit log that is used to seal and verify the log
+type sealInfo struct {
+	ID              int64     `bson:"id"`
+	SupplierAccount string    `bson:"bk_supplier_account"`
+	ChainSeq        int64     `bson:"chain_seq"`
+	PrevHash        string    `bson:"prev_hash"`
+	Hash            string    `bson:"hash"`
+	OperationTime   time.Time `bson:"operation_time"`
+}
+
+// calcHash calculate the hash of the audit log with its chain sequence, the previous hash and the raw bson elements
+// of the log content as they are stored in db, so that any modification of the content changes the hash.
+func calcHash(raw bson.Raw, seq int64, prevHash string) (string, error) {
+	elements, err := raw.Elements()
+	if err != nil {
+		return "", fmt.Errorf("parse audit log elements failed, err: %v", err)
+	}
+
+	h := sha256.New()
+	h.Write([]byte(prevHash))
+	h.Write([]byte{'|'})
+	h.Write([]byte(strconv.FormatInt(seq, 10)))
+	h.Write([]byte{'|'})
+	for _, element := range elements {
+		if _, exists := chainFields[element.Key()]; exists {
+			continue
+		}
+		h.Write(element)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// signCheckpoint sign the checkpoint with hmac-sha256 using the secret
+func signCheckpoint(secret string, cp *metadata.AuditLogCheckpoint) string {
+	buf := bytes.NewBufferString(string(cp.Type))
+	_, _ = fmt.Fprintf(buf, "|%s|%d|%d|%s|%d", cp.SupplierAccount, cp.ChainSeq, cp.AuditID, cp.Hash,
+		cp.CreateTime.Unix())
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(buf.Bytes())
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// isCheckpointValid check if the signature of the checkpoint is valid
+func isCheckpointValid(secret string, cp *metadata.AuditLogCheckpoint) bool {
+	expected := signCheckpoint(secret, cp)
+	return hmac.Equal([]byte(expected), []byte(cp.Signature))
+}
+
+// signHead sign the chain head with hmac-sha256 using the secret
+func signHead(secret string, head *metadata.AuditLogChainHeadDoc) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = fmt.Fprintf(mac, "%s|%s|%d|%d|%s", head.Type, head.SupplierAccount, head.ChainSeq, head.AuditID, head.Hash)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// isHeadValid check if the signature of the chain head is valid
+func isHeadValid(secret string, head *metadata.AuditLogChainHeadDoc) bool {
+	expected := signHead(secret, head)
+	return hmac.Equal([]byte(expected), []byte(head.Signature))
+}
@@ -0,0 +1,120 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auditchain
+
+import (
+	"testing"
+	"time"
+
+	"configcenter/src/common/metadata"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestCalcHash(t *testing.T) {
+	raw, err := bson.Marshal(bson.D{{Key: "id", Value: 1}, {Key: "user", Value: "admin"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hash, err := calcHash(raw, 1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sealed, err := bson.Marshal(bson.D{{Key: "id", Value: 1}, {Key: "user", Value: "admin"},
+		{Key: chainSeqField, Value: 1}, {Key: prevHashField, Value: ""}, {Key: hashField, Value: hash}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sealedHash, err := calcHash(sealed, 1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sealedHash != hash {
+		t.Errorf("the chain fields should not change the hash, expected: %s, actual: %s", hash, sealedHash)
+	}
+
+	modified, err := bson.Marshal(bson.D{{Key: "id", Value: 1}, {Key: "user", Value: "guest"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	modifiedHash, err := calcHash(modified, 1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if modifiedHash == hash {
+		t.Errorf("the modified audit log should have a different hash")
+	}
+
+	nextHash, err := calcHash(raw, 2, hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nextHash == hash {
+		t.Errorf("the chain sequence and prev hash should change the hash")
+	}
+}
+
+func TestSignCheckpoint(t *testing.T) {
+	cp := &metadata.AuditLogCheckpoint{
+		ID:              1,
+		Type:            metadata.AuditLogCheckpointPeriodic,
+		SupplierAccount: "0",
+		ChainSeq:        10,
+		AuditID:         100,
+		Hash:            "abc",
+		CreateTime:      time.Unix(1666000000, 0),
+	}
+	cp.Signature = signCheckpoint("secret", cp)
+
+	if !isCheckpointValid("secret", cp) {
+		t.Errorf("checkpoint signature should be valid")
+	}
+
+	if isCheckpointValid("other", cp) {
+		t.Errorf("checkpoint signature should be invalid with another secret")
+	}
+
+	cp.ChainSeq = 11
+	if isCheckpointValid("secret", cp) {
+		t.Errorf("checkpoint signature should be invalid after the checkpoint is modified")
+	}
+}
+
+func TestSignHead(t *testing.T) {
+	head := &metadata.AuditLogChainHeadDoc{
+		Type:            metadata.AuditLogChainHead,
+		SupplierAccount: "0",
+		ChainSeq:        10,
+		AuditID:         100,
+		Hash:            "abc",
+	}
+	head.Signature = signHead("secret", head)
+
+	if !isHeadValid("secret", head) {
+		t.Errorf("head signature should be valid")
+	}
+
+	if isHeadValid("other", head) {
+		t.Errorf("head signature should be invalid with another secret")
+	}
+
+	// rolling back the head to delete the tail of the chain breaks the signature
+	head.ChainSeq, head.AuditID, head.Hash = 8, 98, "def"
+	if isHeadValid("secret", head) {
+		t.Errorf("head signature should be invalid after the head is rolled back")
+	}
+}
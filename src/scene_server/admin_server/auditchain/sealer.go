@@ -0,0 +1,274 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auditchain
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"configcenter/src/common"
+	"configcenter/src/common/backbone"
+	"configcenter/src/common/blog"
+	"configcenter/src/common/mapstr"
+	"configcenter/src/common/metadata"
+	"configcenter/src/common/util"
+	"configcenter/src/storage/dal"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	// sealInterval is the interval to seal the new audit logs
+	sealInterval = 10 * time.Second
+	// sealDelay is the delay to seal the audit log after it is created, the audit logs are inserted with the ids
+	// generated before the transaction commits, so the logs with smaller ids may be visible later than the bigger ones
+	sealDelay = time.Minute
+	// sealBatchSize is the max number of the audit logs that are sealed in one batch
+	sealBatchSize = 500
+	// defaultCheckpointInterval is the default interval of the periodic checkpoints
+	defaultCheckpointInterval = 60 * time.Minute
+)
+
+// Sealer seals the new audit logs into the hash chains and generates the periodic checkpoints,
+// it only runs on the master admin server.
+type Sealer struct {
+	engine             *backbone.Engine
+	db                 dal.RDB
+	secret             string
+	checkpointInterval time.Duration
+	lastCheckpoint     time.Time
+}
+
+// NewSealer new audit log sealer
+func NewSealer(engine *backbone.Engine, db dal.RDB, secret string, checkpointIntervalMinutes int) *Sealer {
+	interval := defaultCheckpointInterval
+	if checkpointIntervalMinutes > 0 {
+		interval = time.Duration(checkpointIntervalMinutes) * time.Minute
+	}
+
+	return &Sealer{
+		engine:             engine,
+		db:                 db,
+		secret:             secret,
+		checkpointInterval: interval,
+	}
+}
+
+// Run seal the audit logs periodically until the context is done
+func (s *Sealer) Run(ctx context.Context) {
+	if s.secret == "" {
+		blog.Warnf("audit log chain secret is not set, the checkpoints of the audit log chains will not be generated")
+	}
+
+	ticker := time.NewTicker(sealInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if !s.engine.ServiceManageInterface.IsMaster() {
+			continue
+		}
+
+		rid := util.GenerateRID()
+		kitCtx := context.WithValue(ctx, common.ContextRequestIDField, rid)
+
+		for {
+			sealed, err := s.seal(kitCtx, rid)
+			if err != nil {
+				blog.Errorf("seal audit logs failed, err: %v, rid: %s", err, rid)
+				break
+			}
+
+			if sealed < sealBatchSize {
+				break
+			}
+		}
+
+		if s.secret == "" || time.Since(s.lastCheckpoint) < s.checkpointInterval {
+			continue
+		}
+
+		if err := s.checkpointHeads(kitCtx, rid); err != nil {
+			blog.Errorf("generate periodic audit log checkpoints failed, err: %v, rid: %s", err, rid)
+			continue
+		}
+		s.lastCheckpoint = time.Now()
+	}
+}
+
+// seal one batch of the audit logs after the cursor into the hash chains, returns the number of the sealed logs
+func (s *Sealer) seal(ctx context.Context, rid string) (int, error) {
+	cursor, err := getCursor(ctx, s.db)
+	if err != nil {
+		blog.Errorf("get audit log chain cursor failed, err: %v, rid: %s", err, rid)
+		return 0, err
+	}
+
+	cond := mapstr.MapStr{common.BKFieldID: mapstr.MapStr{common.BKDBGT: cursor.LastID}}
+	logs := make([]bson.Raw, 0)
+	err = s.db.Table(common.BKTableNameAuditLog).Find(cond).Sort(common.BKFieldID).Limit(sealBatchSize).
+		All(ctx, &logs)
+	if err != nil {
+		blog.Errorf("get audit logs to seal failed, cond: %+v, err: %v, rid: %s", cond, err, rid)
+		return 0, err
+	}
+
+	if len(logs) == 0 {
+		return 0, nil
+	}
+
+	heads, err := getHeads(ctx, s.db)
+	if err != nil {
+		blog.Errorf("get audit log chain heads failed, err: %v, rid: %s", err, rid)
+		return 0, err
+	}
+
+	sealed := 0
+	deadline := time.Now().Add(-sealDelay)
+	changed := make(map[string]struct{})
+	for _, raw := range logs {
+		info := new(sealInfo)
+		if err := bson.Unmarshal(raw, info); err != nil {
+			blog.Errorf("unmarshal audit log %s failed, err: %v, rid: %s", raw.String(), err, rid)
+			return 0, err
+		}
+
+		// stop at the first log that is too new, so that the logs are always sealed in the order of their ids
+		if info.OperationTime.After(deadline) {
+			break
+		}
+		sealed++
+
+		head, exists := heads[info.SupplierAccount]
+		if !exists {
+			head = &metadata.AuditLogChainHeadDoc{Type: metadata.AuditLogChainHead,
+				SupplierAccount: info.SupplierAccount}
+			heads[info.SupplierAccount] = head
+		}
+
+		// the log is sealed but the head is not saved because of the crash of the last round, adopt it as the head
+		if info.ChainSeq > 0 {
+			if info.ChainSeq > head.ChainSeq {
+				head.ChainSeq, head.AuditID, head.Hash = info.ChainSeq, info.ID, info.Hash
+				changed[info.SupplierAccount] = struct{}{}
+			}
+			cursor.LastID = info.ID
+			continue
+		}
+
+		seq := head.ChainSeq + 1
+		hash, err := calcHash(raw, seq, head.Hash)
+		if err != nil {
+			blog.Errorf("calculate hash of audit log %d failed, err: %v, rid: %s", info.ID, err, rid)
+			return 0, err
+		}
+
+		update := mapstr.MapStr{chainSeqField: seq, prevHashField: head.Hash, hashField: hash}
+		logCond := mapstr.MapStr{common.BKFieldID: info.ID}
+		if err := s.db.Table(common.BKTableNameAuditLog).Update(ctx, logCond, update); err != nil {
+			blog.Errorf("seal audit log %d failed, err: %v, rid: %s", info.ID, err, rid)
+			return 0, err
+		}
+
+		head.ChainSeq, head.AuditID, head.Hash = seq, info.ID, hash
+		changed[info.SupplierAccount] = struct{}{}
+		cursor.LastID = info.ID
+	}
+
+	for supplier := range changed {
+		head := heads[supplier]
+		if s.secret != "" {
+			head.Signature = signHead(s.secret, head)
+		}
+		headCond := mapstr.MapStr{"type": metadata.AuditLogChainHead, common.BkSupplierAccount: supplier}
+		if err := s.db.Table(common.BKTableNameAuditLogChain).Upsert(ctx, headCond, head); err != nil {
+			blog.Errorf("save audit log chain head %+v failed, err: %v, rid: %s", head, err, rid)
+			return 0, err
+		}
+	}
+
+	cursorCond := mapstr.MapStr{"type": metadata.AuditLogChainCursor}
+	if err := s.db.Table(common.BKTableNameAuditLogChain).Upsert(ctx, cursorCond, cursor); err != nil {
+		blog.Errorf("save audit log chain cursor %+v failed, err: %v, rid: %s", cursor, err, rid)
+		return 0, err
+	}
+
+	return sealed, nil
+}
+
+// checkpointHeads generate the periodic checkpoints for the chain heads that have changed since the last checkpoint
+func (s *Sealer) checkpointHeads(ctx context.Context, rid string) error {
+	heads, err := getHeads(ctx, s.db)
+	if err != nil {
+		blog.Errorf("get audit log chain heads failed, err: %v, rid: %s", err, rid)
+		return err
+	}
+
+	for supplier, head := range heads {
+		last := make([]metadata.AuditLogCheckpoint, 0)
+		cond := mapstr.MapStr{common.BkSupplierAccount: supplier}
+		err := s.db.Table(common.BKTableNameAuditLogCheckpoint).Find(cond).Sort("-"+chainSeqField).Limit(1).
+			All(ctx, &last)
+		if err != nil {
+			blog.Errorf("get the last audit log checkpoint of %s failed, err: %v, rid: %s", supplier, err, rid)
+			return err
+		}
+
+		if len(last) > 0 && last[0].ChainSeq >= head.ChainSeq {
+			continue
+		}
+
+		_, err = addCheckpoint(ctx, s.db, s.secret, metadata.AuditLogCheckpointPeriodic, supplier, head.ChainSeq,
+			head.AuditID, head.Hash)
+		if err != nil {
+			blog.Errorf("add periodic checkpoint for head %+v failed, err: %v, rid: %s", head, err, rid)
+			return err
+		}
+	}
+	return nil
+}
+
+// getCursor get the cursor of the audit log sealer
+func getCursor(ctx context.Context, db dal.RDB) (*metadata.AuditLogChainCursorDoc, error) {
+	cursors := make([]metadata.AuditLogChainCursorDoc, 0)
+	cond := mapstr.MapStr{"type": metadata.AuditLogChainCursor}
+	if err := db.Table(common.BKTableNameAuditLogChain).Find(cond).All(ctx, &cursors); err != nil {
+		return nil, err
+	}
+
+	if len(cursors) == 0 {
+		return nil, fmt.Errorf("audit log chain cursor is not initialized")
+	}
+	return &cursors[0], nil
+}
+
+// getHeads get the hash chain heads of all supplier accounts, returns supplier account to head map
+func getHeads(ctx context.Context, db dal.RDB) (map[string]*metadata.AuditLogChainHeadDoc, error) {
+	heads := make([]metadata.AuditLogChainHeadDoc, 0)
+	cond := mapstr.MapStr{"type": metadata.AuditLogChainHead}
+	if err := db.Table(common.BKTableNameAuditLogChain).Find(cond).All(ctx, &heads); err != nil {
+		return nil, err
+	}
+
+	headMap := make(map[string]*metadata.AuditLogChainHeadDoc, len(heads))
+	for idx := range heads {
+		headMap[heads[idx].SupplierAccount] = &heads[idx]
+	}
+	return headMap, nil
+}
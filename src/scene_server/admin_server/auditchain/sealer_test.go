@@ -0,0 +1,450 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auditchain
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"configcenter/src/common"
+	"configcenter/src/common/mapstr"
+	"configcenter/src/common/metadata"
+	"configcenter/src/storage/dal"
+	"configcenter/src/storage/dal/types"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	testSecret   = "secret"
+	testSupplier = "0"
+)
+
+// fakeDB is an in-memory db that keeps the field order of the docs, it only supports the conditions and the
+// operations used by the sealer and the verifier
+type fakeDB struct {
+	dal.RDB
+	tables   map[string][]bson.D
+	sequence uint64
+}
+
+func newFakeDB() *fakeDB {
+	return &fakeDB{tables: make(map[string][]bson.D)}
+}
+
+// Table returns the fake table of the collection
+func (db *fakeDB) Table(collection string) types.Table {
+	return &fakeTable{db: db, name: collection}
+}
+
+// NextSequence returns the next id
+func (db *fakeDB) NextSequence(_ context.Context, _ string) (uint64, error) {
+	db.sequence++
+	return db.sequence, nil
+}
+
+// toDoc converts the doc to the bson document as it is stored in db
+func toDoc(doc interface{}) (bson.D, error) {
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	d := make(bson.D, 0)
+	if err := bson.Unmarshal(data, &d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// lookup get the value of the field in the doc
+func lookup(doc bson.D, field string) (interface{}, bool) {
+	for _, e := range doc {
+		if e.Key == field {
+			return e.Value, true
+		}
+	}
+	return nil, false
+}
+
+// setField set the value of the field in the doc, the new field is appended to the end of the doc
+func setField(doc bson.D, field string, value interface{}) bson.D {
+	for idx := range doc {
+		if doc[idx].Key == field {
+			doc[idx].Value = value
+			return doc
+		}
+	}
+	return append(doc, bson.E{Key: field, Value: value})
+}
+
+// compare compares the numbers by their values and the others by their string forms
+func compare(a, b interface{}) int {
+	toFloat := func(v interface{}) (float64, bool) {
+		switch n := v.(type) {
+		case int:
+			return float64(n), true
+		case int32:
+			return float64(n), true
+		case int64:
+			return float64(n), true
+		case float64:
+			return n, true
+		}
+		return 0, false
+	}
+
+	x, ok1 := toFloat(a)
+	y, ok2 := toFloat(b)
+	if !ok1 || !ok2 {
+		return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+	}
+
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+// match check if the doc matches the filter of the equal, comparison and $exists conditions
+func match(doc bson.D, filter mapstr.MapStr) bool {
+	for field, cond := range filter {
+		value, exists := lookup(doc, field)
+		ops, ok := cond.(mapstr.MapStr)
+		if !ok {
+			if !exists || compare(value, cond) != 0 {
+				return false
+			}
+			continue
+		}
+
+		for op, expect := range ops {
+			if op == common.BKDBExists {
+				if exists != expect.(bool) {
+					return false
+				}
+				continue
+			}
+
+			if !exists {
+				return false
+			}
+			result := compare(value, expect)
+			switch op {
+			case common.BKDBGT:
+				ok = result > 0
+			case common.BKDBGTE:
+				ok = result >= 0
+			case common.BKDBLT:
+				ok = result < 0
+			case common.BKDBLTE:
+				ok = result <= 0
+			default:
+				panic(fmt.Sprintf("unsupported operator %s", op))
+			}
+			if !ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+type fakeTable struct {
+	types.Table
+	db   *fakeDB
+	name string
+}
+
+// Find returns the fake find of the filter
+func (t *fakeTable) Find(filter types.Filter, _ ...*types.FindOpts) types.Find {
+	return &fakeFind{table: t, filter: filter.(mapstr.MapStr)}
+}
+
+// Insert inserts the doc into the table
+func (t *fakeTable) Insert(_ context.Context, doc interface{}) error {
+	d, err := toDoc(doc)
+	if err != nil {
+		return err
+	}
+	t.db.tables[t.name] = append(t.db.tables[t.name], d)
+	return nil
+}
+
+// Update sets the fields of the docs that match the filter
+func (t *fakeTable) Update(_ context.Context, filter types.Filter, doc interface{}) error {
+	docs := t.db.tables[t.name]
+	for idx := range docs {
+		if !match(docs[idx], filter.(mapstr.MapStr)) {
+			continue
+		}
+		for field, value := range doc.(mapstr.MapStr) {
+			docs[idx] = setField(docs[idx], field, value)
+		}
+	}
+	return nil
+}
+
+// Upsert replaces the docs that match the filter, or inserts the doc if none of them matches
+func (t *fakeTable) Upsert(ctx context.Context, filter types.Filter, doc interface{}) error {
+	d, err := toDoc(doc)
+	if err != nil {
+		return err
+	}
+
+	matched := false
+	docs := t.db.tables[t.name]
+	for idx := range docs {
+		if match(docs[idx], filter.(mapstr.MapStr)) {
+			docs[idx] = d
+			matched = true
+		}
+	}
+	if !matched {
+		t.db.tables[t.name] = append(docs, d)
+	}
+	return nil
+}
+
+type fakeFind struct {
+	types.Find
+	table  *fakeTable
+	filter mapstr.MapStr
+	sort   string
+	limit  uint64
+}
+
+// Sort sets the sort field of the docs, only one field is supported
+func (f *fakeFind) Sort(sort string) types.Find {
+	f.sort = sort
+	return f
+}
+
+// Limit sets the max number of the docs
+func (f *fakeFind) Limit(limit uint64) types.Find {
+	f.limit = limit
+	return f
+}
+
+// find returns the sorted and limited docs that match the filter
+func (f *fakeFind) find() []bson.D {
+	docs := make([]bson.D, 0)
+	for _, doc := range f.table.db.tables[f.table.name] {
+		if match(doc, f.filter) {
+			docs = append(docs, doc)
+		}
+	}
+
+	if f.sort != "" {
+		field, desc := strings.TrimPrefix(f.sort, "-"), strings.HasPrefix(f.sort, "-")
+		sort.SliceStable(docs, func(i, j int) bool {
+			a, _ := lookup(docs[i], field)
+			b, _ := lookup(docs[j], field)
+			if desc {
+				return compare(a, b) > 0
+			}
+			return compare(a, b) < 0
+		})
+	}
+
+	if f.limit > 0 && uint64(len(docs)) > f.limit {
+		docs = docs[:f.limit]
+	}
+	return docs
+}
+
+// All decodes the docs into the result
+func (f *fakeFind) All(_ context.Context, result interface{}) error {
+	data, err := bson.Marshal(bson.M{"docs": f.find()})
+	if err != nil {
+		return err
+	}
+	return bson.Raw(data).Lookup("docs").Unmarshal(result)
+}
+
+// Count counts the docs that match the filter
+func (f *fakeFind) Count(_ context.Context) (uint64, error) {
+	f.limit = 0
+	return uint64(len(f.find())), nil
+}
+
+// addTestLogs add the audit logs of the ids that are created at the operation time
+func addTestLogs(t *testing.T, db *fakeDB, supplier string, opTime time.Time, ids ...int64) {
+	for _, id := range ids {
+		log := bson.D{
+			{Key: common.BKFieldID, Value: id},
+			{Key: common.BkSupplierAccount, Value: supplier},
+			{Key: "user", Value: "admin"},
+			{Key: "action", Value: "update"},
+			{Key: "operation_time", Value: opTime},
+		}
+		if err := db.Table(common.BKTableNameAuditLog).Insert(context.Background(), log); err != nil {
+			t.Fatalf("insert audit log %d failed, err: %v", id, err)
+		}
+	}
+}
+
+// newTestChain returns the db with a sealed chain of the audit logs whose ids and chain sequences are 1 to n
+func newTestChain(t *testing.T, n int64) *fakeDB {
+	db := newFakeDB()
+	cursor := &metadata.AuditLogChainCursorDoc{Type: metadata.AuditLogChainCursor}
+	if err := db.Table(common.BKTableNameAuditLogChain).Insert(context.Background(), cursor); err != nil {
+		t.Fatalf("insert cursor failed, err: %v", err)
+	}
+
+	for id := int64(1); id <= n; id++ {
+		addTestLogs(t, db, testSupplier, time.Now().Add(-2*sealDelay), id)
+	}
+
+	s := &Sealer{db: db, secret: testSecret}
+	sealed, err := s.seal(context.Background(), "")
+	if err != nil {
+		t.Fatalf("seal audit logs failed, err: %v", err)
+	}
+	if int64(sealed) != n {
+		t.Fatalf("expect %d sealed audit logs, got %d", n, sealed)
+	}
+	return db
+}
+
+// getTestLog get the audit log by id, returns nil if it does not exist
+func getTestLog(t *testing.T, db *fakeDB, id int64) *sealInfo {
+	for _, doc := range db.tables[common.BKTableNameAuditLog] {
+		if value, _ := lookup(doc, common.BKFieldID); compare(value, id) != 0 {
+			continue
+		}
+
+		data, err := bson.Marshal(doc)
+		if err != nil {
+			t.Fatalf("marshal audit log %d failed, err: %v", id, err)
+		}
+		info := new(sealInfo)
+		if err := bson.Unmarshal(data, info); err != nil {
+			t.Fatalf("unmarshal audit log %d failed, err: %v", id, err)
+		}
+		return info
+	}
+	return nil
+}
+
+func TestSeal(t *testing.T) {
+	db := newFakeDB()
+	cursor := &metadata.AuditLogChainCursorDoc{Type: metadata.AuditLogChainCursor}
+	if err := db.Table(common.BKTableNameAuditLogChain).Insert(context.Background(), cursor); err != nil {
+		t.Fatalf("insert cursor failed, err: %v", err)
+	}
+
+	old := time.Now().Add(-2 * sealDelay)
+	addTestLogs(t, db, testSupplier, old, 1, 2)
+	addTestLogs(t, db, "1", old, 3)
+	// the logs that are newer than the seal delay are not sealed, neither are the logs after them
+	addTestLogs(t, db, testSupplier, time.Now(), 4)
+	addTestLogs(t, db, testSupplier, old, 5)
+
+	s := &Sealer{db: db, secret: testSecret}
+	sealed, err := s.seal(context.Background(), "")
+	if err != nil {
+		t.Fatalf("seal audit logs failed, err: %v", err)
+	}
+	if sealed != 3 {
+		t.Fatalf("expect 3 sealed audit logs, got %d", sealed)
+	}
+
+	// the logs of each supplier account are sealed into its own chain
+	expects := map[int64]struct {
+		seq  int64
+		prev int64
+	}{1: {seq: 1}, 2: {seq: 2, prev: 1}, 3: {seq: 1}, 4: {}, 5: {}}
+	for id, expect := range expects {
+		info := getTestLog(t, db, id)
+		if info.ChainSeq != expect.seq {
+			t.Errorf("expect audit log %d chain seq %d, got %d", id, expect.seq, info.ChainSeq)
+		}
+		if expect.prev > 0 && info.PrevHash != getTestLog(t, db, expect.prev).Hash {
+			t.Errorf("prev hash of audit log %d does not match the hash of audit log %d", id, expect.prev)
+		}
+	}
+
+	heads, err := getHeads(context.Background(), db)
+	if err != nil {
+		t.Fatalf("get heads failed, err: %v", err)
+	}
+	if len(heads) != 2 {
+		t.Fatalf("expect 2 chain heads, got %+v", heads)
+	}
+	for supplier, lastID := range map[string]int64{testSupplier: 2, "1": 3} {
+		head := heads[supplier]
+		if head == nil || head.AuditID != lastID || head.Hash != getTestLog(t, db, lastID).Hash ||
+			!isHeadValid(testSecret, head) {
+			t.Errorf("unexpected chain head %+v of supplier account %s", head, supplier)
+		}
+	}
+
+	cursor, err = getCursor(context.Background(), db)
+	if err != nil {
+		t.Fatalf("get cursor failed, err: %v", err)
+	}
+	if cursor.LastID != 3 {
+		t.Errorf("expect cursor last id 3, got %d", cursor.LastID)
+	}
+}
+
+func TestSealAdoptHalfSealedBatch(t *testing.T) {
+	db := newTestChain(t, 1)
+	// the chain head and the cursor that are saved before the crash
+	saved := append([]bson.D{}, db.tables[common.BKTableNameAuditLogChain]...)
+
+	addTestLogs(t, db, testSupplier, time.Now().Add(-2*sealDelay), 2, 3)
+	s := &Sealer{db: db, secret: testSecret}
+	if _, err := s.seal(context.Background(), ""); err != nil {
+		t.Fatalf("seal audit logs failed, err: %v", err)
+	}
+	hashes := map[int64]string{2: getTestLog(t, db, 2).Hash, 3: getTestLog(t, db, 3).Hash}
+
+	// the audit logs 2 and 3 are sealed, but the sealer crashes before the head and the cursor are saved
+	db.tables[common.BKTableNameAuditLogChain] = saved
+	addTestLogs(t, db, testSupplier, time.Now().Add(-2*sealDelay), 4)
+
+	sealed, err := s.seal(context.Background(), "")
+	if err != nil {
+		t.Fatalf("seal audit logs failed, err: %v", err)
+	}
+	if sealed != 3 {
+		t.Fatalf("expect 3 sealed audit logs, got %d", sealed)
+	}
+
+	for id, hash := range hashes {
+		if info := getTestLog(t, db, id); info.ChainSeq != id || info.Hash != hash {
+			t.Errorf("the sealed audit log %d should be adopted as it is, got %+v", id, info)
+		}
+	}
+	if info := getTestLog(t, db, 4); info.ChainSeq != 4 || info.PrevHash != hashes[3] {
+		t.Errorf("audit log 4 should be chained after the adopted audit log 3, got %+v", info)
+	}
+
+	result, err := Verify(context.Background(), db, testSecret,
+		&metadata.VerifyAuditLogChainOption{SupplierAccount: testSupplier}, "")
+	if err != nil {
+		t.Fatalf("verify chain failed, err: %v", err)
+	}
+	if !result.Valid || result.HeadSeq != 4 || result.Checked != 4 {
+		t.Errorf("the chain should be valid after adopting the half sealed batch, result: %+v", result)
+	}
+}
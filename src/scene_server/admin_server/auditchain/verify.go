@@ -0,0 +1,327 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auditchain
+
+import (
+	"context"
+
+	"configcenter/src/common"
+	"configcenter/src/common/blog"
+	"configcenter/src/common/mapstr"
+	"configcenter/src/common/metadata"
+	"configcenter/src/storage/dal"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	// verifyBatchSize is the number of the audit logs that are verified in one batch
+	verifyBatchSize = 500
+	// maxVerifyProblems is the max number of the gaps or issues that are returned in the verify result
+	maxVerifyProblems = 100
+)
+
+// chainLink is the last verified link of the chain
+type chainLink struct {
+	seq  int64
+	hash string
+	// known is false if the previous link is missing, then the prev hash of the next link can not be checked
+	known bool
+}
+
+type verifier struct {
+	db         dal.RDB
+	secret     string
+	rid        string
+	opt        *metadata.VerifyAuditLogChainOption
+	result     *metadata.VerifyAuditLogChainResult
+	checkpoint map[int64][]metadata.AuditLogCheckpoint
+}
+
+// Verify verify the audit log hash chain of the supplier account in the chain sequence range of the option,
+// returns the gaps of the missing logs and the issues of the modified logs and checkpoints.
+func Verify(ctx context.Context, db dal.RDB, secret string, opt *metadata.VerifyAuditLogChainOption,
+	rid string) (*metadata.VerifyAuditLogChainResult, error) {
+
+	v := &verifier{
+		db:     db,
+		secret: secret,
+		rid:    rid,
+		opt:    opt,
+		result: &metadata.VerifyAuditLogChainResult{
+			SupplierAccount: opt.SupplierAccount,
+			Gaps:            make([]metadata.AuditLogChainGap, 0),
+			Issues:          make([]metadata.AuditLogChainIssue, 0),
+		},
+	}
+
+	// the checkpoints and the head can be forged without the secret, so the chain is never valid in this case
+	if secret == "" {
+		v.addIssue(metadata.AuditLogChainIssue{Reason: "audit log chain secret is not configured, the chain head " +
+			"and checkpoints can not be verified"})
+	}
+
+	if err := v.verifyHead(ctx); err != nil {
+		return nil, err
+	}
+
+	start, end := opt.StartSeq, opt.EndSeq
+	if start < 1 {
+		start = 1
+	}
+	if end == 0 || end > v.result.HeadSeq {
+		end = v.result.HeadSeq
+	}
+
+	if err := v.verifyCheckpoints(ctx, start-1, end); err != nil {
+		return nil, err
+	}
+
+	if start <= end {
+		if err := v.verifyLogs(ctx, start, end); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := v.countUnsealed(ctx); err != nil {
+		return nil, err
+	}
+
+	v.result.Valid = len(v.result.Gaps) == 0 && len(v.result.Issues) == 0 && v.result.Unsealed == 0
+	return v.result, nil
+}
+
+// verifyHead verify the signature of the chain head and set the head sequence of the result, the head is signed so
+// that deleting the tail of the chain is found as a gap before the head. if the head is missing, the sealed audit logs
+// are still verified up to the last one of them.
+func (v *verifier) verifyHead(ctx context.Context) error {
+	heads, err := getHeads(ctx, v.db)
+	if err != nil {
+		blog.Errorf("get audit log chain heads failed, err: %v, rid: %s", err, v.rid)
+		return err
+	}
+
+	if head, exists := heads[v.opt.SupplierAccount]; exists {
+		v.result.HeadSeq = head.ChainSeq
+		if v.secret != "" && head.ChainSeq > 0 && !isHeadValid(v.secret, head) {
+			v.addIssue(metadata.AuditLogChainIssue{AuditID: head.AuditID, ChainSeq: head.ChainSeq,
+				Reason: "chain head signature is invalid"})
+		}
+		return nil
+	}
+
+	cond := mapstr.MapStr{
+		common.BkSupplierAccount: v.opt.SupplierAccount,
+		chainSeqField:            mapstr.MapStr{common.BKDBGT: 0},
+	}
+	logs := make([]sealInfo, 0)
+	err = v.db.Table(common.BKTableNameAuditLog).Find(cond).Sort("-"+chainSeqField).Limit(1).All(ctx, &logs)
+	if err != nil {
+		blog.Errorf("get the last sealed audit log failed, cond: %+v, err: %v, rid: %s", cond, err, v.rid)
+		return err
+	}
+
+	if len(logs) > 0 {
+		v.result.HeadSeq = logs[0].ChainSeq
+		v.addIssue(metadata.AuditLogChainIssue{AuditID: logs[0].ID, ChainSeq: logs[0].ChainSeq,
+			Reason: "chain head is missing while there are sealed audit logs"})
+	}
+	return nil
+}
+
+// verifyCheckpoints verify the signatures of the checkpoints in the chain sequence range
+func (v *verifier) verifyCheckpoints(ctx context.Context, start, end int64) error {
+	cond := mapstr.MapStr{
+		common.BkSupplierAccount: v.opt.SupplierAccount,
+		chainSeqField:            mapstr.MapStr{common.BKDBGTE: start, common.BKDBLTE: end},
+	}
+	checkpoints := make([]metadata.AuditLogCheckpoint, 0)
+	err := v.db.Table(common.BKTableNameAuditLogCheckpoint).Find(cond).Sort(chainSeqField).All(ctx, &checkpoints)
+	if err != nil {
+		blog.Errorf("get audit log checkpoints failed, cond: %+v, err: %v, rid: %s", cond, err, v.rid)
+		return err
+	}
+
+	v.checkpoint = make(map[int64][]metadata.AuditLogCheckpoint)
+	for _, cp := range checkpoints {
+		v.result.Checkpoints++
+		if v.secret != "" && !isCheckpointValid(v.secret, &cp) {
+			v.addIssue(metadata.AuditLogChainIssue{CheckpointID: cp.ID, ChainSeq: cp.ChainSeq,
+				Reason: "checkpoint signature is invalid"})
+			continue
+		}
+		v.checkpoint[cp.ChainSeq] = append(v.checkpoint[cp.ChainSeq], cp)
+	}
+	return nil
+}
+
+// verifyLogs walk through the audit logs in the chain sequence range and verify the links between them
+func (v *verifier) verifyLogs(ctx context.Context, start, end int64) error {
+	prev, err := v.getAnchor(ctx, start)
+	if err != nil {
+		return err
+	}
+	first := true
+
+	for cur := start; cur <= end; {
+		cond := mapstr.MapStr{
+			common.BkSupplierAccount: v.opt.SupplierAccount,
+			chainSeqField:            mapstr.MapStr{common.BKDBGTE: cur, common.BKDBLTE: end},
+		}
+		logs := make([]bson.Raw, 0)
+		err := v.db.Table(common.BKTableNameAuditLog).Find(cond).Sort(chainSeqField).Limit(verifyBatchSize).
+			All(ctx, &logs)
+		if err != nil {
+			blog.Errorf("get audit logs to verify failed, cond: %+v, err: %v, rid: %s", cond, err, v.rid)
+			return err
+		}
+
+		for _, raw := range logs {
+			info := new(sealInfo)
+			if err := bson.Unmarshal(raw, info); err != nil {
+				blog.Errorf("unmarshal audit log %s failed, err: %v, rid: %s", raw.String(), err, v.rid)
+				return err
+			}
+
+			v.result.Checked++
+			if first {
+				v.result.FirstSeq = info.ChainSeq
+			}
+			v.result.LastSeq = info.ChainSeq
+
+			if info.ChainSeq == prev.seq {
+				v.addIssue(metadata.AuditLogChainIssue{AuditID: info.ID, ChainSeq: info.ChainSeq,
+					Reason: "duplicate chain sequence"})
+				continue
+			}
+
+			if info.ChainSeq > prev.seq+1 {
+				// the head of the chain may be deleted by the retention policy with a truncate checkpoint
+				if first && v.isAnchoredByCheckpoint(info) {
+					prev = chainLink{seq: info.ChainSeq - 1, hash: info.PrevHash, known: true}
+				} else {
+					v.addGap(prev.seq+1, info.ChainSeq-1)
+					prev.known = false
+				}
+			}
+			first = false
+
+			if prev.known && info.PrevHash != prev.hash {
+				v.addIssue(metadata.AuditLogChainIssue{AuditID: info.ID, ChainSeq: info.ChainSeq,
+					Reason: "prev_hash does not match the hash of the previous audit log"})
+			}
+
+			hash, err := calcHash(raw, info.ChainSeq, info.PrevHash)
+			if err != nil {
+				blog.Errorf("calculate hash of audit log %d failed, err: %v, rid: %s", info.ID, err, v.rid)
+				return err
+			}
+			if hash != info.Hash {
+				v.addIssue(metadata.AuditLogChainIssue{AuditID: info.ID, ChainSeq: info.ChainSeq,
+					Reason: "audit log content does not match its hash"})
+			}
+
+			for _, cp := range v.checkpoint[info.ChainSeq] {
+				if cp.Hash != info.Hash {
+					v.addIssue(metadata.AuditLogChainIssue{AuditID: info.ID, CheckpointID: cp.ID,
+						ChainSeq: info.ChainSeq, Reason: "audit log hash does not match the checkpoint"})
+				}
+			}
+
+			prev = chainLink{seq: info.ChainSeq, hash: info.Hash, known: true}
+		}
+
+		if len(logs) < verifyBatchSize {
+			break
+		}
+		cur = prev.seq + 1
+	}
+
+	if prev.seq < end {
+		v.addGap(prev.seq+1, end)
+	}
+	return nil
+}
+
+// getAnchor get the link before the start sequence, which is the previous audit log or a checkpoint
+func (v *verifier) getAnchor(ctx context.Context, start int64) (chainLink, error) {
+	if start == 1 {
+		return chainLink{seq: 0, hash: "", known: true}, nil
+	}
+
+	cond := mapstr.MapStr{common.BkSupplierAccount: v.opt.SupplierAccount, chainSeqField: start - 1}
+	logs := make([]sealInfo, 0)
+	if err := v.db.Table(common.BKTableNameAuditLog).Find(cond).Limit(1).All(ctx, &logs); err != nil {
+		blog.Errorf("get audit log before the start sequence failed, cond: %+v, err: %v, rid: %s", cond, err, v.rid)
+		return chainLink{}, err
+	}
+
+	if len(logs) > 0 {
+		return chainLink{seq: start - 1, hash: logs[0].Hash, known: true}, nil
+	}
+
+	if cps := v.checkpoint[start-1]; len(cps) > 0 {
+		return chainLink{seq: start - 1, hash: cps[0].Hash, known: true}, nil
+	}
+
+	// the previous log is deleted, the first log is either anchored by a checkpoint or reported as a gap
+	return chainLink{seq: start - 1, known: false}, nil
+}
+
+// isAnchoredByCheckpoint check if there is a valid checkpoint right before the audit log that matches its prev hash
+func (v *verifier) isAnchoredByCheckpoint(info *sealInfo) bool {
+	for _, cp := range v.checkpoint[info.ChainSeq-1] {
+		if cp.Hash == info.PrevHash {
+			return true
+		}
+	}
+	return false
+}
+
+// countUnsealed count the audit logs of the supplier account that are behind the sealer cursor but are not sealed
+func (v *verifier) countUnsealed(ctx context.Context) error {
+	cursor, err := getCursor(ctx, v.db)
+	if err != nil {
+		blog.Errorf("get audit log chain cursor failed, err: %v, rid: %s", err, v.rid)
+		return err
+	}
+
+	cond := mapstr.MapStr{
+		common.BkSupplierAccount: v.opt.SupplierAccount,
+		common.BKFieldID:         mapstr.MapStr{common.BKDBGT: cursor.StartID, common.BKDBLTE: cursor.LastID},
+		chainSeqField:            mapstr.MapStr{common.BKDBExists: false},
+	}
+	cnt, err := v.db.Table(common.BKTableNameAuditLog).Find(cond).Count(ctx)
+	if err != nil {
+		blog.Errorf("count unsealed audit logs failed, cond: %+v, err: %v, rid: %s", cond, err, v.rid)
+		return err
+	}
+	v.result.Unsealed = int64(cnt)
+	return nil
+}
+
+func (v *verifier) addGap(from, to int64) {
+	if len(v.result.Gaps) >= maxVerifyProblems {
+		v.result.Truncated = true
+		return
+	}
+	v.result.Gaps = append(v.result.Gaps, metadata.AuditLogChainGap{FromSeq: from, ToSeq: to})
+}
+
+func (v *verifier) addIssue(issue metadata.AuditLogChainIssue) {
+	if len(v.result.Issues) >= maxVerifyProblems {
+		v.result.Truncated = true
+		return
+	}
+	v.result.Issues = append(v.result.Issues, issue)
+}
@@ -0,0 +1,237 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auditchain
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"configcenter/src/common"
+	"configcenter/src/common/mapstr"
+	"configcenter/src/common/metadata"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// deleteTestLogs delete the audit logs of the ids
+func deleteTestLogs(db *fakeDB, ids ...int64) {
+	for _, id := range ids {
+		remain := make([]bson.D, 0)
+		for _, doc := range db.tables[common.BKTableNameAuditLog] {
+			if value, _ := lookup(doc, common.BKFieldID); compare(value, id) != 0 {
+				remain = append(remain, doc)
+			}
+		}
+		db.tables[common.BKTableNameAuditLog] = remain
+	}
+}
+
+// updateTestLog update the fields of the audit log of the id
+func updateTestLog(t *testing.T, db *fakeDB, id int64, doc mapstr.MapStr) {
+	err := db.Table(common.BKTableNameAuditLog).Update(context.Background(), mapstr.MapStr{common.BKFieldID: id}, doc)
+	if err != nil {
+		t.Fatalf("update audit log %d failed, err: %v", id, err)
+	}
+}
+
+// addTestCheckpoint add the checkpoint at the audit log of the id
+func addTestCheckpoint(t *testing.T, db *fakeDB, typ metadata.AuditLogCheckpointType, id int64) {
+	info := getTestLog(t, db, id)
+	_, err := addCheckpoint(context.Background(), db, testSecret, typ, testSupplier, info.ChainSeq, info.ID,
+		info.Hash)
+	if err != nil {
+		t.Fatalf("add checkpoint at audit log %d failed, err: %v", id, err)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		name string
+		// modify changes the sealed chain of the audit logs 1 to 5
+		modify     func(t *testing.T, db *fakeDB)
+		opt        metadata.VerifyAuditLogChainOption
+		wantGaps   []metadata.AuditLogChainGap
+		wantIssues []string
+		wantHead   int64
+	}{
+		{
+			name:     "intact chain",
+			modify:   func(t *testing.T, db *fakeDB) {},
+			wantHead: 5,
+		},
+		{
+			name: "deleted middle log",
+			modify: func(t *testing.T, db *fakeDB) {
+				deleteTestLogs(db, 3)
+			},
+			wantGaps: []metadata.AuditLogChainGap{{FromSeq: 3, ToSeq: 3}},
+			wantHead: 5,
+		},
+		{
+			name: "deleted tail is found by the signed head",
+			modify: func(t *testing.T, db *fakeDB) {
+				deleteTestLogs(db, 4, 5)
+			},
+			wantGaps: []metadata.AuditLogChainGap{{FromSeq: 4, ToSeq: 5}},
+			wantHead: 5,
+		},
+		{
+			name: "deleted tail with the rolled back head",
+			modify: func(t *testing.T, db *fakeDB) {
+				deleteTestLogs(db, 5)
+				info := getTestLog(t, db, 4)
+				doc := db.tables[common.BKTableNameAuditLogChain][1]
+				doc = setField(doc, chainSeqField, info.ChainSeq)
+				doc = setField(doc, "audit_id", info.ID)
+				db.tables[common.BKTableNameAuditLogChain][1] = setField(doc, hashField, info.Hash)
+			},
+			wantIssues: []string{"4: chain head signature is invalid"},
+			wantHead:   4,
+		},
+		{
+			name: "truncated head anchored by the truncate checkpoint",
+			modify: func(t *testing.T, db *fakeDB) {
+				addTestCheckpoint(t, db, metadata.AuditLogCheckpointTruncate, 2)
+				deleteTestLogs(db, 1, 2)
+			},
+			wantHead: 5,
+		},
+		{
+			name: "truncated head without checkpoint",
+			modify: func(t *testing.T, db *fakeDB) {
+				deleteTestLogs(db, 1, 2)
+			},
+			wantGaps: []metadata.AuditLogChainGap{{FromSeq: 1, ToSeq: 2}},
+			wantHead: 5,
+		},
+		{
+			name: "modified content hash",
+			modify: func(t *testing.T, db *fakeDB) {
+				updateTestLog(t, db, 3, mapstr.MapStr{"user": "guest"})
+			},
+			wantIssues: []string{"3: audit log content does not match its hash"},
+			wantHead:   5,
+		},
+		{
+			name: "modified content with the recalculated hash",
+			modify: func(t *testing.T, db *fakeDB) {
+				updateTestLog(t, db, 3, mapstr.MapStr{"user": "guest"})
+				updateTestLog(t, db, 3, mapstr.MapStr{hashField: "forged"})
+			},
+			wantIssues: []string{"3: audit log content does not match its hash",
+				"4: prev_hash does not match the hash of the previous audit log"},
+			wantHead: 5,
+		},
+		{
+			name: "audit log does not match the periodic checkpoint",
+			modify: func(t *testing.T, db *fakeDB) {
+				addTestCheckpoint(t, db, metadata.AuditLogCheckpointPeriodic, 3)
+				updateTestLog(t, db, 3, mapstr.MapStr{hashField: "forged"})
+			},
+			wantIssues: []string{"3: audit log content does not match its hash",
+				"3: audit log hash does not match the checkpoint",
+				"4: prev_hash does not match the hash of the previous audit log"},
+			wantHead: 5,
+		},
+		{
+			name: "range anchored by the previous log",
+			modify: func(t *testing.T, db *fakeDB) {
+				updateTestLog(t, db, 3, mapstr.MapStr{prevHashField: "forged"})
+			},
+			opt: metadata.VerifyAuditLogChainOption{StartSeq: 3, EndSeq: 4},
+			wantIssues: []string{"3: prev_hash does not match the hash of the previous audit log",
+				"3: audit log content does not match its hash"},
+			wantHead: 5,
+		},
+		{
+			name: "range anchored by the truncate checkpoint",
+			modify: func(t *testing.T, db *fakeDB) {
+				addTestCheckpoint(t, db, metadata.AuditLogCheckpointTruncate, 2)
+				deleteTestLogs(db, 1, 2)
+			},
+			opt:      metadata.VerifyAuditLogChainOption{StartSeq: 3},
+			wantHead: 5,
+		},
+		{
+			name: "range with the unknown anchor",
+			modify: func(t *testing.T, db *fakeDB) {
+				deleteTestLogs(db, 2)
+				updateTestLog(t, db, 3, mapstr.MapStr{prevHashField: "forged"})
+			},
+			opt:        metadata.VerifyAuditLogChainOption{StartSeq: 3},
+			wantIssues: []string{"3: audit log content does not match its hash"},
+			wantHead:   5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestChain(t, 5)
+			tt.modify(t, db)
+
+			opt := tt.opt
+			opt.SupplierAccount = testSupplier
+			result, err := Verify(context.Background(), db, testSecret, &opt, "")
+			if err != nil {
+				t.Fatalf("verify chain failed, err: %v", err)
+			}
+
+			issues := make([]string, len(result.Issues))
+			for idx, issue := range result.Issues {
+				issues[idx] = fmt.Sprintf("%d: %s", issue.ChainSeq, issue.Reason)
+			}
+
+			wantGaps := tt.wantGaps
+			if wantGaps == nil {
+				wantGaps = make([]metadata.AuditLogChainGap, 0)
+			}
+			wantIssues := tt.wantIssues
+			if wantIssues == nil {
+				wantIssues = make([]string, 0)
+			}
+
+			if !reflect.DeepEqual(result.Gaps, wantGaps) {
+				t.Errorf("Verify() gaps = %+v, want %+v", result.Gaps, wantGaps)
+			}
+			if !reflect.DeepEqual(issues, wantIssues) {
+				t.Errorf("Verify() issues = %v, want %v", issues, wantIssues)
+			}
+			if result.HeadSeq != tt.wantHead {
+				t.Errorf("Verify() head seq = %d, want %d", result.HeadSeq, tt.wantHead)
+			}
+			if valid := len(wantGaps) == 0 && len(wantIssues) == 0; result.Valid != valid {
+				t.Errorf("Verify() valid = %v, want %v", result.Valid, valid)
+			}
+		})
+	}
+}
+
+func TestVerifyUnsealed(t *testing.T) {
+	db := newTestChain(t, 2)
+	// the audit log is behind the cursor but it is not sealed, the chain fields may be removed to hide it
+	addTestLogs(t, db, testSupplier, time.Now(), 3)
+	cursor := db.tables[common.BKTableNameAuditLogChain][0]
+	db.tables[common.BKTableNameAuditLogChain][0] = setField(cursor, "last_id", int64(3))
+
+	result, err := Verify(context.Background(), db, testSecret,
+		&metadata.VerifyAuditLogChainOption{SupplierAccount: testSupplier}, "")
+	if err != nil {
+		t.Fatalf("verify chain failed, err: %v", err)
+	}
+	if result.Valid || result.Unsealed != 1 {
+		t.Errorf("the unsealed audit log should be found, result: %+v", result)
+	}
+}
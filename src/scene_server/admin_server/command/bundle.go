@@ -0,0 +1,201 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"configcenter/src/common/mapstr"
+
+	yl "github.com/ghodss/yaml"
+)
+
+// bundleVersion 拓扑导入导出包的格式版本，格式不兼容时需要升级版本
+const bundleVersion = "v1"
+
+const (
+	bundleFormatJSON = "json"
+	bundleFormatYAML = "yaml"
+)
+
+const (
+	// conflictSkip 目标环境中已存在时跳过，使用已存在数据的id
+	conflictSkip = "skip"
+	// conflictOverwrite 目标环境中已存在时使用导入包中的数据覆盖
+	conflictOverwrite = "overwrite"
+	// conflictRename 目标环境中已存在时重命名后新建，不支持重命名的数据按跳过处理
+	conflictRename = "rename"
+)
+
+// Bundle 可移植的拓扑导入导出包，格式说明见 docs/wiki/bkbiz_bundle.md
+// 数据中保留了导出环境的id，导入时重新生成id并替换数据间的引用
+type Bundle struct {
+	Version    string    `json:"version"`
+	ExportTime time.Time `json:"export_time"`
+	// Mainline 主线模型拓扑，从业务到模块，导入环境的主线拓扑需要与之一致
+	Mainline         []string         `json:"mainline"`
+	Classifications  []mapstr.MapStr  `json:"classifications"`
+	AssociationKinds []mapstr.MapStr  `json:"association_kinds"`
+	Models           []BundleModel    `json:"models"`
+	Associations     []mapstr.MapStr  `json:"associations"`
+	Businesses       []BundleBusiness `json:"businesses"`
+}
+
+// BundleModel 模型及其字段分组、字段和唯一校验，内置模型只包含用户自定义的字段分组、字段和唯一校验
+type BundleModel struct {
+	Object     mapstr.MapStr   `json:"object"`
+	Groups     []mapstr.MapStr `json:"groups"`
+	Attributes []mapstr.MapStr `json:"attributes"`
+	Uniques    []BundleUnique  `json:"uniques"`
+}
+
+// BundleUnique 模型唯一校验，使用字段的 bk_property_id 描述，导入时转换为目标环境中字段的id
+type BundleUnique struct {
+	ID   int64    `json:"id"`
+	Keys []string `json:"keys"`
+}
+
+// BundleBusiness 业务及其下的服务分类、模板、拓扑和主机属性自动应用规则
+type BundleBusiness struct {
+	Business mapstr.MapStr `json:"business"`
+	// Attributes 业务下自定义的模型字段
+	Attributes []mapstr.MapStr `json:"attributes"`
+	// ServiceCategories 包含业务自定义的和内置的服务分类，内置的服务分类只用于id映射，不会导入
+	ServiceCategories    []mapstr.MapStr `json:"service_categories"`
+	ServiceTemplates     []mapstr.MapStr `json:"service_templates"`
+	ProcessTemplates     []mapstr.MapStr `json:"process_templates"`
+	SetTemplates         []mapstr.MapStr `json:"set_templates"`
+	SetTemplateRelations []mapstr.MapStr `json:"set_template_relations"`
+	// Topology 业务下的主线拓扑实例，包含空闲机池
+	Topology []*Node `json:"topology"`
+	// HostApplyRules 主机属性自动应用规则，使用 bk_property_id 描述规则对应的主机字段
+	HostApplyRules []mapstr.MapStr `json:"host_apply_rules"`
+}
+
+// getBundleFormat 获取导入导出包的格式，未指定时根据文件后缀判断
+func getBundleFormat(format, position string) (string, error) {
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(position)) {
+		case ".yaml", ".yml":
+			return bundleFormatYAML, nil
+		default:
+			return bundleFormatJSON, nil
+		}
+	}
+
+	switch format {
+	case bundleFormatJSON, bundleFormatYAML:
+		return format, nil
+	default:
+		return "", fmt.Errorf("bundle format %s is invalid, could be [json] or [yaml]", format)
+	}
+}
+
+func marshalBundle(bundle *Bundle, format string) ([]byte, error) {
+	if format == bundleFormatYAML {
+		return yl.Marshal(bundle)
+	}
+	return json.MarshalIndent(bundle, "", "    ")
+}
+
+func unmarshalBundle(data []byte, format string) (*Bundle, error) {
+	if format == bundleFormatYAML {
+		var err error
+		data, err = yl.YAMLToJSON(data)
+		if err != nil {
+			return nil, fmt.Errorf("convert yaml to json failed, err: %v", err)
+		}
+	}
+
+	// 使用 json.Number 解析，避免整数被解析为浮点数后写入db
+	bundle := new(Bundle)
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(bundle); err != nil {
+		return nil, err
+	}
+
+	if bundle.Version != bundleVersion {
+		return nil, fmt.Errorf("bundle version %s is not supported, supported version: %s", bundle.Version,
+			bundleVersion)
+	}
+
+	normalizeDocs(bundle.Classifications)
+	normalizeDocs(bundle.AssociationKinds)
+	normalizeDocs(bundle.Associations)
+	for _, model := range bundle.Models {
+		normalizeDocs([]mapstr.MapStr{model.Object})
+		normalizeDocs(model.Groups)
+		normalizeDocs(model.Attributes)
+	}
+	for _, biz := range bundle.Businesses {
+		normalizeDocs([]mapstr.MapStr{biz.Business})
+		normalizeDocs(biz.Attributes)
+		normalizeDocs(biz.ServiceCategories)
+		normalizeDocs(biz.ServiceTemplates)
+		normalizeDocs(biz.ProcessTemplates)
+		normalizeDocs(biz.SetTemplates)
+		normalizeDocs(biz.SetTemplateRelations)
+		normalizeDocs(biz.HostApplyRules)
+		for _, node := range biz.Topology {
+			_ = node.walk(func(node *Node) error {
+				node.Data = normalizeNumber(node.Data).(map[string]interface{})
+				return nil
+			})
+		}
+	}
+
+	return bundle, nil
+}
+
+func normalizeDocs(docs []mapstr.MapStr) {
+	for _, doc := range docs {
+		for key, val := range doc {
+			doc[key] = normalizeNumber(val)
+		}
+	}
+}
+
+// normalizeNumber 将 json.Number 转换为 int64 或 float64
+func normalizeNumber(val interface{}) interface{} {
+	switch v := val.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = normalizeNumber(item)
+		}
+		return v
+	case mapstr.MapStr:
+		for key, item := range v {
+			v[key] = normalizeNumber(item)
+		}
+		return v
+	case []interface{}:
+		for idx, item := range v {
+			v[idx] = normalizeNumber(item)
+		}
+		return v
+	default:
+		return val
+	}
+}
@@ -0,0 +1,372 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package command
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"configcenter/src/common"
+	"configcenter/src/common/mapstr"
+	"configcenter/src/common/metadata"
+	"configcenter/src/common/util"
+	"configcenter/src/storage/dal"
+)
+
+// exportBundle 导出业务拓扑、模型和模板等数据到可移植的导入导出包中
+func exportBundle(ctx context.Context, db dal.RDB, opt *option) error {
+	format, err := getBundleFormat(opt.format, opt.position)
+	if err != nil {
+		return err
+	}
+
+	exporter := &bundleExporter{db: db, opt: opt}
+	bundle, err := exporter.export(ctx)
+	if err != nil {
+		return err
+	}
+
+	data, err := marshalBundle(bundle, format)
+	if err != nil {
+		return fmt.Errorf("marshal bundle failed, err: %v", err)
+	}
+
+	return ioutil.WriteFile(opt.position, data, 0644)
+}
+
+type bundleExporter struct {
+	db  dal.RDB
+	opt *option
+}
+
+func (be *bundleExporter) export(ctx context.Context) (*Bundle, error) {
+	mainline, err := getMainline(ctx, be.db)
+	if err != nil {
+		return nil, err
+	}
+
+	bundle := &Bundle{
+		Version:    bundleVersion,
+		ExportTime: time.Now(),
+		Mainline:   mainline,
+	}
+
+	if err := be.exportModels(ctx, bundle); err != nil {
+		return nil, err
+	}
+
+	bundle.AssociationKinds, err = be.findAll(ctx, common.BKTableNameAsstDes,
+		mapstr.MapStr{common.BKIsPre: mapstr.MapStr{common.BKDBNE: true}})
+	if err != nil {
+		return nil, err
+	}
+
+	asstCond := mapstr.MapStr{
+		common.AssociationKindIDField: mapstr.MapStr{common.BKDBNE: common.AssociationKindMainline},
+		common.BKIsPre:                mapstr.MapStr{common.BKDBNE: true},
+	}
+	bundle.Associations, err = be.findAll(ctx, common.BKTableNameObjAsst, asstCond)
+	if err != nil {
+		return nil, err
+	}
+
+	bizCond := mapstr.MapStr{
+		common.BKDefaultField:    common.DefaultFlagDefaultValue,
+		common.BKDataStatusField: mapstr.MapStr{common.BKDBNE: common.DataStatusDisabled},
+	}
+	if len(be.opt.bizNames) > 0 {
+		bizCond[common.BKAppNameField] = mapstr.MapStr{common.BKDBIN: be.opt.bizNames}
+	}
+	businesses, err := be.findAll(ctx, common.BKTableNameBaseApp, bizCond)
+	if err != nil {
+		return nil, err
+	}
+	if len(businesses) == 0 {
+		return nil, fmt.Errorf("no business is found to export")
+	}
+
+	for _, biz := range businesses {
+		bundleBiz, err := be.exportBusiness(ctx, biz, mainline)
+		if err != nil {
+			return nil, fmt.Errorf("export business %v failed, err: %v", biz[common.BKAppNameField], err)
+		}
+		bundle.Businesses = append(bundle.Businesses, *bundleBiz)
+		fmt.Printf("business %v is exported\n", biz[common.BKAppNameField])
+	}
+
+	return bundle, nil
+}
+
+// exportModels 导出自定义模型，以及内置模型中用户自定义的字段分组、字段和唯一校验
+func (be *bundleExporter) exportModels(ctx context.Context, bundle *Bundle) error {
+	objects, err := be.findAll(ctx, common.BKTableNameObjDes, mapstr.MapStr{})
+	if err != nil {
+		return err
+	}
+
+	classificationIDs := make([]string, 0)
+	for _, object := range objects {
+		objID := util.GetStrByInterface(object[common.BKObjIDField])
+		isPre, _ := object[common.BKIsPre].(bool)
+
+		cond := mapstr.MapStr{common.BKObjIDField: objID}
+		if isPre {
+			cond[common.BKIsPre] = false
+		}
+		uniques := make([]metadata.ObjectUnique, 0)
+		if err := be.db.Table(common.BKTableNameObjUnique).Find(cond).All(ctx, &uniques); err != nil {
+			return fmt.Errorf("find object %s uniques failed, err: %v", objID, err)
+		}
+
+		cond[common.BKAppIDField] = 0
+		groups, err := be.findAll(ctx, common.BKTableNamePropertyGroup, cond)
+		if err != nil {
+			return err
+		}
+		attributes, err := be.findAll(ctx, common.BKTableNameObjAttDes, cond)
+		if err != nil {
+			return err
+		}
+
+		if isPre && len(uniques) == 0 && len(groups) == 0 && len(attributes) == 0 {
+			continue
+		}
+
+		bundleUniques, err := be.convertUniques(ctx, objID, uniques)
+		if err != nil {
+			return err
+		}
+
+		bundle.Models = append(bundle.Models, BundleModel{
+			Object:     object,
+			Groups:     groups,
+			Attributes: attributes,
+			Uniques:    bundleUniques,
+		})
+
+		if !isPre {
+			classificationIDs = append(classificationIDs, util.GetStrByInterface(object[common.BKClassificationIDField]))
+		}
+	}
+
+	classificationCond := mapstr.MapStr{
+		common.BKClassificationIDField: mapstr.MapStr{common.BKDBIN: util.StrArrayUnique(classificationIDs)},
+	}
+	bundle.Classifications, err = be.findAll(ctx, common.BKTableNameObjClassification, classificationCond)
+	return err
+}
+
+// convertUniques 将唯一校验中字段的id转换为 bk_property_id
+func (be *bundleExporter) convertUniques(ctx context.Context, objID string, uniques []metadata.ObjectUnique) (
+	[]BundleUnique, error) {
+
+	if len(uniques) == 0 {
+		return make([]BundleUnique, 0), nil
+	}
+
+	attrs := make([]metadata.Attribute, 0)
+	cond := mapstr.MapStr{common.BKObjIDField: objID}
+	err := be.db.Table(common.BKTableNameObjAttDes).Find(cond).Fields(common.BKFieldID, common.BKPropertyIDField).
+		All(ctx, &attrs)
+	if err != nil {
+		return nil, fmt.Errorf("find object %s attributes failed, err: %v", objID, err)
+	}
+	propertyIDMap := make(map[uint64]string, len(attrs))
+	for _, attr := range attrs {
+		propertyIDMap[uint64(attr.ID)] = attr.PropertyID
+	}
+
+	bundleUniques := make([]BundleUnique, 0, len(uniques))
+	for _, unique := range uniques {
+		bundleUnique := BundleUnique{ID: int64(unique.ID)}
+		for _, key := range unique.Keys {
+			propertyID, exists := propertyIDMap[key.ID]
+			if !exists {
+				return nil, fmt.Errorf("object %s unique %d key %d is not found", objID, unique.ID, key.ID)
+			}
+			bundleUnique.Keys = append(bundleUnique.Keys, propertyID)
+		}
+		bundleUniques = append(bundleUniques, bundleUnique)
+	}
+	return bundleUniques, nil
+}
+
+func (be *bundleExporter) exportBusiness(ctx context.Context, biz mapstr.MapStr, mainline []string) (
+	*BundleBusiness, error) {
+
+	bizID, err := util.GetInt64ByInterface(biz[common.BKAppIDField])
+	if err != nil {
+		return nil, err
+	}
+	bizCond := mapstr.MapStr{common.BKAppIDField: bizID}
+
+	bundleBiz := &BundleBusiness{Business: biz}
+	if bundleBiz.Attributes, err = be.findAll(ctx, common.BKTableNameObjAttDes, bizCond); err != nil {
+		return nil, err
+	}
+
+	categoryCond := mapstr.MapStr{common.BKAppIDField: mapstr.MapStr{common.BKDBIN: []int64{0, bizID}}}
+	if bundleBiz.ServiceCategories, err = be.findAll(ctx, common.BKTableNameServiceCategory,
+		categoryCond); err != nil {
+		return nil, err
+	}
+	if bundleBiz.ServiceTemplates, err = be.findAll(ctx, common.BKTableNameServiceTemplate, bizCond); err != nil {
+		return nil, err
+	}
+	if bundleBiz.ProcessTemplates, err = be.findAll(ctx, common.BKTableNameProcessTemplate, bizCond); err != nil {
+		return nil, err
+	}
+	if bundleBiz.SetTemplates, err = be.findAll(ctx, common.BKTableNameSetTemplate, bizCond); err != nil {
+		return nil, err
+	}
+	if bundleBiz.SetTemplateRelations, err = be.findAll(ctx, common.BKTableNameSetServiceTemplateRelation,
+		bizCond); err != nil {
+		return nil, err
+	}
+
+	if bundleBiz.Topology, err = be.exportTopology(ctx, bizID, mainline); err != nil {
+		return nil, err
+	}
+
+	if bundleBiz.HostApplyRules, err = be.exportHostApplyRules(ctx, bizID); err != nil {
+		return nil, err
+	}
+
+	return bundleBiz, nil
+}
+
+// exportTopology 按主线拓扑逐层导出业务下的实例， 返回业务下第一层的节点
+func (be *bundleExporter) exportTopology(ctx context.Context, bizID int64, mainline []string) ([]*Node, error) {
+	topology := make([]*Node, 0)
+	parentNodes := map[int64]*Node{bizID: nil}
+
+	for _, objID := range mainline[1:] {
+		cond := mapstr.MapStr{common.BKAppIDField: bizID}
+		if !util.IsInnerObject(objID) {
+			cond[common.BKObjIDField] = objID
+		}
+		insts, err := be.findAll(ctx, common.GetInstTableName(objID, be.opt.OwnerID), cond)
+		if err != nil {
+			return nil, err
+		}
+
+		nodes := make(map[int64]*Node, len(insts))
+		for _, inst := range insts {
+			instID, err := util.GetInt64ByInterface(inst[common.GetInstIDField(objID)])
+			if err != nil {
+				return nil, fmt.Errorf("%s instance id is invalid, err: %v", objID, err)
+			}
+			parentID, err := util.GetInt64ByInterface(inst[common.BKParentIDField])
+			if err != nil {
+				return nil, fmt.Errorf("%s instance %d parent id is invalid, err: %v", objID, instID, err)
+			}
+
+			parent, exists := parentNodes[parentID]
+			if !exists {
+				return nil, fmt.Errorf("%s instance %d parent %d is not found", objID, instID, parentID)
+			}
+
+			node := newNode(objID)
+			node.Data = inst
+			nodes[instID] = node
+			if parent == nil {
+				topology = append(topology, node)
+			} else {
+				parent.Children = append(parent.Children, node)
+			}
+		}
+		parentNodes = nodes
+	}
+
+	return topology, nil
+}
+
+// exportHostApplyRules 导出主机属性自动应用规则，将主机字段的id转换为 bk_property_id
+func (be *bundleExporter) exportHostApplyRules(ctx context.Context, bizID int64) ([]mapstr.MapStr, error) {
+	rules, err := be.findAll(ctx, common.BKTableNameHostApplyRule, mapstr.MapStr{common.BKAppIDField: bizID})
+	if err != nil || len(rules) == 0 {
+		return rules, err
+	}
+
+	attrs := make([]metadata.Attribute, 0)
+	cond := mapstr.MapStr{common.BKObjIDField: common.BKInnerObjIDHost}
+	err = be.db.Table(common.BKTableNameObjAttDes).Find(cond).Fields(common.BKFieldID, common.BKPropertyIDField).
+		All(ctx, &attrs)
+	if err != nil {
+		return nil, fmt.Errorf("find host attributes failed, err: %v", err)
+	}
+	propertyIDMap := make(map[int64]string, len(attrs))
+	for _, attr := range attrs {
+		propertyIDMap[attr.ID] = attr.PropertyID
+	}
+
+	for _, rule := range rules {
+		attrID, err := util.GetInt64ByInterface(rule[common.BKAttributeIDField])
+		if err != nil {
+			return nil, fmt.Errorf("host apply rule %v attribute id is invalid, err: %v", rule[common.BKFieldID], err)
+		}
+		propertyID, exists := propertyIDMap[attrID]
+		if !exists {
+			return nil, fmt.Errorf("host apply rule %v attribute %d is not found", rule[common.BKFieldID], attrID)
+		}
+		rule[common.BKPropertyIDField] = propertyID
+		delete(rule, common.BKAttributeIDField)
+	}
+	return rules, nil
+}
+
+func (be *bundleExporter) findAll(ctx context.Context, table string, cond mapstr.MapStr) ([]mapstr.MapStr, error) {
+	docs := make([]mapstr.MapStr, 0)
+	if err := be.db.Table(table).Find(cond).Sort(common.BKFieldID).All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("find %s data failed, err: %v", table, err)
+	}
+
+	for _, doc := range docs {
+		delete(doc, "_id")
+	}
+	return docs, nil
+}
+
+// getMainline 获取主线模型拓扑，从业务到模块
+func getMainline(ctx context.Context, db dal.RDB) ([]string, error) {
+	assts := make([]metadata.Association, 0)
+	cond := mapstr.MapStr{common.AssociationKindIDField: common.AssociationKindMainline}
+	if err := db.Table(common.BKTableNameObjAsst).Find(cond).All(ctx, &assts); err != nil {
+		return nil, fmt.Errorf("find mainline associations failed, err: %v", err)
+	}
+
+	childMap := make(map[string]string, len(assts))
+	for _, asst := range assts {
+		childMap[asst.AsstObjID] = asst.ObjectID
+	}
+
+	mainline := []string{common.BKInnerObjIDApp}
+	for objID := common.BKInnerObjIDApp; ; {
+		child, exists := childMap[objID]
+		if !exists {
+			break
+		}
+		mainline = append(mainline, child)
+		if len(mainline) > len(assts)+1 {
+			return nil, fmt.Errorf("mainline associations are invalid, mainline: %v", mainline)
+		}
+		objID = child
+	}
+
+	if mainline[len(mainline)-1] != common.BKInnerObjIDModule {
+		return nil, fmt.Errorf("mainline topology %v does not end with module", mainline)
+	}
+	return mainline, nil
+}
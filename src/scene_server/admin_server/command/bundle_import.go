@@ -0,0 +1,923 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package command
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"time"
+
+	"configcenter/src/common"
+	"configcenter/src/common/mapstr"
+	"configcenter/src/common/metadata"
+	"configcenter/src/common/util"
+	"configcenter/src/storage/dal"
+)
+
+// objectBaseMappingTable 自定义模型实例id与模型id的对应关系表
+const objectBaseMappingTable = "cc_ObjectBaseMapping"
+
+// maxRenameTimes 重命名时尝试的最大次数
+const maxRenameTimes = 100
+
+// importBundle 从可移植的导入导出包中导入数据，按冲突策略处理目标环境中已存在的数据，并重新生成数据的id
+func importBundle(ctx context.Context, db dal.RDB, opt *option) error {
+	format, err := getBundleFormat(opt.format, opt.position)
+	if err != nil {
+		return err
+	}
+
+	data, err := ioutil.ReadFile(opt.position)
+	if err != nil {
+		return err
+	}
+
+	bundle, err := unmarshalBundle(data, format)
+	if err != nil {
+		return fmt.Errorf("parse bundle file %s failed, err: %v", opt.position, err)
+	}
+
+	importer := &bundleImporter{
+		db:       db,
+		opt:      opt,
+		now:      time.Now(),
+		idMap:    make(map[string]map[int64]int64),
+		objIDMap: make(map[string]string),
+		stat:     make(map[string]int),
+	}
+	if err := importer.importBundle(ctx, bundle); err != nil {
+		return err
+	}
+
+	importer.printStat()
+	return nil
+}
+
+type bundleImporter struct {
+	db  dal.RDB
+	opt *option
+	now time.Time
+	// idMap 导出环境中数据的id到目标环境中数据的id的映射, map[table]map[source id]target id
+	idMap map[string]map[int64]int64
+	// objIDMap 导出环境中的模型id到目标环境中的模型id的映射，模型被重命名时两者不同
+	objIDMap map[string]string
+	// fakeID dryrun 时用于代替新建数据的id
+	fakeID int64
+	// stat 各种操作的数据条数
+	stat map[string]int
+}
+
+// saveOption 写入一条数据的选项
+type saveOption struct {
+	// kind 和 name 用于输出导入过程
+	kind  string
+	name  string
+	table string
+	// idField 数据的id字段，为空表示数据没有自增id
+	idField string
+	// cond 根据数据生成查询目标环境中已存在数据的条件
+	cond func(doc mapstr.MapStr) mapstr.MapStr
+	// nameFields 冲突策略为 rename 时需要重命名的字段，为空表示不支持重命名，按 skip 处理
+	nameFields []string
+	// beforeCreate 新建数据前调用，用于设置依赖自身id的字段
+	beforeCreate func(doc mapstr.MapStr, id int64)
+	// afterCreate 新建数据后调用，dryrun 时不调用
+	afterCreate func(ctx context.Context, id int64) error
+}
+
+func (bi *bundleImporter) importBundle(ctx context.Context, bundle *Bundle) error {
+	mainline, err := getMainline(ctx, bi.db)
+	if err != nil {
+		return err
+	}
+	if strings.Join(mainline, ",") != strings.Join(bundle.Mainline, ",") {
+		return fmt.Errorf("mainline topology %v is different from the bundle mainline topology %v, please create "+
+			"the custom mainline models first", mainline, bundle.Mainline)
+	}
+
+	for _, classification := range bundle.Classifications {
+		opt := &saveOption{
+			kind:    "classification",
+			name:    util.GetStrByInterface(classification[common.BKClassificationIDField]),
+			table:   common.BKTableNameObjClassification,
+			idField: common.BKFieldID,
+			cond:    bi.fieldsCond(common.BKClassificationIDField),
+		}
+		if _, err := bi.save(ctx, opt, classification); err != nil {
+			return err
+		}
+	}
+
+	for _, kind := range bundle.AssociationKinds {
+		opt := &saveOption{
+			kind:    "association kind",
+			name:    util.GetStrByInterface(kind[common.AssociationKindIDField]),
+			table:   common.BKTableNameAsstDes,
+			idField: common.BKFieldID,
+			cond:    bi.fieldsCond(common.AssociationKindIDField),
+		}
+		if _, err := bi.save(ctx, opt, kind); err != nil {
+			return err
+		}
+	}
+
+	if err := bi.importModels(ctx, bundle.Models, mainline); err != nil {
+		return err
+	}
+
+	for _, asst := range bundle.Associations {
+		objID := bi.objID(util.GetStrByInterface(asst[common.BKObjIDField]))
+		asstObjID := bi.objID(util.GetStrByInterface(asst[common.BKAsstObjIDField]))
+		asst[common.BKObjIDField] = objID
+		asst[common.BKAsstObjIDField] = asstObjID
+		asst[common.AssociationObjAsstIDField] = fmt.Sprintf("%s_%s_%s", objID,
+			util.GetStrByInterface(asst[common.AssociationKindIDField]), asstObjID)
+
+		opt := &saveOption{
+			kind:    "model association",
+			name:    util.GetStrByInterface(asst[common.AssociationObjAsstIDField]),
+			table:   common.BKTableNameObjAsst,
+			idField: common.BKFieldID,
+			cond:    bi.fieldsCond(common.AssociationObjAsstIDField),
+		}
+		if _, err := bi.save(ctx, opt, asst); err != nil {
+			return err
+		}
+	}
+
+	for _, biz := range bundle.Businesses {
+		if err := bi.importBusiness(ctx, biz); err != nil {
+			return fmt.Errorf("import business %v failed, err: %v", biz.Business[common.BKAppNameField], err)
+		}
+	}
+
+	return nil
+}
+
+// importModels 导入模型，内置模型和主线模型需要在目标环境中存在，只导入其自定义的字段分组、字段和唯一校验
+func (bi *bundleImporter) importModels(ctx context.Context, models []BundleModel, mainline []string) error {
+	mainlineMap := make(map[string]struct{}, len(mainline))
+	for _, objID := range mainline {
+		mainlineMap[objID] = struct{}{}
+	}
+
+	for _, model := range models {
+		objID := util.GetStrByInterface(model.Object[common.BKObjIDField])
+		isPre, _ := model.Object[common.BKIsPre].(bool)
+		if _, isMainline := mainlineMap[objID]; isPre || isMainline {
+			cnt, err := bi.db.Table(common.BKTableNameObjDes).Find(mapstr.MapStr{common.BKObjIDField: objID}).
+				Count(ctx)
+			if err != nil {
+				return fmt.Errorf("find model %s failed, err: %v", objID, err)
+			}
+			if cnt == 0 {
+				return fmt.Errorf("built-in or mainline model %s is not found", objID)
+			}
+			bi.objIDMap[objID] = objID
+			continue
+		}
+
+		opt := &saveOption{
+			kind:       "model",
+			name:       objID,
+			table:      common.BKTableNameObjDes,
+			idField:    common.BKFieldID,
+			cond:       bi.fieldsCond(common.BKObjIDField),
+			nameFields: []string{common.BKObjIDField, common.BKObjNameField},
+			afterCreate: func(ctx context.Context, id int64) error {
+				return bi.createModelTables(ctx, util.GetStrByInterface(model.Object[common.BKObjIDField]))
+			},
+		}
+		if _, err := bi.save(ctx, opt, model.Object); err != nil {
+			return err
+		}
+		bi.objIDMap[objID] = util.GetStrByInterface(model.Object[common.BKObjIDField])
+	}
+
+	for _, model := range models {
+		srcObjID := util.GetStrByInterface(model.Object[common.BKObjIDField])
+		objID := bi.objID(srcObjID)
+
+		for _, group := range model.Groups {
+			group[common.BKObjIDField] = objID
+			opt := &saveOption{
+				kind:    "attribute group",
+				name:    fmt.Sprintf("%s.%v", objID, group[common.BKPropertyGroupIDField]),
+				table:   common.BKTableNamePropertyGroup,
+				idField: common.BKFieldID,
+				cond:    bi.fieldsCond(common.BKObjIDField, common.BKPropertyGroupIDField, common.BKAppIDField),
+			}
+			if _, err := bi.save(ctx, opt, group); err != nil {
+				return err
+			}
+		}
+
+		for _, attr := range model.Attributes {
+			attr[common.BKObjIDField] = objID
+			if err := bi.importAttribute(ctx, attr); err != nil {
+				return err
+			}
+		}
+
+		for _, unique := range model.Uniques {
+			if err := bi.importUnique(ctx, objID, unique); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// createModelTables 创建自定义模型的实例表和实例关联关系表，表的索引由 admin_server 的同步任务创建
+func (bi *bundleImporter) createModelTables(ctx context.Context, objID string) error {
+	tables := []string{
+		common.GetObjectInstTableName(objID, bi.opt.OwnerID),
+		common.GetObjectInstAsstTableName(objID, bi.opt.OwnerID),
+	}
+	for _, table := range tables {
+		exists, err := bi.db.HasTable(ctx, table)
+		if err != nil {
+			return fmt.Errorf("check table %s failed, err: %v", table, err)
+		}
+		if exists {
+			continue
+		}
+		if err := bi.db.CreateTable(ctx, table); err != nil && !bi.db.IsDuplicatedError(err) {
+			return fmt.Errorf("create table %s failed, err: %v", table, err)
+		}
+	}
+	return nil
+}
+
+func (bi *bundleImporter) importAttribute(ctx context.Context, attr mapstr.MapStr) error {
+	opt := &saveOption{
+		kind:    "attribute",
+		name:    fmt.Sprintf("%v.%v", attr[common.BKObjIDField], attr[common.BKPropertyIDField]),
+		table:   common.BKTableNameObjAttDes,
+		idField: common.BKFieldID,
+		cond:    bi.fieldsCond(common.BKObjIDField, common.BKPropertyIDField, common.BKAppIDField),
+	}
+	_, err := bi.save(ctx, opt, attr)
+	return err
+}
+
+// importUnique 导入模型唯一校验，目标环境中已存在相同字段组合的唯一校验时跳过
+func (bi *bundleImporter) importUnique(ctx context.Context, objID string, unique BundleUnique) error {
+	name := fmt.Sprintf("%s(%s)", objID, strings.Join(unique.Keys, ","))
+
+	attrs := make([]metadata.Attribute, 0)
+	attrCond := mapstr.MapStr{
+		common.BKObjIDField:      objID,
+		common.BKPropertyIDField: mapstr.MapStr{common.BKDBIN: unique.Keys},
+		common.BKAppIDField:      0,
+	}
+	err := bi.db.Table(common.BKTableNameObjAttDes).Find(attrCond).Fields(common.BKFieldID,
+		common.BKPropertyIDField).All(ctx, &attrs)
+	if err != nil {
+		return fmt.Errorf("find model %s attributes failed, err: %v", objID, err)
+	}
+	attrIDMap := make(map[string]uint64, len(attrs))
+	for _, attr := range attrs {
+		attrIDMap[attr.PropertyID] = uint64(attr.ID)
+	}
+
+	keys := make([]metadata.UniqueKey, 0, len(unique.Keys))
+	keyIDs := make([]string, 0, len(unique.Keys))
+	for _, propertyID := range unique.Keys {
+		attrID, exists := attrIDMap[propertyID]
+		if !exists {
+			if bi.opt.dryrun {
+				// dryrun 时新建的字段不会写入db
+				bi.printAction("create", "unique", name)
+				return nil
+			}
+			return fmt.Errorf("unique %s attribute %s is not found", name, propertyID)
+		}
+		keys = append(keys, metadata.UniqueKey{Kind: metadata.UniqueKeyKindProperty, ID: attrID})
+		keyIDs = append(keyIDs, fmt.Sprint(attrID))
+	}
+	sort.Strings(keyIDs)
+
+	uniques := make([]metadata.ObjectUnique, 0)
+	if err := bi.db.Table(common.BKTableNameObjUnique).Find(mapstr.MapStr{common.BKObjIDField: objID}).
+		All(ctx, &uniques); err != nil {
+		return fmt.Errorf("find model %s uniques failed, err: %v", objID, err)
+	}
+	for _, exist := range uniques {
+		existKeyIDs := make([]string, 0, len(exist.Keys))
+		for _, key := range exist.Keys {
+			existKeyIDs = append(existKeyIDs, fmt.Sprint(key.ID))
+		}
+		sort.Strings(existKeyIDs)
+		if strings.Join(existKeyIDs, ",") == strings.Join(keyIDs, ",") {
+			bi.printAction("skip", "unique", name)
+			return nil
+		}
+	}
+
+	id, err := bi.nextID(ctx, common.BKTableNameObjUnique)
+	if err != nil {
+		return err
+	}
+	bi.setID(common.BKTableNameObjUnique, unique.ID, id)
+
+	bi.printAction("create", "unique", name)
+	if bi.opt.dryrun {
+		return nil
+	}
+
+	doc := metadata.ObjectUnique{
+		ID:       uint64(id),
+		ObjID:    objID,
+		Keys:     keys,
+		OwnerID:  bi.opt.OwnerID,
+		LastTime: metadata.Now(),
+	}
+	if err := bi.db.Table(common.BKTableNameObjUnique).Insert(ctx, doc); err != nil {
+		return fmt.Errorf("create unique %s failed, err: %v", name, err)
+	}
+	return nil
+}
+
+func (bi *bundleImporter) importBusiness(ctx context.Context, biz BundleBusiness) error {
+	srcBizID, err := util.GetInt64ByInterface(biz.Business[common.BKAppIDField])
+	if err != nil {
+		return fmt.Errorf("business id is invalid, err: %v", err)
+	}
+
+	opt := &saveOption{
+		kind:       "business",
+		name:       util.GetStrByInterface(biz.Business[common.BKAppNameField]),
+		table:      common.BKTableNameBaseApp,
+		idField:    common.BKAppIDField,
+		cond:       bi.fieldsCond(common.BKAppNameField, common.BKOwnerIDField),
+		nameFields: []string{common.BKAppNameField},
+	}
+	bizID, err := bi.save(ctx, opt, biz.Business)
+	if err != nil {
+		return err
+	}
+	bi.setID(common.BKTableNameBaseApp, srcBizID, bizID)
+
+	for _, attr := range biz.Attributes {
+		attr[common.BKAppIDField] = bizID
+		attr[common.BKObjIDField] = bi.objID(util.GetStrByInterface(attr[common.BKObjIDField]))
+		if err := bi.importAttribute(ctx, attr); err != nil {
+			return err
+		}
+	}
+
+	if err := bi.importServiceCategories(ctx, bizID, biz.ServiceCategories); err != nil {
+		return err
+	}
+
+	if err := bi.importTemplates(ctx, bizID, biz); err != nil {
+		return err
+	}
+
+	if err := bi.importTopology(ctx, bizID, bizID, biz.Topology); err != nil {
+		return err
+	}
+
+	return bi.importHostApplyRules(ctx, bizID, biz.HostApplyRules)
+}
+
+// importServiceCategories 导入服务分类，内置的服务分类只做id映射，需要在目标环境中存在
+func (bi *bundleImporter) importServiceCategories(ctx context.Context, bizID int64,
+	categories []mapstr.MapStr) error {
+
+	// 先处理一级分类，再处理二级分类
+	sort.SliceStable(categories, func(i, j int) bool {
+		iParentID, _ := util.GetInt64ByInterface(categories[i][common.BKParentIDField])
+		jParentID, _ := util.GetInt64ByInterface(categories[j][common.BKParentIDField])
+		return iParentID == 0 && jParentID != 0
+	})
+
+	for _, category := range categories {
+		srcID, err := util.GetInt64ByInterface(category[common.BKFieldID])
+		if err != nil {
+			return fmt.Errorf("service category id is invalid, err: %v", err)
+		}
+		srcBizID, err := util.GetInt64ByInterface(category[common.BKAppIDField])
+		if err != nil {
+			return fmt.Errorf("service category %d biz id is invalid, err: %v", srcID, err)
+		}
+		parentID, err := bi.getID(common.BKTableNameServiceCategory, category[common.BKParentIDField])
+		if err != nil {
+			return err
+		}
+		category[common.BKParentIDField] = parentID
+
+		if srcBizID == 0 {
+			id, err := bi.findBuiltInServiceCategory(ctx, category)
+			if err != nil {
+				return err
+			}
+			bi.setID(common.BKTableNameServiceCategory, srcID, id)
+			continue
+		}
+
+		category[common.BKAppIDField] = bizID
+		opt := &saveOption{
+			kind:    "service category",
+			name:    util.GetStrByInterface(category[common.BKFieldName]),
+			table:   common.BKTableNameServiceCategory,
+			idField: common.BKFieldID,
+			cond:    bi.fieldsCond(common.BKAppIDField, common.BKParentIDField, common.BKFieldName),
+		}
+		if parentID == 0 {
+			// 一级分类的 bk_root_id 是其自身的id
+			delete(category, common.BKRootIDField)
+			opt.beforeCreate = func(doc mapstr.MapStr, id int64) {
+				doc[common.BKRootIDField] = id
+			}
+		} else {
+			category[common.BKRootIDField] = parentID
+		}
+
+		id, err := bi.save(ctx, opt, category)
+		if err != nil {
+			return err
+		}
+		bi.setID(common.BKTableNameServiceCategory, srcID, id)
+	}
+
+	return nil
+}
+
+func (bi *bundleImporter) findBuiltInServiceCategory(ctx context.Context, category mapstr.MapStr) (int64, error) {
+	cond := mapstr.MapStr{
+		common.BKAppIDField:    0,
+		common.BKFieldName:     category[common.BKFieldName],
+		common.BKParentIDField: category[common.BKParentIDField],
+	}
+	categories := make([]metadata.ServiceCategory, 0)
+	if err := bi.db.Table(common.BKTableNameServiceCategory).Find(cond).All(ctx, &categories); err != nil {
+		return 0, fmt.Errorf("find built-in service category %v failed, err: %v", category[common.BKFieldName], err)
+	}
+	if len(categories) == 0 {
+		return 0, fmt.Errorf("built-in service category %v is not found", category[common.BKFieldName])
+	}
+	return categories[0].ID, nil
+}
+
+// importTemplates 导入服务模板、进程模板、集群模板及集群模板与服务模板的关系
+func (bi *bundleImporter) importTemplates(ctx context.Context, bizID int64, biz BundleBusiness) error {
+	for _, template := range biz.ServiceTemplates {
+		srcID, err := util.GetInt64ByInterface(template[common.BKFieldID])
+		if err != nil {
+			return fmt.Errorf("service template id is invalid, err: %v", err)
+		}
+		template[common.BKAppIDField] = bizID
+		if template[common.BKServiceCategoryIDField], err = bi.getID(common.BKTableNameServiceCategory,
+			template[common.BKServiceCategoryIDField]); err != nil {
+			return err
+		}
+
+		opt := &saveOption{
+			kind:       "service template",
+			name:       util.GetStrByInterface(template[common.BKFieldName]),
+			table:      common.BKTableNameServiceTemplate,
+			idField:    common.BKFieldID,
+			cond:       bi.fieldsCond(common.BKAppIDField, common.BKFieldName),
+			nameFields: []string{common.BKFieldName},
+		}
+		id, err := bi.save(ctx, opt, template)
+		if err != nil {
+			return err
+		}
+		bi.setID(common.BKTableNameServiceTemplate, srcID, id)
+	}
+
+	for _, template := range biz.ProcessTemplates {
+		var err error
+		template[common.BKAppIDField] = bizID
+		if template[common.BKServiceTemplateIDField], err = bi.getID(common.BKTableNameServiceTemplate,
+			template[common.BKServiceTemplateIDField]); err != nil {
+			return err
+		}
+
+		opt := &saveOption{
+			kind:    "process template",
+			name:    util.GetStrByInterface(template[common.BKProcessNameField]),
+			table:   common.BKTableNameProcessTemplate,
+			idField: common.BKFieldID,
+			cond: bi.fieldsCond(common.BKAppIDField, common.BKServiceTemplateIDField,
+				common.BKProcessNameField),
+		}
+		if _, err := bi.save(ctx, opt, template); err != nil {
+			return err
+		}
+	}
+
+	for _, template := range biz.SetTemplates {
+		srcID, err := util.GetInt64ByInterface(template[common.BKFieldID])
+		if err != nil {
+			return fmt.Errorf("set template id is invalid, err: %v", err)
+		}
+		template[common.BKAppIDField] = bizID
+
+		opt := &saveOption{
+			kind:       "set template",
+			name:       util.GetStrByInterface(template[common.BKFieldName]),
+			table:      common.BKTableNameSetTemplate,
+			idField:    common.BKFieldID,
+			cond:       bi.fieldsCond(common.BKAppIDField, common.BKFieldName),
+			nameFields: []string{common.BKFieldName},
+		}
+		id, err := bi.save(ctx, opt, template)
+		if err != nil {
+			return err
+		}
+		bi.setID(common.BKTableNameSetTemplate, srcID, id)
+	}
+
+	for _, relation := range biz.SetTemplateRelations {
+		var err error
+		relation[common.BKAppIDField] = bizID
+		if relation[common.BKSetTemplateIDField], err = bi.getID(common.BKTableNameSetTemplate,
+			relation[common.BKSetTemplateIDField]); err != nil {
+			return err
+		}
+		if relation[common.BKServiceTemplateIDField], err = bi.getID(common.BKTableNameServiceTemplate,
+			relation[common.BKServiceTemplateIDField]); err != nil {
+			return err
+		}
+
+		opt := &saveOption{
+			kind:  "set template relation",
+			name:  fmt.Sprintf("%v-%v", relation[common.BKSetTemplateIDField], relation[common.BKServiceTemplateIDField]),
+			table: common.BKTableNameSetServiceTemplateRelation,
+			cond: bi.fieldsCond(common.BKAppIDField, common.BKSetTemplateIDField,
+				common.BKServiceTemplateIDField),
+		}
+		if _, err := bi.save(ctx, opt, relation); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// importTopology 逐层导入业务下的主线拓扑实例
+func (bi *bundleImporter) importTopology(ctx context.Context, bizID, parentID int64, nodes []*Node) error {
+	for _, node := range nodes {
+		id, err := bi.importTopoNode(ctx, bizID, parentID, node)
+		if err != nil {
+			return err
+		}
+
+		if err := bi.importTopology(ctx, bizID, id, node.Children); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (bi *bundleImporter) importTopoNode(ctx context.Context, bizID, parentID int64, node *Node) (int64, error) {
+	objID := node.ObjID
+	table := common.GetInstTableName(objID, bi.opt.OwnerID)
+	idField := common.GetInstIDField(objID)
+	nameField := common.GetInstNameField(objID)
+
+	doc := mapstr.MapStr(node.Data)
+	srcID, err := util.GetInt64ByInterface(doc[idField])
+	if err != nil {
+		return 0, fmt.Errorf("%s instance id is invalid, err: %v", objID, err)
+	}
+	doc[common.BKAppIDField] = bizID
+	doc[common.BKParentIDField] = parentID
+
+	condFields := []string{common.BKAppIDField, common.BKParentIDField, nameField}
+	nameFields := []string{nameField}
+	// 空闲机池等内置的集群和模块按 default 字段匹配，不支持重命名，用户自定义的空闲机模块仍按名称匹配
+	defaultFlag, _ := util.GetInt64ByInterface(doc[common.BKDefaultField])
+	if isBuiltInTopoNode(objID, defaultFlag) {
+		condFields = []string{common.BKAppIDField, common.BKParentIDField, common.BKDefaultField}
+		nameFields = nil
+	}
+
+	opt := &saveOption{
+		kind:       objID,
+		name:       util.GetStrByInterface(doc[nameField]),
+		table:      table,
+		idField:    idField,
+		nameFields: nameFields,
+	}
+
+	switch objID {
+	case common.BKInnerObjIDSet:
+		if doc[common.BKSetTemplateIDField], err = bi.getID(common.BKTableNameSetTemplate,
+			doc[common.BKSetTemplateIDField]); err != nil {
+			return 0, err
+		}
+	case common.BKInnerObjIDModule:
+		doc[common.BKSetIDField] = parentID
+		if doc[common.BKSetTemplateIDField], err = bi.getID(common.BKTableNameSetTemplate,
+			doc[common.BKSetTemplateIDField]); err != nil {
+			return 0, err
+		}
+		if doc[common.BKServiceTemplateIDField], err = bi.getID(common.BKTableNameServiceTemplate,
+			doc[common.BKServiceTemplateIDField]); err != nil {
+			return 0, err
+		}
+		if doc[common.BKServiceCategoryIDField], err = bi.getID(common.BKTableNameServiceCategory,
+			doc[common.BKServiceCategoryIDField]); err != nil {
+			return 0, err
+		}
+	default:
+		doc[common.BKObjIDField] = objID
+		condFields = append(condFields, common.BKObjIDField)
+		opt.afterCreate = func(ctx context.Context, id int64) error {
+			mapping := metadata.ObjectMapping{ID: id, ObjectID: objID, OwnerID: bi.opt.OwnerID}
+			return bi.db.Table(objectBaseMappingTable).Insert(ctx, mapping)
+		}
+	}
+	opt.cond = bi.fieldsCond(condFields...)
+
+	id, err := bi.save(ctx, opt, doc)
+	if err != nil {
+		return 0, err
+	}
+	bi.setID(table, srcID, id)
+	return id, nil
+}
+
+// isBuiltInTopoNode 判断是否为业务内置的空闲机池、空闲机、故障机或待回收模块，这些节点在每个业务中唯一
+func isBuiltInTopoNode(objID string, defaultFlag int64) bool {
+	switch objID {
+	case common.BKInnerObjIDSet:
+		return defaultFlag == int64(common.DefaultResSetFlag)
+	case common.BKInnerObjIDModule:
+		return defaultFlag == int64(common.DefaultResModuleFlag) ||
+			defaultFlag == int64(common.DefaultFaultModuleFlag) || defaultFlag == int64(common.DefaultRecycleModuleFlag)
+	default:
+		return false
+	}
+}
+
+// importHostApplyRules 导入主机属性自动应用规则，根据 bk_property_id 找到目标环境中的主机字段
+func (bi *bundleImporter) importHostApplyRules(ctx context.Context, bizID int64, rules []mapstr.MapStr) error {
+	if len(rules) == 0 {
+		return nil
+	}
+
+	attrs := make([]metadata.Attribute, 0)
+	cond := mapstr.MapStr{
+		common.BKObjIDField: common.BKInnerObjIDHost,
+		common.BKAppIDField: mapstr.MapStr{common.BKDBIN: []int64{0, bizID}},
+	}
+	err := bi.db.Table(common.BKTableNameObjAttDes).Find(cond).Fields(common.BKFieldID, common.BKPropertyIDField).
+		All(ctx, &attrs)
+	if err != nil {
+		return fmt.Errorf("find host attributes failed, err: %v", err)
+	}
+	attrIDMap := make(map[string]int64, len(attrs))
+	for _, attr := range attrs {
+		attrIDMap[attr.PropertyID] = attr.ID
+	}
+
+	for _, rule := range rules {
+		propertyID := util.GetStrByInterface(rule[common.BKPropertyIDField])
+		delete(rule, common.BKPropertyIDField)
+		attrID, exists := attrIDMap[propertyID]
+		if !exists {
+			if !bi.opt.dryrun {
+				return fmt.Errorf("host attribute %s of host apply rule is not found", propertyID)
+			}
+			// dryrun 时新建的字段不会写入db
+			attrID = bi.fakeID + 1
+		}
+		rule[common.BKAttributeIDField] = attrID
+		rule[common.BKAppIDField] = bizID
+
+		if rule[common.BKModuleIDField], err = bi.getID(common.BKTableNameBaseModule,
+			rule[common.BKModuleIDField]); err != nil {
+			return err
+		}
+		if rule[common.BKServiceTemplateIDField], err = bi.getID(common.BKTableNameServiceTemplate,
+			rule[common.BKServiceTemplateIDField]); err != nil {
+			return err
+		}
+
+		opt := &saveOption{
+			kind: "host apply rule",
+			name: fmt.Sprintf("%s(module: %v, service template: %v)", propertyID, rule[common.BKModuleIDField],
+				rule[common.BKServiceTemplateIDField]),
+			table:   common.BKTableNameHostApplyRule,
+			idField: common.BKFieldID,
+			cond: bi.fieldsCond(common.BKAppIDField, common.BKModuleIDField, common.BKServiceTemplateIDField,
+				common.BKAttributeIDField),
+		}
+		if _, err := bi.save(ctx, opt, rule); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// save 按冲突策略写入一条数据，返回数据在目标环境中的id
+func (bi *bundleImporter) save(ctx context.Context, opt *saveOption, doc mapstr.MapStr) (int64, error) {
+	delete(doc, "_id")
+	doc[common.BKOwnerIDField] = bi.opt.OwnerID
+
+	existing := make([]mapstr.MapStr, 0)
+	if err := bi.db.Table(opt.table).Find(opt.cond(doc)).Limit(1).All(ctx, &existing); err != nil {
+		return 0, fmt.Errorf("find %s %s failed, err: %v", opt.kind, opt.name, err)
+	}
+
+	if len(existing) > 0 {
+		var existID int64
+		if opt.idField != "" {
+			var err error
+			if existID, err = util.GetInt64ByInterface(existing[0][opt.idField]); err != nil {
+				return 0, fmt.Errorf("%s %s id is invalid, err: %v", opt.kind, opt.name, err)
+			}
+		}
+
+		switch {
+		case bi.opt.conflict == conflictOverwrite:
+			return existID, bi.overwrite(ctx, opt, doc, existID)
+		case bi.opt.conflict == conflictRename && len(opt.nameFields) > 0:
+			if err := bi.rename(ctx, opt, doc); err != nil {
+				return 0, err
+			}
+		default:
+			bi.printAction("skip", opt.kind, opt.name)
+			return existID, nil
+		}
+	}
+
+	var id int64
+	if opt.idField != "" {
+		var err error
+		if id, err = bi.nextID(ctx, opt.table); err != nil {
+			return 0, err
+		}
+		doc[opt.idField] = id
+		if opt.beforeCreate != nil {
+			opt.beforeCreate(doc, id)
+		}
+	}
+	if _, exists := doc[common.CreateTimeField]; exists {
+		doc[common.CreateTimeField] = bi.now
+	}
+	if _, exists := doc[common.LastTimeField]; exists {
+		doc[common.LastTimeField] = bi.now
+	}
+
+	bi.printAction("create", opt.kind, opt.name)
+	if bi.opt.dryrun {
+		return id, nil
+	}
+
+	if err := bi.db.Table(opt.table).Insert(ctx, doc); err != nil {
+		return 0, fmt.Errorf("create %s %s failed, err: %v", opt.kind, opt.name, err)
+	}
+	if opt.afterCreate != nil {
+		if err := opt.afterCreate(ctx, id); err != nil {
+			return 0, fmt.Errorf("create %s %s failed, err: %v", opt.kind, opt.name, err)
+		}
+	}
+	return id, nil
+}
+
+// overwrite 使用导入包中的数据覆盖目标环境中已存在的数据， 数据的id和创建信息不变
+func (bi *bundleImporter) overwrite(ctx context.Context, opt *saveOption, doc mapstr.MapStr, id int64) error {
+	update := doc.Clone()
+	delete(update, common.CreateTimeField)
+	delete(update, common.CreatorField)
+	if _, exists := update[common.LastTimeField]; exists {
+		update[common.LastTimeField] = bi.now
+	}
+
+	cond := opt.cond(doc)
+	if opt.idField != "" {
+		delete(update, opt.idField)
+		cond = mapstr.MapStr{opt.idField: id}
+	}
+
+	bi.printAction("overwrite", opt.kind, opt.name)
+	if bi.opt.dryrun {
+		return nil
+	}
+
+	if err := bi.db.Table(opt.table).Update(ctx, cond, update); err != nil {
+		return fmt.Errorf("overwrite %s %s failed, err: %v", opt.kind, opt.name, err)
+	}
+	return nil
+}
+
+// rename 在名称字段后添加序号，直到目标环境中不存在冲突的数据
+func (bi *bundleImporter) rename(ctx context.Context, opt *saveOption, doc mapstr.MapStr) error {
+	origin := make(map[string]string, len(opt.nameFields))
+	for _, field := range opt.nameFields {
+		origin[field] = util.GetStrByInterface(doc[field])
+	}
+
+	for idx := 1; idx <= maxRenameTimes; idx++ {
+		for _, field := range opt.nameFields {
+			doc[field] = fmt.Sprintf("%s_%d", origin[field], idx)
+		}
+
+		cnt, err := bi.db.Table(opt.table).Find(opt.cond(doc)).Count(ctx)
+		if err != nil {
+			return fmt.Errorf("find %s %s failed, err: %v", opt.kind, opt.name, err)
+		}
+		if cnt == 0 {
+			newName := util.GetStrByInterface(doc[opt.nameFields[0]])
+			bi.printAction("rename", opt.kind, fmt.Sprintf("%s -> %s", opt.name, newName))
+			opt.name = newName
+			return nil
+		}
+	}
+
+	return fmt.Errorf("rename %s %s failed, all the candidate names are used", opt.kind, opt.name)
+}
+
+// fieldsCond 返回使用数据中的字段值作为查询条件的函数
+func (bi *bundleImporter) fieldsCond(fields ...string) func(doc mapstr.MapStr) mapstr.MapStr {
+	return func(doc mapstr.MapStr) mapstr.MapStr {
+		cond := make(mapstr.MapStr, len(fields))
+		for _, field := range fields {
+			cond[field] = doc[field]
+		}
+		return cond
+	}
+}
+
+func (bi *bundleImporter) nextID(ctx context.Context, table string) (int64, error) {
+	if bi.opt.dryrun {
+		bi.fakeID++
+		return bi.fakeID, nil
+	}
+
+	id, err := bi.db.NextSequence(ctx, table)
+	if err != nil {
+		return 0, fmt.Errorf("generate %s id failed, err: %v", table, err)
+	}
+	return int64(id), nil
+}
+
+func (bi *bundleImporter) setID(table string, srcID, id int64) {
+	if _, exists := bi.idMap[table]; !exists {
+		bi.idMap[table] = make(map[int64]int64)
+	}
+	bi.idMap[table][srcID] = id
+}
+
+// getID 返回导出环境中的id对应的目标环境中的id，id为0表示没有引用数据
+func (bi *bundleImporter) getID(table string, srcID interface{}) (int64, error) {
+	if srcID == nil {
+		return 0, nil
+	}
+
+	id, err := util.GetInt64ByInterface(srcID)
+	if err != nil {
+		return 0, fmt.Errorf("%s id %v is invalid, err: %v", table, srcID, err)
+	}
+	if id == 0 {
+		return 0, nil
+	}
+
+	targetID, exists := bi.idMap[table][id]
+	if !exists {
+		return 0, fmt.Errorf("%s id %d is not found in the bundle", table, id)
+	}
+	return targetID, nil
+}
+
+func (bi *bundleImporter) objID(srcObjID string) string {
+	if objID, exists := bi.objIDMap[srcObjID]; exists {
+		return objID
+	}
+	return srcObjID
+}
+
+func (bi *bundleImporter) printAction(action, kind, name string) {
+	bi.stat[action]++
+	if bi.opt.dryrun {
+		fmt.Printf("[dryrun] %s %s %s\n", action, kind, name)
+		return
+	}
+	fmt.Printf("%s %s %s\n", action, kind, name)
+}
+
+func (bi *bundleImporter) printStat() {
+	fmt.Printf("created: %d, overwritten: %d, renamed: %d, skipped: %d\n", bi.stat["create"],
+		bi.stat["overwrite"], bi.stat["rename"], bi.stat["skip"])
+}
@@ -0,0 +1,344 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package command
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"configcenter/src/common"
+	"configcenter/src/common/mapstr"
+	"configcenter/src/storage/dal"
+	"configcenter/src/storage/dal/types"
+)
+
+func TestGetBundleFormat(t *testing.T) {
+	cases := []struct {
+		format   string
+		position string
+		expect   string
+		hasErr   bool
+	}{
+		{format: "", position: "bundle.json", expect: bundleFormatJSON},
+		{format: "", position: "bundle.YAML", expect: bundleFormatYAML},
+		{format: "", position: "bundle.yml", expect: bundleFormatYAML},
+		{format: "yaml", position: "bundle.json", expect: bundleFormatYAML},
+		{format: "xml", position: "bundle.json", hasErr: true},
+	}
+
+	for _, c := range cases {
+		format, err := getBundleFormat(c.format, c.position)
+		if c.hasErr != (err != nil) {
+			t.Errorf("format %s position %s, unexpected err: %v", c.format, c.position, err)
+			continue
+		}
+		if format != c.expect {
+			t.Errorf("format %s position %s, expect %s, got %s", c.format, c.position, c.expect, format)
+		}
+	}
+}
+
+func TestMarshalBundle(t *testing.T) {
+	module := newNode("module")
+	module.Data = map[string]interface{}{"bk_module_id": int64(3), "bk_module_name": "gse"}
+	set := newNode("set")
+	set.Data = map[string]interface{}{"bk_set_id": int64(2), "bk_capacity": 1.5}
+	set.Children = []*Node{module}
+
+	bundle := &Bundle{
+		Version:    bundleVersion,
+		ExportTime: time.Now(),
+		Mainline:   []string{"biz", "set", "module"},
+		Businesses: []BundleBusiness{{
+			Business: mapstr.MapStr{"bk_biz_id": int64(2), "bk_biz_name": "demo"},
+			Topology: []*Node{set},
+		}},
+	}
+
+	for _, format := range []string{bundleFormatJSON, bundleFormatYAML} {
+		data, err := marshalBundle(bundle, format)
+		if err != nil {
+			t.Fatalf("marshal %s bundle failed, err: %v", format, err)
+		}
+
+		result, err := unmarshalBundle(data, format)
+		if err != nil {
+			t.Fatalf("unmarshal %s bundle failed, err: %v", format, err)
+		}
+
+		biz := result.Businesses[0]
+		if biz.Business["bk_biz_id"] != int64(2) {
+			t.Errorf("%s bundle biz id should be int64, got %#v", format, biz.Business["bk_biz_id"])
+		}
+		if biz.Topology[0].Data["bk_capacity"] != 1.5 {
+			t.Errorf("%s bundle set capacity should be float64, got %#v", format, biz.Topology[0].Data["bk_capacity"])
+		}
+		if biz.Topology[0].Children[0].Data["bk_module_id"] != int64(3) {
+			t.Errorf("%s bundle module id should be int64, got %#v", format,
+				biz.Topology[0].Children[0].Data["bk_module_id"])
+		}
+	}
+
+	bundle.Version = "v0"
+	data, _ := marshalBundle(bundle, bundleFormatJSON)
+	if _, err := unmarshalBundle(data, bundleFormatJSON); err == nil {
+		t.Errorf("unsupported bundle version should return error")
+	}
+}
+
+// fakeDB is an in-memory db that only supports the operations used by the bundle importer
+type fakeDB struct {
+	dal.RDB
+	tables   map[string][]mapstr.MapStr
+	sequence map[string]uint64
+}
+
+func newFakeDB() *fakeDB {
+	return &fakeDB{tables: make(map[string][]mapstr.MapStr), sequence: make(map[string]uint64)}
+}
+
+// Table returns the fake table of the collection
+func (db *fakeDB) Table(collection string) types.Table {
+	return &fakeTable{db: db, name: collection}
+}
+
+// NextSequence returns the next id of the table
+func (db *fakeDB) NextSequence(_ context.Context, sequenceName string) (uint64, error) {
+	db.sequence[sequenceName]++
+	return db.sequence[sequenceName], nil
+}
+
+type fakeTable struct {
+	types.Table
+	db   *fakeDB
+	name string
+}
+
+// Find returns the fake find of the filter, the filter only supports the equal conditions
+func (t *fakeTable) Find(filter types.Filter, _ ...*types.FindOpts) types.Find {
+	return &fakeFind{table: t, filter: filter.(mapstr.MapStr)}
+}
+
+// Insert inserts the doc into the table
+func (t *fakeTable) Insert(_ context.Context, doc interface{}) error {
+	t.db.tables[t.name] = append(t.db.tables[t.name], doc.(mapstr.MapStr).Clone())
+	return nil
+}
+
+// Update updates the docs that match the filter
+func (t *fakeTable) Update(_ context.Context, filter types.Filter, doc interface{}) error {
+	for _, data := range t.db.tables[t.name] {
+		if matchFilter(data, filter.(mapstr.MapStr)) {
+			data.Merge(doc.(mapstr.MapStr))
+		}
+	}
+	return nil
+}
+
+type fakeFind struct {
+	types.Find
+	table  *fakeTable
+	filter mapstr.MapStr
+}
+
+// Limit is ignored by the fake find
+func (f *fakeFind) Limit(_ uint64) types.Find {
+	return f
+}
+
+// All finds the docs that match the filter
+func (f *fakeFind) All(_ context.Context, result interface{}) error {
+	docs := result.(*[]mapstr.MapStr)
+	for _, data := range f.table.db.tables[f.table.name] {
+		if matchFilter(data, f.filter) {
+			*docs = append(*docs, data.Clone())
+		}
+	}
+	return nil
+}
+
+// Count counts the docs that match the filter
+func (f *fakeFind) Count(ctx context.Context) (uint64, error) {
+	docs := make([]mapstr.MapStr, 0)
+	if err := f.All(ctx, &docs); err != nil {
+		return 0, err
+	}
+	return uint64(len(docs)), nil
+}
+
+func matchFilter(data, filter mapstr.MapStr) bool {
+	for field, value := range filter {
+		if fmt.Sprint(data[field]) != fmt.Sprint(value) {
+			return false
+		}
+	}
+	return true
+}
+
+func newTestImporter(db *fakeDB, conflict string, dryrun bool) *bundleImporter {
+	return &bundleImporter{
+		db:       db,
+		opt:      &option{OwnerID: common.BKDefaultOwnerID, conflict: conflict, dryrun: dryrun},
+		now:      time.Now(),
+		idMap:    make(map[string]map[int64]int64),
+		objIDMap: make(map[string]string),
+		stat:     make(map[string]int),
+	}
+}
+
+func TestImportTopology(t *testing.T) {
+	const bizID int64 = 10
+	db := newFakeDB()
+	db.sequence[common.BKTableNameBaseSet] = 20
+	db.sequence[common.BKTableNameBaseModule] = 30
+	db.tables[common.BKTableNameBaseSet] = []mapstr.MapStr{{
+		common.BKSetIDField: int64(11), common.BKSetNameField: "空闲机池", common.BKAppIDField: bizID,
+		common.BKParentIDField: bizID, common.BKDefaultField: common.DefaultResSetFlag,
+	}}
+	db.tables[common.BKTableNameBaseModule] = []mapstr.MapStr{{
+		common.BKModuleIDField: int64(12), common.BKModuleNameField: "空闲机", common.BKAppIDField: bizID,
+		common.BKParentIDField: int64(11), common.BKDefaultField: common.DefaultResModuleFlag,
+	}, {
+		common.BKModuleIDField: int64(13), common.BKModuleNameField: "existing", common.BKAppIDField: bizID,
+		common.BKParentIDField: int64(11), common.BKDefaultField: common.DefaultResSelfDefinedModuleFlag,
+	}}
+
+	newModule := func(id int64, name string, defaultFlag int) *Node {
+		module := newNode(common.BKInnerObjIDModule)
+		module.Data = map[string]interface{}{common.BKModuleIDField: id, common.BKModuleNameField: name,
+			common.BKDefaultField: defaultFlag}
+		return module
+	}
+	idleSet := newNode(common.BKInnerObjIDSet)
+	idleSet.Data = map[string]interface{}{common.BKSetIDField: int64(2), common.BKSetNameField: "idle pool",
+		common.BKDefaultField: common.DefaultResSetFlag}
+	idleSet.Children = []*Node{newModule(3, "idle", common.DefaultResModuleFlag),
+		newModule(4, "custom idle", common.DefaultResSelfDefinedModuleFlag)}
+	set := newNode(common.BKInnerObjIDSet)
+	set.Data = map[string]interface{}{common.BKSetIDField: int64(5), common.BKSetNameField: "gse",
+		common.BKDefaultField: common.DefaultFlagDefaultValue}
+	set.Children = []*Node{newModule(6, "gse", common.NormalModuleFlag)}
+
+	bi := newTestImporter(db, conflictSkip, false)
+	if err := bi.importTopology(context.Background(), bizID, bizID, []*Node{idleSet, set}); err != nil {
+		t.Fatalf("import topology failed, err: %v", err)
+	}
+
+	// the built-in nodes are matched by the default flag, the others are matched by name and get new ids
+	expectSetIDs := map[int64]int64{2: 11, 5: 21}
+	expectModuleIDs := map[int64]int64{3: 12, 4: 31, 6: 32}
+	for srcID, id := range expectSetIDs {
+		if bi.idMap[common.BKTableNameBaseSet][srcID] != id {
+			t.Errorf("set %d should be mapped to %d, got %d", srcID, id, bi.idMap[common.BKTableNameBaseSet][srcID])
+		}
+	}
+	for srcID, id := range expectModuleIDs {
+		if bi.idMap[common.BKTableNameBaseModule][srcID] != id {
+			t.Errorf("module %d should be mapped to %d, got %d", srcID, id,
+				bi.idMap[common.BKTableNameBaseModule][srcID])
+		}
+	}
+
+	modules := make([]mapstr.MapStr, 0)
+	cond := mapstr.MapStr{common.BKModuleIDField: int64(32)}
+	_ = db.Table(common.BKTableNameBaseModule).Find(cond).All(context.Background(), &modules)
+	if len(modules) != 1 || modules[0][common.BKParentIDField] != int64(21) ||
+		modules[0][common.BKSetIDField] != int64(21) || modules[0][common.BKAppIDField] != bizID {
+		t.Errorf("created module should be under the new set 21 of business %d, got %v", bizID, modules)
+	}
+	if bi.stat["create"] != 3 || bi.stat["skip"] != 2 {
+		t.Errorf("expect 3 created and 2 skipped nodes, got stat %v", bi.stat)
+	}
+}
+
+func TestImporterSave(t *testing.T) {
+	const table = common.BKTableNameServiceCategory
+	cases := []struct {
+		name     string
+		conflict string
+		dryrun   bool
+		// expectID is the id returned by save
+		expectID int64
+		// expect is the names of the docs in the table after save
+		expect []string
+	}{
+		{name: "skip", conflict: conflictSkip, expectID: 1, expect: []string{"old"}},
+		{name: "overwrite", conflict: conflictOverwrite, expectID: 1, expect: []string{"demo"}},
+		{name: "rename", conflict: conflictRename, expectID: 3, expect: []string{"old", "demo_1"}},
+		{name: "dryrun overwrite", conflict: conflictOverwrite, dryrun: true, expectID: 1, expect: []string{"old"}},
+		{name: "dryrun rename", conflict: conflictRename, dryrun: true, expectID: 1, expect: []string{"old"}},
+	}
+
+	for _, c := range cases {
+		db := newFakeDB()
+		db.sequence[table] = 2
+		db.tables[table] = []mapstr.MapStr{{common.BKFieldID: int64(1), common.BKFieldName: "demo",
+			common.BKDescriptionField: "old"}}
+		bi := newTestImporter(db, c.conflict, c.dryrun)
+
+		opt := &saveOption{
+			kind:       "service category",
+			name:       "demo",
+			table:      table,
+			idField:    common.BKFieldID,
+			cond:       bi.fieldsCond(common.BKFieldName),
+			nameFields: []string{common.BKFieldName},
+		}
+		doc := mapstr.MapStr{common.BKFieldID: int64(100), common.BKFieldName: "demo", common.BKDescriptionField: "new"}
+		id, err := bi.save(context.Background(), opt, doc)
+		if err != nil {
+			t.Errorf("%s: save failed, err: %v", c.name, err)
+			continue
+		}
+		if id != c.expectID {
+			t.Errorf("%s: expect id %d, got %d", c.name, c.expectID, id)
+		}
+
+		got := make([]string, 0)
+		for _, data := range db.tables[table] {
+			if data[common.BKDescriptionField] == "new" {
+				got = append(got, data[common.BKFieldName].(string))
+				continue
+			}
+			got = append(got, "old")
+		}
+		if fmt.Sprint(got) != fmt.Sprint(c.expect) {
+			t.Errorf("%s: expect docs %v, got %v", c.name, c.expect, got)
+		}
+	}
+}
+
+func TestIsBuiltInTopoNode(t *testing.T) {
+	cases := []struct {
+		objID       string
+		defaultFlag int
+		expect      bool
+	}{
+		{objID: common.BKInnerObjIDSet, defaultFlag: common.DefaultResSetFlag, expect: true},
+		{objID: common.BKInnerObjIDSet, defaultFlag: common.DefaultFlagDefaultValue, expect: false},
+		{objID: common.BKInnerObjIDModule, defaultFlag: common.DefaultResModuleFlag, expect: true},
+		{objID: common.BKInnerObjIDModule, defaultFlag: common.DefaultFaultModuleFlag, expect: true},
+		{objID: common.BKInnerObjIDModule, defaultFlag: common.DefaultRecycleModuleFlag, expect: true},
+		{objID: common.BKInnerObjIDModule, defaultFlag: common.DefaultResSelfDefinedModuleFlag, expect: false},
+		{objID: common.BKInnerObjIDModule, defaultFlag: common.NormalModuleFlag, expect: false},
+		{objID: "custom", defaultFlag: 1, expect: false},
+	}
+
+	for _, c := range cases {
+		if got := isBuiltInTopoNode(c.objID, int64(c.defaultFlag)); got != c.expect {
+			t.Errorf("object %s default %d, expect %v, got %v", c.objID, c.defaultFlag, c.expect, got)
+		}
+	}
+}
@@ -20,6 +20,7 @@ import (
 
 	"configcenter/src/common"
 	cc "configcenter/src/common/backbone/configcenter"
+	"configcenter/src/storage/dal"
 	"configcenter/src/storage/dal/mongo/local"
 
 	"github.com/spf13/pflag"
@@ -43,6 +44,10 @@ func Parse(args []string) error {
 		configPosition string
 		bizName        string
 		scope          string
+		bundleFlag     bool
+		format         string
+		bizNames       []string
+		conflict       string
 	)
 
 	if len(args) <= 1 || args[1] != bkbizCmdName {
@@ -59,6 +64,14 @@ func Parse(args []string) error {
 	cmdFlags.StringVar(&filePath, "file", "", "export/import filepath")
 	cmdFlags.StringVar(&configPosition, "config", "conf/api.conf", "The config path. e.g conf/api.conf")
 	cmdFlags.StringVar(&bizName, "biz_name", "蓝鲸", "export/import the specified business topo")
+	cmdFlags.BoolVar(&bundleFlag, "bundle", false, "bundle flag, export/import multiple businesses, models and "+
+		"templates in the portable bundle format, see docs/wiki/bkbiz_bundle.md")
+	cmdFlags.StringVar(&format, "format", "", "bundle file format, could be [json] or [yaml], default is decided "+
+		"by the file extension")
+	cmdFlags.StringSliceVar(&bizNames, "biz_names", nil, "export the specified businesses in bundle, separated "+
+		"by comma, default all businesses")
+	cmdFlags.StringVar(&conflict, "conflict", conflictSkip, "bundle import conflict policy, could be [skip], "+
+		"[overwrite] or [rename]")
 	err := cmdFlags.Parse(args[1:])
 
 	if err != nil {
@@ -86,6 +99,14 @@ func Parse(args []string) error {
 		mini:     miniFlag,
 		scope:    scope,
 		bizName:  bizName,
+		bundle:   bundleFlag,
+		format:   format,
+		bizNames: bizNames,
+		conflict: conflict,
+	}
+
+	if bundleFlag {
+		return runBundle(ctx, db, exportFlag, importFlag, opt)
 	}
 
 	if exportFlag {
@@ -124,3 +145,40 @@ func Parse(args []string) error {
 	os.Exit(0)
 	return nil
 }
+
+// runBundle 使用可移植的导入导出包格式导出或导入数据
+func runBundle(ctx context.Context, db dal.RDB, exportFlag, importFlag bool, opt *option) error {
+	switch opt.conflict {
+	case conflictSkip, conflictOverwrite, conflictRename:
+	default:
+		fmt.Printf("invalid conflict policy %s, could be [skip], [overwrite] or [rename]\n", opt.conflict)
+		os.Exit(2)
+	}
+
+	if exportFlag {
+		fmt.Printf("exporting bundle to %s\n", opt.position)
+		if err := exportBundle(ctx, db, opt); err != nil {
+			fmt.Printf("export error: %s\n", err.Error())
+			os.Exit(2)
+		}
+		fmt.Printf("bundle has been export to %s\n", opt.position)
+	} else if importFlag {
+		if opt.dryrun {
+			fmt.Printf("dryrun import bundle from %s with conflict policy %s\n", opt.position, opt.conflict)
+		} else {
+			fmt.Printf("importing bundle from %s with conflict policy %s\n", opt.position, opt.conflict)
+		}
+		if err := importBundle(ctx, db, opt); err != nil {
+			fmt.Printf("import error: %s\n", err.Error())
+			os.Exit(2)
+		}
+		if !opt.dryrun {
+			fmt.Printf("bundle has been import from %s\n", opt.position)
+		}
+	} else {
+		fmt.Printf("invalid argument\n")
+	}
+
+	os.Exit(0)
+	return nil
+}
@@ -27,6 +27,11 @@ type option struct {
 	mini     bool
 	scope    string
 	bizName  string
+	// bundle 使用可移植的导入导出包格式
+	bundle   bool
+	format   string
+	bizNames []string
+	conflict string
 }
 
 // Node topo node define
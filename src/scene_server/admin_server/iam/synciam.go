@@ -99,7 +99,7 @@ func newKit() *rest.Kit {
 
 // SyncIAM sync the system instances resource between CMDB and IAM
 func (s *syncor) SyncIAM(iamCli *iamcli.IAM, lgc *logics.Logics) {
-	if !auth.EnableAuthorize() {
+	if !auth.EnableAuthorize() || iamcli.IsLocalAuthMode() {
 		return
 	}
 	time.Sleep(time.Minute)
@@ -81,4 +81,12 @@ import (
 	_ "configcenter/src/scene_server/admin_server/upgrader/y3.10.202208032125"
 	_ "configcenter/src/scene_server/admin_server/upgrader/y3.10.202209231617"
 	_ "configcenter/src/scene_server/admin_server/upgrader/y3.10.202209281408"
+	_ "configcenter/src/scene_server/admin_server/upgrader/y3.10.202210101630"
+	_ "configcenter/src/scene_server/admin_server/upgrader/y3.10.202210171030"
+	_ "configcenter/src/scene_server/admin_server/upgrader/y3.10.202210181030"
+	_ "configcenter/src/scene_server/admin_server/upgrader/y3.10.202210191030"
+	_ "configcenter/src/scene_server/admin_server/upgrader/y3.10.202210201030"
+	_ "configcenter/src/scene_server/admin_server/upgrader/y3.10.202210211030"
+	_ "configcenter/src/scene_server/admin_server/upgrader/y3.10.202210221030"
+	_ "configcenter/src/scene_server/admin_server/upgrader/y3.10.202210231030"
 )
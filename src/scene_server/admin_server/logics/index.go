@@ -129,32 +129,44 @@ func RunSyncDBTableIndex(ctx context.Context, e *backbone.Engine, db dal.RDB,
 // RunSyncDBIndex TODO
 func RunSyncDBIndex(ctx context.Context, e *backbone.Engine) error {
 	rid := util.ExtractRequestIDFromContext(ctx)
+
+	db, err := getReadyDB(ctx, e)
+	if err != nil {
+		return err
+	}
+
+	dt := &dbTable{db: db, rid: rid}
+	blog.Infof("start table common index rid: %s", rid)
+	if err := dt.syncIndexes(ctx); err != nil {
+		blog.Errorf("model table sync error. err: %s, rid: %s", err.Error(), dt.rid)
+	}
+	blog.Infof("end sync table index rid: %s", rid)
+
+	return nil
+}
+
+// getReadyDB 返回同步索引使用的db， db 需要已经初始化完成
+func getReadyDB(ctx context.Context, e *backbone.Engine) (dal.RDB, error) {
+	rid := util.ExtractRequestIDFromContext(ctx)
 	ccErr := e.CCErr.CreateDefaultCCErrorIf("en")
 
 	if defaultDBTable == nil {
 		blog.Errorf("db client not initialization is complete, rid: %s", rid)
-		return ccErr.CCError(common.CCErrCommDBSelectFailed)
+		return nil, ccErr.CCError(common.CCErrCommDBSelectFailed)
 	}
 	// defaultDBTable DBSync 负责在启动时候初始化
 	dbReady, err := upgrader.DBReady(ctx, defaultDBTable)
 	if err != nil {
 		blog.Errorf("Check whether the db initialization is complete error. err: %s rid: %s", err.Error(), rid)
-		return ccErr.CCError(common.CCErrCommDBSelectFailed)
+		return nil, ccErr.CCError(common.CCErrCommDBSelectFailed)
 	}
 	if !dbReady {
 		blog.Errorf("db not initialization is complete, rid: %s", rid)
-		return ccErr.CCError(common.CCErrCommDBSelectFailed)
+		return nil, ccErr.CCError(common.CCErrCommDBSelectFailed)
 
 	}
 
-	dt := &dbTable{db: defaultDBTable, rid: rid}
-	blog.Infof("start table common index rid: %s", rid)
-	if err := dt.syncIndexes(ctx); err != nil {
-		blog.Errorf("model table sync error. err: %s, rid: %s", err.Error(), dt.rid)
-	}
-	blog.Infof("end sync table index rid: %s", rid)
-
-	return nil
+	return defaultDBTable, nil
 }
 
 // syncIndexes 同步表中定义的索引
@@ -0,0 +1,313 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logics
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"configcenter/src/common"
+	"configcenter/src/common/backbone"
+	"configcenter/src/common/blog"
+	"configcenter/src/common/index"
+	"configcenter/src/common/mapstr"
+	"configcenter/src/common/metadata"
+	"configcenter/src/common/util"
+	"configcenter/src/storage/dal/types"
+)
+
+// FindIndexDrift 对比表中定义的索引和db中实际存在的索引，只读取不修改db中的数据
+func FindIndexDrift(ctx context.Context, e *backbone.Engine, opt *metadata.IndexDriftOption) (
+	*metadata.IndexDriftReport, error) {
+
+	rid := util.ExtractRequestIDFromContext(ctx)
+	db, err := getReadyDB(ctx, e)
+	if err != nil {
+		return nil, err
+	}
+
+	dt := &dbTable{db: db, rid: rid}
+	drifts, err := dt.findIndexDrift(ctx, opt.Tables, true)
+	if err != nil {
+		return nil, e.CCErr.CreateDefaultCCErrorIf("en").CCError(common.CCErrCommDBSelectFailed)
+	}
+
+	return &metadata.IndexDriftReport{CheckTime: time.Now(), Tables: drifts}, nil
+}
+
+// findIndexDrift 返回存在索引差异的表， tables 为空时检查所有定义了索引的表
+func (dt *dbTable) findIndexDrift(ctx context.Context, tables []string, withUsage bool) (
+	[]metadata.TableIndexDrift, error) {
+
+	logicIndexes, err := dt.findLogicTableIndexes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	dbTables, err := dt.db.ListTables(ctx)
+	if err != nil {
+		blog.Errorf("list db tables failed, err: %v, rid: %s", err, dt.rid)
+		return nil, err
+	}
+	dbTableMap := make(map[string]struct{}, len(dbTables))
+	for _, table := range dbTables {
+		dbTableMap[table] = struct{}{}
+	}
+
+	if len(tables) == 0 {
+		for table := range logicIndexes {
+			tables = append(tables, table)
+		}
+	}
+	tables = util.StrArrayUnique(tables)
+	sort.Strings(tables)
+
+	deprecatedIndexNames := index.DeprecatedIndexName()
+	drifts := make([]metadata.TableIndexDrift, 0)
+	for _, table := range tables {
+		if _, exists := dbTableMap[table]; !exists {
+			if len(logicIndexes[table]) == 0 {
+				continue
+			}
+			drifts = append(drifts, metadata.TableIndexDrift{
+				Table:        table,
+				TableMissing: true,
+				Missing:      logicIndexes[table],
+			})
+			continue
+		}
+
+		dbIndexes, err := dt.db.Table(table).Indexes(ctx)
+		if err != nil {
+			blog.Errorf("find db table(%s) index list failed, err: %v, rid: %s", table, err, dt.rid)
+			drifts = append(drifts, metadata.TableIndexDrift{Table: table, Error: err.Error()})
+			continue
+		}
+
+		drift := index.CompareTableIndexes(table, logicIndexes[table], dbIndexes, deprecatedIndexNames[table])
+		if withUsage {
+			unused, err := dt.findUnusedIndexes(ctx, table)
+			if err != nil {
+				drift.Error = err.Error()
+			}
+			drift.Unused = unused
+		}
+
+		if drift.HasDrift() {
+			drifts = append(drifts, drift)
+		}
+	}
+
+	return drifts, nil
+}
+
+// findLogicTableIndexes 返回所有表中定义的索引，包括模型实例表、实例关联关系表和模型唯一校验的索引
+func (dt *dbTable) findLogicTableIndexes(ctx context.Context) (map[string][]types.Index, error) {
+	dtIndexesMap, err := dt.findSyncIndexesLogicUnique(ctx)
+	if err != nil {
+		blog.Errorf("find db logic unique failed, err: %v, rid: %s", err, dt.rid)
+		return nil, err
+	}
+
+	logicIndexes := make(map[string][]types.Index)
+	for table, indexes := range index.TableIndexes() {
+		logicIndexes[table] = append(logicIndexes[table], indexes...)
+	}
+	for table, indexes := range dtIndexesMap {
+		logicIndexes[table] = append(logicIndexes[table], indexes...)
+	}
+
+	return logicIndexes, nil
+}
+
+// indexStat is the result of the $indexStats aggregation of mongodb
+type indexStat struct {
+	Name     string `bson:"name"`
+	Accesses struct {
+		Ops   int64     `bson:"ops"`
+		Since time.Time `bson:"since"`
+	} `bson:"accesses"`
+}
+
+// findUnusedIndexes 通过 $indexStats 统计表中没有被使用过的索引, 统计数据在db服务重启后会重置
+func (dt *dbTable) findUnusedIndexes(ctx context.Context, table string) ([]metadata.IndexUsage, error) {
+	pipeline := []mapstr.MapStr{{"$indexStats": mapstr.MapStr{}}}
+	stats := make([]indexStat, 0)
+	if err := dt.db.Table(table).AggregateAll(ctx, pipeline, &stats); err != nil {
+		blog.Errorf("get table(%s) index stats failed, err: %v, rid: %s", table, err, dt.rid)
+		return nil, err
+	}
+
+	// 副本集和分片集群中的每个节点都会返回一条统计数据, 需要合并
+	usageMap := make(map[string]*metadata.IndexUsage)
+	for _, stat := range stats {
+		usage, exists := usageMap[stat.Name]
+		if !exists {
+			usageMap[stat.Name] = &metadata.IndexUsage{
+				Name:  stat.Name,
+				Ops:   stat.Accesses.Ops,
+				Since: stat.Accesses.Since,
+			}
+			continue
+		}
+
+		usage.Ops += stat.Accesses.Ops
+		if stat.Accesses.Since.Before(usage.Since) {
+			usage.Since = stat.Accesses.Since
+		}
+	}
+
+	unused := make([]metadata.IndexUsage, 0)
+	for name, usage := range usageMap {
+		if name == "_id_" || usage.Ops > 0 {
+			continue
+		}
+		unused = append(unused, *usage)
+	}
+
+	sort.Slice(unused, func(i, j int) bool {
+		return unused[i].Name < unused[j].Name
+	})
+	return unused, nil
+}
+
+// indexReconciler 在后台逐个创建db中缺少的索引，并记录进度
+type indexReconciler struct {
+	lock     sync.RWMutex
+	progress *metadata.IndexReconcileProgress
+}
+
+var reconciler = new(indexReconciler)
+
+// StartIndexReconcile 启动后台任务，逐个创建db中缺少的索引， 同一时间只允许一个任务执行
+func StartIndexReconcile(ctx context.Context, e *backbone.Engine, opt *metadata.IndexDriftOption) (
+	*metadata.IndexReconcileProgress, error) {
+
+	rid := util.ExtractRequestIDFromContext(ctx)
+	ccErr := e.CCErr.CreateDefaultCCErrorIf("en")
+
+	db, err := getReadyDB(ctx, e)
+	if err != nil {
+		return nil, err
+	}
+
+	if reconciler.isRunning() {
+		return nil, ccErr.CCError(common.CCErrAdminIndexReconcileRunning)
+	}
+
+	dt := &dbTable{db: db, rid: rid}
+	drifts, err := dt.findIndexDrift(ctx, opt.Tables, false)
+	if err != nil {
+		return nil, ccErr.CCError(common.CCErrCommDBSelectFailed)
+	}
+
+	items := make([]metadata.IndexReconcileItem, 0)
+	for _, drift := range drifts {
+		for _, missing := range drift.Missing {
+			// 和表中定义的索引保持一致，否则创建后的索引会被认为和定义的不一致
+			items = append(items, metadata.IndexReconcileItem{
+				Table:  drift.Table,
+				Index:  missing,
+				Status: metadata.IndexReconcileStatusPending,
+			})
+		}
+	}
+
+	progress := &metadata.IndexReconcileProgress{
+		Status:    metadata.IndexReconcileStatusRunning,
+		StartTime: time.Now(),
+		Total:     len(items),
+		Items:     items,
+	}
+
+	reconciler.lock.Lock()
+	if reconciler.progress != nil && reconciler.progress.Status == metadata.IndexReconcileStatusRunning {
+		reconciler.lock.Unlock()
+		return nil, ccErr.CCError(common.CCErrAdminIndexReconcileRunning)
+	}
+	reconciler.progress = progress
+	reconciler.lock.Unlock()
+
+	blog.Infof("start reconcile %d missing db indexes, rid: %s", len(items), rid)
+	go reconciler.run(dt)
+
+	return reconciler.getProgress(), nil
+}
+
+// GetIndexReconcileProgress 返回最近一次索引补齐任务的进度, 没有执行过任务时返回nil
+func GetIndexReconcileProgress() *metadata.IndexReconcileProgress {
+	return reconciler.getProgress()
+}
+
+func (r *indexReconciler) isRunning() bool {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.progress != nil && r.progress.Status == metadata.IndexReconcileStatusRunning
+}
+
+func (r *indexReconciler) getProgress() *metadata.IndexReconcileProgress {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	if r.progress == nil {
+		return nil
+	}
+
+	progress := *r.progress
+	progress.Items = make([]metadata.IndexReconcileItem, len(r.progress.Items))
+	copy(progress.Items, r.progress.Items)
+	return &progress
+}
+
+func (r *indexReconciler) run(dt *dbTable) {
+	ctx := context.WithValue(context.Background(), common.ContextRequestIDField, dt.rid)
+
+	for idx := range r.progress.Items {
+		r.lock.Lock()
+		item := &r.progress.Items[idx]
+		start := time.Now()
+		item.Status = metadata.IndexReconcileStatusRunning
+		item.StartTime = &start
+		table, dbIndex := item.Table, item.Index
+		r.lock.Unlock()
+
+		err := dt.db.Table(table).CreateIndex(ctx, dbIndex)
+
+		r.lock.Lock()
+		end := time.Now()
+		item.EndTime = &end
+		if err != nil {
+			blog.Errorf("create table(%s) index(%s) failed, err: %v, rid: %s", table, dbIndex.Name, err, dt.rid)
+			item.Status = metadata.IndexReconcileStatusFailed
+			item.Error = err.Error()
+			r.progress.Failed++
+		} else {
+			item.Status = metadata.IndexReconcileStatusSuccess
+			r.progress.Succeeded++
+		}
+		r.lock.Unlock()
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	end := time.Now()
+	r.progress.EndTime = &end
+	r.progress.Status = metadata.IndexReconcileStatusSuccess
+	if r.progress.Failed > 0 {
+		r.progress.Status = metadata.IndexReconcileStatusFailed
+	}
+	blog.Infof("finish reconcile db indexes, succeeded: %d, failed: %d, rid: %s", r.progress.Succeeded,
+		r.progress.Failed, dt.rid)
+}
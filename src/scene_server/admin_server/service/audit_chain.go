@@ -0,0 +1,63 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"configcenter/src/common"
+	"configcenter/src/common/blog"
+	"configcenter/src/common/metadata"
+	"configcenter/src/common/util"
+	"configcenter/src/scene_server/admin_server/auditchain"
+
+	"github.com/emicklei/go-restful/v3"
+)
+
+// VerifyAuditLogChain verify the audit log hash chain of a supplier account to find the deleted or modified logs
+func (s *Service) VerifyAuditLogChain(req *restful.Request, resp *restful.Response) {
+	rHeader := req.Request.Header
+	rid := util.GetHTTPCCRequestID(rHeader)
+	ctx := context.WithValue(context.Background(), common.ContextRequestIDField, rid)
+	defErr := s.CCErr.CreateDefaultCCErrorIf(util.GetLanguage(rHeader))
+
+	opt := new(metadata.VerifyAuditLogChainOption)
+	if err := json.NewDecoder(req.Request.Body).Decode(opt); err != nil && err != io.EOF {
+		blog.Errorf("decode verify audit log chain option failed, err: %v, rid: %s", err, rid)
+		resp.WriteError(http.StatusOK, &metadata.RespError{Msg: defErr.Error(common.CCErrCommJSONUnmarshalFailed)})
+		return
+	}
+
+	if err := opt.Validate(); err != nil {
+		blog.Errorf("verify audit log chain option %+v is invalid, err: %v, rid: %s", opt, err, rid)
+		resp.WriteError(http.StatusOK, &metadata.RespError{Msg: defErr.Errorf(common.CCErrCommParamsInvalid,
+			err.Error())})
+		return
+	}
+
+	if opt.SupplierAccount == "" {
+		opt.SupplierAccount = util.GetOwnerID(rHeader)
+	}
+
+	result, err := auditchain.Verify(ctx, s.db, s.Config.AuditLogChain.Secret, opt, rid)
+	if err != nil {
+		blog.Errorf("verify audit log chain failed, opt: %+v, err: %v, rid: %s", opt, err, rid)
+		resp.WriteError(http.StatusOK, &metadata.RespError{Msg: defErr.Error(common.CCErrCommDBSelectFailed)})
+		return
+	}
+
+	resp.WriteEntity(metadata.NewSuccessResp(result))
+}
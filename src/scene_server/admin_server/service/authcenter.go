@@ -16,6 +16,7 @@ import (
 	"encoding/json"
 	"net/http"
 
+	iamcli "configcenter/src/ac/iam"
 	"configcenter/src/common"
 	"configcenter/src/common/auth"
 	"configcenter/src/common/blog"
@@ -28,7 +29,7 @@ import (
 
 // InitAuthCenter init auth resources on IAM
 func (s *Service) InitAuthCenter(req *restful.Request, resp *restful.Response) {
-	if !auth.EnableAuthorize() {
+	if !auth.EnableAuthorize() || iamcli.IsLocalAuthMode() {
 		_ = resp.WriteEntity(metadata.NewSuccessResp(nil))
 		return
 	}
@@ -90,7 +91,7 @@ func (s *Service) InitAuthCenter(req *restful.Request, resp *restful.Response) {
 */
 // RegisterAuthAccount register auth account to iam
 func (s *Service) RegisterAuthAccount(req *restful.Request, resp *restful.Response) {
-	if !auth.EnableAuthorize() {
+	if !auth.EnableAuthorize() || iamcli.IsLocalAuthMode() {
 		_ = resp.WriteEntity(metadata.NewSuccessResp(nil))
 		return
 	}
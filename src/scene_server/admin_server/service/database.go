@@ -14,13 +14,17 @@ package service
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"time"
 
 	"configcenter/src/common"
+	"configcenter/src/common/auditlog/exporter"
 	"configcenter/src/common/blog"
+	"configcenter/src/common/mapstr"
 	"configcenter/src/common/metadata"
 	"configcenter/src/common/util"
+	"configcenter/src/scene_server/admin_server/auditchain"
 
 	"github.com/emicklei/go-restful/v3"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -40,6 +44,8 @@ const (
 type deleteAuditLogReq struct {
 	// delete logs before this day,the date format like '2021-08-19'.
 	BeforeDay string `json:"beforeDay"`
+	// Force delete the audit logs even if they are not exported by the audit log exporters yet
+	Force bool `json:"force"`
 }
 type deleteAuditLogRsp struct {
 	Num int `json:"num"`
@@ -102,6 +108,35 @@ func (s *Service) DeleteAuditLog(req *restful.Request, resp *restful.Response) {
 		_ = resp.WriteError(http.StatusInternalServerError, err)
 		return
 	}
+
+	// the audit logs that are not exported yet will be lost for the collectors after they are deleted
+	truncateObjID := primitive.NewObjectIDFromTimestamp(time.Unix(baseDay, 0))
+	unexportedCond := mapstr.MapStr{"_id": mapstr.MapStr{common.BKDBLT: truncateObjID}}
+	unexported, err := exporter.CountUnexported(s.ctx, s.db, unexportedCond, rid)
+	if err != nil {
+		blog.Errorf("count the unexported audit logs to delete failed, err: %v, rid: %s", err, rid)
+		_ = resp.WriteError(http.StatusInternalServerError, &metadata.RespError{Msg: err})
+		return
+	}
+
+	if len(unexported) > 0 {
+		if !param.Force {
+			blog.Errorf("audit logs to delete are not exported yet, unexported: %v, rid: %s", unexported, rid)
+			errInfo := metadata.RespError{Msg: fmt.Errorf("audit logs to delete are not exported yet, exporter "+
+				"to unexported count: %v, set force to true to delete them anyway", unexported)}
+			_ = resp.WriteError(http.StatusBadRequest, &errInfo)
+			return
+		}
+		blog.Warnf("force delete the unexported audit logs, unexported: %v, rid: %s", unexported, rid)
+	}
+
+	// seal the chains at the last logs to delete, so that the remaining audit log chains can still be verified
+	err = auditchain.CheckpointBeforeTruncate(s.ctx, s.db, s.Config.AuditLogChain.Secret, truncateObjID, rid)
+	if err != nil {
+		blog.Errorf("generate audit log checkpoints before deletion failed, err: %v, rid: %s", err, rid)
+		_ = resp.WriteError(http.StatusInternalServerError, &metadata.RespError{Msg: err})
+		return
+	}
 	var cnt, total int
 
 	for {
@@ -14,12 +14,15 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"time"
 
 	"configcenter/src/common"
 	cc "configcenter/src/common/backbone/configcenter"
+	"configcenter/src/common/blog"
 	"configcenter/src/common/metadata"
 	"configcenter/src/common/util"
 	"configcenter/src/scene_server/admin_server/app/options"
@@ -64,3 +67,54 @@ func (s *Service) RunSyncDBIndex(req *restful.Request, resp *restful.Response) {
 
 	return
 }
+
+// FindDBIndexDrift find the difference between the defined indexes and the indexes that exist in db
+func (s *Service) FindDBIndexDrift(req *restful.Request, resp *restful.Response) {
+	rHeader := req.Request.Header
+	rid := util.GetHTTPCCRequestID(rHeader)
+	ctx := context.WithValue(context.Background(), common.ContextRequestIDField, rid)
+	defErr := s.CCErr.CreateDefaultCCErrorIf(util.GetLanguage(rHeader))
+
+	opt := new(metadata.IndexDriftOption)
+	if err := json.NewDecoder(req.Request.Body).Decode(opt); err != nil && err != io.EOF {
+		blog.Errorf("decode index drift option failed, err: %v, rid: %s", err, rid)
+		resp.WriteError(http.StatusOK, &metadata.RespError{Msg: defErr.Error(common.CCErrCommJSONUnmarshalFailed)})
+		return
+	}
+
+	report, err := logics.FindIndexDrift(ctx, s.Engine, opt)
+	if err != nil {
+		resp.WriteError(http.StatusOK, &metadata.RespError{Msg: err})
+		return
+	}
+
+	resp.WriteEntity(metadata.NewSuccessResp(report))
+}
+
+// ReconcileDBIndex start a background task to create the missing indexes in db one at a time
+func (s *Service) ReconcileDBIndex(req *restful.Request, resp *restful.Response) {
+	rHeader := req.Request.Header
+	rid := util.GetHTTPCCRequestID(rHeader)
+	ctx := context.WithValue(context.Background(), common.ContextRequestIDField, rid)
+	defErr := s.CCErr.CreateDefaultCCErrorIf(util.GetLanguage(rHeader))
+
+	opt := new(metadata.IndexDriftOption)
+	if err := json.NewDecoder(req.Request.Body).Decode(opt); err != nil && err != io.EOF {
+		blog.Errorf("decode index reconcile option failed, err: %v, rid: %s", err, rid)
+		resp.WriteError(http.StatusOK, &metadata.RespError{Msg: defErr.Error(common.CCErrCommJSONUnmarshalFailed)})
+		return
+	}
+
+	progress, err := logics.StartIndexReconcile(ctx, s.Engine, opt)
+	if err != nil {
+		resp.WriteError(http.StatusOK, &metadata.RespError{Msg: err})
+		return
+	}
+
+	resp.WriteEntity(metadata.NewSuccessResp(progress))
+}
+
+// FindDBIndexReconcileProgress find the progress of the latest db index reconcile task
+func (s *Service) FindDBIndexReconcileProgress(req *restful.Request, resp *restful.Response) {
+	resp.WriteEntity(metadata.NewSuccessResp(logics.GetIndexReconcileProgress()))
+}
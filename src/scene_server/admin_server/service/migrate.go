@@ -132,7 +132,8 @@ func (s *Service) createWatchDBChainCollections(rid string) error {
 				ExpireAfterSeconds: dbChainTTLTime},
 		}
 
-		if cursorType == watch.ObjectBase || cursorType == watch.MainlineInstance || cursorType == watch.InstAsst {
+		if cursorType == watch.ObjectBase || cursorType == watch.MainlineInstance || cursorType == watch.InstAsst ||
+			cursorType == watch.DynamicGroupMember {
 
 			subResourceIndex := daltypes.Index{
 				Name: "index_sub_resource", Keys: bson.D{{common.BKSubResourceField, 1}}, Background: true,
@@ -209,6 +210,23 @@ func (s *Service) createWatchDBChainCollections(rid string) error {
 			continue
 		}
 
+		if key.Collection() == event.DynamicGroupMemberKey.Collection() {
+			// dynamic group member's watch token is generated in the same way with the biz set relation's watch token
+			data := mapstr.MapStr{
+				"_id":                              key.Collection(),
+				common.BKTableNameBaseHost:         watch.LastChainNodeData{Coll: common.BKTableNameBaseHost},
+				common.BKTableNameModuleHostConfig: watch.LastChainNodeData{Coll: common.BKTableNameModuleHostConfig},
+				common.BKTableNameDynamicGroup:     watch.LastChainNodeData{Coll: common.BKTableNameDynamicGroup},
+				common.BKFieldID:                   0,
+				common.BKTokenField:                "",
+			}
+			if err := s.watchDB.Table(common.BKTableNameWatchToken).Insert(s.ctx, data); err != nil {
+				blog.Errorf("init last dynamic group member watch token failed, err: %v, data: %+v", err, data)
+				return err
+			}
+			continue
+		}
+
 		data := watch.LastChainNodeData{
 			Coll:  key.Collection(),
 			Token: "",
@@ -0,0 +1,75 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"configcenter/src/common"
+	"configcenter/src/common/blog"
+	"configcenter/src/common/metadata"
+	"configcenter/src/common/util"
+	"configcenter/src/scene_server/admin_server/upgrader"
+
+	"github.com/emicklei/go-restful/v3"
+)
+
+// migrateDryRun list the pending upgraders and the db operations that each of them would do without changing data
+func (s *Service) migrateDryRun(req *restful.Request, resp *restful.Response) {
+	rHeader := req.Request.Header
+	rid := util.GetHTTPCCRequestID(rHeader)
+	defErr := s.CCErr.CreateDefaultCCErrorIf(util.GetLanguage(rHeader))
+
+	result, err := upgrader.DryRun(s.ctx, s.db)
+	if err != nil {
+		blog.Errorf("migrate dry run failed, err: %v, rid: %s", err, rid)
+		_ = resp.WriteError(http.StatusOK, &metadata.RespError{
+			Msg: defErr.Errorf(common.CCErrCommMigrateFailed, err.Error()),
+		})
+		return
+	}
+
+	_ = resp.WriteEntity(metadata.NewSuccessResp(result))
+}
+
+// listMigrationRecords list the execution records of the upgraders
+func (s *Service) listMigrationRecords(req *restful.Request, resp *restful.Response) {
+	rHeader := req.Request.Header
+	rid := util.GetHTTPCCRequestID(rHeader)
+	defErr := s.CCErr.CreateDefaultCCErrorIf(util.GetLanguage(rHeader))
+
+	opt := new(metadata.ListMigrationRecordOption)
+	if err := json.NewDecoder(req.Request.Body).Decode(opt); err != nil {
+		blog.Errorf("decode list migration record option failed, err: %v, rid: %s", err, rid)
+		_ = resp.WriteError(http.StatusOK, &metadata.RespError{Msg: defErr.Error(common.CCErrCommJSONUnmarshalFailed)})
+		return
+	}
+
+	if err := opt.Validate(); err != nil {
+		blog.Errorf("list migration record option is invalid, err: %v, opt: %+v, rid: %s", err, opt, rid)
+		_ = resp.WriteError(http.StatusOK, &metadata.RespError{
+			Msg: defErr.Errorf(common.CCErrCommParamsInvalid, err.Error()),
+		})
+		return
+	}
+
+	result, err := upgrader.ListRecords(s.ctx, s.db, opt)
+	if err != nil {
+		blog.Errorf("list migration records failed, err: %v, opt: %+v, rid: %s", err, opt, rid)
+		_ = resp.WriteError(http.StatusOK, &metadata.RespError{Msg: defErr.Error(common.CCErrCommDBSelectFailed)})
+		return
+	}
+
+	_ = resp.WriteEntity(metadata.NewSuccessResp(result))
+}
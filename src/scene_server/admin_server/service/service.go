@@ -106,11 +106,17 @@ func (s *Service) WebService() *restful.Container {
 	api.Route(api.GET("/find/system_config/platform_setting/{type}").To(s.SearchPlatformSettingConfig))
 
 	api.Route(api.POST("/migrate/specify/version/{distribution}/{ownerID}").To(s.migrateSpecifyVersion))
+	api.Route(api.POST("/migrate/dryrun/{distribution}/{ownerID}").To(s.migrateDryRun))
+	api.Route(api.POST("/find/migrate/records").To(s.listMigrationRecords))
 	api.Route(api.POST("/migrate/config/refresh").To(s.refreshConfig))
 	api.Route(api.POST("/migrate/dataid").To(s.migrateDataID))
 	api.Route(api.POST("/migrate/old/dataid").To(s.migrateOldDataID))
 	api.Route(api.POST("/delete/auditlog").To(s.DeleteAuditLog))
+	api.Route(api.POST("/find/auditlog/chain/verify").To(s.VerifyAuditLogChain))
 	api.Route(api.POST("/migrate/sync/db/index").To(s.RunSyncDBIndex))
+	api.Route(api.POST("/find/db/index/drift").To(s.FindDBIndexDrift))
+	api.Route(api.POST("/migrate/reconcile/db/index").To(s.ReconcileDBIndex))
+	api.Route(api.GET("/find/db/index/reconcile/progress").To(s.FindDBIndexReconcileProgress))
 	api.Route(api.GET("/healthz").To(s.Healthz))
 	api.Route(api.GET("/monitor_healthz").To(s.MonitorHealth))
 
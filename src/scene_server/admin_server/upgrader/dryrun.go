@@ -0,0 +1,433 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package upgrader
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+
+	"configcenter/src/common"
+	"configcenter/src/common/blog"
+	"configcenter/src/common/metadata"
+	"configcenter/src/storage/dal"
+	"configcenter/src/storage/dal/redis"
+	"configcenter/src/storage/dal/types"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// dry run operation names
+const (
+	opInsert       = "insert"
+	opUpdate       = "update"
+	opUpsert       = "upsert"
+	opDelete       = "delete"
+	opCreateIndex  = "create_index"
+	opDropIndex    = "drop_index"
+	opAddColumn    = "add_column"
+	opRenameColumn = "rename_column"
+	opDropColumn   = "drop_column"
+	opCreateTable  = "create_table"
+	opDropTable    = "drop_table"
+	opRenameTable  = "rename_table"
+	opNextSequence = "next_sequence"
+	opCommitTxn    = "commit_transaction"
+	opAbortTxn     = "abort_transaction"
+	opAggregateOut = "aggregate_out"
+)
+
+const (
+	// dryRunSequence is the start of the fake sequences that are returned in dry run
+	dryRunSequence = uint64(1) << 40
+	// dryRunNoCount means the number of the touched documents can not be counted
+	dryRunNoCount = int64(-1)
+)
+
+// DryRun runs the pending upgraders against a db that records the write operations instead of doing them, and
+// returns the collections, indexes and documents each upgrader would touch.
+// NOTICE: the writes of the former upgraders are not applied, so the later upgraders see the current data, and the
+// result is an estimation. upgraders that use the raw mongo client, redis or iam, or aggregate with the $out or $merge
+// stage can not be dry run, the error is returned in the result of the upgrader.
+func DryRun(ctx context.Context, db dal.RDB) (*metadata.MigrationDryRunResult, error) {
+	sort.Slice(upgraderPool, func(i, j int) bool {
+		return VersionCmp(upgraderPool[i].version, upgraderPool[j].version) < 0
+	})
+
+	// do not use getVersion, it inserts the version data if it does not exist
+	cmdbVersion := new(Version)
+	err := db.Table(common.BKTableNameSystem).Find(map[string]interface{}{"type": SystemTypeVersion}).One(ctx, cmdbVersion)
+	if err != nil && !db.IsNotFoundError(err) {
+		blog.Errorf("get system version failed, err: %v", err)
+		return nil, err
+	}
+
+	result := &metadata.MigrationDryRunResult{
+		CurrentVersion: remapVersion(cmdbVersion.CurrentVersion),
+		Pending:        make([]metadata.MigrationDryRunItem, 0),
+	}
+
+	conf := &Config{OwnerID: common.BKDefaultOwnerID, User: common.CCSystemOperatorUserName}
+	for _, v := range upgraderPool {
+		if VersionCmp(v.version, result.CurrentVersion) <= 0 {
+			continue
+		}
+
+		recorder := newDryRunDB(db)
+		item := metadata.MigrationDryRunItem{Version: v.version}
+		if err := recorder.run(ctx, v, conf); err != nil {
+			item.Error = err.Error()
+		}
+		item.Collections, item.Operations = recorder.result()
+		result.Pending = append(result.Pending, item)
+	}
+
+	return result, nil
+}
+
+// dryRunDB is a db that records the write operations and does the read operations with the real db
+type dryRunDB struct {
+	dal.RDB
+	lock       sync.Mutex
+	operations map[string]*metadata.MigrationOperation
+	sequence   uint64
+}
+
+func newDryRunDB(db dal.RDB) *dryRunDB {
+	return &dryRunDB{
+		RDB:        db,
+		operations: make(map[string]*metadata.MigrationOperation),
+		sequence:   dryRunSequence,
+	}
+}
+
+// run the upgrader with the dry run db, the redis and iam are not given, so that nothing is changed outside the db.
+// the upgrader that uses them panics, the panic is recovered and returned as an error.
+func (d *dryRunDB) run(ctx context.Context, v Upgrader, conf *Config) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("upgrader can not be dry run, panic: %v", r)
+		}
+	}()
+
+	var cache redis.Client
+	return v.do(ctx, d, cache, nil, conf)
+}
+
+func (d *dryRunDB) record(collection, operation, index string, documents int64) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	key := collection + "/" + operation + "/" + index
+	op, exists := d.operations[key]
+	if !exists {
+		op = &metadata.MigrationOperation{Collection: collection, Operation: operation, Index: index}
+		d.operations[key] = op
+	}
+	op.Times++
+	if documents > 0 {
+		op.Documents += documents
+	}
+}
+
+func (d *dryRunDB) result() ([]string, []metadata.MigrationOperation) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	collections := make([]string, 0)
+	collectionMap := make(map[string]struct{})
+	operations := make([]metadata.MigrationOperation, 0, len(d.operations))
+	for _, op := range d.operations {
+		operations = append(operations, *op)
+		if _, exists := collectionMap[op.Collection]; !exists && op.Collection != "" {
+			collectionMap[op.Collection] = struct{}{}
+			collections = append(collections, op.Collection)
+		}
+	}
+
+	sort.Strings(collections)
+	sort.Slice(operations, func(i, j int) bool {
+		if operations[i].Collection != operations[j].Collection {
+			return operations[i].Collection < operations[j].Collection
+		}
+		if operations[i].Operation != operations[j].Operation {
+			return operations[i].Operation < operations[j].Operation
+		}
+		return operations[i].Index < operations[j].Index
+	})
+	return collections, operations
+}
+
+// Table returns the table that records the write operations
+func (d *dryRunDB) Table(collection string) types.Table {
+	return &dryRunTable{Table: d.RDB.Table(collection), db: d, collection: collection}
+}
+
+// NextSequence returns a fake sequence, so that the real sequence is not increased
+func (d *dryRunDB) NextSequence(ctx context.Context, sequenceName string) (uint64, error) {
+	d.record(sequenceName, opNextSequence, "", 0)
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.sequence++
+	return d.sequence, nil
+}
+
+// NextSequences returns the fake sequences
+func (d *dryRunDB) NextSequences(ctx context.Context, sequenceName string, num int) ([]uint64, error) {
+	d.record(sequenceName, opNextSequence, "", 0)
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	sequences := make([]uint64, num)
+	for i := range sequences {
+		d.sequence++
+		sequences[i] = d.sequence
+	}
+	return sequences, nil
+}
+
+// DropTable records the drop table operation
+func (d *dryRunDB) DropTable(ctx context.Context, name string) error {
+	d.record(name, opDropTable, "", d.count(ctx, name, nil))
+	return nil
+}
+
+// CreateTable records the create table operation
+func (d *dryRunDB) CreateTable(ctx context.Context, name string) error {
+	d.record(name, opCreateTable, "", 0)
+	return nil
+}
+
+// RenameTable records the rename table operation
+func (d *dryRunDB) RenameTable(ctx context.Context, prevName, currName string) error {
+	d.record(prevName, opRenameTable, "", d.count(ctx, prevName, nil))
+	d.record(currName, opRenameTable, "", 0)
+	return nil
+}
+
+// CommitTransaction records the commit transaction operation
+func (d *dryRunDB) CommitTransaction(ctx context.Context, cap *metadata.TxnCapable) error {
+	d.record("", opCommitTxn, "", 0)
+	return nil
+}
+
+// AbortTransaction records the abort transaction operation
+func (d *dryRunDB) AbortTransaction(ctx context.Context, cap *metadata.TxnCapable) (bool, error) {
+	d.record("", opAbortTxn, "", 0)
+	return false, nil
+}
+
+// InitTxnManager does nothing in dry run
+func (d *dryRunDB) InitTxnManager(r redis.Client) error {
+	return nil
+}
+
+// Close does nothing in dry run, the real db is used by the admin server
+func (d *dryRunDB) Close() error {
+	return nil
+}
+
+// count the documents that matches the filter, returns -1 if it fails, which is not added to the documents
+func (d *dryRunDB) count(ctx context.Context, collection string, filter types.Filter) int64 {
+	count, err := d.RDB.Table(collection).Find(filter).Count(ctx)
+	if err != nil {
+		blog.Warnf("count %s documents in dry run failed, filter: %+v, err: %v", collection, filter, err)
+		return dryRunNoCount
+	}
+	return int64(count)
+}
+
+// dryRunTable is a table that records the write operations and does the read operations with the real table
+type dryRunTable struct {
+	types.Table
+	db         *dryRunDB
+	collection string
+}
+
+func (t *dryRunTable) record(ctx context.Context, operation string, filter types.Filter) {
+	t.db.record(t.collection, operation, "", t.db.count(ctx, t.collection, filter))
+}
+
+// Insert records the insert operation
+func (t *dryRunTable) Insert(ctx context.Context, docs interface{}) error {
+	count := int64(1)
+	value := reflect.ValueOf(docs)
+	if value.Kind() == reflect.Ptr {
+		value = value.Elem()
+	}
+	if value.Kind() == reflect.Slice || value.Kind() == reflect.Array {
+		count = int64(value.Len())
+	}
+	t.db.record(t.collection, opInsert, "", count)
+	return nil
+}
+
+// Update records the update operation
+func (t *dryRunTable) Update(ctx context.Context, filter types.Filter, doc interface{}) error {
+	t.record(ctx, opUpdate, filter)
+	return nil
+}
+
+// Upsert records the upsert operation
+func (t *dryRunTable) Upsert(ctx context.Context, filter types.Filter, doc interface{}) error {
+	t.db.record(t.collection, opUpsert, "", 1)
+	return nil
+}
+
+// UpdateMultiModel records the update operation
+func (t *dryRunTable) UpdateMultiModel(ctx context.Context, filter types.Filter,
+	updateModel ...types.ModeUpdate) error {
+
+	t.record(ctx, opUpdate, filter)
+	return nil
+}
+
+// Delete records the delete operation
+func (t *dryRunTable) Delete(ctx context.Context, filter types.Filter) error {
+	t.record(ctx, opDelete, filter)
+	return nil
+}
+
+// CreateIndex records the create index operation
+func (t *dryRunTable) CreateIndex(ctx context.Context, index types.Index) error {
+	t.db.record(t.collection, opCreateIndex, index.Name, 0)
+	return nil
+}
+
+// DropIndex records the drop index operation
+func (t *dryRunTable) DropIndex(ctx context.Context, indexName string) error {
+	t.db.record(t.collection, opDropIndex, indexName, 0)
+	return nil
+}
+
+// AddColumn records the add column operation
+func (t *dryRunTable) AddColumn(ctx context.Context, column string, value interface{}) error {
+	t.record(ctx, opAddColumn, nil)
+	return nil
+}
+
+// RenameColumn records the rename column operation
+func (t *dryRunTable) RenameColumn(ctx context.Context, filter types.Filter, oldName, newColumn string) error {
+	t.record(ctx, opRenameColumn, filter)
+	return nil
+}
+
+// DropColumn records the drop column operation
+func (t *dryRunTable) DropColumn(ctx context.Context, field string) error {
+	t.record(ctx, opDropColumn, nil)
+	return nil
+}
+
+// DropColumns records the drop column operation
+func (t *dryRunTable) DropColumns(ctx context.Context, filter types.Filter, fields []string) error {
+	t.record(ctx, opDropColumn, filter)
+	return nil
+}
+
+// DropDocsColumn records the drop column operation
+func (t *dryRunTable) DropDocsColumn(ctx context.Context, field string, filter types.Filter) error {
+	t.record(ctx, opDropColumn, filter)
+	return nil
+}
+
+// DeleteMany records the delete operation, returns the number of documents that would be deleted
+func (t *dryRunTable) DeleteMany(ctx context.Context, filter types.Filter) (uint64, error) {
+	count := t.db.count(ctx, t.collection, filter)
+	t.db.record(t.collection, opDelete, "", count)
+	if count < 0 {
+		return 0, nil
+	}
+	return uint64(count), nil
+}
+
+// UpdateMany records the update operation, returns the number of documents that would be updated
+func (t *dryRunTable) UpdateMany(ctx context.Context, filter types.Filter, doc interface{}) (uint64, error) {
+	count := t.db.count(ctx, t.collection, filter)
+	t.db.record(t.collection, opUpdate, "", count)
+	if count < 0 {
+		return 0, nil
+	}
+	return uint64(count), nil
+}
+
+// AggregateOne blocks the pipeline that writes to a collection, the read only pipeline is done with the real table
+func (t *dryRunTable) AggregateOne(ctx context.Context, pipeline interface{}, result interface{}) error {
+	if err := t.checkReadOnlyPipeline(pipeline); err != nil {
+		return err
+	}
+	return t.Table.AggregateOne(ctx, pipeline, result)
+}
+
+// AggregateAll blocks the pipeline that writes to a collection, the read only pipeline is done with the real table
+func (t *dryRunTable) AggregateAll(ctx context.Context, pipeline interface{}, result interface{},
+	opts ...*types.AggregateOpts) error {
+
+	if err := t.checkReadOnlyPipeline(pipeline); err != nil {
+		return err
+	}
+	return t.Table.AggregateAll(ctx, pipeline, result, opts...)
+}
+
+// checkReadOnlyPipeline check that the aggregate pipeline has no $out or $merge stage, which writes the result to a
+// collection. the write is recorded and returns an error, so that the upgrader stops before it uses the result.
+func (t *dryRunTable) checkReadOnlyPipeline(pipeline interface{}) error {
+	stage, err := getPipelineWriteStage(pipeline)
+	if err != nil {
+		t.db.record(t.collection, opAggregateOut, "", dryRunNoCount)
+		return fmt.Errorf("aggregate pipeline of %s can not be dry run, parse it failed, err: %v", t.collection, err)
+	}
+
+	if stage != "" {
+		t.db.record(t.collection, opAggregateOut, "", dryRunNoCount)
+		return fmt.Errorf("aggregate pipeline of %s with %s stage can not be dry run", t.collection, stage)
+	}
+	return nil
+}
+
+// getPipelineWriteStage returns the $out or $merge stage name of the aggregate pipeline, returns empty if not exists
+func getPipelineWriteStage(pipeline interface{}) (string, error) {
+	data, err := bson.Marshal(bson.M{"pipeline": pipeline})
+	if err != nil {
+		return "", err
+	}
+
+	stages, ok := bson.Raw(data).Lookup("pipeline").ArrayOK()
+	if !ok {
+		return "", fmt.Errorf("pipeline is not an array")
+	}
+
+	values, err := stages.Values()
+	if err != nil {
+		return "", err
+	}
+
+	for _, value := range values {
+		stage, ok := value.DocumentOK()
+		if !ok {
+			return "", fmt.Errorf("pipeline stage is not a document")
+		}
+
+		elements, err := stage.Elements()
+		if err != nil {
+			return "", err
+		}
+
+		for _, element := range elements {
+			if key := element.Key(); key == "$out" || key == "$merge" {
+				return key, nil
+			}
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,355 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package upgrader
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"configcenter/src/common/mapstr"
+	"configcenter/src/common/metadata"
+	"configcenter/src/storage/dal"
+	"configcenter/src/storage/dal/types"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// fakeRDB is the real db of the dry run db in tests, it only supports counting and aggregating, the write
+// operations panic because the embedded db is nil, so they must be intercepted by the dry run db.
+type fakeRDB struct {
+	dal.RDB
+	// count is the number of documents that matches any filter
+	count      uint64
+	aggregated int
+}
+
+func (f *fakeRDB) Table(collection string) types.Table {
+	return &fakeTable{db: f}
+}
+
+type fakeTable struct {
+	types.Table
+	db *fakeRDB
+}
+
+func (f *fakeTable) Find(filter types.Filter, opts ...*types.FindOpts) types.Find {
+	return &fakeFind{count: f.db.count}
+}
+
+func (f *fakeTable) AggregateOne(ctx context.Context, pipeline interface{}, result interface{}) error {
+	f.db.aggregated++
+	return nil
+}
+
+func (f *fakeTable) AggregateAll(ctx context.Context, pipeline interface{}, result interface{},
+	opts ...*types.AggregateOpts) error {
+
+	f.db.aggregated++
+	return nil
+}
+
+type fakeFind struct {
+	types.Find
+	count uint64
+}
+
+func (f *fakeFind) Count(ctx context.Context) (uint64, error) {
+	return f.count, nil
+}
+
+func TestDryRunWriteOperations(t *testing.T) {
+	ctx := context.Background()
+	filter := mapstr.MapStr{"bk_obj_id": "host"}
+
+	testCases := []struct {
+		name      string
+		operate   func(db *dryRunDB) error
+		operation metadata.MigrationOperation
+	}{
+		{
+			name: "insert one",
+			operate: func(db *dryRunDB) error {
+				return db.Table("cc_ObjDes").Insert(ctx, mapstr.MapStr{"bk_obj_id": "host"})
+			},
+			operation: metadata.MigrationOperation{Collection: "cc_ObjDes", Operation: opInsert, Times: 1,
+				Documents: 1},
+		},
+		{
+			name: "insert many",
+			operate: func(db *dryRunDB) error {
+				return db.Table("cc_ObjDes").Insert(ctx, []mapstr.MapStr{{"bk_obj_id": "a"}, {"bk_obj_id": "b"}})
+			},
+			operation: metadata.MigrationOperation{Collection: "cc_ObjDes", Operation: opInsert, Times: 1,
+				Documents: 2},
+		},
+		{
+			name: "update",
+			operate: func(db *dryRunDB) error {
+				return db.Table("cc_ObjDes").Update(ctx, filter, mapstr.MapStr{"ispaused": false})
+			},
+			operation: metadata.MigrationOperation{Collection: "cc_ObjDes", Operation: opUpdate, Times: 1,
+				Documents: 3},
+		},
+		{
+			name: "upsert",
+			operate: func(db *dryRunDB) error {
+				return db.Table("cc_ObjDes").Upsert(ctx, filter, mapstr.MapStr{"ispaused": false})
+			},
+			operation: metadata.MigrationOperation{Collection: "cc_ObjDes", Operation: opUpsert, Times: 1,
+				Documents: 1},
+		},
+		{
+			name: "update multi model",
+			operate: func(db *dryRunDB) error {
+				return db.Table("cc_ObjDes").UpdateMultiModel(ctx, filter,
+					types.ModeUpdate{Op: "set", Doc: mapstr.MapStr{"ispaused": false}})
+			},
+			operation: metadata.MigrationOperation{Collection: "cc_ObjDes", Operation: opUpdate, Times: 1,
+				Documents: 3},
+		},
+		{
+			name: "update many",
+			operate: func(db *dryRunDB) error {
+				count, err := db.Table("cc_ObjDes").UpdateMany(ctx, filter, mapstr.MapStr{"ispaused": false})
+				if err == nil && count != 3 {
+					t.Errorf("update many returns count %d, expect 3", count)
+				}
+				return err
+			},
+			operation: metadata.MigrationOperation{Collection: "cc_ObjDes", Operation: opUpdate, Times: 1,
+				Documents: 3},
+		},
+		{
+			name: "delete",
+			operate: func(db *dryRunDB) error {
+				return db.Table("cc_ObjDes").Delete(ctx, filter)
+			},
+			operation: metadata.MigrationOperation{Collection: "cc_ObjDes", Operation: opDelete, Times: 1,
+				Documents: 3},
+		},
+		{
+			name: "delete many",
+			operate: func(db *dryRunDB) error {
+				count, err := db.Table("cc_ObjDes").DeleteMany(ctx, filter)
+				if err == nil && count != 3 {
+					t.Errorf("delete many returns count %d, expect 3", count)
+				}
+				return err
+			},
+			operation: metadata.MigrationOperation{Collection: "cc_ObjDes", Operation: opDelete, Times: 1,
+				Documents: 3},
+		},
+		{
+			name: "create index",
+			operate: func(db *dryRunDB) error {
+				return db.Table("cc_ObjDes").CreateIndex(ctx, types.Index{Name: "bkcc_idx_ObjID"})
+			},
+			operation: metadata.MigrationOperation{Collection: "cc_ObjDes", Operation: opCreateIndex,
+				Index: "bkcc_idx_ObjID", Times: 1},
+		},
+		{
+			name: "drop index",
+			operate: func(db *dryRunDB) error {
+				return db.Table("cc_ObjDes").DropIndex(ctx, "bkcc_idx_ObjID")
+			},
+			operation: metadata.MigrationOperation{Collection: "cc_ObjDes", Operation: opDropIndex,
+				Index: "bkcc_idx_ObjID", Times: 1},
+		},
+		{
+			name: "add column",
+			operate: func(db *dryRunDB) error {
+				return db.Table("cc_ObjDes").AddColumn(ctx, "ispaused", false)
+			},
+			operation: metadata.MigrationOperation{Collection: "cc_ObjDes", Operation: opAddColumn, Times: 1,
+				Documents: 3},
+		},
+		{
+			name: "rename column",
+			operate: func(db *dryRunDB) error {
+				return db.Table("cc_ObjDes").RenameColumn(ctx, filter, "ispaused", "is_paused")
+			},
+			operation: metadata.MigrationOperation{Collection: "cc_ObjDes", Operation: opRenameColumn, Times: 1,
+				Documents: 3},
+		},
+		{
+			name: "drop column",
+			operate: func(db *dryRunDB) error {
+				return db.Table("cc_ObjDes").DropColumn(ctx, "ispaused")
+			},
+			operation: metadata.MigrationOperation{Collection: "cc_ObjDes", Operation: opDropColumn, Times: 1,
+				Documents: 3},
+		},
+		{
+			name: "drop columns",
+			operate: func(db *dryRunDB) error {
+				return db.Table("cc_ObjDes").DropColumns(ctx, filter, []string{"ispaused"})
+			},
+			operation: metadata.MigrationOperation{Collection: "cc_ObjDes", Operation: opDropColumn, Times: 1,
+				Documents: 3},
+		},
+		{
+			name: "drop docs column",
+			operate: func(db *dryRunDB) error {
+				return db.Table("cc_ObjDes").DropDocsColumn(ctx, "ispaused", filter)
+			},
+			operation: metadata.MigrationOperation{Collection: "cc_ObjDes", Operation: opDropColumn, Times: 1,
+				Documents: 3},
+		},
+		{
+			name: "create table",
+			operate: func(db *dryRunDB) error {
+				return db.CreateTable(ctx, "cc_ObjDes")
+			},
+			operation: metadata.MigrationOperation{Collection: "cc_ObjDes", Operation: opCreateTable, Times: 1},
+		},
+		{
+			name: "drop table",
+			operate: func(db *dryRunDB) error {
+				return db.DropTable(ctx, "cc_ObjDes")
+			},
+			operation: metadata.MigrationOperation{Collection: "cc_ObjDes", Operation: opDropTable, Times: 1,
+				Documents: 3},
+		},
+		{
+			name: "next sequence",
+			operate: func(db *dryRunDB) error {
+				if _, err := db.NextSequence(ctx, "cc_ObjDes"); err != nil {
+					return err
+				}
+				_, err := db.NextSequences(ctx, "cc_ObjDes", 2)
+				return err
+			},
+			operation: metadata.MigrationOperation{Collection: "cc_ObjDes", Operation: opNextSequence, Times: 2},
+		},
+		{
+			name: "commit transaction",
+			operate: func(db *dryRunDB) error {
+				return db.CommitTransaction(ctx, nil)
+			},
+			operation: metadata.MigrationOperation{Operation: opCommitTxn, Times: 1},
+		},
+		{
+			name: "abort transaction",
+			operate: func(db *dryRunDB) error {
+				_, err := db.AbortTransaction(ctx, nil)
+				return err
+			},
+			operation: metadata.MigrationOperation{Operation: opAbortTxn, Times: 1},
+		},
+	}
+
+	for _, tc := range testCases {
+		db := newDryRunDB(&fakeRDB{count: 3})
+		if err := tc.operate(db); err != nil {
+			t.Errorf("%s: operate failed, err: %v", tc.name, err)
+			continue
+		}
+
+		_, operations := db.result()
+		expected := []metadata.MigrationOperation{tc.operation}
+		if !reflect.DeepEqual(operations, expected) {
+			t.Errorf("%s: recorded operations %+v, expect %+v", tc.name, operations, expected)
+		}
+	}
+}
+
+func TestDryRunRenameTable(t *testing.T) {
+	db := newDryRunDB(&fakeRDB{count: 3})
+	if err := db.RenameTable(context.Background(), "cc_Old", "cc_New"); err != nil {
+		t.Fatalf("rename table failed, err: %v", err)
+	}
+
+	collections, operations := db.result()
+	if !reflect.DeepEqual(collections, []string{"cc_New", "cc_Old"}) {
+		t.Errorf("unexpected collections %v", collections)
+	}
+
+	expected := []metadata.MigrationOperation{
+		{Collection: "cc_New", Operation: opRenameTable, Times: 1},
+		{Collection: "cc_Old", Operation: opRenameTable, Times: 1, Documents: 3},
+	}
+	if !reflect.DeepEqual(operations, expected) {
+		t.Errorf("recorded operations %+v, expect %+v", operations, expected)
+	}
+}
+
+func TestDryRunAggregate(t *testing.T) {
+	ctx := context.Background()
+
+	testCases := []struct {
+		name     string
+		pipeline interface{}
+		blocked  bool
+	}{
+		{
+			name:     "read only pipeline",
+			pipeline: []mapstr.MapStr{{"$match": mapstr.MapStr{"bk_obj_id": "host"}}, {"$count": "count"}},
+		},
+		{
+			name:     "read only pipeline of bson.D",
+			pipeline: mongo.Pipeline{{{Key: "$match", Value: bson.M{"bk_obj_id": "host"}}}},
+		},
+		{
+			name:     "out stage",
+			pipeline: []mapstr.MapStr{{"$match": mapstr.MapStr{"bk_obj_id": "host"}}, {"$out": "cc_ObjDes_bak"}},
+			blocked:  true,
+		},
+		{
+			name: "merge stage",
+			pipeline: []interface{}{
+				bson.M{"$project": bson.M{"bk_obj_id": 1}},
+				bson.D{{Key: "$merge", Value: bson.M{"into": "cc_ObjDes_bak"}}},
+			},
+			blocked: true,
+		},
+		{
+			name:     "invalid pipeline",
+			pipeline: "$out",
+			blocked:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		for _, aggregateAll := range []bool{true, false} {
+			realDB := &fakeRDB{count: 3}
+			db := newDryRunDB(realDB)
+
+			var err error
+			result := make([]mapstr.MapStr, 0)
+			if aggregateAll {
+				err = db.Table("cc_ObjDes").AggregateAll(ctx, tc.pipeline, &result)
+			} else {
+				err = db.Table("cc_ObjDes").AggregateOne(ctx, tc.pipeline, &result)
+			}
+
+			_, operations := db.result()
+			if !tc.blocked {
+				if err != nil || realDB.aggregated != 1 || len(operations) != 0 {
+					t.Errorf("%s: read only aggregation should be done, err: %v, operations: %+v", tc.name, err,
+						operations)
+				}
+				continue
+			}
+
+			if err == nil || realDB.aggregated != 0 {
+				t.Errorf("%s: aggregation that writes to a collection should be blocked", tc.name)
+			}
+			expected := []metadata.MigrationOperation{{Collection: "cc_ObjDes", Operation: opAggregateOut, Times: 1}}
+			if !reflect.DeepEqual(operations, expected) {
+				t.Errorf("%s: recorded operations %+v, expect %+v", tc.name, operations, expected)
+			}
+		}
+	}
+}
@@ -0,0 +1,104 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package upgrader
+
+import (
+	"context"
+	"time"
+
+	"configcenter/src/common"
+	"configcenter/src/common/blog"
+	"configcenter/src/common/metadata"
+	"configcenter/src/storage/dal"
+)
+
+// startRecord save the running record of the upgrader, the failure of saving records does not stop the migration,
+// returns nil record if it fails.
+func startRecord(ctx context.Context, db dal.RDB, version string, mode metadata.MigrationMode) *metadata.MigrationRecord {
+	id, err := db.NextSequence(ctx, common.BKTableNameMigrationRecord)
+	if err != nil {
+		blog.Errorf("generate migration record id for version %s failed, err: %v", version, err)
+		return nil
+	}
+
+	record := &metadata.MigrationRecord{
+		ID:        int64(id),
+		Version:   version,
+		Mode:      mode,
+		Status:    metadata.MigrationStatusRunning,
+		StartTime: time.Now(),
+	}
+
+	if err := db.Table(common.BKTableNameMigrationRecord).Insert(ctx, record); err != nil {
+		blog.Errorf("save migration record %+v failed, err: %v", record, err)
+		return nil
+	}
+	return record
+}
+
+// finishRecord update the upgrader record with the execution result
+func finishRecord(ctx context.Context, db dal.RDB, record *metadata.MigrationRecord, runErr error) {
+	if record == nil {
+		return
+	}
+
+	endTime := time.Now()
+	update := map[string]interface{}{
+		"status":   metadata.MigrationStatusSuccess,
+		"end_time": endTime,
+		"duration": endTime.Sub(record.StartTime).Milliseconds(),
+	}
+	if runErr != nil {
+		update["status"] = metadata.MigrationStatusFailed
+		update["error"] = runErr.Error()
+	}
+
+	filter := map[string]interface{}{common.BKFieldID: record.ID}
+	if err := db.Table(common.BKTableNameMigrationRecord).Update(ctx, filter, update); err != nil {
+		blog.Errorf("update migration record %d of version %s failed, err: %v", record.ID, record.Version, err)
+	}
+}
+
+// ListRecords list the upgrader execution records, the latest records are returned first by default
+func ListRecords(ctx context.Context, db dal.RDB, opt *metadata.ListMigrationRecordOption) (
+	*metadata.ListMigrationRecordResult, error) {
+
+	filter := make(map[string]interface{})
+	if len(opt.Versions) > 0 {
+		filter["version"] = map[string]interface{}{common.BKDBIN: opt.Versions}
+	}
+	if opt.Status != "" {
+		filter["status"] = opt.Status
+	}
+
+	table := db.Table(common.BKTableNameMigrationRecord)
+	count, err := table.Find(filter).Count(ctx)
+	if err != nil {
+		blog.Errorf("count migration records failed, filter: %+v, err: %v", filter, err)
+		return nil, err
+	}
+
+	sort := opt.Page.Sort
+	if sort == "" {
+		sort = "-" + common.BKFieldID
+	}
+
+	records := make([]metadata.MigrationRecord, 0)
+	err = table.Find(filter).Sort(sort).Start(uint64(opt.Page.Start)).Limit(uint64(opt.Page.Limit)).All(ctx, &records)
+	if err != nil {
+		blog.Errorf("list migration records failed, filter: %+v, err: %v", filter, err)
+		return nil, err
+	}
+
+	return &metadata.ListMigrationRecordResult{Count: count, Info: records}, nil
+}
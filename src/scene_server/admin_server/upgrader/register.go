@@ -24,6 +24,7 @@ import (
 	"configcenter/src/ac/iam"
 	"configcenter/src/common"
 	"configcenter/src/common/blog"
+	"configcenter/src/common/metadata"
 	"configcenter/src/common/util"
 	ccversion "configcenter/src/common/version"
 	"configcenter/src/storage/dal"
@@ -244,7 +245,9 @@ func Upgrade(ctx context.Context, db dal.RDB, cache redis.Client, iam *iam.IAM,
 			continue
 		}
 		blog.Infof(`run migration: %s`, v.version)
+		record := startRecord(ctx, db, v.version, metadata.MigrationModeUpgrade)
 		err = v.do(ctx, db, cache, iam, conf)
+		finishRecord(ctx, db, record, err)
 		if err != nil {
 			blog.Errorf("upgrade version %s error: %s", v.version, err.Error())
 			return currentVersion, finishedMigrations, fmt.Errorf("run migration %s failed, err: %s", v.version, err.Error())
@@ -309,7 +312,9 @@ func UpgradeSpecifyVersion(ctx context.Context, db dal.RDB, cache redis.Client,
 			continue
 		}
 		blog.Infof(`run specify migration: %s`, v.version)
+		record := startRecord(ctx, db, v.version, metadata.MigrationModeSpecify)
 		err = v.do(ctx, db, cache, iam, conf)
+		finishRecord(ctx, db, record, err)
 		if err != nil {
 			blog.Errorf("upgrade specify version %s error: %s", v.version, err.Error())
 			return fmt.Errorf("run specify migration %s failed, err: %s", v.version, err.Error())
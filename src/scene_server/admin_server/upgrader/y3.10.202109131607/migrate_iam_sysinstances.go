@@ -32,7 +32,7 @@ import (
 
 // migrateIAMSysInstances migrate iam system instances
 func migrateIAMSysInstances(ctx context.Context, db dal.RDB, iam *iamtype.IAM, conf *upgrader.Config) error {
-	if !auth.EnableAuthorize() {
+	if !auth.EnableAuthorize() || iamtype.IsLocalAuthMode() {
 		return nil
 	}
 
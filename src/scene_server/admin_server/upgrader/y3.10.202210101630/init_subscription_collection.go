@@ -0,0 +1,124 @@
+/*
+ * Tencent is pleased to support the open source community by making
+ * 蓝鲸智云 - 配置平台 (BlueKing - Configuration System) available.
+ * Copyright (C) 2017 THL A29 Limited,
+ * a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on
+ * an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+package y3_10_202210101630
+
+import (
+	"context"
+
+	"configcenter/src/common"
+	"configcenter/src/common/blog"
+	"configcenter/src/storage/dal"
+	"configcenter/src/storage/dal/types"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func addSubscriptionCollection(ctx context.Context, db dal.RDB) error {
+	collections := []string{common.BKTableNameEventSubscription, common.BKTableNameSubscriptionDeadLetter}
+
+	for _, collection := range collections {
+		exists, err := db.HasTable(ctx, collection)
+		if err != nil {
+			blog.Errorf("check if %s table exists failed, err: %v", collection, err)
+			return err
+		}
+
+		if exists {
+			continue
+		}
+
+		if err := db.CreateTable(ctx, collection); err != nil {
+			blog.Errorf("create %s table failed, err: %v", collection, err)
+			return err
+		}
+	}
+	return nil
+}
+
+func addSubscriptionCollectionIndex(ctx context.Context, db dal.RDB) error {
+	subscriptionIndexes := []types.Index{
+		{
+			Name: common.CCLogicUniqueIdxNamePrefix + common.BKFieldID,
+			Keys: bson.D{
+				{common.BKFieldID, 1},
+			},
+			Background: true,
+			Unique:     true,
+		},
+		{
+			Name: common.CCLogicUniqueIdxNamePrefix + "name_bk_supplier_account",
+			Keys: bson.D{
+				{common.BKFieldName, 1},
+				{common.BkSupplierAccount, 1},
+			},
+			Background: true,
+			Unique:     true,
+		},
+	}
+
+	if err := createIndexes(ctx, db, common.BKTableNameEventSubscription, subscriptionIndexes); err != nil {
+		return err
+	}
+
+	deadLetterIndexes := []types.Index{
+		{
+			Name: common.CCLogicUniqueIdxNamePrefix + common.BKFieldID,
+			Keys: bson.D{
+				{common.BKFieldID, 1},
+			},
+			Background: true,
+			Unique:     true,
+		},
+		{
+			Name: common.CCLogicIndexNamePrefix + "subscription_id_create_time",
+			Keys: bson.D{
+				{"subscription_id", 1},
+				{common.CreateTimeField, 1},
+			},
+			Background: true,
+		},
+	}
+
+	return createIndexes(ctx, db, common.BKTableNameSubscriptionDeadLetter, deadLetterIndexes)
+}
+
+func createIndexes(ctx context.Context, db dal.RDB, collection string, indexes []types.Index) error {
+	existIndexArr, err := db.Table(collection).Indexes(ctx)
+	if err != nil {
+		blog.Errorf("get exist index for %s table failed, err: %v", collection, err)
+		return err
+	}
+
+	existIdxMap := make(map[string]bool)
+	for _, index := range existIndexArr {
+		existIdxMap[index.Name] = true
+	}
+
+	for _, index := range indexes {
+		if _, exist := existIdxMap[index.Name]; exist {
+			continue
+		}
+
+		err = db.Table(collection).CreateIndex(ctx, index)
+		if err != nil && !db.IsDuplicatedError(err) {
+			blog.Errorf("create index for %s table failed, index: %+v, err: %v", collection, index, err)
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+/*
+ * Tencent is pleased to support the open source community by making
+ * 蓝鲸智云 - 配置平台 (BlueKing - Configuration System) available.
+ * Copyright (C) 2017 THL A29 Limited,
+ * a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on
+ * an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+package y3_10_202210181030
+
+import (
+	"context"
+
+	"configcenter/src/common"
+	"configcenter/src/common/blog"
+	"configcenter/src/common/mapstr"
+	"configcenter/src/storage/dal"
+	"configcenter/src/storage/dal/types"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// addAPITaskPriority set the priority of the existing tasks to the default priority 0, so that they are sorted
+// together with the new tasks with default priority.
+func addAPITaskPriority(ctx context.Context, db dal.RDB) error {
+	cond := mapstr.MapStr{
+		"priority": mapstr.MapStr{common.BKDBExists: false},
+	}
+
+	if err := db.Table(common.BKTableNameAPITask).Update(ctx, cond, mapstr.MapStr{"priority": 0}); err != nil {
+		blog.Errorf("set api task default priority failed, err: %v", err)
+		return err
+	}
+	return nil
+}
+
+func addAPITaskPriorityIndex(ctx context.Context, db dal.RDB) error {
+	index := types.Index{
+		Name: common.CCLogicIndexNamePrefix + "task_type_status_priority_create_time",
+		Keys: bson.D{
+			{common.BKTaskTypeField, 1},
+			{common.BKStatusField, 1},
+			{"priority", -1},
+			{common.CreateTimeField, 1},
+		},
+		Background: true,
+	}
+
+	existIndexArr, err := db.Table(common.BKTableNameAPITask).Indexes(ctx)
+	if err != nil {
+		blog.Errorf("get exist index for api task table failed, err: %v", err)
+		return err
+	}
+
+	for _, existIndex := range existIndexArr {
+		if existIndex.Name == index.Name {
+			return nil
+		}
+	}
+
+	err = db.Table(common.BKTableNameAPITask).CreateIndex(ctx, index)
+	if err != nil && !db.IsDuplicatedError(err) {
+		blog.Errorf("create index for api task table failed, index: %+v, err: %v", index, err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+/*
+ * Tencent is pleased to support the open source community by making
+ * 蓝鲸智云 - 配置平台 (BlueKing - Configuration System) available.
+ * Copyright (C) 2017 THL A29 Limited,
+ * a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on
+ * an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+package y3_10_202210191030
+
+import (
+	"context"
+
+	"configcenter/src/common"
+	"configcenter/src/common/blog"
+	"configcenter/src/common/mapstr"
+	"configcenter/src/common/metadata"
+	"configcenter/src/storage/dal"
+)
+
+// enumVal enum option val
+type enumVal struct {
+	ID        string `bson:"id"`
+	Name      string `bson:"name"`
+	Type      string `bson:"type"`
+	IsDefault bool   `bson:"is_default"`
+}
+
+type cloudVendorAttr struct {
+	ID     int64     `bson:"id"`
+	Option []enumVal `bson:"option"`
+}
+
+// addOpenStackCloudVendor add openstack to the enum options of host and cloud area's cloud vendor attribute
+func addOpenStackCloudVendor(ctx context.Context, db dal.RDB) error {
+	cond := map[string]interface{}{
+		common.BKObjIDField: mapstr.MapStr{
+			common.BKDBIN: []string{common.BKInnerObjIDHost, common.BKInnerObjIDPlat},
+		},
+		common.BKPropertyIDField: common.BKCloudVendor,
+	}
+
+	attrs := make([]cloudVendorAttr, 0)
+	if err := db.Table(common.BKTableNameObjAttDes).Find(cond).Fields(common.BKFieldID, common.BKOptionField).
+		All(ctx, &attrs); err != nil {
+		blog.Errorf("get cloud vendor attributes failed, cond: %#v, err: %v", cond, err)
+		return err
+	}
+
+	for _, attr := range attrs {
+		exists := false
+		for _, option := range attr.Option {
+			if option.ID == metadata.OpenStack {
+				exists = true
+				break
+			}
+		}
+		if exists {
+			continue
+		}
+
+		option := append(attr.Option, enumVal{ID: metadata.OpenStack, Name: "OpenStack", Type: "text"})
+		updateCond := map[string]interface{}{common.BKFieldID: attr.ID}
+		updateData := mapstr.MapStr{common.BKOptionField: option}
+		if err := db.Table(common.BKTableNameObjAttDes).Update(ctx, updateCond, updateData); err != nil {
+			blog.Errorf("update cloud vendor attribute option failed, id: %d, err: %v", attr.ID, err)
+			return err
+		}
+	}
+
+	return nil
+}
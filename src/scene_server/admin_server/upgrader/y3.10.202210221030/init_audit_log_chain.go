@@ -0,0 +1,144 @@
+/*
+ * Tencent is pleased to support the open source community by making
+ * 蓝鲸智云 - 配置平台 (BlueKing - Configuration System) available.
+ * Copyright (C) 2017 THL A29 Limited,
+ * a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on
+ * an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+package y3_10_202210221030
+
+import (
+	"context"
+
+	"configcenter/src/common"
+	"configcenter/src/common/blog"
+	"configcenter/src/common/mapstr"
+	"configcenter/src/common/metadata"
+	"configcenter/src/storage/dal"
+	"configcenter/src/storage/dal/types"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var auditLogChainTableIndexes = map[string][]types.Index{
+	common.BKTableNameAuditLogChain: {
+		{
+			Name:       common.CCLogicUniqueIdxNamePrefix + "type_bk_supplier_account",
+			Keys:       bson.D{{"type", 1}, {common.BkSupplierAccount, 1}},
+			Background: true,
+			Unique:     true,
+		},
+	},
+	common.BKTableNameAuditLogCheckpoint: {
+		{
+			Name:       common.CCLogicUniqueIdxNamePrefix + common.BKFieldID,
+			Keys:       bson.D{{common.BKFieldID, 1}},
+			Background: true,
+			Unique:     true,
+		},
+		{
+			Name:       common.CCLogicIndexNamePrefix + "bk_supplier_account_chain_seq",
+			Keys:       bson.D{{common.BkSupplierAccount, 1}, {"chain_seq", 1}},
+			Background: true,
+		},
+	},
+	common.BKTableNameAuditLog: {
+		{
+			Name:       common.CCLogicIndexNamePrefix + "bk_supplier_account_chain_seq",
+			Keys:       bson.D{{common.BkSupplierAccount, 1}, {"chain_seq", 1}},
+			Background: true,
+		},
+	},
+}
+
+func addAuditLogChainCollections(ctx context.Context, db dal.RDB) error {
+	for _, table := range []string{common.BKTableNameAuditLogChain, common.BKTableNameAuditLogCheckpoint} {
+		exists, err := db.HasTable(ctx, table)
+		if err != nil {
+			blog.Errorf("check if %s table exists failed, err: %v", table, err)
+			return err
+		}
+
+		if exists {
+			continue
+		}
+
+		if err := db.CreateTable(ctx, table); err != nil && !db.IsDuplicatedError(err) {
+			blog.Errorf("create %s table failed, err: %v", table, err)
+			return err
+		}
+	}
+	return nil
+}
+
+func addAuditLogChainIndexes(ctx context.Context, db dal.RDB) error {
+	for table, indexes := range auditLogChainTableIndexes {
+		existIndexArr, err := db.Table(table).Indexes(ctx)
+		if err != nil {
+			blog.Errorf("get exist index for %s table failed, err: %v", table, err)
+			return err
+		}
+
+		existIdxMap := make(map[string]bool)
+		for _, index := range existIndexArr {
+			existIdxMap[index.Name] = true
+		}
+
+		for _, index := range indexes {
+			if _, exist := existIdxMap[index.Name]; exist {
+				continue
+			}
+
+			err = db.Table(table).CreateIndex(ctx, index)
+			if err != nil && !db.IsDuplicatedError(err) {
+				blog.Errorf("create index for %s table failed, index: %+v, err: %v", table, index, err)
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// initAuditLogChainCursor the existing audit logs are not chained, the sealer starts from the current max audit id
+func initAuditLogChainCursor(ctx context.Context, db dal.RDB) error {
+	cursorFilter := mapstr.MapStr{"type": metadata.AuditLogChainCursor}
+	cnt, err := db.Table(common.BKTableNameAuditLogChain).Find(cursorFilter).Count(ctx)
+	if err != nil {
+		blog.Errorf("count audit log chain cursor failed, err: %v", err)
+		return err
+	}
+
+	if cnt > 0 {
+		return nil
+	}
+
+	lastLogs := make([]metadata.AuditLog, 0)
+	err = db.Table(common.BKTableNameAuditLog).Find(mapstr.MapStr{}).Fields(common.BKFieldID).
+		Sort("-"+common.BKFieldID).Limit(1).All(ctx, &lastLogs)
+	if err != nil {
+		blog.Errorf("get the last audit log failed, err: %v", err)
+		return err
+	}
+
+	cursor := metadata.AuditLogChainCursorDoc{Type: metadata.AuditLogChainCursor}
+	if len(lastLogs) > 0 {
+		cursor.StartID = lastLogs[0].ID
+		cursor.LastID = lastLogs[0].ID
+	}
+
+	if err := db.Table(common.BKTableNameAuditLogChain).Insert(ctx, cursor); err != nil && !db.IsDuplicatedError(err) {
+		blog.Errorf("insert audit log chain cursor %+v failed, err: %v", cursor, err)
+		return err
+	}
+	return nil
+}
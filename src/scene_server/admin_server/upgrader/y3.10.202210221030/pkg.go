@@ -0,0 +1,52 @@
+/*
+ * Tencent is pleased to support the open source community by making
+ * 蓝鲸智云 - 配置平台 (BlueKing - Configuration System) available.
+ * Copyright (C) 2017 THL A29 Limited,
+ * a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on
+ * an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+package y3_10_202210221030
+
+import (
+	"context"
+
+	"configcenter/src/common/blog"
+	"configcenter/src/scene_server/admin_server/upgrader"
+	"configcenter/src/storage/dal"
+)
+
+func init() {
+	upgrader.RegistUpgrader("y3.10.202210221030", upgrade)
+}
+
+func upgrade(ctx context.Context, db dal.RDB, conf *upgrader.Config) (err error) {
+	blog.Infof("start execute y3.10.202210221030, init audit log hash chain")
+
+	if err = addAuditLogChainCollections(ctx, db); err != nil {
+		blog.Errorf("upgrade y3.10.202210221030 add audit log chain collections failed, err: %v", err)
+		return err
+	}
+
+	if err = addAuditLogChainIndexes(ctx, db); err != nil {
+		blog.Errorf("upgrade y3.10.202210221030 add audit log chain indexes failed, err: %v", err)
+		return err
+	}
+
+	if err = initAuditLogChainCursor(ctx, db); err != nil {
+		blog.Errorf("upgrade y3.10.202210221030 init audit log chain cursor failed, err: %v", err)
+		return err
+	}
+
+	blog.Infof("upgrade y3.10.202210221030 init audit log hash chain success")
+	return nil
+}
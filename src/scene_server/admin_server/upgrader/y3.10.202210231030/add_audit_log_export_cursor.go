@@ -0,0 +1,69 @@
+/*
+ * Tencent is pleased to support the open source community by making
+ * 蓝鲸智云 - 配置平台 (BlueKing - Configuration System) available.
+ * Copyright (C) 2017 THL A29 Limited,
+ * a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on
+ * an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ * We undertake not to change the open source license (MIT license) applicable
+ * to the current version of the project delivered to anyone in the future.
+ */
+
+package y3_10_202210231030
+
+import (
+	"context"
+
+	"configcenter/src/common"
+	"configcenter/src/common/blog"
+	"configcenter/src/storage/dal"
+	"configcenter/src/storage/dal/types"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func addAuditLogExportCursorCollection(ctx context.Context, db dal.RDB) error {
+	table := common.BKTableNameAuditLogExportCursor
+	exists, err := db.HasTable(ctx, table)
+	if err != nil {
+		blog.Errorf("check if %s table exists failed, err: %v", table, err)
+		return err
+	}
+
+	if !exists {
+		if err := db.CreateTable(ctx, table); err != nil && !db.IsDuplicatedError(err) {
+			blog.Errorf("create %s table failed, err: %v", table, err)
+			return err
+		}
+	}
+
+	existIndexArr, err := db.Table(table).Indexes(ctx)
+	if err != nil {
+		blog.Errorf("get exist index for %s table failed, err: %v", table, err)
+		return err
+	}
+
+	index := types.Index{
+		Name:       common.CCLogicUniqueIdxNamePrefix + "name",
+		Keys:       bson.D{{"name", 1}},
+		Background: true,
+		Unique:     true,
+	}
+	for _, existIndex := range existIndexArr {
+		if existIndex.Name == index.Name {
+			return nil
+		}
+	}
+
+	if err := db.Table(table).CreateIndex(ctx, index); err != nil && !db.IsDuplicatedError(err) {
+		blog.Errorf("create index for %s table failed, index: %+v, err: %v", table, index, err)
+		return err
+	}
+	return nil
+}
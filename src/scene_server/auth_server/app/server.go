@@ -31,8 +31,12 @@ import (
 	"configcenter/src/scene_server/auth_server/sdk/client"
 	sdktypes "configcenter/src/scene_server/auth_server/sdk/types"
 	"configcenter/src/scene_server/auth_server/service"
+	"configcenter/src/storage/dal/mongo/local"
 )
 
+// defaultDBConnectTimeout is the connect timeout of cc db which is used in local auth mode
+const defaultDBConnectTimeout = 5 * time.Second
+
 // Run TODO
 func Run(ctx context.Context, cancel context.CancelFunc, op *options.ServerOption) error {
 	svrInfo, err := types.NewServerInfo(op.ServConf)
@@ -61,6 +65,22 @@ func Run(ctx context.Context, cancel context.CancelFunc, op *options.ServerOptio
 			continue
 		}
 
+		if iam.IsLocalAuthMode() {
+			mongoConf, err := engine.WithMongo()
+			if err != nil {
+				return fmt.Errorf("init mongodb configs failed, err: %v", err)
+			}
+
+			db, err := local.NewMgo(mongoConf.GetMongoConf(), defaultDBConnectTimeout)
+			if err != nil {
+				return fmt.Errorf("new mongodb client failed, err: %v", err)
+			}
+
+			blog.Infof("auth server runs in local auth mode")
+			authServer.Service = service.NewLocalAuthService(engine, db)
+			break
+		}
+
 		authConf := authServer.Config.Auth
 		iamConf := sdktypes.IamConfig{
 			Address:   authConf.Address,
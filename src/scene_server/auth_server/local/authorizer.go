@@ -0,0 +1,328 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package local is the local rbac authorizer which authorizes the users with the roles stored in cc db
+package local
+
+import (
+	"strconv"
+	"strings"
+
+	"configcenter/src/ac/meta"
+	"configcenter/src/common"
+	cc "configcenter/src/common/backbone/configcenter"
+	"configcenter/src/common/blog"
+	"configcenter/src/common/http/rest"
+	"configcenter/src/common/mapstr"
+	"configcenter/src/common/util"
+	"configcenter/src/scene_server/auth_server/sdk/types"
+	"configcenter/src/storage/dal"
+)
+
+// Authorizer authorizes the users with the local roles, user groups and role bindings
+type Authorizer struct {
+	db dal.RDB
+}
+
+// NewAuthorizer new local rbac authorizer
+func NewAuthorizer(db dal.RDB) *Authorizer {
+	return &Authorizer{db: db}
+}
+
+// policy is a role bound to the user in the scope of the binding
+type policy struct {
+	binding meta.LocalAuthRoleBinding
+	role    meta.LocalAuthRole
+}
+
+// isMatched returns if the policy allows the action on the resource type
+func (p *policy) isMatched(resourceType meta.ResourceType, action meta.Action) bool {
+	for _, perm := range p.role.Permissions {
+		if perm.IsMatched(resourceType, action) {
+			return true
+		}
+	}
+	return false
+}
+
+// AuthorizeBatch authorize the resources of the user, if exact is false, the resource is authorized as long
+// as the user has the permission of the action on any instance of the resource type
+func (a *Authorizer) AuthorizeBatch(kit *rest.Kit, opts *meta.LocalAuthBatchOption, exact bool) (
+	[]types.Decision, error) {
+
+	decisions := make([]types.Decision, len(opts.Resources))
+	if IsAdmin(opts.User.UserName) {
+		for idx := range decisions {
+			decisions[idx].Authorized = true
+		}
+		return decisions, nil
+	}
+
+	policies, err := a.getUserPolicies(kit, opts.User)
+	if err != nil {
+		return nil, err
+	}
+
+	return authorizeResources(policies, opts.Resources, exact), nil
+}
+
+// authorizeResources authorize the resources with the policies of the user
+func authorizeResources(policies []policy, resources []meta.ResourceAttribute, exact bool) []types.Decision {
+	decisions := make([]types.Decision, len(resources))
+	for idx := range resources {
+		resource := &resources[idx]
+		for _, p := range policies {
+			if !p.isMatched(resource.Type, resource.Action) {
+				continue
+			}
+
+			if !exact || p.binding.IsResourceInScope(resource) {
+				decisions[idx].Authorized = true
+				break
+			}
+		}
+	}
+	return decisions
+}
+
+// ListAuthorizedResources list the ids of the resources that the user has the permission of the action
+func (a *Authorizer) ListAuthorizedResources(kit *rest.Kit, input *meta.ListAuthorizedResourcesParam) (
+	*types.AuthorizeList, error) {
+
+	if IsAdmin(input.UserName) {
+		return &types.AuthorizeList{IsAny: true}, nil
+	}
+
+	user := meta.UserInfo{UserName: input.UserName, SupplierAccount: kit.SupplierAccount}
+	policies, err := a.getUserPolicies(kit, user)
+	if err != nil {
+		return nil, err
+	}
+
+	list, bizIDs := listAuthorizedResources(policies, input)
+	if list.IsAny || len(bizIDs) == 0 {
+		return list, nil
+	}
+
+	// the resources in the businesses of the biz scoped roles are authorized
+	ids, err := a.getBizResourceIDs(kit, input.ResourceType, bizIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	idMap := make(map[string]struct{})
+	for _, id := range list.Ids {
+		idMap[id] = struct{}{}
+	}
+	for _, id := range ids {
+		if _, exists := idMap[id]; exists {
+			continue
+		}
+		idMap[id] = struct{}{}
+		list.Ids = append(list.Ids, id)
+	}
+	return list, nil
+}
+
+// listAuthorizedResources list the authorized resources with the policies of the user, returns the authorized
+// resource ids and the businesses whose resources of the resource type are all authorized.
+func listAuthorizedResources(policies []policy, input *meta.ListAuthorizedResourcesParam) (*types.AuthorizeList,
+	[]int64) {
+
+	ids := make([]string, 0)
+	idMap := make(map[int64]struct{})
+	bizIDs := make([]int64, 0)
+	bizIDMap := make(map[int64]struct{})
+	for _, p := range policies {
+		if !p.isMatched(input.ResourceType, input.Action) {
+			continue
+		}
+
+		switch p.binding.ScopeType {
+		case meta.LocalAuthScopeGlobal:
+			return &types.AuthorizeList{IsAny: true}, nil
+		case meta.LocalAuthScopeBiz:
+			// all the resources in the business are authorized
+			if input.BizID > 0 && input.BizID == p.binding.ScopeID {
+				return &types.AuthorizeList{IsAny: true}, nil
+			}
+			if input.ResourceType != meta.Business {
+				if input.BizID == 0 {
+					if _, exists := bizIDMap[p.binding.ScopeID]; !exists {
+						bizIDMap[p.binding.ScopeID] = struct{}{}
+						bizIDs = append(bizIDs, p.binding.ScopeID)
+					}
+				}
+				continue
+			}
+		case meta.LocalAuthScopeBizSet:
+			if input.ResourceType != meta.BizSet {
+				continue
+			}
+		case meta.LocalAuthScopeModel:
+			if input.ResourceType != meta.Model {
+				continue
+			}
+		default:
+			continue
+		}
+
+		if _, exists := idMap[p.binding.ScopeID]; exists {
+			continue
+		}
+		idMap[p.binding.ScopeID] = struct{}{}
+		ids = append(ids, strconv.FormatInt(p.binding.ScopeID, 10))
+	}
+
+	return &types.AuthorizeList{Ids: ids}, bizIDs
+}
+
+// bizResourceTable is the table and id field of the business resource
+type bizResourceTable struct {
+	table   string
+	idField string
+}
+
+// bizResourceTables are the tables of the business resources that can be authorized by the biz scoped roles, the
+// resources of other types are not in the scope of any business.
+var bizResourceTables = map[meta.ResourceType]bizResourceTable{
+	meta.HostInstance:           {common.BKTableNameModuleHostConfig, common.BKHostIDField},
+	meta.ModelSet:               {common.BKTableNameBaseSet, common.BKSetIDField},
+	meta.ModelModule:            {common.BKTableNameBaseModule, common.BKModuleIDField},
+	meta.Process:                {common.BKTableNameBaseProcess, common.BKProcessIDField},
+	meta.ProcessServiceInstance: {common.BKTableNameServiceInstance, common.BKFieldID},
+	meta.ProcessServiceTemplate: {common.BKTableNameServiceTemplate, common.BKFieldID},
+	meta.ProcessServiceCategory: {common.BKTableNameServiceCategory, common.BKFieldID},
+	meta.SetTemplate:            {common.BKTableNameSetTemplate, common.BKFieldID},
+	meta.DynamicGrouping:        {common.BKTableNameDynamicGroup, common.BKFieldID},
+}
+
+// getBizResourceIDs get the ids of the resources of the resource type in the businesses
+func (a *Authorizer) getBizResourceIDs(kit *rest.Kit, resourceType meta.ResourceType, bizIDs []int64) ([]string,
+	error) {
+
+	resTable, exists := bizResourceTables[resourceType]
+	if !exists {
+		return make([]string, 0), nil
+	}
+
+	filter := util.SetModOwner(mapstr.MapStr{common.BKAppIDField: mapstr.MapStr{common.BKDBIN: bizIDs}},
+		kit.SupplierAccount)
+	rawIDs, err := a.db.Table(resTable.table).Distinct(kit.Ctx, resTable.idField, filter)
+	if err != nil {
+		blog.Errorf("get %s ids in biz %v failed, err: %v, rid: %s", resourceType, bizIDs, err, kit.Rid)
+		return nil, kit.CCError.CCError(common.CCErrCommDBSelectFailed)
+	}
+
+	ids := make([]string, 0, len(rawIDs))
+	for _, rawID := range rawIDs {
+		if id, ok := rawID.(string); ok {
+			ids = append(ids, id)
+			continue
+		}
+
+		id, err := util.GetInt64ByInterface(rawID)
+		if err != nil {
+			blog.Errorf("parse %s id %v failed, err: %v, rid: %s", resourceType, rawID, err, kit.Rid)
+			return nil, kit.CCError.CCErrorf(common.CCErrCommParamsIsInvalid, resTable.idField)
+		}
+		ids = append(ids, strconv.FormatInt(id, 10))
+	}
+	return ids, nil
+}
+
+// getUserPolicies get the roles that are bound to the user directly or to the groups that the user belongs to
+func (a *Authorizer) getUserPolicies(kit *rest.Kit, user meta.UserInfo) ([]policy, error) {
+	supplierAccount := user.SupplierAccount
+	if supplierAccount == "" {
+		supplierAccount = kit.SupplierAccount
+	}
+
+	groupFilter := util.SetModOwner(mapstr.MapStr{"members": user.UserName}, supplierAccount)
+	groups := make([]meta.LocalAuthGroup, 0)
+	err := a.db.Table(common.BKTableNameLocalAuthGroup).Find(groupFilter).Fields(common.BKFieldID).
+		All(kit.Ctx, &groups)
+	if err != nil {
+		blog.Errorf("get user %s groups failed, err: %v, rid: %s", user.UserName, err, kit.Rid)
+		return nil, kit.CCError.CCError(common.CCErrCommDBSelectFailed)
+	}
+
+	subjectCond := []mapstr.MapStr{{"subject_type": meta.LocalAuthSubjectUser, "subject": user.UserName}}
+	if len(groups) > 0 {
+		groupIDs := make([]string, len(groups))
+		for idx, group := range groups {
+			groupIDs[idx] = strconv.FormatInt(group.ID, 10)
+		}
+		subjectCond = append(subjectCond, mapstr.MapStr{
+			"subject_type": meta.LocalAuthSubjectGroup,
+			"subject":      mapstr.MapStr{common.BKDBIN: groupIDs},
+		})
+	}
+
+	bindingFilter := util.SetModOwner(mapstr.MapStr{common.BKDBOR: subjectCond}, supplierAccount)
+	bindings := make([]meta.LocalAuthRoleBinding, 0)
+	err = a.db.Table(common.BKTableNameLocalAuthRoleBinding).Find(bindingFilter).All(kit.Ctx, &bindings)
+	if err != nil {
+		blog.Errorf("get user %s role bindings failed, err: %v, rid: %s", user.UserName, err, kit.Rid)
+		return nil, kit.CCError.CCError(common.CCErrCommDBSelectFailed)
+	}
+
+	if len(bindings) == 0 {
+		return make([]policy, 0), nil
+	}
+
+	roleIDs := make([]int64, 0)
+	for _, binding := range bindings {
+		roleIDs = append(roleIDs, binding.RoleID)
+	}
+
+	roleFilter := util.SetModOwner(mapstr.MapStr{
+		common.BKFieldID: mapstr.MapStr{common.BKDBIN: util.IntArrayUnique(roleIDs)},
+	}, supplierAccount)
+	roles := make([]meta.LocalAuthRole, 0)
+	err = a.db.Table(common.BKTableNameLocalAuthRole).Find(roleFilter).All(kit.Ctx, &roles)
+	if err != nil {
+		blog.Errorf("get user %s roles failed, ids: %v, err: %v, rid: %s", user.UserName, roleIDs, err, kit.Rid)
+		return nil, kit.CCError.CCError(common.CCErrCommDBSelectFailed)
+	}
+
+	roleMap := make(map[int64]meta.LocalAuthRole)
+	for _, role := range roles {
+		roleMap[role.ID] = role
+	}
+
+	policies := make([]policy, 0)
+	for _, binding := range bindings {
+		role, exists := roleMap[binding.RoleID]
+		if !exists {
+			continue
+		}
+		policies = append(policies, policy{binding: binding, role: role})
+	}
+	return policies, nil
+}
+
+// IsAdmin returns if the user is the super admin configured by authServer.localAdmins, who has all permissions.
+// the super admins are used to initialize the roles and bindings when the local authorizer is first used.
+func IsAdmin(user string) bool {
+	admins, _ := cc.String("authServer.localAdmins")
+	if user == "" || admins == "" {
+		return false
+	}
+
+	for _, admin := range strings.Split(admins, ",") {
+		if strings.TrimSpace(admin) == user {
+			return true
+		}
+	}
+	return false
+}
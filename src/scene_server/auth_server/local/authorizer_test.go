@@ -0,0 +1,205 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package local
+
+import (
+	"reflect"
+	"testing"
+
+	"configcenter/src/ac/meta"
+)
+
+func newTestPolicy(scopeType meta.LocalAuthScopeType, scopeID int64, perms ...meta.LocalAuthPermission) policy {
+	return policy{
+		binding: meta.LocalAuthRoleBinding{ScopeType: scopeType, ScopeID: scopeID},
+		role:    meta.LocalAuthRole{Permissions: perms},
+	}
+}
+
+func TestAuthorizeResources(t *testing.T) {
+	hostUpdate := meta.LocalAuthPermission{ResourceType: meta.HostInstance, Actions: []meta.Action{meta.Update}}
+	anyAction := meta.LocalAuthPermission{ResourceType: meta.HostInstance, Actions: []meta.Action{meta.LocalAuthAny}}
+	anyType := meta.LocalAuthPermission{ResourceType: meta.LocalAuthAny, Actions: []meta.Action{meta.Update}}
+
+	resources := []meta.ResourceAttribute{
+		{Basic: meta.Basic{Type: meta.HostInstance, Action: meta.Update, InstanceID: 1}, BusinessID: 2},
+		{Basic: meta.Basic{Type: meta.HostInstance, Action: meta.Update, InstanceID: 2}, BusinessID: 3},
+		{Basic: meta.Basic{Type: meta.HostInstance, Action: meta.Delete, InstanceID: 1}, BusinessID: 2},
+		{Basic: meta.Basic{Type: meta.ModelSet, Action: meta.Update, InstanceID: 1}, BusinessID: 2},
+	}
+
+	tests := []struct {
+		name     string
+		policies []policy
+		exact    bool
+		want     []bool
+	}{
+		{
+			name:  "no policies",
+			exact: true,
+			want:  []bool{false, false, false, false},
+		},
+		{
+			name:     "global scope",
+			policies: []policy{newTestPolicy(meta.LocalAuthScopeGlobal, 0, hostUpdate)},
+			exact:    true,
+			want:     []bool{true, true, false, false},
+		},
+		{
+			name:     "biz scope only authorizes resources in the business",
+			policies: []policy{newTestPolicy(meta.LocalAuthScopeBiz, 2, hostUpdate)},
+			exact:    true,
+			want:     []bool{true, false, false, false},
+		},
+		{
+			name:     "any authorize ignores the scope",
+			policies: []policy{newTestPolicy(meta.LocalAuthScopeBiz, 2, hostUpdate)},
+			exact:    false,
+			want:     []bool{true, true, false, false},
+		},
+		{
+			name:     "any action",
+			policies: []policy{newTestPolicy(meta.LocalAuthScopeBiz, 2, anyAction)},
+			exact:    true,
+			want:     []bool{true, false, true, false},
+		},
+		{
+			name:     "any resource type",
+			policies: []policy{newTestPolicy(meta.LocalAuthScopeBiz, 2, anyType)},
+			exact:    true,
+			want:     []bool{true, false, false, true},
+		},
+		{
+			name: "multiple policies",
+			policies: []policy{
+				newTestPolicy(meta.LocalAuthScopeBiz, 3, hostUpdate),
+				newTestPolicy(meta.LocalAuthScopeBiz, 2, anyAction),
+			},
+			exact: true,
+			want:  []bool{true, true, true, false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decisions := authorizeResources(tt.policies, resources, tt.exact)
+			if len(decisions) != len(resources) {
+				t.Fatalf("got %d decisions, want %d", len(decisions), len(resources))
+			}
+			for idx, decision := range decisions {
+				if decision.Authorized != tt.want[idx] {
+					t.Errorf("resource %d authorized = %v, want %v", idx, decision.Authorized, tt.want[idx])
+				}
+			}
+		})
+	}
+}
+
+func TestListAuthorizedResources(t *testing.T) {
+	bizFind := meta.LocalAuthPermission{ResourceType: meta.Business, Actions: []meta.Action{meta.Find}}
+	hostFind := meta.LocalAuthPermission{ResourceType: meta.HostInstance, Actions: []meta.Action{meta.Find}}
+	bizSetFind := meta.LocalAuthPermission{ResourceType: meta.BizSet, Actions: []meta.Action{meta.Find}}
+	modelFind := meta.LocalAuthPermission{ResourceType: meta.Model, Actions: []meta.Action{meta.Find}}
+
+	tests := []struct {
+		name       string
+		policies   []policy
+		input      meta.ListAuthorizedResourcesParam
+		wantAny    bool
+		wantIDs    []string
+		wantBizIDs []int64
+	}{
+		{
+			name:     "no matched policy",
+			policies: []policy{newTestPolicy(meta.LocalAuthScopeGlobal, 0, bizFind)},
+			input:    meta.ListAuthorizedResourcesParam{ResourceType: meta.HostInstance, Action: meta.Find},
+			wantIDs:  []string{},
+		},
+		{
+			name:     "global scope is any",
+			policies: []policy{newTestPolicy(meta.LocalAuthScopeGlobal, 0, hostFind)},
+			input:    meta.ListAuthorizedResourcesParam{ResourceType: meta.HostInstance, Action: meta.Find},
+			wantAny:  true,
+		},
+		{
+			name:     "biz scope with the same business is any",
+			policies: []policy{newTestPolicy(meta.LocalAuthScopeBiz, 2, hostFind)},
+			input:    meta.ListAuthorizedResourcesParam{BizID: 2, ResourceType: meta.HostInstance, Action: meta.Find},
+			wantAny:  true,
+		},
+		{
+			name:     "biz scope with other business",
+			policies: []policy{newTestPolicy(meta.LocalAuthScopeBiz, 2, hostFind)},
+			input:    meta.ListAuthorizedResourcesParam{BizID: 3, ResourceType: meta.HostInstance, Action: meta.Find},
+			wantIDs:  []string{},
+		},
+		{
+			name: "biz scope without business returns the businesses",
+			policies: []policy{
+				newTestPolicy(meta.LocalAuthScopeBiz, 2, hostFind),
+				newTestPolicy(meta.LocalAuthScopeBiz, 3, hostFind),
+				newTestPolicy(meta.LocalAuthScopeBiz, 2, hostFind),
+			},
+			input:      meta.ListAuthorizedResourcesParam{ResourceType: meta.HostInstance, Action: meta.Find},
+			wantIDs:    []string{},
+			wantBizIDs: []int64{2, 3},
+		},
+		{
+			name: "biz scope returns the business ids",
+			policies: []policy{
+				newTestPolicy(meta.LocalAuthScopeBiz, 2, bizFind),
+				newTestPolicy(meta.LocalAuthScopeBiz, 3, bizFind),
+				newTestPolicy(meta.LocalAuthScopeBiz, 2, bizFind),
+			},
+			input:   meta.ListAuthorizedResourcesParam{ResourceType: meta.Business, Action: meta.Find},
+			wantIDs: []string{"2", "3"},
+		},
+		{
+			name: "biz set scope returns the biz set ids",
+			policies: []policy{
+				newTestPolicy(meta.LocalAuthScopeBizSet, 5, bizSetFind),
+				newTestPolicy(meta.LocalAuthScopeBiz, 2, bizSetFind),
+			},
+			input:      meta.ListAuthorizedResourcesParam{ResourceType: meta.BizSet, Action: meta.Find},
+			wantIDs:    []string{"5"},
+			wantBizIDs: []int64{2},
+		},
+		{
+			name: "model scope returns the model ids",
+			policies: []policy{
+				newTestPolicy(meta.LocalAuthScopeModel, 7, modelFind),
+				newTestPolicy(meta.LocalAuthScopeBizSet, 5, modelFind),
+			},
+			input:   meta.ListAuthorizedResourcesParam{ResourceType: meta.Model, Action: meta.Find},
+			wantIDs: []string{"7"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list, bizIDs := listAuthorizedResources(tt.policies, &tt.input)
+			if list.IsAny != tt.wantAny {
+				t.Fatalf("IsAny = %v, want %v", list.IsAny, tt.wantAny)
+			}
+			if tt.wantAny {
+				return
+			}
+			if !reflect.DeepEqual(list.Ids, tt.wantIDs) {
+				t.Errorf("Ids = %v, want %v", list.Ids, tt.wantIDs)
+			}
+			if len(bizIDs) != len(tt.wantBizIDs) || (len(bizIDs) > 0 && !reflect.DeepEqual(bizIDs, tt.wantBizIDs)) {
+				t.Errorf("bizIDs = %v, want %v", bizIDs, tt.wantBizIDs)
+			}
+		})
+	}
+}
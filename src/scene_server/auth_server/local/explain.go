@@ -0,0 +1,112 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package local
+
+import (
+	"fmt"
+
+	"configcenter/src/ac/meta"
+	"configcenter/src/common/http/rest"
+)
+
+// Explain explains why the user is authorized or denied to operate the resources with the local roles
+func (a *Authorizer) Explain(kit *rest.Kit, user meta.UserInfo, results []meta.AuthExplainResult) error {
+	if IsAdmin(user.UserName) {
+		for idx := range results {
+			if results[idx].Skipped {
+				continue
+			}
+			results[idx].Authorized = true
+			results[idx].Reason = "user is the super admin of the local authorizer"
+		}
+		return nil
+	}
+
+	policies, err := a.getUserPolicies(kit, user)
+	if err != nil {
+		return err
+	}
+
+	explainResults(policies, results)
+	return nil
+}
+
+// explainResults explains the authorize decisions of the results with the policies of the user
+func explainResults(policies []policy, results []meta.AuthExplainResult) {
+	for idx := range results {
+		result := &results[idx]
+		if result.Skipped {
+			continue
+		}
+
+		resource := &result.Resource
+		result.ActionID = string(resource.Action)
+		result.ResourceType = string(resource.Type)
+		result.ResourcePath = genResourcePath(resource)
+
+		matched := make([]meta.LocalAuthExplainPolicy, 0)
+		for _, p := range policies {
+			if !p.isMatched(resource.Type, resource.Action) {
+				continue
+			}
+
+			inScope := p.binding.IsResourceInScope(resource)
+			matched = append(matched, meta.LocalAuthExplainPolicy{
+				RoleID:      p.role.ID,
+				RoleName:    p.role.Name,
+				BindingID:   p.binding.ID,
+				SubjectType: p.binding.SubjectType,
+				Subject:     p.binding.Subject,
+				ScopeType:   p.binding.ScopeType,
+				ScopeID:     p.binding.ScopeID,
+				InScope:     inScope,
+			})
+
+			if inScope && !result.Authorized {
+				result.Authorized = true
+				result.Reason = fmt.Sprintf("authorized by role %s(%d) bound in %s scope %d", p.role.Name, p.role.ID,
+					p.binding.ScopeType, p.binding.ScopeID)
+			}
+		}
+
+		if len(matched) == 0 {
+			result.Reason = fmt.Sprintf("no role bound to the user allows action %s of resource type %s",
+				resource.Action, resource.Type)
+			continue
+		}
+
+		result.Policy = matched
+		if !result.Authorized {
+			result.Reason = fmt.Sprintf("%d roles bound to the user allow action %s of resource type %s, but the "+
+				"resource is not in the scopes of their bindings", len(matched), resource.Action, resource.Type)
+		}
+	}
+}
+
+// genResourcePath generate the topology path of the resource with its business and layers,
+// like: /business,1/modelSet,2/
+func genResourcePath(resource *meta.ResourceAttribute) []string {
+	path := "/"
+	if resource.BusinessID > 0 {
+		path += fmt.Sprintf("%s,%d/", meta.Business, resource.BusinessID)
+	}
+
+	for _, layer := range resource.Layers {
+		path += fmt.Sprintf("%s,%d/", layer.Type, layer.InstanceID)
+	}
+
+	if resource.InstanceID > 0 {
+		path += fmt.Sprintf("%s,%d/", resource.Type, resource.InstanceID)
+	}
+	return []string{path}
+}
@@ -0,0 +1,149 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package local
+
+import (
+	"reflect"
+	"testing"
+
+	"configcenter/src/ac/meta"
+)
+
+func TestExplainResults(t *testing.T) {
+	hostUpdate := meta.LocalAuthPermission{ResourceType: meta.HostInstance, Actions: []meta.Action{meta.Update}}
+	bizPolicy := newTestPolicy(meta.LocalAuthScopeBiz, 2, hostUpdate)
+	bizPolicy.role.ID, bizPolicy.role.Name, bizPolicy.binding.ID = 1, "host-maintainer", 10
+	otherBizPolicy := newTestPolicy(meta.LocalAuthScopeBiz, 3, hostUpdate)
+	otherBizPolicy.role.ID, otherBizPolicy.role.Name, otherBizPolicy.binding.ID = 1, "host-maintainer", 11
+
+	host := meta.ResourceAttribute{
+		Basic:      meta.Basic{Type: meta.HostInstance, Action: meta.Update, InstanceID: 5},
+		BusinessID: 2,
+	}
+
+	tests := []struct {
+		name         string
+		policies     []policy
+		result       meta.AuthExplainResult
+		wantAuth     bool
+		wantPolicies []meta.LocalAuthExplainPolicy
+	}{
+		{
+			name:     "no matched role",
+			policies: []policy{newTestPolicy(meta.LocalAuthScopeGlobal, 0)},
+			result:   meta.AuthExplainResult{Resource: host},
+		},
+		{
+			name:     "authorized by the role in scope",
+			policies: []policy{otherBizPolicy, bizPolicy},
+			result:   meta.AuthExplainResult{Resource: host},
+			wantAuth: true,
+			wantPolicies: []meta.LocalAuthExplainPolicy{
+				{RoleID: 1, RoleName: "host-maintainer", BindingID: 11, ScopeType: meta.LocalAuthScopeBiz, ScopeID: 3},
+				{RoleID: 1, RoleName: "host-maintainer", BindingID: 10, ScopeType: meta.LocalAuthScopeBiz, ScopeID: 2,
+					InScope: true},
+			},
+		},
+		{
+			name:     "matched role is not in scope",
+			policies: []policy{otherBizPolicy},
+			result:   meta.AuthExplainResult{Resource: host},
+			wantPolicies: []meta.LocalAuthExplainPolicy{
+				{RoleID: 1, RoleName: "host-maintainer", BindingID: 11, ScopeType: meta.LocalAuthScopeBiz, ScopeID: 3},
+			},
+		},
+		{
+			name:     "skipped result is not explained",
+			policies: []policy{otherBizPolicy},
+			result:   meta.AuthExplainResult{Resource: host, Skipped: true, Authorized: true, Reason: "skipped"},
+			wantAuth: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := []meta.AuthExplainResult{tt.result}
+			explainResults(tt.policies, results)
+			result := results[0]
+
+			if result.Authorized != tt.wantAuth {
+				t.Errorf("Authorized = %v, want %v", result.Authorized, tt.wantAuth)
+			}
+			if result.Reason == "" {
+				t.Errorf("Reason should not be empty")
+			}
+
+			if tt.result.Skipped {
+				if result.Reason != tt.result.Reason || result.ActionID != "" || result.Policy != nil {
+					t.Errorf("skipped result should not be changed, got: %+v", result)
+				}
+				return
+			}
+
+			if result.ActionID != string(meta.Update) || result.ResourceType != string(meta.HostInstance) {
+				t.Errorf("ActionID = %s, ResourceType = %s, want %s and %s", result.ActionID, result.ResourceType,
+					meta.Update, meta.HostInstance)
+			}
+
+			if tt.wantPolicies == nil {
+				if result.Policy != nil {
+					t.Errorf("Policy = %v, want nil", result.Policy)
+				}
+				return
+			}
+			if !reflect.DeepEqual(result.Policy, tt.wantPolicies) {
+				t.Errorf("Policy = %+v, want %+v", result.Policy, tt.wantPolicies)
+			}
+		})
+	}
+}
+
+func TestGenResourcePath(t *testing.T) {
+	tests := []struct {
+		name     string
+		resource meta.ResourceAttribute
+		want     string
+	}{
+		{
+			name:     "resource without topology",
+			resource: meta.ResourceAttribute{Basic: meta.Basic{Type: meta.Model, Action: meta.Update}},
+			want:     "/",
+		},
+		{
+			name: "business resource",
+			resource: meta.ResourceAttribute{
+				Basic:      meta.Basic{Type: meta.HostInstance, Action: meta.Update, InstanceID: 5},
+				BusinessID: 2,
+			},
+			want: "/business,2/hostInstance,5/",
+		},
+		{
+			name: "resource with layers",
+			resource: meta.ResourceAttribute{
+				Basic:      meta.Basic{Type: meta.ModelModule, Action: meta.Update, InstanceID: 7},
+				BusinessID: 2,
+				Layers:     []meta.Item{{Type: meta.ModelSet, InstanceID: 3}},
+			},
+			want: "/business,2/modelSet,3/modelModule,7/",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := genResourcePath(&tt.resource)
+			if len(got) != 1 || got[0] != tt.want {
+				t.Errorf("genResourcePath() = %v, want [%s]", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,239 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+
+	"configcenter/src/ac/iam"
+	"configcenter/src/ac/meta"
+	"configcenter/src/ac/parser"
+	"configcenter/src/common"
+	"configcenter/src/common/auth"
+	"configcenter/src/common/blog"
+	"configcenter/src/common/http/rest"
+	"configcenter/src/scene_server/auth_server/sdk/types"
+
+	"github.com/emicklei/go-restful/v3"
+)
+
+// ExplainAuthorize explains why the user is authorized or denied to operate the resources, the resources can be
+// parsed from an api request by the ac parser, so that we can find out which action and resource path is checked,
+// and which policy or missing policy causes the decision.
+func (s *AuthService) ExplainAuthorize(ctx *rest.Contexts) {
+	opt := new(meta.AuthExplainOption)
+	if err := ctx.DecodeInto(opt); err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	if err := opt.Validate(); err != nil {
+		blog.Errorf("auth explain option is invalid, err: %v, rid: %s", err, ctx.Kit.Rid)
+		ctx.RespAutoError(ctx.Kit.CCError.CCErrorf(common.CCErrCommParamsIsInvalid, err.Error()))
+		return
+	}
+
+	if opt.User.SupplierAccount == "" {
+		opt.User.SupplierAccount = ctx.Kit.SupplierAccount
+	}
+
+	results := make([]meta.AuthExplainResult, 0)
+	if opt.Request != nil {
+		resources, err := s.parseExplainRequest(ctx.Kit, opt.User, opt.Request)
+		if err != nil {
+			ctx.RespAutoError(err)
+			return
+		}
+
+		for _, resource := range resources {
+			results = append(results, meta.AuthExplainResult{Resource: resource, FromRequest: true})
+		}
+	}
+
+	for _, resource := range opt.Resources {
+		results = append(results, meta.AuthExplainResult{Resource: resource})
+	}
+
+	for idx := range results {
+		if !auth.EnableAuthorize() {
+			results[idx].Authorized = true
+			results[idx].Reason = "authorize is disabled"
+			continue
+		}
+
+		if results[idx].Resource.Action == meta.SkipAction {
+			results[idx].Skipped = true
+			results[idx].Authorized = true
+			results[idx].Reason = "resource with skip action does not need to be authorized"
+		}
+	}
+
+	if !auth.EnableAuthorize() {
+		ctx.RespEntity(results)
+		return
+	}
+
+	var err error
+	if iam.IsLocalAuthMode() {
+		err = s.localAuthorizer.Explain(ctx.Kit, opt.User, results)
+	} else {
+		err = s.explainWithIAM(ctx.Kit, opt.User, results)
+	}
+	if err != nil {
+		blog.Errorf("explain authorize failed, err: %v, opt: %+v, rid: %s", err, opt, ctx.Kit.Rid)
+		ctx.RespAutoError(err)
+		return
+	}
+
+	ctx.RespEntity(results)
+}
+
+// parseExplainRequest parse the resources of the api request with the ac parser as the request is sent by the user
+func (s *AuthService) parseExplainRequest(kit *rest.Kit, user meta.UserInfo, opt *meta.AuthExplainRequest) (
+	[]meta.ResourceAttribute, error) {
+
+	req, err := http.NewRequestWithContext(kit.Ctx, opt.Method, opt.URL, bytes.NewReader(opt.Body))
+	if err != nil {
+		blog.Errorf("generate explain request failed, err: %v, opt: %+v, rid: %s", err, opt, kit.Rid)
+		return nil, kit.CCError.CCErrorf(common.CCErrCommParamsIsInvalid, "request")
+	}
+
+	req.Header = kit.Header.Clone()
+	req.Header.Set(common.BKHTTPHeaderUser, user.UserName)
+	req.Header.Set(common.BKHTTPOwnerID, user.SupplierAccount)
+
+	attribute, err := parser.ParseAttribute(restful.NewRequest(req), s.engine)
+	if err != nil {
+		blog.Errorf("parse explain request attribute failed, err: %v, opt: %+v, rid: %s", err, opt, kit.Rid)
+		return nil, kit.CCError.CCErrorf(common.CCErrCommParamsIsInvalid, fmt.Sprintf("request, %v", err))
+	}
+
+	return attribute.Resources, nil
+}
+
+// explainWithIAM explain the authorize decisions with the iam policies of the user
+func (s *AuthService) explainWithIAM(kit *rest.Kit, user meta.UserInfo, results []meta.AuthExplainResult) error {
+	batch := make([]*types.AuthBatch, 0)
+	batchIndexes := make([]int, 0)
+	actionMap := make(map[string]types.Action)
+	for idx := range results {
+		result := &results[idx]
+		if result.Skipped {
+			continue
+		}
+
+		action, resources, err := iam.AdaptAuthOptions(&result.Resource)
+		if err != nil {
+			result.Reason = fmt.Sprintf("convert resource to iam action and resource failed, err: %v", err)
+			continue
+		}
+
+		if action == iam.Skip {
+			result.Skipped = true
+			result.Authorized = true
+			result.Reason = "the iam action of the resource is skipped, it does not need to be authorized"
+			continue
+		}
+
+		result.ActionID = string(action)
+		result.Resources = resources
+		if len(resources) > 0 {
+			result.ResourceType = string(resources[0].Type)
+			result.ResourcePath = getIamPath(resources[0])
+		}
+
+		iamAction := types.Action{ID: string(action)}
+		actionMap[iamAction.ID] = iamAction
+		batch = append(batch, &types.AuthBatch{Action: iamAction, Resources: resources})
+		batchIndexes = append(batchIndexes, idx)
+	}
+
+	if len(batch) == 0 {
+		return nil
+	}
+
+	subject := types.Subject{Type: "user", ID: user.UserName}
+	decisions, err := s.authorizer.AuthorizeBatch(kit.Ctx, &types.AuthBatchOptions{
+		System:  iam.SystemIDCMDB,
+		Subject: subject,
+		Batch:   batch,
+	})
+	if err != nil {
+		blog.Errorf("authorize batch failed, err: %v, user: %s, rid: %s", err, user.UserName, kit.Rid)
+		return err
+	}
+
+	actions := make([]types.Action, 0)
+	for _, action := range actionMap {
+		actions = append(actions, action)
+	}
+
+	policies, err := s.iamClient.ListUserPolicies(kit.Ctx, &types.ListPolicyOptions{
+		System:  iam.SystemIDCMDB,
+		Subject: subject,
+		Actions: actions,
+	})
+	if err != nil {
+		blog.Errorf("list user policies failed, err: %v, user: %s, rid: %s", err, user.UserName, kit.Rid)
+		return err
+	}
+
+	policyMap := make(map[string]*types.ActionPolicy)
+	for _, policy := range policies {
+		policyMap[policy.Action.ID] = policy
+	}
+
+	for batchIdx, idx := range batchIndexes {
+		result := &results[idx]
+		result.Authorized = decisions[batchIdx].Authorized
+
+		policy, exists := policyMap[result.ActionID]
+		if !exists || policy.Policy == nil || policy.Policy.Operator == "" {
+			result.Reason = fmt.Sprintf("user has no policy of action %s", result.ActionID)
+			continue
+		}
+
+		result.Policy = policy.Policy
+		if result.Authorized {
+			result.Reason = fmt.Sprintf("authorized by the policy of action %s", result.ActionID)
+			continue
+		}
+		result.Reason = fmt.Sprintf("the policy of action %s does not cover the resource path %v", result.ActionID,
+			result.ResourcePath)
+	}
+
+	return nil
+}
+
+// getIamPath get the iam topology path of the resource
+func getIamPath(resource types.Resource) []string {
+	path, exists := resource.Attribute[types.IamPathKey]
+	if !exists {
+		return make([]string, 0)
+	}
+
+	switch val := path.(type) {
+	case []string:
+		return val
+	case []interface{}:
+		paths := make([]string, 0)
+		for _, one := range val {
+			paths = append(paths, fmt.Sprintf("%v", one))
+		}
+		return paths
+	default:
+		return []string{fmt.Sprintf("%v", val)}
+	}
+}
@@ -0,0 +1,521 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"strconv"
+	"time"
+
+	"configcenter/src/ac/meta"
+	"configcenter/src/common"
+	"configcenter/src/common/blog"
+	"configcenter/src/common/http/rest"
+	"configcenter/src/common/mapstr"
+	"configcenter/src/common/metadata"
+	"configcenter/src/common/util"
+)
+
+// LocalAuthorizeBatch check if a user has the authority to operate resources with the local rbac authorizer
+func (s *AuthService) LocalAuthorizeBatch(ctx *rest.Contexts) {
+	s.localAuthorizeBatch(ctx, true)
+}
+
+// LocalAuthorizeAnyBatch check if a user has any authority for actions with the local rbac authorizer
+func (s *AuthService) LocalAuthorizeAnyBatch(ctx *rest.Contexts) {
+	s.localAuthorizeBatch(ctx, false)
+}
+
+func (s *AuthService) localAuthorizeBatch(ctx *rest.Contexts, exact bool) {
+	opts := new(meta.LocalAuthBatchOption)
+	if err := ctx.DecodeInto(opts); err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	decisions, err := s.localAuthorizer.AuthorizeBatch(ctx.Kit, opts, exact)
+	if err != nil {
+		blog.ErrorJSON("local authorize batch failed, err: %s, exact: %s, ops: %s, rid: %s", err, exact, opts,
+			ctx.Kit.Rid)
+		ctx.RespAutoError(err)
+		return
+	}
+	ctx.RespEntity(decisions)
+}
+
+// LocalListAuthorizedResources returns the resources the user has the authority to operate with the local authorizer
+func (s *AuthService) LocalListAuthorizedResources(ctx *rest.Contexts) {
+	input := new(meta.ListAuthorizedResourcesParam)
+	if err := ctx.DecodeInto(input); err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	list, err := s.localAuthorizer.ListAuthorizedResources(ctx.Kit, input)
+	if err != nil {
+		blog.ErrorJSON("local list authorized resources failed, err: %s, input: %s, rid: %s", err, input,
+			ctx.Kit.Rid)
+		ctx.RespAutoError(err)
+		return
+	}
+	ctx.RespEntity(list)
+}
+
+// CreateLocalRole create a role of the local rbac authorizer
+func (s *AuthService) CreateLocalRole(ctx *rest.Contexts) {
+	role := new(meta.LocalAuthRole)
+	if err := ctx.DecodeInto(role); err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	if err := role.Validate(); err != nil {
+		blog.Errorf("local role is invalid, err: %v, rid: %s", err, ctx.Kit.Rid)
+		ctx.RespAutoError(ctx.Kit.CCError.CCErrorf(common.CCErrCommParamsIsInvalid, err.Error()))
+		return
+	}
+
+	id, err := s.db.NextSequence(ctx.Kit.Ctx, common.BKTableNameLocalAuthRole)
+	if err != nil {
+		blog.Errorf("generate local role id failed, err: %v, rid: %s", err, ctx.Kit.Rid)
+		ctx.RespAutoError(ctx.Kit.CCError.CCError(common.CCErrCommDBInsertFailed))
+		return
+	}
+
+	now := time.Now()
+	role.ID = int64(id)
+	role.SupplierAccount = ctx.Kit.SupplierAccount
+	role.Creator = ctx.Kit.User
+	role.Modifier = ctx.Kit.User
+	role.CreateTime = now
+	role.LastTime = now
+
+	if err := s.db.Table(common.BKTableNameLocalAuthRole).Insert(ctx.Kit.Ctx, role); err != nil {
+		blog.Errorf("create local role %+v failed, err: %v, rid: %s", role, err, ctx.Kit.Rid)
+		if s.db.IsDuplicatedError(err) {
+			ctx.RespAutoError(ctx.Kit.CCError.CCErrorf(common.CCErrCommDuplicateItem, common.BKFieldName))
+			return
+		}
+		ctx.RespAutoError(ctx.Kit.CCError.CCError(common.CCErrCommDBInsertFailed))
+		return
+	}
+
+	ctx.RespEntity(metadata.RspID{ID: role.ID})
+}
+
+// UpdateLocalRole update the name, description and permissions of a local role
+func (s *AuthService) UpdateLocalRole(ctx *rest.Contexts) {
+	id, err := s.parseLocalAuthID(ctx)
+	if err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	role := new(meta.LocalAuthRole)
+	if err := ctx.DecodeInto(role); err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	if err := role.Validate(); err != nil {
+		blog.Errorf("local role is invalid, err: %v, rid: %s", err, ctx.Kit.Rid)
+		ctx.RespAutoError(ctx.Kit.CCError.CCErrorf(common.CCErrCommParamsIsInvalid, err.Error()))
+		return
+	}
+
+	update := mapstr.MapStr{
+		common.BKFieldName:   role.Name,
+		"description":        role.Description,
+		"permissions":        role.Permissions,
+		common.ModifierField: ctx.Kit.User,
+		common.LastTimeField: time.Now(),
+	}
+	if err := s.updateLocalAuthData(ctx.Kit, common.BKTableNameLocalAuthRole, id, update); err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	ctx.RespEntity(nil)
+}
+
+// DeleteLocalRole delete a local role and its bindings
+func (s *AuthService) DeleteLocalRole(ctx *rest.Contexts) {
+	id, err := s.parseLocalAuthID(ctx)
+	if err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	if err := s.deleteLocalAuthData(ctx.Kit, common.BKTableNameLocalAuthRole, id); err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	bindingFilter := util.SetModOwner(mapstr.MapStr{"role_id": id}, ctx.Kit.SupplierAccount)
+	err = s.db.Table(common.BKTableNameLocalAuthRoleBinding).Delete(ctx.Kit.Ctx, bindingFilter)
+	if err != nil {
+		blog.Errorf("delete local role %d bindings failed, err: %v, rid: %s", id, err, ctx.Kit.Rid)
+		ctx.RespAutoError(ctx.Kit.CCError.CCError(common.CCErrCommDBDeleteFailed))
+		return
+	}
+
+	ctx.RespEntity(nil)
+}
+
+// ListLocalRole list the local roles
+func (s *AuthService) ListLocalRole(ctx *rest.Contexts) {
+	opt, filter, err := s.decodeLocalAuthListOption(ctx)
+	if err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	result := new(meta.LocalAuthRoleListResult)
+	result.Info = make([]meta.LocalAuthRole, 0)
+	result.Count, err = s.listLocalAuthData(ctx.Kit, common.BKTableNameLocalAuthRole, filter, opt.Page, &result.Info)
+	if err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	ctx.RespEntity(result)
+}
+
+// CreateLocalGroup create a user group of the local rbac authorizer
+func (s *AuthService) CreateLocalGroup(ctx *rest.Contexts) {
+	group := new(meta.LocalAuthGroup)
+	if err := ctx.DecodeInto(group); err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	if err := group.Validate(); err != nil {
+		blog.Errorf("local group is invalid, err: %v, rid: %s", err, ctx.Kit.Rid)
+		ctx.RespAutoError(ctx.Kit.CCError.CCErrorf(common.CCErrCommParamsIsInvalid, err.Error()))
+		return
+	}
+
+	id, err := s.db.NextSequence(ctx.Kit.Ctx, common.BKTableNameLocalAuthGroup)
+	if err != nil {
+		blog.Errorf("generate local group id failed, err: %v, rid: %s", err, ctx.Kit.Rid)
+		ctx.RespAutoError(ctx.Kit.CCError.CCError(common.CCErrCommDBInsertFailed))
+		return
+	}
+
+	now := time.Now()
+	group.ID = int64(id)
+	group.Members = util.StrArrayUnique(group.Members)
+	group.SupplierAccount = ctx.Kit.SupplierAccount
+	group.Creator = ctx.Kit.User
+	group.Modifier = ctx.Kit.User
+	group.CreateTime = now
+	group.LastTime = now
+
+	if err := s.db.Table(common.BKTableNameLocalAuthGroup).Insert(ctx.Kit.Ctx, group); err != nil {
+		blog.Errorf("create local group %+v failed, err: %v, rid: %s", group, err, ctx.Kit.Rid)
+		if s.db.IsDuplicatedError(err) {
+			ctx.RespAutoError(ctx.Kit.CCError.CCErrorf(common.CCErrCommDuplicateItem, common.BKFieldName))
+			return
+		}
+		ctx.RespAutoError(ctx.Kit.CCError.CCError(common.CCErrCommDBInsertFailed))
+		return
+	}
+
+	ctx.RespEntity(metadata.RspID{ID: group.ID})
+}
+
+// UpdateLocalGroup update the name, description and members of a local user group
+func (s *AuthService) UpdateLocalGroup(ctx *rest.Contexts) {
+	id, err := s.parseLocalAuthID(ctx)
+	if err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	group := new(meta.LocalAuthGroup)
+	if err := ctx.DecodeInto(group); err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	if err := group.Validate(); err != nil {
+		blog.Errorf("local group is invalid, err: %v, rid: %s", err, ctx.Kit.Rid)
+		ctx.RespAutoError(ctx.Kit.CCError.CCErrorf(common.CCErrCommParamsIsInvalid, err.Error()))
+		return
+	}
+
+	members := util.StrArrayUnique(group.Members)
+	if members == nil {
+		members = make([]string, 0)
+	}
+
+	update := mapstr.MapStr{
+		common.BKFieldName:   group.Name,
+		"description":        group.Description,
+		"members":            members,
+		common.ModifierField: ctx.Kit.User,
+		common.LastTimeField: time.Now(),
+	}
+	if err := s.updateLocalAuthData(ctx.Kit, common.BKTableNameLocalAuthGroup, id, update); err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	ctx.RespEntity(nil)
+}
+
+// DeleteLocalGroup delete a local user group and the role bindings of the group
+func (s *AuthService) DeleteLocalGroup(ctx *rest.Contexts) {
+	id, err := s.parseLocalAuthID(ctx)
+	if err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	if err := s.deleteLocalAuthData(ctx.Kit, common.BKTableNameLocalAuthGroup, id); err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	bindingFilter := util.SetModOwner(mapstr.MapStr{
+		"subject_type": meta.LocalAuthSubjectGroup,
+		"subject":      strconv.FormatInt(id, 10),
+	}, ctx.Kit.SupplierAccount)
+	err = s.db.Table(common.BKTableNameLocalAuthRoleBinding).Delete(ctx.Kit.Ctx, bindingFilter)
+	if err != nil {
+		blog.Errorf("delete local group %d bindings failed, err: %v, rid: %s", id, err, ctx.Kit.Rid)
+		ctx.RespAutoError(ctx.Kit.CCError.CCError(common.CCErrCommDBDeleteFailed))
+		return
+	}
+
+	ctx.RespEntity(nil)
+}
+
+// ListLocalGroup list the local user groups
+func (s *AuthService) ListLocalGroup(ctx *rest.Contexts) {
+	opt, filter, err := s.decodeLocalAuthListOption(ctx)
+	if err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	result := new(meta.LocalAuthGroupListResult)
+	result.Info = make([]meta.LocalAuthGroup, 0)
+	result.Count, err = s.listLocalAuthData(ctx.Kit, common.BKTableNameLocalAuthGroup, filter, opt.Page,
+		&result.Info)
+	if err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	ctx.RespEntity(result)
+}
+
+// CreateLocalRoleBinding bind a local role to a user or a user group in the scope
+func (s *AuthService) CreateLocalRoleBinding(ctx *rest.Contexts) {
+	binding := new(meta.LocalAuthRoleBinding)
+	if err := ctx.DecodeInto(binding); err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	if err := binding.Validate(); err != nil {
+		blog.Errorf("local role binding is invalid, err: %v, rid: %s", err, ctx.Kit.Rid)
+		ctx.RespAutoError(ctx.Kit.CCError.CCErrorf(common.CCErrCommParamsIsInvalid, err.Error()))
+		return
+	}
+
+	if err := s.checkLocalAuthDataExists(ctx.Kit, common.BKTableNameLocalAuthRole, binding.RoleID,
+		"role_id"); err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	if binding.SubjectType == meta.LocalAuthSubjectGroup {
+		groupID, err := strconv.ParseInt(binding.Subject, 10, 64)
+		if err != nil {
+			blog.Errorf("local role binding group id %s is invalid, err: %v, rid: %s", binding.Subject, err,
+				ctx.Kit.Rid)
+			ctx.RespAutoError(ctx.Kit.CCError.CCErrorf(common.CCErrCommParamsIsInvalid, "subject"))
+			return
+		}
+
+		if err := s.checkLocalAuthDataExists(ctx.Kit, common.BKTableNameLocalAuthGroup, groupID,
+			"subject"); err != nil {
+			ctx.RespAutoError(err)
+			return
+		}
+	}
+
+	id, err := s.db.NextSequence(ctx.Kit.Ctx, common.BKTableNameLocalAuthRoleBinding)
+	if err != nil {
+		blog.Errorf("generate local role binding id failed, err: %v, rid: %s", err, ctx.Kit.Rid)
+		ctx.RespAutoError(ctx.Kit.CCError.CCError(common.CCErrCommDBInsertFailed))
+		return
+	}
+
+	binding.ID = int64(id)
+	binding.SupplierAccount = ctx.Kit.SupplierAccount
+	binding.Creator = ctx.Kit.User
+	binding.CreateTime = time.Now()
+
+	if err := s.db.Table(common.BKTableNameLocalAuthRoleBinding).Insert(ctx.Kit.Ctx, binding); err != nil {
+		blog.Errorf("create local role binding %+v failed, err: %v, rid: %s", binding, err, ctx.Kit.Rid)
+		ctx.RespAutoError(ctx.Kit.CCError.CCError(common.CCErrCommDBInsertFailed))
+		return
+	}
+
+	ctx.RespEntity(metadata.RspID{ID: binding.ID})
+}
+
+// DeleteLocalRoleBinding delete a local role binding
+func (s *AuthService) DeleteLocalRoleBinding(ctx *rest.Contexts) {
+	id, err := s.parseLocalAuthID(ctx)
+	if err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	if err := s.deleteLocalAuthData(ctx.Kit, common.BKTableNameLocalAuthRoleBinding, id); err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	ctx.RespEntity(nil)
+}
+
+// ListLocalRoleBinding list the local role bindings
+func (s *AuthService) ListLocalRoleBinding(ctx *rest.Contexts) {
+	opt, filter, err := s.decodeLocalAuthListOption(ctx)
+	if err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	if opt.RoleID > 0 {
+		filter["role_id"] = opt.RoleID
+	}
+	if opt.Subject != "" {
+		filter["subject"] = opt.Subject
+	}
+
+	result := new(meta.LocalAuthRoleBindingListResult)
+	result.Info = make([]meta.LocalAuthRoleBinding, 0)
+	result.Count, err = s.listLocalAuthData(ctx.Kit, common.BKTableNameLocalAuthRoleBinding, filter, opt.Page,
+		&result.Info)
+	if err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	ctx.RespEntity(result)
+}
+
+func (s *AuthService) parseLocalAuthID(ctx *rest.Contexts) (int64, error) {
+	id, err := strconv.ParseInt(ctx.Request.PathParameter(common.BKFieldID), 10, 64)
+	if err != nil || id <= 0 {
+		blog.Errorf("local auth data id %s is invalid, err: %v, rid: %s", ctx.Request.PathParameter(common.BKFieldID),
+			err, ctx.Kit.Rid)
+		return 0, ctx.Kit.CCError.CCErrorf(common.CCErrCommParamsIsInvalid, common.BKFieldID)
+	}
+	return id, nil
+}
+
+func (s *AuthService) checkLocalAuthDataExists(kit *rest.Kit, table string, id int64, field string) error {
+	filter := util.SetModOwner(mapstr.MapStr{common.BKFieldID: id}, kit.SupplierAccount)
+	cnt, err := s.db.Table(table).Find(filter).Count(kit.Ctx)
+	if err != nil {
+		blog.Errorf("count %s data %d failed, err: %v, rid: %s", table, id, err, kit.Rid)
+		return kit.CCError.CCError(common.CCErrCommDBSelectFailed)
+	}
+
+	if cnt == 0 {
+		blog.Errorf("%s data %d is not exist, rid: %s", table, id, kit.Rid)
+		return kit.CCError.CCErrorf(common.CCErrCommParamsIsInvalid, field)
+	}
+	return nil
+}
+
+func (s *AuthService) updateLocalAuthData(kit *rest.Kit, table string, id int64, update mapstr.MapStr) error {
+	if err := s.checkLocalAuthDataExists(kit, table, id, common.BKFieldID); err != nil {
+		return err
+	}
+
+	filter := util.SetModOwner(mapstr.MapStr{common.BKFieldID: id}, kit.SupplierAccount)
+	if err := s.db.Table(table).Update(kit.Ctx, filter, update); err != nil {
+		blog.Errorf("update %s data %d failed, data: %+v, err: %v, rid: %s", table, id, update, err, kit.Rid)
+		if s.db.IsDuplicatedError(err) {
+			return kit.CCError.CCErrorf(common.CCErrCommDuplicateItem, common.BKFieldName)
+		}
+		return kit.CCError.CCError(common.CCErrCommDBUpdateFailed)
+	}
+	return nil
+}
+
+func (s *AuthService) deleteLocalAuthData(kit *rest.Kit, table string, id int64) error {
+	if err := s.checkLocalAuthDataExists(kit, table, id, common.BKFieldID); err != nil {
+		return err
+	}
+
+	filter := util.SetModOwner(mapstr.MapStr{common.BKFieldID: id}, kit.SupplierAccount)
+	if err := s.db.Table(table).Delete(kit.Ctx, filter); err != nil {
+		blog.Errorf("delete %s data %d failed, err: %v, rid: %s", table, id, err, kit.Rid)
+		return kit.CCError.CCError(common.CCErrCommDBDeleteFailed)
+	}
+	return nil
+}
+
+func (s *AuthService) decodeLocalAuthListOption(ctx *rest.Contexts) (*meta.LocalAuthListOption, mapstr.MapStr,
+	error) {
+
+	opt := new(meta.LocalAuthListOption)
+	if err := ctx.DecodeInto(opt); err != nil {
+		return nil, nil, err
+	}
+
+	if rawErr := opt.Page.ValidateWithEnableCount(false); rawErr.ErrCode != 0 {
+		return nil, nil, rawErr.ToCCError(ctx.Kit.CCError)
+	}
+
+	filter := util.SetModOwner(mapstr.MapStr{}, ctx.Kit.SupplierAccount)
+	if len(opt.IDs) > 0 {
+		filter[common.BKFieldID] = mapstr.MapStr{common.BKDBIN: opt.IDs}
+	}
+	return opt, filter, nil
+}
+
+// listLocalAuthData list the local auth data into result, returns the count if page.EnableCount is set
+func (s *AuthService) listLocalAuthData(kit *rest.Kit, table string, filter mapstr.MapStr,
+	page metadata.BasePage, result interface{}) (uint64, error) {
+
+	if page.EnableCount {
+		count, err := s.db.Table(table).Find(filter).Count(kit.Ctx)
+		if err != nil {
+			blog.Errorf("count %s data failed, filter: %+v, err: %v, rid: %s", table, filter, err, kit.Rid)
+			return 0, kit.CCError.CCError(common.CCErrCommDBSelectFailed)
+		}
+		return count, nil
+	}
+
+	sort := page.Sort
+	if len(sort) == 0 {
+		sort = common.BKFieldID
+	}
+
+	err := s.db.Table(table).Find(filter).Sort(sort).Start(uint64(page.Start)).Limit(uint64(page.Limit)).
+		All(kit.Ctx, result)
+	if err != nil {
+		blog.Errorf("list %s data failed, filter: %+v, err: %v, rid: %s", table, filter, err, kit.Rid)
+		return 0, kit.CCError.CCError(common.CCErrCommDBSelectFailed)
+	}
+	return 0, nil
+}
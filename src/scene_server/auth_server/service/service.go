@@ -28,10 +28,12 @@ import (
 	"configcenter/src/common/metadata"
 	"configcenter/src/common/util"
 	"configcenter/src/common/webservice/restfulservice"
+	"configcenter/src/scene_server/auth_server/local"
 	"configcenter/src/scene_server/auth_server/logics"
 	sdkauth "configcenter/src/scene_server/auth_server/sdk/auth"
 	"configcenter/src/scene_server/auth_server/sdk/client"
 	"configcenter/src/scene_server/auth_server/types"
+	"configcenter/src/storage/dal"
 	"configcenter/src/thirdparty/logplatform/opentelemetry"
 
 	"github.com/emicklei/go-restful/v3"
@@ -43,6 +45,10 @@ type AuthService struct {
 	iamClient  client.Interface
 	lgc        *logics.Logics
 	authorizer sdkauth.Authorizer
+
+	// db and localAuthorizer are only used in local auth mode
+	db              dal.RDB
+	localAuthorizer *local.Authorizer
 }
 
 // NewAuthService TODO
@@ -55,6 +61,15 @@ func NewAuthService(engine *backbone.Engine, iamClient client.Interface, lgc *lo
 	}
 }
 
+// NewLocalAuthService new auth service that authorizes with the local rbac authorizer instead of iam
+func NewLocalAuthService(engine *backbone.Engine, db dal.RDB) *AuthService {
+	return &AuthService{
+		engine:          engine,
+		db:              db,
+		localAuthorizer: local.NewAuthorizer(db),
+	}
+}
+
 func (s *AuthService) checkRequestFromIamFilter() func(req *restful.Request, resp *restful.Response, chain *restful.FilterChain) {
 	return func(req *restful.Request, resp *restful.Response, chain *restful.FilterChain) {
 		if !auth.EnableAuthorize() {
@@ -153,6 +168,10 @@ func setSupplierID(req *http.Request) {
 
 // WebService TODO
 func (s *AuthService) WebService() *restful.Container {
+	if iam.IsLocalAuthMode() {
+		return s.localWebService()
+	}
+
 	api := new(restful.WebService)
 	api.Path("/auth/v3")
 	api.Filter(s.engine.Metric().RestfulMiddleWare)
@@ -206,6 +225,65 @@ func (s *AuthService) initAuth(api *restful.WebService) {
 	utility.AddHandler(rest.Action{Verb: http.MethodPost, Path: "/find/permission_to_apply", Handler: s.GetPermissionToApply})
 	utility.AddHandler(rest.Action{Verb: http.MethodPost, Path: "/register/resource_creator_action", Handler: s.RegisterResourceCreatorAction})
 	utility.AddHandler(rest.Action{Verb: http.MethodPost, Path: "/register/batch_resource_creator_action", Handler: s.BatchRegisterResourceCreatorAction})
+	utility.AddHandler(rest.Action{Verb: http.MethodPost, Path: "/find/auth/explain", Handler: s.ExplainAuthorize})
+
+	utility.AddToRestfulWebService(api)
+}
+
+// localWebService is the web service in local auth mode, iam resource pull apis are not provided in this mode
+func (s *AuthService) localWebService() *restful.Container {
+	container := restful.NewContainer()
+
+	opentelemetry.AddOtlpFilter(container)
+
+	authAPI := new(restful.WebService).Produces(restful.MIME_JSON)
+	authAPI.Path("/ac/v3")
+	authAPI.Filter(s.engine.Metric().RestfulMiddleWare)
+	s.initLocalAuth(authAPI)
+	container.Add(authAPI)
+
+	// common api
+	commonAPI := new(restful.WebService).Produces(restful.MIME_JSON)
+	commonAPI.Route(commonAPI.GET("/healthz").To(s.Healthz))
+	commonAPI.Route(commonAPI.GET("/version").To(restfulservice.Version))
+	container.Add(commonAPI)
+
+	return container
+}
+
+func (s *AuthService) initLocalAuth(api *restful.WebService) {
+	utility := rest.NewRestUtility(rest.Config{
+		ErrorIf:  s.engine.CCErr,
+		Language: s.engine.Language,
+	})
+
+	utility.AddHandler(rest.Action{Verb: http.MethodPost, Path: "/local/authorize/batch",
+		Handler: s.LocalAuthorizeBatch})
+	utility.AddHandler(rest.Action{Verb: http.MethodPost, Path: "/local/authorize/any/batch",
+		Handler: s.LocalAuthorizeAnyBatch})
+	utility.AddHandler(rest.Action{Verb: http.MethodPost, Path: "/local/findmany/authorized_resource",
+		Handler: s.LocalListAuthorizedResources})
+	utility.AddHandler(rest.Action{Verb: http.MethodPost, Path: "/find/auth/explain", Handler: s.ExplainAuthorize})
+
+	utility.AddHandler(rest.Action{Verb: http.MethodPost, Path: "/create/auth/role", Handler: s.CreateLocalRole})
+	utility.AddHandler(rest.Action{Verb: http.MethodPut, Path: "/update/auth/role/{id}", Handler: s.UpdateLocalRole})
+	utility.AddHandler(rest.Action{Verb: http.MethodDelete, Path: "/delete/auth/role/{id}",
+		Handler: s.DeleteLocalRole})
+	utility.AddHandler(rest.Action{Verb: http.MethodPost, Path: "/findmany/auth/role", Handler: s.ListLocalRole})
+
+	utility.AddHandler(rest.Action{Verb: http.MethodPost, Path: "/create/auth/group", Handler: s.CreateLocalGroup})
+	utility.AddHandler(rest.Action{Verb: http.MethodPut, Path: "/update/auth/group/{id}",
+		Handler: s.UpdateLocalGroup})
+	utility.AddHandler(rest.Action{Verb: http.MethodDelete, Path: "/delete/auth/group/{id}",
+		Handler: s.DeleteLocalGroup})
+	utility.AddHandler(rest.Action{Verb: http.MethodPost, Path: "/findmany/auth/group", Handler: s.ListLocalGroup})
+
+	utility.AddHandler(rest.Action{Verb: http.MethodPost, Path: "/create/auth/role_binding",
+		Handler: s.CreateLocalRoleBinding})
+	utility.AddHandler(rest.Action{Verb: http.MethodDelete, Path: "/delete/auth/role_binding/{id}",
+		Handler: s.DeleteLocalRoleBinding})
+	utility.AddHandler(rest.Action{Verb: http.MethodPost, Path: "/findmany/auth/role_binding",
+		Handler: s.ListLocalRoleBinding})
 
 	utility.AddToRestfulWebService(api)
 }
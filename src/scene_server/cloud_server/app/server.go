@@ -18,7 +18,7 @@ import (
 	"fmt"
 	"time"
 
-	"configcenter/src/ac/iam"
+	"configcenter/src/ac"
 	"configcenter/src/common"
 	"configcenter/src/common/auth"
 	"configcenter/src/common/backbone"
@@ -29,6 +29,7 @@ import (
 	"configcenter/src/common/util"
 	"configcenter/src/scene_server/cloud_server/app/options"
 	"configcenter/src/scene_server/cloud_server/cloudsync"
+	"configcenter/src/scene_server/cloud_server/cloudvendor"
 	"configcenter/src/scene_server/cloud_server/logics"
 	svc "configcenter/src/scene_server/cloud_server/service"
 	"configcenter/src/thirdparty/secrets"
@@ -94,7 +95,7 @@ func Run(ctx context.Context, cancel context.CancelFunc, op *options.ServerOptio
 
 	process.Service.SetEncryptor(accountCryptor)
 
-	authorizer := iam.NewAuthorizer(engine.CoreAPI)
+	authorizer := ac.NewAuthorizer(engine.CoreAPI)
 	service.SetAuthorizer(authorizer)
 
 	mongoConf := mongoConfig.GetMongoConf()
@@ -103,10 +104,10 @@ func Run(ctx context.Context, cancel context.CancelFunc, op *options.ServerOptio
 
 	process.setSyncPeriod()
 	syncConf := cloudsync.SyncConf{
-		ZKClient:  service.Engine.ServiceManageClient().Client(),
-		Logics:    process.Service.Logics,
-		AddrPort:  input.SrvInfo.Instance(),
-		MongoConf: mongoConf,
+		RegDiscvClient: service.Engine.ServiceManageClient(),
+		Logics:         process.Service.Logics,
+		AddrPort:       input.SrvInfo.Instance(),
+		MongoConf:      mongoConf,
 	}
 	err = cloudsync.CloudSync(&syncConf)
 	if err != nil {
@@ -144,6 +145,15 @@ func (c *CloudServer) onCloudConfigUpdate(previous, current cc.ProcessConfig) {
 	c.Config.SecretsProject, _ = cc.String("cloudServer.cryptor.secretsProject")
 	c.Config.SecretsEnv, _ = cc.String("cloudServer.cryptor.secretsEnv")
 	c.Config.SyncPeriodMinutes, _ = cc.Int("cloudServer.syncTask.syncPeriodMinutes")
+
+	authURL, _ := cc.String("cloudServer.openstack.authUrl")
+	endpointInterface, _ := cc.String("cloudServer.openstack.interface")
+	timeoutSeconds, _ := cc.Int("cloudServer.openstack.timeoutSeconds")
+	cloudvendor.SetOpenStackConfig(cloudvendor.OpenStackConfig{
+		AuthURL:   authURL,
+		Interface: endpointInterface,
+		Timeout:   time.Duration(timeoutSeconds) * time.Second,
+	})
 }
 
 // getSecretKey get the secret key from bk-secrets service
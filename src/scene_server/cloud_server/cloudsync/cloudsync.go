@@ -16,10 +16,10 @@ package cloudsync
 import (
 	"context"
 
+	"configcenter/src/common/backbone/service_mange/regdiscv"
 	"configcenter/src/common/blog"
 	"configcenter/src/common/errors"
 	"configcenter/src/common/metadata"
-	"configcenter/src/common/zkclient"
 	"configcenter/src/scene_server/cloud_server/logics"
 	"configcenter/src/storage/dal/mongo/local"
 )
@@ -31,10 +31,10 @@ const (
 
 // SyncConf TODO
 type SyncConf struct {
-	ZKClient  *zkclient.ZkClient
-	Logics    *logics.Logics
-	AddrPort  string
-	MongoConf local.MongoConf
+	RegDiscvClient *regdiscv.Client
+	Logics         *logics.Logics
+	AddrPort       string
+	MongoConf      local.MongoConf
 }
 
 // CloudSyncInterface 云同步接口
@@ -48,10 +48,10 @@ func CloudSync(conf *SyncConf) error {
 	ctx := context.Background()
 
 	schedulerConf := &SchedulerConf{
-		ZKClient:  conf.ZKClient,
-		Logics:    conf.Logics,
-		AddrPort:  conf.AddrPort,
-		MongoConf: conf.MongoConf,
+		RegDiscvClient: conf.RegDiscvClient,
+		Logics:         conf.Logics,
+		AddrPort:       conf.AddrPort,
+		MongoConf:      conf.MongoConf,
 	}
 	scheduler, err := NewTaskScheduler(schedulerConf)
 	if err != nil {
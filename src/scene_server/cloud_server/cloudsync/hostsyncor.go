@@ -301,6 +301,14 @@ func (h *HostSyncor) addCLoudId(accountConf *metadata.CloudAccountConf, hostReso
 
 // getDiffHosts 根据主机实例id获取mongo中的主机信息,并获取有差异的主机
 func (h *HostSyncor) getDiffHosts(hostResource *metadata.CloudHostResource) (map[string][]*metadata.CloudHost, error) {
+	diffHosts, _, err := h.compareHosts(hostResource)
+	return diffHosts, err
+}
+
+// compareHosts 比较云端和本地的主机，返回有差异的主机和以实例id为key的本地主机
+func (h *HostSyncor) compareHosts(hostResource *metadata.CloudHostResource) (map[string][]*metadata.CloudHost,
+	map[string]*metadata.CloudHost, error) {
+
 	// 云端的主机
 	remoteHostsMap := make(map[string]*metadata.CloudHost)
 	for _, hostRes := range hostResource.HostResource {
@@ -323,7 +331,7 @@ func (h *HostSyncor) getDiffHosts(hostResource *metadata.CloudHostResource) (map
 	// 本地已有的云主机
 	localHosts, err := h.getLocalHosts(cloudIDs)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	blog.V(4).Infof("taskid:%d, len(localHosts):%d, rid:%s", hostResource.TaskID, len(localHosts), h.readKit.Rid)
 	localIdHostsMap := make(map[string]*metadata.CloudHost)
@@ -362,7 +370,7 @@ func (h *HostSyncor) getDiffHosts(hostResource *metadata.CloudHostResource) (map
 		}
 	}
 
-	return diffHosts, nil
+	return diffHosts, localIdHostsMap, nil
 }
 
 // syncDiffHosts 同步有差异的主机数据
@@ -786,6 +794,15 @@ func (h *HostSyncor) updateHost(cloudInstID string, updateInfo map[string]interf
 	return nil
 }
 
+// destroyedHostData 云端已销毁的主机同步时更新的数据，内外网ip置空，状态置为已销毁
+func destroyedHostData() mapstr.MapStr {
+	return mapstr.MapStr{
+		common.BKHostInnerIPField:     []string{},
+		common.BKHostOuterIPField:     []string{},
+		common.BKCloudHostStatusField: common.BKCloudHostStatusDestroyed,
+	}
+}
+
 // deleteDestroyedHosts 删除被销毁云主机相关联的数据
 func (h *HostSyncor) deleteDestroyedHosts(hostIDs []int64) (*metadata.SyncResult, error) {
 	result := new(metadata.SyncResult)
@@ -804,11 +821,7 @@ func (h *HostSyncor) deleteDestroyedHosts(hostIDs []int64) (*metadata.SyncResult
 		return nil, err
 	}
 
-	updateHostData := mapstr.MapStr{
-		common.BKHostInnerIPField:     []string{},
-		common.BKHostOuterIPField:     []string{},
-		common.BKCloudHostStatusField: common.BKCloudHostStatusDestroyed,
-	}
+	updateHostData := destroyedHostData()
 
 	// generate audit log.
 	innerIPs := make([]string, 0)
@@ -0,0 +1,266 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloudsync
+
+import (
+	"fmt"
+	"sort"
+
+	"configcenter/src/common"
+	"configcenter/src/common/blog"
+	"configcenter/src/common/http/rest"
+	"configcenter/src/common/mapstr"
+	"configcenter/src/common/metadata"
+)
+
+// Preview 预览云同步任务，使用和同步相同的方式获取云端资源并与本地数据比较，返回同步时将会产生的变更，不写入任何数据
+func (h *HostSyncor) Preview(kit *rest.Kit, opt *metadata.PreviewSyncTaskOption) (*metadata.SyncTaskPreview, error) {
+	h.readKit = kit
+
+	accountConf, err := h.logics.GetCloudAccountConf(kit, opt.AccountID)
+	if err != nil {
+		blog.Errorf("get cloud account conf failed, accountID: %d, err: %v, rid: %s", opt.AccountID, err, kit.Rid)
+		return nil, err
+	}
+
+	task := &metadata.CloudSyncTask{AccountID: opt.AccountID, SyncVpcs: opt.SyncVpcs}
+	hostResource, err := h.getCloudHostResource(task, accountConf)
+	if err != nil {
+		blog.Errorf("get cloud host resource failed, accountID: %d, err: %v, rid: %s", opt.AccountID, err, kit.Rid)
+		return nil, err
+	}
+
+	preview := &metadata.SyncTaskPreview{
+		AddHosts:            make([]metadata.CloudHostPreview, 0),
+		UpdateHosts:         make([]metadata.CloudHostPreview, 0),
+		CreateCloudAreas:    make([]metadata.CloudAreaPreview, 0),
+		DestroyedCloudAreas: make([]metadata.CloudAreaPreview, 0),
+	}
+
+	if err := h.previewDestroyedVpcs(hostResource, preview); err != nil {
+		return nil, err
+	}
+
+	if err := h.previewHostResource(hostResource, preview); err != nil {
+		return nil, err
+	}
+
+	for _, hosts := range [][]metadata.CloudHostPreview{preview.AddHosts, preview.UpdateHosts} {
+		sort.Slice(hosts, func(i, j int) bool {
+			return hosts[i].InstanceID < hosts[j].InstanceID
+		})
+	}
+
+	return preview, nil
+}
+
+// previewDestroyedVpcs 预览被销毁的vpc，对应的云区域将被置为异常，其下的所有主机和同步时一样作为更新的主机被置为已销毁
+func (h *HostSyncor) previewDestroyedVpcs(hostResource *metadata.CloudHostResource,
+	preview *metadata.SyncTaskPreview) error {
+
+	if len(hostResource.DestroyedVpcs) == 0 {
+		return nil
+	}
+
+	vpcIDs := make([]string, 0)
+	cloudIDs := make([]int64, 0)
+	for _, vpc := range hostResource.DestroyedVpcs {
+		vpcIDs = append(vpcIDs, vpc.VpcID)
+		cloudIDs = append(cloudIDs, vpc.CloudID)
+	}
+
+	cloudAreas, err := h.getVpcCloudAreas(vpcIDs)
+	if err != nil {
+		return err
+	}
+
+	for _, vpc := range hostResource.DestroyedVpcs {
+		preview.DestroyedCloudAreas = append(preview.DestroyedCloudAreas, metadata.CloudAreaPreview{
+			CloudID:   vpc.CloudID,
+			CloudName: cloudAreas[vpc.VpcID].CloudName,
+			VpcID:     vpc.VpcID,
+			VpcName:   vpc.VpcName,
+			Region:    vpc.Region,
+		})
+	}
+
+	query := &metadata.QueryCondition{
+		Condition: mapstr.MapStr{common.BKCloudIDField: mapstr.MapStr{common.BKDBIN: cloudIDs}},
+		Page:      metadata.BasePage{Limit: common.BKNoLimit},
+	}
+	res, err := h.logics.CoreAPI.CoreService().Instance().ReadInstance(h.readKit.Ctx, h.readKit.Header,
+		common.BKInnerObjIDHost, query)
+	if err != nil {
+		blog.Errorf("read hosts in destroyed vpcs failed, query: %#v, err: %v, rid: %s", query, err, h.readKit.Rid)
+		return err
+	}
+
+	for _, host := range res.Info {
+		localHost := new(metadata.CloudHost)
+		localHost.InstanceId, _ = host.String(common.BKCloudInstIDField)
+		localHost.PrivateIp, _ = host.String(common.BKHostInnerIPField)
+		localHost.PublicIp, _ = host.String(common.BKHostOuterIPField)
+		localHost.InstanceState, _ = host.String(common.BKCloudHostStatusField)
+		localHost.CloudID, _ = host.Int64(common.BKCloudIDField)
+		localHost.HostID, _ = host.Int64(common.BKHostIDField)
+		preview.UpdateHosts = append(preview.UpdateHosts, newDestroyedHostPreview(localHost))
+	}
+
+	return nil
+}
+
+// previewHostResource 预览没有被销毁的vpc下的主机变更
+func (h *HostSyncor) previewHostResource(hostResource *metadata.CloudHostResource,
+	preview *metadata.SyncTaskPreview) error {
+
+	if len(hostResource.HostResource) == 0 {
+		return nil
+	}
+
+	vpcIDs := make([]string, 0)
+	for _, hostRes := range hostResource.HostResource {
+		vpcIDs = append(vpcIDs, hostRes.Vpc.VpcID)
+	}
+
+	cloudAreas, err := h.getVpcCloudAreas(vpcIDs)
+	if err != nil {
+		return err
+	}
+
+	// 有云区域的vpc需要和本地主机比较差异，没有云区域的vpc下的主机都是新增的
+	existResource := make([]*metadata.VpcInstances, 0)
+	for _, hostRes := range hostResource.HostResource {
+		cloudArea, exists := cloudAreas[hostRes.Vpc.VpcID]
+		if exists {
+			hostRes.CloudID = cloudArea.CloudID
+			existResource = append(existResource, hostRes)
+			continue
+		}
+
+		preview.CreateCloudAreas = append(preview.CreateCloudAreas, metadata.CloudAreaPreview{
+			CloudName: fmt.Sprintf("%d_%s", hostResource.AccountConf.AccountID, hostRes.Vpc.VpcID),
+			VpcID:     hostRes.Vpc.VpcID,
+			VpcName:   hostRes.Vpc.VpcName,
+			Region:    hostRes.Vpc.Region,
+		})
+		for _, inst := range hostRes.Instances {
+			preview.AddHosts = append(preview.AddHosts, newCloudHostPreview(&metadata.CloudHost{Instance: *inst}))
+		}
+	}
+
+	if len(existResource) == 0 {
+		return nil
+	}
+
+	diffHosts, localHosts, err := h.compareHosts(&metadata.CloudHostResource{
+		HostResource: existResource,
+		TaskID:       hostResource.TaskID,
+		AccountConf:  hostResource.AccountConf,
+	})
+	if err != nil {
+		blog.Errorf("compare cloud hosts failed, err: %v, rid: %s", err, h.readKit.Rid)
+		return err
+	}
+
+	for _, host := range diffHosts["add"] {
+		preview.AddHosts = append(preview.AddHosts, newCloudHostPreview(host))
+	}
+
+	for _, host := range diffHosts["update"] {
+		localHost := localHosts[host.InstanceId]
+		hostPreview := newCloudHostPreview(host)
+		hostPreview.HostID = localHost.HostID
+		hostPreview.Changes = diffCloudHostFields(localHost, host)
+		preview.UpdateHosts = append(preview.UpdateHosts, hostPreview)
+	}
+
+	// 云端已销毁的主机在同步时被更新为已销毁状态
+	for _, host := range diffHosts["delete"] {
+		preview.UpdateHosts = append(preview.UpdateHosts, newDestroyedHostPreview(host))
+	}
+
+	return nil
+}
+
+// getVpcCloudAreas 获取vpc对应的云区域，返回以vpc id为key的云区域
+func (h *HostSyncor) getVpcCloudAreas(vpcIDs []string) (map[string]metadata.CloudAreaPreview, error) {
+	query := &metadata.QueryCondition{
+		Fields:    []string{common.BKCloudIDField, common.BKCloudNameField, common.BKVpcID},
+		Condition: mapstr.MapStr{common.BKVpcID: mapstr.MapStr{common.BKDBIN: vpcIDs}},
+		Page:      metadata.BasePage{Limit: common.BKNoLimit},
+	}
+	res, err := h.logics.CoreAPI.CoreService().Instance().ReadInstance(h.readKit.Ctx, h.readKit.Header,
+		common.BKInnerObjIDPlat, query)
+	if err != nil {
+		blog.Errorf("read vpc cloud areas failed, query: %#v, err: %v, rid: %s", query, err, h.readKit.Rid)
+		return nil, err
+	}
+
+	cloudAreas := make(map[string]metadata.CloudAreaPreview)
+	for _, info := range res.Info {
+		vpcID, _ := info.String(common.BKVpcID)
+		cloudID, err := info.Int64(common.BKCloudIDField)
+		if err != nil {
+			blog.Errorf("parse cloud id failed, info: %#v, err: %v, rid: %s", info, err, h.readKit.Rid)
+			return nil, err
+		}
+		cloudName, _ := info.String(common.BKCloudNameField)
+		cloudAreas[vpcID] = metadata.CloudAreaPreview{CloudID: cloudID, CloudName: cloudName, VpcID: vpcID}
+	}
+
+	return cloudAreas, nil
+}
+
+func newCloudHostPreview(host *metadata.CloudHost) metadata.CloudHostPreview {
+	return metadata.CloudHostPreview{
+		InstanceID:    host.InstanceId,
+		CloudID:       host.CloudID,
+		PrivateIp:     host.PrivateIp,
+		PublicIp:      host.PublicIp,
+		InstanceState: host.InstanceState,
+	}
+}
+
+// newDestroyedHostPreview 生成云端已销毁的本地主机的预览，变更的字段和同步时更新的数据一致
+func newDestroyedHostPreview(local *metadata.CloudHost) metadata.CloudHostPreview {
+	data := destroyedHostData()
+	destroyed := &metadata.CloudHost{CloudID: local.CloudID}
+	destroyed.InstanceState, _ = data.String(common.BKCloudHostStatusField)
+
+	hostPreview := newCloudHostPreview(local)
+	hostPreview.HostID = local.HostID
+	hostPreview.Changes = diffCloudHostFields(local, destroyed)
+	return hostPreview
+}
+
+// diffCloudHostFields 获取云主机同步时将会更新的字段变更前后的值，和getDiffHosts中比较的字段一致
+func diffCloudHostFields(local, remote *metadata.CloudHost) []metadata.CloudHostFieldChange {
+	changes := make([]metadata.CloudHostFieldChange, 0)
+	if local.CloudID != remote.CloudID {
+		changes = append(changes, metadata.CloudHostFieldChange{
+			Field: common.BKCloudIDField, Before: local.CloudID, After: remote.CloudID})
+	}
+	if local.PrivateIp != remote.PrivateIp {
+		changes = append(changes, metadata.CloudHostFieldChange{
+			Field: common.BKHostInnerIPField, Before: local.PrivateIp, After: remote.PrivateIp})
+	}
+	if local.PublicIp != remote.PublicIp {
+		changes = append(changes, metadata.CloudHostFieldChange{
+			Field: common.BKHostOuterIPField, Before: local.PublicIp, After: remote.PublicIp})
+	}
+	if local.InstanceState != remote.InstanceState {
+		changes = append(changes, metadata.CloudHostFieldChange{
+			Field: common.BKCloudHostStatusField, Before: local.InstanceState, After: remote.InstanceState})
+	}
+	return changes
+}
@@ -19,9 +19,9 @@ import (
 	"time"
 
 	"configcenter/src/common"
+	"configcenter/src/common/backbone/service_mange/regdiscv"
 	"configcenter/src/common/blog"
 	"configcenter/src/common/metadata"
-	"configcenter/src/common/zkclient"
 	"configcenter/src/scene_server/cloud_server/logics"
 	"configcenter/src/storage/dal/mongo/local"
 	"configcenter/src/storage/reflector"
@@ -38,22 +38,22 @@ var (
 
 // 任务调度器
 type taskScheduler struct {
-	zkClient   *zkclient.ZkClient
-	logics     *logics.Logics
-	addrport   string
-	reflector  reflector.Interface
-	hashring   *consistent.Consistent
-	tasklist   map[string]*metadata.CloudSyncTask
-	mu         sync.RWMutex
-	listerDone chan bool
+	regDiscvClient *regdiscv.Client
+	logics         *logics.Logics
+	addrport       string
+	reflector      reflector.Interface
+	hashring       *consistent.Consistent
+	tasklist       map[string]*metadata.CloudSyncTask
+	mu             sync.RWMutex
+	listerDone     chan bool
 }
 
 // SchedulerConf 调度器配置
 type SchedulerConf struct {
-	ZKClient  *zkclient.ZkClient
-	Logics    *logics.Logics
-	AddrPort  string
-	MongoConf local.MongoConf
+	RegDiscvClient *regdiscv.Client
+	Logics         *logics.Logics
+	AddrPort       string
+	MongoConf      local.MongoConf
 }
 
 // NewTaskScheduler 调度器实例创建
@@ -64,13 +64,13 @@ func NewTaskScheduler(conf *SchedulerConf) (*taskScheduler, error) {
 		return nil, err
 	}
 	return &taskScheduler{
-		zkClient:   conf.ZKClient,
-		logics:     conf.Logics,
-		addrport:   conf.AddrPort,
-		hashring:   consistent.New(),
-		tasklist:   make(map[string]*metadata.CloudSyncTask),
-		reflector:  reflector,
-		listerDone: make(chan bool),
+		regDiscvClient: conf.RegDiscvClient,
+		logics:         conf.Logics,
+		addrport:       conf.AddrPort,
+		hashring:       consistent.New(),
+		tasklist:       make(map[string]*metadata.CloudSyncTask),
+		reflector:      reflector,
+		listerDone:     make(chan bool),
 	}, nil
 }
 
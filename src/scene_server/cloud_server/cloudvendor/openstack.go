@@ -0,0 +1,602 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloudvendor
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"configcenter/src/common/blog"
+	"configcenter/src/common/metadata"
+	ccom "configcenter/src/scene_server/cloud_server/common"
+)
+
+func init() {
+	Register(metadata.OpenStack, &osClient{vendorName: metadata.OpenStack})
+}
+
+const (
+	osPageSize = 1000
+	// osRegionAvailable openstack的服务目录中没有地域状态，存在计算服务的地域即认为可用
+	osRegionAvailable = "AVAILABLE"
+	// osDefaultInterface 默认使用的服务目录中的endpoint类型
+	osDefaultInterface = "public"
+	// osDefaultTimeout 默认的请求超时时间
+	osDefaultTimeout = 30 * time.Second
+
+	osComputeService = "compute"
+	osNetworkService = "network"
+)
+
+// OpenStackConfig openstack兼容云的配置，账号的SecretID和SecretKey为keystone的应用凭证(application credential)的id和secret
+type OpenStackConfig struct {
+	// AuthURL keystone v3认证地址，如 http://keystone.example.com:5000/v3
+	AuthURL string
+	// Interface 服务目录中的endpoint类型，可选值为public、internal、admin，默认为public
+	Interface string
+	// Timeout 单次请求的超时时间
+	Timeout time.Duration
+}
+
+var (
+	osConfLock sync.RWMutex
+	osConf     = OpenStackConfig{Interface: osDefaultInterface, Timeout: osDefaultTimeout}
+)
+
+// SetOpenStackConfig 设置openstack兼容云的配置
+func SetOpenStackConfig(conf OpenStackConfig) {
+	if conf.Interface == "" {
+		conf.Interface = osDefaultInterface
+	}
+	if conf.Timeout <= 0 {
+		conf.Timeout = osDefaultTimeout
+	}
+	conf.AuthURL = strings.TrimSuffix(conf.AuthURL, "/")
+
+	osConfLock.Lock()
+	osConf = conf
+	osConfLock.Unlock()
+}
+
+func getOpenStackConfig() OpenStackConfig {
+	osConfLock.RLock()
+	defer osConfLock.RUnlock()
+	return osConf
+}
+
+type osClient struct {
+	vendorName string
+	secretID   string
+	secretKey  string
+	conf       OpenStackConfig
+	httpCli    *http.Client
+
+	// token 认证后获得的token，同一个客户端在token过期前复用
+	tokenLock sync.Mutex
+	token     *osToken
+}
+
+// NewVendorClient 创建云厂商客户端
+func (c *osClient) NewVendorClient(secretID, secretKey string) VendorClient {
+	conf := getOpenStackConfig()
+	return &osClient{
+		vendorName: metadata.OpenStack,
+		secretID:   secretID,
+		secretKey:  secretKey,
+		conf:       conf,
+		httpCli:    &http.Client{Timeout: conf.Timeout},
+	}
+}
+
+// GetRegions 获取地域列表，即服务目录中提供计算服务的地域
+// API文档：https://docs.openstack.org/api-ref/identity/v3/#token-authentication-with-scoped-authorization
+func (c *osClient) GetRegions() ([]*metadata.Region, error) {
+	token, err := c.getToken()
+	if err != nil {
+		return nil, err
+	}
+
+	regionIDs := make(map[string]struct{})
+	for _, service := range token.Catalog {
+		if service.Type != osComputeService {
+			continue
+		}
+		for _, endpoint := range service.Endpoints {
+			if endpoint.Interface != c.conf.Interface {
+				continue
+			}
+			regionIDs[endpoint.regionID()] = struct{}{}
+		}
+	}
+
+	regionSet := make([]*metadata.Region, 0)
+	for regionID := range regionIDs {
+		regionName := regionID
+		if token.Project.Name != "" {
+			regionName = fmt.Sprintf("%s(%s)", regionID, token.Project.Name)
+		}
+		regionSet = append(regionSet, &metadata.Region{
+			RegionId:    regionID,
+			RegionName:  regionName,
+			RegionState: osRegionAvailable,
+		})
+	}
+	sort.Slice(regionSet, func(i, j int) bool {
+		return regionSet[i].RegionId < regionSet[j].RegionId
+	})
+
+	return regionSet, nil
+}
+
+// GetVpcs 获取vpc列表，openstack的网络(network)对应vpc
+// API文档：https://docs.openstack.org/api-ref/network/v2/#list-networks
+func (c *osClient) GetVpcs(region string, opt *ccom.VpcOpt) (*metadata.VpcsInfo, error) {
+	if opt == nil {
+		opt = ccom.GetDefaultVpcOpt()
+	}
+
+	token, err := c.getToken()
+	if err != nil {
+		return nil, err
+	}
+
+	networkURL, err := c.getNetworkEndpoint(token, region)
+	if err != nil {
+		return nil, err
+	}
+
+	query := url.Values{}
+	for _, vpcID := range getFilterValues(opt.Filters, "vpc-id") {
+		query.Add("id", vpcID)
+	}
+
+	networks := make([]osNetwork, 0)
+	err = c.listAll(token, networkURL+"/networks", query, "networks", func(data json.RawMessage) (string, int, error) {
+		page := make([]osNetwork, 0)
+		if err := json.Unmarshal(data, &page); err != nil {
+			return "", 0, err
+		}
+		networks = append(networks, page...)
+		if len(page) == 0 {
+			return "", 0, nil
+		}
+		return page[len(page)-1].ID, len(page), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	vpcsInfo := &metadata.VpcsInfo{Count: int64(len(networks)), VpcSet: make([]*metadata.Vpc, 0)}
+	for _, network := range networks {
+		// 在limit小于全部数据量的情况下，只返回limit数量的数据
+		if opt.Limit > 0 && int64(len(vpcsInfo.VpcSet)) >= opt.Limit {
+			break
+		}
+		vpcsInfo.VpcSet = append(vpcsInfo.VpcSet, &metadata.Vpc{
+			VpcId:   network.ID,
+			VpcName: network.Name,
+		})
+	}
+
+	return vpcsInfo, nil
+}
+
+// GetInstances 获取实例列表，nova的云主机对应实例，实例所属的vpc由其网卡(port)所在的网络决定
+// API文档：https://docs.openstack.org/api-ref/compute/#list-servers-detailed
+func (c *osClient) GetInstances(region string, opt *ccom.InstanceOpt) (*metadata.InstancesInfo, error) {
+	if opt == nil {
+		opt = ccom.GetDefaultInstanceOpt()
+	}
+
+	token, err := c.getToken()
+	if err != nil {
+		return nil, err
+	}
+
+	networkURL, err := c.getNetworkEndpoint(token, region)
+	if err != nil {
+		return nil, err
+	}
+
+	computeURL, err := token.endpoint(osComputeService, region, c.conf.Interface)
+	if err != nil {
+		return nil, err
+	}
+
+	vpcIDs := getFilterValues(opt.Filters, "vpc-id")
+	ports, err := c.listPorts(token, networkURL, vpcIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	servers := make([]osServer, 0)
+	err = c.listAll(token, computeURL+"/servers/detail", url.Values{}, "servers",
+		func(data json.RawMessage) (string, int, error) {
+			page := make([]osServer, 0)
+			if err := json.Unmarshal(data, &page); err != nil {
+				return "", 0, err
+			}
+			servers = append(servers, page...)
+			if len(page) == 0 {
+				return "", 0, nil
+			}
+			return page[len(page)-1].ID, len(page), nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	instancesInfo := &metadata.InstancesInfo{InstanceSet: make([]*metadata.Instance, 0)}
+	for _, server := range servers {
+		port, exists := ports[server.ID]
+		// 指定了vpc时，只返回有网卡在这些网络中的实例
+		if len(vpcIDs) > 0 && !exists {
+			continue
+		}
+
+		instancesInfo.Count++
+		// 在limit小于全部数据量的情况下，只返回limit数量的数据
+		if opt.Limit > 0 && int64(len(instancesInfo.InstanceSet)) >= opt.Limit {
+			continue
+		}
+
+		instancesInfo.InstanceSet = append(instancesInfo.InstanceSet, &metadata.Instance{
+			InstanceId:    server.ID,
+			PrivateIp:     port.privateIP(),
+			PublicIp:      server.floatingIP(),
+			InstanceState: ccom.CovertInstState(convertOpenStackServerStatus(server.Status)),
+			VpcId:         port.NetworkID,
+		})
+	}
+
+	return instancesInfo, nil
+}
+
+// GetInstancesTotalCnt 获取实例总个数
+func (c *osClient) GetInstancesTotalCnt(region string, opt *ccom.InstanceOpt) (int64, error) {
+	cntOpt := ccom.GetDefaultInstanceOpt()
+	if opt != nil {
+		// 复制一份请求条件，避免修改调用方的Limit
+		*cntOpt = *opt
+	}
+	// openstack的接口不返回总数，只能获取全部数据后计数，不需要返回实例详情
+	cntOpt.Limit = 1
+	instsInfo, err := c.GetInstances(region, cntOpt)
+	if err != nil {
+		return 0, err
+	}
+	return instsInfo.Count, nil
+}
+
+// listPorts 获取云主机的网卡，返回云主机id到网卡的映射，指定了网络时只获取这些网络中的网卡
+// API文档：https://docs.openstack.org/api-ref/network/v2/#list-ports
+func (c *osClient) listPorts(token *osToken, networkURL string, networkIDs []string) (map[string]osPort, error) {
+	query := url.Values{}
+	for _, networkID := range networkIDs {
+		query.Add("network_id", networkID)
+	}
+
+	ports := make(map[string]osPort)
+	err := c.listAll(token, networkURL+"/ports", query, "ports", func(data json.RawMessage) (string, int, error) {
+		page := make([]osPort, 0)
+		if err := json.Unmarshal(data, &page); err != nil {
+			return "", 0, err
+		}
+		for _, port := range page {
+			if !strings.HasPrefix(port.DeviceOwner, "compute:") || port.DeviceID == "" {
+				continue
+			}
+			// 有多个网卡的云主机以第一个网卡所在的网络作为其vpc
+			if _, exists := ports[port.DeviceID]; !exists {
+				ports[port.DeviceID] = port
+			}
+		}
+		if len(page) == 0 {
+			return "", 0, nil
+		}
+		return page[len(page)-1].ID, len(page), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ports, nil
+}
+
+// listAll 使用limit和marker分页获取全部数据，handler处理每页的数据并返回本页最后一条数据的id和本页的数量
+func (c *osClient) listAll(token *osToken, rawURL string, query url.Values, key string,
+	handler func(data json.RawMessage) (string, int, error)) error {
+
+	query.Set("limit", strconv.Itoa(osPageSize))
+	loopCnt := 0
+	for {
+		resp := make(map[string]json.RawMessage)
+		if err := c.doRequest(http.MethodGet, rawURL+"?"+query.Encode(), token.ID, nil, &resp, nil); err != nil {
+			return err
+		}
+
+		marker, cnt, err := handler(resp[key])
+		if err != nil {
+			return fmt.Errorf("decode %s failed, err: %v", key, err)
+		}
+
+		// 在获取到全部数据的情况下，退出循环
+		if cnt < osPageSize || marker == "" {
+			return nil
+		}
+		// 设置分页请求参数
+		query.Set("marker", marker)
+		loopCnt++
+		if loopCnt > ccom.MaxLoopCnt {
+			blog.Errorf("list openstack %s loopCnt:%d, bigger than MaxLoopCnt", key, loopCnt)
+			return ccom.ErrorLoopCnt
+		}
+	}
+}
+
+// getNetworkEndpoint 获取网络服务的地址，neutron的服务目录中的地址不包含版本
+func (c *osClient) getNetworkEndpoint(token *osToken, region string) (string, error) {
+	networkURL, err := token.endpoint(osNetworkService, region, c.conf.Interface)
+	if err != nil {
+		return "", err
+	}
+
+	if !strings.HasSuffix(networkURL, "/v2.0") {
+		networkURL += "/v2.0"
+	}
+	return networkURL, nil
+}
+
+// getToken 获取token，使用应用凭证认证，token在过期前复用
+// API文档：https://docs.openstack.org/api-ref/identity/v3/#authenticating-with-an-application-credential
+func (c *osClient) getToken() (*osToken, error) {
+	c.tokenLock.Lock()
+	defer c.tokenLock.Unlock()
+
+	// 提前一分钟刷新token，防止请求过程中token过期
+	if c.token != nil && time.Now().Add(time.Minute).Before(c.token.ExpiresAt) {
+		return c.token, nil
+	}
+
+	if c.conf.AuthURL == "" {
+		return nil, errors.New("openstack auth url is not set")
+	}
+
+	body := map[string]interface{}{
+		"auth": map[string]interface{}{
+			"identity": map[string]interface{}{
+				"methods": []string{"application_credential"},
+				"application_credential": map[string]string{
+					"id":     c.secretID,
+					"secret": c.secretKey,
+				},
+			},
+		},
+	}
+
+	resp := new(osTokenResp)
+	header := make(http.Header)
+	if err := c.doRequest(http.MethodPost, c.conf.AuthURL+"/auth/tokens", "", body, resp, header); err != nil {
+		return nil, err
+	}
+
+	resp.Token.ID = header.Get("X-Subject-Token")
+	if resp.Token.ID == "" {
+		return nil, errors.New("openstack auth response has no X-Subject-Token header")
+	}
+
+	c.token = &resp.Token
+	return c.token, nil
+}
+
+// doRequest 发送请求并解析返回的json数据，respHeader不为空时返回响应头
+func (c *osClient) doRequest(method, rawURL, token string, body interface{}, result interface{},
+	respHeader http.Header) error {
+
+	var reqBody []byte
+	if body != nil {
+		var err error
+		reqBody, err = json.Marshal(body)
+		if err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest(method, rawURL, bytes.NewReader(reqBody))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if token != "" {
+		req.Header.Set("X-Auth-Token", token)
+	}
+
+	resp, err := c.httpCli.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("openstack request %s %s failed, status: %d, body: %s", method, req.URL.Path,
+			resp.StatusCode, string(data))
+	}
+
+	for key, values := range resp.Header {
+		if respHeader != nil {
+			respHeader[key] = values
+		}
+	}
+
+	if err := json.Unmarshal(data, result); err != nil {
+		return fmt.Errorf("decode openstack response of %s failed, err: %v", req.URL.Path, err)
+	}
+	return nil
+}
+
+// convertOpenStackServerStatus 将nova的云主机状态转换为通用的实例状态
+func convertOpenStackServerStatus(status string) string {
+	switch strings.ToUpper(status) {
+	case "ACTIVE":
+		return "running"
+	case "BUILD", "REBOOT", "HARD_REBOOT", "REBUILD", "MIGRATING", "RESIZE", "VERIFY_RESIZE", "REVERT_RESIZE":
+		return "starting"
+	case "SHUTOFF", "PAUSED", "SUSPENDED", "SHELVED", "SHELVED_OFFLOADED", "RESCUE":
+		return "stopped"
+	case "DELETED", "SOFT_DELETED":
+		return "terminated"
+	default:
+		return status
+	}
+}
+
+// getFilterValues 获取指定名称的过滤条件的值
+func getFilterValues(filters []*ccom.Filter, name string) []string {
+	values := make([]string, 0)
+	for _, filter := range filters {
+		if filter == nil || filter.Name == nil || *filter.Name != name {
+			continue
+		}
+		for _, value := range filter.Values {
+			if value != nil {
+				values = append(values, *value)
+			}
+		}
+	}
+	return values
+}
+
+type osTokenResp struct {
+	Token osToken `json:"token"`
+}
+
+type osToken struct {
+	ID        string           `json:"-"`
+	ExpiresAt time.Time        `json:"expires_at"`
+	Project   osProject        `json:"project"`
+	Catalog   []osCatalogEntry `json:"catalog"`
+}
+
+// endpoint 获取服务目录中指定服务在指定地域的地址
+func (t *osToken) endpoint(serviceType, region, iface string) (string, error) {
+	for _, service := range t.Catalog {
+		if service.Type != serviceType {
+			continue
+		}
+		for _, endpoint := range service.Endpoints {
+			if endpoint.Interface == iface && endpoint.regionID() == region {
+				return strings.TrimSuffix(endpoint.URL, "/"), nil
+			}
+		}
+	}
+	return "", fmt.Errorf("openstack %s service %s endpoint in region %s is not found", serviceType, iface, region)
+}
+
+type osProject struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type osCatalogEntry struct {
+	Type      string       `json:"type"`
+	Endpoints []osEndpoint `json:"endpoints"`
+}
+
+type osEndpoint struct {
+	Interface string `json:"interface"`
+	RegionID  string `json:"region_id"`
+	Region    string `json:"region"`
+	URL       string `json:"url"`
+}
+
+func (e osEndpoint) regionID() string {
+	if e.RegionID != "" {
+		return e.RegionID
+	}
+	return e.Region
+}
+
+type osNetwork struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type osPort struct {
+	ID          string `json:"id"`
+	NetworkID   string `json:"network_id"`
+	DeviceID    string `json:"device_id"`
+	DeviceOwner string `json:"device_owner"`
+	FixedIPs    []struct {
+		IPAddress string `json:"ip_address"`
+	} `json:"fixed_ips"`
+}
+
+// privateIP 获取网卡的内网ip，优先返回ipv4地址
+func (p osPort) privateIP() string {
+	for _, ip := range p.FixedIPs {
+		if !strings.Contains(ip.IPAddress, ":") {
+			return ip.IPAddress
+		}
+	}
+	if len(p.FixedIPs) > 0 {
+		return p.FixedIPs[0].IPAddress
+	}
+	return ""
+}
+
+type osServer struct {
+	ID        string                       `json:"id"`
+	Name      string                       `json:"name"`
+	Status    string                       `json:"status"`
+	Addresses map[string][]osServerAddress `json:"addresses"`
+}
+
+type osServerAddress struct {
+	Addr    string `json:"addr"`
+	Version int    `json:"version"`
+	Type    string `json:"OS-EXT-IPS:type"`
+}
+
+// floatingIP 获取云主机的浮动ip作为外网ip
+func (s osServer) floatingIP() string {
+	// 按网络名称排序，保证多个浮动ip时返回的结果是稳定的
+	names := make([]string, 0, len(s.Addresses))
+	for name := range s.Addresses {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		for _, addr := range s.Addresses[name] {
+			if addr.Type == "floating" {
+				return addr.Addr
+			}
+		}
+	}
+	return ""
+}
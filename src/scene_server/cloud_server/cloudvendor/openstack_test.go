@@ -0,0 +1,269 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloudvendor
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"configcenter/src/common/metadata"
+	ccom "configcenter/src/scene_server/cloud_server/common"
+)
+
+const (
+	osTestCredentialID     = "app-cred-id"
+	osTestCredentialSecret = "app-cred-secret"
+	osTestToken            = "test-token"
+)
+
+// newOpenStackFake start a fake of the keystone, nova and neutron api with two servers in two networks
+func newOpenStackFake() *httptest.Server {
+	var srv *httptest.Server
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/v3/auth/tokens", func(w http.ResponseWriter, r *http.Request) {
+		body := struct {
+			Auth struct {
+				Identity struct {
+					Credential struct {
+						ID     string `json:"id"`
+						Secret string `json:"secret"`
+					} `json:"application_credential"`
+				} `json:"identity"`
+			} `json:"auth"`
+		}{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		cred := body.Auth.Identity.Credential
+		if cred.ID != osTestCredentialID || cred.Secret != osTestCredentialSecret {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":{"code":401,"message":"The request you have made requires authentication."}}`))
+			return
+		}
+
+		endpoint := func(iface, region, url string) map[string]string {
+			return map[string]string{"interface": iface, "region_id": region, "url": url}
+		}
+		w.Header().Set("X-Subject-Token", osTestToken)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"token": map[string]interface{}{
+				"expires_at": time.Now().Add(time.Hour).UTC().Format(time.RFC3339),
+				"project":    map[string]string{"id": "p1", "name": "demo"},
+				"catalog": []map[string]interface{}{
+					{"type": "compute", "endpoints": []map[string]string{
+						endpoint("public", "RegionOne", srv.URL+"/compute/v2.1"),
+						endpoint("internal", "RegionOne", "http://internal/compute/v2.1"),
+						endpoint("public", "RegionTwo", srv.URL+"/compute/v2.1"),
+					}},
+					{"type": "network", "endpoints": []map[string]string{
+						endpoint("public", "RegionOne", srv.URL+"/network"),
+					}},
+				},
+			},
+		})
+	})
+
+	authorized := func(handler http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("X-Auth-Token") != osTestToken {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			handler(w, r)
+		}
+	}
+
+	networks := []map[string]string{{"id": "net-1", "name": "private"}, {"id": "net-2", "name": "backend"}}
+	mux.HandleFunc("/network/v2.0/networks", authorized(func(w http.ResponseWriter, r *http.Request) {
+		ids := r.URL.Query()["id"]
+		result := make([]map[string]string, 0)
+		for _, network := range networks {
+			if len(ids) == 0 || network["id"] == ids[0] {
+				result = append(result, network)
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"networks": result})
+	}))
+
+	ports := []map[string]interface{}{
+		{"id": "port-1", "network_id": "net-1", "device_id": "vm-1", "device_owner": "compute:nova",
+			"fixed_ips": []map[string]string{{"ip_address": "fd00::5"}, {"ip_address": "10.0.0.5"}}},
+		{"id": "port-2", "network_id": "net-2", "device_id": "vm-2", "device_owner": "compute:az1",
+			"fixed_ips": []map[string]string{{"ip_address": "192.168.0.8"}}},
+		{"id": "port-3", "network_id": "net-1", "device_id": "router-1", "device_owner": "network:router_interface",
+			"fixed_ips": []map[string]string{{"ip_address": "10.0.0.1"}}},
+	}
+	mux.HandleFunc("/network/v2.0/ports", authorized(func(w http.ResponseWriter, r *http.Request) {
+		networkIDs := r.URL.Query()["network_id"]
+		result := make([]map[string]interface{}, 0)
+		for _, port := range ports {
+			if len(networkIDs) == 0 || port["network_id"] == networkIDs[0] {
+				result = append(result, port)
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"ports": result})
+	}))
+
+	mux.HandleFunc("/compute/v2.1/servers/detail", authorized(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"servers": []map[string]interface{}{
+			{"id": "vm-1", "name": "web", "status": "ACTIVE", "addresses": map[string]interface{}{
+				"private": []map[string]interface{}{
+					{"addr": "10.0.0.5", "version": 4, "OS-EXT-IPS:type": "fixed"},
+					{"addr": "172.24.4.10", "version": 4, "OS-EXT-IPS:type": "floating"},
+				},
+			}},
+			{"id": "vm-2", "name": "db", "status": "SHUTOFF", "addresses": map[string]interface{}{
+				"backend": []map[string]interface{}{
+					{"addr": "192.168.0.8", "version": 4, "OS-EXT-IPS:type": "fixed"},
+				},
+			}},
+		}})
+	}))
+
+	srv = httptest.NewServer(mux)
+	return srv
+}
+
+func newOpenStackTestClient(t *testing.T, secretKey string) (VendorClient, func()) {
+	srv := newOpenStackFake()
+	SetOpenStackConfig(OpenStackConfig{AuthURL: srv.URL + "/v3/", Timeout: 5 * time.Second})
+
+	client, err := GetVendorClient(metadata.CloudAccountConf{
+		VendorName: metadata.OpenStack,
+		SecretID:   osTestCredentialID,
+		SecretKey:  secretKey,
+	})
+	if err != nil {
+		srv.Close()
+		t.Fatal(err)
+	}
+
+	return client, func() {
+		srv.Close()
+		SetOpenStackConfig(OpenStackConfig{})
+	}
+}
+
+func TestOpenStackGetRegions(t *testing.T) {
+	client, closeFn := newOpenStackTestClient(t, osTestCredentialSecret)
+	defer closeFn()
+
+	regionSet, err := client.GetRegions()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(regionSet) != 2 || regionSet[0].RegionId != "RegionOne" || regionSet[1].RegionId != "RegionTwo" {
+		t.Fatalf("unexpected regions: %+v", regionSet)
+	}
+
+	if regionSet[0].RegionName != "RegionOne(demo)" || regionSet[0].RegionState != osRegionAvailable {
+		t.Fatalf("unexpected region: %+v", *regionSet[0])
+	}
+}
+
+func TestOpenStackAuthFailed(t *testing.T) {
+	client, closeFn := newOpenStackTestClient(t, "wrong-secret")
+	defer closeFn()
+
+	if _, err := client.GetRegions(); err == nil {
+		t.Fatal("expect auth failed error, but got nil")
+	}
+}
+
+func TestOpenStackGetVpcs(t *testing.T) {
+	client, closeFn := newOpenStackTestClient(t, osTestCredentialSecret)
+	defer closeFn()
+
+	vpcsInfo, err := client.GetVpcs("RegionOne", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vpcsInfo.Count != 2 || len(vpcsInfo.VpcSet) != 2 {
+		t.Fatalf("unexpected vpcs: %+v", vpcsInfo)
+	}
+
+	vpcsInfo, err = client.GetVpcs("RegionOne", &ccom.VpcOpt{BaseOpt: ccom.BaseOpt{
+		Filters: []*ccom.Filter{{Name: ccom.StringPtr("vpc-id"), Values: ccom.StringPtrs([]string{"net-2"})}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vpcsInfo.Count != 1 || vpcsInfo.VpcSet[0].VpcId != "net-2" || vpcsInfo.VpcSet[0].VpcName != "backend" {
+		t.Fatalf("unexpected vpcs: %+v", vpcsInfo)
+	}
+
+	// region without network service
+	if _, err := client.GetVpcs("RegionTwo", nil); err == nil {
+		t.Fatal("expect endpoint not found error, but got nil")
+	}
+}
+
+func TestOpenStackGetInstances(t *testing.T) {
+	client, closeFn := newOpenStackTestClient(t, osTestCredentialSecret)
+	defer closeFn()
+
+	instancesInfo, err := client.GetInstances("RegionOne", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if instancesInfo.Count != 2 || len(instancesInfo.InstanceSet) != 2 {
+		t.Fatalf("unexpected instances: %+v", instancesInfo)
+	}
+
+	expected := map[string]metadata.Instance{
+		"vm-1": {InstanceId: "vm-1", PrivateIp: "10.0.0.5", PublicIp: "172.24.4.10", VpcId: "net-1",
+			InstanceState: ccom.CovertInstState("running")},
+		"vm-2": {InstanceId: "vm-2", PrivateIp: "192.168.0.8", VpcId: "net-2",
+			InstanceState: ccom.CovertInstState("stopped")},
+	}
+	for _, inst := range instancesInfo.InstanceSet {
+		if *inst != expected[inst.InstanceId] {
+			t.Fatalf("unexpected instance: %+v", *inst)
+		}
+	}
+
+	opt := &ccom.InstanceOpt{BaseOpt: ccom.BaseOpt{
+		Filters: []*ccom.Filter{{Name: ccom.StringPtr("vpc-id"), Values: ccom.StringPtrs([]string{"net-1"})}},
+	}}
+	instancesInfo, err = client.GetInstances("RegionOne", opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if instancesInfo.Count != 1 || instancesInfo.InstanceSet[0].InstanceId != "vm-1" {
+		t.Fatalf("unexpected instances: %+v", instancesInfo)
+	}
+
+	count, err := client.GetInstancesTotalCnt("RegionOne", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Fatalf("unexpected instance count: %d", count)
+	}
+
+	opt.Limit = ccom.MaxLimit
+	count, err = client.GetInstancesTotalCnt("RegionOne", opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 || opt.Limit != ccom.MaxLimit {
+		t.Fatalf("unexpected instance count: %d, or the option limit is changed to %d", count, opt.Limit)
+	}
+}
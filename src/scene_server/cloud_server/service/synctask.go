@@ -21,6 +21,7 @@ import (
 	"configcenter/src/common/blog"
 	"configcenter/src/common/http/rest"
 	"configcenter/src/common/metadata"
+	"configcenter/src/scene_server/cloud_server/cloudsync"
 )
 
 // SearchVpc TODO
@@ -197,3 +198,27 @@ func (s *Service) SearchSyncRegion(ctx *rest.Contexts) {
 
 	ctx.RespEntity(result)
 }
+
+// PreviewSyncTask 预览云同步任务，返回同步时将会新增、更新、删除的主机和将要创建、置为异常的云区域，不写入任何数据
+func (s *Service) PreviewSyncTask(ctx *rest.Contexts) {
+	option := new(metadata.PreviewSyncTaskOption)
+	if err := ctx.DecodeInto(option); err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	rawErr := option.Validate()
+	if rawErr.ErrCode != 0 {
+		ctx.RespAutoError(rawErr.ToCCError(ctx.Kit.CCError))
+		return
+	}
+
+	result, err := cloudsync.NewHostSyncor(s.Logics).Preview(ctx.Kit, option)
+	if err != nil {
+		blog.Errorf("preview sync task failed, option: %#v, err: %v, rid: %s", option, err, ctx.Kit.Rid)
+		ctx.RespAutoError(err)
+		return
+	}
+
+	ctx.RespEntity(result)
+}
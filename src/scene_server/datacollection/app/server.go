@@ -370,20 +370,10 @@ func (c *DataCollection) initModules() error {
 
 	// connect to snap kafka.
 	if c.config.SnapReportMode == "kafka" {
-		config := sarama.NewConfig()
+		config := kafka.NewSaramaConfig(c.config.SnapKafka)
 		config.Consumer.Return.Errors = false
 		config.Consumer.Offsets.AutoCommit.Enable = false // 禁用自动提交，改为手动
 		config.Consumer.Offsets.Initial = sarama.OffsetOldest
-		if c.config.SnapKafka.User != "" && c.config.SnapKafka.Password != "" {
-			config.Net.SASL.Enable = true
-			config.Net.SASL.User = c.config.SnapKafka.User
-			config.Net.SASL.Password = c.config.SnapKafka.Password
-			config.Net.SASL.Handshake = true
-			config.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient {
-				return &kafka.XDGSCRAMClient{HashGeneratorFcn: kafka.SHA512}
-			}
-			config.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA512
-		}
 		var err error
 		c.snapConsumerGroup, err = sarama.NewConsumerGroup(c.config.SnapKafka.Brokers, c.config.SnapKafka.GroupID,
 			config)
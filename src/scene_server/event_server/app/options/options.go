@@ -17,6 +17,7 @@ import (
 	"configcenter/src/ac/iam"
 	"configcenter/src/common/auth"
 	"configcenter/src/common/core/cc/config"
+	"configcenter/src/scene_server/event_server/subscription"
 	"configcenter/src/scene_server/event_server/sync/hostidentifier"
 	"configcenter/src/storage/dal/mongo"
 	"configcenter/src/storage/dal/redis"
@@ -41,7 +42,7 @@ func NewServerOption() *ServerOption {
 // AddFlags add flags to server options.
 func (s *ServerOption) AddFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&s.ServConf.AddrPort, "addrport", "127.0.0.1:60009", "The ip address and port for the serve on")
-	fs.StringVar(&s.ServConf.RegDiscover, "regdiscv", "", "hosts of register and discover server. e.g: 127.0.0.1:2181")
+	fs.StringVar(&s.ServConf.RegDiscover, "regdiscv", "", "hosts of register and discover server. e.g: 127.0.0.1:2181, etcd://127.0.0.1:2379 or file:///data/cmdb/regdiscv")
 	fs.StringVar(&s.ServConf.ExConfig, "config", "", "The config path. e.g conf/api.conf")
 	fs.StringVar(&s.ServConf.RegisterIP, "register-ip", "", "the ip address registered on zookeeper, it can be domain")
 	fs.Var(auth.EnableAuthFlag, "enable-auth", "The auth center enable status, true for enabled, false for disabled")
@@ -61,6 +62,9 @@ type Config struct {
 	// IdentifierConf host identifier config
 	IdentifierConf *hostidentifier.HostIdentifierConf
 
+	// SubscriptionConf event subscription push config
+	SubscriptionConf *subscription.Config
+
 	// TaskConf gse taskServer connection config
 	TaskConf *client.GseConnConfig
 
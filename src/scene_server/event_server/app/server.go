@@ -19,6 +19,7 @@ import (
 	"sync"
 	"time"
 
+	"configcenter/src/ac"
 	"configcenter/src/ac/extensions"
 	"configcenter/src/ac/iam"
 	"configcenter/src/common/auth"
@@ -29,6 +30,7 @@ import (
 	"configcenter/src/common/types"
 	"configcenter/src/scene_server/event_server/app/options"
 	svc "configcenter/src/scene_server/event_server/service"
+	"configcenter/src/scene_server/event_server/subscription"
 	"configcenter/src/scene_server/event_server/sync/hostidentifier"
 	"configcenter/src/storage/dal"
 	"configcenter/src/storage/dal/mongo/local"
@@ -180,6 +182,12 @@ func (es *EventServer) initConfigs() error {
 		return err
 	}
 
+	es.config.SubscriptionConf, err = subscription.ParseConfig()
+	if err != nil {
+		blog.Errorf("parse eventServer subscription config error, err: %v", err)
+		return err
+	}
+
 	identifierConf, err := hostidentifier.ParseIdentifierConf()
 	if err != nil {
 		blog.Errorf("parse eventServer host identifier config error, err: %v", err)
@@ -227,7 +235,7 @@ func (es *EventServer) initModules() error {
 	blog.Infof("init modules, connected to cc redis, %+v", es.config.Redis)
 
 	// initialize auth authorizer
-	es.service.SetAuthorizer(iam.NewAuthorizer(es.engine.CoreAPI))
+	es.service.SetAuthorizer(ac.NewAuthorizer(es.engine.CoreAPI))
 
 	iamCli := new(iam.IAM)
 	if auth.EnableAuthorize() {
@@ -240,6 +248,7 @@ func (es *EventServer) initModules() error {
 		blog.Infof("disable auth center access")
 	}
 	es.service.AuthManager = extensions.NewAuthManager(es.engine.CoreAPI, iamCli)
+	es.service.SetSubscriptionConf(es.config.SubscriptionConf)
 
 	return nil
 }
@@ -258,12 +267,26 @@ func (es *EventServer) Run() error {
 	}
 	blog.Info("init modules success!")
 
+	es.runSubscriptionPusher()
+
 	if err := es.runSyncData(); err != nil {
 		return err
 	}
 	return nil
 }
 
+func (es *EventServer) runSubscriptionPusher() {
+	if !es.config.SubscriptionConf.StartUp {
+		blog.Warnf("eventServer.subscription.startUp is false, will not push subscription events")
+		return
+	}
+
+	pusher := subscription.NewPusher(es.ctx, es.engine, es.db, es.config.SubscriptionConf,
+		es.service.AuthManager)
+	go pusher.Run()
+	blog.Info("run subscription pusher success!")
+}
+
 func (es *EventServer) runSyncData() error {
 	if !es.config.IdentifierConf.StartUp {
 		return nil
@@ -28,6 +28,7 @@ import (
 	"configcenter/src/common/rdapi"
 	"configcenter/src/common/types"
 	"configcenter/src/common/webservice/restfulservice"
+	"configcenter/src/scene_server/event_server/subscription"
 	"configcenter/src/scene_server/event_server/sync/hostidentifier"
 	"configcenter/src/storage/dal"
 	"configcenter/src/storage/dal/redis"
@@ -46,6 +47,8 @@ type Service struct {
 	authorizer  ac.AuthorizeInterface
 	AuthManager *extensions.AuthManager
 
+	subscriptionConf *subscription.Config
+
 	// SyncData is sync host identifier operator
 	SyncData *hostidentifier.HostIdentifier
 }
@@ -70,6 +73,11 @@ func (s *Service) SetAuthorizer(authorizer ac.AuthorizeInterface) {
 	s.authorizer = authorizer
 }
 
+// SetSubscriptionConf setups event subscription config.
+func (s *Service) SetSubscriptionConf(conf *subscription.Config) {
+	s.subscriptionConf = conf
+}
+
 // WebService setups a new restful web service.
 func (s *Service) WebService() *restful.Container {
 	container := restful.NewContainer()
@@ -112,6 +120,18 @@ func (s *Service) initService(web *restful.WebService) {
 	utility.AddHandler(rest.Action{Verb: http.MethodPost, Path: "/find/host_identifier_push_result",
 		Handler: s.GetHostIdentifierPushResult})
 
+	// webhook subscription of the resource watch events
+	utility.AddHandler(rest.Action{Verb: http.MethodPost, Path: "/create/subscription", Handler: s.CreateSubscription})
+	utility.AddHandler(rest.Action{Verb: http.MethodPut, Path: "/update/subscription/{id}",
+		Handler: s.UpdateSubscription})
+	utility.AddHandler(rest.Action{Verb: http.MethodDelete, Path: "/delete/subscription/{id}",
+		Handler: s.DeleteSubscription})
+	utility.AddHandler(rest.Action{Verb: http.MethodPost, Path: "/findmany/subscription", Handler: s.ListSubscription})
+	utility.AddHandler(rest.Action{Verb: http.MethodPost, Path: "/find/subscription/delivery_status",
+		Handler: s.GetSubscriptionStatus})
+	utility.AddHandler(rest.Action{Verb: http.MethodPost, Path: "/findmany/subscription/{id}/dead_letter",
+		Handler: s.ListSubscriptionDeadLetter})
+
 	utility.AddToRestfulWebService(web)
 
 }
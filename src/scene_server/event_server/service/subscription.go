@@ -0,0 +1,431 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"strconv"
+	"time"
+
+	"configcenter/src/ac"
+	"configcenter/src/common"
+	"configcenter/src/common/blog"
+	"configcenter/src/common/http/rest"
+	"configcenter/src/common/mapstr"
+	"configcenter/src/common/metadata"
+	"configcenter/src/common/util"
+	"configcenter/src/common/watch"
+	"configcenter/src/scene_server/event_server/subscription"
+)
+
+// CreateSubscription create a webhook subscription of the resource watch events
+func (s *Service) CreateSubscription(ctx *rest.Contexts) {
+	opt := new(watch.CreateSubscriptionOption)
+	if err := ctx.DecodeInto(opt); err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	if err := opt.Validate(); err != nil {
+		blog.Errorf("create subscription option is invalid, err: %v, rid: %s", err, ctx.Kit.Rid)
+		ctx.RespAutoError(ctx.Kit.CCError.CCErrorf(common.CCErrCommParamsIsInvalid, err.Error()))
+		return
+	}
+
+	err := subscription.CheckCallbackURL(ctx.Kit.Ctx, opt.CallbackURL, s.subscriptionConf.AllowPrivateCallback)
+	if err != nil {
+		blog.Errorf("create subscription callback url is not allowed, err: %v, rid: %s", err, ctx.Kit.Rid)
+		ctx.RespAutoError(ctx.Kit.CCError.CCErrorf(common.CCErrCommParamsIsInvalid, err.Error()))
+		return
+	}
+
+	id, err := s.db.NextSequence(ctx.Kit.Ctx, common.BKTableNameEventSubscription)
+	if err != nil {
+		blog.Errorf("generate subscription id failed, err: %v, rid: %s", err, ctx.Kit.Rid)
+		ctx.RespAutoError(ctx.Kit.CCError.CCError(common.CCErrCommDBInsertFailed))
+		return
+	}
+
+	batchSize := opt.BatchSize
+	if batchSize == 0 {
+		batchSize = watch.DefaultSubscriptionBatchSize
+	}
+
+	// start to push events from the create time if the start time is not set
+	startFrom := opt.StartFrom
+	if startFrom == 0 {
+		startFrom = time.Now().Unix()
+	}
+
+	now := metadata.Now()
+	sub := &watch.Subscription{
+		ID:              int64(id),
+		Name:            opt.Name,
+		Resource:        opt.Resource,
+		EventTypes:      opt.EventTypes,
+		Fields:          opt.Fields,
+		Filter:          opt.Filter,
+		CallbackURL:     opt.CallbackURL,
+		Secret:          opt.Secret,
+		BatchSize:       batchSize,
+		Enabled:         !opt.Disabled,
+		StartFrom:       startFrom,
+		SupplierAccount: ctx.Kit.SupplierAccount,
+		Creator:         ctx.Kit.User,
+		Modifier:        ctx.Kit.User,
+		CreateTime:      now,
+		LastTime:        now,
+	}
+
+	if err := s.db.Table(common.BKTableNameEventSubscription).Insert(ctx.Kit.Ctx, sub); err != nil {
+		blog.Errorf("create subscription %+v failed, err: %v, rid: %s", sub, err, ctx.Kit.Rid)
+		if s.db.IsDuplicatedError(err) {
+			ctx.RespAutoError(ctx.Kit.CCError.CCErrorf(common.CCErrCommDuplicateItem, "name"))
+			return
+		}
+		ctx.RespAutoError(ctx.Kit.CCError.CCError(common.CCErrCommDBInsertFailed))
+		return
+	}
+
+	ctx.RespEntity(metadata.RspID{ID: sub.ID})
+}
+
+// UpdateSubscription update a webhook subscription, the subscribed resource can not be changed
+func (s *Service) UpdateSubscription(ctx *rest.Contexts) {
+	id, err := s.parseSubscriptionID(ctx)
+	if err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	opt := new(watch.UpdateSubscriptionOption)
+	if err := ctx.DecodeInto(opt); err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	sub, err := s.getSubscription(ctx.Kit, id)
+	if err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	if !s.authorizeSubscription(ctx, sub) {
+		return
+	}
+
+	if err := opt.Validate(sub); err != nil {
+		blog.Errorf("update subscription option is invalid, err: %v, rid: %s", err, ctx.Kit.Rid)
+		ctx.RespAutoError(ctx.Kit.CCError.CCErrorf(common.CCErrCommParamsIsInvalid, err.Error()))
+		return
+	}
+
+	if opt.CallbackURL != nil {
+		err := subscription.CheckCallbackURL(ctx.Kit.Ctx, *opt.CallbackURL, s.subscriptionConf.AllowPrivateCallback)
+		if err != nil {
+			blog.Errorf("update subscription callback url is not allowed, err: %v, rid: %s", err, ctx.Kit.Rid)
+			ctx.RespAutoError(ctx.Kit.CCError.CCErrorf(common.CCErrCommParamsIsInvalid, err.Error()))
+			return
+		}
+	}
+
+	update := mapstr.MapStr{
+		common.ModifierField: ctx.Kit.User,
+		common.LastTimeField: metadata.Now(),
+	}
+	if opt.Name != nil {
+		update["name"] = *opt.Name
+	}
+	if opt.EventTypes != nil {
+		update["bk_event_types"] = opt.EventTypes
+	}
+	if opt.Fields != nil {
+		update["bk_fields"] = opt.Fields
+	}
+	if opt.CallbackURL != nil {
+		update["callback_url"] = *opt.CallbackURL
+	}
+	if opt.Secret != nil {
+		update["secret"] = *opt.Secret
+	}
+	if opt.BatchSize != nil {
+		update["batch_size"] = *opt.BatchSize
+	}
+	if opt.Enabled != nil {
+		update["enabled"] = *opt.Enabled
+	}
+
+	filter := s.subscriptionFilter(ctx.Kit, id)
+	if err := s.db.Table(common.BKTableNameEventSubscription).Update(ctx.Kit.Ctx, filter, update); err != nil {
+		blog.Errorf("update subscription %d failed, data: %+v, err: %v, rid: %s", id, update, err, ctx.Kit.Rid)
+		if s.db.IsDuplicatedError(err) {
+			ctx.RespAutoError(ctx.Kit.CCError.CCErrorf(common.CCErrCommDuplicateItem, "name"))
+			return
+		}
+		ctx.RespAutoError(ctx.Kit.CCError.CCError(common.CCErrCommDBUpdateFailed))
+		return
+	}
+
+	ctx.RespEntity(nil)
+}
+
+// DeleteSubscription delete a webhook subscription and its dead letters
+func (s *Service) DeleteSubscription(ctx *rest.Contexts) {
+	id, err := s.parseSubscriptionID(ctx)
+	if err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	sub, err := s.getSubscription(ctx.Kit, id)
+	if err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	if !s.authorizeSubscription(ctx, sub) {
+		return
+	}
+
+	filter := s.subscriptionFilter(ctx.Kit, id)
+	if err := s.db.Table(common.BKTableNameEventSubscription).Delete(ctx.Kit.Ctx, filter); err != nil {
+		blog.Errorf("delete subscription %d failed, err: %v, rid: %s", id, err, ctx.Kit.Rid)
+		ctx.RespAutoError(ctx.Kit.CCError.CCError(common.CCErrCommDBDeleteFailed))
+		return
+	}
+
+	deadLetterFilter := mapstr.MapStr{"subscription_id": id}
+	err = s.db.Table(common.BKTableNameSubscriptionDeadLetter).Delete(ctx.Kit.Ctx, deadLetterFilter)
+	if err != nil {
+		blog.Errorf("delete subscription %d dead letters failed, err: %v, rid: %s", id, err, ctx.Kit.Rid)
+		ctx.RespAutoError(ctx.Kit.CCError.CCError(common.CCErrCommDBDeleteFailed))
+		return
+	}
+
+	ctx.RespEntity(nil)
+}
+
+// ListSubscription list webhook subscriptions, the secret of the subscription is not returned
+func (s *Service) ListSubscription(ctx *rest.Contexts) {
+	opt := new(watch.ListSubscriptionOption)
+	if err := ctx.DecodeInto(opt); err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	if rawErr := opt.Page.ValidateWithEnableCount(false); rawErr.ErrCode != 0 {
+		ctx.RespAutoError(rawErr.ToCCError(ctx.Kit.CCError))
+		return
+	}
+
+	// only the subscriptions created by the user can be seen, because they contain the callback of the user
+	filter := util.SetModOwner(mapstr.MapStr{common.CreatorField: ctx.Kit.User}, ctx.Kit.SupplierAccount)
+	if len(opt.IDs) > 0 {
+		filter[common.BKFieldID] = mapstr.MapStr{common.BKDBIN: opt.IDs}
+	}
+	if len(opt.Resource) > 0 {
+		filter["bk_resource"] = opt.Resource
+	}
+
+	table := s.db.Table(common.BKTableNameEventSubscription)
+	if opt.Page.EnableCount {
+		count, err := table.Find(filter).Count(ctx.Kit.Ctx)
+		if err != nil {
+			blog.Errorf("count subscriptions failed, filter: %+v, err: %v, rid: %s", filter, err, ctx.Kit.Rid)
+			ctx.RespAutoError(ctx.Kit.CCError.CCError(common.CCErrCommDBSelectFailed))
+			return
+		}
+		ctx.RespEntity(watch.ListSubscriptionResult{Count: count})
+		return
+	}
+
+	sort := opt.Page.Sort
+	if len(sort) == 0 {
+		sort = common.BKFieldID
+	}
+
+	subs := make([]watch.Subscription, 0)
+	err := table.Find(filter).Sort(sort).Start(uint64(opt.Page.Start)).Limit(uint64(opt.Page.Limit)).
+		All(ctx.Kit.Ctx, &subs)
+	if err != nil {
+		blog.Errorf("list subscriptions failed, filter: %+v, err: %v, rid: %s", filter, err, ctx.Kit.Rid)
+		ctx.RespAutoError(ctx.Kit.CCError.CCError(common.CCErrCommDBSelectFailed))
+		return
+	}
+
+	for idx := range subs {
+		subs[idx].Secret = ""
+	}
+
+	ctx.RespEntity(watch.ListSubscriptionResult{Info: subs})
+}
+
+// GetSubscriptionStatus get the delivery status and the cursor of the webhook subscriptions
+func (s *Service) GetSubscriptionStatus(ctx *rest.Contexts) {
+	opt := new(watch.SubscriptionStatusOption)
+	if err := ctx.DecodeInto(opt); err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	if err := opt.Validate(); err != nil {
+		blog.Errorf("get subscription status option is invalid, err: %v, rid: %s", err, ctx.Kit.Rid)
+		ctx.RespAutoError(ctx.Kit.CCError.CCErrorf(common.CCErrCommParamsIsInvalid, err.Error()))
+		return
+	}
+
+	filter := mapstr.MapStr{
+		common.BKFieldID:    mapstr.MapStr{common.BKDBIN: opt.IDs},
+		common.CreatorField: ctx.Kit.User,
+	}
+	filter = util.SetModOwner(filter, ctx.Kit.SupplierAccount)
+	subs := make([]watch.Subscription, 0)
+	err := s.db.Table(common.BKTableNameEventSubscription).Find(filter).
+		Fields(common.BKFieldID, "enabled", "bk_cursor", "delivery_status").All(ctx.Kit.Ctx, &subs)
+	if err != nil {
+		blog.Errorf("get subscription status failed, ids: %v, err: %v, rid: %s", opt.IDs, err, ctx.Kit.Rid)
+		ctx.RespAutoError(ctx.Kit.CCError.CCError(common.CCErrCommDBSelectFailed))
+		return
+	}
+
+	status := make([]watch.SubscriptionStatus, len(subs))
+	for idx, sub := range subs {
+		status[idx] = watch.SubscriptionStatus{
+			ID:      sub.ID,
+			Enabled: sub.Enabled,
+			Cursor:  sub.Cursor,
+			Status:  sub.Status,
+		}
+	}
+
+	ctx.RespEntity(status)
+}
+
+// ListSubscriptionDeadLetter list the events of the subscription that are failed to push after all retries
+func (s *Service) ListSubscriptionDeadLetter(ctx *rest.Contexts) {
+	id, err := s.parseSubscriptionID(ctx)
+	if err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	opt := new(watch.ListDeadLetterOption)
+	if err := ctx.DecodeInto(opt); err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	if rawErr := opt.Page.ValidateWithEnableCount(false); rawErr.ErrCode != 0 {
+		ctx.RespAutoError(rawErr.ToCCError(ctx.Kit.CCError))
+		return
+	}
+
+	sub, err := s.getSubscription(ctx.Kit, id)
+	if err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	if !s.authorizeSubscription(ctx, sub) {
+		return
+	}
+
+	filter := mapstr.MapStr{"subscription_id": id}
+	table := s.db.Table(common.BKTableNameSubscriptionDeadLetter)
+	if opt.Page.EnableCount {
+		count, err := table.Find(filter).Count(ctx.Kit.Ctx)
+		if err != nil {
+			blog.Errorf("count subscription %d dead letters failed, err: %v, rid: %s", id, err, ctx.Kit.Rid)
+			ctx.RespAutoError(ctx.Kit.CCError.CCError(common.CCErrCommDBSelectFailed))
+			return
+		}
+		ctx.RespEntity(watch.ListDeadLetterResult{Count: count})
+		return
+	}
+
+	sort := opt.Page.Sort
+	if len(sort) == 0 {
+		sort = "-" + common.CreateTimeField
+	}
+
+	deadLetters := make([]watch.SubscriptionDeadLetter, 0)
+	err = table.Find(filter).Sort(sort).Start(uint64(opt.Page.Start)).Limit(uint64(opt.Page.Limit)).
+		All(ctx.Kit.Ctx, &deadLetters)
+	if err != nil {
+		blog.Errorf("list subscription %d dead letters failed, err: %v, rid: %s", id, err, ctx.Kit.Rid)
+		ctx.RespAutoError(ctx.Kit.CCError.CCError(common.CCErrCommDBSelectFailed))
+		return
+	}
+
+	ctx.RespEntity(watch.ListDeadLetterResult{Info: deadLetters})
+}
+
+// authorizeSubscription authorize if the user can operate the subscription, only the creator of the subscription who
+// still has the authority to watch the subscribed resource can operate it. returns false if it is not authorized,
+// and the response is already written.
+func (s *Service) authorizeSubscription(ctx *rest.Contexts, sub *watch.Subscription) bool {
+	if sub.Creator != ctx.Kit.User {
+		blog.Errorf("user %s is not the creator %s of subscription %d, rid: %s", ctx.Kit.User, sub.Creator, sub.ID,
+			ctx.Kit.Rid)
+		ctx.RespAutoError(ctx.Kit.CCError.CCError(common.CCErrCommAuthNotHavePermission))
+		return false
+	}
+
+	err := s.AuthManager.AuthorizeWatchResource(ctx.Kit.Ctx, ctx.Kit.Header, sub.Resource, sub.Filter.SubResource)
+	if err == nil {
+		return true
+	}
+
+	if err != ac.NoAuthorizeError {
+		blog.Errorf("authorize subscription %d resource %s failed, err: %v, rid: %s", sub.ID, sub.Resource, err,
+			ctx.Kit.Rid)
+		ctx.RespAutoError(ctx.Kit.CCError.CCError(common.CCErrCommAuthorizeFailed))
+		return false
+	}
+
+	perm, err := s.AuthManager.GenWatchResourceNoPermissionResp(ctx.Kit.Ctx, ctx.Kit.Header, sub.Resource,
+		sub.Filter.SubResource)
+	if err != nil {
+		ctx.RespAutoError(ctx.Kit.CCError.CCError(common.CCErrCommAuthorizeFailed))
+		return false
+	}
+	ctx.RespEntityWithError(perm, ac.NoAuthorizeError)
+	return false
+}
+
+func (s *Service) parseSubscriptionID(ctx *rest.Contexts) (int64, error) {
+	id, err := strconv.ParseInt(ctx.Request.PathParameter(common.BKFieldID), 10, 64)
+	if err != nil || id <= 0 {
+		blog.Errorf("subscription id %s is invalid, err: %v, rid: %s", ctx.Request.PathParameter(common.BKFieldID),
+			err, ctx.Kit.Rid)
+		return 0, ctx.Kit.CCError.CCErrorf(common.CCErrCommParamsIsInvalid, common.BKFieldID)
+	}
+	return id, nil
+}
+
+func (s *Service) subscriptionFilter(kit *rest.Kit, id int64) mapstr.MapStr {
+	return util.SetModOwner(mapstr.MapStr{common.BKFieldID: id}, kit.SupplierAccount)
+}
+
+func (s *Service) getSubscription(kit *rest.Kit, id int64) (*watch.Subscription, error) {
+	sub := new(watch.Subscription)
+	err := s.db.Table(common.BKTableNameEventSubscription).Find(s.subscriptionFilter(kit, id)).One(kit.Ctx, sub)
+	if err != nil {
+		if s.db.IsNotFoundError(err) {
+			blog.Errorf("subscription %d is not exist, rid: %s", id, kit.Rid)
+			return nil, kit.CCError.CCError(common.CCErrCommNotFound)
+		}
+		blog.Errorf("get subscription %d failed, err: %v, rid: %s", id, err, kit.Rid)
+		return nil, kit.CCError.CCError(common.CCErrCommDBSelectFailed)
+	}
+	return sub, nil
+}
@@ -0,0 +1,394 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"configcenter/src/ac"
+	"configcenter/src/ac/extensions"
+	"configcenter/src/ac/meta"
+	"configcenter/src/common"
+	ccErr "configcenter/src/common/errors"
+	"configcenter/src/common/http/rest"
+	"configcenter/src/common/mapstr"
+	"configcenter/src/common/watch"
+	sdktypes "configcenter/src/scene_server/auth_server/sdk/types"
+	"configcenter/src/scene_server/event_server/subscription"
+	"configcenter/src/storage/dal"
+	"configcenter/src/storage/dal/types"
+
+	"github.com/emicklei/go-restful/v3"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var errNotFound = errors.New("not found")
+
+// fakeDB is an in-memory db that only supports the equal and $in conditions used by the subscription handlers
+type fakeDB struct {
+	dal.RDB
+	tables   map[string][]bson.M
+	sequence uint64
+}
+
+// Table returns the fake table of the collection
+func (db *fakeDB) Table(collection string) types.Table {
+	return &fakeTable{db: db, name: collection}
+}
+
+// NextSequence returns the next id
+func (db *fakeDB) NextSequence(_ context.Context, _ string) (uint64, error) {
+	db.sequence++
+	return db.sequence, nil
+}
+
+// IsNotFoundError check if the error is the not found error of the fake db
+func (db *fakeDB) IsNotFoundError(err error) bool {
+	return err == errNotFound
+}
+
+// IsDuplicatedError the fake db has no unique index
+func (db *fakeDB) IsDuplicatedError(error) bool {
+	return false
+}
+
+// insert the doc to the table
+func (db *fakeDB) insert(table string, doc interface{}) error {
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	m := make(bson.M)
+	if err := bson.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	db.tables[table] = append(db.tables[table], m)
+	return nil
+}
+
+type fakeTable struct {
+	types.Table
+	db   *fakeDB
+	name string
+}
+
+// Find returns the fake find of the filter
+func (t *fakeTable) Find(filter types.Filter, _ ...*types.FindOpts) types.Find {
+	return &fakeFind{table: t, filter: filter.(mapstr.MapStr)}
+}
+
+// Insert inserts the doc into the table
+func (t *fakeTable) Insert(_ context.Context, doc interface{}) error {
+	return t.db.insert(t.name, doc)
+}
+
+// Update sets the fields of the docs that match the filter
+func (t *fakeTable) Update(_ context.Context, filter types.Filter, doc interface{}) error {
+	for _, data := range t.db.tables[t.name] {
+		if matchFilter(data, filter.(mapstr.MapStr)) {
+			for key, value := range doc.(mapstr.MapStr) {
+				data[key] = value
+			}
+		}
+	}
+	return nil
+}
+
+// Delete deletes the docs that match the filter
+func (t *fakeTable) Delete(_ context.Context, filter types.Filter) error {
+	remain := make([]bson.M, 0)
+	for _, data := range t.db.tables[t.name] {
+		if !matchFilter(data, filter.(mapstr.MapStr)) {
+			remain = append(remain, data)
+		}
+	}
+	t.db.tables[t.name] = remain
+	return nil
+}
+
+type fakeFind struct {
+	types.Find
+	table  *fakeTable
+	filter mapstr.MapStr
+}
+
+// Fields is ignored by the fake find
+func (f *fakeFind) Fields(_ ...string) types.Find {
+	return f
+}
+
+// Sort is ignored by the fake find
+func (f *fakeFind) Sort(_ string) types.Find {
+	return f
+}
+
+// Start is ignored by the fake find
+func (f *fakeFind) Start(_ uint64) types.Find {
+	return f
+}
+
+// Limit is ignored by the fake find
+func (f *fakeFind) Limit(_ uint64) types.Find {
+	return f
+}
+
+// All decodes the docs that match the filter into the result
+func (f *fakeFind) All(_ context.Context, result interface{}) error {
+	docs := make([]bson.M, 0)
+	for _, data := range f.table.db.tables[f.table.name] {
+		if matchFilter(data, f.filter) {
+			docs = append(docs, data)
+		}
+	}
+
+	data, err := bson.Marshal(bson.M{"docs": docs})
+	if err != nil {
+		return err
+	}
+	return bson.Raw(data).Lookup("docs").Unmarshal(result)
+}
+
+// One decodes the first doc that matches the filter into the result
+func (f *fakeFind) One(_ context.Context, result interface{}) error {
+	for _, data := range f.table.db.tables[f.table.name] {
+		if matchFilter(data, f.filter) {
+			raw, err := bson.Marshal(data)
+			if err != nil {
+				return err
+			}
+			return bson.Unmarshal(raw, result)
+		}
+	}
+	return errNotFound
+}
+
+// Count counts the docs that match the filter
+func (f *fakeFind) Count(_ context.Context) (uint64, error) {
+	var count uint64
+	for _, data := range f.table.db.tables[f.table.name] {
+		if matchFilter(data, f.filter) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func matchFilter(data bson.M, filter mapstr.MapStr) bool {
+	for field, value := range filter {
+		cond, ok := value.(mapstr.MapStr)
+		if !ok {
+			if fmt.Sprint(data[field]) != fmt.Sprint(value) {
+				return false
+			}
+			continue
+		}
+
+		matched := false
+		values, _ := json.Marshal(cond[common.BKDBIN])
+		items := make([]interface{}, 0)
+		_ = json.Unmarshal(values, &items)
+		for _, item := range items {
+			if fmt.Sprint(data[field]) == fmt.Sprint(item) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// fakeAuthorizer authorizes all the resources
+type fakeAuthorizer struct {
+	ac.AuthorizeInterface
+}
+
+// AuthorizeBatch authorizes all the resources
+func (f *fakeAuthorizer) AuthorizeBatch(_ context.Context, _ http.Header, _ meta.UserInfo,
+	resources ...meta.ResourceAttribute) ([]sdktypes.Decision, error) {
+
+	decisions := make([]sdktypes.Decision, len(resources))
+	for idx := range decisions {
+		decisions[idx].Authorized = true
+	}
+	return decisions, nil
+}
+
+func newTestService(allowPrivate bool) (*Service, *fakeDB, *restful.Container) {
+	db := &fakeDB{tables: make(map[string][]bson.M)}
+	s := &Service{
+		db:               db,
+		AuthManager:      &extensions.AuthManager{Authorizer: new(fakeAuthorizer)},
+		subscriptionConf: &subscription.Config{AllowPrivateCallback: allowPrivate},
+	}
+
+	utility := rest.NewRestUtility(rest.Config{ErrorIf: ccErr.NewFromCtx(ccErr.EmptyErrorsSetting)})
+	utility.AddHandler(rest.Action{Verb: http.MethodPost, Path: "/create/subscription", Handler: s.CreateSubscription})
+	utility.AddHandler(rest.Action{Verb: http.MethodPut, Path: "/update/subscription/{id}",
+		Handler: s.UpdateSubscription})
+	utility.AddHandler(rest.Action{Verb: http.MethodPost, Path: "/findmany/subscription/{id}/dead_letter",
+		Handler: s.ListSubscriptionDeadLetter})
+
+	ws := new(restful.WebService)
+	utility.AddToRestfulWebService(ws)
+	container := restful.NewContainer()
+	container.Add(ws)
+	return s, db, container
+}
+
+type testResp struct {
+	Result bool            `json:"result"`
+	Code   int             `json:"bk_error_code"`
+	Data   json.RawMessage `json:"data"`
+}
+
+func doRequest(t *testing.T, container *restful.Container, method, path, user string, body interface{}) *testResp {
+	data, _ := json.Marshal(body)
+	req := httptest.NewRequest(method, path, bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(common.BKHTTPHeaderUser, user)
+	req.Header.Set(common.BKHTTPOwnerID, common.BKDefaultOwnerID)
+
+	recorder := httptest.NewRecorder()
+	container.ServeHTTP(recorder, req)
+
+	resp := new(testResp)
+	if err := json.Unmarshal(recorder.Body.Bytes(), resp); err != nil {
+		t.Fatalf("unmarshal response %s failed, err: %v", recorder.Body.String(), err)
+	}
+	return resp
+}
+
+func TestCreateSubscription(t *testing.T) {
+	opt := mapstr.MapStr{
+		"name":         "demo",
+		"bk_resource":  watch.Host,
+		"bk_fields":    []string{common.BKHostIDField},
+		"callback_url": "http://127.0.0.1:8080/callback",
+		"secret":       "secret",
+	}
+
+	_, db, container := newTestService(false)
+	resp := doRequest(t, container, http.MethodPost, "/create/subscription", "admin", opt)
+	if resp.Result || resp.Code != common.CCErrCommParamsIsInvalid {
+		t.Errorf("private callback should be rejected, got code %d", resp.Code)
+	}
+
+	invalidOpt := opt.Clone()
+	delete(invalidOpt, "bk_fields")
+	resp = doRequest(t, container, http.MethodPost, "/create/subscription", "admin", invalidOpt)
+	if resp.Result || resp.Code != common.CCErrCommParamsIsInvalid {
+		t.Errorf("host subscription without fields should be rejected, got code %d", resp.Code)
+	}
+	if len(db.tables[common.BKTableNameEventSubscription]) != 0 {
+		t.Fatalf("invalid subscriptions should not be created")
+	}
+
+	_, db, container = newTestService(true)
+	resp = doRequest(t, container, http.MethodPost, "/create/subscription", "admin", opt)
+	if !resp.Result {
+		t.Fatalf("create subscription failed, code: %d", resp.Code)
+	}
+
+	subs := db.tables[common.BKTableNameEventSubscription]
+	if len(subs) != 1 || subs[0]["creator"] != "admin" || subs[0]["enabled"] != true ||
+		fmt.Sprint(subs[0]["batch_size"]) != fmt.Sprint(watch.DefaultSubscriptionBatchSize) {
+		t.Errorf("unexpected created subscriptions %v", subs)
+	}
+}
+
+func TestUpdateSubscription(t *testing.T) {
+	_, db, container := newTestService(false)
+	sub := &watch.Subscription{ID: 1, Name: "demo", Resource: watch.Host, Fields: []string{common.BKHostIDField},
+		CallbackURL: "https://8.8.8.8/callback", Secret: "secret", Enabled: true,
+		SupplierAccount: common.BKDefaultOwnerID, Creator: "admin"}
+	if err := db.insert(common.BKTableNameEventSubscription, sub); err != nil {
+		t.Fatalf("insert subscription failed, err: %v", err)
+	}
+
+	cases := []struct {
+		name       string
+		path       string
+		user       string
+		opt        mapstr.MapStr
+		expectCode int
+	}{
+		{name: "not the creator", path: "/update/subscription/1", user: "other", opt: mapstr.MapStr{"name": "new"},
+			expectCode: common.CCErrCommAuthNotHavePermission},
+		{name: "not exist", path: "/update/subscription/2", user: "admin", opt: mapstr.MapStr{"name": "new"},
+			expectCode: common.CCErrCommNotFound},
+		{name: "clear the required fields", path: "/update/subscription/1", user: "admin",
+			opt: mapstr.MapStr{"bk_fields": []string{}}, expectCode: common.CCErrCommParamsIsInvalid},
+		{name: "private callback", path: "/update/subscription/1", user: "admin",
+			opt: mapstr.MapStr{"callback_url": "http://10.0.0.1/callback"}, expectCode: common.CCErrCommParamsIsInvalid},
+		{name: "success", path: "/update/subscription/1", user: "admin", opt: mapstr.MapStr{"name": "new"},
+			expectCode: common.CCSuccess},
+	}
+
+	for _, c := range cases {
+		resp := doRequest(t, container, http.MethodPut, c.path, c.user, c.opt)
+		if resp.Code != c.expectCode {
+			t.Errorf("%s: expect code %d, got %d", c.name, c.expectCode, resp.Code)
+		}
+	}
+
+	data := db.tables[common.BKTableNameEventSubscription][0]
+	if data["name"] != "new" || data["callback_url"] != sub.CallbackURL || data["modifier"] != "admin" {
+		t.Errorf("unexpected updated subscription %v", data)
+	}
+}
+
+func TestListSubscriptionDeadLetter(t *testing.T) {
+	_, db, container := newTestService(false)
+	for id := int64(1); id <= 2; id++ {
+		sub := &watch.Subscription{ID: id, Resource: watch.Host, SupplierAccount: common.BKDefaultOwnerID,
+			Creator: "admin"}
+		if err := db.insert(common.BKTableNameEventSubscription, sub); err != nil {
+			t.Fatalf("insert subscription failed, err: %v", err)
+		}
+		deadLetter := &watch.SubscriptionDeadLetter{ID: id, SubscriptionID: id, Resource: watch.Host,
+			StartCursor: "a", EndCursor: "b", SupplierAccount: common.BKDefaultOwnerID}
+		if err := db.insert(common.BKTableNameSubscriptionDeadLetter, deadLetter); err != nil {
+			t.Fatalf("insert dead letter failed, err: %v", err)
+		}
+	}
+
+	page := mapstr.MapStr{"page": mapstr.MapStr{"limit": 10}}
+	resp := doRequest(t, container, http.MethodPost, "/findmany/subscription/1/dead_letter", "other", page)
+	if resp.Code != common.CCErrCommAuthNotHavePermission {
+		t.Errorf("the dead letters of others should not be listed, got code %d", resp.Code)
+	}
+
+	resp = doRequest(t, container, http.MethodPost, "/findmany/subscription/1/dead_letter", "admin", page)
+	if !resp.Result {
+		t.Fatalf("list dead letters failed, code: %d", resp.Code)
+	}
+
+	result := new(watch.ListDeadLetterResult)
+	if err := json.Unmarshal(resp.Data, result); err != nil {
+		t.Fatalf("unmarshal dead letters failed, err: %v", err)
+	}
+	if len(result.Info) != 1 || result.Info[0].SubscriptionID != 1 {
+		t.Errorf("expect the dead letter of subscription 1, got %+v", result.Info)
+	}
+}
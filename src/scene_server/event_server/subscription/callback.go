@@ -0,0 +1,88 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package subscription
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+)
+
+// CheckCallbackURL check if the host of the callback url resolves to the addresses that are allowed to push to
+func CheckCallbackURL(ctx context.Context, callback string, allowPrivate bool) error {
+	if allowPrivate {
+		return nil
+	}
+
+	u, err := url.Parse(callback)
+	if err != nil {
+		return fmt.Errorf("callback_url is invalid, err: %v", err)
+	}
+
+	host := u.Hostname()
+	ips := make([]net.IP, 0)
+	if ip := net.ParseIP(host); ip != nil {
+		ips = append(ips, ip)
+	} else {
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return fmt.Errorf("resolve callback_url host %s failed, err: %v", host, err)
+		}
+		for _, addr := range addrs {
+			ips = append(ips, addr.IP)
+		}
+	}
+
+	for _, ip := range ips {
+		if isPrivateIP(ip) {
+			return fmt.Errorf("callback_url host %s is the private address %s, which is not allowed", host, ip)
+		}
+	}
+	return nil
+}
+
+// isPrivateIP check if the ip is a loopback, link-local, private or unspecified address
+func isPrivateIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsPrivate() || ip.IsUnspecified()
+}
+
+// rejectPrivateAddress rejects the connections to the private addresses, it checks the resolved address before
+// connecting, so that the callback host can not be changed to resolve to a private address after it is validated
+func rejectPrivateAddress(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || isPrivateIP(ip) {
+		return fmt.Errorf("pushing events to the private address %s is not allowed", host)
+	}
+	return nil
+}
+
+// newHTTPClient create the http client to push events to the callbacks
+func newHTTPClient(conf *Config) *http.Client {
+	dialer := &net.Dialer{Timeout: conf.Timeout}
+	if !conf.AllowPrivateCallback {
+		dialer.Control = rejectPrivateAddress
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: conf.Timeout, Transport: transport}
+}
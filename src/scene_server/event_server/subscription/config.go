@@ -0,0 +1,106 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package subscription
+
+import (
+	"time"
+
+	cc "configcenter/src/common/backbone/configcenter"
+	"configcenter/src/common/blog"
+)
+
+const (
+	defaultMaxRetry      = 5
+	defaultRetryInterval = time.Second
+	defaultTimeout       = 10 * time.Second
+)
+
+// Config event subscription push config
+type Config struct {
+	// StartUp defines whether to push the subscribed events to the callbacks
+	StartUp bool
+	// MaxRetry is the max retry times when the events are failed to push
+	MaxRetry int
+	// RetryInterval is the interval of the first retry, it is doubled for each of the following retries
+	RetryInterval time.Duration
+	// Timeout is the timeout of the push request
+	Timeout time.Duration
+	// AllowPrivateCallback defines whether the callbacks can be loopback, link-local or private addresses, they are
+	// not allowed by default so that the subscriptions can not be used to access the internal services
+	AllowPrivateCallback bool
+}
+
+// ParseConfig parse event subscription push config, use the default value if the config is not set
+func ParseConfig() (*Config, error) {
+	conf := &Config{
+		StartUp:       true,
+		MaxRetry:      defaultMaxRetry,
+		RetryInterval: defaultRetryInterval,
+		Timeout:       defaultTimeout,
+	}
+
+	var err error
+	if cc.IsExist("eventServer.subscription.startUp") {
+		conf.StartUp, err = cc.Bool("eventServer.subscription.startUp")
+		if err != nil {
+			blog.Errorf("get eventServer.subscription.startUp error, err: %v", err)
+			return nil, err
+		}
+	}
+
+	if cc.IsExist("eventServer.subscription.maxRetry") {
+		conf.MaxRetry, err = cc.Int("eventServer.subscription.maxRetry")
+		if err != nil {
+			blog.Errorf("get eventServer.subscription.maxRetry error, err: %v", err)
+			return nil, err
+		}
+
+		if conf.MaxRetry < 0 {
+			conf.MaxRetry = 0
+		}
+	}
+
+	if cc.IsExist("eventServer.subscription.retryIntervalSeconds") {
+		interval, err := cc.Int("eventServer.subscription.retryIntervalSeconds")
+		if err != nil {
+			blog.Errorf("get eventServer.subscription.retryIntervalSeconds error, err: %v", err)
+			return nil, err
+		}
+
+		if interval > 0 {
+			conf.RetryInterval = time.Duration(interval) * time.Second
+		}
+	}
+
+	if cc.IsExist("eventServer.subscription.timeoutSeconds") {
+		timeout, err := cc.Int("eventServer.subscription.timeoutSeconds")
+		if err != nil {
+			blog.Errorf("get eventServer.subscription.timeoutSeconds error, err: %v", err)
+			return nil, err
+		}
+
+		if timeout > 0 {
+			conf.Timeout = time.Duration(timeout) * time.Second
+		}
+	}
+
+	if cc.IsExist("eventServer.subscription.allowPrivateCallback") {
+		conf.AllowPrivateCallback, err = cc.Bool("eventServer.subscription.allowPrivateCallback")
+		if err != nil {
+			blog.Errorf("get eventServer.subscription.allowPrivateCallback error, err: %v", err)
+			return nil, err
+		}
+	}
+
+	return conf, nil
+}
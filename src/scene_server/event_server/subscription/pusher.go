@@ -0,0 +1,477 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package subscription pushes the subscribed resource watch events to the subscription callbacks
+package subscription
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"configcenter/src/ac"
+	"configcenter/src/common"
+	"configcenter/src/common/backbone"
+	"configcenter/src/common/blog"
+	"configcenter/src/common/mapstr"
+	"configcenter/src/common/metadata"
+	"configcenter/src/common/util"
+	"configcenter/src/common/watch"
+	"configcenter/src/storage/dal"
+)
+
+const (
+	// syncInterval is the interval to sync the enabled subscriptions to start or stop their push loop
+	syncInterval = 10 * time.Second
+	// maxRetryInterval is the max interval between two retries
+	maxRetryInterval = time.Minute
+	// maxErrorLength is the max length of the delivery error that is saved
+	maxErrorLength = 512
+	// authorizeInterval is the interval to authorize the creator of the subscription again
+	authorizeInterval = time.Minute
+)
+
+// watchAuthorizer authorizes if the user can watch the resource
+type watchAuthorizer interface {
+	AuthorizeWatchResource(ctx context.Context, header http.Header, resource watch.CursorType,
+		subResource string) error
+}
+
+// Pusher watches the events of all the enabled subscriptions and pushes them to the callbacks,
+// it only works on the master event server.
+type Pusher struct {
+	ctx    context.Context
+	engine *backbone.Engine
+	db     dal.RDB
+	conf   *Config
+	client *http.Client
+	// authorizer is used to check if the creator of the subscription can still watch the subscribed resource
+	authorizer watchAuthorizer
+
+	// running is the cancel function of the push loop of the subscriptions, key is the subscription id
+	running map[int64]context.CancelFunc
+	lock    sync.Mutex
+}
+
+// NewPusher create a new subscription event pusher
+func NewPusher(ctx context.Context, engine *backbone.Engine, db dal.RDB, conf *Config,
+	authorizer watchAuthorizer) *Pusher {
+
+	return &Pusher{
+		ctx:        ctx,
+		engine:     engine,
+		db:         db,
+		conf:       conf,
+		client:     newHTTPClient(conf),
+		authorizer: authorizer,
+		running:    make(map[int64]context.CancelFunc),
+	}
+}
+
+// Run loop sync the enabled subscriptions, start the push loop for the new ones and stop it for the removed ones
+func (p *Pusher) Run() {
+	for {
+		select {
+		case <-p.ctx.Done():
+			p.stopAll()
+			return
+		default:
+		}
+
+		if !p.engine.Discovery().IsMaster() {
+			blog.V(4).Infof("loop push subscription events, but not master, skip.")
+			p.stopAll()
+			time.Sleep(syncInterval)
+			continue
+		}
+
+		ids, err := p.listEnabledSubscriptionIDs()
+		if err != nil {
+			blog.Errorf("list enabled subscriptions failed, err: %v", err)
+			time.Sleep(syncInterval)
+			continue
+		}
+
+		p.sync(ids)
+		time.Sleep(syncInterval)
+	}
+}
+
+func (p *Pusher) listEnabledSubscriptionIDs() (map[int64]struct{}, error) {
+	subs := make([]watch.Subscription, 0)
+	filter := mapstr.MapStr{"enabled": true}
+	err := p.db.Table(common.BKTableNameEventSubscription).Find(filter).Fields(common.BKFieldID).All(p.ctx, &subs)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[int64]struct{}, len(subs))
+	for _, sub := range subs {
+		ids[sub.ID] = struct{}{}
+	}
+	return ids, nil
+}
+
+func (p *Pusher) sync(ids map[int64]struct{}) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	for id, cancel := range p.running {
+		if _, exists := ids[id]; !exists {
+			blog.Infof("subscription %d is disabled or deleted, stop pushing its events", id)
+			cancel()
+			delete(p.running, id)
+		}
+	}
+
+	for id := range ids {
+		if _, exists := p.running[id]; exists {
+			continue
+		}
+
+		blog.Infof("start pushing events of subscription %d", id)
+		ctx, cancel := context.WithCancel(p.ctx)
+		p.running[id] = cancel
+		go p.loopPush(ctx, id)
+	}
+}
+
+func (p *Pusher) stopAll() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	for id, cancel := range p.running {
+		cancel()
+		delete(p.running, id)
+	}
+}
+
+// loopPush watches and pushes the events of the subscription until the loop is canceled
+func (p *Pusher) loopPush(ctx context.Context, id int64) {
+	failCount := 0
+	var authorizeTime time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		// get the latest subscription every time, so that the updated config takes effect immediately
+		sub := new(watch.Subscription)
+		filter := mapstr.MapStr{common.BKFieldID: id}
+		if err := p.db.Table(common.BKTableNameEventSubscription).Find(filter).One(ctx, sub); err != nil {
+			if !p.db.IsNotFoundError(err) {
+				blog.Errorf("get subscription %d failed, err: %v", id, err)
+			}
+			failCount++
+			sleep(ctx, retryInterval(time.Second, failCount))
+			continue
+		}
+
+		if !sub.Enabled {
+			// wait to be stopped by the sync loop
+			sleep(ctx, syncInterval)
+			continue
+		}
+
+		// the events are watched as system operator, so the creator is authorized again periodically
+		if time.Since(authorizeTime) > authorizeInterval {
+			authorized, err := p.authorize(ctx, sub)
+			if err != nil {
+				failCount++
+				sleep(ctx, retryInterval(time.Second, failCount))
+				continue
+			}
+			if !authorized {
+				sleep(ctx, authorizeInterval)
+				continue
+			}
+			authorizeTime = time.Now()
+		}
+
+		if err := p.push(ctx, sub); err != nil {
+			failCount++
+			sleep(ctx, retryInterval(time.Second, failCount))
+			continue
+		}
+		failCount = 0
+	}
+}
+
+// authorize if the creator of the subscription still has the authority to watch the subscribed resource, the events
+// are not pushed until the creator is authorized again. returns false if the creator is not authorized.
+func (p *Pusher) authorize(ctx context.Context, sub *watch.Subscription) (bool, error) {
+	header, rid := newHeaderWithRid(sub.SupplierAccount)
+	header.Set(common.BKHTTPHeaderUser, sub.Creator)
+	ctx = context.WithValue(ctx, common.ContextRequestIDField, rid)
+
+	err := p.authorizer.AuthorizeWatchResource(ctx, header, sub.Resource, sub.Filter.SubResource)
+	if err == nil {
+		return true, nil
+	}
+
+	if err != ac.NoAuthorizeError {
+		blog.Errorf("authorize subscription %d creator %s failed, err: %v, rid: %s", sub.ID, sub.Creator, err, rid)
+		return false, err
+	}
+
+	blog.Errorf("subscription %d creator %s has no permission to watch %s, stop pushing events, rid: %s", sub.ID,
+		sub.Creator, sub.Resource, rid)
+	errMsg := fmt.Sprintf("creator %s has no permission to watch the subscribed resource", sub.Creator)
+	if sub.Status.LastError == errMsg {
+		return false, nil
+	}
+	return false, p.updateSubscription(ctx, sub.ID, mapstr.MapStr{"delivery_status.last_error": errMsg})
+}
+
+// push watches the events of the subscription from its cursor, and pushes them in batches
+func (p *Pusher) push(ctx context.Context, sub *watch.Subscription) error {
+	header, rid := newHeaderWithRid(sub.SupplierAccount)
+
+	resp, ccErr := p.engine.CoreAPI.CacheService().Cache().Event().WatchEvent(ctx, header, sub.WatchOptions())
+	if ccErr != nil {
+		if ccErr.GetCode() == common.CCErrEventChainNodeNotExist {
+			// the cursor is expired, reset it to watch from now
+			blog.Errorf("subscription %d cursor %s is not exist, reset it to watch from now, rid: %s", sub.ID,
+				sub.Cursor, rid)
+			return p.updateSubscription(ctx, sub.ID, mapstr.MapStr{"bk_cursor": "", "bk_start_from": 0})
+		}
+
+		blog.Errorf("watch subscription %d events failed, err: %v, rid: %s", sub.ID, ccErr, rid)
+		return ccErr
+	}
+
+	watchResp := new(watch.WatchResp)
+	if err := json.Unmarshal([]byte(*resp), watchResp); err != nil {
+		blog.Errorf("unmarshal subscription %d watch response %s failed, err: %v, rid: %s", sub.ID, *resp, err, rid)
+		return err
+	}
+
+	if len(watchResp.Events) == 0 {
+		return nil
+	}
+
+	if !watchResp.Watched {
+		// no event is watched, save the returned latest cursor so that the next watch starts from it
+		cursor := watchResp.Events[0].Cursor
+		if len(cursor) == 0 || cursor == sub.Cursor {
+			return nil
+		}
+		return p.updateSubscription(ctx, sub.ID, mapstr.MapStr{"bk_cursor": cursor})
+	}
+
+	batchSize := sub.BatchSize
+	if batchSize <= 0 {
+		batchSize = watch.DefaultSubscriptionBatchSize
+	}
+
+	for start := 0; start < len(watchResp.Events); start += batchSize {
+		end := start + batchSize
+		if end > len(watchResp.Events) {
+			end = len(watchResp.Events)
+		}
+
+		if err := p.pushBatch(ctx, sub, watchResp.Events[start:end], rid); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// pushBatch pushes a batch of events to the callback with retry, the batch is saved as a dead letter if all
+// the retries are failed. the cursor is moved to the last event of the batch after the batch is handled.
+func (p *Pusher) pushBatch(ctx context.Context, sub *watch.Subscription, events []*watch.WatchEventDetail,
+	rid string) error {
+
+	payload := &watch.SubscriptionPayload{
+		SubscriptionID: sub.ID,
+		Resource:       sub.Resource,
+		Events:         events,
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		blog.Errorf("marshal subscription %d payload failed, err: %v, rid: %s", sub.ID, err, rid)
+		return err
+	}
+
+	retryTimes, deliverErr := p.deliverWithRetry(ctx, sub, body, rid)
+	if deliverErr != nil && ctx.Err() != nil {
+		// the push loop is canceled, do not move the cursor so that the events will be pushed again
+		return ctx.Err()
+	}
+
+	now := metadata.Now()
+	cursor := events[len(events)-1].Cursor
+	sub.Cursor = cursor
+	sub.Status.LastDeliveryTime = &now
+
+	if deliverErr == nil {
+		sub.Status.LastSuccessTime = &now
+		sub.Status.LastError = ""
+		sub.Status.FailedTimes = 0
+		sub.Status.DeliveredCount += int64(len(events))
+	} else {
+		errMsg := deliverErr.Error()
+		if len(errMsg) > maxErrorLength {
+			errMsg = errMsg[:maxErrorLength]
+		}
+		sub.Status.LastError = errMsg
+		sub.Status.FailedTimes++
+		sub.Status.DeadLetterCount += int64(len(events))
+
+		if err := p.saveDeadLetter(ctx, sub, events, string(body), errMsg, retryTimes); err != nil {
+			blog.Errorf("save subscription %d dead letter failed, err: %v, rid: %s", sub.ID, err, rid)
+			return err
+		}
+	}
+
+	update := mapstr.MapStr{
+		"bk_cursor":       cursor,
+		"delivery_status": sub.Status,
+	}
+	return p.updateSubscription(ctx, sub.ID, update)
+}
+
+// deliverWithRetry pushes the payload to the callback, retry with exponential backoff if it is failed,
+// returns the retry times and the last error.
+func (p *Pusher) deliverWithRetry(ctx context.Context, sub *watch.Subscription, body []byte, rid string) (int,
+	error) {
+
+	var err error
+	for retry := 0; retry <= p.conf.MaxRetry; retry++ {
+		if retry > 0 {
+			if !sleep(ctx, retryInterval(p.conf.RetryInterval, retry)) {
+				return retry - 1, err
+			}
+		}
+
+		err = p.deliver(ctx, sub, body)
+		if err == nil {
+			return retry, nil
+		}
+		blog.Errorf("push events to subscription %d callback failed, retry: %d, err: %v, rid: %s", sub.ID, retry,
+			err, rid)
+	}
+
+	return p.conf.MaxRetry, err
+}
+
+func (p *Pusher) deliver(ctx context.Context, sub *watch.Subscription, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.CallbackURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(watch.SubscriptionIDHeader, strconv.FormatInt(sub.ID, 10))
+	req.Header.Set(watch.SubscriptionTimestampHeader, timestamp)
+	req.Header.Set(watch.SubscriptionSignatureHeader, Sign(sub.Secret, timestamp, body))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		respBody, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorLength))
+		return fmt.Errorf("callback response status code %d, body: %s", resp.StatusCode, respBody)
+	}
+	return nil
+}
+
+func (p *Pusher) saveDeadLetter(ctx context.Context, sub *watch.Subscription, events []*watch.WatchEventDetail,
+	payload, errMsg string, retryTimes int) error {
+
+	id, err := p.db.NextSequence(ctx, common.BKTableNameSubscriptionDeadLetter)
+	if err != nil {
+		return err
+	}
+
+	deadLetter := &watch.SubscriptionDeadLetter{
+		ID:              int64(id),
+		SubscriptionID:  sub.ID,
+		Resource:        sub.Resource,
+		StartCursor:     events[0].Cursor,
+		EndCursor:       events[len(events)-1].Cursor,
+		Payload:         payload,
+		Error:           errMsg,
+		RetryTimes:      retryTimes,
+		SupplierAccount: sub.SupplierAccount,
+		CreateTime:      metadata.Now(),
+	}
+	return p.db.Table(common.BKTableNameSubscriptionDeadLetter).Insert(ctx, deadLetter)
+}
+
+func (p *Pusher) updateSubscription(ctx context.Context, id int64, update mapstr.MapStr) error {
+	filter := mapstr.MapStr{common.BKFieldID: id}
+	if err := p.db.Table(common.BKTableNameEventSubscription).Update(ctx, filter, update); err != nil {
+		blog.Errorf("update subscription %d failed, data: %+v, err: %v", id, update, err)
+		return err
+	}
+	return nil
+}
+
+// Sign calculates the hmac-sha256 signature of the pushed payload in hex format
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func newHeaderWithRid(supplierAccount string) (http.Header, string) {
+	header := http.Header{}
+	header.Add(common.BKHTTPOwnerID, supplierAccount)
+	header.Add(common.BKHTTPHeaderUser, common.CCSystemOperatorUserName)
+	rid := util.GenerateRID()
+	header.Add(common.BKHTTPCCRequestID, rid)
+	return header, rid
+}
+
+// retryInterval returns the exponential backoff interval of the retry times
+func retryInterval(base time.Duration, times int) time.Duration {
+	interval := base
+	for i := 1; i < times && interval < maxRetryInterval; i++ {
+		interval *= 2
+	}
+
+	if interval > maxRetryInterval {
+		interval = maxRetryInterval
+	}
+	return interval
+}
+
+// sleep for the duration, returns false if the context is done
+func sleep(ctx context.Context, duration time.Duration) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(duration):
+		return true
+	}
+}
@@ -0,0 +1,300 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package subscription
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"configcenter/src/ac"
+	"configcenter/src/common"
+	"configcenter/src/common/mapstr"
+	"configcenter/src/common/watch"
+	"configcenter/src/storage/dal"
+	"configcenter/src/storage/dal/types"
+)
+
+func TestSign(t *testing.T) {
+	expect := "bc93d472738d1fb40c2b3d3b61fcf0f7fb8a8dc24e06e885498d0d90872effaa"
+	if got := Sign("secret", "1665000000", []byte(`{"subscription_id":1}`)); got != expect {
+		t.Errorf("expect signature %s, got %s", expect, got)
+	}
+
+	if Sign("other", "1665000000", []byte(`{"subscription_id":1}`)) == expect {
+		t.Errorf("signature should be different with different secret")
+	}
+	if Sign("secret", "1665000001", []byte(`{"subscription_id":1}`)) == expect {
+		t.Errorf("signature should be different with different timestamp")
+	}
+}
+
+func TestRetryInterval(t *testing.T) {
+	cases := []struct {
+		base   time.Duration
+		times  int
+		expect time.Duration
+	}{
+		{base: time.Second, times: 1, expect: time.Second},
+		{base: time.Second, times: 2, expect: 2 * time.Second},
+		{base: time.Second, times: 4, expect: 8 * time.Second},
+		{base: time.Second, times: 10, expect: maxRetryInterval},
+		{base: 2 * maxRetryInterval, times: 1, expect: maxRetryInterval},
+	}
+
+	for _, c := range cases {
+		if got := retryInterval(c.base, c.times); got != c.expect {
+			t.Errorf("base %s times %d, expect %s, got %s", c.base, c.times, c.expect, got)
+		}
+	}
+}
+
+func TestCheckCallbackURL(t *testing.T) {
+	cases := []struct {
+		callback     string
+		allowPrivate bool
+		hasErr       bool
+	}{
+		{callback: "http://127.0.0.1:8080/callback", hasErr: true},
+		{callback: "http://[::1]/callback", hasErr: true},
+		{callback: "http://10.0.0.1/callback", hasErr: true},
+		{callback: "http://192.168.1.1/callback", hasErr: true},
+		{callback: "http://169.254.169.254/latest/meta-data", hasErr: true},
+		{callback: "http://0.0.0.0/callback", hasErr: true},
+		{callback: "https://8.8.8.8/callback", hasErr: false},
+		{callback: "http://127.0.0.1:8080/callback", allowPrivate: true, hasErr: false},
+	}
+
+	for _, c := range cases {
+		err := CheckCallbackURL(context.Background(), c.callback, c.allowPrivate)
+		if c.hasErr != (err != nil) {
+			t.Errorf("callback %s allow private %v, unexpected err: %v", c.callback, c.allowPrivate, err)
+		}
+	}
+}
+
+func TestHTTPClientRejectPrivateAddress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	// the loopback test server can not be connected unless the private callbacks are allowed
+	if _, err := newHTTPClient(&Config{Timeout: time.Second}).Get(server.URL); err == nil {
+		t.Errorf("push to loopback address should be rejected")
+	}
+
+	resp, err := newHTTPClient(&Config{Timeout: time.Second, AllowPrivateCallback: true}).Get(server.URL)
+	if err != nil {
+		t.Fatalf("push to loopback address should be allowed, err: %v", err)
+	}
+	resp.Body.Close()
+
+	if err := rejectPrivateAddress("tcp", net.JoinHostPort("8.8.8.8", "443"), nil); err != nil {
+		t.Errorf("public address should not be rejected, err: %v", err)
+	}
+}
+
+// fakeUpdate is an update of the fake db
+type fakeUpdate struct {
+	table  string
+	filter mapstr.MapStr
+	doc    mapstr.MapStr
+}
+
+// fakeDB is an in-memory db that records the inserted and updated data of the pusher
+type fakeDB struct {
+	dal.RDB
+	sequence uint64
+	inserted []interface{}
+	updates  []fakeUpdate
+}
+
+// Table returns the fake table of the collection
+func (db *fakeDB) Table(collection string) types.Table {
+	return &fakeTable{db: db, name: collection}
+}
+
+// NextSequence returns the next id
+func (db *fakeDB) NextSequence(_ context.Context, _ string) (uint64, error) {
+	db.sequence++
+	return db.sequence, nil
+}
+
+type fakeTable struct {
+	types.Table
+	db   *fakeDB
+	name string
+}
+
+// Insert records the inserted doc
+func (t *fakeTable) Insert(_ context.Context, doc interface{}) error {
+	t.db.inserted = append(t.db.inserted, doc)
+	return nil
+}
+
+// Update records the update
+func (t *fakeTable) Update(_ context.Context, filter types.Filter, doc interface{}) error {
+	t.db.updates = append(t.db.updates, fakeUpdate{table: t.name, filter: filter.(mapstr.MapStr),
+		doc: doc.(mapstr.MapStr)})
+	return nil
+}
+
+func newTestEvents(cursors ...string) []*watch.WatchEventDetail {
+	events := make([]*watch.WatchEventDetail, len(cursors))
+	for idx, cursor := range cursors {
+		events[idx] = &watch.WatchEventDetail{Cursor: cursor, Resource: watch.Host, EventType: watch.Create}
+	}
+	return events
+}
+
+func TestPushBatch(t *testing.T) {
+	cases := []struct {
+		name string
+		// failTimes is the times that the callback fails before it succeeds
+		failTimes int32
+		// expectRequests is the number of requests that the callback receives
+		expectRequests int32
+		expectDead     bool
+	}{
+		{name: "success", failTimes: 0, expectRequests: 1},
+		{name: "success after retry", failTimes: 2, expectRequests: 3},
+		{name: "dead letter after all retries", failTimes: 10, expectRequests: 4, expectDead: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var requests int32
+			var signErr error
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := ioutil.ReadAll(r.Body)
+				sign := Sign("secret", r.Header.Get(watch.SubscriptionTimestampHeader), body)
+				if sign != r.Header.Get(watch.SubscriptionSignatureHeader) ||
+					r.Header.Get(watch.SubscriptionIDHeader) != "1" {
+					signErr = errors.New("the pushed request is not signed correctly")
+				}
+				if atomic.AddInt32(&requests, 1) <= c.failTimes {
+					w.WriteHeader(http.StatusInternalServerError)
+				}
+			}))
+			defer server.Close()
+
+			db := new(fakeDB)
+			conf := &Config{MaxRetry: 3, RetryInterval: time.Millisecond, Timeout: time.Second,
+				AllowPrivateCallback: true}
+			p := &Pusher{db: db, conf: conf, client: newHTTPClient(conf)}
+			sub := &watch.Subscription{ID: 1, Resource: watch.Host, CallbackURL: server.URL, Secret: "secret"}
+
+			if err := p.pushBatch(context.Background(), sub, newTestEvents("a", "b"), "test"); err != nil {
+				t.Fatalf("push batch failed, err: %v", err)
+			}
+			if signErr != nil {
+				t.Error(signErr)
+			}
+			if requests != c.expectRequests {
+				t.Errorf("expect %d requests, got %d", c.expectRequests, requests)
+			}
+
+			if len(db.updates) != 1 || db.updates[0].doc["bk_cursor"] != "b" {
+				t.Fatalf("cursor should be moved to the last event, updates: %+v", db.updates)
+			}
+			status := db.updates[0].doc["delivery_status"].(watch.DeliveryStatus)
+
+			if !c.expectDead {
+				if len(db.inserted) != 0 || status.DeliveredCount != 2 || status.FailedTimes != 0 ||
+					status.LastSuccessTime == nil {
+					t.Errorf("unexpected dead letters %+v or status %+v", db.inserted, status)
+				}
+				return
+			}
+
+			if len(db.inserted) != 1 {
+				t.Fatalf("expect 1 dead letter, got %d", len(db.inserted))
+			}
+			deadLetter := db.inserted[0].(*watch.SubscriptionDeadLetter)
+			if deadLetter.SubscriptionID != 1 || deadLetter.StartCursor != "a" || deadLetter.EndCursor != "b" ||
+				deadLetter.RetryTimes != conf.MaxRetry || deadLetter.Error == "" {
+				t.Errorf("unexpected dead letter %+v", deadLetter)
+			}
+			if status.DeadLetterCount != 2 || status.FailedTimes != 1 || status.LastSuccessTime != nil {
+				t.Errorf("unexpected status %+v", status)
+			}
+		})
+	}
+}
+
+func TestPushBatchCanceled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	db := new(fakeDB)
+	conf := &Config{MaxRetry: 3, RetryInterval: time.Minute, Timeout: time.Second, AllowPrivateCallback: true}
+	p := &Pusher{db: db, conf: conf, client: newHTTPClient(conf)}
+	sub := &watch.Subscription{ID: 1, Resource: watch.Host, CallbackURL: server.URL, Secret: "secret"}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := p.pushBatch(ctx, sub, newTestEvents("a"), "test"); err == nil {
+		t.Errorf("push batch should fail when it is canceled")
+	}
+	if len(db.updates) != 0 || len(db.inserted) != 0 {
+		t.Errorf("cursor should not be moved when it is canceled, updates: %+v, inserted: %+v", db.updates,
+			db.inserted)
+	}
+}
+
+// fakeAuthorizer authorizes the users in the authorized map
+type fakeAuthorizer struct {
+	authorized map[string]bool
+}
+
+// AuthorizeWatchResource authorizes the user in the header
+func (f *fakeAuthorizer) AuthorizeWatchResource(_ context.Context, header http.Header, _ watch.CursorType,
+	_ string) error {
+
+	if f.authorized[header.Get(common.BKHTTPHeaderUser)] {
+		return nil
+	}
+	return ac.NoAuthorizeError
+}
+
+func TestAuthorize(t *testing.T) {
+	db := new(fakeDB)
+	p := &Pusher{db: db, authorizer: &fakeAuthorizer{authorized: map[string]bool{"admin": true}}}
+
+	authorized, err := p.authorize(context.Background(), &watch.Subscription{ID: 1, Creator: "admin"})
+	if err != nil || !authorized || len(db.updates) != 0 {
+		t.Errorf("creator should be authorized, authorized: %v, err: %v", authorized, err)
+	}
+
+	sub := &watch.Subscription{ID: 2, Creator: "user"}
+	authorized, err = p.authorize(context.Background(), sub)
+	if err != nil || authorized {
+		t.Fatalf("creator should not be authorized, authorized: %v, err: %v", authorized, err)
+	}
+	if len(db.updates) != 1 || db.updates[0].doc["delivery_status.last_error"] == "" {
+		t.Fatalf("the no permission error should be saved, updates: %+v", db.updates)
+	}
+
+	// the same error is not saved again
+	sub.Status.LastError = db.updates[0].doc["delivery_status.last_error"].(string)
+	if authorized, err = p.authorize(context.Background(), sub); err != nil || authorized || len(db.updates) != 1 {
+		t.Errorf("the same no permission error should not be saved again, updates: %+v", db.updates)
+	}
+}
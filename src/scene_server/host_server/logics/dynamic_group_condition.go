@@ -0,0 +1,390 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logics
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"configcenter/src/common"
+	"configcenter/src/common/blog"
+	"configcenter/src/common/dynamicgroup"
+	"configcenter/src/common/http/rest"
+	"configcenter/src/common/mapstr"
+	"configcenter/src/common/metadata"
+	"configcenter/src/common/util"
+)
+
+// ExecuteDynamicGroup executes the dynamic group with its conditions, filter expressions and sub groups.
+func (lgc *Logics) ExecuteDynamicGroup(kit *rest.Kit, group *metadata.DynamicGroup, fields []string,
+	page metadata.BasePage, disableCounter bool) (*metadata.InstDataInfo, error) {
+
+	visited := map[string]struct{}{group.ID: {}}
+	return lgc.executeDynamicGroup(kit, group, fields, page, disableCounter, visited)
+}
+
+// executeDynamicGroup executes the dynamic group, visited is the dynamic group ids in the current nesting path,
+// which is used to find out the cyclic references.
+func (lgc *Logics) executeDynamicGroup(kit *rest.Kit, group *metadata.DynamicGroup, fields []string,
+	page metadata.BasePage, disableCounter bool, visited map[string]struct{}) (*metadata.InstDataInfo, error) {
+
+	searchConditions, filter, err := lgc.parseDynamicGroupCond(kit, group, visited)
+	if err != nil {
+		return nil, err
+	}
+
+	switch group.ObjID {
+	case common.BKInnerObjIDHost:
+		searchHostCondition := metadata.HostCommonSearch{AppID: group.AppID, Condition: searchConditions, Page: page}
+		data, err := lgc.ExecuteHostDynamicGroup(kit, &searchHostCondition, filter, fields, disableCounter)
+		if err != nil {
+			return nil, err
+		}
+		return &metadata.InstDataInfo{Count: data.Count, Info: data.Info}, nil
+
+	case common.BKInnerObjIDSet:
+		searchSetCondition := metadata.SetCommonSearch{AppID: group.AppID, Condition: searchConditions, Page: page}
+		return lgc.ExecuteSetDynamicGroup(kit, &searchSetCondition, filter, fields, disableCounter)
+
+	default:
+		blog.Errorf("unknown dynamic group object type %s, id: %s, rid: %s", group.ObjID, group.ID, kit.Rid)
+		return nil, kit.CCError.CCErrorf(common.CCErrCommParamsIsInvalid, common.BKObjIDField)
+	}
+}
+
+// parseDynamicGroupCond parses the dynamic group to the legacy search conditions and the mongo filter of the
+// group's object. filter expressions of other objects are converted to the instance id conditions, and sub
+// groups are converted to the member id conditions.
+func (lgc *Logics) parseDynamicGroupCond(kit *rest.Kit, group *metadata.DynamicGroup,
+	visited map[string]struct{}) ([]metadata.SearchCondition, mapstr.MapStr, error) {
+
+	searchConditions := make([]metadata.SearchCondition, 0)
+	filters := make([]interface{}, 0)
+
+	for _, cond := range group.Info.Condition {
+		searchCondition := metadata.SearchCondition{ObjectID: cond.ObjID, Condition: []metadata.ConditionItem{}}
+		for _, item := range cond.Condition {
+			condItem := metadata.ConditionItem{Field: item.Field, Operator: item.Operator, Value: item.Value}
+			searchCondition.Condition = append(searchCondition.Condition, condItem)
+		}
+		searchCondition.TimeCondition = cond.TimeCondition
+
+		if cond.Filter != nil {
+			mgoFilter, err := cond.Filter.ToMgo()
+			if err != nil {
+				blog.Errorf("parse %s filter failed, err: %v, group: %s, rid: %s", cond.ObjID, err, group.ID, kit.Rid)
+				return nil, nil, kit.CCError.CCErrorf(common.CCErrCommParamsIsInvalid, "info.condition.filter")
+			}
+
+			if cond.ObjID == group.ObjID {
+				filters = append(filters, mgoFilter)
+			} else {
+				ids, err := lgc.getInstIDsByFilter(kit, group.AppID, cond.ObjID, mgoFilter)
+				if err != nil {
+					return nil, nil, err
+				}
+				searchCondition.Condition = append(searchCondition.Condition, metadata.ConditionItem{
+					Field:    common.GetInstIDField(cond.ObjID),
+					Operator: common.BKDBIN,
+					Value:    ids,
+				})
+			}
+		}
+		searchConditions = append(searchConditions, searchCondition)
+	}
+
+	idField := common.GetInstIDField(group.ObjID)
+	if group.ObjID == common.BKInnerObjIDHost && len(group.Info.SubGroups) > 0 {
+		// host sub groups are evaluated by the evaluator shared with the dynamic group member event
+		evaluator := dynamicgroup.NewEvaluator(&dynamicGroupGetter{lgc: lgc, kit: kit})
+		ids, err := evaluator.MatchSubGroups(kit.Ctx, group, nil)
+		if err != nil {
+			if errors.Is(err, dynamicgroup.ErrInvalidSubGroup) {
+				blog.Errorf("dynamic group %s has invalid sub group, err: %v, rid: %s", group.ID, err, kit.Rid)
+				return nil, nil, kit.CCError.CCErrorf(common.CCErrCommParamsIsInvalid, "info.sub_groups")
+			}
+			return nil, nil, err
+		}
+		filters = append(filters, map[string]interface{}{idField: map[string]interface{}{common.BKDBIN: ids}})
+		return searchConditions, mapstr.MapStr{common.BKDBAND: filters}, nil
+	}
+
+	for _, subGroupID := range group.Info.SubGroups {
+		ids, err := lgc.getSubGroupMemberIDs(kit, group, subGroupID, visited)
+		if err != nil {
+			return nil, nil, err
+		}
+		filters = append(filters, map[string]interface{}{idField: map[string]interface{}{common.BKDBIN: ids}})
+	}
+
+	if len(filters) == 0 {
+		return searchConditions, nil, nil
+	}
+	return searchConditions, mapstr.MapStr{common.BKDBAND: filters}, nil
+}
+
+// getInstIDsByFilter returns the ids of the object instances in the business that matches the mongo filter.
+func (lgc *Logics) getInstIDsByFilter(kit *rest.Kit, bizID int64, objID string, filter mapstr.MapStr) (
+	[]int64, error) {
+
+	idField := common.GetInstIDField(objID)
+	query := &metadata.QueryCondition{
+		Condition: mapstr.MapStr{
+			common.BKAppIDField: bizID,
+			common.BKDBAND:      []interface{}{filter},
+		},
+		Fields:         []string{idField},
+		Page:           metadata.BasePage{Limit: common.BKNoLimit},
+		DisableCounter: true,
+	}
+
+	result, err := lgc.CoreAPI.CoreService().Instance().ReadInstance(kit.Ctx, kit.Header, objID, query)
+	if err != nil {
+		blog.Errorf("search %s by filter failed, err: %v, cond: %+v, rid: %s", objID, err, query, kit.Rid)
+		return nil, err
+	}
+
+	ids := make([]int64, 0, len(result.Info))
+	for _, inst := range result.Info {
+		id, err := util.GetInt64ByInterface(inst[idField])
+		if err != nil {
+			blog.Errorf("parse %s id failed, err: %v, inst: %+v, rid: %s", objID, err, inst, kit.Rid)
+			return nil, kit.CCError.CCErrorf(common.CCErrCommParamsIsInvalid, idField)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// getSubGroupMemberIDs executes the sub group of the dynamic group and returns all its member ids.
+func (lgc *Logics) getSubGroupMemberIDs(kit *rest.Kit, group *metadata.DynamicGroup, subGroupID string,
+	visited map[string]struct{}) ([]int64, error) {
+
+	if _, exists := visited[subGroupID]; exists {
+		blog.Errorf("dynamic group %s has cyclic sub group %s, rid: %s", group.ID, subGroupID, kit.Rid)
+		return nil, kit.CCError.CCErrorf(common.CCErrCommParamsIsInvalid, "info.sub_groups")
+	}
+	if len(visited) >= metadata.DynamicGroupMaxDepth {
+		blog.Errorf("dynamic group %s sub groups exceeds max depth %d, rid: %s", group.ID,
+			metadata.DynamicGroupMaxDepth, kit.Rid)
+		return nil, kit.CCError.CCErrorf(common.CCErrCommParamsIsInvalid, "info.sub_groups")
+	}
+
+	subGroup, err := lgc.getDynamicGroup(kit, group.AppID, subGroupID)
+	if err != nil {
+		return nil, err
+	}
+	if subGroup.ObjID != group.ObjID {
+		blog.Errorf("sub group %s object %s mismatches group %s object %s, rid: %s", subGroupID, subGroup.ObjID,
+			group.ID, group.ObjID, kit.Rid)
+		return nil, kit.CCError.CCErrorf(common.CCErrCommParamsIsInvalid, "info.sub_groups")
+	}
+
+	visited[subGroupID] = struct{}{}
+	defer delete(visited, subGroupID)
+
+	idField := common.GetInstIDField(subGroup.ObjID)
+	page := metadata.BasePage{Limit: common.BKNoLimit}
+	result, err := lgc.executeDynamicGroup(kit, subGroup, []string{idField}, page, true, visited)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int64, 0, len(result.Info))
+	for _, inst := range result.Info {
+		id, err := util.GetInt64ByInterface(inst[idField])
+		if err != nil {
+			blog.Errorf("parse sub group %s member id failed, err: %v, inst: %+v, rid: %s", subGroupID, err, inst,
+				kit.Rid)
+			return nil, kit.CCError.CCErrorf(common.CCErrCommParamsIsInvalid, idField)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// getDynamicGroup returns the dynamic group in the business.
+func (lgc *Logics) getDynamicGroup(kit *rest.Kit, bizID int64, id string) (*metadata.DynamicGroup, error) {
+	result, err := lgc.CoreAPI.CoreService().Host().GetDynamicGroup(kit.Ctx, strconv.FormatInt(bizID, 10), id,
+		kit.Header)
+	if err != nil {
+		blog.Errorf("get dynamic group %s failed, err: %v, bizID: %d, rid: %s", id, err, bizID, kit.Rid)
+		return nil, kit.CCError.CCError(common.CCErrCommHTTPDoRequestFailed)
+	}
+	if err := result.CCError(); err != nil {
+		blog.Errorf("get dynamic group %s failed, err: %v, bizID: %d, rid: %s", id, err, bizID, kit.Rid)
+		return nil, err
+	}
+	if len(result.Data.ID) == 0 {
+		blog.Errorf("dynamic group %s is not found, bizID: %d, rid: %s", id, bizID, kit.Rid)
+		return nil, kit.CCError.CCErrorf(common.CCErrCommParamsIsInvalid, "info.sub_groups")
+	}
+	return &result.Data, nil
+}
+
+// dynamicGroupGetter gets the data to evaluate the host dynamic group from core service
+type dynamicGroupGetter struct {
+	lgc *Logics
+	kit *rest.Kit
+}
+
+// GetGroup returns the dynamic group in the business, returns nil if it does not exist
+func (g *dynamicGroupGetter) GetGroup(_ context.Context, bizID int64, id string) (*metadata.DynamicGroup, error) {
+	kit := g.kit
+	result, err := g.lgc.CoreAPI.CoreService().Host().GetDynamicGroup(kit.Ctx, strconv.FormatInt(bizID, 10), id,
+		kit.Header)
+	if err != nil {
+		blog.Errorf("get dynamic group %s failed, err: %v, bizID: %d, rid: %s", id, err, bizID, kit.Rid)
+		return nil, kit.CCError.CCError(common.CCErrCommHTTPDoRequestFailed)
+	}
+	if err := result.CCError(); err != nil {
+		blog.Errorf("get dynamic group %s failed, err: %v, bizID: %d, rid: %s", id, err, bizID, kit.Rid)
+		return nil, err
+	}
+	if len(result.Data.ID) == 0 {
+		return nil, nil
+	}
+	return &result.Data, nil
+}
+
+// GetInstIDs returns the ids of the set or module instances in the business that match the condition
+func (g *dynamicGroupGetter) GetInstIDs(_ context.Context, bizID int64, objID string,
+	cond map[string]interface{}) ([]int64, error) {
+
+	return g.lgc.getInstIDsByFilter(g.kit, bizID, objID, cond)
+}
+
+// GetRelationHostIDs returns the distinct ids of the hosts that have relations matching the option
+func (g *dynamicGroupGetter) GetRelationHostIDs(_ context.Context,
+	opt *metadata.DistinctHostIDByTopoRelationRequest) ([]int64, error) {
+
+	kit := g.kit
+	hostIDs, err := g.lgc.CoreAPI.CoreService().Host().GetDistinctHostIDByTopology(kit.Ctx, kit.Header, opt)
+	if err != nil {
+		blog.Errorf("get host ids by topology failed, opt: %+v, err: %v, rid: %s", opt, err, kit.Rid)
+		return nil, err
+	}
+	return hostIDs, nil
+}
+
+// GetHostIDs returns the ids of the hosts in hostIDs that match the condition
+func (g *dynamicGroupGetter) GetHostIDs(_ context.Context, hostIDs []int64, cond map[string]interface{}) ([]int64,
+	error) {
+
+	kit := g.kit
+	hostCond := mapstr.MapStr{common.BKHostIDField: mapstr.MapStr{common.BKDBIN: hostIDs}}
+	if cond != nil {
+		hostCond = mapstr.MapStr{common.BKDBAND: []interface{}{hostCond, cond}}
+	}
+	query := &metadata.QueryCondition{
+		Condition:      hostCond,
+		Fields:         []string{common.BKHostIDField},
+		Page:           metadata.BasePage{Limit: common.BKNoLimit},
+		DisableCounter: true,
+	}
+
+	result, err := g.lgc.CoreAPI.CoreService().Instance().ReadInstance(kit.Ctx, kit.Header,
+		common.BKInnerObjIDHost, query)
+	if err != nil {
+		blog.Errorf("search hosts failed, err: %v, cond: %+v, rid: %s", err, query, kit.Rid)
+		return nil, err
+	}
+
+	ids := make([]int64, 0, len(result.Info))
+	for _, host := range result.Info {
+		id, err := util.GetInt64ByInterface(host[common.BKHostIDField])
+		if err != nil {
+			blog.Errorf("parse host id failed, err: %v, host: %+v, rid: %s", err, host, kit.Rid)
+			return nil, kit.CCError.CCErrorf(common.CCErrCommParamsIsInvalid, common.BKHostIDField)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// ValidateDynamicGroupSubGroups validates the sub groups of the dynamic group, sub groups must be in the same
+// business with the same object type, and must not reference the dynamic group itself directly or indirectly.
+func (lgc *Logics) ValidateDynamicGroupSubGroups(kit *rest.Kit, bizID int64, groupID, objID string,
+	subGroups []string) error {
+
+	if len(subGroups) == 0 {
+		return nil
+	}
+
+	path := make(map[string]struct{})
+	if len(groupID) != 0 {
+		path[groupID] = struct{}{}
+	}
+	return lgc.validateSubGroups(kit, bizID, objID, subGroups, path, 1)
+}
+
+func (lgc *Logics) validateSubGroups(kit *rest.Kit, bizID int64, objID string, subGroups []string,
+	path map[string]struct{}, depth int) error {
+
+	if len(subGroups) == 0 {
+		return nil
+	}
+
+	if depth >= metadata.DynamicGroupMaxDepth {
+		blog.Errorf("sub groups %v exceeds max depth %d, rid: %s", subGroups, metadata.DynamicGroupMaxDepth, kit.Rid)
+		return kit.CCError.CCErrorf(common.CCErrCommParamsIsInvalid, "info.sub_groups")
+	}
+
+	for _, subGroupID := range subGroups {
+		if _, exists := path[subGroupID]; exists {
+			blog.Errorf("sub group %s is cyclic referenced, rid: %s", subGroupID, kit.Rid)
+			return kit.CCError.CCErrorf(common.CCErrCommParamsIsInvalid, "info.sub_groups")
+		}
+
+		subGroup, err := lgc.getDynamicGroup(kit, bizID, subGroupID)
+		if err != nil {
+			return err
+		}
+		if subGroup.ObjID != objID {
+			blog.Errorf("sub group %s object %s mismatches %s, rid: %s", subGroupID, subGroup.ObjID, objID, kit.Rid)
+			return kit.CCError.CCErrorf(common.CCErrCommParamsIsInvalid, "info.sub_groups")
+		}
+
+		path[subGroupID] = struct{}{}
+		if err := lgc.validateSubGroups(kit, bizID, objID, subGroup.Info.SubGroups, path, depth+1); err != nil {
+			return err
+		}
+		delete(path, subGroupID)
+	}
+	return nil
+}
+
+// GetReferencingDynamicGroups returns the dynamic groups in the business that use the dynamic group as sub group.
+func (lgc *Logics) GetReferencingDynamicGroups(kit *rest.Kit, bizID int64, id string) ([]metadata.DynamicGroup,
+	error) {
+
+	query := &metadata.QueryCondition{
+		Condition: mapstr.MapStr{
+			common.BKAppIDField: bizID,
+			"info.sub_groups":   id,
+		},
+		Page: metadata.BasePage{Limit: common.BKNoLimit},
+	}
+
+	result, err := lgc.CoreAPI.CoreService().Host().SearchDynamicGroup(kit.Ctx, kit.Header, query)
+	if err != nil {
+		blog.Errorf("search referencing dynamic groups failed, err: %v, id: %s, rid: %s", err, id, kit.Rid)
+		return nil, kit.CCError.CCError(common.CCErrCommHTTPDoRequestFailed)
+	}
+	if err := result.CCError(); err != nil {
+		blog.Errorf("search referencing dynamic groups failed, err: %v, id: %s, rid: %s", err, id, kit.Rid)
+		return nil, err
+	}
+	return result.Data.Info, nil
+}
@@ -0,0 +1,253 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logics
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"configcenter/src/apimachinery"
+	"configcenter/src/apimachinery/coreservice"
+	"configcenter/src/apimachinery/coreservice/host"
+	"configcenter/src/apimachinery/coreservice/instance"
+	"configcenter/src/common"
+	"configcenter/src/common/backbone"
+	"configcenter/src/common/errors"
+	"configcenter/src/common/http/rest"
+	"configcenter/src/common/mapstr"
+	"configcenter/src/common/metadata"
+)
+
+type fakeDynamicGroupClientSet struct {
+	apimachinery.ClientSetInterface
+	groups map[string]metadata.DynamicGroup
+}
+
+func (f *fakeDynamicGroupClientSet) CoreService() coreservice.CoreServiceClientInterface {
+	return &fakeDynamicGroupCoreService{groups: f.groups}
+}
+
+type fakeDynamicGroupCoreService struct {
+	coreservice.CoreServiceClientInterface
+	groups map[string]metadata.DynamicGroup
+}
+
+func (f *fakeDynamicGroupCoreService) Host() host.HostClientInterface {
+	return &fakeDynamicGroupHostClient{groups: f.groups}
+}
+
+func (f *fakeDynamicGroupCoreService) Instance() instance.InstanceClientInterface {
+	return new(fakeDynamicGroupInstClient)
+}
+
+type fakeDynamicGroupInstClient struct {
+	instance.InstanceClientInterface
+}
+
+// ReadInstance returns the hosts 1 and 2
+func (f *fakeDynamicGroupInstClient) ReadInstance(_ context.Context, _ http.Header, _ string,
+	_ *metadata.QueryCondition) (*metadata.InstDataInfo, error) {
+
+	return &metadata.InstDataInfo{Info: []mapstr.MapStr{{common.BKHostIDField: 1}, {common.BKHostIDField: 2}}}, nil
+}
+
+type fakeDynamicGroupHostClient struct {
+	host.HostClientInterface
+	groups map[string]metadata.DynamicGroup
+}
+
+// GetDynamicGroup returns the dynamic group in the fake groups, returns empty group if not exists
+func (f *fakeDynamicGroupHostClient) GetDynamicGroup(_ context.Context, _, id string, _ http.Header) (
+	*metadata.GetDynamicGroupResult, error) {
+
+	return &metadata.GetDynamicGroupResult{BaseResp: metadata.BaseResp{Result: true}, Data: f.groups[id]}, nil
+}
+
+// GetDistinctHostIDByTopology returns the hosts 1 and 2
+func (f *fakeDynamicGroupHostClient) GetDistinctHostIDByTopology(_ context.Context, _ http.Header,
+	_ *metadata.DistinctHostIDByTopoRelationRequest) ([]int64, errors.CCErrorCoder) {
+
+	return []int64{1, 2}, nil
+}
+
+// newDynamicGroupTestLogics returns the logics whose dynamic groups are the groups with the sub group chains,
+// each chain is the group ids that the former group uses the latter one as its sub group.
+func newDynamicGroupTestLogics(objIDs map[string]string, chains ...[]string) (*Logics, *rest.Kit) {
+	groups := make(map[string]metadata.DynamicGroup)
+	for _, chain := range chains {
+		for index, id := range chain {
+			group := groups[id]
+			group.AppID, group.ID, group.ObjID = 1, id, common.BKInnerObjIDHost
+			if objID, exists := objIDs[id]; exists {
+				group.ObjID = objID
+			}
+			if index+1 < len(chain) {
+				group.Info.SubGroups = append(group.Info.SubGroups, chain[index+1])
+			}
+			groups[id] = group
+		}
+	}
+
+	lgc := &Logics{Engine: &backbone.Engine{CoreAPI: &fakeDynamicGroupClientSet{groups: groups}}}
+	kit := &rest.Kit{
+		Rid:     "test",
+		Ctx:     context.Background(),
+		Header:  make(http.Header),
+		CCError: errors.NewFromCtx(errors.EmptyErrorsSetting).CreateDefaultCCErrorIf("en"),
+	}
+	return lgc, kit
+}
+
+// genDynamicGroupChain returns the group ids g0, g1 ... g(n-1)
+func genDynamicGroupChain(n int) []string {
+	chain := make([]string, n)
+	for i := range chain {
+		chain[i] = fmt.Sprintf("g%d", i)
+	}
+	return chain
+}
+
+func assertDynamicGroupErr(t *testing.T, err error, wantErr bool) {
+	if !wantErr {
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		return
+	}
+
+	ccErr, ok := err.(errors.CCErrorCoder)
+	if !ok || ccErr.GetCode() != common.CCErrCommParamsIsInvalid {
+		t.Errorf("expect params invalid error, got: %v", err)
+	}
+}
+
+func TestValidateDynamicGroupSubGroups(t *testing.T) {
+	maxChain := genDynamicGroupChain(metadata.DynamicGroupMaxDepth)
+
+	tests := []struct {
+		name      string
+		objIDs    map[string]string
+		chains    [][]string
+		groupID   string
+		subGroups []string
+		wantErr   bool
+	}{
+		{
+			name:      "no sub groups",
+			groupID:   "g0",
+			subGroups: nil,
+		},
+		{
+			name:      "sub groups without cycle",
+			chains:    [][]string{{"g1", "g2"}, {"g3", "g2"}},
+			groupID:   "g0",
+			subGroups: []string{"g1", "g3"},
+		},
+		{
+			name:      "reference itself",
+			groupID:   "g0",
+			chains:    [][]string{{"g0"}},
+			subGroups: []string{"g0"},
+			wantErr:   true,
+		},
+		{
+			name:      "indirect cycle",
+			chains:    [][]string{{"g1", "g2", "g0"}},
+			groupID:   "g0",
+			subGroups: []string{"g1"},
+			wantErr:   true,
+		},
+		{
+			name:      "cycle among sub groups of new group",
+			chains:    [][]string{{"g1", "g2", "g1"}},
+			subGroups: []string{"g1"},
+			wantErr:   true,
+		},
+		{
+			name:      "nesting reaches max depth",
+			chains:    [][]string{maxChain},
+			groupID:   maxChain[0],
+			subGroups: maxChain[1:2],
+		},
+		{
+			name:      "nesting exceeds max depth",
+			chains:    [][]string{append(genDynamicGroupChain(metadata.DynamicGroupMaxDepth), "gx")},
+			groupID:   maxChain[0],
+			subGroups: maxChain[1:2],
+			wantErr:   true,
+		},
+		{
+			name:      "sub group object mismatches",
+			objIDs:    map[string]string{"g2": common.BKInnerObjIDSet},
+			chains:    [][]string{{"g1", "g2"}},
+			groupID:   "g0",
+			subGroups: []string{"g1"},
+			wantErr:   true,
+		},
+		{
+			name:      "sub group not exists",
+			groupID:   "g0",
+			subGroups: []string{"g1"},
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lgc, kit := newDynamicGroupTestLogics(tt.objIDs, tt.chains...)
+			err := lgc.ValidateDynamicGroupSubGroups(kit, 1, tt.groupID, common.BKInnerObjIDHost, tt.subGroups)
+			assertDynamicGroupErr(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestExecuteDynamicGroupSubGroupCheck(t *testing.T) {
+	tests := []struct {
+		name   string
+		objIDs map[string]string
+		chains [][]string
+	}{
+		{
+			name:   "reference itself",
+			chains: [][]string{{"g0", "g0"}},
+		},
+		{
+			name:   "indirect cycle",
+			chains: [][]string{{"g0", "g1", "g2", "g0"}},
+		},
+		{
+			name:   "nesting exceeds max depth",
+			chains: [][]string{genDynamicGroupChain(metadata.DynamicGroupMaxDepth + 1)},
+		},
+		{
+			name:   "sub group object mismatches",
+			objIDs: map[string]string{"g1": common.BKInnerObjIDSet},
+			chains: [][]string{{"g0", "g1"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lgc, kit := newDynamicGroupTestLogics(tt.objIDs, tt.chains...)
+			group, err := lgc.getDynamicGroup(kit, 1, "g0")
+			if err != nil {
+				t.Fatalf("get dynamic group failed, err: %v", err)
+			}
+
+			_, err = lgc.ExecuteDynamicGroup(kit, group, nil, metadata.BasePage{Limit: common.BKNoLimit}, true)
+			assertDynamicGroupErr(t, err, true)
+		})
+	}
+}
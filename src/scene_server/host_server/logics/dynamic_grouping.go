@@ -30,12 +30,14 @@ import (
 
 /* reuse old part codes of hostsearch.go */
 
-// ExecuteHostDynamicGroup searches hosts base on conditions without filling topology informations.
-func (lgc *Logics) ExecuteHostDynamicGroup(kit *rest.Kit, data *metadata.HostCommonSearch,
+// ExecuteHostDynamicGroup searches hosts base on conditions without filling topology informations,
+// hostFilter is the optional mongo condition of hosts that is combined with the host conditions.
+func (lgc *Logics) ExecuteHostDynamicGroup(kit *rest.Kit, data *metadata.HostCommonSearch, hostFilter mapstr.MapStr,
 	fields []string, disableCounter bool) (*metadata.SearchHost, error) {
 
 	// create search host action instance.
 	executor := NewHostDynamicGroupExecutor(kit, lgc, data, fields, disableCounter)
+	executor.hostFilter = hostFilter
 
 	hostInfos, count, err := executor.Execute()
 	if err != nil {
@@ -62,6 +64,9 @@ type HostDynamicGroupExecutor struct {
 	params *metadata.HostCommonSearch
 	conds  searchHostConds
 
+	// hostFilter mongo condition of hosts parsed from filter expressions and sub groups.
+	hostFilter mapstr.MapStr
+
 	idArr        searchHostIDArr
 	cacheInfoMap searchHostInfoMapCache
 
@@ -385,6 +390,9 @@ func (e *HostDynamicGroupExecutor) searchByHostConds() error {
 	if err != nil {
 		return err
 	}
+	if len(e.hostFilter) > 0 {
+		condition = map[string]interface{}{common.BKDBAND: []interface{}{condition, e.hostFilter}}
+	}
 
 	query := &metadata.QueryInput{
 		Fields:         strings.Join(e.conds.hostCond.Fields, ","),
@@ -467,7 +475,8 @@ func (e *HostDynamicGroupExecutor) appendHostTopoConds() error {
 
 	// 当有根据主机实例内容查询的时候的时候，无法在程序中完成分页
 	hasHostCond := false
-	if len(e.params.Ip.Data) > 0 || len(e.conds.hostCond.Condition) > 0 || e.conds.hostCond.TimeCondition != nil {
+	if len(e.params.Ip.Data) > 0 || len(e.conds.hostCond.Condition) > 0 || e.conds.hostCond.TimeCondition != nil ||
+		len(e.hostFilter) > 0 {
 		hasHostCond = true
 	}
 
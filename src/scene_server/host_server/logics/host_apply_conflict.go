@@ -0,0 +1,241 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logics
+
+import (
+	"sort"
+
+	"configcenter/src/common"
+	"configcenter/src/common/blog"
+	"configcenter/src/common/errors"
+	"configcenter/src/common/http/rest"
+	"configcenter/src/common/json"
+	"configcenter/src/common/metadata"
+)
+
+// GetHostApplyConflictResolvers 根据属性的冲突解决策略，为主机所属多个模块的规则值不一致的属性生成冲突解决项，
+// 属性的冲突解决策略保存在其规则中，请求中指定的策略优先于已保存的策略
+func (lgc *Logics) GetHostApplyConflictResolvers(kit *rest.Kit, policies []metadata.HostApplyConflictPolicy,
+	rules []metadata.HostApplyRule, hostModules []metadata.Host2Modules) ([]metadata.HostApplyConflictResolver,
+	errors.CCErrorCoder) {
+
+	policies = getHostApplyConflictPolicies(policies, rules)
+	if len(policies) == 0 {
+		return make([]metadata.HostApplyConflictResolver, 0), nil
+	}
+
+	attrIDs := make([]int64, len(policies))
+	for index, policy := range policies {
+		attrIDs[index] = policy.AttributeID
+	}
+
+	attrCond := &metadata.QueryCondition{
+		Fields: []string{common.BKFieldID, common.BKPropertyTypeField},
+		Page:   metadata.BasePage{Limit: common.BKNoLimit},
+		Condition: map[string]interface{}{
+			common.BKFieldID: map[string]interface{}{common.BKDBIN: attrIDs},
+		},
+	}
+	attrRes, err := lgc.CoreAPI.CoreService().Model().ReadModelAttr(kit.Ctx, kit.Header, common.BKInnerObjIDHost,
+		attrCond)
+	if err != nil {
+		blog.Errorf("read host attributes failed, cond: %#v, err: %v, rid: %s", attrCond, err, kit.Rid)
+		return nil, kit.CCError.CCError(common.CCErrCommHTTPDoRequestFailed)
+	}
+
+	attrTypeMap := make(map[int64]string)
+	for _, attr := range attrRes.Info {
+		attrTypeMap[attr.ID] = attr.PropertyType
+	}
+
+	for _, policy := range policies {
+		propertyType, exists := attrTypeMap[policy.AttributeID]
+		if !exists {
+			blog.Errorf("conflict policy attribute %d not exists, rid: %s", policy.AttributeID, kit.Rid)
+			return nil, kit.CCError.CCErrorf(common.CCErrCommParamsInvalid, "conflict_policies.bk_attribute_id")
+		}
+
+		// 只有多值类型的字段才能将多个规则的值合并
+		if policy.Strategy == metadata.HostApplyUnion && propertyType != common.FieldTypeOrganization {
+			blog.Errorf("attribute %d type %s can not use union strategy, rid: %s", policy.AttributeID,
+				propertyType, kit.Rid)
+			return nil, kit.CCError.CCErrorf(common.CCErrCommParamsInvalid, "conflict_policies.strategy")
+		}
+	}
+
+	return resolveHostApplyConflicts(policies, rules, hostModules), nil
+}
+
+// getHostApplyConflictPolicies merge the policies saved in the rules with the specified policies, the specified
+// policy of an attribute overrides the saved one, the result is sorted by attribute id.
+func getHostApplyConflictPolicies(policies []metadata.HostApplyConflictPolicy,
+	rules []metadata.HostApplyRule) []metadata.HostApplyConflictPolicy {
+
+	policyMap := make(map[int64]metadata.HostApplyConflictPolicy)
+	for _, rule := range rules {
+		if rule.ConflictPolicy == nil {
+			continue
+		}
+		if _, exists := policyMap[rule.AttributeID]; !exists {
+			policy := *rule.ConflictPolicy
+			policy.AttributeID = rule.AttributeID
+			policyMap[rule.AttributeID] = policy
+		}
+	}
+	for _, policy := range policies {
+		policyMap[policy.AttributeID] = policy
+	}
+
+	result := make([]metadata.HostApplyConflictPolicy, 0, len(policyMap))
+	for _, policy := range policyMap {
+		result = append(result, policy)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].AttributeID < result[j].AttributeID })
+	return result
+}
+
+// resolveHostApplyConflicts 按策略计算每个主机冲突属性的最终取值，并记录取值来源的规则
+func resolveHostApplyConflicts(policies []metadata.HostApplyConflictPolicy, rules []metadata.HostApplyRule,
+	hostModules []metadata.Host2Modules) []metadata.HostApplyConflictResolver {
+
+	// attribute id -> module id -> rule
+	attrModuleRules := make(map[int64]map[int64]metadata.HostApplyRule)
+	for _, policy := range policies {
+		attrModuleRules[policy.AttributeID] = make(map[int64]metadata.HostApplyRule)
+	}
+	for _, rule := range rules {
+		if moduleRules, exists := attrModuleRules[rule.AttributeID]; exists {
+			moduleRules[rule.ModuleID] = rule
+		}
+	}
+
+	resolvers := make([]metadata.HostApplyConflictResolver, 0)
+	for _, hostModule := range hostModules {
+		moduleIDs := make([]int64, len(hostModule.ModuleIDs))
+		copy(moduleIDs, hostModule.ModuleIDs)
+		sort.Slice(moduleIDs, func(i, j int) bool { return moduleIDs[i] < moduleIDs[j] })
+
+		for _, policy := range policies {
+			hostRules := make([]metadata.HostApplyRule, 0)
+			for _, moduleID := range moduleIDs {
+				if rule, exists := attrModuleRules[policy.AttributeID][moduleID]; exists {
+					hostRules = append(hostRules, rule)
+				}
+			}
+
+			if !isHostApplyRulesConflict(hostRules) {
+				continue
+			}
+
+			value, sourceRules, resolved := resolveHostApplyConflict(policy, hostRules)
+			if !resolved {
+				continue
+			}
+
+			sourceRuleIDs := make([]int64, 0)
+			for _, rule := range sourceRules {
+				// the rules that are not saved yet has no id
+				if rule.ID != 0 {
+					sourceRuleIDs = append(sourceRuleIDs, rule.ID)
+				}
+			}
+
+			resolvers = append(resolvers, metadata.HostApplyConflictResolver{
+				HostID: hostModule.HostID,
+				HostAttribute: metadata.HostAttribute{
+					AttributeID:   policy.AttributeID,
+					PropertyValue: value,
+				},
+				Strategy:      policy.Strategy,
+				SourceRuleIDs: sourceRuleIDs,
+			})
+		}
+	}
+	return resolvers
+}
+
+// resolveHostApplyConflict resolve the conflict of the rules that are sorted by module id, returns the resolved value
+// and the rules that the value comes from, if the conflict can not be resolved by the policy, returns false.
+func resolveHostApplyConflict(policy metadata.HostApplyConflictPolicy, rules []metadata.HostApplyRule) (
+	interface{}, []metadata.HostApplyRule, bool) {
+
+	switch policy.Strategy {
+	case metadata.HostApplyModulePriority:
+		for _, moduleID := range policy.ModulePriority {
+			for _, rule := range rules {
+				if rule.ModuleID == moduleID {
+					return rule.PropertyValue, []metadata.HostApplyRule{rule}, true
+				}
+			}
+		}
+		return nil, nil, false
+
+	case metadata.HostApplyFirstMatch:
+		return rules[0].PropertyValue, rules[:1], true
+
+	case metadata.HostApplyLastUpdated:
+		last := rules[0]
+		for _, rule := range rules[1:] {
+			if rule.LastTime.After(last.LastTime) || (rule.LastTime.Equal(last.LastTime) && rule.ID > last.ID) {
+				last = rule
+			}
+		}
+		return last.PropertyValue, []metadata.HostApplyRule{last}, true
+
+	case metadata.HostApplyUnion:
+		values := make([]interface{}, 0)
+		exists := make(map[string]struct{})
+		for _, rule := range rules {
+			items, ok := rule.PropertyValue.([]interface{})
+			if !ok {
+				items = []interface{}{rule.PropertyValue}
+			}
+			for _, item := range items {
+				key := hostApplyValueKey(item)
+				if _, ok := exists[key]; ok {
+					continue
+				}
+				exists[key] = struct{}{}
+				values = append(values, item)
+			}
+		}
+		return values, rules, true
+	}
+
+	return nil, nil, false
+}
+
+// isHostApplyRulesConflict check if the rules of the same attribute have different values
+func isHostApplyRulesConflict(rules []metadata.HostApplyRule) bool {
+	if len(rules) < 2 {
+		return false
+	}
+
+	firstKey := hostApplyValueKey(rules[0].PropertyValue)
+	for _, rule := range rules[1:] {
+		if hostApplyValueKey(rule.PropertyValue) != firstKey {
+			return true
+		}
+	}
+	return false
+}
+
+// hostApplyValueKey use the json encoding of the value as the key, so that values decoded into different number types
+// are treated as equal
+func hostApplyValueKey(value interface{}) string {
+	key, err := json.Marshal(value)
+	if err != nil {
+		return ""
+	}
+	return string(key)
+}
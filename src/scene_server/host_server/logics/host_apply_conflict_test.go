@@ -0,0 +1,149 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logics
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"configcenter/src/common/metadata"
+)
+
+func TestResolveHostApplyConflicts(t *testing.T) {
+	now := time.Now()
+	rules := []metadata.HostApplyRule{
+		{ID: 1, ModuleID: 10, AttributeID: 100, PropertyValue: "a", LastTime: now},
+		{ID: 2, ModuleID: 11, AttributeID: 100, PropertyValue: "b", LastTime: now.Add(time.Hour)},
+		{ID: 3, ModuleID: 12, AttributeID: 100, PropertyValue: "a", LastTime: now},
+		{ID: 4, ModuleID: 10, AttributeID: 200, PropertyValue: []interface{}{"u1", "u2"}},
+		{ID: 5, ModuleID: 11, AttributeID: 200, PropertyValue: []interface{}{"u2", "u3"}},
+		{ID: 6, ModuleID: 12, AttributeID: 200, PropertyValue: []interface{}{"u1", "u2"}},
+	}
+
+	tests := []struct {
+		name        string
+		policies    []metadata.HostApplyConflictPolicy
+		hostModules []metadata.Host2Modules
+		want        []metadata.HostApplyConflictResolver
+	}{
+		{
+			name: "module priority uses the rule of the first prior module",
+			policies: []metadata.HostApplyConflictPolicy{
+				{AttributeID: 100, Strategy: metadata.HostApplyModulePriority, ModulePriority: []int64{11, 10}},
+			},
+			hostModules: []metadata.Host2Modules{{HostID: 1, ModuleIDs: []int64{10, 11}}},
+			want: []metadata.HostApplyConflictResolver{{
+				HostID:        1,
+				HostAttribute: metadata.HostAttribute{AttributeID: 100, PropertyValue: "b"},
+				Strategy:      metadata.HostApplyModulePriority,
+				SourceRuleIDs: []int64{2},
+			}},
+		},
+		{
+			name: "module priority without the modules of host is unresolved",
+			policies: []metadata.HostApplyConflictPolicy{
+				{AttributeID: 100, Strategy: metadata.HostApplyModulePriority, ModulePriority: []int64{12}},
+			},
+			hostModules: []metadata.Host2Modules{{HostID: 1, ModuleIDs: []int64{10, 11}}},
+			want:        []metadata.HostApplyConflictResolver{},
+		},
+		{
+			name: "first match uses the rule of the smallest module id",
+			policies: []metadata.HostApplyConflictPolicy{
+				{AttributeID: 100, Strategy: metadata.HostApplyFirstMatch},
+			},
+			hostModules: []metadata.Host2Modules{{HostID: 1, ModuleIDs: []int64{11, 10}}},
+			want: []metadata.HostApplyConflictResolver{{
+				HostID:        1,
+				HostAttribute: metadata.HostAttribute{AttributeID: 100, PropertyValue: "a"},
+				Strategy:      metadata.HostApplyFirstMatch,
+				SourceRuleIDs: []int64{1},
+			}},
+		},
+		{
+			name: "last updated uses the latest modified rule",
+			policies: []metadata.HostApplyConflictPolicy{
+				{AttributeID: 100, Strategy: metadata.HostApplyLastUpdated},
+			},
+			hostModules: []metadata.Host2Modules{{HostID: 1, ModuleIDs: []int64{10, 11, 12}}},
+			want: []metadata.HostApplyConflictResolver{{
+				HostID:        1,
+				HostAttribute: metadata.HostAttribute{AttributeID: 100, PropertyValue: "b"},
+				Strategy:      metadata.HostApplyLastUpdated,
+				SourceRuleIDs: []int64{2},
+			}},
+		},
+		{
+			name: "union merges the values without duplication",
+			policies: []metadata.HostApplyConflictPolicy{
+				{AttributeID: 200, Strategy: metadata.HostApplyUnion},
+			},
+			hostModules: []metadata.Host2Modules{{HostID: 1, ModuleIDs: []int64{10, 11}}},
+			want: []metadata.HostApplyConflictResolver{{
+				HostID:        1,
+				HostAttribute: metadata.HostAttribute{AttributeID: 200, PropertyValue: []interface{}{"u1", "u2", "u3"}},
+				Strategy:      metadata.HostApplyUnion,
+				SourceRuleIDs: []int64{4, 5},
+			}},
+		},
+		{
+			name: "rules with the same value are not conflict",
+			policies: []metadata.HostApplyConflictPolicy{
+				{AttributeID: 100, Strategy: metadata.HostApplyFirstMatch},
+				{AttributeID: 200, Strategy: metadata.HostApplyUnion},
+			},
+			hostModules: []metadata.Host2Modules{
+				{HostID: 1, ModuleIDs: []int64{10, 12}},
+				{HostID: 2, ModuleIDs: []int64{11}},
+			},
+			want: []metadata.HostApplyConflictResolver{},
+		},
+		{
+			name:        "attribute without policy is not resolved",
+			policies:    []metadata.HostApplyConflictPolicy{},
+			hostModules: []metadata.Host2Modules{{HostID: 1, ModuleIDs: []int64{10, 11}}},
+			want:        []metadata.HostApplyConflictResolver{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := resolveHostApplyConflicts(tt.policies, rules, tt.hostModules)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("resolveHostApplyConflicts() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetHostApplyConflictPolicies(t *testing.T) {
+	rules := []metadata.HostApplyRule{
+		{ID: 1, AttributeID: 200, ConflictPolicy: &metadata.HostApplyConflictPolicy{
+			Strategy: metadata.HostApplyUnion}},
+		{ID: 2, AttributeID: 100, ConflictPolicy: &metadata.HostApplyConflictPolicy{
+			Strategy: metadata.HostApplyFirstMatch}},
+		{ID: 3, AttributeID: 300},
+	}
+	policies := []metadata.HostApplyConflictPolicy{
+		{AttributeID: 100, Strategy: metadata.HostApplyLastUpdated},
+	}
+
+	want := []metadata.HostApplyConflictPolicy{
+		{AttributeID: 100, Strategy: metadata.HostApplyLastUpdated},
+		{AttributeID: 200, Strategy: metadata.HostApplyUnion},
+	}
+	if got := getHostApplyConflictPolicies(policies, rules); !reflect.DeepEqual(got, want) {
+		t.Errorf("getHostApplyConflictPolicies() = %#v, want %#v", got, want)
+	}
+}
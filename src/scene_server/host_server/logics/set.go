@@ -61,8 +61,8 @@ func (lgc *Logics) GetSetIDByCond(kit *rest.Kit, cond metadata.ConditionWithTime
 }
 
 // ExecuteSetDynamicGroup searches sets base on conditions without filling topology informations.
-func (lgc *Logics) ExecuteSetDynamicGroup(kit *rest.Kit, setCommonSearch *metadata.SetCommonSearch, fields []string,
-	disableCounter bool) (*metadata.InstDataInfo, errors.CCError) {
+func (lgc *Logics) ExecuteSetDynamicGroup(kit *rest.Kit, setCommonSearch *metadata.SetCommonSearch,
+	setFilter mapstr.MapStr, fields []string, disableCounter bool) (*metadata.InstDataInfo, errors.CCError) {
 
 	// search parameters with condition.
 	queryParams := &metadata.QueryCondition{Fields: fields, Page: setCommonSearch.Page, Condition: mapstr.New(),
@@ -84,6 +84,9 @@ func (lgc *Logics) ExecuteSetDynamicGroup(kit *rest.Kit, setCommonSearch *metada
 		queryParams.TimeCondition = searchCondition.TimeCondition
 	}
 	queryParams.Condition.Set(common.BKAppIDField, setCommonSearch.AppID)
+	if len(setFilter) > 0 {
+		queryParams.Condition.Set(common.BKDBAND, []interface{}{setFilter})
+	}
 
 	// search set with conditions.
 	result, err := lgc.CoreAPI.CoreService().Instance().ReadInstance(kit.Ctx, kit.Header, common.BKInnerObjIDSet,
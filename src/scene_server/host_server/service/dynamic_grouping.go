@@ -48,6 +48,13 @@ func (s *Service) CreateDynamicGroup(ctx *rest.Contexts) {
 		ctx.RespAutoError(ctx.Kit.CCError.Errorf(common.CCErrCommParamsIsInvalid, err.Error()))
 		return
 	}
+
+	// validate sub groups, there must be no cyclic references.
+	if err := s.Logic.ValidateDynamicGroupSubGroups(ctx.Kit, newDynamicGroup.AppID, "", newDynamicGroup.ObjID,
+		newDynamicGroup.Info.SubGroups); err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
 	newDynamicGroup.CreateUser = ctx.Kit.User
 	newDynamicGroup.CreateTime = time.Now().UTC()
 	response := &meta.IDResult{}
@@ -186,6 +193,28 @@ func (s *Service) UpdateDynamicGroup(ctx *rest.Contexts) {
 			ctx.RespAutoError(ctx.Kit.CCError.Errorf(common.CCErrCommParamsIsInvalid, err.Error()))
 			return
 		}
+
+		// validate sub groups, there must be no cyclic references.
+		if err := s.Logic.ValidateDynamicGroupSubGroups(ctx.Kit, bizIDInt64, targetID, objectID,
+			dynamicGroupInfo.SubGroups); err != nil {
+			ctx.RespAutoError(err)
+			return
+		}
+
+		// the groups that reference this group as sub group must have the same object type.
+		referencingGroups, err := s.Logic.GetReferencingDynamicGroups(ctx.Kit, bizIDInt64, targetID)
+		if err != nil {
+			ctx.RespAutoError(err)
+			return
+		}
+		for _, group := range referencingGroups {
+			if group.ObjID != objectID {
+				blog.Errorf("update dynamic group failed, it's referenced by group %s with object %s, rid: %s",
+					group.ID, group.ObjID, ctx.Kit.Rid)
+				ctx.RespAutoError(ctx.Kit.CCError.Errorf(common.CCErrCommParamsIsInvalid, "bk_obj_id"))
+				return
+			}
+		}
 		updates[common.BKObjIDField] = objectID
 		updates["info"] = dynamicGroupInfo
 
@@ -198,8 +227,9 @@ func (s *Service) UpdateDynamicGroup(ctx *rest.Contexts) {
 			return
 		}
 
-		_, isExist = params[common.BKFieldName]
-		if !isExist {
+		_, isNameExist := params[common.BKFieldName]
+		_, isWatchMemberExist := params[common.BKWatchMemberField]
+		if !isNameExist && !isWatchMemberExist {
 			blog.Errorf("update dynamic group failed, empty update content, bk_biz_id/info/name, input: %+v, rid: %s",
 				params, ctx.Kit.Rid)
 			ctx.RespAutoError(ctx.Kit.CCError.Errorf(common.CCErrCommParamsIsInvalid, "info.condition"))
@@ -212,6 +242,12 @@ func (s *Service) UpdateDynamicGroup(ctx *rest.Contexts) {
 		updates[common.BKFieldName] = name
 	}
 
+	// update watch member flag, only host dynamic group supports watching its members.
+	if err := s.parseDynamicGroupWatchMember(ctx.Kit, bizID, targetID, params, updates); err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
 	// update base on auto run txn with func.
 	autoRunTxnFunc := func() error {
 		// audit log.
@@ -249,6 +285,48 @@ func (s *Service) UpdateDynamicGroup(ctx *rest.Contexts) {
 	ctx.RespEntity(nil)
 }
 
+// parseDynamicGroupWatchMember parses the watch member flag in update params into updates, and checks if the final
+// dynamic group object type supports watching members.
+func (s *Service) parseDynamicGroupWatchMember(kit *rest.Kit, bizID, targetID string, params,
+	updates map[string]interface{}) error {
+
+	watchMemberParam, isWatchMemberExist := params[common.BKWatchMemberField]
+	objectID, isObjIDExist := updates[common.BKObjIDField].(string)
+	if !isWatchMemberExist && !isObjIDExist {
+		return nil
+	}
+
+	result, err := s.CoreAPI.CoreService().Host().GetDynamicGroup(kit.Ctx, bizID, targetID, kit.Header)
+	if err != nil {
+		blog.Errorf("get dynamic group failed, err: %v, biz: %s, ID: %s, rid: %s", err, bizID, targetID, kit.Rid)
+		return kit.CCError.CCError(common.CCErrCommHTTPDoRequestFailed)
+	}
+	if err := result.CCError(); err != nil {
+		blog.Errorf("get dynamic group failed, err: %v, biz: %s, ID: %s, rid: %s", err, bizID, targetID, kit.Rid)
+		return err
+	}
+
+	watchMember := result.Data.WatchMember
+	if isWatchMemberExist {
+		var ok bool
+		if watchMember, ok = watchMemberParam.(bool); !ok {
+			blog.Errorf("invalid watch_member type, watch_member: %+v, rid: %s", watchMemberParam, kit.Rid)
+			return kit.CCError.CCErrorf(common.CCErrCommParamsIsInvalid, common.BKWatchMemberField)
+		}
+		updates[common.BKWatchMemberField] = watchMember
+	}
+
+	if !isObjIDExist {
+		objectID = result.Data.ObjID
+	}
+
+	if watchMember && objectID != common.BKInnerObjIDHost {
+		blog.Errorf("%s dynamic group %s does not support watching members, rid: %s", objectID, targetID, kit.Rid)
+		return kit.CCError.CCErrorf(common.CCErrCommParamsIsInvalid, common.BKWatchMemberField)
+	}
+	return nil
+}
+
 // DeleteDynamicGroup deletes target dynamic group.
 func (s *Service) DeleteDynamicGroup(ctx *rest.Contexts) {
 	req := ctx.Request
@@ -273,6 +351,19 @@ func (s *Service) DeleteDynamicGroup(ctx *rest.Contexts) {
 	}
 	dynamicGroup := result.Data
 
+	// dynamic group that is referenced by other groups as sub group can not be deleted.
+	referencingGroups, err := s.Logic.GetReferencingDynamicGroups(ctx.Kit, dynamicGroup.AppID, targetID)
+	if err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+	if len(referencingGroups) > 0 {
+		blog.Errorf("delete dynamic group failed, it's referenced by group %s, rid: %s", referencingGroups[0].ID,
+			ctx.Kit.Rid)
+		ctx.RespAutoError(ctx.Kit.CCError.CCError(common.CCErrCommRemoveReferencedRecordForbidden))
+		return
+	}
+
 	// delete base on auto run txn with func.
 	autoRunTxnFunc := func() error {
 		result, err := s.CoreAPI.CoreService().Host().DeleteDynamicGroup(ctx.Kit.Ctx, bizID, targetID, ctx.Kit.Header)
@@ -418,8 +509,7 @@ func (s *Service) ExecuteDynamicGroup(ctx *rest.Contexts) {
 	// target dynamic group ID.
 	targetID := req.PathParameter("id")
 
-	bizIDInt64, err := strconv.ParseInt(bizID, 10, 64)
-	if err != nil {
+	if _, err := strconv.ParseInt(bizID, 10, 64); err != nil {
 		blog.Errorf("execute dynamic group failed, invalid bizID from path, bizID: %s, rid: %s", bizID, ctx.Kit.Rid)
 		ctx.RespAutoError(ctx.Kit.CCError.Errorf(common.CCErrCommParamsIsInvalid, "bk_biz_id"))
 		return
@@ -471,68 +561,20 @@ func (s *Service) ExecuteDynamicGroup(ctx *rest.Contexts) {
 	// target dynamic group.
 	targetDynamicGroup := result.Data
 
-	// build search conditions.
-	searchConditions := []meta.SearchCondition{}
-
-	// parse all dynamic group conditions to search condition.
-	for _, cond := range targetDynamicGroup.Info.Condition {
-		searchCondition := meta.SearchCondition{ObjectID: cond.ObjID, Condition: []meta.ConditionItem{}}
-
-		// build condition items.
-		for _, item := range cond.Condition {
-			condItem := meta.ConditionItem{Field: item.Field, Operator: item.Operator, Value: item.Value}
-			searchCondition.Condition = append(searchCondition.Condition, condItem)
-		}
-		searchCondition.TimeCondition = cond.TimeCondition
-		searchConditions = append(searchConditions, searchCondition)
-	}
-
-	// execute dynamic group with target object type.
-	if targetDynamicGroup.ObjID == common.BKInnerObjIDHost {
-		// build host search conditions.
-		searchHostCondition := meta.HostCommonSearch{AppID: bizIDInt64, Condition: searchConditions, Page: searchPage}
-
-		// execute host object dynamic group.
-		data, err := logics.NewLogics(s.Engine, s.CacheDB, s.AuthManager).
-			ExecuteHostDynamicGroup(ctx.Kit, &searchHostCondition, input.Fields, input.DisableCounter)
-		if err != nil {
-			blog.Errorf("execute dynamic group failed, search hosts, err: %+v, bizID: %s, ID: %s, rid: %s",
-				err, bizID, targetID, ctx.Kit.Rid)
-			ctx.RespAutoError(ctx.Kit.CCError.Errorf(common.CCErrGetUserCustomQueryDetailFailed, err.Error()))
-			return
-		}
-
-		ctx.RespEntity(meta.InstDataInfo{
-			Count: data.Count,
-			Info:  data.Info,
-		})
-		return
-
-	} else if targetDynamicGroup.ObjID == common.BKInnerObjIDSet {
-		// build host search conditions.
-		searchSetCondition := meta.SetCommonSearch{AppID: bizIDInt64, Condition: searchConditions, Page: searchPage}
-
-		// execute set object dynamic group.
-		data, err := logics.NewLogics(s.Engine, s.CacheDB, s.AuthManager).
-			ExecuteSetDynamicGroup(ctx.Kit, &searchSetCondition, input.Fields, input.DisableCounter)
-		if err != nil {
-			blog.Errorf("execute dynamic group failed, search sets, err: %+v, bizID: %s, ID: %s, rid: %s",
-				err, bizID, targetID, ctx.Kit.Rid)
-			ctx.RespAutoError(ctx.Kit.CCError.Errorf(common.CCErrGetUserCustomQueryDetailFailed, err.Error()))
-			return
-		}
-
-		ctx.RespEntity(meta.InstDataInfo{
-			Count: data.Count,
-			Info:  data.Info,
-		})
+	// execute dynamic group with its conditions, filter expressions and sub groups.
+	data, err := s.Logic.ExecuteDynamicGroup(ctx.Kit, &targetDynamicGroup, input.Fields, searchPage,
+		input.DisableCounter)
+	if err != nil {
+		blog.Errorf("execute dynamic group failed, err: %+v, bizID: %s, ID: %s, rid: %s", err, bizID, targetID,
+			ctx.Kit.Rid)
+		ctx.RespAutoError(ctx.Kit.CCError.Errorf(common.CCErrGetUserCustomQueryDetailFailed, err.Error()))
 		return
 	}
 
-	// unknown group object type, should no-reach here.
-	blog.Errorf("execute dynamic group failed, unknown group object type[%s], bizID: %s, ID: %s, rid: %s",
-		targetDynamicGroup.ObjID, bizID, targetID, ctx.Kit.Rid)
-	ctx.RespAutoError(ctx.Kit.CCError.Errorf(common.CCSystemUnknownError))
+	ctx.RespEntity(meta.InstDataInfo{
+		Count: data.Count,
+		Info:  data.Info,
+	})
 }
 
 // changeTimeToMatchLocalZone TODO
@@ -22,6 +22,7 @@ import (
 	"time"
 
 	"configcenter/src/common"
+	"configcenter/src/common/auditlog"
 	"configcenter/src/common/blog"
 	"configcenter/src/common/errors"
 	"configcenter/src/common/http/rest"
@@ -314,6 +315,11 @@ func (s *Service) GenerateModuleApplyPlan(ctx *rest.Contexts) {
 		return
 	}
 
+	if rawErr := planRequest.ValidateConflictPolicies(); rawErr.ErrCode != 0 {
+		ctx.RespAutoError(rawErr.ToCCError(ctx.Kit.CCError))
+		return
+	}
+
 	result, err := s.generateModuleApplyPlan(ctx, &planRequest)
 	if err != nil {
 		blog.Errorf("generate module apply plan failed, request: %s, err: %v, rid:%s", planRequest, err, rid)
@@ -511,9 +517,18 @@ func (s *Service) generateModuleApplyPlan(ctx *rest.Contexts, planRequest *metad
 		finalRules = append(finalRules, item)
 	}
 
+	resolvers, ccErr := s.Logic.GetHostApplyConflictResolvers(ctx.Kit, planRequest.ConflictPolicies, finalRules,
+		hostModules)
+	if ccErr != nil {
+		blog.Errorf("get conflict resolvers failed, policies: %#v, err: %v, rid: %s", planRequest.ConflictPolicies,
+			ccErr, rid)
+		return metadata.HostApplyPlanResult{}, ccErr
+	}
+
 	planOption := metadata.HostApplyPlanOption{
-		Rules:       finalRules,
-		HostModules: hostModules,
+		Rules:             finalRules,
+		HostModules:       hostModules,
+		ConflictResolvers: resolvers,
 	}
 
 	planResult, ccErr := s.CoreAPI.CoreService().HostApplyRule().GenerateApplyPlan(ctx.Kit.Ctx, ctx.Kit.Header,
@@ -704,12 +719,19 @@ func (s *Service) ExecModuleHostApplyRule(ctx *rest.Contexts) {
 		ctx.RespAutoError(err)
 		return
 	}
+
+	if rawErr := planReq.ValidateConflictPolicies(); rawErr.ErrCode != 0 {
+		ctx.RespAutoError(rawErr.ToCCError(ctx.Kit.CCError))
+		return
+	}
+
 	hostIDs, err := s.getHostIDByCondition(ctx.Kit, planReq.BizID, planReq.ModuleIDs, planReq.HostIDs)
 	if err != nil {
 		ctx.RespAutoError(err)
 		return
 	}
 
+	hasConflictPolicy := len(planReq.ConflictPolicies) > 0
 	txnErr := s.Engine.CoreAPI.CoreService().Txn().AutoRunTxn(ctx.Kit.Ctx, ctx.Kit.Header, func() error {
 		// enable host apply on module
 		op := &metadata.UpdateOption{
@@ -732,14 +754,17 @@ func (s *Service) ExecModuleHostApplyRule(ctx *rest.Contexts) {
 				ModuleID:      rule.ModuleID,
 				PropertyValue: rule.PropertyValue})
 		}
-		// 1、update or add rules.
-		saveRuleOp := metadata.BatchCreateOrUpdateApplyRuleOption{Rules: rulesOption}
-		if _, ccErr := s.CoreAPI.CoreService().HostApplyRule().BatchUpdateHostApplyRule(ctx.Kit.Ctx, ctx.Kit.Header,
-			planReq.BizID, saveRuleOp); ccErr != nil {
+		// 1、update or add rules, and save the conflict policies with the rules.
+		saveRuleOp := metadata.BatchCreateOrUpdateApplyRuleOption{Rules: rulesOption,
+			ConflictPolicies: planReq.ConflictPolicies}
+		saveRes, ccErr := s.CoreAPI.CoreService().HostApplyRule().BatchUpdateHostApplyRule(ctx.Kit.Ctx,
+			ctx.Kit.Header, planReq.BizID, saveRuleOp)
+		if ccErr != nil {
 			blog.Errorf("update host rule failed, bizID: %s, req: %s, err: %v, rid: %s", planReq.BizID, saveRuleOp,
 				ccErr, rid)
 			return ccErr
 		}
+		hasConflictPolicy = hasConflictPolicy || saveRes.HasConflictPolicy()
 
 		// 2、delete rules.
 		if len(planReq.RemoveRuleIDs) > 0 {
@@ -769,6 +794,33 @@ func (s *Service) ExecModuleHostApplyRule(ctx *rest.Contexts) {
 	// update host operation is not done in a transaction, since the successfully updated hosts need not roll back
 	ctx.Kit.Header.Del(common.TransactionIdHeader)
 
+	// when the attributes have conflict policies, the hosts are updated by the plan whose conflicts are resolved by
+	// the policies
+	if hasConflictPolicy {
+		option := &metadata.HostApplyModulesOption{
+			HostApplyPlanBase: metadata.HostApplyPlanBase{
+				BizID:            planReq.BizID,
+				IgnoreRuleIDs:    planReq.IgnoreRuleIDs,
+				HostIDs:          planReq.HostIDs,
+				ConflictPolicies: planReq.ConflictPolicies,
+			},
+			ModuleIDs: planReq.ModuleIDs,
+		}
+		planResult, err := s.generateModuleApplyPlan(ctx, option)
+		if err != nil {
+			blog.Errorf("generate module apply plan failed, option: %#v, err: %v, rid: %s", option, err, rid)
+			ctx.RespAutoError(err)
+			return
+		}
+
+		if err := s.execHostApplyPlan(ctx.Kit, planReq.BizID, planResult, planReq.AdditionalRules); err != nil {
+			ctx.RespAutoError(err)
+			return
+		}
+		ctx.RespEntity(nil)
+		return
+	}
+
 	attributes := make([]metadata.HostAttribute, 0)
 
 	for _, rule := range planReq.AdditionalRules {
@@ -785,6 +837,58 @@ func (s *Service) ExecModuleHostApplyRule(ctx *rest.Contexts) {
 	ctx.RespEntity(nil)
 }
 
+// execHostApplyPlan update the attributes of the additional rules to hosts by the apply plan whose conflicts are
+// resolved by the conflict policies, and record the source rules of the resolved conflicts in audit log.
+func (s *Service) execHostApplyPlan(kit *rest.Kit, bizID int64, planResult metadata.HostApplyPlanResult,
+	additionalRules []metadata.CreateHostApplyRuleOption) errors.CCErrorCoder {
+
+	// only the attributes of the additional rules are applied, which is the same as the scene without policies
+	attrIDs := make(map[int64]struct{})
+	for _, rule := range additionalRules {
+		attrIDs[rule.AttributeID] = struct{}{}
+	}
+
+	plans := make([]metadata.OneHostApplyPlan, 0)
+	for _, plan := range planResult.Plans {
+		if err := plan.GetError(); err != nil {
+			return err
+		}
+
+		updateFields := make([]metadata.HostApplyUpdateField, 0)
+		for _, field := range plan.UpdateFields {
+			if _, exists := attrIDs[field.AttributeID]; exists {
+				updateFields = append(updateFields, field)
+			}
+		}
+		conflictFields := make([]metadata.HostApplyConflictField, 0)
+		for _, field := range plan.ConflictFields {
+			if _, exists := attrIDs[field.AttributeID]; exists {
+				conflictFields = append(conflictFields, field)
+			}
+		}
+		plan.UpdateFields, plan.ConflictFields = updateFields, conflictFields
+		plans = append(plans, plan)
+	}
+	planResult.Plans = plans
+
+	if _, err := s.updateHostPlan(planResult, kit); err != nil {
+		return err
+	}
+
+	audit := auditlog.NewHostApplyAuditLog(s.CoreAPI.CoreService())
+	auditParam := auditlog.NewGenerateAuditCommonParameter(kit, metadata.AuditUpdate)
+	auditLogs := audit.GenerateAuditLog(auditParam, bizID, plans)
+	if len(auditLogs) == 0 {
+		return nil
+	}
+
+	if err := audit.SaveAuditLog(kit, auditLogs...); err != nil {
+		blog.Errorf("save host apply audit log failed, err: %v, rid: %s", err, kit.Rid)
+		return kit.CCError.CCError(common.CCErrAuditSaveLogFailed)
+	}
+	return nil
+}
+
 func (s *Service) getModuleRelateHostApply(kit *rest.Kit, bizID int64, moduleIDs []int64, srvTemplateIDs []int64) (
 	[]metadata.ModuleInst, error) {
 
@@ -1074,6 +1178,11 @@ func (s *Service) GenerateTemplateApplyPlan(ctx *rest.Contexts) {
 		return
 	}
 
+	if rawErr := planRequest.ValidateConflictPolicies(); rawErr.ErrCode != 0 {
+		ctx.RespAutoError(rawErr.ToCCError(ctx.Kit.CCError))
+		return
+	}
+
 	result, err := s.generateServiceTemplateApplyPlan(ctx.Kit, &planRequest)
 	if err != nil {
 		blog.Errorf("generate service template apply plan failed, request: %v, err: %v, rid: %s",
@@ -1093,6 +1202,55 @@ func (s *Service) GenerateTemplateApplyPlan(ctx *rest.Contexts) {
 	return
 }
 
+// ExecServiceTemplateApplyPlan update the hosts of service templates by the apply plan whose conflicts are resolved
+// by the conflict policies, the rules of the service templates are already saved by the caller.
+func (s *Service) ExecServiceTemplateApplyPlan(ctx *rest.Contexts) {
+	planReq := new(metadata.HostApplyServiceTemplateOption)
+	if err := ctx.DecodeInto(planReq); err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	if planReq.BizID == 0 {
+		ctx.RespAutoError(ctx.Kit.CCError.Errorf(common.CCErrCommParamsInvalid, common.BKAppIDField))
+		return
+	}
+
+	if err := checkIDs(planReq.ServiceTemplateIDs); err != nil {
+		blog.Errorf("service template ids are invalid, err: %v, rid: %s", err, ctx.Kit.Rid)
+		ctx.RespAutoError(ctx.Kit.CCError.Errorf(common.CCErrCommParamsInvalid, "service_template_ids"))
+		return
+	}
+
+	if rawErr := planReq.ValidateConflictPolicies(); rawErr.ErrCode != 0 {
+		ctx.RespAutoError(rawErr.ToCCError(ctx.Kit.CCError))
+		return
+	}
+
+	option := &metadata.HostApplyServiceTemplateOption{
+		HostApplyPlanBase: metadata.HostApplyPlanBase{
+			BizID:            planReq.BizID,
+			IgnoreRuleIDs:    planReq.IgnoreRuleIDs,
+			HostIDs:          planReq.HostIDs,
+			ConflictPolicies: planReq.ConflictPolicies,
+		},
+		ServiceTemplateIDs: planReq.ServiceTemplateIDs,
+	}
+	planResult, err := s.generateServiceTemplateApplyPlan(ctx.Kit, option)
+	if err != nil {
+		blog.Errorf("generate service template apply plan failed, option: %#v, err: %v, rid: %s", option, err,
+			ctx.Kit.Rid)
+		ctx.RespAutoError(err)
+		return
+	}
+
+	if err := s.execHostApplyPlan(ctx.Kit, planReq.BizID, planResult, planReq.AdditionalRules); err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+	ctx.RespEntity(nil)
+}
+
 func (s *Service) generateServiceTemplateApplyPlan(kit *rest.Kit, option *metadata.HostApplyServiceTemplateOption) (
 	metadata.HostApplyPlanResult, errors.CCErrorCoder) {
 
@@ -1160,10 +1318,19 @@ func (s *Service) generateServiceTemplateApplyPlan(kit *rest.Kit, option *metada
 		})
 	}
 
-	// 4.生成预览结果
+	// 4.按冲突解决策略生成冲突解决项
+	resolvers, ccErr := s.Logic.GetHostApplyConflictResolvers(kit, option.ConflictPolicies, finalRules, hostModules)
+	if ccErr != nil {
+		blog.Errorf("get conflict resolvers failed, policies: %#v, err: %v, rid: %s", option.ConflictPolicies,
+			ccErr, kit.Rid)
+		return metadata.HostApplyPlanResult{}, ccErr
+	}
+
+	// 5.生成预览结果
 	planOption := metadata.HostApplyPlanOption{
-		Rules:       finalRules,
-		HostModules: hostModules,
+		Rules:             finalRules,
+		HostModules:       hostModules,
+		ConflictResolvers: resolvers,
 	}
 
 	planResult, ccErr := s.CoreAPI.CoreService().HostApplyRule().GenerateApplyPlan(kit.Ctx, kit.Header, option.BizID,
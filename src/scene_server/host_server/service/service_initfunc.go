@@ -177,6 +177,9 @@ func (s *Service) initHostapplyrule(web *restful.WebService) {
 	utility.AddHandler(rest.Action{Verb: http.MethodPost,
 		Path:    "/updatemany/module/host_apply_plan/task",
 		Handler: s.ExecModuleHostApplyRule})
+	utility.AddHandler(rest.Action{Verb: http.MethodPost,
+		Path:    "/updatemany/service_template/host_apply_plan/exec",
+		Handler: s.ExecServiceTemplateApplyPlan})
 
 	utility.AddHandler(rest.Action{Verb: http.MethodPost,
 		Path:    "/host/findmany/module/host_apply_plan/status",
@@ -716,9 +716,16 @@ func (s *Service) generateHostApplyPlans(kit *rest.Kit, bizID int64, plans []met
 		hostModules = append(hostModules, host2Module)
 	}
 
+	resolvers, ccErr := s.Logic.GetHostApplyConflictResolvers(kit, nil, rules, hostModules)
+	if ccErr != nil {
+		blog.Errorf("get conflict resolvers failed, biz: %d, err: %v, rid: %s", bizID, ccErr, kit.Rid)
+		return plans, ccErr
+	}
+
 	planOpt := metadata.HostApplyPlanOption{
-		Rules:       rules,
-		HostModules: hostModules,
+		Rules:             rules,
+		HostModules:       hostModules,
+		ConflictResolvers: resolvers,
 	}
 
 	hostApplyPlanResult, ccErr := s.CoreAPI.CoreService().HostApplyRule().GenerateApplyPlan(kit.Ctx, kit.Header, bizID,
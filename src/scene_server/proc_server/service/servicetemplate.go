@@ -342,11 +342,18 @@ func (ps *ProcServer) ExecServiceTemplateHostApplyRule(ctx *rest.Contexts) {
 		ctx.RespAutoError(err)
 		return
 	}
+
+	if rawErr := planReq.ValidateConflictPolicies(); rawErr.ErrCode != 0 {
+		ctx.RespAutoError(rawErr.ToCCError(ctx.Kit.CCError))
+		return
+	}
+
 	hostIDs, err := ps.getHostIDByCondition(ctx.Kit, planReq.BizID, planReq.ServiceTemplateIDs, planReq.HostIDs)
 	if err != nil {
 		ctx.RespAutoError(err)
 		return
 	}
+	hasConflictPolicy := len(planReq.ConflictPolicies) > 0
 	txnErr := ps.Engine.CoreAPI.CoreService().Txn().AutoRunTxn(ctx.Kit.Ctx, ctx.Kit.Header, func() error {
 		// enable host apply on service template
 		updateOption := &metadata.UpdateOption{
@@ -363,7 +370,7 @@ func (ps *ProcServer) ExecServiceTemplateHostApplyRule(ctx *rest.Contexts) {
 			return err
 		}
 
-		// 1、update or add rules.
+		// 1、update or add rules, and save the conflict policies with the rules.
 		rulesOption := make([]metadata.CreateOrUpdateApplyRuleOption, 0)
 		for _, rule := range planReq.AdditionalRules {
 			rulesOption = append(rulesOption, metadata.CreateOrUpdateApplyRuleOption{
@@ -372,13 +379,16 @@ func (ps *ProcServer) ExecServiceTemplateHostApplyRule(ctx *rest.Contexts) {
 				PropertyValue:     rule.PropertyValue,
 			})
 		}
-		saveRuleOp := metadata.BatchCreateOrUpdateApplyRuleOption{Rules: rulesOption}
-		if _, ccErr := ps.CoreAPI.CoreService().HostApplyRule().BatchUpdateHostApplyRule(ctx.Kit.Ctx, ctx.Kit.Header,
-			planReq.BizID, saveRuleOp); ccErr != nil {
+		saveRuleOp := metadata.BatchCreateOrUpdateApplyRuleOption{Rules: rulesOption,
+			ConflictPolicies: planReq.ConflictPolicies}
+		saveRes, ccErr := ps.CoreAPI.CoreService().HostApplyRule().BatchUpdateHostApplyRule(ctx.Kit.Ctx,
+			ctx.Kit.Header, planReq.BizID, saveRuleOp)
+		if ccErr != nil {
 			blog.Errorf("update host rule failed, bizID: %s, req: %s, err: %v, rid: %s", planReq.BizID, saveRuleOp,
 				ccErr, rid)
 			return ccErr
 		}
+		hasConflictPolicy = hasConflictPolicy || saveRes.HasConflictPolicy()
 
 		// 2、delete rules.
 		if len(planReq.RemoveRuleIDs) > 0 {
@@ -414,6 +424,19 @@ func (ps *ProcServer) ExecServiceTemplateHostApplyRule(ctx *rest.Contexts) {
 	// update host operation is not done in a transaction, since the successfully updated hosts need not roll back
 	ctx.Kit.Header.Del(common.TransactionIdHeader)
 
+	// when the attributes have conflict policies, the hosts are updated by host server with the apply plan whose
+	// conflicts are resolved by the policies
+	if hasConflictPolicy {
+		ccErr := ps.CoreAPI.HostServer().ExecServiceTemplateApplyPlan(ctx.Kit.Ctx, ctx.Kit.Header, planReq)
+		if ccErr != nil {
+			blog.Errorf("exec service template apply plan failed, req: %#v, err: %v, rid: %s", planReq, ccErr, rid)
+			ctx.RespAutoError(ccErr)
+			return
+		}
+		ctx.RespEntity(nil)
+		return
+	}
+
 	// host apply attribute rules to the host.
 	err = ps.updateHostAttributes(ctx.Kit, planReq, hostIDs)
 	if err != nil {
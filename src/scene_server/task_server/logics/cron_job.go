@@ -0,0 +1,341 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logics
+
+import (
+	"time"
+
+	"configcenter/src/common"
+	"configcenter/src/common/blog"
+	"configcenter/src/common/errors"
+	"configcenter/src/common/http/rest"
+	"configcenter/src/common/mapstr"
+	"configcenter/src/common/metadata"
+	"configcenter/src/common/util"
+	"configcenter/src/scene_server/task_server/taskconfig"
+)
+
+// CreateCronJob create a cron job that creates api task periodically, returns the id of the job
+func (lgc *Logics) CreateCronJob(kit *rest.Kit, opt *metadata.CreateTaskCronJobOption) (int64, error) {
+	if err := opt.Validate(); err != nil {
+		blog.Errorf("create cron job option is invalid, err: %v, rid: %s", err, kit.Rid)
+		return 0, kit.CCError.CCErrorf(common.CCErrCommParamsIsInvalid, err.Error())
+	}
+
+	if !isCodeTaskType(opt.TaskType) {
+		blog.Errorf("cron job task type %s is not supported, rid: %s", opt.TaskType, kit.Rid)
+		return 0, kit.CCError.CCErrorf(common.CCErrCommParamsInvalid, common.BKTaskTypeField)
+	}
+
+	schedule, _ := metadata.ParseCronJobSpec(opt.Spec)
+
+	id, err := lgc.db.NextSequence(kit.Ctx, common.BKTableNameAPITaskCronJob)
+	if err != nil {
+		blog.Errorf("generate cron job id failed, err: %v, rid: %s", err, kit.Rid)
+		return 0, kit.CCError.CCError(common.CCErrCommDBInsertFailed)
+	}
+
+	now := time.Now()
+	job := &metadata.TaskCronJob{
+		ID:              int64(id),
+		Name:            opt.Name,
+		Spec:            opt.Spec,
+		TaskType:        opt.TaskType,
+		InstID:          opt.InstID,
+		Data:            opt.Data,
+		Enabled:         !opt.Disabled,
+		OverlapPolicy:   opt.OverlapPolicy,
+		Header:          GetDBHTTPHeader(kit.Header),
+		NextRunTime:     schedule.Next(now),
+		SupplierAccount: kit.SupplierAccount,
+		Creator:         kit.User,
+		Modifier:        kit.User,
+		CreateTime:      now,
+		LastTime:        now,
+	}
+
+	if err := lgc.db.Table(common.BKTableNameAPITaskCronJob).Insert(kit.Ctx, job); err != nil {
+		blog.Errorf("create cron job %+v failed, err: %v, rid: %s", job, err, kit.Rid)
+		if lgc.db.IsDuplicatedError(err) {
+			return 0, kit.CCError.CCErrorf(common.CCErrCommDuplicateItem, common.BKFieldName)
+		}
+		return 0, kit.CCError.CCError(common.CCErrCommDBInsertFailed)
+	}
+
+	return job.ID, nil
+}
+
+// UpdateCronJob update a cron job, the job is rescheduled from now on if its spec is changed or it is enabled.
+func (lgc *Logics) UpdateCronJob(kit *rest.Kit, id int64, opt *metadata.UpdateTaskCronJobOption) error {
+	if err := opt.Validate(); err != nil {
+		blog.Errorf("update cron job option is invalid, err: %v, rid: %s", err, kit.Rid)
+		return kit.CCError.CCErrorf(common.CCErrCommParamsIsInvalid, err.Error())
+	}
+
+	job, err := lgc.GetCronJob(kit, id)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	update := mapstr.MapStr{
+		"header":             GetDBHTTPHeader(kit.Header),
+		common.ModifierField: kit.User,
+		common.LastTimeField: now,
+	}
+	if opt.Name != nil {
+		update[common.BKFieldName] = *opt.Name
+	}
+	if opt.InstID != nil {
+		update[common.BKInstIDField] = *opt.InstID
+	}
+	if opt.Data != nil {
+		update["data"] = opt.Data
+	}
+	if opt.OverlapPolicy != nil {
+		update["overlap_policy"] = *opt.OverlapPolicy
+	}
+	if opt.Enabled != nil {
+		update["enabled"] = *opt.Enabled
+	}
+
+	spec := job.Spec
+	if opt.Spec != nil {
+		spec = *opt.Spec
+		update["spec"] = spec
+	}
+	if opt.Spec != nil || (opt.Enabled != nil && *opt.Enabled && !job.Enabled) {
+		schedule, _ := metadata.ParseCronJobSpec(spec)
+		update["next_run_time"] = schedule.Next(now)
+	}
+
+	filter := cronJobFilter(kit, id)
+	if err := lgc.db.Table(common.BKTableNameAPITaskCronJob).Update(kit.Ctx, filter, update); err != nil {
+		blog.Errorf("update cron job %d failed, data: %+v, err: %v, rid: %s", id, update, err, kit.Rid)
+		if lgc.db.IsDuplicatedError(err) {
+			return kit.CCError.CCErrorf(common.CCErrCommDuplicateItem, common.BKFieldName)
+		}
+		return kit.CCError.CCError(common.CCErrCommDBUpdateFailed)
+	}
+
+	return nil
+}
+
+// DeleteCronJob delete a cron job and its run history, the tasks created by the job are not deleted.
+func (lgc *Logics) DeleteCronJob(kit *rest.Kit, id int64) error {
+	if _, err := lgc.GetCronJob(kit, id); err != nil {
+		return err
+	}
+
+	if err := lgc.db.Table(common.BKTableNameAPITaskCronJob).Delete(kit.Ctx, cronJobFilter(kit, id)); err != nil {
+		blog.Errorf("delete cron job %d failed, err: %v, rid: %s", id, err, kit.Rid)
+		return kit.CCError.CCError(common.CCErrCommDBDeleteFailed)
+	}
+
+	historyFilter := mapstr.MapStr{"job_id": id}
+	if err := lgc.db.Table(common.BKTableNameAPITaskCronJobHistory).Delete(kit.Ctx, historyFilter); err != nil {
+		blog.Errorf("delete cron job %d history failed, err: %v, rid: %s", id, err, kit.Rid)
+		return kit.CCError.CCError(common.CCErrCommDBDeleteFailed)
+	}
+
+	return nil
+}
+
+// GetCronJob get a cron job by id
+func (lgc *Logics) GetCronJob(kit *rest.Kit, id int64) (*metadata.TaskCronJob, error) {
+	job := new(metadata.TaskCronJob)
+	err := lgc.db.Table(common.BKTableNameAPITaskCronJob).Find(cronJobFilter(kit, id)).One(kit.Ctx, job)
+	if err != nil {
+		if lgc.db.IsNotFoundError(err) {
+			blog.Errorf("cron job %d is not exist, rid: %s", id, kit.Rid)
+			return nil, kit.CCError.CCError(common.CCErrCommNotFound)
+		}
+		blog.Errorf("get cron job %d failed, err: %v, rid: %s", id, err, kit.Rid)
+		return nil, kit.CCError.CCError(common.CCErrCommDBSelectFailed)
+	}
+	return job, nil
+}
+
+// ListCronJob list cron jobs
+func (lgc *Logics) ListCronJob(kit *rest.Kit, opt *metadata.ListTaskCronJobOption) (
+	*metadata.ListTaskCronJobResult, error) {
+
+	if rawErr := opt.Page.ValidateWithEnableCount(false); rawErr.ErrCode != 0 {
+		return nil, rawErr.ToCCError(kit.CCError)
+	}
+
+	filter := util.SetQueryOwner(mapstr.MapStr{}, kit.SupplierAccount)
+	if len(opt.IDs) > 0 {
+		filter[common.BKFieldID] = mapstr.MapStr{common.BKDBIN: opt.IDs}
+	}
+	if len(opt.TaskType) > 0 {
+		filter[common.BKTaskTypeField] = opt.TaskType
+	}
+
+	table := lgc.db.Table(common.BKTableNameAPITaskCronJob)
+	if opt.Page.EnableCount {
+		count, err := table.Find(filter).Count(kit.Ctx)
+		if err != nil {
+			blog.Errorf("count cron jobs failed, filter: %+v, err: %v, rid: %s", filter, err, kit.Rid)
+			return nil, kit.CCError.CCError(common.CCErrCommDBSelectFailed)
+		}
+		return &metadata.ListTaskCronJobResult{Count: count}, nil
+	}
+
+	sort := opt.Page.Sort
+	if len(sort) == 0 {
+		sort = common.BKFieldID
+	}
+
+	jobs := make([]metadata.TaskCronJob, 0)
+	err := table.Find(filter).Sort(sort).Start(uint64(opt.Page.Start)).Limit(uint64(opt.Page.Limit)).
+		All(kit.Ctx, &jobs)
+	if err != nil {
+		blog.Errorf("list cron jobs failed, filter: %+v, err: %v, rid: %s", filter, err, kit.Rid)
+		return nil, kit.CCError.CCError(common.CCErrCommDBSelectFailed)
+	}
+
+	return &metadata.ListTaskCronJobResult{Info: jobs}, nil
+}
+
+// ListCronJobHistory list the run history of a cron job, the latest run is returned first by default
+func (lgc *Logics) ListCronJobHistory(kit *rest.Kit, id int64, opt *metadata.ListTaskCronJobHistoryOption) (
+	*metadata.ListTaskCronJobHistoryResult, error) {
+
+	if rawErr := opt.Page.ValidateWithEnableCount(false); rawErr.ErrCode != 0 {
+		return nil, rawErr.ToCCError(kit.CCError)
+	}
+
+	if _, err := lgc.GetCronJob(kit, id); err != nil {
+		return nil, err
+	}
+
+	filter := mapstr.MapStr{"job_id": id}
+	table := lgc.db.Table(common.BKTableNameAPITaskCronJobHistory)
+	if opt.Page.EnableCount {
+		count, err := table.Find(filter).Count(kit.Ctx)
+		if err != nil {
+			blog.Errorf("count cron job %d history failed, err: %v, rid: %s", id, err, kit.Rid)
+			return nil, kit.CCError.CCError(common.CCErrCommDBSelectFailed)
+		}
+		return &metadata.ListTaskCronJobHistoryResult{Count: count}, nil
+	}
+
+	sort := opt.Page.Sort
+	if len(sort) == 0 {
+		sort = "-" + common.CreateTimeField
+	}
+
+	history := make([]metadata.TaskCronJobRun, 0)
+	err := table.Find(filter).Sort(sort).Start(uint64(opt.Page.Start)).Limit(uint64(opt.Page.Limit)).
+		All(kit.Ctx, &history)
+	if err != nil {
+		blog.Errorf("list cron job %d history failed, err: %v, rid: %s", id, err, kit.Rid)
+		return nil, kit.CCError.CCError(common.CCErrCommDBSelectFailed)
+	}
+
+	return &metadata.ListTaskCronJobHistoryResult{Info: history}, nil
+}
+
+// RunCronJob create the task of the cron job, then record the run history and schedule the next run.
+// if there is an unfinished task of the same task type and instance, the run is skipped or delayed by the
+// overlap policy of the job, the delayed run is not recorded and is retried when the job is checked next time.
+func (lgc *Logics) RunCronJob(kit *rest.Kit, job *metadata.TaskCronJob, now time.Time) error {
+	schedule, err := metadata.ParseCronJobSpec(job.Spec)
+	if err != nil {
+		blog.Errorf("parse cron job %d spec failed, err: %v, rid: %s", job.ID, err, kit.Rid)
+		return kit.CCError.CCErrorf(common.CCErrCommParamsIsInvalid, err.Error())
+	}
+
+	task, err := lgc.Create(kit, &metadata.CreateTaskRequest{
+		TaskType: job.TaskType,
+		InstID:   job.InstID,
+		Data:     job.Data,
+	})
+	if err != nil && !isTaskConflictErr(err) {
+		blog.Errorf("create task for cron job %d failed, err: %v, rid: %s", job.ID, err, kit.Rid)
+	}
+
+	run, delayed := genCronJobRun(job, task, err, now)
+	if delayed {
+		blog.Infof("cron job %d has unfinished task, delay the run, rid: %s", job.ID, kit.Rid)
+		return nil
+	}
+
+	if err := lgc.db.Table(common.BKTableNameAPITaskCronJobHistory).Insert(kit.Ctx, run); err != nil {
+		blog.Errorf("create cron job %d history %+v failed, err: %v, rid: %s", job.ID, run, err, kit.Rid)
+		return kit.CCError.CCError(common.CCErrCommDBInsertFailed)
+	}
+
+	filter := mapstr.MapStr{common.BKFieldID: job.ID}
+	update := mapstr.MapStr{
+		"last_run":      run,
+		"next_run_time": schedule.Next(now),
+	}
+	if err := lgc.db.Table(common.BKTableNameAPITaskCronJob).Update(kit.Ctx, filter, update); err != nil {
+		blog.Errorf("update cron job %d run result failed, data: %+v, err: %v, rid: %s", job.ID, update, err, kit.Rid)
+		return kit.CCError.CCError(common.CCErrCommDBUpdateFailed)
+	}
+
+	blog.Infof("run cron job %d done, status: %s, task id: %s, rid: %s", job.ID, run.Status, run.TaskID, kit.Rid)
+	return nil
+}
+
+// genCronJobRun generate the run history of the cron job by the task creation result, returns true if the run
+// is delayed by the overlap policy, the delayed run is not recorded.
+func genCronJobRun(job *metadata.TaskCronJob, task metadata.APITaskDetail, createErr error, now time.Time) (
+	*metadata.TaskCronJobRun, bool) {
+
+	run := &metadata.TaskCronJobRun{
+		JobID:           job.ID,
+		ScheduleTime:    job.NextRunTime,
+		SupplierAccount: job.SupplierAccount,
+		CreateTime:      now,
+	}
+
+	switch {
+	case createErr == nil:
+		run.Status = metadata.CronJobRunCreated
+		run.TaskID = task.TaskID
+	case isTaskConflictErr(createErr):
+		if job.OverlapPolicy == metadata.CronJobOverlapDelay {
+			return nil, true
+		}
+		run.Status = metadata.CronJobRunSkipped
+		run.Message = createErr.Error()
+	default:
+		run.Status = metadata.CronJobRunFailed
+		run.Message = createErr.Error()
+	}
+
+	return run, false
+}
+
+func cronJobFilter(kit *rest.Kit, id int64) mapstr.MapStr {
+	return util.SetQueryOwner(mapstr.MapStr{common.BKFieldID: id}, kit.SupplierAccount)
+}
+
+// isCodeTaskType check if the task type is one of the task queues that are defined in code
+func isCodeTaskType(taskType string) bool {
+	for _, config := range taskconfig.GetCodeTaskConfig() {
+		if config.Name == taskType {
+			return true
+		}
+	}
+	return false
+}
+
+func isTaskConflictErr(err error) bool {
+	ccErr, ok := err.(errors.CCErrorCoder)
+	return ok && ccErr.GetCode() == common.CCErrTaskCreateConflict
+}
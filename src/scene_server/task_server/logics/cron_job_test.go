@@ -0,0 +1,160 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logics
+
+import (
+	"testing"
+	"time"
+
+	"configcenter/src/common"
+	"configcenter/src/common/errors"
+	"configcenter/src/common/metadata"
+)
+
+func TestGenCronJobRun(t *testing.T) {
+	now := time.Date(2022, 10, 17, 10, 0, 5, 0, time.UTC)
+	scheduled := time.Date(2022, 10, 17, 10, 0, 0, 0, time.UTC)
+	conflictErr := errors.New(common.CCErrTaskCreateConflict, "task is not finished")
+	otherErr := errors.New(common.CCErrCommDBInsertFailed, "insert failed")
+
+	cases := []struct {
+		name      string
+		policy    metadata.TaskCronJobOverlapPolicy
+		createErr error
+		delayed   bool
+		status    metadata.TaskCronJobRunStatus
+		taskID    string
+	}{
+		{name: "task created", policy: metadata.CronJobOverlapSkip, status: metadata.CronJobRunCreated,
+			taskID: "task1"},
+		{name: "conflict with skip policy", policy: metadata.CronJobOverlapSkip, createErr: conflictErr,
+			status: metadata.CronJobRunSkipped},
+		{name: "conflict with delay policy", policy: metadata.CronJobOverlapDelay, createErr: conflictErr,
+			delayed: true},
+		{name: "other error with delay policy", policy: metadata.CronJobOverlapDelay, createErr: otherErr,
+			status: metadata.CronJobRunFailed},
+	}
+
+	for _, c := range cases {
+		job := &metadata.TaskCronJob{ID: 1, OverlapPolicy: c.policy, NextRunTime: scheduled, SupplierAccount: "0"}
+		task := metadata.APITaskDetail{}
+		if c.createErr == nil {
+			task.TaskID = "task1"
+		}
+
+		run, delayed := genCronJobRun(job, task, c.createErr, now)
+		if delayed != c.delayed {
+			t.Errorf("%s: expect delayed %v, but got %v", c.name, c.delayed, delayed)
+			continue
+		}
+
+		if delayed {
+			if run != nil {
+				t.Errorf("%s: delayed run should not be recorded, but got %+v", c.name, run)
+			}
+			continue
+		}
+
+		if run.Status != c.status || run.TaskID != c.taskID {
+			t.Errorf("%s: expect status %s task %s, but got %s task %s", c.name, c.status, c.taskID, run.Status,
+				run.TaskID)
+		}
+
+		if !run.ScheduleTime.Equal(scheduled) || !run.CreateTime.Equal(now) || run.JobID != 1 {
+			t.Errorf("%s: run times or job id is invalid: %+v", c.name, run)
+		}
+
+		if c.createErr != nil && run.Message != c.createErr.Error() {
+			t.Errorf("%s: run message should be the create error, but got %s", c.name, run.Message)
+		}
+	}
+}
+
+func TestParseCronJobSpec(t *testing.T) {
+	base := time.Date(2022, 10, 17, 10, 7, 0, 0, time.Local)
+
+	cases := []struct {
+		spec string
+		next time.Time
+	}{
+		{spec: "*/15 * * * *", next: time.Date(2022, 10, 17, 10, 15, 0, 0, time.Local)},
+		{spec: "30 2 * * *", next: time.Date(2022, 10, 18, 2, 30, 0, 0, time.Local)},
+		{spec: "@daily", next: time.Date(2022, 10, 18, 0, 0, 0, 0, time.Local)},
+		{spec: "@every 1h30m", next: base.Add(90 * time.Minute)},
+	}
+
+	for _, c := range cases {
+		schedule, err := metadata.ParseCronJobSpec(c.spec)
+		if err != nil {
+			t.Errorf("parse spec %s failed, err: %v", c.spec, err)
+			continue
+		}
+
+		if next := schedule.Next(base); !next.Equal(c.next) {
+			t.Errorf("spec %s next run time should be %s, but got %s", c.spec, c.next, next)
+		}
+	}
+
+	for _, spec := range []string{"", " ", "* * *", "61 * * * *", "0 0 * * * *"} {
+		if _, err := metadata.ParseCronJobSpec(spec); err == nil {
+			t.Errorf("invalid spec %q should not be parsed", spec)
+		}
+	}
+}
+
+func TestCreateTaskCronJobOptionValidate(t *testing.T) {
+	opt := &metadata.CreateTaskCronJobOption{Name: "sync", Spec: "@hourly", TaskType: "cloud_sync",
+		Data: []interface{}{map[string]interface{}{"id": 1}}}
+	if err := opt.Validate(); err != nil {
+		t.Fatalf("valid option is rejected, err: %v", err)
+	}
+
+	if opt.OverlapPolicy != metadata.CronJobOverlapSkip {
+		t.Errorf("default overlap policy should be skip, but got %s", opt.OverlapPolicy)
+	}
+
+	invalid := []metadata.CreateTaskCronJobOption{
+		{Spec: "@hourly", TaskType: "cloud_sync", Data: []interface{}{1}},
+		{Name: "sync", Spec: "bad", TaskType: "cloud_sync", Data: []interface{}{1}},
+		{Name: "sync", Spec: "@hourly", Data: []interface{}{1}},
+		{Name: "sync", Spec: "@hourly", TaskType: "cloud_sync"},
+		{Name: "sync", Spec: "@hourly", TaskType: "cloud_sync", Data: []interface{}{1}, OverlapPolicy: "replace"},
+	}
+	for idx := range invalid {
+		if err := invalid[idx].Validate(); err == nil {
+			t.Errorf("invalid option %+v should be rejected", invalid[idx])
+		}
+	}
+}
+
+func TestUpdateTaskCronJobOptionValidate(t *testing.T) {
+	empty := ""
+	badSpec := "bad"
+	badPolicy := metadata.TaskCronJobOverlapPolicy("replace")
+
+	invalid := []metadata.UpdateTaskCronJobOption{
+		{Name: &empty},
+		{Spec: &badSpec},
+		{Data: []interface{}{}},
+		{OverlapPolicy: &badPolicy},
+	}
+	for idx := range invalid {
+		if err := invalid[idx].Validate(); err == nil {
+			t.Errorf("invalid update option %d should be rejected", idx)
+		}
+	}
+
+	if err := (&metadata.UpdateTaskCronJobOption{}).Validate(); err != nil {
+		t.Errorf("empty update option should be valid, err: %v", err)
+	}
+}
@@ -47,7 +47,7 @@ func (lgc *Logics) Create(kit *rest.Kit, input *metadata.CreateTaskRequest) (met
 		common.BKInstIDField:   input.InstID,
 		common.BKStatusField: map[string]interface{}{
 			common.BKDBIN: []metadata.APITaskStatus{metadata.APITaskStatusNew, metadata.APITaskStatusWaitExecute,
-				metadata.APITaskStatusExecute},
+				metadata.APITaskStatusExecute, metadata.APITaskStatusPaused},
 		},
 	}
 
@@ -72,6 +72,7 @@ func (lgc *Logics) Create(kit *rest.Kit, input *metadata.CreateTaskRequest) (met
 	dbTask.User = kit.User
 	dbTask.TaskType = input.TaskType
 	dbTask.InstID = input.InstID
+	dbTask.Priority = input.Priority
 	dbTask.Header = GetDBHTTPHeader(kit.Header)
 	dbTask.Status = metadata.APITaskStatusNew
 	dbTask.CreateTime = time.Now()
@@ -152,6 +153,7 @@ func (lgc *Logics) CreateBatch(kit *rest.Kit, tasks []metadata.CreateTaskRequest
 		dbTask.TaskID = getStrTaskID("id")
 		dbTask.TaskType = task.TaskType
 		dbTask.InstID = task.InstID
+		dbTask.Priority = task.Priority
 		dbTask.Detail = make([]metadata.APISubTaskDetail, 0)
 		for _, taskItem := range task.Data {
 			dbTask.Detail = append(dbTask.Detail, metadata.APISubTaskDetail{
@@ -175,7 +177,7 @@ func (lgc *Logics) CreateBatch(kit *rest.Kit, tasks []metadata.CreateTaskRequest
 		common.BKInstIDField:   map[string]interface{}{common.BKDBIN: instIDs},
 		common.BKStatusField: map[string]interface{}{
 			common.BKDBIN: []metadata.APITaskStatus{metadata.APITaskStatusNew, metadata.APITaskStatusWaitExecute,
-				metadata.APITaskStatusExecute},
+				metadata.APITaskStatusExecute, metadata.APITaskStatusPaused},
 		},
 	}
 
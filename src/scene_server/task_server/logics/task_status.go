@@ -0,0 +1,155 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logics
+
+import (
+	"context"
+	"time"
+
+	"configcenter/src/common"
+	"configcenter/src/common/blog"
+	"configcenter/src/common/http/rest"
+	"configcenter/src/common/mapstr"
+	"configcenter/src/common/metadata"
+)
+
+// changeTaskStatusMaxRetry is the max retry times when the task status is changed by the task executor concurrently
+const changeTaskStatusMaxRetry = 3
+
+// CancelTask cancel a task that is not finished, the sub tasks that are not executed will not be executed.
+// if the task is executing, the executing sub task is not interrupted, the task executor stops after it is done.
+func (lgc *Logics) CancelTask(kit *rest.Kit, taskID string) error {
+	return lgc.changeTaskStatus(kit, taskID, metadata.APITaskStatusCanceled)
+}
+
+// PauseTask pause a task that is not finished, the sub tasks that are not executed will be executed after the task
+// is resumed. if the task is executing, the executing sub task is not interrupted, the task executor stops after
+// it is done.
+func (lgc *Logics) PauseTask(kit *rest.Kit, taskID string) error {
+	return lgc.changeTaskStatus(kit, taskID, metadata.APITaskStatusPaused)
+}
+
+// ResumeTask resume a paused task, it is put back to the wait execute queue.
+func (lgc *Logics) ResumeTask(kit *rest.Kit, taskID string) error {
+	return lgc.changeTaskStatus(kit, taskID, metadata.APITaskStatusWaitExecute)
+}
+
+func (lgc *Logics) changeTaskStatus(kit *rest.Kit, taskID string, status metadata.APITaskStatus) error {
+	for retry := 0; retry < changeTaskStatusMaxRetry; retry++ {
+		task, err := lgc.Detail(kit, taskID)
+		if err != nil {
+			return err
+		}
+
+		if task == nil {
+			blog.Errorf("task %s is not exist, rid: %s", taskID, kit.Rid)
+			return kit.CCError.CCError(common.CCErrTaskNotFound)
+		}
+
+		if !task.Status.CanChangeTo(status) {
+			blog.Errorf("task %s status %s can not change to %s, rid: %s", taskID, task.Status, status, kit.Rid)
+			return kit.CCError.CCErrorf(common.CCErrTaskStatusNotAllowChangeTo, status)
+		}
+
+		// only the status of the executing task is changed, its sub tasks are handled by the task executor
+		cond := mapstr.MapStr{
+			common.BKTaskIDField: taskID,
+			common.BKStatusField: task.Status,
+		}
+		update := mapstr.MapStr{
+			common.BKStatusField: status,
+			common.LastTimeField: time.Now(),
+		}
+		if status == metadata.APITaskStatusCanceled && task.Status != metadata.APITaskStatusExecute {
+			update["detail"] = cancelSubTasks(task.Detail)
+		}
+
+		cnt, err := lgc.db.Table(common.BKTableNameAPITask).UpdateMany(kit.Ctx, cond, update)
+		if err != nil {
+			blog.Errorf("update task status failed, cond: %#v, data: %#v, err: %v, rid: %s", cond, update, err, kit.Rid)
+			return kit.CCError.CCError(common.CCErrCommDBUpdateFailed)
+		}
+
+		if cnt == 0 {
+			blog.Infof("task %s status is changed from %s, retry to change it to %s, rid: %s", taskID, task.Status,
+				status, kit.Rid)
+			continue
+		}
+
+		historyCond := mapstr.MapStr{common.BKTaskIDField: taskID}
+		historyUpdate := mapstr.MapStr{
+			common.BKStatusField: status,
+			common.LastTimeField: update[common.LastTimeField],
+		}
+		err = lgc.db.Table(common.BKTableNameAPITaskSyncHistory).Update(kit.Ctx, historyCond, historyUpdate)
+		if err != nil {
+			blog.Errorf("update task %s history status to %s failed, err: %v, rid: %s", taskID, status, err, kit.Rid)
+			return kit.CCError.CCError(common.CCErrCommDBUpdateFailed)
+		}
+
+		blog.Infof("change task %s status from %s to %s, user: %s, rid: %s", taskID, task.Status, status, kit.User,
+			kit.Rid)
+		return nil
+	}
+
+	return kit.CCError.CCErrorf(common.CCErrTaskStatusNotAllowChangeTo, status)
+}
+
+// CancelUnfinishedSubTasks set the status of the canceled task's sub tasks that are not executed to canceled,
+// it is used by the task executor after the executing task is canceled.
+func (lgc *Logics) CancelUnfinishedSubTasks(ctx context.Context, taskID string, rid string) error {
+	cond := mapstr.MapStr{
+		common.BKTaskIDField: taskID,
+		common.BKStatusField: metadata.APITaskStatusCanceled,
+	}
+
+	task := new(metadata.APITaskDetail)
+	if err := lgc.db.Table(common.BKTableNameAPITask).Find(cond).One(ctx, task); err != nil {
+		blog.Errorf("get canceled task %s failed, err: %v, rid: %s", taskID, err, rid)
+		return err
+	}
+
+	update := mapstr.MapStr{
+		"detail":             cancelSubTasks(task.Detail),
+		common.LastTimeField: time.Now(),
+	}
+	if err := lgc.db.Table(common.BKTableNameAPITask).Update(ctx, cond, update); err != nil {
+		blog.Errorf("cancel task %s sub tasks failed, err: %v, rid: %s", taskID, err, rid)
+		return err
+	}
+	return nil
+}
+
+// GetTaskStatus get the current status of the task
+func (lgc *Logics) GetTaskStatus(ctx context.Context, taskID string, rid string) (metadata.APITaskStatus, error) {
+	task := new(metadata.APITaskDetail)
+	err := lgc.db.Table(common.BKTableNameAPITask).Find(mapstr.MapStr{common.BKTaskIDField: taskID}).
+		Fields(common.BKStatusField).One(ctx, task)
+	if err != nil {
+		blog.Errorf("get task %s status failed, err: %v, rid: %s", taskID, err, rid)
+		return "", err
+	}
+	return task.Status, nil
+}
+
+// cancelSubTasks returns the sub tasks whose status is changed to canceled if they are not executed
+func cancelSubTasks(subTasks []metadata.APISubTaskDetail) []metadata.APISubTaskDetail {
+	result := make([]metadata.APISubTaskDetail, len(subTasks))
+	for idx, subTask := range subTasks {
+		if subTask.Status == metadata.APITaskStatusNew || subTask.Status == metadata.APITaskStatusWaitExecute {
+			subTask.Status = metadata.APITaskStatusCanceled
+		}
+		result[idx] = subTask
+	}
+	return result
+}
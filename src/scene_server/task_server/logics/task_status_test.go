@@ -0,0 +1,107 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logics
+
+import (
+	"testing"
+
+	"configcenter/src/common/metadata"
+)
+
+func TestTaskStatusCanChangeTo(t *testing.T) {
+	allStatus := []metadata.APITaskStatus{
+		metadata.APITaskStatusNew,
+		metadata.APITaskStatusWaitExecute,
+		metadata.APITaskStatusExecute,
+		metadata.APITaskStatusSuccess,
+		metadata.APITAskStatusFail,
+		metadata.APITaskStatusCanceled,
+		metadata.APITaskStatusPaused,
+	}
+
+	// allowed is the allowed status changes, the others are not allowed
+	allowed := map[metadata.APITaskStatus][]metadata.APITaskStatus{
+		metadata.APITaskStatusCanceled: {metadata.APITaskStatusNew, metadata.APITaskStatusWaitExecute,
+			metadata.APITaskStatusExecute, metadata.APITaskStatusPaused},
+		metadata.APITaskStatusPaused: {metadata.APITaskStatusNew, metadata.APITaskStatusWaitExecute,
+			metadata.APITaskStatusExecute},
+		metadata.APITaskStatusWaitExecute: {metadata.APITaskStatusPaused},
+	}
+
+	for _, target := range allStatus {
+		for _, from := range allStatus {
+			expected := false
+			for _, status := range allowed[target] {
+				if status == from {
+					expected = true
+					break
+				}
+			}
+
+			if from.CanChangeTo(target) != expected {
+				t.Errorf("status %s change to %s should be %v", from, target, expected)
+			}
+		}
+	}
+}
+
+func TestTaskStatusIsFinished(t *testing.T) {
+	finished := map[metadata.APITaskStatus]bool{
+		metadata.APITaskStatusNew:         false,
+		metadata.APITaskStatusWaitExecute: false,
+		metadata.APITaskStatusExecute:     false,
+		metadata.APITaskStatusPaused:      false,
+		metadata.APITaskStatusSuccess:     true,
+		metadata.APITAskStatusFail:        true,
+		metadata.APITaskStatusCanceled:    true,
+	}
+
+	for status, expected := range finished {
+		if status.IsFinished() != expected {
+			t.Errorf("status %s finished should be %v", status, expected)
+		}
+	}
+}
+
+func TestCancelSubTasks(t *testing.T) {
+	subTasks := []metadata.APISubTaskDetail{
+		{SubTaskID: "1", Status: metadata.APITaskStatusSuccess},
+		{SubTaskID: "2", Status: metadata.APITAskStatusFail},
+		{SubTaskID: "3", Status: metadata.APITaskStatusExecute},
+		{SubTaskID: "4", Status: metadata.APITaskStatusWaitExecute},
+		{SubTaskID: "5", Status: metadata.APITaskStatusNew},
+	}
+
+	expected := []metadata.APITaskStatus{
+		metadata.APITaskStatusSuccess,
+		metadata.APITAskStatusFail,
+		metadata.APITaskStatusExecute,
+		metadata.APITaskStatusCanceled,
+		metadata.APITaskStatusCanceled,
+	}
+
+	result := cancelSubTasks(subTasks)
+	if len(result) != len(subTasks) {
+		t.Fatalf("sub tasks count should be %d, but got %d", len(subTasks), len(result))
+	}
+
+	for idx, subTask := range result {
+		if subTask.Status != expected[idx] || subTask.SubTaskID != subTasks[idx].SubTaskID {
+			t.Errorf("sub task %s status should be %s, but got %s", subTask.SubTaskID, expected[idx], subTask.Status)
+		}
+	}
+
+	if subTasks[3].Status != metadata.APITaskStatusWaitExecute {
+		t.Errorf("the original sub tasks should not be changed")
+	}
+}
@@ -0,0 +1,152 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"strconv"
+
+	"configcenter/src/common"
+	"configcenter/src/common/blog"
+	"configcenter/src/common/http/rest"
+	"configcenter/src/common/metadata"
+)
+
+// CreateCronJob create a cron job that creates api task periodically
+func (s *Service) CreateCronJob(ctx *rest.Contexts) {
+	opt := new(metadata.CreateTaskCronJobOption)
+	if err := ctx.DecodeInto(opt); err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	id, err := s.Logics.CreateCronJob(ctx.Kit, opt)
+	if err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	ctx.RespEntity(metadata.RspID{ID: id})
+}
+
+// UpdateCronJob update a cron job, the task type of the job can not be changed
+func (s *Service) UpdateCronJob(ctx *rest.Contexts) {
+	id, err := parseCronJobID(ctx)
+	if err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	opt := new(metadata.UpdateTaskCronJobOption)
+	if err := ctx.DecodeInto(opt); err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	if err := s.Logics.UpdateCronJob(ctx.Kit, id, opt); err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	ctx.RespEntity(nil)
+}
+
+// EnableCronJob enable a cron job, it is scheduled from now on
+func (s *Service) EnableCronJob(ctx *rest.Contexts) {
+	s.setCronJobEnabled(ctx, true)
+}
+
+// DisableCronJob disable a cron job, the tasks that are already created are not affected
+func (s *Service) DisableCronJob(ctx *rest.Contexts) {
+	s.setCronJobEnabled(ctx, false)
+}
+
+func (s *Service) setCronJobEnabled(ctx *rest.Contexts, enabled bool) {
+	id, err := parseCronJobID(ctx)
+	if err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	if err := s.Logics.UpdateCronJob(ctx.Kit, id, &metadata.UpdateTaskCronJobOption{Enabled: &enabled}); err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	ctx.RespEntity(nil)
+}
+
+// DeleteCronJob delete a cron job and its run history
+func (s *Service) DeleteCronJob(ctx *rest.Contexts) {
+	id, err := parseCronJobID(ctx)
+	if err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	if err := s.Logics.DeleteCronJob(ctx.Kit, id); err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	ctx.RespEntity(nil)
+}
+
+// ListCronJob list cron jobs
+func (s *Service) ListCronJob(ctx *rest.Contexts) {
+	opt := new(metadata.ListTaskCronJobOption)
+	if err := ctx.DecodeInto(opt); err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	result, err := s.Logics.ListCronJob(ctx.Kit, opt)
+	if err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	ctx.RespEntity(result)
+}
+
+// ListCronJobHistory list the run history of a cron job
+func (s *Service) ListCronJobHistory(ctx *rest.Contexts) {
+	id, err := parseCronJobID(ctx)
+	if err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	opt := new(metadata.ListTaskCronJobHistoryOption)
+	if err := ctx.DecodeInto(opt); err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	result, err := s.Logics.ListCronJobHistory(ctx.Kit, id, opt)
+	if err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	ctx.RespEntity(result)
+}
+
+func parseCronJobID(ctx *rest.Contexts) (int64, error) {
+	id, err := strconv.ParseInt(ctx.Request.PathParameter(common.BKFieldID), 10, 64)
+	if err != nil || id <= 0 {
+		blog.Errorf("cron job id %s is invalid, err: %v, rid: %s", ctx.Request.PathParameter(common.BKFieldID), err,
+			ctx.Kit.Rid)
+		return 0, ctx.Kit.CCError.CCErrorf(common.CCErrCommParamsIsInvalid, common.BKFieldID)
+	}
+	return id, nil
+}
@@ -0,0 +1,151 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"configcenter/src/common"
+	"configcenter/src/common/blog"
+	"configcenter/src/common/http/rest"
+	"configcenter/src/common/mapstr"
+	"configcenter/src/common/metadata"
+	"configcenter/src/common/util"
+	"configcenter/src/scene_server/task_server/logics"
+)
+
+const (
+	// cronJobCheckInterval is the interval to check if there are cron jobs that need to run
+	cronJobCheckInterval = 20 * time.Second
+	// cronJobLockTTL is the expire time of the cron job lock in minutes
+	cronJobLockTTL = 1
+	// cronJobHistoryExpire is the time after which the cron job run history is deleted
+	cronJobHistoryExpire = 60 * 24 * time.Hour
+)
+
+// scheduleCronJobs loop check the enabled cron jobs and run the ones that reach their next run time. the cron
+// jobs are run only by the master, and each run is locked with the task lock, so that a job is never run by
+// two task servers at the same time, even if the master is switched.
+func (tq *TaskQueue) scheduleCronJobs(ctx context.Context) {
+	ticker := time.NewTicker(cronJobCheckInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if tq.close {
+			return
+		}
+
+		if !tq.service.Engine.ServiceManageInterface.IsMaster() {
+			blog.V(4).Infof("schedule cron jobs, but is not master, skip")
+			continue
+		}
+
+		tq.runDueCronJobs(ctx)
+	}
+}
+
+func (tq *TaskQueue) runDueCronJobs(ctx context.Context) {
+	defer func() {
+		if fetalErr := recover(); fetalErr != nil {
+			blog.Errorf("run cron jobs panic, err: %v, stack: %s", fetalErr, debug.Stack())
+		}
+	}()
+
+	now := time.Now()
+	cond := mapstr.MapStr{
+		"enabled":       true,
+		"next_run_time": mapstr.MapStr{common.BKDBLTE: now},
+	}
+
+	jobs := make([]metadata.TaskCronJob, 0)
+	err := tq.service.DB.Table(common.BKTableNameAPITaskCronJob).Find(cond).Sort("next_run_time").
+		Limit(common.BKMaxPageSize).All(ctx, &jobs)
+	if err != nil {
+		blog.Errorf("get cron jobs to run failed, cond: %+v, err: %v", cond, err)
+		return
+	}
+
+	for idx := range jobs {
+		if tq.close {
+			return
+		}
+		tq.runCronJob(ctx, &jobs[idx])
+	}
+}
+
+func (tq *TaskQueue) runCronJob(ctx context.Context, job *metadata.TaskCronJob) {
+	header := logics.GetDBHTTPHeader(job.Header)
+	header.Set(common.BKHTTPCCRequestID, util.GenerateRID())
+	kit := rest.NewKitFromHeader(header, tq.service.CCErr)
+	kit.Ctx = ctx
+
+	lockID := cronJobLockID(job.ID)
+	locked, err := tq.lockTask(ctx, lockID, cronJobLockTTL)
+	if err != nil {
+		blog.Errorf("lock cron job %d failed, err: %v, rid: %s", job.ID, err, kit.Rid)
+		return
+	}
+	if !locked {
+		blog.Infof("cron job %d is locked, skip, rid: %s", job.ID, kit.Rid)
+		return
+	}
+
+	defer func() {
+		if err := tq.unLockTask(ctx, lockID); err != nil {
+			blog.Errorf("unlock cron job %d failed, err: %v, rid: %s", job.ID, err, kit.Rid)
+		}
+	}()
+
+	// check again with the lock, in case the job is already run or changed before the lock is acquired
+	cond := mapstr.MapStr{
+		common.BKFieldID: job.ID,
+		"enabled":        true,
+		"next_run_time":  job.NextRunTime,
+	}
+	count, err := tq.service.DB.Table(common.BKTableNameAPITaskCronJob).Find(cond).Count(ctx)
+	if err != nil {
+		blog.Errorf("check cron job %d failed, cond: %+v, err: %v, rid: %s", job.ID, cond, err, kit.Rid)
+		return
+	}
+	if count == 0 {
+		blog.Infof("cron job %d is already run or changed, skip, rid: %s", job.ID, kit.Rid)
+		return
+	}
+
+	blog.Infof("start run cron job %d(%s), task type: %s, rid: %s", job.ID, job.Name, job.TaskType, kit.Rid)
+	if err := tq.service.Logics.RunCronJob(kit, job, time.Now()); err != nil {
+		blog.Errorf("run cron job %d failed, err: %v, rid: %s", job.ID, err, kit.Rid)
+	}
+}
+
+func cronJobLockID(id int64) string {
+	return fmt.Sprintf("cronJob:%d", id)
+}
+
+// deleteRedundancyCronJobHistory delete cron job run history from two month ago
+func (s *Service) deleteRedundancyCronJobHistory(ctx context.Context, rid string) error {
+	cond := map[string]interface{}{
+		common.CreateTimeField: map[string]interface{}{
+			common.BKDBLT: time.Now().Add(-cronJobHistoryExpire),
+		},
+	}
+
+	if err := s.DB.Table(common.BKTableNameAPITaskCronJobHistory).Delete(ctx, cond); err != nil {
+		blog.Errorf("delete redundancy cron job history failed, err: %v, rid: %s", err, rid)
+		return err
+	}
+	return nil
+}
@@ -21,6 +21,7 @@ import (
 
 	taskUtil "configcenter/src/apimachinery/taskserver/util"
 	"configcenter/src/common"
+	cc "configcenter/src/common/backbone/configcenter"
 	"configcenter/src/common/blog"
 	"configcenter/src/common/http/rest"
 	"configcenter/src/common/mapstr"
@@ -42,6 +43,8 @@ type TaskInfo struct {
 	Path    string
 	Retry   int64
 	LockTTL int64
+	// Concurrency the max number of tasks that are executed at the same time in the task queue
+	Concurrency int
 }
 
 // TaskQueue TODO
@@ -84,10 +87,11 @@ func (tq *TaskQueue) Stop() {
 // Start TODO
 func (tq *TaskQueue) Start() {
 	go tq.compensate(context.Background())
+	go tq.scheduleCronJobs(context.Background())
 
 	for _, taskInfo := range tq.task {
+		tq.Add(1)
 		go func(taskInfo TaskInfo) {
-			tq.Add(1)
 			defer tq.Done()
 			tq.execute(context.Background(), taskInfo)
 		}(taskInfo)
@@ -110,6 +114,15 @@ func (tq *TaskQueue) execute(ctx context.Context, task TaskInfo) {
 		task.LockTTL = 1
 	}
 
+	if task.Concurrency < 1 {
+		task.Concurrency = 1
+	}
+
+	// slots limits the number of executing tasks, running records the executing task ids so that they are not
+	// executed again before their status is changed to executing.
+	slots := make(chan struct{}, task.Concurrency)
+	running := new(sync.Map)
+
 	for {
 		if tq.close {
 			return
@@ -122,48 +135,69 @@ func (tq *TaskQueue) execute(ctx context.Context, task TaskInfo) {
 			continue
 		}
 
-		canSleep := true
-		taskQueueInfoArr, err := tq.getWaitExecute(ctx, task.Name)
+		// wait until there is a free slot before fetching the tasks, so that the task with the highest priority at
+		// the time is executed
+		slots <- struct{}{}
+
+		taskQueueInfoArr, err := tq.getWaitExecute(ctx, task.Name, task.Concurrency)
 		if err != nil {
+			<-slots
 			blog.Errorf("get wait execute task failed, err: %v, task type: %s", err, task.Name)
 			// select db failed, sleep 10s
 			time.Sleep(time.Second * 10)
 			continue
 		}
 
-		if len(taskQueueInfoArr) == 0 {
-			// no task, sleep 5s
-			time.Sleep(time.Second * 5)
-			continue
-		}
-
-		for _, taskQueueInfo := range taskQueueInfoArr {
-			if tq.close {
-				return
+		var taskQueueInfo *metadata.APITaskDetail
+		for idx := range taskQueueInfoArr {
+			if _, exists := running.LoadOrStore(taskQueueInfoArr[idx].TaskID, struct{}{}); !exists {
+				taskQueueInfo = &taskQueueInfoArr[idx]
+				break
 			}
+		}
 
-			isMaster := tq.service.Engine.ServiceManageInterface.IsMaster()
-			if !isMaster {
-				blog.Infof("execute task %s, but is not master, skip", taskQueueInfo.TaskID)
-				time.Sleep(time.Minute)
+		if taskQueueInfo == nil {
+			<-slots
+			if len(taskQueueInfoArr) == 0 {
+				// no task, sleep 5s
+				time.Sleep(time.Second * 5)
 				continue
 			}
-
-			execute := tq.executeTaskQueueItem(ctx, task, taskQueueInfo)
-			if execute {
-				canSleep = false
-			}
+			// all the fetched tasks are being started, retry soon
+			time.Sleep(time.Second)
+			continue
 		}
 
-		if canSleep {
+		// execute the task asynchronously, started reports whether the task is actually executed
+		started := make(chan bool, 1)
+		tq.Add(1)
+		go func(taskQueueInfo metadata.APITaskDetail) {
+			defer func() {
+				running.Delete(taskQueueInfo.TaskID)
+				<-slots
+				tq.Done()
+			}()
+			tq.executeTaskQueueItem(ctx, task, taskQueueInfo, started)
+		}(*taskQueueInfo)
+
+		// the task is not executed because it is locked or its status is changed, sleep to avoid fetching and
+		// dispatching it again in a tight loop
+		if !<-started {
 			time.Sleep(time.Second * 10)
 		}
 	}
 }
 
-// executeTaskQueueItem 执行异步任务，返回是否执行了任务
+// executeTaskQueueItem 执行异步任务，started 用于通知是否开始执行了任务
 func (tq *TaskQueue) executeTaskQueueItem(ctx context.Context, taskInfo TaskInfo,
-	taskQueueInfo metadata.APITaskDetail) bool {
+	taskQueueInfo metadata.APITaskDetail, started chan<- bool) {
+
+	executed := false
+	defer func() {
+		if !executed {
+			started <- false
+		}
+	}()
 
 	// set timeout and execute the task
 	ctx, cancel := context.WithTimeout(ctx, time.Minute*time.Duration(taskInfo.LockTTL))
@@ -175,12 +209,11 @@ func (tq *TaskQueue) executeTaskQueueItem(ctx context.Context, taskInfo TaskInfo
 	locked, err := tq.lockTask(ctx, taskQueueInfo.TaskID, taskInfo.LockTTL)
 	if err != nil {
 		blog.Errorf("lock task failed, task name: %s, taskID: %s, err: %v", taskInfo.Name, taskQueueInfo.TaskID, err)
-		time.Sleep(time.Second)
-		return false
+		return
 	}
 	if !locked {
 		blog.Errorf("task(type %s, id: %s) is locked, return and retry later", taskInfo.Name, taskQueueInfo.TaskID)
-		return false
+		return
 	}
 
 	defer func() {
@@ -191,17 +224,17 @@ func (tq *TaskQueue) executeTaskQueueItem(ctx context.Context, taskInfo TaskInfo
 
 	canExecute, err := tq.changeTaskToExecuting(ctx, taskQueueInfo.TaskID)
 	if err != nil {
-		time.Sleep(time.Second)
-		return false
+		return
 	}
 
 	blog.Infof("change task %s to executing, can execute %v", taskQueueInfo.TaskID, canExecute)
 	if !canExecute {
-		return false
+		return
 	}
 
+	executed = true
+	started <- true
 	tq.executePush(ctx, taskInfo, &taskQueueInfo)
-	return true
 }
 
 func (tq *TaskQueue) executePush(ctx context.Context, taskInfo TaskInfo, taskQueue *metadata.APITaskDetail) {
@@ -225,10 +258,15 @@ func (tq *TaskQueue) executePush(ctx context.Context, taskInfo TaskInfo, taskQue
 		}
 	}
 
-	// 所有任务执行完成，修改整个任务状态
+	// 所有任务执行完成，修改整个任务状态, 执行过程中被取消或暂停的任务保持其状态不变
 	blog.Infof("execute task %s done, all subtask success: %v, rid: %s", taskQueue.TaskID, allSucc, kit.Rid)
 
-	updateCond := mapstr.MapStr{common.BKTaskIDField: taskQueue.TaskID}
+	updateCond := mapstr.MapStr{
+		common.BKTaskIDField: taskQueue.TaskID,
+		common.BKStatusField: mapstr.MapStr{
+			common.BKDBNIN: []metadata.APITaskStatus{metadata.APITaskStatusCanceled, metadata.APITaskStatusPaused},
+		},
+	}
 	var updateStatus metadata.APITaskStatus
 	if allSucc {
 		updateStatus = metadata.APITaskStatusSuccess
@@ -266,6 +304,11 @@ func (tq *TaskQueue) executeSubTask(kit *rest.Kit, taskInfo TaskInfo, taskID str
 		return false, false
 	}
 
+	// check if the task is canceled or paused before each subtask is executed
+	if tq.isTaskInterrupted(kit, taskID) {
+		return false, true
+	}
+
 	var resp *metadata.Response
 	var err error
 	needReturn := retryWrapper(kit, int(taskInfo.Retry), func() error {
@@ -294,7 +337,6 @@ func (tq *TaskQueue) executeSubTask(kit *rest.Kit, taskInfo TaskInfo, taskID str
 
 	if err != nil || !resp.Result {
 		updateData.Set("detail.$.status", metadata.APITAskStatusFail)
-		updateData.Set("status", metadata.APITAskStatusFail)
 	} else {
 		updateData.Set("detail.$.status", metadata.APITaskStatusSuccess)
 	}
@@ -315,6 +357,30 @@ func (tq *TaskQueue) executeSubTask(kit *rest.Kit, taskInfo TaskInfo, taskID str
 		return false, true
 	}
 
+	// the subtask fails, set the task status to fail unless it is canceled or paused while the subtask is executing
+	if err != nil || !resp.Result {
+		failCond := mapstr.MapStr{
+			common.BKTaskIDField: taskID,
+			common.BKStatusField: mapstr.MapStr{
+				common.BKDBNIN: []metadata.APITaskStatus{metadata.APITaskStatusCanceled, metadata.APITaskStatusPaused},
+			},
+		}
+		failData := mapstr.MapStr{common.BKStatusField: metadata.APITAskStatusFail, common.LastTimeField: time.Now()}
+		needReturn = retryWrapper(kit, dbMaxRetry, func() error {
+			err := tq.service.DB.Table(common.BKTableNameAPITask).Update(kit.Ctx, failCond, failData)
+			if err != nil {
+				blog.Errorf("update task status failed, err: %v, cond: %#v, data: %#v", err, failCond, failData)
+				time.Sleep(time.Second * 3)
+				return err
+			}
+			return nil
+		})
+
+		if needReturn {
+			return false, true
+		}
+	}
+
 	blog.Infof("finished executing task(id: %s) subtask(id: %s)", taskID, subTask.SubTaskID)
 
 	// the subtask is not successful, returns the status after updating its response to end the task
@@ -325,6 +391,37 @@ func (tq *TaskQueue) executeSubTask(kit *rest.Kit, taskInfo TaskInfo, taskID str
 	return true, false
 }
 
+// isTaskInterrupted returns if the executing task is canceled or paused by user, the unfinished subtasks of the
+// canceled task are canceled too, and the ones of the paused task are executed after the task is resumed.
+func (tq *TaskQueue) isTaskInterrupted(kit *rest.Kit, taskID string) bool {
+	var status metadata.APITaskStatus
+	needReturn := retryWrapper(kit, dbMaxRetry, func() error {
+		var err error
+		if status, err = tq.service.Logics.GetTaskStatus(kit.Ctx, taskID, kit.Rid); err != nil {
+			time.Sleep(time.Second)
+			return err
+		}
+		return nil
+	})
+	if needReturn {
+		return true
+	}
+
+	switch status {
+	case metadata.APITaskStatusCanceled:
+		blog.Infof("task %s is canceled, stop executing it, rid: %s", taskID, kit.Rid)
+		if err := tq.service.Logics.CancelUnfinishedSubTasks(kit.Ctx, taskID, kit.Rid); err != nil {
+			blog.Errorf("cancel task %s unfinished subtasks failed, err: %v, rid: %s", taskID, err, kit.Rid)
+		}
+		return true
+	case metadata.APITaskStatusPaused:
+		blog.Infof("task %s is paused, stop executing it, rid: %s", taskID, kit.Rid)
+		return true
+	default:
+		return false
+	}
+}
+
 // retryWrapper retry task execute step wrapper, returns if task is terminated.
 func retryWrapper(kit *rest.Kit, maxRetry int, handler func() error) bool {
 	for retry := 0; retry < maxRetry; retry++ {
@@ -380,7 +477,16 @@ func (tq *TaskQueue) isTaskLocked(ctx context.Context, taskID string) (bool, err
 	return result == 1, nil
 }
 
-func (tq *TaskQueue) getWaitExecute(ctx context.Context, name string) ([]metadata.APITaskDetail, error) {
+// getWaitExecute get the tasks that are waiting to execute, the tasks with higher priority are returned first,
+// and the tasks with the same priority are returned in the create order.
+func (tq *TaskQueue) getWaitExecute(ctx context.Context, name string, concurrency int) ([]metadata.APITaskDetail,
+	error) {
+
+	limit := 20
+	if concurrency > limit {
+		limit = concurrency
+	}
+
 	cond := mapstr.MapStr{
 		common.BKTaskTypeField: name,
 		common.BKStatusField: mapstr.MapStr{
@@ -389,7 +495,8 @@ func (tq *TaskQueue) getWaitExecute(ctx context.Context, name string) ([]metadat
 	}
 
 	rows := make([]metadata.APITaskDetail, 0)
-	err := tq.service.DB.Table(common.BKTableNameAPITask).Find(cond).Sort("create_time").Limit(20).All(ctx, &rows)
+	err := tq.service.DB.Table(common.BKTableNameAPITask).Find(cond).Sort("-priority,create_time").Limit(uint64(limit)).
+		All(ctx, &rows)
 	if err != nil {
 		blog.ErrorJSON("query wait execute failed, err: %v, task type: %s, cond: %#v", err, name, cond)
 		return nil, tq.service.CCErr.Error("zh-cn", common.CCErrCommDBSelectFailed)
@@ -536,10 +643,11 @@ func (s *Service) initCodeTaskConfig() map[string]TaskInfo {
 
 	for _, codeTaskConfig := range codeTaskConfigArr {
 		ti := TaskInfo{
-			Name:    codeTaskConfig.Name,
-			Retry:   codeTaskConfig.Retry,
-			Path:    codeTaskConfig.Path,
-			LockTTL: codeTaskConfig.LockTTL,
+			Name:        codeTaskConfig.Name,
+			Retry:       codeTaskConfig.Retry,
+			Path:        codeTaskConfig.Path,
+			LockTTL:     codeTaskConfig.LockTTL,
+			Concurrency: getQueueConcurrency(codeTaskConfig.Name),
 		}
 		switch codeTaskConfig.SvrType {
 		case types.CC_MODULE_APISERVER:
@@ -561,3 +669,26 @@ func (s *Service) initCodeTaskConfig() map[string]TaskInfo {
 
 	return taskInfoMap
 }
+
+// getQueueConcurrency get the max number of tasks that are executed at the same time in the task queue from config
+// taskServer.concurrency.{queue name}, if it is not set, use taskServer.concurrency.default, default is 1.
+func getQueueConcurrency(name string) int {
+	for _, key := range []string{"taskServer.concurrency." + name, "taskServer.concurrency.default"} {
+		if !cc.IsExist(key) {
+			continue
+		}
+
+		concurrency, err := cc.Int(key)
+		if err != nil {
+			blog.Errorf("get %s config failed, use default concurrency 1, err: %v", key, err)
+			return 1
+		}
+
+		if concurrency < 1 {
+			blog.Errorf("%s config %d is invalid, use default concurrency 1", key, concurrency)
+			return 1
+		}
+		return concurrency
+	}
+	return 1
+}
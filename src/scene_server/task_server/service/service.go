@@ -83,11 +83,24 @@ func (s *Service) addAPIService(web *restful.WebService) {
 	utility.AddHandler(rest.Action{Verb: http.MethodPost, Path: "/task/findmany/list/latest/{name}", Handler: s.ListLatestTask})
 	utility.AddHandler(rest.Action{Verb: http.MethodPost, Path: "/task/findone/detail/{task_id}", Handler: s.DetailTask})
 	utility.AddHandler(rest.Action{Verb: http.MethodPost, Path: "/task/deletemany", Handler: s.DeleteTask})
+	utility.AddHandler(rest.Action{Verb: http.MethodPost, Path: "/task/cancel/{task_id}", Handler: s.CancelTask})
+	utility.AddHandler(rest.Action{Verb: http.MethodPost, Path: "/task/pause/{task_id}", Handler: s.PauseTask})
+	utility.AddHandler(rest.Action{Verb: http.MethodPost, Path: "/task/resume/{task_id}", Handler: s.ResumeTask})
 	utility.AddHandler(rest.Action{Verb: http.MethodPost, Path: "/findmany/latest/sync_status",
 		Handler: s.ListLatestSyncStatus})
 	utility.AddHandler(rest.Action{Verb: http.MethodPost, Path: "/findmany/sync_status_history",
 		Handler: s.ListSyncStatusHistory})
 
+	// cron job api
+	utility.AddHandler(rest.Action{Verb: http.MethodPost, Path: "/cron_job/create", Handler: s.CreateCronJob})
+	utility.AddHandler(rest.Action{Verb: http.MethodPut, Path: "/cron_job/update/{id}", Handler: s.UpdateCronJob})
+	utility.AddHandler(rest.Action{Verb: http.MethodPost, Path: "/cron_job/enable/{id}", Handler: s.EnableCronJob})
+	utility.AddHandler(rest.Action{Verb: http.MethodPost, Path: "/cron_job/disable/{id}", Handler: s.DisableCronJob})
+	utility.AddHandler(rest.Action{Verb: http.MethodDelete, Path: "/cron_job/delete/{id}", Handler: s.DeleteCronJob})
+	utility.AddHandler(rest.Action{Verb: http.MethodPost, Path: "/cron_job/findmany", Handler: s.ListCronJob})
+	utility.AddHandler(rest.Action{Verb: http.MethodPost, Path: "/cron_job/findmany/history/{id}",
+		Handler: s.ListCronJobHistory})
+
 	utility.AddToRestfulWebService(web)
 
 }
@@ -122,6 +122,36 @@ func (s *Service) DeleteTask(ctx *rest.Contexts) {
 	ctx.RespEntity(common.CCSuccessStr)
 }
 
+// CancelTask cancel a task that is not finished
+func (s *Service) CancelTask(ctx *rest.Contexts) {
+	if err := s.Logics.CancelTask(ctx.Kit, ctx.Request.PathParameter("task_id")); err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	ctx.RespEntity(nil)
+}
+
+// PauseTask pause a task that is not finished
+func (s *Service) PauseTask(ctx *rest.Contexts) {
+	if err := s.Logics.PauseTask(ctx.Kit, ctx.Request.PathParameter("task_id")); err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	ctx.RespEntity(nil)
+}
+
+// ResumeTask resume a paused task
+func (s *Service) ResumeTask(ctx *rest.Contexts) {
+	if err := s.Logics.ResumeTask(ctx.Kit, ctx.Request.PathParameter("task_id")); err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	ctx.RespEntity(nil)
+}
+
 // ListLatestSyncStatus list latest api task sync status
 func (s *Service) ListLatestSyncStatus(ctx *rest.Contexts) {
 	input := new(metadata.ListLatestSyncStatusRequest)
@@ -183,6 +213,11 @@ func (s *Service) TimerDeleteHistoryTask(ctx context.Context) {
 			continue
 		}
 		blog.Infof("delete redundancy task history completed, time: %v, rid: %s", time.Now(), rid)
+
+		if err := s.deleteRedundancyCronJobHistory(ctx, rid); err != nil {
+			continue
+		}
+		blog.Infof("delete redundancy cron job history completed, time: %v, rid: %s", time.Now(), rid)
 	}
 }
 
@@ -42,6 +42,7 @@ type Logics interface {
 	BusinessSetOperation() inst.BusinessSetOperationInterface
 	SetTemplateOperation() settemplate.SetTemplate
 	KubeOperation() kube.KubeOperationInterface
+	SchemaOperation() model.SchemaOperationInterface
 }
 
 type logics struct {
@@ -61,6 +62,7 @@ type logics struct {
 	businessSet       inst.BusinessSetOperationInterface
 	setTemplate       settemplate.SetTemplate
 	kube              kube.KubeOperationInterface
+	schema            model.SchemaOperationInterface
 }
 
 // New create a logics manager
@@ -82,6 +84,7 @@ func New(client apimachinery.ClientSetInterface, authManager *extensions.AuthMan
 	businessSetOperation := inst.NewBusinessSetOperation(client, authManager)
 	kubeOperation := kube.NewClusterOperation(client, authManager)
 	setTemplate := settemplate.NewSetTemplate(client)
+	schemaOperation := model.NewSchemaOperation(client)
 
 	instOperation.SetProxy(instAssociationOperation)
 	instAssociationOperation.SetProxy(instOperation)
@@ -93,6 +96,8 @@ func New(client apimachinery.ClientSetInterface, authManager *extensions.AuthMan
 	attributeOperation.SetProxy(groupOperation, objectOperation)
 	businessOperation.SetProxy(instOperation, moduleOperation, setOperation)
 	businessSetOperation.SetProxy(instOperation)
+	schemaOperation.SetProxy(classificationOperation, objectOperation, attributeOperation, groupOperation,
+		associationOperation)
 	return &logics{
 		classification:    classificationOperation,
 		set:               setOperation,
@@ -110,6 +115,7 @@ func New(client apimachinery.ClientSetInterface, authManager *extensions.AuthMan
 		businessSet:       businessSetOperation,
 		setTemplate:       setTemplate,
 		kube:              kubeOperation,
+		schema:            schemaOperation,
 	}
 }
 
@@ -192,3 +198,8 @@ func (l *logics) KubeOperation() kube.KubeOperationInterface {
 func (l *logics) SetTemplateOperation() settemplate.SetTemplate {
 	return l.setTemplate
 }
+
+// SchemaOperation return a schema provide SchemaOperationInterface
+func (l *logics) SchemaOperation() model.SchemaOperationInterface {
+	return l.schema
+}
@@ -0,0 +1,597 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model
+
+import (
+	"strings"
+
+	"configcenter/src/ac/meta"
+	"configcenter/src/apimachinery"
+	"configcenter/src/common"
+	"configcenter/src/common/blog"
+	"configcenter/src/common/http/rest"
+	"configcenter/src/common/mapstr"
+	"configcenter/src/common/metadata"
+	"configcenter/src/common/util"
+)
+
+// SchemaOperationInterface model schema operation methods
+type SchemaOperationInterface interface {
+	// DiffModelSchema compare model schema with the live models, returns the changes that need to be applied
+	DiffModelSchema(kit *rest.Kit, schema *metadata.ModelSchema, allowDelete bool) ([]metadata.ModelSchemaChange,
+		error)
+	// ApplyModelSchema apply model schema to the live models, returns the applied changes and the created objects
+	ApplyModelSchema(kit *rest.Kit, schema *metadata.ModelSchema, allowDelete bool) ([]metadata.ModelSchemaChange,
+		[]metadata.Object, error)
+	// GetModelSchemaAuthResources returns the resources that need to be authorized to apply model schema
+	GetModelSchemaAuthResources(kit *rest.Kit, schema *metadata.ModelSchema, allowDelete bool) (
+		[]meta.ResourceAttribute, error)
+	SetProxy(cls ClassificationOperationInterface, obj ObjectOperationInterface, attr AttributeOperationInterface,
+		grp GroupOperationInterface, asst AssociationOperationInterface)
+}
+
+// NewSchemaOperation create a new model schema operation instance
+func NewSchemaOperation(client apimachinery.ClientSetInterface) SchemaOperationInterface {
+	return &schema{
+		clientSet: client,
+	}
+}
+
+type schema struct {
+	clientSet apimachinery.ClientSetInterface
+	cls       ClassificationOperationInterface
+	obj       ObjectOperationInterface
+	attr      AttributeOperationInterface
+	grp       GroupOperationInterface
+	asst      AssociationOperationInterface
+}
+
+// SetProxy initialize the operations that are used to apply model schema
+func (s *schema) SetProxy(cls ClassificationOperationInterface, obj ObjectOperationInterface,
+	attr AttributeOperationInterface, grp GroupOperationInterface, asst AssociationOperationInterface) {
+	s.cls = cls
+	s.obj = obj
+	s.attr = attr
+	s.grp = grp
+	s.asst = asst
+}
+
+// DiffModelSchema compare model schema with the live models, returns the changes that need to be applied
+func (s *schema) DiffModelSchema(kit *rest.Kit, schema *metadata.ModelSchema, allowDelete bool) (
+	[]metadata.ModelSchemaChange, error) {
+
+	state, err := s.getSchemaState(kit, schema)
+	if err != nil {
+		return nil, err
+	}
+
+	return diffModelSchema(schema, state, allowDelete), nil
+}
+
+// ApplyModelSchema apply model schema to the live models, classifications and models are applied at first, then the
+// changes of their groups, attributes, uniques and associations are calculated again with the created models.
+func (s *schema) ApplyModelSchema(kit *rest.Kit, schema *metadata.ModelSchema, allowDelete bool) (
+	[]metadata.ModelSchemaChange, []metadata.Object, error) {
+
+	state, err := s.getSchemaState(kit, schema)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	applied := make([]metadata.ModelSchemaChange, 0)
+	objects := make([]metadata.Object, 0)
+	for _, change := range diffModelSchema(schema, state, allowDelete) {
+		switch change.Kind {
+		case metadata.ModelSchemaKindClassification:
+			if err := s.applyClassification(kit, schema, state, change); err != nil {
+				return nil, nil, err
+			}
+		case metadata.ModelSchemaKindModel:
+			obj, err := s.applyObject(kit, schema, state, change)
+			if err != nil {
+				return nil, nil, err
+			}
+			if obj != nil {
+				objects = append(objects, *obj)
+			}
+		default:
+			continue
+		}
+		applied = append(applied, change)
+	}
+
+	if len(applied) > 0 {
+		if state, err = s.getSchemaState(kit, schema); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for _, change := range diffModelSchema(schema, state, allowDelete) {
+		switch change.Kind {
+		case metadata.ModelSchemaKindGroup:
+			err = s.applyGroup(kit, schema, state, change)
+		case metadata.ModelSchemaKindAttribute:
+			err = s.applyAttribute(kit, schema, state, change)
+		case metadata.ModelSchemaKindUnique:
+			err = s.applyUnique(kit, state, change)
+		case metadata.ModelSchemaKindAssociation:
+			err = s.applyAssociation(kit, schema, state, change)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		applied = append(applied, change)
+	}
+
+	return applied, objects, nil
+}
+
+func (s *schema) applyClassification(kit *rest.Kit, schema *metadata.ModelSchema, state *schemaState,
+	change metadata.ModelSchemaChange) error {
+
+	var cls metadata.SchemaClassification
+	for _, item := range schema.Classifications {
+		if item.ClassificationID == change.Key {
+			cls = item
+			break
+		}
+	}
+
+	switch change.Action {
+	case metadata.ModelSchemaCreate:
+		data := mapstr.MapStr{
+			common.BKClassificationIDField:   cls.ClassificationID,
+			common.BKClassificationNameField: cls.ClassificationName,
+			common.BKClassificationIconField: cls.ClassificationIcon,
+		}
+		if _, err := s.cls.CreateClassification(kit, data); err != nil {
+			blog.Errorf("create classification %s failed, err: %v, rid: %s", cls.ClassificationID, err, kit.Rid)
+			return err
+		}
+	case metadata.ModelSchemaUpdate:
+		data := diffClassification(cls, state.classifications[cls.ClassificationID])
+		if err := s.cls.UpdateClassification(kit, data, change.ID); err != nil {
+			blog.Errorf("update classification %s failed, data: %v, err: %v, rid: %s", cls.ClassificationID, data,
+				err, kit.Rid)
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *schema) applyObject(kit *rest.Kit, schema *metadata.ModelSchema, state *schemaState,
+	change metadata.ModelSchemaChange) (*metadata.Object, error) {
+
+	model := findSchemaModel(schema, change.ObjectID)
+
+	switch change.Action {
+	case metadata.ModelSchemaCreate:
+		data := mapstr.MapStr{
+			common.BKObjIDField:            model.ObjectID,
+			common.BKObjNameField:          model.ObjectName,
+			common.BKObjIconField:          model.ObjIcon,
+			common.BKClassificationIDField: model.ObjCls,
+			common.BkSupplierAccount:       kit.SupplierAccount,
+		}
+		obj, err := s.obj.CreateObject(kit, false, data)
+		if err != nil {
+			blog.Errorf("create object %s failed, err: %v, rid: %s", model.ObjectID, err, kit.Rid)
+			return nil, err
+		}
+		return obj, nil
+	case metadata.ModelSchemaUpdate:
+		data := diffObject(model, state.objects[model.ObjectID])
+		if err := s.obj.UpdateObject(kit, data, change.ID); err != nil {
+			blog.Errorf("update object %s failed, data: %v, err: %v, rid: %s", model.ObjectID, data, err, kit.Rid)
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+func (s *schema) applyGroup(kit *rest.Kit, schema *metadata.ModelSchema, state *schemaState,
+	change metadata.ModelSchemaChange) error {
+
+	if change.Action == metadata.ModelSchemaDelete {
+		if err := s.grp.DeleteObjectGroup(kit, change.ID); err != nil {
+			blog.Errorf("delete object %s group %s failed, err: %v, rid: %s", change.ObjectID, change.Key, err,
+				kit.Rid)
+			return err
+		}
+		return nil
+	}
+
+	var group metadata.SchemaGroup
+	for _, item := range findSchemaModel(schema, change.ObjectID).Groups {
+		if item.GroupID == change.Key {
+			group = item
+			break
+		}
+	}
+
+	switch change.Action {
+	case metadata.ModelSchemaCreate:
+		data := &metadata.Group{
+			GroupID:   group.GroupID,
+			GroupName: group.GroupName,
+			ObjectID:  change.ObjectID,
+			OwnerID:   kit.SupplierAccount,
+		}
+		if group.GroupIndex != nil {
+			data.GroupIndex = *group.GroupIndex
+		}
+		if group.IsCollapse != nil {
+			data.IsCollapse = *group.IsCollapse
+		}
+		if _, err := s.grp.CreateObjectGroup(kit, data); err != nil {
+			blog.Errorf("create object %s group %s failed, err: %v, rid: %s", change.ObjectID, group.GroupID, err,
+				kit.Rid)
+			return err
+		}
+	case metadata.ModelSchemaUpdate:
+		cond := &metadata.UpdateGroupCondition{}
+		cond.Condition.ID = change.ID
+		live := state.groups[change.ObjectID][group.GroupID]
+		if group.GroupName != live.GroupName {
+			cond.Data.Name = &group.GroupName
+		}
+		if group.GroupIndex != nil && *group.GroupIndex != live.GroupIndex {
+			cond.Data.Index = group.GroupIndex
+		}
+		if group.IsCollapse != nil && *group.IsCollapse != live.IsCollapse {
+			cond.Data.IsCollapse = group.IsCollapse
+		}
+		if err := s.grp.UpdateObjectGroup(kit, cond); err != nil {
+			blog.Errorf("update object %s group %s failed, err: %v, rid: %s", change.ObjectID, group.GroupID, err,
+				kit.Rid)
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *schema) applyAttribute(kit *rest.Kit, schema *metadata.ModelSchema, state *schemaState,
+	change metadata.ModelSchemaChange) error {
+
+	if change.Action == metadata.ModelSchemaDelete {
+		cond := mapstr.MapStr{metadata.AttributeFieldID: change.ID}
+		if err := s.attr.DeleteObjectAttribute(kit, cond, 0); err != nil {
+			blog.Errorf("delete object %s attribute %s failed, err: %v, rid: %s", change.ObjectID, change.Key, err,
+				kit.Rid)
+			return err
+		}
+		return nil
+	}
+
+	var attr metadata.SchemaAttribute
+	for _, item := range findSchemaModel(schema, change.ObjectID).Attributes {
+		if item.PropertyID == change.Key {
+			attr = item
+			break
+		}
+	}
+
+	switch change.Action {
+	case metadata.ModelSchemaCreate:
+		data := &metadata.Attribute{
+			OwnerID:       kit.SupplierAccount,
+			ObjectID:      change.ObjectID,
+			PropertyID:    attr.PropertyID,
+			PropertyName:  attr.PropertyName,
+			PropertyGroup: attr.PropertyGroup,
+			PropertyType:  attr.PropertyType,
+			Unit:          attr.Unit,
+			Placeholder:   attr.Placeholder,
+			Description:   attr.Description,
+			IsEditable:    true,
+			Option:        attr.Option,
+		}
+		if len(data.PropertyGroup) == 0 {
+			data.PropertyGroup = NewGroupID(true)
+		}
+		if attr.IsEditable != nil {
+			data.IsEditable = *attr.IsEditable
+		}
+		if attr.IsRequired != nil {
+			data.IsRequired = *attr.IsRequired
+		}
+		if attr.IsReadOnly != nil {
+			data.IsReadOnly = *attr.IsReadOnly
+		}
+		created, err := s.attr.CreateObjectAttribute(kit, data)
+		if err != nil {
+			blog.Errorf("create object %s attribute %s failed, err: %v, rid: %s", change.ObjectID, attr.PropertyID,
+				err, kit.Rid)
+			return err
+		}
+		// record the created attribute so that the uniques can refer to it
+		if _, exists := state.attrs[change.ObjectID]; !exists {
+			state.attrs[change.ObjectID] = make(map[string]metadata.Attribute)
+		}
+		state.attrs[change.ObjectID][attr.PropertyID] = *created
+	case metadata.ModelSchemaUpdate:
+		live := state.attrs[change.ObjectID][attr.PropertyID]
+		data := diffAttribute(attr, live)
+
+		// attribute group can not be updated with other fields, it is moved to the new group separately
+		if data.Exists(metadata.AttributeFieldPropertyGroup) {
+			data.Remove(metadata.AttributeFieldPropertyGroup)
+			cond := metadata.PropertyGroupObjectAtt{}
+			cond.Condition.OwnerID = kit.SupplierAccount
+			cond.Condition.ObjectID = change.ObjectID
+			cond.Condition.PropertyID = attr.PropertyID
+			cond.Data.PropertyGroupID = attr.PropertyGroup
+			cond.Data.PropertyIndex = int(live.PropertyIndex)
+			if err := s.grp.UpdateObjectAttributeGroup(kit, []metadata.PropertyGroupObjectAtt{cond}, 0); err != nil {
+				blog.Errorf("move object %s attribute %s to group %s failed, err: %v, rid: %s", change.ObjectID,
+					attr.PropertyID, attr.PropertyGroup, err, kit.Rid)
+				return err
+			}
+		}
+
+		if len(data) == 0 {
+			return nil
+		}
+		if err := s.attr.UpdateObjectAttribute(kit, data, change.ID, 0); err != nil {
+			blog.Errorf("update object %s attribute %s failed, data: %v, err: %v, rid: %s", change.ObjectID,
+				attr.PropertyID, data, err, kit.Rid)
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *schema) applyUnique(kit *rest.Kit, state *schemaState, change metadata.ModelSchemaChange) error {
+	// mainline object's unique can not be changed, except for host.
+	if change.ObjectID != common.BKInnerObjIDHost {
+		isMainline, err := s.asst.IsMainlineObject(kit, change.ObjectID)
+		if err != nil {
+			return err
+		}
+		if isMainline {
+			return kit.CCError.CCError(common.CCErrorTopoMainlineObjectCanNotBeChanged)
+		}
+	}
+
+	switch change.Action {
+	case metadata.ModelSchemaCreate:
+		keys := make([]metadata.UniqueKey, 0)
+		for _, propertyID := range strings.Split(change.Key, ",") {
+			attr, exists := state.attrs[change.ObjectID][propertyID]
+			if !exists {
+				blog.Errorf("object %s unique key %s does not exist, rid: %s", change.ObjectID, propertyID, kit.Rid)
+				return kit.CCError.CCErrorf(common.CCErrCommParamsInvalid, change.ObjectID+" unique "+propertyID)
+			}
+			keys = append(keys, metadata.UniqueKey{Kind: metadata.UniqueKeyKindProperty, ID: uint64(attr.ID)})
+		}
+
+		unique := metadata.CreateModelAttrUnique{Data: metadata.ObjectUnique{ObjID: change.ObjectID, Keys: keys}}
+		_, err := s.clientSet.CoreService().Model().CreateModelAttrUnique(kit.Ctx, kit.Header, change.ObjectID, unique)
+		if err != nil {
+			blog.Errorf("create object %s unique %s failed, err: %v, rid: %s", change.ObjectID, change.Key, err,
+				kit.Rid)
+			return err
+		}
+	case metadata.ModelSchemaDelete:
+		_, err := s.clientSet.CoreService().Model().DeleteModelAttrUnique(kit.Ctx, kit.Header, change.ObjectID,
+			uint64(change.ID))
+		if err != nil {
+			blog.Errorf("delete object %s unique %s failed, err: %v, rid: %s", change.ObjectID, change.Key, err,
+				kit.Rid)
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *schema) applyAssociation(kit *rest.Kit, schema *metadata.ModelSchema, state *schemaState,
+	change metadata.ModelSchemaChange) error {
+
+	if change.Action == metadata.ModelSchemaDelete {
+		if err := s.asst.DeleteAssociationWithPreCheck(kit, change.ID); err != nil {
+			blog.Errorf("delete association %s failed, err: %v, rid: %s", change.Key, err, kit.Rid)
+			return err
+		}
+		return nil
+	}
+
+	var asst metadata.SchemaAssociation
+	for _, item := range schema.Associations {
+		if item.AssociationName == change.Key {
+			asst = item
+			break
+		}
+	}
+
+	switch change.Action {
+	case metadata.ModelSchemaCreate:
+		data := &metadata.Association{
+			OwnerID:              kit.SupplierAccount,
+			AssociationName:      asst.AssociationName,
+			AssociationAliasName: asst.AssociationAliasName,
+			ObjectID:             asst.ObjectID,
+			AsstObjID:            asst.AsstObjID,
+			AsstKindID:           asst.AsstKindID,
+			Mapping:              asst.Mapping,
+			OnDelete:             asst.OnDelete,
+		}
+		if len(data.Mapping) == 0 {
+			data.Mapping = metadata.ManyToManyMapping
+		}
+		if _, err := s.asst.CreateCommonAssociation(kit, data); err != nil {
+			blog.Errorf("create association %s failed, err: %v, rid: %s", asst.AssociationName, err, kit.Rid)
+			return err
+		}
+	case metadata.ModelSchemaUpdate:
+		data := diffAssociation(asst, state.associations[asst.AssociationName])
+		if err := s.asst.UpdateObjectAssociation(kit, data, change.ID); err != nil {
+			blog.Errorf("update association %s failed, data: %v, err: %v, rid: %s", asst.AssociationName, data, err,
+				kit.Rid)
+			return err
+		}
+	}
+	return nil
+}
+
+func findSchemaModel(schema *metadata.ModelSchema, objID string) metadata.SchemaModel {
+	for _, model := range schema.Models {
+		if model.ObjectID == objID {
+			return model
+		}
+	}
+	return metadata.SchemaModel{ObjectID: objID}
+}
+
+// getSchemaState get the live models that are declared in model schema
+func (s *schema) getSchemaState(kit *rest.Kit, schema *metadata.ModelSchema) (*schemaState, error) {
+	state := newSchemaState()
+
+	clsIDs := make([]string, 0)
+	for _, cls := range schema.Classifications {
+		clsIDs = append(clsIDs, cls.ClassificationID)
+	}
+	if len(clsIDs) > 0 {
+		cond := &metadata.QueryCondition{
+			Condition:      mapstr.MapStr{common.BKClassificationIDField: mapstr.MapStr{common.BKDBIN: clsIDs}},
+			Page:           metadata.BasePage{Limit: common.BKNoLimit},
+			DisableCounter: true,
+		}
+		rsp, err := s.clientSet.CoreService().Model().ReadModelClassification(kit.Ctx, kit.Header, cond)
+		if err != nil {
+			blog.Errorf("find classifications %v failed, err: %v, rid: %s", clsIDs, err, kit.Rid)
+			return nil, err
+		}
+		for _, cls := range rsp.Info {
+			state.classifications[cls.ClassificationID] = cls
+		}
+	}
+
+	objIDs := make([]string, 0)
+	for _, model := range schema.Models {
+		objIDs = append(objIDs, model.ObjectID)
+	}
+	if len(objIDs) > 0 {
+		if err := s.getSchemaObjectState(kit, objIDs, state); err != nil {
+			return nil, err
+		}
+	}
+
+	asstNames := make([]string, 0)
+	for _, asst := range schema.Associations {
+		asstNames = append(asstNames, asst.AssociationName)
+	}
+	if len(asstNames) > 0 || len(objIDs) > 0 {
+		cond := &metadata.QueryCondition{
+			Condition: mapstr.MapStr{common.BKDBOR: []mapstr.MapStr{
+				{common.AssociationObjAsstIDField: mapstr.MapStr{common.BKDBIN: asstNames}},
+				{common.BKObjIDField: mapstr.MapStr{common.BKDBIN: objIDs}},
+			}},
+			Page:           metadata.BasePage{Limit: common.BKNoLimit},
+			DisableCounter: true,
+		}
+		rsp, err := s.clientSet.CoreService().Association().ReadModelAssociation(kit.Ctx, kit.Header, cond)
+		if err != nil {
+			blog.Errorf("find model associations failed, cond: %v, err: %v, rid: %s", cond, err, kit.Rid)
+			return nil, err
+		}
+		for _, asst := range rsp.Info {
+			state.associations[asst.AssociationName] = asst
+		}
+	}
+
+	return state, nil
+}
+
+// getSchemaObjectState get the objects and their groups, attributes and uniques, the objects that do not exist are
+// filled with the default group, attribute and unique that will be created along with them.
+func (s *schema) getSchemaObjectState(kit *rest.Kit, objIDs []string, state *schemaState) error {
+	objCond := &metadata.QueryCondition{
+		Condition:      mapstr.MapStr{common.BKObjIDField: mapstr.MapStr{common.BKDBIN: objIDs}},
+		Page:           metadata.BasePage{Limit: common.BKNoLimit},
+		DisableCounter: true,
+	}
+	objRsp, err := s.clientSet.CoreService().Model().ReadModel(kit.Ctx, kit.Header, objCond)
+	if err != nil {
+		blog.Errorf("find objects %v failed, err: %v, rid: %s", objIDs, err, kit.Rid)
+		return err
+	}
+	for _, obj := range objRsp.Info {
+		state.objects[obj.ObjectID] = obj
+	}
+
+	// only the global groups and attributes are managed by model schema
+	grpCond := mapstr.MapStr{common.BKObjIDField: mapstr.MapStr{common.BKDBIN: objIDs}}
+	util.AddModelBizIDCondition(grpCond, 0)
+	grpRsp, err := s.clientSet.CoreService().Model().ReadAttributeGroupByCondition(kit.Ctx, kit.Header,
+		metadata.QueryCondition{Condition: grpCond, Page: metadata.BasePage{Limit: common.BKNoLimit},
+			DisableCounter: true})
+	if err != nil {
+		blog.Errorf("find object %v groups failed, err: %v, rid: %s", objIDs, err, kit.Rid)
+		return err
+	}
+	for _, group := range grpRsp.Info {
+		if _, exists := state.groups[group.ObjectID]; !exists {
+			state.groups[group.ObjectID] = make(map[string]metadata.Group)
+		}
+		state.groups[group.ObjectID][group.GroupID] = group
+	}
+
+	attrCond := mapstr.MapStr{common.BKObjIDField: mapstr.MapStr{common.BKDBIN: objIDs}}
+	util.AddModelBizIDCondition(attrCond, 0)
+	attrRsp, err := s.clientSet.CoreService().Model().ReadModelAttrByCondition(kit.Ctx, kit.Header,
+		&metadata.QueryCondition{Condition: attrCond, Page: metadata.BasePage{Limit: common.BKNoLimit},
+			DisableCounter: true})
+	if err != nil {
+		blog.Errorf("find object %v attributes failed, err: %v, rid: %s", objIDs, err, kit.Rid)
+		return err
+	}
+	propertyIDs := make(map[int64]string)
+	for _, attr := range attrRsp.Info {
+		if _, exists := state.attrs[attr.ObjectID]; !exists {
+			state.attrs[attr.ObjectID] = make(map[string]metadata.Attribute)
+		}
+		state.attrs[attr.ObjectID][attr.PropertyID] = attr
+		propertyIDs[attr.ID] = attr.PropertyID
+	}
+
+	uniqueRsp, err := s.clientSet.CoreService().Model().ReadModelAttrUnique(kit.Ctx, kit.Header,
+		metadata.QueryCondition{Condition: mapstr.MapStr{common.BKObjIDField: mapstr.MapStr{common.BKDBIN: objIDs}},
+			Page: metadata.BasePage{Limit: common.BKNoLimit}})
+	if err != nil {
+		blog.Errorf("find object %v uniques failed, err: %v, rid: %s", objIDs, err, kit.Rid)
+		return err
+	}
+	for _, unique := range uniqueRsp.Info {
+		keys := make([]string, 0)
+		for _, key := range unique.Keys {
+			if key.Kind != metadata.UniqueKeyKindProperty {
+				continue
+			}
+			keys = append(keys, propertyIDs[int64(key.ID)])
+		}
+		state.uniques[unique.ObjID] = append(state.uniques[unique.ObjID], schemaUnique{
+			id:    int64(unique.ID),
+			isPre: unique.Ispre,
+			key:   uniqueKey(keys),
+		})
+	}
+
+	for _, objID := range objIDs {
+		if _, exists := state.objects[objID]; !exists {
+			state.addDefaultObject(objID)
+		}
+	}
+	return nil
+}
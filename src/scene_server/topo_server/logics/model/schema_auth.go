@@ -0,0 +1,191 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model
+
+import (
+	"configcenter/src/ac/meta"
+	"configcenter/src/common"
+	"configcenter/src/common/blog"
+	"configcenter/src/common/http/rest"
+	"configcenter/src/common/mapstr"
+	"configcenter/src/common/metadata"
+)
+
+// GetModelSchemaAuthResources resolve the changes of model schema, returns the resources that need to be authorized
+// to apply these changes.
+func (s *schema) GetModelSchemaAuthResources(kit *rest.Kit, schema *metadata.ModelSchema, allowDelete bool) (
+	[]meta.ResourceAttribute, error) {
+
+	state, err := s.getSchemaState(kit, schema)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := diffModelSchema(schema, state, allowDelete)
+
+	if err := s.getSchemaAuthState(kit, schema, state); err != nil {
+		return nil, err
+	}
+
+	return genSchemaAuthResources(schema, state, changes), nil
+}
+
+// getSchemaAuthState get the classifications of the created models and the associated objects that are not declared
+// in model schema, they are needed to generate the auth resources of the created models and the associations.
+func (s *schema) getSchemaAuthState(kit *rest.Kit, schema *metadata.ModelSchema, state *schemaState) error {
+	declaredObjs := make(map[string]struct{})
+	for _, model := range schema.Models {
+		declaredObjs[model.ObjectID] = struct{}{}
+	}
+
+	clsIDs := make([]string, 0)
+	for _, model := range schema.Models {
+		if _, exists := state.objects[model.ObjectID]; exists {
+			continue
+		}
+		if _, exists := state.classifications[model.ObjCls]; !exists {
+			clsIDs = append(clsIDs, model.ObjCls)
+		}
+	}
+
+	objIDs := make([]string, 0)
+	for _, asst := range schema.Associations {
+		for _, objID := range []string{asst.ObjectID, asst.AsstObjID} {
+			if _, exists := declaredObjs[objID]; !exists {
+				objIDs = append(objIDs, objID)
+			}
+		}
+	}
+	for _, asst := range state.associations {
+		if _, exists := declaredObjs[asst.AsstObjID]; !exists {
+			objIDs = append(objIDs, asst.AsstObjID)
+		}
+	}
+
+	if len(clsIDs) > 0 {
+		cond := &metadata.QueryCondition{
+			Condition:      mapstr.MapStr{common.BKClassificationIDField: mapstr.MapStr{common.BKDBIN: clsIDs}},
+			Page:           metadata.BasePage{Limit: common.BKNoLimit},
+			DisableCounter: true,
+		}
+		rsp, err := s.clientSet.CoreService().Model().ReadModelClassification(kit.Ctx, kit.Header, cond)
+		if err != nil {
+			blog.Errorf("find classifications %v failed, err: %v, rid: %s", clsIDs, err, kit.Rid)
+			return err
+		}
+		for _, cls := range rsp.Info {
+			state.classifications[cls.ClassificationID] = cls
+		}
+	}
+
+	if len(objIDs) > 0 {
+		cond := &metadata.QueryCondition{
+			Condition:      mapstr.MapStr{common.BKObjIDField: mapstr.MapStr{common.BKDBIN: objIDs}},
+			Page:           metadata.BasePage{Limit: common.BKNoLimit},
+			DisableCounter: true,
+		}
+		rsp, err := s.clientSet.CoreService().Model().ReadModel(kit.Ctx, kit.Header, cond)
+		if err != nil {
+			blog.Errorf("find objects %v failed, err: %v, rid: %s", objIDs, err, kit.Rid)
+			return err
+		}
+		for _, obj := range rsp.Info {
+			state.objects[obj.ObjectID] = obj
+		}
+	}
+
+	return nil
+}
+
+var schemaAuthActions = map[metadata.ModelSchemaAction]meta.Action{
+	metadata.ModelSchemaCreate: meta.Create,
+	metadata.ModelSchemaUpdate: meta.Update,
+	metadata.ModelSchemaDelete: meta.Delete,
+}
+
+// genSchemaAuthResources generate the auth resources of model schema changes in the same way as the apis that apply
+// each of the changes. the groups, attributes and uniques of the created models are authorized by the model create
+// permission, so they are skipped.
+func genSchemaAuthResources(schema *metadata.ModelSchema, state *schemaState,
+	changes []metadata.ModelSchemaChange) []meta.ResourceAttribute {
+
+	resources := make([]meta.ResourceAttribute, 0)
+	for _, change := range changes {
+		action := schemaAuthActions[change.Action]
+
+		switch change.Kind {
+		case metadata.ModelSchemaKindClassification:
+			resources = append(resources, meta.ResourceAttribute{
+				Basic: meta.Basic{Type: meta.ModelClassification, Action: action, InstanceID: change.ID},
+			})
+
+		case metadata.ModelSchemaKindModel:
+			if change.Action != metadata.ModelSchemaCreate {
+				resources = append(resources, meta.ResourceAttribute{
+					Basic: meta.Basic{Type: meta.Model, Action: action, InstanceID: change.ID},
+				})
+				continue
+			}
+
+			resource := meta.ResourceAttribute{Basic: meta.Basic{Type: meta.Model, Action: action}}
+			if cls, exists := state.classifications[findSchemaModel(schema, change.ObjectID).ObjCls]; exists {
+				resource.Layers = []meta.Item{{Type: meta.ModelClassification, InstanceID: cls.ID}}
+			}
+			resources = append(resources, resource)
+
+		case metadata.ModelSchemaKindGroup, metadata.ModelSchemaKindAttribute, metadata.ModelSchemaKindUnique:
+			obj, exists := state.objects[change.ObjectID]
+			if !exists {
+				continue
+			}
+
+			resourceType := meta.ModelAttributeGroup
+			switch change.Kind {
+			case metadata.ModelSchemaKindAttribute:
+				resourceType = meta.ModelAttribute
+			case metadata.ModelSchemaKindUnique:
+				resourceType = meta.ModelUnique
+			}
+
+			resources = append(resources, meta.ResourceAttribute{
+				Basic:  meta.Basic{Type: resourceType, Action: action, InstanceID: change.ID},
+				Layers: []meta.Item{{Type: meta.Model, InstanceID: obj.ID}},
+			})
+
+		case metadata.ModelSchemaKindAssociation:
+			objIDs := make([]string, 0)
+			if asst, exists := state.associations[change.Key]; exists {
+				objIDs = append(objIDs, asst.ObjectID, asst.AsstObjID)
+			} else {
+				for _, asst := range schema.Associations {
+					if asst.AssociationName == change.Key {
+						objIDs = append(objIDs, asst.ObjectID, asst.AsstObjID)
+					}
+				}
+			}
+
+			// association is authorized by both of its models, the created models are authorized by model create
+			for _, objID := range objIDs {
+				obj, exists := state.objects[objID]
+				if !exists {
+					continue
+				}
+				resources = append(resources, meta.ResourceAttribute{
+					Basic: meta.Basic{Type: meta.ModelAssociation, Action: action, InstanceID: obj.ID},
+				})
+			}
+		}
+	}
+
+	return resources
+}
@@ -0,0 +1,91 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model
+
+import (
+	"reflect"
+	"testing"
+
+	"configcenter/src/ac/meta"
+	"configcenter/src/common/metadata"
+)
+
+func TestGenSchemaAuthResources(t *testing.T) {
+	schema := &metadata.ModelSchema{
+		Classifications: []metadata.SchemaClassification{
+			{ClassificationID: "bk_network", ClassificationName: "Network"},
+		},
+		Models: []metadata.SchemaModel{
+			{
+				ObjectID:   "switch",
+				ObjectName: "Switch",
+				ObjCls:     "bk_network",
+				Attributes: []metadata.SchemaAttribute{
+					{PropertyID: "sn", PropertyName: "SN", PropertyType: "singlechar"},
+				},
+				Uniques: [][]string{{"sn"}},
+			},
+			{
+				ObjectID:   "router",
+				ObjectName: "Router",
+				ObjCls:     "bk_network",
+				Attributes: []metadata.SchemaAttribute{
+					{PropertyID: "sn", PropertyName: "SN", PropertyType: "singlechar"},
+				},
+			},
+		},
+		Associations: []metadata.SchemaAssociation{
+			{AssociationName: "switch_connect_router", ObjectID: "switch", AsstObjID: "router", AsstKindID: "connect"},
+			{AssociationName: "switch_connect_host", ObjectID: "switch", AsstObjID: "host", AsstKindID: "connect"},
+		},
+	}
+
+	state := newSchemaState()
+	state.classifications["bk_network"] = metadata.Classification{ID: 1, ClassificationID: "bk_network",
+		ClassificationName: "network"}
+	state.objects["switch"] = metadata.Object{ID: 2, ObjectID: "switch", ObjectName: "Switch", ObjCls: "bk_network"}
+	state.addDefaultObject("switch")
+	state.attrs["switch"]["useless"] = metadata.Attribute{ID: 3, PropertyID: "useless"}
+	state.associations["switch_belong_host"] = metadata.Association{ID: 4, AssociationName: "switch_belong_host",
+		ObjectID: "switch", AsstObjID: "host", AsstKindID: "belong"}
+	state.addDefaultObject("router")
+	// the associated object that is not declared in schema
+	state.objects["host"] = metadata.Object{ID: 5, ObjectID: "host"}
+
+	changes := diffModelSchema(schema, state, true)
+	resources := genSchemaAuthResources(schema, state, changes)
+
+	expected := []meta.ResourceAttribute{
+		{Basic: meta.Basic{Type: meta.ModelClassification, Action: meta.Update, InstanceID: 1}},
+		{Basic: meta.Basic{Type: meta.Model, Action: meta.Create},
+			Layers: []meta.Item{{Type: meta.ModelClassification, InstanceID: 1}}},
+		{Basic: meta.Basic{Type: meta.ModelAttribute, Action: meta.Create},
+			Layers: []meta.Item{{Type: meta.Model, InstanceID: 2}}},
+		{Basic: meta.Basic{Type: meta.ModelUnique, Action: meta.Create},
+			Layers: []meta.Item{{Type: meta.Model, InstanceID: 2}}},
+		// association with the created model only requires the permission of the existing model
+		{Basic: meta.Basic{Type: meta.ModelAssociation, Action: meta.Create, InstanceID: 2}},
+		{Basic: meta.Basic{Type: meta.ModelAssociation, Action: meta.Create, InstanceID: 2}},
+		{Basic: meta.Basic{Type: meta.ModelAssociation, Action: meta.Create, InstanceID: 5}},
+		{Basic: meta.Basic{Type: meta.ModelAssociation, Action: meta.Delete, InstanceID: 2}},
+		{Basic: meta.Basic{Type: meta.ModelAssociation, Action: meta.Delete, InstanceID: 5}},
+		{Basic: meta.Basic{Type: meta.ModelUnique, Action: meta.Delete},
+			Layers: []meta.Item{{Type: meta.Model, InstanceID: 2}}},
+		{Basic: meta.Basic{Type: meta.ModelAttribute, Action: meta.Delete, InstanceID: 3},
+			Layers: []meta.Item{{Type: meta.Model, InstanceID: 2}}},
+	}
+
+	if !reflect.DeepEqual(resources, expected) {
+		t.Errorf("auth resources %+v is not as expected %+v, changes: %+v", resources, expected, changes)
+	}
+}
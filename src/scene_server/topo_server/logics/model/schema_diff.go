@@ -0,0 +1,420 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+
+	"configcenter/src/common"
+	"configcenter/src/common/mapstr"
+	"configcenter/src/common/metadata"
+)
+
+// schemaState the live models that are related to a model schema
+type schemaState struct {
+	classifications map[string]metadata.Classification
+	objects         map[string]metadata.Object
+	// groups object id -> group id -> group
+	groups map[string]map[string]metadata.Group
+	// attrs object id -> property id -> attribute
+	attrs map[string]map[string]metadata.Attribute
+	// uniques object id -> uniques
+	uniques      map[string][]schemaUnique
+	associations map[string]metadata.Association
+}
+
+// schemaUnique object unique whose keys are converted to property ids
+type schemaUnique struct {
+	id    int64
+	isPre bool
+	key   string
+}
+
+func newSchemaState() *schemaState {
+	return &schemaState{
+		classifications: make(map[string]metadata.Classification),
+		objects:         make(map[string]metadata.Object),
+		groups:          make(map[string]map[string]metadata.Group),
+		attrs:           make(map[string]map[string]metadata.Attribute),
+		uniques:         make(map[string][]schemaUnique),
+		associations:    make(map[string]metadata.Association),
+	}
+}
+
+// addDefaultObject add the group, attribute and unique that will be created along with a new object,
+// so that the diff result of a new object is the same as the one after it is created.
+func (s *schemaState) addDefaultObject(objID string) {
+	groupID := NewGroupID(true)
+	s.groups[objID] = map[string]metadata.Group{
+		groupID: {GroupID: groupID, GroupName: "Default", GroupIndex: -1, ObjectID: objID, IsDefault: true},
+	}
+
+	instName := common.GetInstNameField(objID)
+	s.attrs[objID] = map[string]metadata.Attribute{
+		instName: {
+			ObjectID:      objID,
+			PropertyID:    instName,
+			PropertyName:  common.DefaultInstName,
+			PropertyGroup: groupID,
+			PropertyType:  common.FieldTypeSingleChar,
+			IsOnly:        true,
+			IsPre:         true,
+			IsEditable:    true,
+			IsRequired:    true,
+		},
+	}
+	s.uniques[objID] = []schemaUnique{{key: instName}}
+}
+
+// uniqueKey convert unique property ids to the key of a unique, the order of property ids is ignored
+func uniqueKey(propertyIDs []string) string {
+	keys := make([]string, len(propertyIDs))
+	copy(keys, propertyIDs)
+	sort.Strings(keys)
+	return strings.Join(keys, ",")
+}
+
+// diffModelSchema compare model schema with the live models, returns the changes that make the live models consistent
+// with the schema. creates and updates are ordered by dependency, and deletes are placed after them in reverse order.
+func diffModelSchema(schema *metadata.ModelSchema, state *schemaState, allowDelete bool) []metadata.ModelSchemaChange {
+	changes := make([]metadata.ModelSchemaChange, 0)
+
+	for _, cls := range schema.Classifications {
+		live, exists := state.classifications[cls.ClassificationID]
+		if !exists {
+			changes = append(changes, newSchemaChange(metadata.ModelSchemaCreate,
+				metadata.ModelSchemaKindClassification, "", cls.ClassificationID, 0, nil))
+			continue
+		}
+		if data := diffClassification(cls, live); len(data) > 0 {
+			changes = append(changes, newSchemaChange(metadata.ModelSchemaUpdate,
+				metadata.ModelSchemaKindClassification, "", cls.ClassificationID, live.ID, data))
+		}
+	}
+
+	for _, model := range schema.Models {
+		live, exists := state.objects[model.ObjectID]
+		if !exists {
+			changes = append(changes, newSchemaChange(metadata.ModelSchemaCreate, metadata.ModelSchemaKindModel,
+				model.ObjectID, model.ObjectID, 0, nil))
+			continue
+		}
+		if data := diffObject(model, live); len(data) > 0 {
+			changes = append(changes, newSchemaChange(metadata.ModelSchemaUpdate, metadata.ModelSchemaKindModel,
+				model.ObjectID, model.ObjectID, live.ID, data))
+		}
+	}
+
+	for _, kind := range []metadata.ModelSchemaKind{metadata.ModelSchemaKindGroup, metadata.ModelSchemaKindAttribute,
+		metadata.ModelSchemaKindUnique} {
+		for _, model := range schema.Models {
+			changes = append(changes, diffModelItems(kind, model, state)...)
+		}
+	}
+
+	for _, asst := range schema.Associations {
+		live, exists := state.associations[asst.AssociationName]
+		if !exists {
+			changes = append(changes, newSchemaChange(metadata.ModelSchemaCreate,
+				metadata.ModelSchemaKindAssociation, asst.ObjectID, asst.AssociationName, 0, nil))
+			continue
+		}
+		if data := diffAssociation(asst, live); len(data) > 0 {
+			changes = append(changes, newSchemaChange(metadata.ModelSchemaUpdate,
+				metadata.ModelSchemaKindAssociation, asst.ObjectID, asst.AssociationName, live.ID, data))
+		}
+	}
+
+	if allowDelete {
+		changes = append(changes, diffDeletions(schema, state)...)
+	}
+
+	return changes
+}
+
+func newSchemaChange(action metadata.ModelSchemaAction, kind metadata.ModelSchemaKind, objID, key string, id int64,
+	data mapstr.MapStr) metadata.ModelSchemaChange {
+
+	change := metadata.ModelSchemaChange{Action: action, Kind: kind, ObjectID: objID, Key: key, ID: id}
+	if len(data) > 0 {
+		change.Fields = make([]string, 0, len(data))
+		for field := range data {
+			change.Fields = append(change.Fields, field)
+		}
+		sort.Strings(change.Fields)
+	}
+	return change
+}
+
+// diffModelItems diff the groups, attributes or uniques of a declared model
+func diffModelItems(kind metadata.ModelSchemaKind, model metadata.SchemaModel,
+	state *schemaState) []metadata.ModelSchemaChange {
+
+	changes := make([]metadata.ModelSchemaChange, 0)
+	objID := model.ObjectID
+
+	switch kind {
+	case metadata.ModelSchemaKindGroup:
+		for _, group := range model.Groups {
+			live, exists := state.groups[objID][group.GroupID]
+			if !exists {
+				changes = append(changes, newSchemaChange(metadata.ModelSchemaCreate, kind, objID, group.GroupID, 0,
+					nil))
+				continue
+			}
+			if data := diffGroup(group, live); len(data) > 0 {
+				changes = append(changes, newSchemaChange(metadata.ModelSchemaUpdate, kind, objID, group.GroupID,
+					live.ID, data))
+			}
+		}
+
+	case metadata.ModelSchemaKindAttribute:
+		for _, attr := range model.Attributes {
+			live, exists := state.attrs[objID][attr.PropertyID]
+			if !exists {
+				changes = append(changes, newSchemaChange(metadata.ModelSchemaCreate, kind, objID, attr.PropertyID, 0,
+					nil))
+				continue
+			}
+			if data := diffAttribute(attr, live); len(data) > 0 {
+				changes = append(changes, newSchemaChange(metadata.ModelSchemaUpdate, kind, objID, attr.PropertyID,
+					live.ID, data))
+			}
+		}
+
+	case metadata.ModelSchemaKindUnique:
+		liveKeys := make(map[string]struct{})
+		for _, unique := range state.uniques[objID] {
+			liveKeys[unique.key] = struct{}{}
+		}
+		for _, keys := range model.Uniques {
+			key := uniqueKey(keys)
+			if _, exists := liveKeys[key]; !exists {
+				changes = append(changes, newSchemaChange(metadata.ModelSchemaCreate, kind, objID, key, 0, nil))
+				liveKeys[key] = struct{}{}
+			}
+		}
+	}
+
+	return changes
+}
+
+// diffDeletions find the live resources that are not declared in schema, preset ones are never deleted.
+func diffDeletions(schema *metadata.ModelSchema, state *schemaState) []metadata.ModelSchemaChange {
+	changes := make([]metadata.ModelSchemaChange, 0)
+
+	declaredObjs := make(map[string]struct{})
+	declaredAssts := make(map[string]struct{})
+	for _, asst := range schema.Associations {
+		declaredAssts[asst.AssociationName] = struct{}{}
+	}
+	for _, model := range schema.Models {
+		declaredObjs[model.ObjectID] = struct{}{}
+	}
+
+	asstNames := make([]string, 0)
+	for name, asst := range state.associations {
+		if _, exists := declaredAssts[name]; exists {
+			continue
+		}
+		if _, exists := declaredObjs[asst.ObjectID]; !exists {
+			continue
+		}
+		if (asst.IsPre != nil && *asst.IsPre) || asst.AsstKindID == common.AssociationKindMainline {
+			continue
+		}
+		asstNames = append(asstNames, name)
+	}
+	sort.Strings(asstNames)
+	for _, name := range asstNames {
+		asst := state.associations[name]
+		changes = append(changes, newSchemaChange(metadata.ModelSchemaDelete, metadata.ModelSchemaKindAssociation,
+			asst.ObjectID, name, asst.ID, nil))
+	}
+
+	for _, model := range schema.Models {
+		objID := model.ObjectID
+
+		declared := make(map[string]struct{})
+		for _, keys := range model.Uniques {
+			declared[uniqueKey(keys)] = struct{}{}
+		}
+		for _, unique := range state.uniques[objID] {
+			if _, exists := declared[unique.key]; exists || unique.isPre {
+				continue
+			}
+			changes = append(changes, newSchemaChange(metadata.ModelSchemaDelete, metadata.ModelSchemaKindUnique,
+				objID, unique.key, unique.id, nil))
+		}
+	}
+
+	for _, model := range schema.Models {
+		objID := model.ObjectID
+
+		declared := make(map[string]struct{})
+		for _, attr := range model.Attributes {
+			declared[attr.PropertyID] = struct{}{}
+		}
+		propertyIDs := make([]string, 0)
+		for propertyID, attr := range state.attrs[objID] {
+			if _, exists := declared[propertyID]; exists || attr.IsPre {
+				continue
+			}
+			propertyIDs = append(propertyIDs, propertyID)
+		}
+		sort.Strings(propertyIDs)
+		for _, propertyID := range propertyIDs {
+			changes = append(changes, newSchemaChange(metadata.ModelSchemaDelete, metadata.ModelSchemaKindAttribute,
+				objID, propertyID, state.attrs[objID][propertyID].ID, nil))
+		}
+	}
+
+	for _, model := range schema.Models {
+		objID := model.ObjectID
+
+		declared := make(map[string]struct{})
+		for _, group := range model.Groups {
+			declared[group.GroupID] = struct{}{}
+		}
+		groupIDs := make([]string, 0)
+		for groupID, group := range state.groups[objID] {
+			if _, exists := declared[groupID]; exists || group.IsPre || group.IsDefault {
+				continue
+			}
+			groupIDs = append(groupIDs, groupID)
+		}
+		sort.Strings(groupIDs)
+		for _, groupID := range groupIDs {
+			changes = append(changes, newSchemaChange(metadata.ModelSchemaDelete, metadata.ModelSchemaKindGroup,
+				objID, groupID, state.groups[objID][groupID].ID, nil))
+		}
+	}
+
+	return changes
+}
+
+// diffClassification returns the fields of classification that need to be updated
+func diffClassification(cls metadata.SchemaClassification, live metadata.Classification) mapstr.MapStr {
+	data := make(mapstr.MapStr)
+	if cls.ClassificationName != live.ClassificationName {
+		data[common.BKClassificationNameField] = cls.ClassificationName
+	}
+	if len(cls.ClassificationIcon) > 0 && cls.ClassificationIcon != live.ClassificationIcon {
+		data[common.BKClassificationIconField] = cls.ClassificationIcon
+	}
+	return data
+}
+
+// diffObject returns the fields of object that need to be updated
+func diffObject(model metadata.SchemaModel, live metadata.Object) mapstr.MapStr {
+	data := make(mapstr.MapStr)
+	if model.ObjectName != live.ObjectName {
+		data[common.BKObjNameField] = model.ObjectName
+	}
+	if len(model.ObjIcon) > 0 && model.ObjIcon != live.ObjIcon {
+		data[common.BKObjIconField] = model.ObjIcon
+	}
+	if model.ObjCls != live.ObjCls {
+		data[common.BKClassificationIDField] = model.ObjCls
+	}
+	return data
+}
+
+// diffGroup returns the fields of attribute group that need to be updated
+func diffGroup(group metadata.SchemaGroup, live metadata.Group) mapstr.MapStr {
+	data := make(mapstr.MapStr)
+	if group.GroupName != live.GroupName {
+		data[metadata.GroupFieldGroupName] = group.GroupName
+	}
+	if group.GroupIndex != nil && *group.GroupIndex != live.GroupIndex {
+		data[metadata.GroupFieldGroupIndex] = *group.GroupIndex
+	}
+	if group.IsCollapse != nil && *group.IsCollapse != live.IsCollapse {
+		data[common.BKIsCollapseField] = *group.IsCollapse
+	}
+	return data
+}
+
+// diffAttribute returns the fields of attribute that need to be updated
+func diffAttribute(attr metadata.SchemaAttribute, live metadata.Attribute) mapstr.MapStr {
+	data := make(mapstr.MapStr)
+	if attr.PropertyName != live.PropertyName {
+		data[metadata.AttributeFieldPropertyName] = attr.PropertyName
+	}
+	if len(attr.PropertyGroup) > 0 && attr.PropertyGroup != live.PropertyGroup {
+		data[metadata.AttributeFieldPropertyGroup] = attr.PropertyGroup
+	}
+	if attr.PropertyType != live.PropertyType {
+		data[metadata.AttributeFieldPropertyType] = attr.PropertyType
+	}
+	if attr.Unit != live.Unit {
+		data[metadata.AttributeFieldUnit] = attr.Unit
+	}
+	if attr.Placeholder != live.Placeholder {
+		data[metadata.AttributeFieldPlaceHolder] = attr.Placeholder
+	}
+	if attr.Description != live.Description {
+		data[metadata.AttributeFieldDescription] = attr.Description
+	}
+	if attr.IsEditable != nil && *attr.IsEditable != live.IsEditable {
+		data[metadata.AttributeFieldIsEditable] = *attr.IsEditable
+	}
+	if attr.IsRequired != nil && *attr.IsRequired != live.IsRequired {
+		data[metadata.AttributeFieldIsRequired] = *attr.IsRequired
+	}
+	if attr.IsReadOnly != nil && *attr.IsReadOnly != live.IsReadOnly {
+		data[metadata.AttributeFieldIsReadOnly] = *attr.IsReadOnly
+	}
+	if attr.Option != nil && !isSchemaValueEqual(attr.Option, live.Option) {
+		data[metadata.AttributeFieldOption] = attr.Option
+	}
+	return data
+}
+
+// diffAssociation returns the fields of model association that need to be updated
+func diffAssociation(asst metadata.SchemaAssociation, live metadata.Association) mapstr.MapStr {
+	data := make(mapstr.MapStr)
+	if asst.AssociationAliasName != live.AssociationAliasName {
+		data["bk_obj_asst_name"] = asst.AssociationAliasName
+	}
+	if asst.AsstKindID != live.AsstKindID {
+		data[common.AssociationKindIDField] = asst.AsstKindID
+	}
+	if len(asst.OnDelete) > 0 && asst.OnDelete != live.OnDelete {
+		data["on_delete"] = asst.OnDelete
+	}
+	// the following fields can not be updated, they are reported so that the apply fails with a clear reason
+	if asst.ObjectID != live.ObjectID {
+		data[common.BKObjIDField] = asst.ObjectID
+	}
+	if asst.AsstObjID != live.AsstObjID {
+		data[common.BKAsstObjIDField] = asst.AsstObjID
+	}
+	if len(asst.Mapping) > 0 && asst.Mapping != live.Mapping {
+		data["mapping"] = asst.Mapping
+	}
+	return data
+}
+
+// isSchemaValueEqual compare values decoded from different sources by their json form
+func isSchemaValueEqual(a, b interface{}) bool {
+	aJs, aErr := json.Marshal(a)
+	bJs, bErr := json.Marshal(b)
+	if aErr != nil || bErr != nil {
+		return reflect.DeepEqual(a, b)
+	}
+	return string(aJs) == string(bJs)
+}
@@ -0,0 +1,105 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model
+
+import (
+	"reflect"
+	"testing"
+
+	"configcenter/src/common/metadata"
+)
+
+func TestDiffModelSchema(t *testing.T) {
+	editable := false
+	schema := &metadata.ModelSchema{
+		Classifications: []metadata.SchemaClassification{
+			{ClassificationID: "bk_network", ClassificationName: "network"},
+		},
+		Models: []metadata.SchemaModel{
+			{
+				ObjectID:   "switch",
+				ObjectName: "Switch",
+				ObjCls:     "bk_network",
+				Groups:     []metadata.SchemaGroup{{GroupID: "default", GroupName: "Default"}},
+				Attributes: []metadata.SchemaAttribute{
+					{PropertyID: "bk_inst_name", PropertyName: "实例名", PropertyType: "singlechar"},
+					{PropertyID: "sn", PropertyName: "SN", PropertyType: "singlechar", IsEditable: &editable},
+				},
+				Uniques: [][]string{{"sn"}, {"bk_inst_name"}},
+			},
+			{
+				ObjectID:   "router",
+				ObjectName: "Router",
+				ObjCls:     "bk_network",
+				Attributes: []metadata.SchemaAttribute{
+					{PropertyID: "sn", PropertyName: "SN", PropertyType: "singlechar"},
+				},
+				Uniques: [][]string{{"sn", "bk_inst_name"}},
+			},
+		},
+		Associations: []metadata.SchemaAssociation{
+			{AssociationName: "switch_connect_router", ObjectID: "switch", AsstObjID: "router", AsstKindID: "connect"},
+		},
+	}
+
+	state := newSchemaState()
+	state.classifications["bk_network"] = metadata.Classification{ID: 1, ClassificationID: "bk_network",
+		ClassificationName: "network"}
+	state.objects["switch"] = metadata.Object{ID: 2, ObjectID: "switch", ObjectName: "switch", ObjCls: "bk_network"}
+	state.addDefaultObject("switch")
+	state.groups["switch"]["custom"] = metadata.Group{ID: 3, GroupID: "custom", GroupName: "custom"}
+	state.attrs["switch"]["sn"] = metadata.Attribute{ID: 4, PropertyID: "sn", PropertyName: "SN",
+		PropertyType: "singlechar", PropertyGroup: "default", IsEditable: true}
+	state.attrs["switch"]["useless"] = metadata.Attribute{ID: 5, PropertyID: "useless"}
+	state.uniques["switch"] = append(state.uniques["switch"], schemaUnique{id: 6, key: "useless"})
+	state.associations["switch_belong_router"] = metadata.Association{ID: 7, AssociationName: "switch_belong_router",
+		ObjectID: "switch", AsstObjID: "router", AsstKindID: "belong"}
+	state.addDefaultObject("router")
+
+	expected := []metadata.ModelSchemaChange{
+		{Action: metadata.ModelSchemaUpdate, Kind: metadata.ModelSchemaKindModel, ObjectID: "switch", Key: "switch",
+			ID: 2, Fields: []string{"bk_obj_name"}},
+		{Action: metadata.ModelSchemaCreate, Kind: metadata.ModelSchemaKindModel, ObjectID: "router", Key: "router"},
+		{Action: metadata.ModelSchemaUpdate, Kind: metadata.ModelSchemaKindAttribute, ObjectID: "switch", Key: "sn",
+			ID: 4, Fields: []string{"editable"}},
+		{Action: metadata.ModelSchemaCreate, Kind: metadata.ModelSchemaKindAttribute, ObjectID: "router", Key: "sn"},
+		{Action: metadata.ModelSchemaCreate, Kind: metadata.ModelSchemaKindUnique, ObjectID: "switch", Key: "sn"},
+		{Action: metadata.ModelSchemaCreate, Kind: metadata.ModelSchemaKindUnique, ObjectID: "router",
+			Key: "bk_inst_name,sn"},
+		{Action: metadata.ModelSchemaCreate, Kind: metadata.ModelSchemaKindAssociation, ObjectID: "switch",
+			Key: "switch_connect_router"},
+	}
+
+	changes := diffModelSchema(schema, state, false)
+	if !reflect.DeepEqual(changes, expected) {
+		t.Fatalf("diff without delete, expect %+v, actual %+v", expected, changes)
+	}
+
+	expected = append(expected,
+		metadata.ModelSchemaChange{Action: metadata.ModelSchemaDelete, Kind: metadata.ModelSchemaKindAssociation,
+			ObjectID: "switch", Key: "switch_belong_router", ID: 7},
+		metadata.ModelSchemaChange{Action: metadata.ModelSchemaDelete, Kind: metadata.ModelSchemaKindUnique,
+			ObjectID: "switch", Key: "useless", ID: 6},
+		metadata.ModelSchemaChange{Action: metadata.ModelSchemaDelete, Kind: metadata.ModelSchemaKindUnique,
+			ObjectID: "router", Key: "bk_inst_name"},
+		metadata.ModelSchemaChange{Action: metadata.ModelSchemaDelete, Kind: metadata.ModelSchemaKindAttribute,
+			ObjectID: "switch", Key: "useless", ID: 5},
+		metadata.ModelSchemaChange{Action: metadata.ModelSchemaDelete, Kind: metadata.ModelSchemaKindGroup,
+			ObjectID: "switch", Key: "custom", ID: 3},
+	)
+
+	changes = diffModelSchema(schema, state, true)
+	if !reflect.DeepEqual(changes, expected) {
+		t.Fatalf("diff with delete, expect %+v, actual %+v", expected, changes)
+	}
+}
@@ -0,0 +1,557 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"configcenter/src/common"
+	"configcenter/src/common/blog"
+	"configcenter/src/common/http/rest"
+	"configcenter/src/common/json"
+	"configcenter/src/common/mapstr"
+	"configcenter/src/common/metadata"
+	"configcenter/src/common/util"
+)
+
+// instHistoryActions are the audit log actions that changes the attributes of an instance
+var instHistoryActions = []metadata.ActionType{metadata.AuditCreate, metadata.AuditUpdate, metadata.AuditDelete,
+	metadata.AuditArchive, metadata.AuditRecover}
+
+// instHistoryIgnoredFields are the fields that are not compared in the instance history diff
+var instHistoryIgnoredFields = map[string]struct{}{
+	"_id":                {},
+	common.LastTimeField: {},
+}
+
+// FindInstHistory reconstruct the attribute state of an instance at a point in time from its audit logs
+func (s *Service) FindInstHistory(ctx *rest.Contexts) {
+	objID, instID, err := parseInstHistoryPath(ctx)
+	if err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	opt := new(metadata.InstHistoryOption)
+	if err := ctx.DecodeInto(opt); err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	t, err := opt.Validate()
+	if err != nil {
+		blog.Errorf("instance history option %+v is invalid, err: %v, rid: %s", opt, err, ctx.Kit.Rid)
+		ctx.RespAutoError(ctx.Kit.CCError.CCErrorf(common.CCErrCommParamsInvalid, err.Error()))
+		return
+	}
+
+	ctx.SetReadPreference(common.SecondaryPreferredMode)
+	snapshot, err := s.getInstSnapshot(ctx.Kit, objID, instID, t)
+	if err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	ctx.RespEntity(snapshot)
+}
+
+// FindInstHistoryDiff compare the attribute state of an instance between two points in time field by field
+func (s *Service) FindInstHistoryDiff(ctx *rest.Contexts) {
+	objID, instID, err := parseInstHistoryPath(ctx)
+	if err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	opt := new(metadata.InstHistoryDiffOption)
+	if err := ctx.DecodeInto(opt); err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	from, to, err := opt.Validate()
+	if err != nil {
+		blog.Errorf("instance history diff option %+v is invalid, err: %v, rid: %s", opt, err, ctx.Kit.Rid)
+		ctx.RespAutoError(ctx.Kit.CCError.CCErrorf(common.CCErrCommParamsInvalid, err.Error()))
+		return
+	}
+
+	ctx.SetReadPreference(common.SecondaryPreferredMode)
+	fromSnapshot, err := s.getInstSnapshot(ctx.Kit, objID, instID, from)
+	if err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	toSnapshot, err := s.getInstSnapshot(ctx.Kit, objID, instID, to)
+	if err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	result := &metadata.InstHistoryDiffResult{
+		From:  fromSnapshot,
+		To:    toSnapshot,
+		Diffs: diffInstData(fromSnapshot.Data, toSnapshot.Data, opt.Fields),
+	}
+	ctx.RespEntity(result)
+}
+
+// RevertInst revert the selected fields of an instance to their values at a point in time, the instance is updated
+// with the normal update logics, so the revert itself is also recorded in audit log
+func (s *Service) RevertInst(ctx *rest.Contexts) {
+	objID, instID, err := parseInstHistoryPath(ctx)
+	if err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	// only the instances that can be updated with the common update api can be reverted
+	if common.IsInnerModel(objID) && !util.InArray(objID, whiteList) {
+		blog.Errorf("revert %s instance with common api forbidden, rid: %s", objID, ctx.Kit.Rid)
+		ctx.RespAutoError(ctx.Kit.CCError.CCError(common.CCErrCommForbiddenOperateInnerModelInstanceWithCommonAPI))
+		return
+	}
+
+	opt := new(metadata.InstRevertOption)
+	if err := ctx.DecodeInto(opt); err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	t, err := opt.Validate()
+	if err != nil {
+		blog.Errorf("instance revert option %+v is invalid, err: %v, rid: %s", opt, err, ctx.Kit.Rid)
+		ctx.RespAutoError(ctx.Kit.CCError.CCErrorf(common.CCErrCommParamsInvalid, err.Error()))
+		return
+	}
+
+	if err := s.validateRevertFields(ctx.Kit, objID, opt.Fields); err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	// the host fields that are managed by the host apply rules would be overwritten by the rules again
+	if objID == common.BKInnerObjIDHost {
+		if err := s.validateHostApplyLockedFields(ctx.Kit, instID, opt.Fields); err != nil {
+			ctx.RespAutoError(err)
+			return
+		}
+	}
+
+	snapshot, err := s.getInstSnapshot(ctx.Kit, objID, instID, t)
+	if err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	if !snapshot.Exists {
+		blog.Errorf("%s instance %d does not exist at %s, can not revert, rid: %s", objID, instID, opt.Time,
+			ctx.Kit.Rid)
+		ctx.RespAutoError(ctx.Kit.CCError.CCErrorf(common.CCErrCommParamsInvalid, "time"))
+		return
+	}
+
+	current, exists, err := s.getCurrentInst(ctx.Kit, objID, instID)
+	if err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	if !exists {
+		blog.Errorf("%s instance %d is not found, rid: %s", objID, instID, ctx.Kit.Rid)
+		ctx.RespAutoError(ctx.Kit.CCError.CCError(common.CCErrCommNotFound))
+		return
+	}
+
+	diffs := diffInstData(current, snapshot.Data, opt.Fields)
+	result := &metadata.InstRevertResult{Diffs: diffs}
+	if len(diffs) == 0 {
+		ctx.RespEntity(result)
+		return
+	}
+
+	data := make(mapstr.MapStr, len(diffs))
+	for _, diff := range diffs {
+		data[diff.Field] = diff.To
+	}
+
+	cond := mapstr.MapStr{metadata.GetInstIDFieldByObjID(objID): instID}
+	txnErr := s.Engine.CoreAPI.CoreService().Txn().AutoRunTxn(ctx.Kit.Ctx, ctx.Kit.Header, func() error {
+		err = s.Logics.InstOperation().UpdateInst(ctx.Kit, cond, data, objID)
+		if err != nil {
+			blog.Errorf("revert %s inst %d failed, data: %#v, err: %v, rid: %s", objID, instID, data, err,
+				ctx.Kit.Rid)
+			return err
+		}
+		return nil
+	})
+
+	if txnErr != nil {
+		ctx.RespAutoError(txnErr)
+		return
+	}
+	ctx.RespEntity(result)
+}
+
+func parseInstHistoryPath(ctx *rest.Contexts) (string, int64, error) {
+	objID := ctx.Request.PathParameter("bk_obj_id")
+	if objID == "" {
+		return "", 0, ctx.Kit.CCError.CCErrorf(common.CCErrCommParamsNeedSet, common.BKObjIDField)
+	}
+
+	instID, err := strconv.ParseInt(ctx.Request.PathParameter("bk_inst_id"), 10, 64)
+	if err != nil || instID <= 0 {
+		blog.Errorf("parse instance id failed, err: %v, rid: %s", err, ctx.Kit.Rid)
+		return "", 0, ctx.Kit.CCError.CCErrorf(common.CCErrCommParamsNeedInt, common.BKInstIDField)
+	}
+	return objID, instID, nil
+}
+
+// getInstSnapshot get the attribute state of an instance at the time. The first audit log after the time records the
+// exact state before it as its pre data, if there is no change after the time, the current state is returned.
+// Otherwise, the state is replayed from the last audit log before the time.
+func (s *Service) getInstSnapshot(kit *rest.Kit, objID string, instID int64, t time.Time) (*metadata.InstSnapshot,
+	error) {
+
+	snapshot := &metadata.InstSnapshot{
+		ObjID:  objID,
+		InstID: instID,
+		Time:   formatInstHistoryTime(t),
+	}
+
+	cond, err := s.genInstHistoryAuditCond(kit, objID, instID)
+	if err != nil {
+		return nil, err
+	}
+
+	next, err := s.searchOneInstAudit(kit, cond, common.BKDBGT, t, common.BKFieldID)
+	if err != nil {
+		return nil, err
+	}
+
+	if next != nil {
+		if setSnapshotBeforeAudit(snapshot, next) {
+			return snapshot, nil
+		}
+	} else {
+		current, exists, err := s.getCurrentInst(kit, objID, instID)
+		if err != nil {
+			return nil, err
+		}
+
+		if exists {
+			snapshot.Exists, snapshot.Data = true, cleanInstData(current)
+			return snapshot, nil
+		}
+	}
+
+	prev, err := s.searchOneInstAudit(kit, cond, common.BKDBLTE, t, "-"+common.BKFieldID)
+	if err != nil {
+		return nil, err
+	}
+
+	if prev != nil {
+		setSnapshotAfterAudit(snapshot, prev)
+	}
+	return snapshot, nil
+}
+
+// setSnapshotBeforeAudit set the snapshot to the state of the instance right before the audit log, returns false if
+// the state can not be decided by the audit log
+func setSnapshotBeforeAudit(snapshot *metadata.InstSnapshot, audit *metadata.AuditLog) bool {
+	if audit.Action == metadata.AuditCreate {
+		snapshot.AuditID = audit.ID
+		return true
+	}
+
+	details := getInstAuditDetails(audit)
+	if details == nil || details.PreData == nil {
+		return false
+	}
+
+	snapshot.Exists, snapshot.AuditID, snapshot.Data = true, audit.ID, cleanInstData(details.PreData)
+	return true
+}
+
+// setSnapshotAfterAudit set the snapshot to the state of the instance replayed from the audit log
+func setSnapshotAfterAudit(snapshot *metadata.InstSnapshot, audit *metadata.AuditLog) {
+	if audit.Action == metadata.AuditDelete {
+		return
+	}
+
+	details := getInstAuditDetails(audit)
+	if details == nil {
+		return
+	}
+
+	data := make(mapstr.MapStr)
+	if audit.Action == metadata.AuditCreate {
+		data.Merge(details.CurData)
+	} else {
+		data.Merge(details.PreData)
+		data.Merge(details.UpdateFields)
+	}
+	snapshot.Exists, snapshot.AuditID, snapshot.Data = true, audit.ID, cleanInstData(data)
+}
+
+// genInstHistoryAuditCond generate the condition of the audit logs that changes the attributes of the instance
+func (s *Service) genInstHistoryAuditCond(kit *rest.Kit, objID string, instID int64) (mapstr.MapStr, error) {
+	isMainline, err := s.Logics.AssociationOperation().IsMainlineObject(kit, objID)
+	if err != nil {
+		blog.Errorf("check if object %s is mainline object failed, err: %v, rid: %s", objID, err, kit.Rid)
+		return nil, err
+	}
+
+	resourceType := metadata.GetResourceTypeByObjID(objID, isMainline)
+	cond := mapstr.MapStr{
+		common.BKResourceTypeField: resourceType,
+		common.BKResourceIDField:   instID,
+		common.BKActionField:       mapstr.MapStr{common.BKDBIN: instHistoryActions},
+	}
+
+	if resourceType == metadata.ModelInstanceRes || resourceType == metadata.MainlineInstanceRes {
+		cond[common.BKOperationDetailField+"."+common.BKObjIDField] = objID
+	}
+	return cond, nil
+}
+
+// searchOneInstAudit search the first audit log of the instance that matches the operation time condition
+func (s *Service) searchOneInstAudit(kit *rest.Kit, cond mapstr.MapStr, timeOperator string, t time.Time,
+	sortField string) (*metadata.AuditLog, error) {
+
+	auditCond := cond.Clone()
+	// the time is formatted in full precision, so that the audit logs in the same second are compared exactly
+	auditCond[common.BKOperationTimeField] = mapstr.MapStr{timeOperator: formatInstHistoryTime(t)}
+	query := metadata.QueryCondition{
+		Condition: auditCond,
+		Page:      metadata.BasePage{Sort: sortField, Limit: 1},
+	}
+
+	rsp, err := s.Engine.CoreAPI.CoreService().Audit().SearchAuditLog(kit.Ctx, kit.Header, query)
+	if err != nil {
+		blog.Errorf("search instance audit log failed, cond: %#v, err: %v, rid: %s", auditCond, err, kit.Rid)
+		return nil, err
+	}
+
+	if len(rsp.Info) == 0 {
+		return nil, nil
+	}
+	return &rsp.Info[0], nil
+}
+
+// getCurrentInst get the current state of the instance, returns if the instance exists
+func (s *Service) getCurrentInst(kit *rest.Kit, objID string, instID int64) (mapstr.MapStr, bool, error) {
+	query := &metadata.QueryCondition{
+		Condition: mapstr.MapStr{metadata.GetInstIDFieldByObjID(objID): instID},
+		Page:      metadata.BasePage{Limit: 1},
+	}
+
+	rsp, err := s.Engine.CoreAPI.CoreService().Instance().ReadInstance(kit.Ctx, kit.Header, objID, query)
+	if err != nil {
+		blog.Errorf("get %s instance %d failed, err: %v, rid: %s", objID, instID, err, kit.Rid)
+		return nil, false, kit.CCError.CCError(common.CCErrTopoInstSelectFailed)
+	}
+
+	if len(rsp.Info) == 0 {
+		return nil, false, nil
+	}
+	return rsp.Info[0], true, nil
+}
+
+// validateRevertFields check if the fields are the editable attributes of the object that can be reverted
+func (s *Service) validateRevertFields(kit *rest.Kit, objID string, fields []string) error {
+	query := &metadata.QueryCondition{
+		Condition: mapstr.MapStr{
+			common.BKObjIDField:      objID,
+			common.BKPropertyIDField: mapstr.MapStr{common.BKDBIN: fields},
+		},
+		Page: metadata.BasePage{Limit: common.BKNoLimit},
+	}
+
+	rsp, err := s.Engine.CoreAPI.CoreService().Model().ReadModelAttr(kit.Ctx, kit.Header, objID, query)
+	if err != nil {
+		blog.Errorf("get %s attributes %v failed, err: %v, rid: %s", objID, fields, err, kit.Rid)
+		return err
+	}
+
+	editable := make(map[string]bool, len(rsp.Info))
+	for _, attr := range rsp.Info {
+		editable[attr.PropertyID] = attr.IsEditable
+	}
+
+	for _, field := range fields {
+		switch field {
+		case metadata.GetInstIDFieldByObjID(objID), common.BKAppIDField, common.BKParentIDField:
+			blog.Errorf("field %s of %s instance can not be reverted, rid: %s", field, objID, kit.Rid)
+			return kit.CCError.CCErrorf(common.CCErrCommParamsInvalid, field)
+		}
+
+		if !editable[field] {
+			blog.Errorf("field %s is not an editable attribute of %s, rid: %s", field, objID, kit.Rid)
+			return kit.CCError.CCErrorf(common.CCErrCommParamsInvalid, field)
+		}
+	}
+	return nil
+}
+
+// validateHostApplyLockedFields check if the fields are locked by the host apply rules that take effect on the host
+func (s *Service) validateHostApplyLockedFields(kit *rest.Kit, hostID int64, fields []string) error {
+	relReq := &metadata.HostModuleRelationRequest{
+		HostIDArr: []int64{hostID},
+		Page:      metadata.BasePage{Limit: common.BKNoLimit},
+		Fields:    []string{common.BKAppIDField, common.BKModuleIDField},
+	}
+	relRsp, err := s.Engine.CoreAPI.CoreService().Host().GetHostModuleRelation(kit.Ctx, kit.Header, relReq)
+	if err != nil {
+		blog.Errorf("get host %d module relations failed, err: %v, rid: %s", hostID, err, kit.Rid)
+		return err
+	}
+
+	if len(relRsp.Info) == 0 {
+		return nil
+	}
+
+	moduleIDs := make([]int64, len(relRsp.Info))
+	for idx, relation := range relRsp.Info {
+		moduleIDs[idx] = relation.ModuleID
+	}
+
+	ruleOpt := &metadata.ModuleFinalRulesParam{ApplicationID: relRsp.Info[0].AppID, ModuleIDs: moduleIDs}
+	rules, err := s.Engine.CoreAPI.HostServer().GetModuleFinalRules(kit.Ctx, kit.Header, ruleOpt)
+	if err != nil {
+		blog.Errorf("get host apply rules of modules %v failed, err: %v, rid: %s", moduleIDs, err, kit.Rid)
+		return err
+	}
+
+	if len(rules) == 0 {
+		return nil
+	}
+
+	attrIDs := make([]int64, len(rules))
+	for idx, rule := range rules {
+		attrIDs[idx] = rule.AttributeID
+	}
+
+	query := &metadata.QueryCondition{
+		Condition: mapstr.MapStr{common.BKFieldID: mapstr.MapStr{common.BKDBIN: attrIDs}},
+		Fields:    []string{common.BKFieldID, common.BKPropertyIDField},
+		Page:      metadata.BasePage{Limit: common.BKNoLimit},
+	}
+	attrRsp, err := s.Engine.CoreAPI.CoreService().Model().ReadModelAttr(kit.Ctx, kit.Header,
+		common.BKInnerObjIDHost, query)
+	if err != nil {
+		blog.Errorf("get host attributes %v failed, err: %v, rid: %s", attrIDs, err, kit.Rid)
+		return err
+	}
+
+	propertyIDs := make(map[int64]string, len(attrRsp.Info))
+	for _, attr := range attrRsp.Info {
+		propertyIDs[attr.ID] = attr.PropertyID
+	}
+
+	locked := getHostApplyLockedFields(fields, rules, propertyIDs)
+	if len(locked) > 0 {
+		blog.Errorf("host %d fields %v are locked by host apply rules, rid: %s", hostID, locked, kit.Rid)
+		return kit.CCError.CCErrorf(common.CCErrCommModifyFieldForbidden, strings.Join(locked, ","))
+	}
+	return nil
+}
+
+// getHostApplyLockedFields get the fields that are locked by the host apply rules, propertyIDs is the map of the
+// attribute id to its property id
+func getHostApplyLockedFields(fields []string, rules []metadata.HostApplyRule, propertyIDs map[int64]string) []string {
+	lockedMap := make(map[string]struct{}, len(rules))
+	for _, rule := range rules {
+		if propertyID, exists := propertyIDs[rule.AttributeID]; exists {
+			lockedMap[propertyID] = struct{}{}
+		}
+	}
+
+	locked := make([]string, 0)
+	for _, field := range fields {
+		if _, exists := lockedMap[field]; exists {
+			locked = append(locked, field)
+		}
+	}
+	return locked
+}
+
+// formatInstHistoryTime format the time in full precision, it is accepted by the audit log search api
+func formatInstHistoryTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+func getInstAuditDetails(audit *metadata.AuditLog) *metadata.BasicContent {
+	detail, ok := audit.OperationDetail.(*metadata.InstanceOpDetail)
+	if !ok || detail == nil {
+		return nil
+	}
+	return detail.Details
+}
+
+func cleanInstData(data mapstr.MapStr) mapstr.MapStr {
+	cleaned := make(mapstr.MapStr, len(data))
+	for key, val := range data {
+		if key == "_id" {
+			continue
+		}
+		cleaned[key] = val
+	}
+	return cleaned
+}
+
+// diffInstData compare the instance data field by field, compare all the fields if fields are not set
+func diffInstData(from, to mapstr.MapStr, fields []string) []metadata.InstFieldDiff {
+	if len(fields) == 0 {
+		fieldMap := make(map[string]struct{})
+		for field := range from {
+			fieldMap[field] = struct{}{}
+		}
+		for field := range to {
+			fieldMap[field] = struct{}{}
+		}
+
+		for field := range fieldMap {
+			if _, ignored := instHistoryIgnoredFields[field]; ignored {
+				continue
+			}
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+	}
+
+	diffs := make([]metadata.InstFieldDiff, 0)
+	for _, field := range fields {
+		fromVal, toVal := from[field], to[field]
+		if isInstValueEqual(fromVal, toVal) {
+			continue
+		}
+		diffs = append(diffs, metadata.InstFieldDiff{Field: field, From: fromVal, To: toVal})
+	}
+	return diffs
+}
+
+// isInstValueEqual compare the values by their json encoding, since the numbers decoded from db and api may be of
+// different types
+func isInstValueEqual(a, b interface{}) bool {
+	aJs, aErr := json.Marshal(a)
+	bJs, bErr := json.Marshal(b)
+	if aErr != nil || bErr != nil {
+		return false
+	}
+	return string(aJs) == string(bJs)
+}
@@ -0,0 +1,232 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"configcenter/src/common/mapstr"
+	"configcenter/src/common/metadata"
+)
+
+func newInstAudit(id int64, action metadata.ActionType, details *metadata.BasicContent) *metadata.AuditLog {
+	return &metadata.AuditLog{
+		ID:     id,
+		Action: action,
+		OperationDetail: &metadata.InstanceOpDetail{
+			BasicOpDetail: metadata.BasicOpDetail{Details: details},
+			ModelID:       "test",
+		},
+	}
+}
+
+func TestDiffInstData(t *testing.T) {
+	from := mapstr.MapStr{
+		"_id":       "abc",
+		"name":      "a",
+		"count":     int64(1),
+		"tags":      []interface{}{"x", "y"},
+		"removed":   "r",
+		"last_time": "2022-10-20",
+	}
+	to := mapstr.MapStr{
+		"_id":       "def",
+		"name":      "b",
+		"count":     float64(1),
+		"tags":      []interface{}{"x", "y"},
+		"added":     "n",
+		"last_time": "2022-10-21",
+	}
+
+	tests := []struct {
+		name   string
+		fields []string
+		want   []metadata.InstFieldDiff
+	}{
+		{
+			name: "compare all fields",
+			want: []metadata.InstFieldDiff{
+				{Field: "added", From: nil, To: "n"},
+				{Field: "name", From: "a", To: "b"},
+				{Field: "removed", From: "r", To: nil},
+			},
+		},
+		{
+			name:   "compare selected fields",
+			fields: []string{"count", "name"},
+			want:   []metadata.InstFieldDiff{{Field: "name", From: "a", To: "b"}},
+		},
+		{
+			name:   "compare the same fields",
+			fields: []string{"count", "tags"},
+			want:   []metadata.InstFieldDiff{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := diffInstData(from, to, tt.fields); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffInstData() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetSnapshotBeforeAudit(t *testing.T) {
+	tests := []struct {
+		name       string
+		audit      *metadata.AuditLog
+		wantOK     bool
+		wantExists bool
+		wantData   mapstr.MapStr
+	}{
+		{
+			name:   "instance is not created before the create audit",
+			audit:  newInstAudit(1, metadata.AuditCreate, &metadata.BasicContent{CurData: map[string]interface{}{"a": 1}}),
+			wantOK: true,
+		},
+		{
+			name: "instance state before the update audit",
+			audit: newInstAudit(2, metadata.AuditUpdate, &metadata.BasicContent{
+				PreData:      map[string]interface{}{"_id": "x", "a": 1, "b": 2},
+				UpdateFields: map[string]interface{}{"a": 3},
+			}),
+			wantOK:     true,
+			wantExists: true,
+			wantData:   mapstr.MapStr{"a": 1, "b": 2},
+		},
+		{
+			name:       "instance state before the delete audit",
+			audit:      newInstAudit(3, metadata.AuditDelete, &metadata.BasicContent{PreData: map[string]interface{}{"a": 1}}),
+			wantOK:     true,
+			wantExists: true,
+			wantData:   mapstr.MapStr{"a": 1},
+		},
+		{
+			name:  "audit without pre data",
+			audit: newInstAudit(4, metadata.AuditUpdate, &metadata.BasicContent{}),
+		},
+		{
+			name:  "audit without instance detail",
+			audit: &metadata.AuditLog{ID: 5, Action: metadata.AuditUpdate},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snapshot := new(metadata.InstSnapshot)
+			ok := setSnapshotBeforeAudit(snapshot, tt.audit)
+			if ok != tt.wantOK {
+				t.Fatalf("setSnapshotBeforeAudit() = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+
+			if snapshot.AuditID != tt.audit.ID || snapshot.Exists != tt.wantExists {
+				t.Errorf("snapshot audit id = %d, exists = %v, want %d and %v", snapshot.AuditID, snapshot.Exists,
+					tt.audit.ID, tt.wantExists)
+			}
+			if !reflect.DeepEqual(snapshot.Data, tt.wantData) {
+				t.Errorf("snapshot data = %v, want %v", snapshot.Data, tt.wantData)
+			}
+		})
+	}
+}
+
+func TestSetSnapshotAfterAudit(t *testing.T) {
+	tests := []struct {
+		name       string
+		audit      *metadata.AuditLog
+		wantExists bool
+		wantData   mapstr.MapStr
+	}{
+		{
+			name: "instance state after the create audit",
+			audit: newInstAudit(1, metadata.AuditCreate, &metadata.BasicContent{
+				CurData: map[string]interface{}{"_id": "x", "a": 1},
+			}),
+			wantExists: true,
+			wantData:   mapstr.MapStr{"a": 1},
+		},
+		{
+			name: "instance state after the update audit",
+			audit: newInstAudit(2, metadata.AuditUpdate, &metadata.BasicContent{
+				PreData:      map[string]interface{}{"a": 1, "b": 2},
+				UpdateFields: map[string]interface{}{"a": 3},
+			}),
+			wantExists: true,
+			wantData:   mapstr.MapStr{"a": 3, "b": 2},
+		},
+		{
+			name:  "instance does not exist after the delete audit",
+			audit: newInstAudit(3, metadata.AuditDelete, &metadata.BasicContent{PreData: map[string]interface{}{"a": 1}}),
+		},
+		{
+			name:  "audit without instance detail",
+			audit: &metadata.AuditLog{ID: 4, Action: metadata.AuditUpdate},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snapshot := new(metadata.InstSnapshot)
+			setSnapshotAfterAudit(snapshot, tt.audit)
+
+			if snapshot.Exists != tt.wantExists {
+				t.Fatalf("snapshot exists = %v, want %v", snapshot.Exists, tt.wantExists)
+			}
+			if !tt.wantExists {
+				return
+			}
+
+			if snapshot.AuditID != tt.audit.ID {
+				t.Errorf("snapshot audit id = %d, want %d", snapshot.AuditID, tt.audit.ID)
+			}
+			if !reflect.DeepEqual(snapshot.Data, tt.wantData) {
+				t.Errorf("snapshot data = %v, want %v", snapshot.Data, tt.wantData)
+			}
+		})
+	}
+}
+
+func TestGetHostApplyLockedFields(t *testing.T) {
+	rules := []metadata.HostApplyRule{{AttributeID: 1}, {AttributeID: 2}, {AttributeID: 3}}
+	propertyIDs := map[int64]string{1: "bk_comment", 2: "operator"}
+
+	got := getHostApplyLockedFields([]string{"operator", "bk_host_name", "bk_comment"}, rules, propertyIDs)
+	want := []string{"operator", "bk_comment"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("getHostApplyLockedFields() = %v, want %v", got, want)
+	}
+}
+
+func TestFormatInstHistoryTime(t *testing.T) {
+	loc := time.FixedZone("UTC+8", 8*3600)
+	tm := time.Date(2022, 10, 20, 10, 11, 12, 345000000, loc)
+
+	got := formatInstHistoryTime(tm)
+	if got != "2022-10-20T02:11:12.345Z" {
+		t.Errorf("formatInstHistoryTime() = %s, want 2022-10-20T02:11:12.345Z", got)
+	}
+
+	parsed, err := metadata.ParseInstHistoryTime(got)
+	if err != nil {
+		t.Fatalf("parse formatted time failed, err: %v", err)
+	}
+	if !parsed.Equal(tm) {
+		t.Errorf("parsed time %s does not equal to %s, the precision is lost", parsed, tm)
+	}
+}
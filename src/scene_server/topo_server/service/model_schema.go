@@ -0,0 +1,152 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"strconv"
+
+	"configcenter/src/ac"
+	"configcenter/src/ac/iam"
+	"configcenter/src/common"
+	"configcenter/src/common/auth"
+	"configcenter/src/common/blog"
+	"configcenter/src/common/http/rest"
+	"configcenter/src/common/mapstr"
+	"configcenter/src/common/metadata"
+)
+
+// DiffModelSchema compare the declared model schema with the live models, returns the changes without applying them
+func (s *Service) DiffModelSchema(ctx *rest.Contexts) {
+	opt := new(metadata.ModelSchemaOption)
+	if err := ctx.DecodeInto(opt); err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	if rawErr := opt.Schema.Validate(); rawErr.ErrCode != 0 {
+		ctx.RespAutoError(rawErr.ToCCError(ctx.Kit.CCError))
+		return
+	}
+
+	changes, err := s.Logics.SchemaOperation().DiffModelSchema(ctx.Kit, &opt.Schema, opt.AllowDelete)
+	if err != nil {
+		blog.Errorf("diff model schema failed, err: %v, rid: %s", err, ctx.Kit.Rid)
+		ctx.RespAutoError(err)
+		return
+	}
+
+	ctx.RespEntity(metadata.ModelSchemaResult{Changes: changes})
+}
+
+// ApplyModelSchema apply the declared model schema to the live models, returns the applied changes
+func (s *Service) ApplyModelSchema(ctx *rest.Contexts) {
+	opt := new(metadata.ModelSchemaOption)
+	if err := ctx.DecodeInto(opt); err != nil {
+		ctx.RespAutoError(err)
+		return
+	}
+
+	if rawErr := opt.Schema.Validate(); rawErr.ErrCode != 0 {
+		ctx.RespAutoError(rawErr.ToCCError(ctx.Kit.CCError))
+		return
+	}
+
+	if err := s.authorizeModelSchema(ctx, opt); err != nil {
+		return
+	}
+
+	// 创建模型前，先创建表，避免模型创建后，对模型数据查询出现 SnapshotUnavailable 错误
+	for _, model := range opt.Schema.Models {
+		isExist, err := s.Logics.ObjectOperation().IsObjectExist(ctx.Kit, model.ObjectID)
+		if err != nil {
+			ctx.RespAutoError(err)
+			return
+		}
+		if isExist {
+			continue
+		}
+		if err := s.createObjectTable(ctx, mapstr.MapStr{common.BKObjIDField: model.ObjectID}); err != nil {
+			ctx.RespAutoError(err)
+			return
+		}
+	}
+
+	var changes []metadata.ModelSchemaChange
+	txnErr := s.Engine.CoreAPI.CoreService().Txn().AutoRunTxn(ctx.Kit.Ctx, ctx.Kit.Header, func() error {
+		var objects []metadata.Object
+		var err error
+		changes, objects, err = s.Logics.SchemaOperation().ApplyModelSchema(ctx.Kit, &opt.Schema, opt.AllowDelete)
+		if err != nil {
+			blog.Errorf("apply model schema failed, err: %v, rid: %s", err, ctx.Kit.Rid)
+			return err
+		}
+
+		if auth.EnableAuthorize() && len(objects) > 0 {
+			iamInstances := make([]metadata.IamInstanceWithCreator, 0)
+			for _, obj := range objects {
+				iamInstances = append(iamInstances, metadata.IamInstanceWithCreator{
+					Type:    string(iam.SysModel),
+					ID:      strconv.FormatInt(obj.ID, 10),
+					Name:    obj.ObjectName,
+					Creator: ctx.Kit.User,
+				})
+			}
+			if err := s.AuthManager.CreateObjectOnIAM(ctx.Kit.Ctx, ctx.Kit.Header, objects, iamInstances); err != nil {
+				blog.Errorf("create object on iam failed, objects: %v, iam instances: %v, err: %v, rid: %s",
+					objects, iamInstances, err, ctx.Kit.Rid)
+				return err
+			}
+		}
+		return nil
+	})
+
+	if txnErr != nil {
+		ctx.RespAutoError(txnErr)
+		return
+	}
+	ctx.RespEntity(metadata.ModelSchemaResult{Changes: changes})
+}
+
+// authorizeModelSchema authorize the resources that are changed by applying model schema, the response is sent if
+// authorize failed.
+func (s *Service) authorizeModelSchema(ctx *rest.Contexts, opt *metadata.ModelSchemaOption) error {
+	if !s.AuthManager.Enabled() {
+		return nil
+	}
+
+	resources, err := s.Logics.SchemaOperation().GetModelSchemaAuthResources(ctx.Kit, &opt.Schema, opt.AllowDelete)
+	if err != nil {
+		blog.Errorf("get model schema auth resources failed, err: %v, rid: %s", err, ctx.Kit.Rid)
+		ctx.RespAutoError(err)
+		return err
+	}
+
+	err = s.AuthManager.AuthorizeResources(ctx.Kit.Ctx, ctx.Kit.Header, resources...)
+	if err == nil {
+		return nil
+	}
+
+	blog.Errorf("authorize model schema failed, resources: %#v, err: %v, rid: %s", resources, err, ctx.Kit.Rid)
+	if err != ac.NoAuthorizeError {
+		ctx.RespAutoError(err)
+		return err
+	}
+
+	perm, err := s.AuthManager.GenResourcesNoPermissionResp(ctx.Kit.Ctx, ctx.Kit.Header, resources)
+	if err != nil {
+		ctx.RespAutoError(err)
+		return err
+	}
+	ctx.RespEntityWithError(perm, ac.NoAuthorizeError)
+	return ac.NoAuthorizeError
+}
@@ -48,6 +48,12 @@ func (s *Service) initAuditLog(web *restful.WebService) {
 	utility.AddHandler(rest.Action{Verb: http.MethodPost, Path: "/findmany/audit_list", Handler: s.SearchAuditList})
 	utility.AddHandler(rest.Action{Verb: http.MethodPost, Path: "/find/audit", Handler: s.SearchAuditDetail})
 	utility.AddHandler(rest.Action{Verb: http.MethodPost, Path: "/find/inst_audit", Handler: s.SearchInstAudit})
+	utility.AddHandler(rest.Action{Verb: http.MethodPost,
+		Path: "/find/inst/history/object/{bk_obj_id}/inst/{bk_inst_id}", Handler: s.FindInstHistory})
+	utility.AddHandler(rest.Action{Verb: http.MethodPost,
+		Path: "/find/inst/history_diff/object/{bk_obj_id}/inst/{bk_inst_id}", Handler: s.FindInstHistoryDiff})
+	utility.AddHandler(rest.Action{Verb: http.MethodPut,
+		Path: "/update/inst/revert/object/{bk_obj_id}/inst/{bk_inst_id}", Handler: s.RevertInst})
 
 	utility.AddToRestfulWebService(web)
 }
@@ -225,6 +231,10 @@ func (s *Service) initObject(web *restful.WebService) {
 	})
 
 	utility.AddHandler(rest.Action{Verb: http.MethodGet, Path: "/object/statistics", Handler: s.GetModelStatistics})
+	utility.AddHandler(rest.Action{Verb: http.MethodPost, Path: "/find/object/schema/diff",
+		Handler: s.DiffModelSchema})
+	utility.AddHandler(rest.Action{Verb: http.MethodPost, Path: "/update/object/schema/apply",
+		Handler: s.ApplyModelSchema})
 
 	utility.AddToRestfulWebService(web)
 }
@@ -0,0 +1,106 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package dgmember defines the dynamic group member event, which is the membership change of the watched dynamic group
+package dgmember
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"configcenter/src/common"
+	"configcenter/src/common/blog"
+	"configcenter/src/source_controller/cacheservice/event"
+	mixevent "configcenter/src/source_controller/cacheservice/event/mix-event"
+	"configcenter/src/storage/dal"
+	"configcenter/src/storage/dal/mongo/local"
+	"configcenter/src/storage/stream"
+)
+
+const (
+	dynamicGroupMemberLockKey = common.BKCacheKeyV3Prefix + "dynamic_group_member:event_lock"
+	dynamicGroupMemberLockTTL = 1 * time.Minute
+)
+
+// NewDynamicGroupMember init and run dynamic group member event watch
+func NewDynamicGroupMember(watch stream.LoopInterface, watchDB *local.Mongo, ccDB dal.DB) error {
+	base := mixevent.MixEventFlowOptions{
+		MixKey:       event.DynamicGroupMemberKey,
+		Watch:        watch,
+		WatchDB:      watchDB,
+		CcDB:         ccDB,
+		EventLockKey: dynamicGroupMemberLockKey,
+		EventLockTTL: dynamicGroupMemberLockTTL,
+	}
+
+	// all the flows share the same member state, so that the member changes are computed and applied one by one
+	state := new(memberState)
+
+	// watch host event
+	host := base
+	host.Key = event.HostKey
+	if err := newDynamicGroupMember(context.Background(), host, state); err != nil {
+		blog.Errorf("watch host event for dynamic group member failed, err: %v", err)
+		return err
+	}
+	blog.Info("watch dynamic group member events, watch host success")
+
+	// watch host relation event
+	relation := base
+	relation.Key = event.ModuleHostRelationKey
+	relation.WatchFields = []string{common.BKAppIDField, common.BKHostIDField}
+	if err := newDynamicGroupMember(context.Background(), relation, state); err != nil {
+		blog.Errorf("watch host relation event for dynamic group member failed, err: %v", err)
+		return err
+	}
+	blog.Info("watch dynamic group member events, watch host relation success")
+
+	// watch set and module event, their attributes are used in the conditions of the dynamic groups
+	set := base
+	set.Key = event.SetKey
+	set.WatchFields = []string{common.BKAppIDField, common.BKSetIDField}
+	if err := newDynamicGroupMember(context.Background(), set, state); err != nil {
+		blog.Errorf("watch set event for dynamic group member failed, err: %v", err)
+		return err
+	}
+	blog.Info("watch dynamic group member events, watch set success")
+
+	module := base
+	module.Key = event.ModuleKey
+	module.WatchFields = []string{common.BKAppIDField, common.BKModuleIDField}
+	if err := newDynamicGroupMember(context.Background(), module, state); err != nil {
+		blog.Errorf("watch module event for dynamic group member failed, err: %v", err)
+		return err
+	}
+	blog.Info("watch dynamic group member events, watch module success")
+
+	// watch dynamic group event
+	group := base
+	group.Key = event.DynamicGroupKey
+	group.WatchFields = []string{common.BKAppIDField, common.BKFieldID}
+	if err := newDynamicGroupMember(context.Background(), group, state); err != nil {
+		blog.Errorf("watch dynamic group event for dynamic group member failed, err: %v", err)
+		return err
+	}
+	blog.Info("watch dynamic group member events, watch dynamic group success")
+
+	return nil
+}
+
+// memberState is the state of the member changes that are being handled
+type memberState struct {
+	// lock is held from the rearrangement of a batch of events to the end of its handling
+	lock sync.Mutex
+	// changes is the member changes of the batch being handled, they are applied after the events are inserted
+	changes []memberChange
+}
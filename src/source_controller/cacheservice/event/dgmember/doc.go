@@ -0,0 +1,33 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dgmember
+
+/*
+  Dynamic group member event is a mix event of cc_HostBase, cc_ModuleHostConfig, cc_SetBase, cc_ModuleBase and
+  cc_DynamicGroup events. It has these features as follows:
+  1. Only the host dynamic groups with "watch_member" enabled are watched, their members are materialized in the
+    cc_DynamicGroupMember collection, and the membership changes are converted to dynamic group member events.
+  2. Host and host relation events are aggregated to the changed host ids in a batch, then the watched dynamic groups
+    in these hosts' business are evaluated only with these hosts, and compared with the materialized members of these
+    hosts to find out the hosts that enter or leave the groups.
+  3. Dynamic group events trigger a full re-evaluation of the watched dynamic groups in the same business, since the
+    conditions or sub groups may be changed. The members of the deleted or no longer watched groups all leave. Set
+    and module events also trigger the full re-evaluation of their business, since the groups' set/module conditions
+    depend on them. The members are evaluated by the same evaluator as the host server.
+  4. Dynamic group member event has a detail in the form of {"bk_biz_id": 1, "dynamic_group_id": "xxx",
+    "bk_host_id": 1}, the event type is create when the host enters the group, and delete when the host leaves it.
+    The chain node's instance id is the host id, and its sub resource is the dynamic group id, so that one group's
+    events can be watched with the sub resource filter.
+  5. The materialized members are updated after the events are inserted, so the events are delivered at least once.
+  6. Dynamic group member event's auth resource is redirect to host resource, and it's event is authorized by host event.
+*/
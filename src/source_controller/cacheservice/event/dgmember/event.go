@@ -0,0 +1,381 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dgmember
+
+import (
+	"context"
+	"sort"
+	"strconv"
+
+	"configcenter/src/common"
+	"configcenter/src/common/blog"
+	"configcenter/src/common/json"
+	"configcenter/src/common/mapstr"
+	"configcenter/src/common/util"
+	"configcenter/src/common/watch"
+	"configcenter/src/source_controller/cacheservice/event"
+	mixevent "configcenter/src/source_controller/cacheservice/event/mix-event"
+	"configcenter/src/storage/dal"
+	"configcenter/src/storage/stream/types"
+
+	"github.com/tidwall/gjson"
+	"go.mongodb.org/mongo-driver/x/bsonx"
+)
+
+// newDynamicGroupMember init and run dynamic group member event watch with sub event key
+func newDynamicGroupMember(ctx context.Context, opts mixevent.MixEventFlowOptions, state *memberState) error {
+	member := dynamicGroupMember{
+		key:     opts.Key,
+		ccDB:    opts.CcDB,
+		state:   state,
+		metrics: event.InitialMetrics(opts.Key.Collection(), "dynamic_group_member"),
+	}
+
+	flow, err := mixevent.NewMixEventFlow(opts, member.rearrangeEvents, member.parseEvent)
+	if err != nil {
+		return err
+	}
+	flow.SetAfterHandle(member.applyChanges)
+
+	return flow.RunFlow(ctx)
+}
+
+// dynamicGroupMember dynamic group member event watch logic struct
+type dynamicGroupMember struct {
+	key     event.Key
+	ccDB    dal.DB
+	state   *memberState
+	metrics *event.EventMetrics
+}
+
+// rearrangeEvents evaluates the watched dynamic groups related to the events, converts the member changes to events
+func (m *dynamicGroupMember) rearrangeEvents(rid string, es []*types.Event) ([]*types.Event, error) {
+	m.state.lock.Lock()
+	m.state.changes = nil
+
+	var changes []memberChange
+	var err error
+	switch m.key.Collection() {
+	case event.HostKey.Collection():
+		changes, err = m.diffHostMembers(context.Background(), m.getHostIDs(es, rid), rid)
+	case event.ModuleHostRelationKey.Collection():
+		changes, err = m.rearrangeHostRelationEvents(es, rid)
+	case event.DynamicGroupKey.Collection():
+		changes, err = m.rearrangeDynamicGroupEvents(es, rid)
+	case event.SetKey.Collection(), event.ModuleKey.Collection():
+		changes, err = m.rearrangeTopoEvents(es, rid)
+	default:
+		blog.Errorf("received unsupported dynamic group member event, skip, es: %+v, rid: %s", es, rid)
+		return es[:0], nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	m.state.changes = changes
+	return genMemberEvents(es[len(es)-1], changes, rid)
+}
+
+// getHostIDs get the host ids of the host events, deleted hosts are skipped, since their relations are deleted too,
+// and they leave the groups with the host relation events.
+func (m *dynamicGroupMember) getHostIDs(es []*types.Event, rid string) []int64 {
+	hostIDs := make([]int64, 0)
+	reminder := make(map[int64]struct{})
+	for _, one := range es {
+		if one.OperationType == types.Delete {
+			continue
+		}
+
+		hostID := gjson.GetBytes(one.DocBytes, common.BKHostIDField).Int()
+		if hostID <= 0 {
+			blog.Errorf("dynamic group member event, get host id from host: %s failed, skip, rid: %s", one.DocBytes,
+				rid)
+			continue
+		}
+
+		if _, exists := reminder[hostID]; exists {
+			continue
+		}
+		reminder[hostID] = struct{}{}
+		hostIDs = append(hostIDs, hostID)
+	}
+	return hostIDs
+}
+
+// rearrangeHostRelationEvents get the changed host ids of the host relation events, then evaluates these hosts
+func (m *dynamicGroupMember) rearrangeHostRelationEvents(es []*types.Event, rid string) ([]memberChange, error) {
+	hostIDs := make([]int64, 0)
+	deleteOids := make([]string, 0)
+	for _, one := range es {
+		if one.OperationType == types.Delete {
+			deleteOids = append(deleteOids, one.Oid)
+			continue
+		}
+
+		hostID := gjson.GetBytes(one.DocBytes, common.BKHostIDField).Int()
+		if hostID <= 0 {
+			blog.Errorf("dynamic group member event, get host id from relation: %s failed, skip, rid: %s",
+				one.DocBytes, rid)
+			continue
+		}
+		hostIDs = append(hostIDs, hostID)
+	}
+
+	if len(deleteOids) > 0 {
+		filter := map[string]interface{}{
+			"oid":  map[string]interface{}{common.BKDBIN: deleteOids},
+			"coll": common.BKTableNameModuleHostConfig,
+		}
+
+		docs := make([]bsonx.Doc, 0)
+		err := m.ccDB.Table(common.BKTableNameDelArchive).Find(filter).All(context.Background(), &docs)
+		if err != nil {
+			m.metrics.CollectMongoError()
+			blog.Errorf("dynamic group member event, get archive host relation failed, oids: %+v, err: %v, rid: %s",
+				deleteOids, err, rid)
+			return nil, err
+		}
+
+		for _, doc := range docs {
+			hostID, ok := doc.Lookup("detail", common.BKHostIDField).Int64OK()
+			if !ok || hostID <= 0 {
+				blog.Errorf("host id is illegal, skip, relation: %s, rid: %s", doc.Lookup("detail").String(), rid)
+				continue
+			}
+			hostIDs = append(hostIDs, hostID)
+		}
+	}
+
+	return m.diffHostMembers(context.Background(), util.IntArrayUnique(hostIDs), rid)
+}
+
+// rearrangeDynamicGroupEvents evaluates the watched dynamic groups in the business of the changed groups, and cleans
+// the members of the deleted groups
+func (m *dynamicGroupMember) rearrangeDynamicGroupEvents(es []*types.Event, rid string) ([]memberChange, error) {
+	changes, err := m.diffBizGroupMembers(getEventBizIDs(es, rid), rid)
+	if err != nil {
+		return nil, err
+	}
+
+	hasDeleted := false
+	for _, one := range es {
+		if one.OperationType == types.Delete {
+			hasDeleted = true
+			break
+		}
+	}
+
+	if hasDeleted {
+		orphanChanges, err := m.diffOrphanMembers(context.Background(), rid)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, orphanChanges...)
+	}
+
+	return changes, nil
+}
+
+// rearrangeTopoEvents evaluates the watched dynamic groups in the business of the changed sets or modules, since the
+// dynamic groups can use their attributes as conditions. the deleted sets and modules have no hosts, so they are
+// skipped.
+func (m *dynamicGroupMember) rearrangeTopoEvents(es []*types.Event, rid string) ([]memberChange, error) {
+	return m.diffBizGroupMembers(getEventBizIDs(es, rid), rid)
+}
+
+// diffBizGroupMembers fully evaluates the watched dynamic groups in the businesses
+func (m *dynamicGroupMember) diffBizGroupMembers(bizIDs []int64, rid string) ([]memberChange, error) {
+	changes := make([]memberChange, 0)
+	for _, bizID := range bizIDs {
+		bizChanges, err := m.diffGroupMembers(context.Background(), bizID, rid)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, bizChanges...)
+	}
+	return changes, nil
+}
+
+// getEventBizIDs get the distinct business ids of the events that are not deletion
+func getEventBizIDs(es []*types.Event, rid string) []int64 {
+	bizIDs := make([]int64, 0)
+	reminder := make(map[int64]struct{})
+	for _, one := range es {
+		if one.OperationType == types.Delete {
+			continue
+		}
+
+		bizID := gjson.GetBytes(one.DocBytes, common.BKAppIDField).Int()
+		if bizID <= 0 {
+			blog.Errorf("dynamic group member event, get biz id from doc: %s failed, skip, rid: %s", one.DocBytes,
+				rid)
+			continue
+		}
+
+		if _, exists := reminder[bizID]; exists {
+			continue
+		}
+		reminder[bizID] = struct{}{}
+		bizIDs = append(bizIDs, bizID)
+	}
+	return bizIDs
+}
+
+// genMemberEvents converts the member changes to events, these events share the same oid and cluster time with
+// the last event of the batch, and use the group id and host id as the unique key of their cursors.
+func genMemberEvents(last *types.Event, changes []memberChange, rid string) ([]*types.Event, error) {
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].member.GroupID != changes[j].member.GroupID {
+			return changes[i].member.GroupID < changes[j].member.GroupID
+		}
+		return changes[i].member.HostID < changes[j].member.HostID
+	})
+
+	events := make([]*types.Event, 0, len(changes))
+	for _, change := range changes {
+		doc, err := json.Marshal(change.member)
+		if err != nil {
+			blog.Errorf("marshal dynamic group member %+v failed, err: %v, rid: %s", change.member, err, rid)
+			return nil, err
+		}
+
+		one := *last
+		one.Document = nil
+		one.DocBytes = doc
+		one.ChangeDesc = nil
+		one.OperationType = types.Delete
+		if change.enter {
+			one.OperationType = types.Insert
+		}
+		events = append(events, &one)
+	}
+	return events, nil
+}
+
+// parseEvent parse event into chain node and detail, detail is the dynamic group member
+func (m *dynamicGroupMember) parseEvent(e *types.Event, id uint64, rid string) (*watch.ChainNode, []byte, bool,
+	error) {
+
+	if err := event.DynamicGroupMemberKey.Validate(e.DocBytes); err != nil {
+		blog.Errorf("dynamic group member event, received invalid event doc: %s, err: %v, rid: %s", e.DocBytes,
+			err, rid)
+		return nil, nil, false, nil
+	}
+
+	groupID := gjson.GetBytes(e.DocBytes, common.BKDynamicGroupIDField).String()
+	hostID := event.DynamicGroupMemberKey.InstanceID(e.DocBytes)
+
+	cursor := &watch.Cursor{
+		Type:        watch.DynamicGroupMember,
+		ClusterTime: e.ClusterTime,
+		Oid:         e.Oid,
+		Oper:        e.OperationType,
+		UniqKey:     groupID + ":" + strconv.FormatInt(hostID, 10),
+	}
+
+	cursorEncode, err := cursor.Encode()
+	if err != nil {
+		blog.Errorf("encode dynamic group member cursor failed, cursor: %+v, err: %v, rid: %s", cursor, err, rid)
+		return nil, nil, false, err
+	}
+
+	chainNode := &watch.ChainNode{
+		ID:              id,
+		ClusterTime:     e.ClusterTime,
+		Oid:             e.Oid,
+		EventType:       watch.ConvertOperateType(e.OperationType),
+		Token:           e.Token.Data,
+		Cursor:          cursorEncode,
+		InstanceID:      hostID,
+		SubResource:     []string{groupID},
+		SupplierAccount: common.BKDefaultOwnerID,
+	}
+
+	detail := types.EventDetail{
+		Detail: types.JsonString(e.DocBytes),
+	}
+	detailBytes, err := json.Marshal(detail)
+	if err != nil {
+		blog.Errorf("marshal dynamic group member detail failed, detail: %+v, err: %v, rid: %s", detail, err, rid)
+		return nil, nil, false, err
+	}
+
+	return chainNode, detailBytes, false, nil
+}
+
+// applyChanges applies the member changes to the materialized members after the events are inserted, then releases
+// the member state lock
+func (m *dynamicGroupMember) applyChanges(rid string, succeed bool) {
+	defer func() {
+		m.state.changes = nil
+		m.state.lock.Unlock()
+	}()
+
+	if !succeed || len(m.state.changes) == 0 {
+		return
+	}
+
+	ctx := context.Background()
+	for _, change := range m.state.changes {
+		filter := mapstr.MapStr{
+			common.BKDynamicGroupIDField: change.member.GroupID,
+			common.BKHostIDField:         change.member.HostID,
+		}
+
+		var err error
+		if change.enter {
+			err = m.ccDB.Table(common.BKTableNameDynamicGroupMember).Upsert(ctx, filter, change.member)
+		} else {
+			err = m.ccDB.Table(common.BKTableNameDynamicGroupMember).Delete(ctx, filter)
+		}
+
+		// the events are already inserted, the failed change will be generated again next time
+		if err != nil {
+			m.metrics.CollectMongoError()
+			blog.Errorf("apply dynamic group member change %+v failed, err: %v, rid: %s", change, err, rid)
+		}
+	}
+}
+
+// diffOrphanMembers returns the changes that the members of the deleted or no longer watched groups leave
+func (m *dynamicGroupMember) diffOrphanMembers(ctx context.Context, rid string) ([]memberChange, error) {
+	groupIDs, err := m.ccDB.Table(common.BKTableNameDynamicGroupMember).Distinct(ctx, common.BKDynamicGroupIDField,
+		mapstr.MapStr{})
+	if err != nil {
+		m.metrics.CollectMongoError()
+		blog.Errorf("get dynamic group member group ids failed, err: %v, rid: %s", err, rid)
+		return nil, err
+	}
+
+	if len(groupIDs) == 0 {
+		return make([]memberChange, 0), nil
+	}
+
+	groups, err := m.getWatchedGroups(ctx, mapstr.MapStr{common.BKFieldID: mapstr.MapStr{common.BKDBIN: groupIDs}},
+		rid)
+	if err != nil {
+		return nil, err
+	}
+
+	watchedIDs := make([]string, 0, len(groups))
+	for _, group := range groups {
+		watchedIDs = append(watchedIDs, group.ID)
+	}
+
+	memberCond := mapstr.MapStr{common.BKDynamicGroupIDField: mapstr.MapStr{common.BKDBNIN: watchedIDs}}
+	members, err := m.getMembers(ctx, memberCond, rid)
+	if err != nil {
+		return nil, err
+	}
+	return leaveMembers(members), nil
+}
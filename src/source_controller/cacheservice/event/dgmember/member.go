@@ -0,0 +1,324 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dgmember
+
+import (
+	"context"
+	"errors"
+
+	"configcenter/src/common"
+	"configcenter/src/common/blog"
+	"configcenter/src/common/dynamicgroup"
+	"configcenter/src/common/mapstr"
+	"configcenter/src/common/metadata"
+	"configcenter/src/common/util"
+)
+
+// memberChange is a membership change of a watched dynamic group
+type memberChange struct {
+	member metadata.DynamicGroupMember
+	// enter is whether the host enters the group, otherwise it leaves the group
+	enter bool
+}
+
+// diffHostMembers evaluates the watched dynamic groups with the changed hosts, returns their membership changes
+func (m *dynamicGroupMember) diffHostMembers(ctx context.Context, hostIDs []int64, rid string) ([]memberChange,
+	error) {
+
+	if len(hostIDs) == 0 {
+		return make([]memberChange, 0), nil
+	}
+
+	// get the changed hosts' business, hosts that are deleted or transferred out have no relations in the business
+	relations := make([]metadata.ModuleHost, 0)
+	relationCond := mapstr.MapStr{common.BKHostIDField: mapstr.MapStr{common.BKDBIN: hostIDs}}
+	err := m.ccDB.Table(common.BKTableNameModuleHostConfig).Find(relationCond).
+		Fields(common.BKAppIDField, common.BKHostIDField).All(ctx, &relations)
+	if err != nil {
+		m.metrics.CollectMongoError()
+		blog.Errorf("get host relations failed, host ids: %v, err: %v, rid: %s", hostIDs, err, rid)
+		return nil, err
+	}
+
+	bizHostMap := make(map[int64][]int64)
+	bizHostExists := make(map[int64]map[int64]struct{})
+	for _, relation := range relations {
+		if _, exists := bizHostExists[relation.AppID]; !exists {
+			bizHostExists[relation.AppID] = make(map[int64]struct{})
+		}
+		if _, exists := bizHostExists[relation.AppID][relation.HostID]; exists {
+			continue
+		}
+		bizHostExists[relation.AppID][relation.HostID] = struct{}{}
+		bizHostMap[relation.AppID] = append(bizHostMap[relation.AppID], relation.HostID)
+	}
+
+	// get the current members of the changed hosts
+	memberCond := mapstr.MapStr{common.BKHostIDField: mapstr.MapStr{common.BKDBIN: hostIDs}}
+	currentMembers, err := m.getMembers(ctx, memberCond, rid)
+	if err != nil {
+		return nil, err
+	}
+
+	bizIDs := make([]int64, 0, len(bizHostMap))
+	for bizID := range bizHostMap {
+		bizIDs = append(bizIDs, bizID)
+	}
+
+	groupIDs := make([]string, 0, len(currentMembers))
+	for groupID := range currentMembers {
+		groupIDs = append(groupIDs, groupID)
+	}
+
+	groupCond := mapstr.MapStr{
+		common.BKDBOR: []mapstr.MapStr{
+			{common.BKAppIDField: mapstr.MapStr{common.BKDBIN: bizIDs}},
+			{common.BKFieldID: mapstr.MapStr{common.BKDBIN: groupIDs}},
+		},
+	}
+	groups, err := m.getWatchedGroups(ctx, groupCond, rid)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make([]memberChange, 0)
+	for idx := range groups {
+		group := &groups[idx]
+
+		matched := make([]int64, 0)
+		if candidates := bizHostMap[group.AppID]; len(candidates) > 0 {
+			matched, err = m.matchHosts(ctx, group, candidates, rid)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		changes = append(changes, diffMembers(group, matched, currentMembers[group.ID])...)
+		delete(currentMembers, group.ID)
+	}
+
+	// the remaining members belong to the groups that are deleted or no longer watched
+	changes = append(changes, leaveMembers(currentMembers)...)
+	return changes, nil
+}
+
+// diffGroupMembers fully evaluates the watched dynamic groups in the business, and also cleans the members of the
+// groups that are deleted or no longer watched, returns their membership changes
+func (m *dynamicGroupMember) diffGroupMembers(ctx context.Context, bizID int64, rid string) ([]memberChange,
+	error) {
+
+	groupCond := mapstr.MapStr{}
+	if bizID > 0 {
+		groupCond[common.BKAppIDField] = bizID
+	}
+	groups, err := m.getWatchedGroups(ctx, groupCond, rid)
+	if err != nil {
+		return nil, err
+	}
+
+	memberCond := mapstr.MapStr{}
+	if bizID > 0 {
+		memberCond[common.BKAppIDField] = bizID
+	}
+	currentMembers, err := m.getMembers(ctx, memberCond, rid)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make([]memberChange, 0)
+	for idx := range groups {
+		group := &groups[idx]
+
+		matched, err := m.matchHosts(ctx, group, nil, rid)
+		if err != nil {
+			return nil, err
+		}
+
+		changes = append(changes, diffMembers(group, matched, currentMembers[group.ID])...)
+		delete(currentMembers, group.ID)
+	}
+
+	changes = append(changes, leaveMembers(currentMembers)...)
+	return changes, nil
+}
+
+// diffMembers compares the matched hosts with the current members of the group, current is the members of the
+// evaluated hosts, which is cleared after the comparison.
+func diffMembers(group *metadata.DynamicGroup, matched []int64,
+	current map[int64]metadata.DynamicGroupMember) []memberChange {
+
+	changes := make([]memberChange, 0)
+	for _, hostID := range matched {
+		if _, exists := current[hostID]; exists {
+			delete(current, hostID)
+			continue
+		}
+
+		changes = append(changes, memberChange{
+			member: metadata.DynamicGroupMember{BizID: group.AppID, GroupID: group.ID, HostID: hostID},
+			enter:  true,
+		})
+	}
+
+	for _, member := range current {
+		changes = append(changes, memberChange{member: member, enter: false})
+	}
+	return changes
+}
+
+// leaveMembers returns the changes that all the members leave their groups
+func leaveMembers(members map[string]map[int64]metadata.DynamicGroupMember) []memberChange {
+	changes := make([]memberChange, 0)
+	for _, groupMembers := range members {
+		for _, member := range groupMembers {
+			changes = append(changes, memberChange{member: member, enter: false})
+		}
+	}
+	return changes
+}
+
+// getWatchedGroups get the watched host dynamic groups by condition
+func (m *dynamicGroupMember) getWatchedGroups(ctx context.Context, cond mapstr.MapStr, rid string) (
+	[]metadata.DynamicGroup, error) {
+
+	cond[common.BKObjIDField] = common.BKInnerObjIDHost
+	cond[common.BKWatchMemberField] = true
+
+	groups := make([]metadata.DynamicGroup, 0)
+	if err := m.ccDB.Table(common.BKTableNameDynamicGroup).Find(cond).All(ctx, &groups); err != nil {
+		m.metrics.CollectMongoError()
+		blog.Errorf("get watched dynamic groups failed, cond: %+v, err: %v, rid: %s", cond, err, rid)
+		return nil, err
+	}
+	return groups, nil
+}
+
+// getMembers get the materialized dynamic group members by condition, returns the group id to host id to member map
+func (m *dynamicGroupMember) getMembers(ctx context.Context, cond mapstr.MapStr, rid string) (
+	map[string]map[int64]metadata.DynamicGroupMember, error) {
+
+	members := make([]metadata.DynamicGroupMember, 0)
+	if err := m.ccDB.Table(common.BKTableNameDynamicGroupMember).Find(cond).All(ctx, &members); err != nil {
+		m.metrics.CollectMongoError()
+		blog.Errorf("get dynamic group members failed, cond: %+v, err: %v, rid: %s", cond, err, rid)
+		return nil, err
+	}
+
+	memberMap := make(map[string]map[int64]metadata.DynamicGroupMember)
+	for _, member := range members {
+		if _, exists := memberMap[member.GroupID]; !exists {
+			memberMap[member.GroupID] = make(map[int64]metadata.DynamicGroupMember)
+		}
+		memberMap[member.GroupID][member.HostID] = member
+	}
+	return memberMap, nil
+}
+
+// matchHosts returns the hosts that are members of the dynamic group, only the hosts in hostIDs are evaluated, or all
+// the hosts in the group's business if hostIDs is nil. the groups with invalid sub groups have no members.
+func (m *dynamicGroupMember) matchHosts(ctx context.Context, group *metadata.DynamicGroup, hostIDs []int64,
+	rid string) ([]int64, error) {
+
+	evaluator := dynamicgroup.NewEvaluator(&memberGetter{member: m, rid: rid})
+	matched, err := evaluator.MatchHosts(ctx, group, hostIDs)
+	if err != nil {
+		if errors.Is(err, dynamicgroup.ErrInvalidSubGroup) {
+			blog.Errorf("dynamic group %s has invalid sub group, err: %v, rid: %s", group.ID, err, rid)
+			return make([]int64, 0), nil
+		}
+		blog.Errorf("match dynamic group %s hosts failed, err: %v, rid: %s", group.ID, err, rid)
+		return nil, err
+	}
+	return matched, nil
+}
+
+// memberGetter gets the data to evaluate the dynamic group from db
+type memberGetter struct {
+	member *dynamicGroupMember
+	rid    string
+}
+
+// GetGroup returns the dynamic group in the business, returns nil if it does not exist
+func (g *memberGetter) GetGroup(ctx context.Context, bizID int64, id string) (*metadata.DynamicGroup, error) {
+	group := new(metadata.DynamicGroup)
+	cond := mapstr.MapStr{common.BKAppIDField: bizID, common.BKFieldID: id}
+	if err := g.member.ccDB.Table(common.BKTableNameDynamicGroup).Find(cond).One(ctx, group); err != nil {
+		if g.member.ccDB.IsNotFoundError(err) {
+			return nil, nil
+		}
+		g.member.metrics.CollectMongoError()
+		blog.Errorf("get dynamic group %s failed, biz: %d, err: %v, rid: %s", id, bizID, err, g.rid)
+		return nil, err
+	}
+	return group, nil
+}
+
+// GetInstIDs returns the ids of the set or module instances in the business that match the condition
+func (g *memberGetter) GetInstIDs(ctx context.Context, bizID int64, objID string, cond map[string]interface{}) (
+	[]int64, error) {
+
+	instCond := map[string]interface{}{
+		common.BKDBAND: []map[string]interface{}{{common.BKAppIDField: bizID}, cond},
+	}
+	return g.distinctIDs(ctx, common.GetInstTableName(objID, common.BKDefaultOwnerID), common.GetInstIDField(objID),
+		instCond)
+}
+
+// GetRelationHostIDs returns the distinct ids of the hosts that have relations matching the option
+func (g *memberGetter) GetRelationHostIDs(ctx context.Context, opt *metadata.DistinctHostIDByTopoRelationRequest) (
+	[]int64, error) {
+
+	cond := make(map[string]interface{})
+	if len(opt.ApplicationIDArr) > 0 {
+		cond[common.BKAppIDField] = map[string]interface{}{common.BKDBIN: opt.ApplicationIDArr}
+	}
+	if len(opt.SetIDArr) > 0 {
+		cond[common.BKSetIDField] = map[string]interface{}{common.BKDBIN: opt.SetIDArr}
+	}
+	if len(opt.ModuleIDArr) > 0 {
+		cond[common.BKModuleIDField] = map[string]interface{}{common.BKDBIN: opt.ModuleIDArr}
+	}
+	if len(opt.HostIDArr) > 0 {
+		cond[common.BKHostIDField] = map[string]interface{}{common.BKDBIN: opt.HostIDArr}
+	}
+	return g.distinctIDs(ctx, common.BKTableNameModuleHostConfig, common.BKHostIDField, cond)
+}
+
+// GetHostIDs returns the ids of the hosts in hostIDs that match the condition
+func (g *memberGetter) GetHostIDs(ctx context.Context, hostIDs []int64, cond map[string]interface{}) ([]int64,
+	error) {
+
+	hostCond := map[string]interface{}{common.BKHostIDField: map[string]interface{}{common.BKDBIN: hostIDs}}
+	if cond != nil {
+		hostCond = map[string]interface{}{common.BKDBAND: []map[string]interface{}{hostCond, cond}}
+	}
+	return g.distinctIDs(ctx, common.BKTableNameBaseHost, common.BKHostIDField, hostCond)
+}
+
+func (g *memberGetter) distinctIDs(ctx context.Context, table, field string, cond map[string]interface{}) ([]int64,
+	error) {
+
+	rawIDs, err := g.member.ccDB.Table(table).Distinct(ctx, field, cond)
+	if err != nil {
+		g.member.metrics.CollectMongoError()
+		blog.Errorf("get distinct %s from %s failed, cond: %+v, err: %v, rid: %s", field, table, cond, err, g.rid)
+		return nil, err
+	}
+
+	ids, err := util.SliceInterfaceToInt64(rawIDs)
+	if err != nil {
+		blog.Errorf("parse %s of %s failed, ids: %v, err: %v, rid: %s", field, table, rawIDs, err, g.rid)
+		return nil, err
+	}
+	return ids, nil
+}
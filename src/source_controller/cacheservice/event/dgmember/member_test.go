@@ -0,0 +1,72 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dgmember
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"configcenter/src/common/metadata"
+	"configcenter/src/storage/stream/types"
+)
+
+func TestDiffMembers(t *testing.T) {
+	group := &metadata.DynamicGroup{AppID: 1, ID: "g1"}
+	current := map[int64]metadata.DynamicGroupMember{
+		1: {BizID: 1, GroupID: "g1", HostID: 1},
+		2: {BizID: 1, GroupID: "g1", HostID: 2},
+	}
+
+	changes := diffMembers(group, []int64{2, 3}, current)
+	sort.Slice(changes, func(i, j int) bool { return changes[i].member.HostID < changes[j].member.HostID })
+
+	want := []memberChange{
+		{member: metadata.DynamicGroupMember{BizID: 1, GroupID: "g1", HostID: 1}, enter: false},
+		{member: metadata.DynamicGroupMember{BizID: 1, GroupID: "g1", HostID: 3}, enter: true},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("diffMembers() = %+v, want %+v", changes, want)
+	}
+}
+
+func TestLeaveMembers(t *testing.T) {
+	members := map[string]map[int64]metadata.DynamicGroupMember{
+		"g1": {1: {BizID: 1, GroupID: "g1", HostID: 1}},
+		"g2": {1: {BizID: 1, GroupID: "g2", HostID: 1}, 2: {BizID: 1, GroupID: "g2", HostID: 2}},
+	}
+
+	changes := leaveMembers(members)
+	if len(changes) != 3 {
+		t.Fatalf("leaveMembers() returns %d changes, want 3", len(changes))
+	}
+	for _, change := range changes {
+		if change.enter {
+			t.Errorf("leaveMembers() returns enter change %+v", change)
+		}
+	}
+}
+
+func TestGetEventBizIDs(t *testing.T) {
+	es := []*types.Event{
+		{OperationType: types.Insert, DocBytes: []byte(`{"bk_biz_id":2,"bk_set_id":1}`)},
+		{OperationType: types.Update, DocBytes: []byte(`{"bk_biz_id":1,"bk_set_id":2}`)},
+		{OperationType: types.Update, DocBytes: []byte(`{"bk_biz_id":2,"bk_set_id":3}`)},
+		{OperationType: types.Delete, DocBytes: []byte(`{"bk_biz_id":3,"bk_set_id":4}`)},
+		{OperationType: types.Update, DocBytes: []byte(`{"bk_set_id":5}`)},
+	}
+
+	if got := getEventBizIDs(es, "test"); !reflect.DeepEqual(got, []int64{2, 1}) {
+		t.Errorf("getEventBizIDs() = %v, want [2 1]", got)
+	}
+}
@@ -327,6 +327,45 @@ func GenBizSetRelationDetail(bizSetID int64, bizIDsStr string) string {
 
 var platFields = []string{common.BKCloudIDField, common.BKCloudNameField}
 
+// dynamicGroupMemberWatchCollName a virtual collection name for host, host relation & dynamic group events in the
+// form of the membership change events of the watched dynamic groups
+const dynamicGroupMemberWatchCollName = "cc_DynamicGroupMemberMixed"
+
+var dynamicGroupMemberFields = []string{common.BKDynamicGroupIDField, common.BKHostIDField}
+
+// DynamicGroupMemberKey dynamic group member event watch key
+var DynamicGroupMemberKey = Key{
+	namespace:  watchCacheNamespace + "dynamic_group_member",
+	collection: dynamicGroupMemberWatchCollName,
+	ttlSeconds: 6 * 60 * 60,
+	validator: func(doc []byte) error {
+		fields := gjson.GetManyBytes(doc, dynamicGroupMemberFields...)
+		for idx := range dynamicGroupMemberFields {
+			if !fields[idx].Exists() {
+				return fmt.Errorf("field %s not exist", dynamicGroupMemberFields[idx])
+			}
+		}
+		return nil
+	},
+	instName: func(doc []byte) string {
+		fields := gjson.GetManyBytes(doc, dynamicGroupMemberFields...)
+		return fmt.Sprintf("dynamic group id: %s, host id: %s", fields[0].String(), fields[1].String())
+	},
+	instID: func(doc []byte) int64 {
+		return gjson.GetBytes(doc, common.BKHostIDField).Int()
+	},
+}
+
+// DynamicGroupKey dynamic group watch key, it is only used to generate the dynamic group member events
+var DynamicGroupKey = Key{
+	namespace:  watchCacheNamespace + "dynamic_group",
+	collection: common.BKTableNameDynamicGroup,
+	ttlSeconds: 6 * 60 * 60,
+	instName: func(doc []byte) string {
+		return gjson.GetBytes(doc, common.BKFieldName).String()
+	},
+}
+
 // PlatKey cloud area event watch key
 var PlatKey = Key{
 	namespace:  watchCacheNamespace + common.BKInnerObjIDPlat,
@@ -55,6 +55,7 @@ type MixEventFlow struct {
 	tokenHandler    *mixEventHandler
 	rearrangeEvents rearrangeEventsFunc
 	parseEvent      parseEventFunc
+	afterHandle     afterHandleFunc
 }
 
 // rearrangeEventsFunc function type for rearranging mix events
@@ -63,6 +64,10 @@ type rearrangeEventsFunc func(rid string, es []*types.Event) ([]*types.Event, er
 // parseEventFunc function type for parsing mix event into chain node and detail
 type parseEventFunc func(e *types.Event, id uint64, rid string) (*watch.ChainNode, []byte, bool, error)
 
+// afterHandleFunc function type called after a batch of mix events is handled, succeed is whether the rearranged
+// events are all successfully inserted, it is called even if the rearrangement failed.
+type afterHandleFunc func(rid string, succeed bool)
+
 // NewMixEventFlow create a new mix event watch flow
 func NewMixEventFlow(opts MixEventFlowOptions, rearrangeEvents rearrangeEventsFunc, parseEvent parseEventFunc) (
 	MixEventFlow, error) {
@@ -85,6 +90,12 @@ func NewMixEventFlow(opts MixEventFlowOptions, rearrangeEvents rearrangeEventsFu
 	}, nil
 }
 
+// SetAfterHandle set the function that is called after every batch of mix events is handled, it's used by those mix
+// events that need to keep their own states consistent with the inserted events.
+func (f *MixEventFlow) SetAfterHandle(afterHandle afterHandleFunc) {
+	f.afterHandle = afterHandle
+}
+
 const batchSize = 500
 
 // RunFlow run mix event flow
@@ -158,6 +169,12 @@ func (f *MixEventFlow) doBatch(es []*types.Event) (retry bool) {
 		f.metrics.CollectCycleDuration(time.Since(start))
 	}()
 
+	if f.afterHandle != nil {
+		defer func() {
+			f.afterHandle(rid, !hasError)
+		}()
+	}
+
 	// rearranging mix events
 	events, err := f.rearrangeEvents(rid, es)
 	if err != nil {
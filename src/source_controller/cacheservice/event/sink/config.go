@@ -0,0 +1,111 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sink
+
+import (
+	"errors"
+	"strings"
+
+	cc "configcenter/src/common/backbone/configcenter"
+	"configcenter/src/common/blog"
+	"configcenter/src/common/watch"
+	"configcenter/src/storage/dal/kafka"
+)
+
+const defaultTopicPrefix = "bk_cmdb_event_"
+
+// Config kafka event sink config
+type Config struct {
+	// Enabled defines whether to publish the events to kafka
+	Enabled bool
+	// Kafka is the kafka connection config
+	Kafka kafka.Config
+	// TopicPrefix is the prefix of the topics, the topic of a resource is {TopicPrefix}{resource}
+	TopicPrefix string
+	// Resources is the resources whose events are published, default is all resources
+	Resources []watch.CursorType
+}
+
+// ParseConfig parse kafka event sink config from kafka.eventSink
+func ParseConfig() (*Config, error) {
+	conf := &Config{}
+
+	if !cc.IsExist("kafka.eventSink.enabled") {
+		return conf, nil
+	}
+
+	var err error
+	conf.Enabled, err = cc.Bool("kafka.eventSink.enabled")
+	if err != nil {
+		blog.Errorf("get kafka.eventSink.enabled failed, err: %v", err)
+		return nil, err
+	}
+
+	if !conf.Enabled {
+		return conf, nil
+	}
+
+	conf.Kafka, err = cc.Kafka("kafka.eventSink")
+	if err != nil {
+		blog.Errorf("get kafka.eventSink config failed, err: %v", err)
+		return nil, err
+	}
+
+	if len(conf.Kafka.Brokers) == 0 {
+		return nil, errors.New("kafka.eventSink.brokers is not set")
+	}
+
+	conf.TopicPrefix = defaultTopicPrefix
+	if cc.IsExist("kafka.eventSink.topicPrefix") {
+		conf.TopicPrefix, err = cc.String("kafka.eventSink.topicPrefix")
+		if err != nil {
+			blog.Errorf("get kafka.eventSink.topicPrefix failed, err: %v", err)
+			return nil, err
+		}
+	}
+
+	conf.Resources = watch.ListCursorTypes()
+	if cc.IsExist("kafka.eventSink.resources") {
+		resources, err := cc.StringSlice("kafka.eventSink.resources")
+		if err != nil {
+			blog.Errorf("get kafka.eventSink.resources failed, err: %v", err)
+			return nil, err
+		}
+
+		if len(resources) > 0 {
+			conf.Resources, err = parseResources(resources)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return conf, nil
+}
+
+func parseResources(resources []string) ([]watch.CursorType, error) {
+	supported := make(map[watch.CursorType]struct{})
+	for _, typ := range watch.ListCursorTypes() {
+		supported[typ] = struct{}{}
+	}
+
+	result := make([]watch.CursorType, 0, len(resources))
+	for _, resource := range resources {
+		typ := watch.CursorType(strings.TrimSpace(resource))
+		if _, exists := supported[typ]; !exists {
+			return nil, errors.New("kafka.eventSink.resources has unsupported resource " + resource)
+		}
+		result = append(result, typ)
+	}
+	return result, nil
+}
@@ -0,0 +1,277 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package sink mirrors the resource watch events to kafka, so that the events can be consumed from kafka topics
+// instead of the cursor based watch api.
+package sink
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"configcenter/src/apimachinery/discovery"
+	"configcenter/src/common"
+	"configcenter/src/common/blog"
+	"configcenter/src/common/errors"
+	"configcenter/src/common/http/rest"
+	"configcenter/src/common/metadata"
+	"configcenter/src/common/util"
+	"configcenter/src/common/watch"
+	"configcenter/src/source_controller/cacheservice/event"
+	watchcli "configcenter/src/source_controller/cacheservice/event/watch"
+	"configcenter/src/storage/dal"
+	"configcenter/src/storage/dal/kafka"
+
+	"github.com/Shopify/sarama"
+)
+
+const (
+	// cursorKeyPrefix is the prefix of the id of the published cursor in the watch token table
+	cursorKeyPrefix = "kafka_event_sink:"
+	// retryInterval is the interval to retry when an error occurs
+	retryInterval = 3 * time.Second
+	// notMasterInterval is the interval to check if the current node becomes master
+	notMasterInterval = 10 * time.Second
+)
+
+// publishedCursor is the cursor of the last event that has been published to kafka
+type publishedCursor struct {
+	ID       string        `bson:"_id"`
+	Cursor   string        `bson:"cursor"`
+	LastTime metadata.Time `bson:"last_time"`
+}
+
+// Sink publishes the events of the resources to the kafka topic of each resource, the cursor of the event
+// is used as the message key. the cursor of the last published event is saved after the events are published,
+// so that it can resume publishing from the cursor after restart or master switch.
+type Sink struct {
+	conf     *Config
+	producer sarama.SyncProducer
+	watchCli *watchcli.Client
+	watchDB  dal.DB
+	isMaster discovery.ServiceManageInterface
+	ccErr    errors.CCErrorIf
+}
+
+// NewSink create a kafka event sink and start to publish the events, it does nothing if the sink is not enabled
+func NewSink(conf *Config, watchCli *watchcli.Client, watchDB dal.DB, isMaster discovery.ServiceManageInterface,
+	ccErr errors.CCErrorIf) error {
+
+	if !conf.Enabled {
+		blog.Infof("kafka event sink is not enabled, skip")
+		return nil
+	}
+
+	producer, err := kafka.NewSyncProducer(conf.Kafka)
+	if err != nil {
+		blog.Errorf("new kafka event sink producer failed, err: %v", err)
+		return err
+	}
+
+	s := &Sink{
+		conf:     conf,
+		producer: producer,
+		watchCli: watchCli,
+		watchDB:  watchDB,
+		isMaster: isMaster,
+		ccErr:    ccErr,
+	}
+
+	for _, resource := range conf.Resources {
+		key, err := event.GetResourceKeyWithCursorType(resource)
+		if err != nil {
+			blog.Errorf("get resource %s key failed, err: %v", resource, err)
+			return err
+		}
+
+		go s.loopPublish(resource, key)
+	}
+
+	blog.Infof("start kafka event sink for resources %v success", conf.Resources)
+	return nil
+}
+
+// loopPublish watches the events of the resource and publishes them to kafka on the master node
+func (s *Sink) loopPublish(resource watch.CursorType, key event.Key) {
+	topic := s.conf.TopicPrefix + string(resource)
+	cursor := ""
+	loaded := false
+
+	for {
+		if !s.isMaster.IsMaster() {
+			// load the published cursor again when it becomes master, it may be changed by the previous master
+			loaded = false
+			blog.V(4).Infof("loop publish %s events to kafka, but not master, skip.", resource)
+			time.Sleep(notMasterInterval)
+			continue
+		}
+
+		kit := s.newKit()
+
+		if !loaded {
+			var err error
+			cursor, err = s.getPublishedCursor(kit, resource)
+			if err != nil {
+				blog.Errorf("get %s published cursor failed, err: %v, rid: %s", resource, err, kit.Rid)
+				time.Sleep(retryInterval)
+				continue
+			}
+			loaded = true
+		}
+
+		nextCursor, err := s.publish(kit, resource, key, topic, cursor)
+		if err != nil {
+			if ccErr, ok := err.(errors.CCErrorCoder); ok && ccErr.GetCode() == common.CCErrEventChainNodeNotExist {
+				// the published cursor is expired, the events between the cursor and now are lost
+				blog.Errorf("%s published cursor %s is not exist, publish from now, rid: %s", resource, cursor,
+					kit.Rid)
+				cursor = ""
+				continue
+			}
+
+			blog.Errorf("publish %s events to kafka topic %s failed, cursor: %s, err: %v, rid: %s", resource, topic,
+				cursor, err, kit.Rid)
+			time.Sleep(retryInterval)
+			continue
+		}
+
+		if nextCursor == cursor {
+			continue
+		}
+
+		if err := s.setPublishedCursor(kit, resource, nextCursor); err != nil {
+			blog.Errorf("set %s published cursor %s failed, err: %v, rid: %s", resource, nextCursor, err, kit.Rid)
+			// the events may be published again after restart, but they can be deduplicated by the message key
+		}
+		cursor = nextCursor
+	}
+}
+
+// publish watches the events after the cursor and publishes them to kafka, returns the cursor to watch next time
+func (s *Sink) publish(kit *rest.Kit, resource watch.CursorType, key event.Key, topic, cursor string) (string,
+	error) {
+
+	opts := &watch.WatchEventOptions{
+		Cursor:   cursor,
+		Resource: resource,
+	}
+
+	if len(cursor) == 0 {
+		// no published cursor, publish the events from now on
+		latest, err := s.watchCli.WatchFromNow(kit, key, opts)
+		if err != nil {
+			return "", err
+		}
+		return latest.Cursor, nil
+	}
+
+	events, err := s.watchCli.WatchWithCursor(kit, key, opts)
+	if err != nil {
+		return "", err
+	}
+
+	if len(events) == 0 {
+		return cursor, nil
+	}
+
+	messages, nextCursor, err := genMessages(topic, resource, cursor, events)
+	if err != nil {
+		blog.Errorf("generate %s event messages failed, err: %v, rid: %s", resource, err, kit.Rid)
+		return "", err
+	}
+
+	if len(messages) == 0 {
+		return nextCursor, nil
+	}
+
+	if err := s.producer.SendMessages(messages); err != nil {
+		return "", err
+	}
+
+	blog.V(4).Infof("published %d %s events to kafka topic %s, last cursor: %s, rid: %s", len(messages), resource,
+		topic, nextCursor, kit.Rid)
+	return nextCursor, nil
+}
+
+// genMessages generates the kafka messages of the events, the events that only contain the cursor are skipped.
+// returns the messages and the cursor to watch next time.
+func genMessages(topic string, resource watch.CursorType, cursor string, events []*watch.WatchEventDetail) (
+	[]*sarama.ProducerMessage, string, error) {
+
+	messages := make([]*sarama.ProducerMessage, 0, len(events))
+	for _, e := range events {
+		if e.Cursor == watch.NoEventCursor || e.Detail == nil {
+			// no event is hit, only the cursor is returned
+			continue
+		}
+
+		value, err := json.Marshal(e)
+		if err != nil {
+			return nil, "", fmt.Errorf("marshal event %s failed, err: %v", e.Cursor, err)
+		}
+
+		messages = append(messages, &sarama.ProducerMessage{
+			Topic: topic,
+			Key:   sarama.StringEncoder(e.Cursor),
+			Value: sarama.ByteEncoder(value),
+			Headers: []sarama.RecordHeader{
+				{Key: []byte("bk_resource"), Value: []byte(resource)},
+				{Key: []byte("bk_event_type"), Value: []byte(e.EventType)},
+			},
+		})
+	}
+
+	nextCursor := cursor
+	if len(events) > 0 && events[len(events)-1].Cursor != watch.NoEventCursor {
+		nextCursor = events[len(events)-1].Cursor
+	}
+
+	return messages, nextCursor, nil
+}
+
+func (s *Sink) getPublishedCursor(kit *rest.Kit, resource watch.CursorType) (string, error) {
+	filter := map[string]interface{}{
+		"_id": cursorKeyPrefix + string(resource),
+	}
+
+	data := new(publishedCursor)
+	if err := s.watchDB.Table(common.BKTableNameWatchToken).Find(filter).One(kit.Ctx, data); err != nil {
+		if s.watchDB.IsNotFoundError(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	return data.Cursor, nil
+}
+
+func (s *Sink) setPublishedCursor(kit *rest.Kit, resource watch.CursorType, cursor string) error {
+	filter := map[string]interface{}{
+		"_id": cursorKeyPrefix + string(resource),
+	}
+
+	data := map[string]interface{}{
+		common.BKCursorField: cursor,
+		common.LastTimeField: metadata.Now(),
+	}
+	return s.watchDB.Table(common.BKTableNameWatchToken).Upsert(kit.Ctx, filter, data)
+}
+
+func (s *Sink) newKit() *rest.Kit {
+	header := http.Header{}
+	header.Add(common.BKHTTPOwnerID, common.BKDefaultOwnerID)
+	header.Add(common.BKHTTPHeaderUser, common.CCSystemOperatorUserName)
+	header.Add(common.BKHTTPCCRequestID, util.GenerateRID())
+
+	return rest.NewKitFromHeader(header, s.ccErr)
+}
@@ -0,0 +1,98 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sink
+
+import (
+	"encoding/json"
+	"testing"
+
+	"configcenter/src/common/watch"
+)
+
+func TestGenMessages(t *testing.T) {
+	events := []*watch.WatchEventDetail{
+		{Cursor: "c1", Resource: watch.Host, EventType: watch.Create, Detail: watch.JsonString(`{"bk_host_id":1}`)},
+		{Cursor: "c2", Resource: watch.Host, EventType: watch.Update, Detail: nil},
+		{Cursor: "c3", Resource: watch.Host, EventType: watch.Delete, Detail: watch.JsonString(`{"bk_host_id":2}`)},
+	}
+
+	messages, next, err := genMessages("topic_host", watch.Host, "c0", events)
+	if err != nil {
+		t.Fatalf("generate messages failed, err: %v", err)
+	}
+
+	if next != "c3" {
+		t.Errorf("next cursor should be c3, but got %s", next)
+	}
+
+	if len(messages) != 2 {
+		t.Fatalf("events without detail should be skipped, expect 2 messages, but got %d", len(messages))
+	}
+
+	for idx, cursor := range []string{"c1", "c3"} {
+		msg := messages[idx]
+		if msg.Topic != "topic_host" {
+			t.Errorf("message %d topic should be topic_host, but got %s", idx, msg.Topic)
+		}
+
+		key, _ := msg.Key.Encode()
+		if string(key) != cursor {
+			t.Errorf("message %d key should be the cursor %s, but got %s", idx, cursor, key)
+		}
+
+		value, _ := msg.Value.Encode()
+		detail := new(watch.WatchEventDetail)
+		if err := json.Unmarshal(value, detail); err != nil {
+			t.Fatalf("unmarshal message %d value failed, err: %v", idx, err)
+		}
+		if detail.Cursor != cursor || detail.Resource != watch.Host {
+			t.Errorf("message %d value is not the event, got %s", idx, value)
+		}
+
+		if len(msg.Headers) != 2 || string(msg.Headers[0].Value) != string(watch.Host) {
+			t.Errorf("message %d headers are invalid: %+v", idx, msg.Headers)
+		}
+	}
+}
+
+func TestGenMessagesWithNoEventCursor(t *testing.T) {
+	events := []*watch.WatchEventDetail{{Cursor: watch.NoEventCursor, Resource: watch.Host}}
+
+	messages, next, err := genMessages("topic_host", watch.Host, "c0", events)
+	if err != nil {
+		t.Fatalf("generate messages failed, err: %v", err)
+	}
+
+	if len(messages) != 0 {
+		t.Errorf("no event cursor should not be published, but got %d messages", len(messages))
+	}
+
+	if next != "c0" {
+		t.Errorf("next cursor should stay at c0 when no event is hit, but got %s", next)
+	}
+}
+
+func TestParseResources(t *testing.T) {
+	resources, err := parseResources([]string{"host", " biz "})
+	if err != nil {
+		t.Fatalf("parse resources failed, err: %v", err)
+	}
+
+	if len(resources) != 2 || resources[0] != watch.Host || resources[1] != watch.Biz {
+		t.Errorf("parse resources got unexpected result: %v", resources)
+	}
+
+	if _, err := parseResources([]string{"host", "not_exist"}); err == nil {
+		t.Errorf("unsupported resource should be rejected")
+	}
+}
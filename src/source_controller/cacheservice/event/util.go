@@ -64,6 +64,8 @@ func GetResourceKeyWithCursorType(res watch.CursorType) (Key, error) {
 		key = KubeWorkloadKey
 	case watch.KubePod:
 		key = KubePodKey
+	case watch.DynamicGroupMember:
+		key = DynamicGroupMemberKey
 	default:
 		return key, fmt.Errorf("unsupported cursor type %s", res)
 	}
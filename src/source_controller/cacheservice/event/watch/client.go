@@ -123,6 +123,13 @@ func (c *Client) getEventDetail(kit *rest.Kit, node *watch.ChainNode, fields []s
 		}
 		return getFirstEventDetail(details)
 
+	case event.DynamicGroupMemberKey.Collection():
+		details, err := c.getDynamicGroupMemberEventDetailWithNodes(kit, []*watch.ChainNode{node})
+		if err != nil {
+			return nil, false, err
+		}
+		return getFirstEventDetail(details)
+
 	default:
 		detail, err := c.getEventDetailFromRedis(kit, node.Cursor, fields, key)
 		if err == nil {
@@ -91,7 +91,7 @@ func (c *Client) WatchWithStartFrom(kit *rest.Kit, key event.Key, opts *watch.Wa
 			}}, nil
 		}
 
-		detail, exists, err := c.getEventDetail(kit, tailNode, opts.Fields, key)
+		detail, exists, err := c.getMatchedEventDetail(kit, tailNode, opts, key)
 		if err != nil {
 			blog.Errorf("get latest event detail failed, err: %v, rid: %s", err, rid)
 			return nil, err
@@ -186,9 +186,122 @@ func (c *Client) WatchWithStartFrom(kit *rest.Kit, key event.Key, opts *watch.Wa
 	return c.getEventDetailsWithNodes(kit, opts, nodes, key)
 }
 
-// getEventDetailsWithNodes get event details with nodes, first get from redis, then get failed ones from mongo
-func (c *Client) getEventDetailsWithNodes(kit *rest.Kit, opts *watch.WatchEventOptions, hitNodes []*watch.ChainNode, key event.Key) (
-	[]*watch.WatchEventDetail, error) {
+// getEventDetailsWithNodes get event details with nodes that matches the filter expression. if all the events are
+// filtered out, the last node's cursor is returned with no detail, so that user can watch from this node continually.
+func (c *Client) getEventDetailsWithNodes(kit *rest.Kit, opts *watch.WatchEventOptions, hitNodes []*watch.ChainNode,
+	key event.Key) ([]*watch.WatchEventDetail, error) {
+
+	details, err := c.getMatchedEventDetailsWithNodes(kit, opts, hitNodes, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(details) == 0 && len(hitNodes) != 0 {
+		lastOne := hitNodes[len(hitNodes)-1]
+		return []*watch.WatchEventDetail{{
+			Cursor:    lastOne.Cursor,
+			Resource:  opts.Resource,
+			EventType: lastOne.EventType,
+			Detail:    nil,
+		}}, nil
+	}
+
+	return details, nil
+}
+
+// getMatchedEventDetailsWithNodes get event details with nodes, then filter them with the filter expression,
+// returns empty details if all the events are filtered out.
+func (c *Client) getMatchedEventDetailsWithNodes(kit *rest.Kit, opts *watch.WatchEventOptions,
+	hitNodes []*watch.ChainNode, key event.Key) ([]*watch.WatchEventDetail, error) {
+
+	if opts.Filter.Expression == nil {
+		return c.searchEventDetailsWithNodes(kit, opts, hitNodes, key)
+	}
+
+	// get the whole event details so that the expression can be matched with the fields that are not watched
+	fullOpts := *opts
+	fullOpts.Fields = nil
+	details, err := c.searchEventDetailsWithNodes(kit, &fullOpts, hitNodes, key)
+	if err != nil {
+		return nil, err
+	}
+
+	matched := make([]*watch.WatchEventDetail, 0)
+	for _, detail := range details {
+		detailStr, ok := detail.Detail.(watch.JsonString)
+		if !ok || len(detailStr) == 0 {
+			continue
+		}
+
+		jsonStr, isMatched, err := c.matchEventDetail(kit, opts, string(detailStr))
+		if err != nil {
+			return nil, err
+		}
+
+		if !isMatched {
+			continue
+		}
+
+		detail.Detail = watch.JsonString(jsonStr)
+		matched = append(matched, detail)
+	}
+
+	return matched, nil
+}
+
+// getMatchedEventDetail get the event detail of the node, the detail is returned as empty if it is not matched
+// with the filter expression.
+func (c *Client) getMatchedEventDetail(kit *rest.Kit, node *watch.ChainNode, opts *watch.WatchEventOptions,
+	key event.Key) (*string, bool, error) {
+
+	if opts.Filter.Expression == nil {
+		return c.getEventDetail(kit, node, opts.Fields, key)
+	}
+
+	detail, exists, err := c.getEventDetail(kit, node, nil, key)
+	if err != nil || !exists || detail == nil || len(*detail) == 0 {
+		return detail, exists, err
+	}
+
+	jsonStr, isMatched, err := c.matchEventDetail(kit, opts, *detail)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if !isMatched {
+		empty := ""
+		return &empty, true, nil
+	}
+	return &jsonStr, true, nil
+}
+
+// matchEventDetail check if the whole event detail matches the filter expression, returns the detail with the
+// watched fields if matched.
+func (c *Client) matchEventDetail(kit *rest.Kit, opts *watch.WatchEventOptions, detail string) (string, bool,
+	error) {
+
+	doc := make(mapstr.MapStr)
+	if err := json.Unmarshal([]byte(detail), &doc); err != nil {
+		blog.Errorf("unmarshal event detail %s failed, err: %v, rid: %s", detail, err, kit.Rid)
+		return "", false, err
+	}
+
+	matched, err := opts.Filter.Expression.Match(doc)
+	if err != nil {
+		blog.Errorf("match event detail %s with expression failed, err: %v, rid: %s", detail, err, kit.Rid)
+		return "", false, kit.CCError.CCErrorf(common.CCErrCommParamsInvalid, "bk_filter.bk_expression")
+	}
+
+	if !matched {
+		return "", false, nil
+	}
+
+	return *json.CutJsonDataWithFields(&detail, opts.Fields), true, nil
+}
+
+// searchEventDetailsWithNodes get event details with nodes, first get from redis, then get failed ones from mongo
+func (c *Client) searchEventDetailsWithNodes(kit *rest.Kit, opts *watch.WatchEventOptions,
+	hitNodes []*watch.ChainNode, key event.Key) ([]*watch.WatchEventDetail, error) {
 
 	if len(hitNodes) == 0 {
 		return make([]*watch.WatchEventDetail, 0), nil
@@ -204,6 +317,10 @@ func (c *Client) getEventDetailsWithNodes(kit *rest.Kit, opts *watch.WatchEventO
 		return c.getBizSetRelationEventDetailWithNodes(kit, hitNodes)
 	}
 
+	if opts.Resource == watch.DynamicGroupMember {
+		return c.getDynamicGroupMemberEventDetailWithNodes(kit, hitNodes)
+	}
+
 	cursors := make([]string, len(hitNodes))
 	for index, node := range hitNodes {
 		cursors[index] = node.Cursor
@@ -496,6 +613,81 @@ func (c *Client) getBizSetRelationEventDetailFromMongo(kit *rest.Kit, bizSetIDs
 	return bizSetDetailMap, nil
 }
 
+// getDynamicGroupMemberEventDetailWithNodes get dynamic group member event detail by chain nodes, the details that
+// are expired in redis are generated by the chain nodes, whose instance id is the host id and sub resource is group id
+func (c *Client) getDynamicGroupMemberEventDetailWithNodes(kit *rest.Kit, hitNodes []*watch.ChainNode) (
+	[]*watch.WatchEventDetail, error) {
+
+	if len(hitNodes) == 0 {
+		return make([]*watch.WatchEventDetail, 0), nil
+	}
+
+	cursors := make([]string, len(hitNodes))
+	for index, node := range hitNodes {
+		cursors[index] = node.Cursor
+	}
+
+	details, errCursors, errCursorIndexMap, err := c.searchEventDetailsFromRedis(kit, cursors,
+		event.DynamicGroupMemberKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// get the biz ids of the dynamic groups whose member event details are not found in redis
+	groupBizMap := make(map[string]int64)
+	if len(errCursors) > 0 {
+		groupIDs := make([]string, 0)
+		for _, node := range hitNodes {
+			if _, exists := errCursorIndexMap[node.Cursor]; exists && len(node.SubResource) > 0 {
+				groupIDs = append(groupIDs, node.SubResource[0])
+			}
+		}
+
+		groupCond := mapstr.MapStr{common.BKFieldID: mapstr.MapStr{common.BKDBIN: util.StrArrayUnique(groupIDs)}}
+		groups := make([]metadata.DynamicGroup, 0)
+		err = c.db.Table(common.BKTableNameDynamicGroup).Find(groupCond).Fields(common.BKFieldID,
+			common.BKAppIDField).All(kit.Ctx, &groups)
+		if err != nil {
+			blog.Errorf("get dynamic groups by cond(%+v) failed, err: %v, rid: %s", groupCond, err, kit.Rid)
+			return nil, err
+		}
+
+		for _, group := range groups {
+			groupBizMap[group.ID] = group.AppID
+		}
+	}
+
+	resp := make([]*watch.WatchEventDetail, len(details))
+	for idx, detail := range details {
+		node := hitNodes[idx]
+		if _, exists := errCursorIndexMap[node.Cursor]; exists {
+			// the biz id is zero if the dynamic group is already deleted
+			member := metadata.DynamicGroupMember{HostID: node.InstanceID}
+			if len(node.SubResource) > 0 {
+				member.GroupID = node.SubResource[0]
+				member.BizID = groupBizMap[member.GroupID]
+			}
+
+			memberJs, err := json.Marshal(member)
+			if err != nil {
+				blog.Errorf("marshal dynamic group member(%+v) failed, err: %v, rid: %s", member, err, kit.Rid)
+				return nil, err
+			}
+			detail = string(memberJs)
+		} else {
+			detail = *types.GetEventDetail(&detail)
+		}
+
+		resp[idx] = &watch.WatchEventDetail{
+			Cursor:    node.Cursor,
+			Resource:  watch.DynamicGroupMember,
+			EventType: node.EventType,
+			Detail:    watch.JsonString(detail),
+		}
+	}
+	return resp, nil
+}
+
 func (c *Client) getBizIDArrStrByCond(kit *rest.Kit, cond map[string]interface{}) (string, error) {
 	const step = 500
 
@@ -560,7 +752,7 @@ func (c *Client) WatchFromNow(kit *rest.Kit, key event.Key, opts *watch.WatchEve
 		}, nil
 	}
 
-	detail, exists, err := c.getEventDetail(kit, node, opts.Fields, key)
+	detail, exists, err := c.getMatchedEventDetail(kit, node, opts, key)
 	if err != nil {
 		blog.Errorf("watch from now, but get latest event detail failed, err: %v, rid: %s", err, rid)
 		return nil, err
@@ -624,13 +816,26 @@ func (c *Client) WatchWithCursor(kit *rest.Kit, key event.Key, opts *watch.Watch
 
 	for {
 		if len(nodes) != 0 {
-			return c.getEventDetailsWithNodes(kit, opts, nodes, key)
+			details, err := c.getMatchedEventDetailsWithNodes(kit, opts, nodes, key)
+			if err != nil {
+				blog.Errorf("get event details after cursor %s failed, err: %v, rid: %s", opts.Cursor, err, rid)
+				return nil, err
+			}
+
+			if len(details) != 0 {
+				return details, nil
+			}
+
+			// all the events are filtered out by the filter expression, continue to watch from the last node
+			nodeID = nodes[len(nodes)-1].ID
 		}
 
 		// we got not even one event, sleep a little, and then try to continue the loop watch
-		time.Sleep(loopInternal)
-		blog.V(5).Infof("watch key: %s with resource: %s, got nothing, try next round. rid: %s",
-			key.Namespace(), opts.Resource, rid)
+		if len(nodes) < eventStep {
+			time.Sleep(loopInternal)
+			blog.V(5).Infof("watch key: %s with resource: %s, got nothing, try next round. rid: %s",
+				key.Namespace(), opts.Resource, rid)
+		}
 
 		if time.Now().Unix()-start > timeoutWatchLoopSeconds {
 			lastNode, exists, err := c.getLatestEvent(kit, key)
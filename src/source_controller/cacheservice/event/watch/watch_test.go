@@ -0,0 +1,128 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package watch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"configcenter/src/common/http/rest"
+	"configcenter/src/common/mapstr"
+	"configcenter/src/common/watch"
+)
+
+// genHostEventDetail generate the host event detail in the same way as the event flow does, the document of the
+// db event is encoded to json, so the datetime values become RFC3339 strings.
+func genHostEventDetail(t *testing.T, createTime time.Time) string {
+	doc := mapstr.MapStr{
+		"bk_host_id":      int64(1),
+		"bk_host_innerip": "127.0.0.1",
+		"bk_cloud_id":     int64(0),
+		"operator":        []interface{}{"admin", "user1"},
+		"create_time":     createTime,
+		"last_time":       createTime.Add(time.Hour),
+		// datetime that is saved as a cmdb time layout string
+		"bk_expire_time": createTime.Local().Format("2006-01-02 15:04:05"),
+	}
+
+	detail, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatalf("marshal host detail failed, err: %v", err)
+	}
+	return string(detail)
+}
+
+func TestMatchEventDetail(t *testing.T) {
+	createTime := time.Date(2022, 10, 17, 9, 30, 0, 123000000, time.UTC)
+	detail := genHostEventDetail(t, createTime)
+	before := createTime.Add(-time.Minute).Local().Format("2006-01-02 15:04:05")
+	after := createTime.Add(time.Minute).Local().Format("2006-01-02 15:04:05")
+	lastTimeRange := fmt.Sprintf("[%d,%d]", createTime.Unix(), createTime.Add(2*time.Hour).Unix())
+
+	cases := []struct {
+		name       string
+		expression string
+		matched    bool
+	}{
+		{
+			name:       "datetime greater",
+			expression: `{"field":"create_time","operator":"datetime_greater","value":"` + before + `"}`,
+			matched:    true,
+		},
+		{
+			name:       "datetime less",
+			expression: `{"field":"create_time","operator":"datetime_less","value":"` + before + `"}`,
+			matched:    false,
+		},
+		{
+			name:       "datetime between with timestamp",
+			expression: `{"field":"last_time","operator":"datetime_between","value":` + lastTimeRange + `}`,
+			matched:    true,
+		},
+		{
+			name:       "datetime of cmdb time layout",
+			expression: `{"field":"bk_expire_time","operator":"datetime_less_or_equal","value":"` + after + `"}`,
+			matched:    true,
+		},
+		{
+			name: "combined with other operators",
+			expression: `{"condition":"AND","rules":[` +
+				`{"field":"create_time","operator":"datetime_greater_or_equal","value":"` + before + `"},` +
+				`{"field":"bk_cloud_id","operator":"equal","value":0},` +
+				`{"field":"operator","operator":"contains","value":"user"}]}`,
+			matched: true,
+		},
+		{
+			name: "combined not matched",
+			expression: `{"condition":"AND","rules":[` +
+				`{"field":"create_time","operator":"datetime_greater_or_equal","value":"` + after + `"},` +
+				`{"field":"bk_cloud_id","operator":"equal","value":0}]}`,
+			matched: false,
+		},
+	}
+
+	client := new(Client)
+	kit := &rest.Kit{Rid: "test", Ctx: context.Background()}
+
+	for _, c := range cases {
+		opts := new(watch.WatchEventOptions)
+		body := `{"bk_resource":"host","bk_fields":["bk_host_id"],"bk_filter":{"bk_expression":` + c.expression + `}}`
+		if err := json.Unmarshal([]byte(body), opts); err != nil {
+			t.Errorf("%s: unmarshal watch options failed, err: %v", c.name, err)
+			continue
+		}
+
+		if err := opts.Validate(); err != nil {
+			t.Errorf("%s: validate watch options failed, err: %v", c.name, err)
+			continue
+		}
+
+		result, matched, err := client.matchEventDetail(kit, opts, detail)
+		if err != nil {
+			t.Errorf("%s: match event detail failed, err: %v", c.name, err)
+			continue
+		}
+
+		if matched != c.matched {
+			t.Errorf("%s: expect matched %v, but got %v, detail: %s", c.name, c.matched, matched, detail)
+			continue
+		}
+
+		if matched && result != `{"bk_host_id":1}` {
+			t.Errorf("%s: matched detail should only contain the watched fields, but got %s", c.name, result)
+		}
+	}
+}
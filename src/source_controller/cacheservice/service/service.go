@@ -29,8 +29,10 @@ import (
 	"configcenter/src/source_controller/cacheservice/cache"
 	cacheop "configcenter/src/source_controller/cacheservice/cache"
 	"configcenter/src/source_controller/cacheservice/event/bsrelation"
+	"configcenter/src/source_controller/cacheservice/event/dgmember"
 	"configcenter/src/source_controller/cacheservice/event/flow"
 	"configcenter/src/source_controller/cacheservice/event/identifier"
+	"configcenter/src/source_controller/cacheservice/event/sink"
 	"configcenter/src/source_controller/coreservice/core"
 	"configcenter/src/storage/dal/mongo/local"
 	"configcenter/src/storage/reflector"
@@ -131,6 +133,22 @@ func (s *cacheService) SetConfig(cfg options.Config, engine *backbone.Engine, er
 		return err
 	}
 
+	if err := dgmember.NewDynamicGroupMember(watcher, watchDB, ccDB); err != nil {
+		blog.Errorf("new dynamic group member event failed, err: %v", err)
+		return err
+	}
+
+	sinkConf, confErr := sink.ParseConfig()
+	if confErr != nil {
+		blog.Errorf("parse kafka event sink config failed, err: %v", confErr)
+		return confErr
+	}
+
+	if err := sink.NewSink(sinkConf, c.Event, watchDB, engine.ServiceManageInterface, engine.CCErr); err != nil {
+		blog.Errorf("new kafka event sink failed, err: %v", err)
+		return err
+	}
+
 	return nil
 }
 
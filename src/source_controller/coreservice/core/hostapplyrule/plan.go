@@ -133,6 +133,9 @@ func (p *hostApplyRule) GenerateApplyPlan(kit *rest.Kit, bizID int64, option met
 		}
 		if hostApplyPlan.UnresolvedConflictCount > 0 {
 			unresolvedConflictCount += 1
+		}
+		// the hosts whose conflicts are all resolved still need to be updated
+		if hostApplyPlan.UnresolvedConflictCount > 0 || len(hostApplyPlan.UpdateFields) > 0 {
 			hostApplyPlans = append(hostApplyPlans, hostApplyPlan)
 		}
 	}
@@ -261,7 +264,7 @@ func preCheckRules(targetRules []metadata.HostApplyRule, attributeID int64, attr
 
 func getOneHostApplyPlan(kit *rest.Kit, attrRules map[int64][]metadata.HostApplyRule,
 	attrMap map[int64]metadata.Attribute, hostID int64, host map[string]interface{}, moduleIDs []int64,
-	resolverMap map[int64]interface{}) (metadata.OneHostApplyPlan, errors.CCErrorCoder) {
+	resolverMap map[int64]metadata.HostApplyConflictResolver) (metadata.OneHostApplyPlan, errors.CCErrorCoder) {
 
 	rid := util.ExtractRequestUserFromContext(kit.Ctx)
 	plan := metadata.OneHostApplyPlan{
@@ -304,17 +307,20 @@ func getOneHostApplyPlan(kit *rest.Kit, attrRules map[int64][]metadata.HostApply
 			needChange = true
 			expectValue = rule.PropertyValue
 			conflictedStillExist = true
-			if propertyValue, exist := resolverMap[attribute.ID]; exist {
+			conflictField := metadata.HostApplyConflictField{
+				AttributeID:   attributeID,
+				PropertyID:    propertyIDField,
+				PropertyValue: originalValue,
+				Rules:         targetRules,
+			}
+			if resolver, exist := resolverMap[attribute.ID]; exist {
 				conflictedStillExist = false
-				expectValue = propertyValue
+				expectValue = resolver.PropertyValue
+				conflictField.Strategy = resolver.Strategy
+				conflictField.SourceRuleIDs = resolver.SourceRuleIDs
 			}
-			plan.ConflictFields = append(plan.ConflictFields, metadata.HostApplyConflictField{
-				AttributeID:             attributeID,
-				PropertyID:              propertyIDField,
-				PropertyValue:           originalValue,
-				Rules:                   targetRules,
-				UnresolvedConflictExist: conflictedStillExist,
-			})
+			conflictField.UnresolvedConflictExist = conflictedStillExist
+			plan.ConflictFields = append(plan.ConflictFields, conflictField)
 			break
 		}
 
@@ -360,12 +366,12 @@ func (p *hostApplyRule) generateOneHostApplyPlan(
 	resolvers []metadata.HostApplyConflictResolver,
 ) (metadata.OneHostApplyPlan, errors.CCErrorCoder) {
 
-	resolverMap := make(map[int64]interface{})
+	resolverMap := make(map[int64]metadata.HostApplyConflictResolver)
 	for _, item := range resolvers {
 		if item.HostID != hostID {
 			continue
 		}
-		resolverMap[item.AttributeID] = item.PropertyValue
+		resolverMap[item.AttributeID] = item
 	}
 
 	moduleIDSet := make(map[int64]bool)
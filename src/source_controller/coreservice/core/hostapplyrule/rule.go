@@ -193,6 +193,10 @@ func (p *hostApplyRule) CreateHostApplyRule(kit *rest.Kit, bizID int64, option m
 	}
 	rule.ID = int64(id)
 
+	if rule.ConflictPolicy, ccErr = p.getConflictPolicy(kit, bizID, rule.AttributeID); ccErr != nil {
+		return rule, ccErr
+	}
+
 	if err := mongodb.Client().Table(common.BKTableNameHostApplyRule).Insert(kit.Ctx, rule); err != nil {
 		if mongodb.Client().IsDuplicatedError(err) {
 			blog.Errorf("CreateHostApplyRule failed, duplicated error, doc: %+v, err: %+v, rid: %s", rule, err, kit.Rid)
@@ -646,6 +650,11 @@ func (p *hostApplyRule) BatchUpdateHostApplyRule(kit *rest.Kit, bizID int64, opt
 			LastTime:          now,
 			SupplierAccount:   kit.SupplierAccount,
 		}
+		if rule.ConflictPolicy, ccErr = p.getConflictPolicy(kit, bizID, item.AttributeID); ccErr != nil {
+			itemResult.SetError(ccErr)
+			batchResult.Items = append(batchResult.Items, itemResult)
+			continue
+		}
 		if err := mongodb.Client().Table(common.BKTableNameHostApplyRule).Insert(kit.Ctx, rule); err != nil {
 			blog.ErrorJSON("BatchUpdateHostApplyRule failed, insert rule failed, doc: %s, err: %s, rid: %s", rule, err.Error(), rid)
 			ccErr := kit.CCError.CCError(common.CCErrCommDBInsertFailed)
@@ -656,6 +665,10 @@ func (p *hostApplyRule) BatchUpdateHostApplyRule(kit *rest.Kit, bizID int64, opt
 		batchResult.Items = append(batchResult.Items, itemResult)
 	}
 
+	if ccErr := p.saveConflictPolicies(kit, bizID, option.ConflictPolicies); ccErr != nil {
+		return batchResult, ccErr
+	}
+
 	for index, item := range option.Rules {
 		rule, ccErr := p.GetHostApplyRuleByAttributeID(kit, bizID, item.ModuleID, item.AttributeID)
 		if ccErr != nil {
@@ -717,3 +730,65 @@ func (p *hostApplyRule) SearchRuleRelatedServiceTemplates(kit *rest.Kit,
 
 	return resultSrvTemplates, nil
 }
+
+// getConflictPolicy get the conflict policy of the attribute, it is shared by all rules of the attribute in business
+func (p *hostApplyRule) getConflictPolicy(kit *rest.Kit, bizID int64, attributeID int64) (
+	*metadata.HostApplyConflictPolicy, errors.CCErrorCoder) {
+
+	filter := map[string]interface{}{
+		common.BKAppIDField:                 bizID,
+		common.BkSupplierAccount:            kit.SupplierAccount,
+		common.BKAttributeIDField:           attributeID,
+		common.HostApplyConflictPolicyField: map[string]interface{}{common.BKDBExists: true},
+	}
+
+	rules := make([]metadata.HostApplyRule, 0)
+	err := mongodb.Client().Table(common.BKTableNameHostApplyRule).Find(filter).
+		Fields(common.HostApplyConflictPolicyField).Limit(1).All(kit.Ctx, &rules)
+	if err != nil {
+		blog.Errorf("get conflict policy failed, filter: %#v, err: %v, rid: %s", filter, err, kit.Rid)
+		return nil, kit.CCError.CCError(common.CCErrCommDBSelectFailed)
+	}
+
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	return rules[0].ConflictPolicy, nil
+}
+
+// saveConflictPolicies save the conflict policies to all rules of their attributes in business
+func (p *hostApplyRule) saveConflictPolicies(kit *rest.Kit, bizID int64,
+	policies []metadata.HostApplyConflictPolicy) errors.CCErrorCoder {
+
+	for _, policy := range policies {
+		if rawErr := policy.Validate(); rawErr.ErrCode != 0 {
+			return rawErr.ToCCError(kit.CCError)
+		}
+
+		attribute, ccErr := p.getHostAttribute(kit, bizID, policy.AttributeID)
+		if ccErr != nil {
+			blog.Errorf("get host attribute %d failed, err: %v, rid: %s", policy.AttributeID, ccErr, kit.Rid)
+			return ccErr
+		}
+
+		// 只有多值类型的字段才能将多个规则的值合并
+		if policy.Strategy == metadata.HostApplyUnion && attribute.PropertyType != common.FieldTypeOrganization {
+			blog.Errorf("attribute %d type %s can not use union strategy, rid: %s", policy.AttributeID,
+				attribute.PropertyType, kit.Rid)
+			return kit.CCError.CCErrorf(common.CCErrCommParamsInvalid, "conflict_policies.strategy")
+		}
+
+		filter := map[string]interface{}{
+			common.BKAppIDField:       bizID,
+			common.BkSupplierAccount:  kit.SupplierAccount,
+			common.BKAttributeIDField: policy.AttributeID,
+		}
+		doc := map[string]interface{}{common.HostApplyConflictPolicyField: policy}
+		if err := mongodb.Client().Table(common.BKTableNameHostApplyRule).Update(kit.Ctx, filter, doc); err != nil {
+			blog.Errorf("save conflict policy failed, filter: %#v, doc: %#v, err: %v, rid: %s", filter, doc, err,
+				kit.Rid)
+			return kit.CCError.CCError(common.CCErrCommDBUpdateFailed)
+		}
+	}
+	return nil
+}
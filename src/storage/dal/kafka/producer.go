@@ -0,0 +1,56 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kafka
+
+import (
+	"errors"
+
+	"github.com/Shopify/sarama"
+)
+
+// NewSaramaConfig new sarama config with the SASL SCRAM-SHA-512 authentication if the user and password is set
+func NewSaramaConfig(conf Config) *sarama.Config {
+	config := sarama.NewConfig()
+	if conf.User != "" && conf.Password != "" {
+		config.Net.SASL.Enable = true
+		config.Net.SASL.User = conf.User
+		config.Net.SASL.Password = conf.Password
+		config.Net.SASL.Handshake = true
+		config.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient {
+			return &XDGSCRAMClient{HashGeneratorFcn: SHA512}
+		}
+		config.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA512
+	}
+	return config
+}
+
+// NewSyncProducer new an idempotent kafka sync producer, the message is sent only once by the producer even if
+// it is retried, and the message is returned success only after it is written to all the in-sync replicas.
+func NewSyncProducer(conf Config) (sarama.SyncProducer, error) {
+	if len(conf.Brokers) == 0 {
+		return nil, errors.New("can not find kafka brokers config")
+	}
+
+	config := NewSaramaConfig(conf)
+	config.Producer.Return.Successes = true
+	config.Producer.Return.Errors = true
+	config.Producer.RequiredAcks = sarama.WaitForAll
+	config.Producer.Idempotent = true
+	config.Producer.Retry.Max = 10
+	config.Net.MaxOpenRequests = 1
+	if !config.Version.IsAtLeast(sarama.V0_11_0_0) {
+		config.Version = sarama.V0_11_0_0
+	}
+
+	return sarama.NewSyncProducer(conf.Brokers, config)
+}
@@ -0,0 +1,115 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"configcenter/src/common"
+	"configcenter/src/common/metadata"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(NewAuditLogCommand())
+}
+
+type auditLogVerifyConf struct {
+	supplierAccount string
+	startSeq        int64
+	endSeq          int64
+}
+
+// NewAuditLogCommand new tool command for audit logs
+func NewAuditLogCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "auditlog",
+		Short: "audit log operations",
+		Run: func(cmd *cobra.Command, args []string) {
+			_ = cmd.Help()
+		},
+	}
+
+	conf := new(auditLogVerifyConf)
+	verifyCmd := &cobra.Command{
+		Use:   "verify",
+		Short: "verify the audit log hash chain to find out the deleted or modified audit logs",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runAuditLogVerify(conf)
+		},
+	}
+	verifyCmd.Flags().StringVar(&conf.supplierAccount, "supplier-account", common.BKDefaultOwnerID,
+		"the supplier account of the audit log chain to verify")
+	verifyCmd.Flags().Int64Var(&conf.startSeq, "start-seq", 0, "the chain sequence to start verifying from")
+	verifyCmd.Flags().Int64Var(&conf.endSeq, "end-seq", 0, "the chain sequence to end verifying at, 0 means the head")
+	cmd.AddCommand(verifyCmd)
+
+	return cmd
+}
+
+func runAuditLogVerify(conf *auditLogVerifyConf) error {
+	clientSet, err := newClientSet()
+	if err != nil {
+		return err
+	}
+
+	opt := &metadata.VerifyAuditLogChainOption{
+		SupplierAccount: conf.supplierAccount,
+		StartSeq:        conf.startSeq,
+		EndSeq:          conf.endSeq,
+	}
+	resp, err := clientSet.AdminServer().VerifyAuditLogChain(context.Background(), systemHeader(), opt)
+	if err != nil {
+		return err
+	}
+	if err := resp.CCError(); err != nil {
+		return err
+	}
+
+	result := resp.Data
+	printInfo("supplier account: %s, head seq: %d, checked: %d (seq %d - %d), checkpoints: %d, unsealed: %d\n",
+		result.SupplierAccount, result.HeadSeq, result.Checked, result.FirstSeq, result.LastSeq, result.Checkpoints,
+		result.Unsealed)
+
+	if len(result.Gaps) > 0 {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "GAP_FROM_SEQ\tGAP_TO_SEQ")
+		for _, gap := range result.Gaps {
+			_, _ = fmt.Fprintf(w, "%d\t%d\n", gap.FromSeq, gap.ToSeq)
+		}
+		_ = w.Flush()
+	}
+
+	if len(result.Issues) > 0 {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "CHAIN_SEQ\tAUDIT_ID\tCHECKPOINT_ID\tREASON")
+		for _, issue := range result.Issues {
+			_, _ = fmt.Fprintf(w, "%d\t%d\t%d\t%s\n", issue.ChainSeq, issue.AuditID, issue.CheckpointID, issue.Reason)
+		}
+		_ = w.Flush()
+	}
+
+	if result.Truncated {
+		printError("too many problems are found, only part of them are listed\n")
+	}
+
+	if !result.Valid {
+		return fmt.Errorf("audit log chain of supplier account %s is broken", result.SupplierAccount)
+	}
+	printInfo("audit log chain is valid\n")
+	return nil
+}
@@ -23,13 +23,12 @@ import (
 	"time"
 
 	"configcenter/src/ac"
-	"configcenter/src/ac/iam"
 	"configcenter/src/ac/meta"
 	"configcenter/src/apimachinery"
 	"configcenter/src/apimachinery/discovery"
 	"configcenter/src/apimachinery/util"
 	"configcenter/src/common"
-	"configcenter/src/common/backbone/service_mange/zk"
+	"configcenter/src/common/backbone/service_mange/regdiscv"
 	"configcenter/src/common/blog"
 	"configcenter/src/tools/cmdb_ctl/app/config"
 
@@ -98,7 +97,10 @@ func newAuthService(c *authConf) (*authService, error) {
 		return nil, errors.New("resource must be set via resource flag or resource file specified by rsc-file flag")
 	}
 
-	client := zk.NewZkClient(config.Conf.ZkAddr, 40*time.Second)
+	client, err := regdiscv.NewClient(config.Conf.ZkAddr, 40*time.Second)
+	if err != nil {
+		return nil, err
+	}
 	if err := client.Start(); err != nil {
 		return nil, fmt.Errorf("connect regdiscv [%s] failed: %v", config.Conf.ZkAddr, err)
 	}
@@ -119,7 +121,7 @@ func newAuthService(c *authConf) (*authService, error) {
 		return nil, fmt.Errorf("new api machinery failed, err: %v", err)
 	}
 	service := &authService{
-		authorizer: iam.NewAuthorizer(clientSet),
+		authorizer: ac.NewAuthorizer(clientSet),
 	}
 
 	if c.resource != "" {
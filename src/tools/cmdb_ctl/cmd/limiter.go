@@ -17,13 +17,16 @@ package cmd
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
 	"strings"
 
+	"configcenter/src/common"
 	"configcenter/src/common/metadata"
 	"configcenter/src/common/types"
+	"configcenter/src/storage/dal/redis"
 	"configcenter/src/tools/cmdb_ctl/app/config"
 
 	"github.com/spf13/cobra"
@@ -41,10 +44,16 @@ const (
 ./tool_ctl limiter set --rule='{"rulename":"rule1","appcode":"gse","user":"admin","ip":"","method":"POST","url":"^/api/v3/module/search/[^\\s/]+/[0-9]+/[0-9]+/?$","limit":1000,"ttl":60,"denyall":false}'
 # 配置策略，将url直接禁掉
 ./tool_ctl limiter set --rule='{"rulename":"rule1","appcode":"gse","user":"admin","url":"^/api/v3/module/search/[^\\s/]+/[0-9]+/[0-9]+/?$","denyall":true}'
+# 配置令牌桶策略，每个开发商各自每60秒补充1000个令牌，最多允许2000个请求的突发
+./tool_ctl limiter set --rule='{"rulename":"rule2","appcode":"gse","pertenant":true,"limit":1000,"ttl":60,"type":"token_bucket","burst":2000}'
+# 配置试运行策略，只记录应被限流的请求，不拦截
+./tool_ctl limiter set --rule='{"rulename":"rule3","bk_supplier_account":"0","limit":1000,"ttl":60,"dryrun":true}'
 # 获取某些策略详情
 ./tool_ctl limiter get --rulenames=test1,test2
 # 删除某些策略
 ./tool_ctl limiter del --rulenames=test1,test2
+# 查看最近24小时内各策略限流的调用方统计，不指定策略名时查看所有策略
+./tool_ctl limiter stats --rulenames=test1,test2
 ********************************************************
 		`
 	// ruleIntro rule introduction
@@ -63,9 +72,15 @@ const (
 | limit    | int64  | 否   | api请求限制总次数                                            |
 | ttl      | int64  | 否   | 策略存活时间，单位为秒                                       |
 | denyall  | bool   | 否   | 是否直接禁掉请求，默认为false，为true时忽略limit和ttl参数    |
+| type     | string | 否   | 限流类型，fixed_window(固定窗口，默认)或token_bucket(令牌桶) |
+| burst    | int64  | 否   | 令牌桶容量，即允许的突发请求数，默认与limit相同              |
+| bk_supplier_account | string | 否 | 请求的开发商账号                                  |
+| pertenant | bool  | 否   | 是否按开发商账号分别计数，为true时每个开发商有各自的配额     |
+| dryrun   | bool   | 否   | 是否试运行，为true时只记录应被限流的请求，不拦截             |
  
-appcode、user、ip、method、url需要至少配置一项  
-denyall配置为false的情况下，limit和ttl配置才能生效
+appcode、user、ip、method、url、bk_supplier_account、pertenant需要至少配置一项  
+denyall配置为false的情况下，limit和ttl配置才能生效  
+fixed_window类型在ttl秒的窗口内最多允许limit次请求，token_bucket类型每ttl秒补充limit个令牌
 ********************************************************
 		`
 )
@@ -123,6 +138,14 @@ func NewLimiterCommand() *cobra.Command {
 		},
 	})
 
+	cmd.AddCommand(&cobra.Command{
+		Use:   "stats",
+		Short: "show the callers limited by api limiter rules, use with flag --rulenames or list all rules' stats",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runRuleStats(conf)
+		},
+	})
+
 	cmd.AddCommand(&cobra.Command{
 		Use:   "ls",
 		Short: "list all api limiter rules",
@@ -148,25 +171,27 @@ func runSetRule(c *limiterConf) error {
 		return err
 	}
 
-	zk, err := config.NewZkService(config.Conf.ZkAddr)
+	client, err := newRegDiscvClient()
 	if err != nil {
 		return err
 	}
+	defer client.Stop()
+
 	path := fmt.Sprintf("%s/%s", types.CC_SERVLIMITER_BASEPATH, rule.RuleName)
-	exist, err := zk.ZkCli.Exist(path)
-	if err != nil {
-		return err
-	}
-	if exist {
+	_, err = client.KV().Get(path)
+	if err == nil {
 		return fmt.Errorf("the rule %s has already existed", rule.RuleName)
 	}
+	if !client.IsNoNodeErr(err) {
+		return err
+	}
 
 	data, err := json.Marshal(rule)
 	if err != nil {
 		return err
 	}
 
-	err = zk.ZkCli.CreateDeepNode(path, data)
+	err = client.Put(path, data)
 	if err != nil {
 		return err
 	}
@@ -185,14 +210,16 @@ func runGetRules(c *limiterConf) error {
 	if c.rulenames == "" {
 		return fmt.Errorf("rulenames must be set")
 	}
-	zk, err := config.NewZkService(config.Conf.ZkAddr)
+	client, err := newRegDiscvClient()
 	if err != nil {
 		return err
 	}
+	defer client.Stop()
+
 	names := strings.Split(c.rulenames, ",")
 	for _, name := range names {
 		path := fmt.Sprintf("%s/%s", types.CC_SERVLIMITER_BASEPATH, name)
-		data, err := zk.ZkCli.Get(path)
+		data, err := client.KV().Get(path)
 		if err != nil {
 			_, _ = fmt.Fprintf(os.Stdout, "get rule %s err:%s\n", name, err)
 			continue
@@ -212,14 +239,16 @@ func runDelRules(c *limiterConf) error {
 	if c.rulenames == "" {
 		return fmt.Errorf("rulenames must be set")
 	}
-	zk, err := config.NewZkService(config.Conf.ZkAddr)
+	client, err := newRegDiscvClient()
 	if err != nil {
 		return err
 	}
+	defer client.Stop()
+
 	names := strings.Split(c.rulenames, ",")
 	for _, name := range names {
 		path := fmt.Sprintf("%s/%s", types.CC_SERVLIMITER_BASEPATH, name)
-		err := zk.ZkCli.Del(path, -1)
+		err := client.Del(path)
 		if err != nil {
 			_, _ = fmt.Fprintf(os.Stdout, "del rule %s err:%s\n", name, err)
 			continue
@@ -230,17 +259,22 @@ func runDelRules(c *limiterConf) error {
 }
 
 func runListRules(c *limiterConf) error {
-	zk, err := config.NewZkService(config.Conf.ZkAddr)
+	client, err := newRegDiscvClient()
 	if err != nil {
 		return err
 	}
+	defer client.Stop()
+
 	path := types.CC_SERVLIMITER_BASEPATH
-	children, err := zk.ZkCli.GetChildren(path)
+	children, err := client.KV().GetChildren(path)
 	if err != nil {
+		if client.IsNoNodeErr(err) {
+			return nil
+		}
 		return err
 	}
 	for _, child := range children {
-		data, err := zk.ZkCli.Get(path + "/" + child)
+		data, err := client.KV().Get(path + "/" + child)
 		if err != nil {
 			_, _ = fmt.Fprintf(os.Stdout, "list rule %s Get err:%s\n", child, err)
 			continue
@@ -255,3 +289,53 @@ func runListRules(c *limiterConf) error {
 	}
 	return nil
 }
+
+func runRuleStats(c *limiterConf) error {
+	config.Conf.RedisConf.MaxOpenConns = redisDefaultConnNum
+	redisCli, err := redis.NewFromConfig(config.Conf.RedisConf)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0)
+	if c.rulenames != "" {
+		for _, name := range strings.Split(c.rulenames, ",") {
+			keys = append(keys, common.ApiCacheLimiterStatsPrefix+name)
+		}
+	} else {
+		cursor := redisDefaultCursor
+		for {
+			scanKeys, nextCursor, err := redisCli.Scan(context.Background(), cursor,
+				common.ApiCacheLimiterStatsPrefix+"*", redisDefaultCount).Result()
+			if err != nil {
+				return err
+			}
+			keys = append(keys, scanKeys...)
+			if nextCursor == 0 {
+				break
+			}
+			cursor = nextCursor
+		}
+	}
+
+	for _, key := range keys {
+		stats, err := redisCli.HGetAll(context.Background(), key).Result()
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stdout, "get rule stats %s err:%s\n", key, err)
+			continue
+		}
+
+		_, _ = fmt.Fprintf(os.Stdout, "rule: %s\n", strings.TrimPrefix(key, common.ApiCacheLimiterStatsPrefix))
+		for field, count := range stats {
+			caller := new(metadata.LimiterCaller)
+			if err := json.Unmarshal([]byte(field), caller); err != nil {
+				_, _ = fmt.Fprintf(os.Stdout, "\t%s\tlimited: %s\n", field, count)
+				continue
+			}
+			_, _ = fmt.Fprintf(os.Stdout, "\tappcode: %s, user: %s, ip: %s, bk_supplier_account: %s, dryrun: %v"+
+				"\tlimited: %s\n", caller.AppCode, caller.User, caller.IP, caller.SupplierAccount, caller.DryRun, count)
+		}
+		_, _ = fmt.Fprintln(os.Stdout)
+	}
+	return nil
+}
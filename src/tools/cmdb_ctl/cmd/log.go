@@ -19,8 +19,8 @@ import (
 	"strconv"
 	"strings"
 
+	"configcenter/src/common/backbone/service_mange/regdiscv"
 	"configcenter/src/common/types"
-	"configcenter/src/tools/cmdb_ctl/app/config"
 
 	"github.com/spf13/cobra"
 )
@@ -59,20 +59,20 @@ func (c *logConf) addFlags(cmd *cobra.Command) {
 }
 
 type logService struct {
-	service  *config.Service
+	client   *regdiscv.Client
 	addrport []string
 }
 
-func newLogService(zkaddr string, addrport string) (*logService, error) {
+func newLogService(addrport string) (*logService, error) {
 	if addrport == "" {
 		return nil, errors.New("addrport must set via flag or environment variable")
 	}
-	service, err := config.NewZkService(zkaddr)
+	client, err := newRegDiscvClient()
 	if err != nil {
 		return nil, err
 	}
 	return &logService{
-		service:  service,
+		client:   client,
 		addrport: strings.Split(addrport, ","),
 	}, nil
 }
@@ -85,10 +85,11 @@ func runLog(c *logConf) error {
 		return fmt.Errorf("can't set log level to v and default at the same time")
 	}
 
-	srv, err := newLogService(config.Conf.ZkAddr, c.addrPort)
+	srv, err := newLogService(c.addrPort)
 	if err != nil {
 		return err
 	}
+	defer srv.client.Stop()
 
 	if c.v != "" {
 		v, err := strconv.ParseInt(c.v, 0, 32)
@@ -105,13 +106,8 @@ func runLog(c *logConf) error {
 
 func (s *logService) setV(v int32) error {
 	for _, addr := range s.addrport {
-		if err := s.service.ZkCli.Ping(); err != nil {
-			if err = s.service.ZkCli.Connect(); err != nil {
-				return err
-			}
-		}
 		logVPath := fmt.Sprintf("%s/%s/%s/v", types.CC_SERVNOTICE_BASEPATH, "log", addr)
-		logVData, err := s.service.ZkCli.Get(logVPath)
+		logVData, err := s.client.KV().Get(logVPath)
 		if err != nil {
 			return err
 		}
@@ -125,7 +121,7 @@ func (s *logService) setV(v int32) error {
 		if err != nil {
 			return err
 		}
-		if err = s.service.ZkCli.Update(logVPath, string(dat)); err != nil {
+		if err = s.client.Put(logVPath, dat); err != nil {
 			return err
 		}
 	}
@@ -134,13 +130,8 @@ func (s *logService) setV(v int32) error {
 
 func (s *logService) setDefault() error {
 	for _, addr := range s.addrport {
-		if err := s.service.ZkCli.Ping(); err != nil {
-			if err = s.service.ZkCli.Connect(); err != nil {
-				return err
-			}
-		}
 		logVPath := fmt.Sprintf("%s/%s/%s/v", types.CC_SERVNOTICE_BASEPATH, "log", addr)
-		logVData, err := s.service.ZkCli.Get(logVPath)
+		logVData, err := s.client.KV().Get(logVPath)
 		if err != nil {
 			return err
 		}
@@ -154,7 +145,7 @@ func (s *logService) setDefault() error {
 		if err != nil {
 			return err
 		}
-		if err = s.service.ZkCli.Update(logVPath, string(dat)); err != nil {
+		if err = s.client.Put(logVPath, dat); err != nil {
 			return err
 		}
 	}
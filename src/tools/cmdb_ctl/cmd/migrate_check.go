@@ -73,6 +73,8 @@ func NewMigrateCheckCommand() *cobra.Command {
 	}
 	uniqueCmd.Flags().BoolVar(clearProc, "clear-proc", false, "clear process with no relation")
 	cmd.AddCommand(uniqueCmd)
+	cmd.AddCommand(newMigratePendingCommand())
+	cmd.AddCommand(newMigrateRecordsCommand())
 
 	return cmd
 }
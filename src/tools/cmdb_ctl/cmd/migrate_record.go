@@ -0,0 +1,126 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"configcenter/src/common"
+	"configcenter/src/common/metadata"
+
+	"github.com/spf13/cobra"
+)
+
+func newMigratePendingCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "pending",
+		Short: "list the pending upgraders and the collections, indexes and documents that each of them would touch",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runMigratePending()
+		},
+	}
+}
+
+type migrateRecordConf struct {
+	versions []string
+	status   string
+	limit    int
+}
+
+func newMigrateRecordsCommand() *cobra.Command {
+	conf := new(migrateRecordConf)
+	cmd := &cobra.Command{
+		Use:   "records",
+		Short: "list the execution records of the upgraders",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runMigrateRecords(conf)
+		},
+	}
+	cmd.Flags().StringSliceVar(&conf.versions, "version", nil, "the upgrader versions to list, comma separated")
+	cmd.Flags().StringVar(&conf.status, "status", "", "the execution status to list, can be running, success or failed")
+	cmd.Flags().IntVar(&conf.limit, "limit", 20, "the max number of records to list")
+	return cmd
+}
+
+func runMigratePending() error {
+	clientSet, err := newClientSet()
+	if err != nil {
+		return err
+	}
+
+	resp, err := clientSet.AdminServer().MigrateDryRun(context.Background(), common.BKDefaultOwnerID, "community",
+		systemHeader())
+	if err != nil {
+		return err
+	}
+	if err := resp.CCError(); err != nil {
+		return err
+	}
+
+	printInfo("current version: %s\n", resp.Data.CurrentVersion)
+	if len(resp.Data.Pending) == 0 {
+		printInfo("there is no pending upgrader\n")
+		return nil
+	}
+
+	for _, item := range resp.Data.Pending {
+		fmt.Println("=================================")
+		printInfo("upgrader %s, collections: %s\n", item.Version, strings.Join(item.Collections, ", "))
+		if len(item.Operations) > 0 {
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			_, _ = fmt.Fprintln(w, "COLLECTION\tOPERATION\tINDEX\tTIMES\tDOCUMENTS")
+			for _, op := range item.Operations {
+				_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\n", op.Collection, op.Operation, op.Index, op.Times,
+					op.Documents)
+			}
+			_ = w.Flush()
+		}
+		if item.Error != "" {
+			printError("upgrader %s can not be fully checked, err: %s\n", item.Version, item.Error)
+		}
+	}
+	return nil
+}
+
+func runMigrateRecords(conf *migrateRecordConf) error {
+	clientSet, err := newClientSet()
+	if err != nil {
+		return err
+	}
+
+	opt := &metadata.ListMigrationRecordOption{
+		Versions: conf.versions,
+		Status:   metadata.MigrationStatus(conf.status),
+		Page:     metadata.BasePage{Limit: conf.limit},
+	}
+	resp, err := clientSet.AdminServer().ListMigrationRecords(context.Background(), systemHeader(), opt)
+	if err != nil {
+		return err
+	}
+	if err := resp.CCError(); err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "ID\tVERSION\tMODE\tSTATUS\tSTART\tDURATION(ms)\tERROR")
+	for _, record := range resp.Data.Info {
+		_, _ = fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%d\t%s\n", record.ID, record.Version, record.Mode, record.Status,
+			record.StartTime.Format(time.RFC3339), record.Duration, record.Error)
+	}
+	return w.Flush()
+}
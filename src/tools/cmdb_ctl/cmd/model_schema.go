@@ -0,0 +1,178 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"configcenter/src/common/metadata"
+
+	yl "github.com/ghodss/yaml"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(NewModelSchemaCommand())
+}
+
+type modelSchemaConf struct {
+	file        string
+	allowDelete bool
+	yes         bool
+}
+
+// NewModelSchemaCommand new model schema command
+func NewModelSchemaCommand() *cobra.Command {
+	conf := new(modelSchemaConf)
+
+	cmd := &cobra.Command{
+		Use:   "model-schema",
+		Short: "diff or apply the models declared in a yaml file",
+		Run: func(cmd *cobra.Command, args []string) {
+			_ = cmd.Help()
+		},
+	}
+
+	diffCmd := &cobra.Command{
+		Use:   "diff",
+		Short: "show the changes that are needed to make the live models consistent with the yaml file",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runModelSchemaDiff(conf)
+		},
+	}
+	applyCmd := &cobra.Command{
+		Use:   "apply",
+		Short: "show the changes and then apply them to the live models",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runModelSchemaApply(conf)
+		},
+	}
+	applyCmd.Flags().BoolVar(&conf.yes, "yes", false, "apply the changes without confirmation")
+
+	for _, subCmd := range []*cobra.Command{diffCmd, applyCmd} {
+		subCmd.Flags().StringVar(&conf.file, "file", "", "the path of the model schema yaml file")
+		subCmd.Flags().BoolVar(&conf.allowDelete, "allow-delete", false,
+			"delete the groups, attributes, uniques and associations of the declared models that are not declared")
+		cmd.AddCommand(subCmd)
+	}
+
+	return cmd
+}
+
+func loadModelSchema(conf *modelSchemaConf) (*metadata.ModelSchemaOption, error) {
+	if conf.file == "" {
+		return nil, fmt.Errorf("model schema file must be set")
+	}
+
+	content, err := ioutil.ReadFile(conf.file)
+	if err != nil {
+		return nil, fmt.Errorf("read model schema file %s failed, err: %v", conf.file, err)
+	}
+
+	opt := &metadata.ModelSchemaOption{AllowDelete: conf.allowDelete}
+	if err := yl.Unmarshal(content, &opt.Schema); err != nil {
+		return nil, fmt.Errorf("parse model schema file %s failed, err: %v", conf.file, err)
+	}
+	return opt, nil
+}
+
+func runModelSchemaDiff(conf *modelSchemaConf) error {
+	opt, err := loadModelSchema(conf)
+	if err != nil {
+		return err
+	}
+
+	clientSet, err := newClientSet()
+	if err != nil {
+		return err
+	}
+
+	result, ccErr := clientSet.TopoServer().Object().DiffModelSchema(context.Background(), systemHeader(), opt)
+	if ccErr != nil {
+		return fmt.Errorf("diff model schema failed, err: %v", ccErr)
+	}
+
+	printModelSchemaChanges(result.Changes)
+	return nil
+}
+
+func runModelSchemaApply(conf *modelSchemaConf) error {
+	opt, err := loadModelSchema(conf)
+	if err != nil {
+		return err
+	}
+
+	clientSet, err := newClientSet()
+	if err != nil {
+		return err
+	}
+
+	diff, ccErr := clientSet.TopoServer().Object().DiffModelSchema(context.Background(), systemHeader(), opt)
+	if ccErr != nil {
+		return fmt.Errorf("diff model schema failed, err: %v", ccErr)
+	}
+
+	printModelSchemaChanges(diff.Changes)
+	if len(diff.Changes) == 0 {
+		return nil
+	}
+
+	if !conf.yes {
+		fmt.Printf("apply the %d changes? [y/N]: ", len(diff.Changes))
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if answer = strings.ToLower(strings.TrimSpace(answer)); answer != "y" && answer != "yes" {
+			fmt.Println("apply is canceled")
+			return nil
+		}
+	}
+
+	result, ccErr := clientSet.TopoServer().Object().ApplyModelSchema(context.Background(), systemHeader(), opt)
+	if ccErr != nil {
+		return fmt.Errorf("apply model schema failed, err: %v", ccErr)
+	}
+
+	fmt.Print(WithGreenColor(fmt.Sprintf("%d changes are applied", len(result.Changes))))
+	return nil
+}
+
+func printModelSchemaChanges(changes []metadata.ModelSchemaChange) {
+	if len(changes) == 0 {
+		fmt.Print(WithGreenColor("the live models are consistent with the model schema"))
+		return
+	}
+
+	var creates, updates, deletes int
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ACTION\tKIND\tOBJECT\tKEY\tFIELDS")
+	for _, change := range changes {
+		switch change.Action {
+		case metadata.ModelSchemaCreate:
+			creates++
+		case metadata.ModelSchemaUpdate:
+			updates++
+		case metadata.ModelSchemaDelete:
+			deletes++
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", change.Action, change.Kind, change.ObjectID, change.Key,
+			strings.Join(change.Fields, ","))
+	}
+	_ = w.Flush()
+
+	fmt.Printf("\n%d to create, %d to update, %d to delete\n", creates, updates, deletes)
+}
@@ -14,6 +14,15 @@ package cmd
 
 import (
 	"fmt"
+	"net/http"
+	"time"
+
+	"configcenter/src/apimachinery"
+	"configcenter/src/apimachinery/discovery"
+	"configcenter/src/apimachinery/util"
+	"configcenter/src/common"
+	"configcenter/src/common/backbone/service_mange/regdiscv"
+	"configcenter/src/tools/cmdb_ctl/app/config"
 )
 
 // WithRedColor TODO
@@ -30,3 +39,48 @@ func WithGreenColor(str string) string {
 func WithBlueColor(str string) string {
 	return fmt.Sprintf("%c[1;40;34m>> %s %c[0m\n", 0x1B, str, 0x1B)
 }
+
+// newRegDiscvClient create and start the register and discover client of the regdiscv address
+func newRegDiscvClient() (*regdiscv.Client, error) {
+	client, err := regdiscv.NewClient(config.Conf.ZkAddr, 40*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	if err := client.Start(); err != nil {
+		return nil, fmt.Errorf("connect regdiscv [%s] failed: %v", config.Conf.ZkAddr, err)
+	}
+	if err := client.Ping(); err != nil {
+		return nil, fmt.Errorf("connect regdiscv [%s] failed: %v", config.Conf.ZkAddr, err)
+	}
+	return client, nil
+}
+
+// newClientSet create api client set that discovers cmdb services from the register and discover backend
+func newClientSet() (apimachinery.ClientSetInterface, error) {
+	client, err := newRegDiscvClient()
+	if err != nil {
+		return nil, err
+	}
+	serviceDiscovery, err := discovery.NewServiceDiscovery(client)
+	if err != nil {
+		return nil, fmt.Errorf("connect regdiscv [%s] failed: %v", config.Conf.ZkAddr, err)
+	}
+	apiMachineryConfig := &util.APIMachineryConfig{
+		QPS:   1000,
+		Burst: 2000,
+	}
+	clientSet, err := apimachinery.NewApiMachinery(apiMachineryConfig, serviceDiscovery)
+	if err != nil {
+		return nil, fmt.Errorf("new api machinery failed, err: %v", err)
+	}
+	return clientSet, nil
+}
+
+// systemHeader header used to call cmdb api as system operator
+func systemHeader() http.Header {
+	header := make(http.Header)
+	header.Add(common.BKHTTPOwnerID, common.BKDefaultOwnerID)
+	header.Add(common.BKHTTPHeaderUser, common.CCSystemOperatorUserName)
+	header.Add("Content-Type", "application/json")
+	return header
+}
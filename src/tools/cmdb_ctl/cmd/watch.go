@@ -21,6 +21,7 @@ import (
 	"strings"
 	"time"
 
+	"configcenter/pkg/filter"
 	"configcenter/src/common/metadata"
 	"configcenter/src/common/types"
 	"configcenter/src/common/util"
@@ -42,6 +43,7 @@ type watchConf struct {
 	fields      []string
 	filter      string
 	subresource string
+	expression  string
 }
 
 func (w *watchConf) addFlags(cmd *cobra.Command) {
@@ -56,6 +58,21 @@ func (w *watchConf) addFlags(cmd *cobra.Command) {
 		"':' , multiple kv is separated with ';', like k1:v1;k2:v2")
 	cmd.PersistentFlags().StringVar(&w.subresource, "sub-rsc", "", "the sub resource to watch, can be the object ID "+
 		"of object_instance or mainline_instance resource")
+	cmd.PersistentFlags().StringVar(&w.expression, "expression", "", "the filter expression in json format that the "+
+		"event detail must match, evaluated by the server, like "+
+		`{"field":"bk_biz_id","operator":"in","value":[1,2]}`)
+}
+
+func parseWatchExpression(expression string) (*filter.Expression, error) {
+	if len(expression) == 0 {
+		return nil, nil
+	}
+
+	expr := new(filter.Expression)
+	if err := json.Unmarshal([]byte(expression), expr); err != nil {
+		return nil, fmt.Errorf("parse expression %s failed, err: %v", expression, err)
+	}
+	return expr, nil
 }
 
 // NewWatchCommand TODO
@@ -162,12 +179,17 @@ func runStartFromWatch(c *watchConf) error {
 		}
 	}
 
+	expr, err := parseWatchExpression(c.expression)
+	if err != nil {
+		return err
+	}
+
 	opt := watch.WatchEventOptions{
 		Fields:    c.fields,
 		StartFrom: c.startFrom,
 		Cursor:    c.cursor,
 		Resource:  watch.CursorType(c.resource),
-		Filter:    watch.WatchEventFilter{SubResource: c.subresource},
+		Filter:    watch.WatchEventFilter{SubResource: c.subresource, Expression: expr},
 	}
 
 	optByte, _ := json.Marshal(opt)
@@ -248,7 +270,7 @@ func runStartFromWatch(c *watchConf) error {
 				Fields:   c.fields,
 				Cursor:   event.Data.Events[len(event.Data.Events)-1].Cursor,
 				Resource: watch.CursorType(c.resource),
-				Filter:   watch.WatchEventFilter{SubResource: c.subresource},
+				Filter:   watch.WatchEventFilter{SubResource: c.subresource, Expression: expr},
 			}
 
 		}
@@ -16,6 +16,9 @@ const vendors = [{
 }, {
   id: '2',
   name: '腾讯云'
+}, {
+  id: '17',
+  name: 'OpenStack'
 }]
 
 export default vendors
@@ -31,7 +31,8 @@ import (
 // GetDepartment get department info from paas
 func (lgc *Logics) GetDepartment(c *gin.Context, config *options.Config) (*metadata.DepartmentData, errors.CCErrorCoder) {
 	if config.LoginVersion == common.BKOpenSourceLoginPluginVersion ||
-		config.LoginVersion == common.BKSkipLoginPluginVersion {
+		config.LoginVersion == common.BKSkipLoginPluginVersion || config.LoginVersion == common.BKLDAPLoginPluginVersion ||
+		config.LoginVersion == common.BKOIDCLoginPluginVersion {
 		return &metadata.DepartmentData{}, nil
 	}
 
@@ -55,7 +56,8 @@ func (lgc *Logics) GetDepartment(c *gin.Context, config *options.Config) (*metad
 // GetDepartmentProfile get department profile from paas
 func (lgc *Logics) GetDepartmentProfile(c *gin.Context, config *options.Config) (*metadata.DepartmentProfileData, errors.CCErrorCoder) {
 	if config.LoginVersion == common.BKOpenSourceLoginPluginVersion ||
-		config.LoginVersion == common.BKSkipLoginPluginVersion {
+		config.LoginVersion == common.BKSkipLoginPluginVersion || config.LoginVersion == common.BKLDAPLoginPluginVersion ||
+		config.LoginVersion == common.BKOIDCLoginPluginVersion {
 		return &metadata.DepartmentProfileData{}, nil
 	}
 
@@ -88,7 +90,8 @@ func (lgc *Logics) getDepartmentMap(ctx context.Context, header http.Header) (ma
 		return nil, defErr.CCErrorf(common.CCErrCommConfMissItem, "webServer.login.version")
 	}
 
-	if loginVersion == common.BKOpenSourceLoginPluginVersion || loginVersion == common.BKSkipLoginPluginVersion {
+	if loginVersion == common.BKOpenSourceLoginPluginVersion || loginVersion == common.BKSkipLoginPluginVersion ||
+		loginVersion == common.BKLDAPLoginPluginVersion || loginVersion == common.BKOIDCLoginPluginVersion {
 		return dpMap, nil
 	}
 
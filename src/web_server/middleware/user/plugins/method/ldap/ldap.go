@@ -0,0 +1,321 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ldap
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	cc "configcenter/src/common/backbone/configcenter"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+const (
+	defaultUserFilter      = "(uid=%s)"
+	defaultUserListFilter  = "(objectClass=person)"
+	defaultUserAttr        = "uid"
+	defaultDisplayNameAttr = "cn"
+	defaultEmailAttr       = "mail"
+	defaultPhoneAttr       = "telephoneNumber"
+	defaultGroupAttr       = "memberOf"
+	defaultTimeoutSeconds  = 10
+	defaultPageSize        = 500
+)
+
+// ldapConfig is the config of the ldap login plugin, it is read from webServer.ldap every time it is used,
+// so that the changes of the config take effect without restarting web server.
+type ldapConfig struct {
+	url string
+	// startTLS upgrade the ldap:// connection to tls by the StartTLS extended operation before binding
+	startTLS           bool
+	insecureSkipVerify bool
+	bindDN             string
+	bindPassword       string
+	baseDN             string
+	// userFilter is the filter to search the login user, %s is replaced by the escaped username
+	userFilter string
+	// userListFilter is the filter to search all the users that can be used in cmdb
+	userListFilter  string
+	userAttr        string
+	displayNameAttr string
+	emailAttr       string
+	phoneAttr       string
+	groupAttr       string
+	// roleMapping maps the lower case group dn or cn to the cmdb role
+	roleMapping map[string]string
+	timeout     time.Duration
+	pageSize    int
+}
+
+func getLdapConfig() (*ldapConfig, error) {
+	conf := &ldapConfig{
+		userFilter:      getString("webServer.ldap.userFilter", defaultUserFilter),
+		userListFilter:  getString("webServer.ldap.userListFilter", defaultUserListFilter),
+		userAttr:        getString("webServer.ldap.userAttr", defaultUserAttr),
+		displayNameAttr: getString("webServer.ldap.displayNameAttr", defaultDisplayNameAttr),
+		emailAttr:       getString("webServer.ldap.emailAttr", defaultEmailAttr),
+		phoneAttr:       getString("webServer.ldap.phoneAttr", defaultPhoneAttr),
+		groupAttr:       getString("webServer.ldap.groupAttr", defaultGroupAttr),
+		bindDN:          getString("webServer.ldap.bindDN", ""),
+		bindPassword:    getString("webServer.ldap.bindPassword", ""),
+		roleMapping:     make(map[string]string),
+		timeout:         defaultTimeoutSeconds * time.Second,
+		pageSize:        defaultPageSize,
+	}
+
+	var err error
+	if conf.url, err = cc.String("webServer.ldap.url"); err != nil || conf.url == "" {
+		return nil, errors.New("webServer.ldap.url is not set")
+	}
+
+	if conf.baseDN, err = cc.String("webServer.ldap.baseDN"); err != nil || conf.baseDN == "" {
+		return nil, errors.New("webServer.ldap.baseDN is not set")
+	}
+
+	if !strings.Contains(conf.userFilter, "%s") {
+		return nil, errors.New("webServer.ldap.userFilter must contain %s as the placeholder of username")
+	}
+
+	if cc.IsExist("webServer.ldap.insecureSkipVerify") {
+		if conf.insecureSkipVerify, err = cc.Bool("webServer.ldap.insecureSkipVerify"); err != nil {
+			return nil, fmt.Errorf("webServer.ldap.insecureSkipVerify is invalid, err: %v", err)
+		}
+	}
+
+	if cc.IsExist("webServer.ldap.startTLS") {
+		if conf.startTLS, err = cc.Bool("webServer.ldap.startTLS"); err != nil {
+			return nil, fmt.Errorf("webServer.ldap.startTLS is invalid, err: %v", err)
+		}
+	}
+
+	if cc.IsExist("webServer.ldap.timeoutSeconds") {
+		timeout, err := cc.Int("webServer.ldap.timeoutSeconds")
+		if err != nil || timeout <= 0 {
+			return nil, errors.New("webServer.ldap.timeoutSeconds is invalid")
+		}
+		conf.timeout = time.Duration(timeout) * time.Second
+	}
+
+	if cc.IsExist("webServer.ldap.pageSize") {
+		if conf.pageSize, err = cc.Int("webServer.ldap.pageSize"); err != nil || conf.pageSize <= 0 {
+			return nil, errors.New("webServer.ldap.pageSize is invalid")
+		}
+	}
+
+	if cc.IsExist("webServer.ldap.roleMapping") {
+		mappings, err := cc.StringSlice("webServer.ldap.roleMapping")
+		if err != nil {
+			return nil, fmt.Errorf("webServer.ldap.roleMapping is invalid, err: %v", err)
+		}
+
+		for _, mapping := range mappings {
+			// group dn may contain ':', so the role is the part after the last ':'
+			idx := strings.LastIndex(mapping, ":")
+			if idx <= 0 || idx == len(mapping)-1 {
+				return nil, fmt.Errorf("webServer.ldap.roleMapping %s is invalid, should be group:role", mapping)
+			}
+			conf.roleMapping[strings.ToLower(strings.TrimSpace(mapping[:idx]))] = strings.TrimSpace(mapping[idx+1:])
+		}
+	}
+
+	return conf, nil
+}
+
+func getString(key, defaultValue string) string {
+	value, err := cc.String(key)
+	if err != nil || value == "" {
+		return defaultValue
+	}
+	return value
+}
+
+// ldapUser is the ldap user info that is saved in session after the user is authenticated
+type ldapUser struct {
+	UserName string `json:"username"`
+	ChName   string `json:"ch_name"`
+	Email    string `json:"email"`
+	Phone    string `json:"phone"`
+	Role     string `json:"role"`
+}
+
+// dial connect to the ldap server and bind with the service account if it is set
+func (l *ldapConfig) dial() (*ldap.Conn, error) {
+	u, err := url.Parse(l.url)
+	if err != nil {
+		return nil, fmt.Errorf("parse ldap url %s failed, err: %v", l.url, err)
+	}
+
+	tlsConf := &tls.Config{
+		ServerName:         u.Hostname(),
+		InsecureSkipVerify: l.insecureSkipVerify,
+	}
+
+	conn, err := ldap.DialURL(l.url, ldap.DialWithDialer(&net.Dialer{Timeout: l.timeout}),
+		ldap.DialWithTLSConfig(tlsConf))
+	if err != nil {
+		return nil, err
+	}
+	conn.SetTimeout(l.timeout)
+
+	if l.startTLS && u.Scheme == "ldap" {
+		if err := conn.StartTLS(tlsConf); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("start tls failed, err: %v", err)
+		}
+	}
+
+	if l.bindDN != "" {
+		if err := conn.Bind(l.bindDN, l.bindPassword); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("bind with service account %s failed, err: %v", l.bindDN, err)
+		}
+	}
+	return conn, nil
+}
+
+// authenticate search the user entry by username and bind as it with the password, returns nil user if the
+// username or password is wrong.
+func (l *ldapConfig) authenticate(userName, password string) (*ldapUser, error) {
+	conn, err := l.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	result, err := conn.Search(ldap.NewSearchRequest(l.baseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2,
+		int(l.timeout/time.Second), false, strings.ReplaceAll(l.userFilter, "%s", ldap.EscapeFilter(userName)),
+		[]string{l.userAttr, l.displayNameAttr, l.emailAttr, l.phoneAttr, l.groupAttr}, nil))
+	if err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+			// the username matches more than one entry
+			return nil, nil
+		}
+		return nil, fmt.Errorf("search user %s failed, err: %v", userName, err)
+	}
+
+	// the username must identify exactly one entry, otherwise the user can not be authenticated
+	if len(result.Entries) != 1 {
+		return nil, nil
+	}
+	entry := result.Entries[0]
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorAnyOf(err, ldap.ErrorEmptyPassword, ldap.LDAPResultInvalidCredentials) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("bind as user %s failed, err: %v", entry.DN, err)
+	}
+
+	user := l.newLdapUser(entry)
+	if user == nil {
+		return nil, fmt.Errorf("user entry %s has no %s attribute", entry.DN, l.userAttr)
+	}
+	return user, nil
+}
+
+// newLdapUser get the user info of the authenticated user entry, returns nil if the entry has no user attribute.
+// the username is the user attribute value of the
+// entry instead of the login input, because the user filter may match the input in another form, like in another
+// case or by another attribute, and the user must be identified by the same name as the one in the user list.
+func (l *ldapConfig) newLdapUser(entry *ldap.Entry) *ldapUser {
+	user := &ldapUser{
+		UserName: entry.GetEqualFoldAttributeValue(l.userAttr),
+		ChName:   entry.GetEqualFoldAttributeValue(l.displayNameAttr),
+		Email:    entry.GetEqualFoldAttributeValue(l.emailAttr),
+		Phone:    entry.GetEqualFoldAttributeValue(l.phoneAttr),
+		Role:     l.getRole(entry.GetEqualFoldAttributeValues(l.groupAttr)),
+	}
+	if user.UserName == "" {
+		return nil
+	}
+	if user.ChName == "" {
+		user.ChName = user.UserName
+	}
+	return user
+}
+
+// getRole get the roles that the groups are mapped to, multiple roles are separated by comma
+func (l *ldapConfig) getRole(groups []string) string {
+	roles := make([]string, 0)
+	exists := make(map[string]struct{})
+	for _, group := range groups {
+		group = strings.ToLower(strings.TrimSpace(group))
+		role, ok := l.roleMapping[group]
+		if !ok {
+			role, ok = l.roleMapping[groupCN(group)]
+		}
+
+		if !ok {
+			continue
+		}
+		if _, exist := exists[role]; exist {
+			continue
+		}
+		exists[role] = struct{}{}
+		roles = append(roles, role)
+	}
+	return strings.Join(roles, ",")
+}
+
+// groupCN get the cn of the group dn, like admin of cn=admin,ou=groups,dc=example,dc=com
+func groupCN(dn string) string {
+	if !strings.HasPrefix(dn, "cn=") {
+		return ""
+	}
+	rdn := strings.TrimPrefix(dn, "cn=")
+	if idx := strings.Index(rdn, ","); idx >= 0 {
+		rdn = rdn[:idx]
+	}
+	return strings.TrimSpace(rdn)
+}
+
+// listUsers list the users that matches the user list filter, if userNames is set, only these users are returned
+func (l *ldapConfig) listUsers(userNames []string) ([]*ldap.Entry, error) {
+	conn, err := l.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	result, err := conn.SearchWithPaging(ldap.NewSearchRequest(l.baseDN, ldap.ScopeWholeSubtree,
+		ldap.NeverDerefAliases, 0, int(l.timeout/time.Second), false, l.genUserListFilter(userNames),
+		[]string{l.userAttr, l.displayNameAttr}, nil), uint32(l.pageSize))
+	if err != nil {
+		return nil, err
+	}
+	return result.Entries, nil
+}
+
+// genUserListFilter generate the filter to search the users, if userNames is set, only these users are matched
+func (l *ldapConfig) genUserListFilter(userNames []string) string {
+	filter := l.userListFilter
+	if !strings.HasPrefix(filter, "(") {
+		filter = "(" + filter + ")"
+	}
+
+	if len(userNames) == 0 {
+		return filter
+	}
+
+	var userFilter strings.Builder
+	for _, userName := range userNames {
+		userFilter.WriteString(fmt.Sprintf("(%s=%s)", l.userAttr, ldap.EscapeFilter(userName)))
+	}
+	return fmt.Sprintf("(&%s(|%s))", filter, userFilter.String())
+}
@@ -0,0 +1,340 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ldap
+
+import (
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+)
+
+// fakeEntry is an entry of the fake ldap directory, the password is used to bind as it
+type fakeEntry struct {
+	dn         string
+	password   string
+	attributes map[string][]string
+}
+
+var fakeDirectory = []fakeEntry{
+	{
+		dn:       "uid=alice,ou=people,dc=example,dc=com",
+		password: "alice-pwd",
+		attributes: map[string][]string{
+			"uid":      {"alice"},
+			"cn":       {"Alice"},
+			"mail":     {"alice@example.com"},
+			"memberOf": {"cn=admin,ou=groups,dc=example,dc=com", "cn=ops,ou=groups,dc=example,dc=com"},
+		},
+	},
+	{
+		dn:         "uid=bob,ou=people,dc=example,dc=com",
+		password:   "bob-pwd",
+		attributes: map[string][]string{"uid": {"bob"}, "mail": {"bob@example.com"}},
+	},
+	{
+		dn:         "uid=bob2,ou=people,dc=example,dc=com",
+		password:   "bob2-pwd",
+		attributes: map[string][]string{"uid": {"bob2"}, "mail": {"bob@example.com"}},
+	},
+}
+
+// fakeServer starts a fake ldap server that supports simple bind and search, returns the ldap url of it.
+func fakeServer(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed, err: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveFakeConn(conn)
+		}
+	}()
+	return "ldap://" + listener.Addr().String()
+}
+
+func serveFakeConn(conn net.Conn) {
+	defer conn.Close()
+	for {
+		msg, err := ber.ReadPacket(conn)
+		if err != nil || len(msg.Children) < 2 {
+			return
+		}
+		msgID := msg.Children[0].Value
+		op := msg.Children[1]
+
+		switch op.Tag {
+		case ldap.ApplicationBindRequest:
+			dn := op.Children[1].Data.String()
+			password := op.Children[2].Data.String()
+			code := uint16(ldap.LDAPResultInvalidCredentials)
+			for _, entry := range fakeDirectory {
+				if entry.dn == dn && entry.password == password {
+					code = ldap.LDAPResultSuccess
+				}
+			}
+			writeFakeResponse(conn, msgID, newFakeResult(ldap.ApplicationBindResponse, code))
+		case ldap.ApplicationSearchRequest:
+			sizeLimit := op.Children[3].Value.(int64)
+			filter, err := ldap.DecompileFilter(op.Children[6])
+			if err != nil {
+				writeFakeResponse(conn, msgID, newFakeResult(ldap.ApplicationSearchResultDone,
+					ldap.LDAPResultProtocolError))
+				continue
+			}
+
+			matched := make([]fakeEntry, 0)
+			for _, entry := range fakeDirectory {
+				if fakeMatch(filter, entry) {
+					matched = append(matched, entry)
+				}
+			}
+
+			code := uint16(ldap.LDAPResultSuccess)
+			if sizeLimit > 0 && int64(len(matched)) > sizeLimit {
+				matched = matched[:sizeLimit]
+				code = ldap.LDAPResultSizeLimitExceeded
+			}
+
+			for _, entry := range matched {
+				writeFakeResponse(conn, msgID, newFakeEntry(entry))
+			}
+			writeFakeResponse(conn, msgID, newFakeResult(ldap.ApplicationSearchResultDone, code))
+		case ldap.ApplicationUnbindRequest:
+			return
+		}
+	}
+}
+
+// fakeMatch matches the entry by the equality assertions in the filter case-insensitively like most ldap servers,
+// the entry matches if any of its attribute values is asserted, the object class assertion matches all entries.
+func fakeMatch(filter string, entry fakeEntry) bool {
+	filter = strings.ToLower(filter)
+	if filter == "(objectclass=person)" {
+		return true
+	}
+
+	for name, values := range entry.attributes {
+		for _, value := range values {
+			if strings.Contains(filter, strings.ToLower("("+name+"="+ldap.EscapeFilter(value)+")")) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func newFakeResult(tag ber.Tag, code uint16) *ber.Packet {
+	result := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "result")
+	result.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, uint64(code), "code"))
+	result.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "matched dn"))
+	result.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "message"))
+	return result
+}
+
+func newFakeEntry(entry fakeEntry) *ber.Packet {
+	result := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultEntry, nil, "entry")
+	result.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, entry.dn, "dn"))
+
+	attributes := ber.NewSequence("attributes")
+	for name, values := range entry.attributes {
+		attribute := ber.NewSequence("attribute")
+		attribute.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "name"))
+		set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "values")
+		for _, value := range values {
+			set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, "value"))
+		}
+		attribute.AppendChild(set)
+		attributes.AppendChild(attribute)
+	}
+	result.AppendChild(attributes)
+	return result
+}
+
+func writeFakeResponse(conn net.Conn, msgID interface{}, op *ber.Packet) {
+	msg := ber.NewSequence("message")
+	msg.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, msgID, "id"))
+	msg.AppendChild(op)
+	_, _ = conn.Write(msg.Bytes())
+}
+
+func newTestConfig(url string) *ldapConfig {
+	return &ldapConfig{
+		url:             url,
+		baseDN:          "ou=people,dc=example,dc=com",
+		userFilter:      "(|(uid=%s)(mail=%s))",
+		userListFilter:  "objectClass=person",
+		userAttr:        "uid",
+		displayNameAttr: defaultDisplayNameAttr,
+		emailAttr:       defaultEmailAttr,
+		phoneAttr:       defaultPhoneAttr,
+		groupAttr:       defaultGroupAttr,
+		roleMapping:     map[string]string{"admin": "admin", "cn=ops,ou=groups,dc=example,dc=com": "operator"},
+		timeout:         3 * time.Second,
+		pageSize:        defaultPageSize,
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	conf := newTestConfig(fakeServer(t))
+
+	testCases := []struct {
+		name     string
+		userName string
+		password string
+		user     *ldapUser
+	}{
+		{
+			name:     "login with the user attribute",
+			userName: "alice",
+			password: "alice-pwd",
+			user: &ldapUser{UserName: "alice", ChName: "Alice", Email: "alice@example.com",
+				Role: "admin,operator"},
+		},
+		{
+			// the login user must be the user attribute value in the directory instead of the input
+			name:     "login with the user attribute in another case",
+			userName: "ALICE",
+			password: "alice-pwd",
+			user: &ldapUser{UserName: "alice", ChName: "Alice", Email: "alice@example.com",
+				Role: "admin,operator"},
+		},
+		{
+			name:     "login with another attribute in the user filter",
+			userName: "alice@example.com",
+			password: "alice-pwd",
+			user: &ldapUser{UserName: "alice", ChName: "Alice", Email: "alice@example.com",
+				Role: "admin,operator"},
+		},
+		{
+			name:     "wrong password",
+			userName: "alice",
+			password: "bob-pwd",
+		},
+		{
+			name:     "empty password",
+			userName: "alice",
+			password: "",
+		},
+		{
+			name:     "user not exist",
+			userName: "carol",
+			password: "alice-pwd",
+		},
+		{
+			name:     "username matches more than one user",
+			userName: "bob@example.com",
+			password: "bob-pwd",
+		},
+		{
+			name:     "filter injection",
+			userName: "*",
+			password: "alice-pwd",
+		},
+	}
+
+	for _, tc := range testCases {
+		user, err := conf.authenticate(tc.userName, tc.password)
+		if err != nil {
+			t.Errorf("%s: authenticate failed, err: %v", tc.name, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(user, tc.user) {
+			t.Errorf("%s: user %+v is not as expected %+v", tc.name, user, tc.user)
+		}
+	}
+}
+
+func TestListUsers(t *testing.T) {
+	conf := newTestConfig(fakeServer(t))
+
+	entries, err := conf.listUsers(nil)
+	if err != nil {
+		t.Fatalf("list users failed, err: %v", err)
+	}
+	if len(entries) != len(fakeDirectory) {
+		t.Errorf("list users returns %d entries, expect %d", len(entries), len(fakeDirectory))
+	}
+
+	entries, err = conf.listUsers([]string{"bob", "carol"})
+	if err != nil {
+		t.Fatalf("list users by name failed, err: %v", err)
+	}
+	if len(entries) != 1 || entries[0].GetEqualFoldAttributeValue("uid") != "bob" {
+		t.Errorf("list users by name returns unexpected entries %+v", entries)
+	}
+}
+
+func TestNewLdapUser(t *testing.T) {
+	conf := newTestConfig("")
+
+	// the attribute names returned by the server may be in another case
+	entry := ldap.NewEntry("uid=alice,ou=people,dc=example,dc=com", map[string][]string{
+		"UID":      {"alice"},
+		"MemberOf": {"CN=Admin,OU=Groups,DC=example,DC=com"},
+	})
+	user := conf.newLdapUser(entry)
+	expected := &ldapUser{UserName: "alice", ChName: "alice", Role: "admin"}
+	if !reflect.DeepEqual(user, expected) {
+		t.Errorf("user %+v is not as expected %+v", user, expected)
+	}
+
+	entry = ldap.NewEntry("cn=alice,ou=people,dc=example,dc=com", map[string][]string{"cn": {"alice"}})
+	if user := conf.newLdapUser(entry); user != nil {
+		t.Errorf("entry without the user attribute should not be a user, but got %+v", user)
+	}
+}
+
+func TestGenUserListFilter(t *testing.T) {
+	conf := newTestConfig("")
+
+	testCases := []struct {
+		userNames []string
+		filter    string
+	}{
+		{
+			userNames: nil,
+			filter:    "(objectClass=person)",
+		},
+		{
+			userNames: []string{"alice", "bob"},
+			filter:    "(&(objectClass=person)(|(uid=alice)(uid=bob)))",
+		},
+		{
+			userNames: []string{"*)(uid=*", `a\b`},
+			filter:    `(&(objectClass=person)(|(uid=\2a\29\28uid=\2a)(uid=a\5cb)))`,
+		},
+	}
+
+	for _, tc := range testCases {
+		filter := conf.genUserListFilter(tc.userNames)
+		if filter != tc.filter {
+			t.Errorf("user list filter of %v is %s, expect %s", tc.userNames, filter, tc.filter)
+		}
+
+		if _, err := ldap.CompileFilter(filter); err != nil {
+			t.Errorf("user list filter %s is invalid, err: %v", filter, err)
+		}
+	}
+}
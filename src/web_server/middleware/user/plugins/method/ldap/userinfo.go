@@ -0,0 +1,196 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package ldap ldap login method, authenticate the user and get the user list from the ldap directory
+package ldap
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"configcenter/src/common"
+	cc "configcenter/src/common/backbone/configcenter"
+	"configcenter/src/common/blog"
+	"configcenter/src/common/errors"
+	"configcenter/src/common/metadata"
+	"configcenter/src/common/util"
+	webCommon "configcenter/src/web_server/common"
+	"configcenter/src/web_server/middleware/user/plugins/manager"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+// ldapUserSessionKey is the session key of the ldap user info that is saved after the user is authenticated
+const ldapUserSessionKey = "ldap_user"
+
+func init() {
+	plugin := &metadata.LoginPluginInfo{
+		Name:       "ldap system",
+		Version:    common.BKLDAPLoginPluginVersion,
+		HandleFunc: &user{},
+	}
+	manager.RegisterPlugin(plugin)
+}
+
+type user struct{}
+
+// AuthenticateUser authenticate the user by binding to the ldap server with the password, returns the user attribute
+// value of the user entry in ldap as the login user name
+func (m *user) AuthenticateUser(c *gin.Context, userName, password string) (string, bool, *errors.RawErrorInfo) {
+	rid := util.GetHTTPCCRequestID(c.Request.Header)
+
+	conf, err := getLdapConfig()
+	if err != nil {
+		blog.Errorf("get ldap config failed, err: %v, rid: %s", err, rid)
+		return "", false, &errors.RawErrorInfo{ErrCode: common.CCErrWebLDAPRequestFail, Args: []interface{}{err.Error()}}
+	}
+
+	ldapUser, err := conf.authenticate(userName, password)
+	if err != nil {
+		blog.Errorf("authenticate ldap user %s failed, err: %v, rid: %s", userName, err, rid)
+		return "", false, &errors.RawErrorInfo{ErrCode: common.CCErrWebLDAPRequestFail, Args: []interface{}{err.Error()}}
+	}
+
+	if ldapUser == nil {
+		blog.Warnf("ldap user %s username or password is wrong, rid: %s", userName, rid)
+		return "", false, nil
+	}
+
+	userInfo, err := json.Marshal(ldapUser)
+	if err != nil {
+		blog.Errorf("marshal ldap user %+v failed, err: %v, rid: %s", ldapUser, err, rid)
+		return "", false, &errors.RawErrorInfo{ErrCode: common.CCErrWebLDAPRequestFail, Args: []interface{}{err.Error()}}
+	}
+
+	session := sessions.Default(c)
+	session.Set(ldapUserSessionKey, string(userInfo))
+	if err := session.Save(); err != nil {
+		blog.Warnf("save session failed, err: %s, rid: %s", err.Error(), rid)
+	}
+	return ldapUser.UserName, true, nil
+}
+
+// LoginUser user login
+func (m *user) LoginUser(c *gin.Context, config map[string]string, isMultiOwner bool) (*metadata.LoginUserInfo, bool) {
+	rid := util.GetHTTPCCRequestID(c.Request.Header)
+	session := sessions.Default(c)
+
+	cookieOwnerID, err := c.Cookie(common.BKHTTPOwnerID)
+	if "" == cookieOwnerID || nil != err {
+		c.SetCookie(common.BKHTTPOwnerID, common.BKDefaultOwnerID, 0, "/", "", false, false)
+		session.Set(common.WEBSessionOwnerUinKey, cookieOwnerID)
+	} else if cookieOwnerID != session.Get(common.WEBSessionOwnerUinKey) {
+		session.Set(common.WEBSessionOwnerUinKey, cookieOwnerID)
+	}
+	if err := session.Save(); err != nil {
+		blog.Warnf("save session failed, err: %s, rid: %s", err.Error(), rid)
+	}
+
+	cookieUser, err := c.Cookie(common.BKUser)
+	if "" == cookieUser || nil != err {
+		blog.Errorf("login user not found, rid: %s", rid)
+		return nil, false
+	}
+
+	loginTime, ok := session.Get(cookieUser).(int64)
+	if !ok {
+		blog.Errorf("login time not int64, rid: %s", rid)
+		return nil, false
+	}
+	if time.Now().Unix()-loginTime >= 24*60*60 {
+		return nil, false
+	}
+
+	// the user info must be the one that is authenticated by ldap, the cookie can be modified by the client
+	ldapUser := new(ldapUser)
+	userInfo, _ := session.Get(ldapUserSessionKey).(string)
+	if err := json.Unmarshal([]byte(userInfo), ldapUser); err != nil || ldapUser.UserName != cookieUser {
+		blog.Errorf("ldap user %s is not authenticated, rid: %s", cookieUser, rid)
+		return nil, false
+	}
+
+	return &metadata.LoginUserInfo{
+		UserName: ldapUser.UserName,
+		ChName:   ldapUser.ChName,
+		Phone:    ldapUser.Phone,
+		Email:    ldapUser.Email,
+		Role:     ldapUser.Role,
+		BkToken:  "",
+		OnwerUin: "0",
+		IsOwner:  false,
+		Language: webCommon.GetLanguageByHTTPRequest(c),
+	}, true
+}
+
+// GetLoginUrl get login url
+func (m *user) GetLoginUrl(c *gin.Context, config map[string]string, input *metadata.LogoutRequestParams) string {
+	var siteURL string
+	var err error
+	if common.LogoutHTTPSchemeHTTPS == input.HTTPScheme {
+		siteURL, err = cc.String("webServer.site.httpsDomainUrl")
+	} else {
+		siteURL, err = cc.String("webServer.site.domainUrl")
+	}
+	if err != nil {
+		siteURL = ""
+	}
+	siteURL = strings.TrimRight(siteURL, "/")
+	return fmt.Sprintf("%s/login?c_url=%s%s", siteURL, siteURL, c.Request.URL.String())
+}
+
+// GetUserList get user list from ldap, if config has exact_lookups, only the users in it are returned
+func (m *user) GetUserList(c *gin.Context, config map[string]string) ([]*metadata.LoginSystemUserInfo,
+	*errors.RawErrorInfo) {
+
+	rid := util.GetHTTPCCRequestID(c.Request.Header)
+
+	conf, err := getLdapConfig()
+	if err != nil {
+		blog.Errorf("get ldap config failed, err: %v, rid: %s", err, rid)
+		return nil, &errors.RawErrorInfo{ErrCode: common.CCErrWebLDAPRequestFail, Args: []interface{}{err.Error()}}
+	}
+
+	userNames := make([]string, 0)
+	for _, userName := range strings.Split(config["exact_lookups"], ",") {
+		if userName = strings.TrimSpace(userName); userName != "" {
+			userNames = append(userNames, userName)
+		}
+	}
+
+	entries, err := conf.listUsers(userNames)
+	if err != nil {
+		blog.Errorf("list ldap users failed, err: %v, rid: %s", err, rid)
+		return nil, &errors.RawErrorInfo{ErrCode: common.CCErrWebLDAPRequestFail, Args: []interface{}{err.Error()}}
+	}
+
+	users := make([]*metadata.LoginSystemUserInfo, 0)
+	for _, entry := range entries {
+		userName := entry.GetEqualFoldAttributeValue(conf.userAttr)
+		if userName == "" {
+			continue
+		}
+
+		displayName := entry.GetEqualFoldAttributeValue(conf.displayNameAttr)
+		if displayName == "" {
+			displayName = userName
+		}
+		users = append(users, &metadata.LoginSystemUserInfo{
+			CnName: displayName,
+			EnName: userName,
+		})
+	}
+
+	return users, nil
+}
@@ -0,0 +1,263 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package oidc
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	cc "configcenter/src/common/backbone/configcenter"
+)
+
+const (
+	defaultUsernameClaim    = "preferred_username"
+	defaultDisplayNameClaim = "name"
+	defaultEmailClaim       = "email"
+	defaultLanguageClaim    = "locale"
+	defaultTimeoutSeconds   = 10
+	// discoveryTTL is the time that the provider metadata is cached
+	discoveryTTL = time.Hour
+	// maxResponseSize is the max size of the response of the identity provider that is read
+	maxResponseSize = 1 << 20
+)
+
+var defaultScopes = []string{"openid", "profile", "email", "offline_access"}
+
+// oidcConfig is the config of the oidc login plugin, it is read from webServer.oidc every time it is used,
+// so that the changes of the config take effect without restarting web server.
+type oidcConfig struct {
+	issuer       string
+	clientID     string
+	clientSecret string
+	// redirectURL is the callback url that is registered in the identity provider, it should be
+	// {webServer.site.domainUrl}/login/callback
+	redirectURL string
+	scopes      []string
+	// claims that are mapped to the login user info
+	usernameClaim    string
+	displayNameClaim string
+	emailClaim       string
+	languageClaim    string
+	// ownerClaim is the claim of the supplier account, the default supplier account is used if it is not set
+	ownerClaim         string
+	insecureSkipVerify bool
+	timeout            time.Duration
+}
+
+func getOidcConfig() (*oidcConfig, error) {
+	conf := &oidcConfig{
+		clientSecret:     getString("webServer.oidc.clientSecret", ""),
+		usernameClaim:    getString("webServer.oidc.usernameClaim", defaultUsernameClaim),
+		displayNameClaim: getString("webServer.oidc.displayNameClaim", defaultDisplayNameClaim),
+		emailClaim:       getString("webServer.oidc.emailClaim", defaultEmailClaim),
+		languageClaim:    getString("webServer.oidc.languageClaim", defaultLanguageClaim),
+		ownerClaim:       getString("webServer.oidc.ownerClaim", ""),
+		scopes:           defaultScopes,
+		timeout:          defaultTimeoutSeconds * time.Second,
+	}
+
+	var err error
+	if conf.issuer, err = cc.String("webServer.oidc.issuer"); err != nil || conf.issuer == "" {
+		return nil, errors.New("webServer.oidc.issuer is not set")
+	}
+	conf.issuer = strings.TrimRight(conf.issuer, "/")
+
+	if conf.clientID, err = cc.String("webServer.oidc.clientID"); err != nil || conf.clientID == "" {
+		return nil, errors.New("webServer.oidc.clientID is not set")
+	}
+
+	if conf.redirectURL, err = cc.String("webServer.oidc.redirectUrl"); err != nil || conf.redirectURL == "" {
+		return nil, errors.New("webServer.oidc.redirectUrl is not set")
+	}
+
+	if cc.IsExist("webServer.oidc.scopes") {
+		scopes, err := cc.StringSlice("webServer.oidc.scopes")
+		if err != nil {
+			return nil, fmt.Errorf("webServer.oidc.scopes is invalid, err: %v", err)
+		}
+		if len(scopes) > 0 {
+			conf.scopes = scopes
+		}
+	}
+
+	hasOpenID := false
+	for _, scope := range conf.scopes {
+		if scope == "openid" {
+			hasOpenID = true
+			break
+		}
+	}
+	if !hasOpenID {
+		conf.scopes = append([]string{"openid"}, conf.scopes...)
+	}
+
+	if cc.IsExist("webServer.oidc.insecureSkipVerify") {
+		if conf.insecureSkipVerify, err = cc.Bool("webServer.oidc.insecureSkipVerify"); err != nil {
+			return nil, fmt.Errorf("webServer.oidc.insecureSkipVerify is invalid, err: %v", err)
+		}
+	}
+
+	if cc.IsExist("webServer.oidc.timeoutSeconds") {
+		timeout, err := cc.Int("webServer.oidc.timeoutSeconds")
+		if err != nil || timeout <= 0 {
+			return nil, errors.New("webServer.oidc.timeoutSeconds is invalid")
+		}
+		conf.timeout = time.Duration(timeout) * time.Second
+	}
+
+	return conf, nil
+}
+
+func getString(key, defaultValue string) string {
+	value, err := cc.String(key)
+	if err != nil || value == "" {
+		return defaultValue
+	}
+	return value
+}
+
+func (o *oidcConfig) httpClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if o.insecureSkipVerify {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	return &http.Client{Transport: transport, Timeout: o.timeout}
+}
+
+// providerMetadata is the openid provider metadata, see openid connect discovery 1.0 section 3
+type providerMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JwksURI               string `json:"jwks_uri"`
+}
+
+type cachedMetadata struct {
+	metadata  *providerMetadata
+	expiredAt time.Time
+}
+
+var (
+	metadataLock  sync.Mutex
+	metadataCache = make(map[string]*cachedMetadata)
+)
+
+// discover get the provider metadata from {issuer}/.well-known/openid-configuration, it is cached for an hour
+func (o *oidcConfig) discover() (*providerMetadata, error) {
+	metadataLock.Lock()
+	defer metadataLock.Unlock()
+
+	if cached, exists := metadataCache[o.issuer]; exists && time.Now().Before(cached.expiredAt) {
+		return cached.metadata, nil
+	}
+
+	meta := new(providerMetadata)
+	if err := o.getJSON(o.issuer+"/.well-known/openid-configuration", meta); err != nil {
+		return nil, fmt.Errorf("discover oidc provider %s failed, err: %v", o.issuer, err)
+	}
+
+	if strings.TrimRight(meta.Issuer, "/") != o.issuer {
+		return nil, fmt.Errorf("oidc provider issuer %s does not match the config %s", meta.Issuer, o.issuer)
+	}
+
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JwksURI == "" {
+		return nil, fmt.Errorf("oidc provider %s metadata is incomplete", o.issuer)
+	}
+
+	metadataCache[o.issuer] = &cachedMetadata{metadata: meta, expiredAt: time.Now().Add(discoveryTTL)}
+	return meta, nil
+}
+
+func (o *oidcConfig) getJSON(url string, result interface{}) error {
+	resp, err := o.httpClient().Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return decodeResponse(resp, result)
+}
+
+// tokenResponse is the successful response of the token endpoint, see rfc6749 5.1
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+	IDToken      string `json:"id_token"`
+}
+
+// tokenErrorResponse is the error response of the token endpoint, see rfc6749 5.2
+type tokenErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// requestToken request the token endpoint with the grant parameters, client is authenticated by client_secret_basic
+func (o *oidcConfig) requestToken(tokenEndpoint string, params url.Values) (*tokenResponse, error) {
+	req, err := http.NewRequest(http.MethodPost, tokenEndpoint, strings.NewReader(params.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if o.clientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(o.clientID), url.QueryEscape(o.clientSecret))
+	}
+
+	resp, err := o.httpClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	token := new(tokenResponse)
+	if err := decodeResponse(resp, token); err != nil {
+		return nil, err
+	}
+
+	if token.AccessToken == "" {
+		return nil, errors.New("token response has no access_token")
+	}
+	return token, nil
+}
+
+func decodeResponse(resp *http.Response, result interface{}) error {
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		errResp := new(tokenErrorResponse)
+		if json.Unmarshal(body, errResp) == nil && errResp.Error != "" {
+			return fmt.Errorf("http status %d, error: %s, description: %s", resp.StatusCode, errResp.Error,
+				errResp.ErrorDescription)
+		}
+		return fmt.Errorf("http status %d", resp.StatusCode)
+	}
+
+	if err := json.Unmarshal(body, result); err != nil {
+		return fmt.Errorf("decode response failed, err: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package oidc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+)
+
+// supportedSigningAlgs is the asymmetric algorithms that the id token can be signed with, none and the symmetric
+// algorithms are not accepted
+var supportedSigningAlgs = []string{
+	oidc.RS256, oidc.RS384, oidc.RS512,
+	oidc.ES256, oidc.ES384, oidc.ES512,
+	oidc.PS256, oidc.PS384, oidc.PS512,
+}
+
+var (
+	keySetLock  sync.Mutex
+	keySetCache = make(map[string]*oidc.RemoteKeySet)
+)
+
+// getKeySet get the json web key set of the jwks uri, the key set is cached and it refreshes the keys itself
+// when a token is signed by an unknown key, so that the rotated keys can be used.
+func (o *oidcConfig) getKeySet(jwksURI string) oidc.KeySet {
+	keySetLock.Lock()
+	defer keySetLock.Unlock()
+
+	keySet, exists := keySetCache[jwksURI]
+	if !exists {
+		keySet = oidc.NewRemoteKeySet(oidc.ClientContext(context.Background(), o.httpClient()), jwksURI)
+		keySetCache[jwksURI] = keySet
+	}
+	return keySet
+}
+
+// verifyIDToken verify the signature and claims of the id token, returns the claims and the expire time
+func (o *oidcConfig) verifyIDToken(meta *providerMetadata, idToken, nonce string) (map[string]interface{},
+	time.Time, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), o.timeout)
+	defer cancel()
+
+	return verifyIDToken(ctx, o.getKeySet(meta.JwksURI), idToken, meta.Issuer, o.clientID, nonce, time.Now)
+}
+
+// verifyIDToken verify the signature of the id token with the key set and validate its claims, see openid connect
+// core 1.0 section 3.1.3.7. nonce is not checked if it is empty, the id token that is refreshed may not contain it.
+func verifyIDToken(ctx context.Context, keySet oidc.KeySet, idToken, issuer, clientID, nonce string,
+	now func() time.Time) (map[string]interface{}, time.Time, error) {
+
+	verifier := oidc.NewVerifier(issuer, keySet, &oidc.Config{
+		ClientID:             clientID,
+		SupportedSigningAlgs: supportedSigningAlgs,
+		Now:                  now,
+	})
+
+	token, err := verifier.Verify(ctx, idToken)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	claims := make(map[string]interface{})
+	if err := token.Claims(&claims); err != nil {
+		return nil, time.Time{}, fmt.Errorf("invalid id token claims, err: %v", err)
+	}
+
+	if azp, exists := claims["azp"].(string); exists && azp != clientID {
+		return nil, time.Time{}, fmt.Errorf("id token authorized party %s is invalid", azp)
+	}
+
+	if nonce != "" && token.Nonce != nonce {
+		return nil, time.Time{}, errors.New("id token nonce is invalid")
+	}
+
+	return claims, token.Expiry, nil
+}
@@ -0,0 +1,151 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+)
+
+func signToken(t *testing.T, alg string, key crypto.Signer, claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": "test"})
+	payload, _ := json.Marshal(claims)
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+
+	var signature []byte
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		var err error
+		if signature, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:]); err != nil {
+			t.Fatalf("sign token failed, err: %v", err)
+		}
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		if err != nil {
+			t.Fatalf("sign token failed, err: %v", err)
+		}
+		signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate rsa key failed, err: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate ec key failed, err: %v", err)
+	}
+	keySet := &oidc.StaticKeySet{PublicKeys: []crypto.PublicKey{&rsaKey.PublicKey, &ecKey.PublicKey}}
+
+	now := time.Now()
+	nowFunc := func() time.Time { return now }
+	const issuer, clientID = "https://idp.example.com", "cmdb"
+	valid := func() map[string]interface{} {
+		return map[string]interface{}{
+			"iss":   issuer,
+			"sub":   "admin",
+			"aud":   []interface{}{clientID, "other"},
+			"azp":   clientID,
+			"exp":   now.Add(time.Hour).Unix(),
+			"iat":   now.Unix(),
+			"nonce": "nonce",
+		}
+	}
+
+	for alg, signer := range map[string]crypto.Signer{"RS256": rsaKey, "ES256": ecKey} {
+		token := signToken(t, alg, signer, valid())
+		claims, expireTime, err := verifyIDToken(context.Background(), keySet, token, issuer, clientID, "nonce",
+			nowFunc)
+		if err != nil {
+			t.Fatalf("verify %s id token failed, err: %v", alg, err)
+		}
+		if claims["sub"] != "admin" {
+			t.Errorf("unexpected %s id token claims: %v", alg, claims)
+		}
+		if expireTime.Unix() != now.Add(time.Hour).Unix() {
+			t.Errorf("unexpected %s id token expire time %v", alg, expireTime)
+		}
+
+		// tamper the payload
+		parts := strings.Split(token, ".")
+		payload := valid()
+		payload["sub"] = "root"
+		data, _ := json.Marshal(payload)
+		parts[1] = base64.RawURLEncoding.EncodeToString(data)
+		_, _, err = verifyIDToken(context.Background(), keySet, strings.Join(parts, "."), issuer, clientID, "nonce",
+			nowFunc)
+		if err == nil {
+			t.Errorf("verify tampered %s id token should fail", alg)
+		}
+	}
+
+	// unsigned token must be rejected
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`))
+	data, _ := json.Marshal(valid())
+	unsigned := header + "." + base64.RawURLEncoding.EncodeToString(data) + "."
+	if _, _, err := verifyIDToken(context.Background(), keySet, unsigned, issuer, clientID, "nonce",
+		nowFunc); err == nil {
+		t.Error("verify unsigned id token should fail")
+	}
+
+	// token signed by an unknown key must be rejected
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate ec key failed, err: %v", err)
+	}
+	if _, _, err := verifyIDToken(context.Background(), keySet, signToken(t, "ES256", otherKey, valid()), issuer,
+		clientID, "nonce", nowFunc); err == nil {
+		t.Error("verify id token signed by unknown key should fail")
+	}
+
+	cases := map[string]func(claims map[string]interface{}){
+		"issuer":   func(claims map[string]interface{}) { claims["iss"] = "https://evil.example.com" },
+		"audience": func(claims map[string]interface{}) { claims["aud"] = "other" },
+		"azp":      func(claims map[string]interface{}) { claims["azp"] = "other" },
+		"expired":  func(claims map[string]interface{}) { claims["exp"] = now.Add(-time.Hour).Unix() },
+		"nonce":    func(claims map[string]interface{}) { claims["nonce"] = "replayed" },
+	}
+	for name, modify := range cases {
+		claims := valid()
+		modify(claims)
+		token := signToken(t, "RS256", rsaKey, claims)
+		if _, _, err := verifyIDToken(context.Background(), keySet, token, issuer, clientID, "nonce",
+			nowFunc); err == nil {
+			t.Errorf("verify id token with invalid %s should fail", name)
+		}
+	}
+
+	// refreshed id token is not required to contain the nonce
+	claims := valid()
+	delete(claims, "nonce")
+	token := signToken(t, "RS256", rsaKey, claims)
+	if _, _, err := verifyIDToken(context.Background(), keySet, token, issuer, clientID, "", nowFunc); err != nil {
+		t.Errorf("verify refreshed id token failed, err: %v", err)
+	}
+}
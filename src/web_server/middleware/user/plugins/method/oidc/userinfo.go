@@ -0,0 +1,436 @@
+/*
+ * Tencent is pleased to support the open source community by making 蓝鲸 available.
+ * Copyright (C) 2017-2018 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ * http://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under
+ * the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+ * either express or implied. See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package oidc openid connect login method, authenticate the user with the authorization code flow with pkce
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"configcenter/src/common"
+	cc "configcenter/src/common/backbone/configcenter"
+	"configcenter/src/common/blog"
+	ccErr "configcenter/src/common/errors"
+	"configcenter/src/common/metadata"
+	"configcenter/src/common/util"
+	webCommon "configcenter/src/web_server/common"
+	"configcenter/src/web_server/middleware/user/plugins/manager"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// authSessionKey is the session key of the authorization request state that is used to validate the callback
+	authSessionKey = "oidc_auth"
+	// userSessionKey is the session key of the authenticated user info and the refresh token
+	userSessionKey = "oidc_user"
+	// refreshAhead is the time before the id token expires that the session is refreshed
+	refreshAhead = 30 * time.Second
+)
+
+func init() {
+	plugin := &metadata.LoginPluginInfo{
+		Name:       "openid connect system",
+		Version:    common.BKOIDCLoginPluginVersion,
+		HandleFunc: &user{},
+	}
+	manager.RegisterPlugin(plugin)
+}
+
+type user struct{}
+
+// authState is the state of the authorization request that is saved in session before redirecting to the provider
+type authState struct {
+	State        string `json:"state"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+	// RedirectURL is the url that the user visits before login
+	RedirectURL string `json:"redirect_url"`
+}
+
+// oidcUser is the authenticated user that is saved in session
+type oidcUser struct {
+	UserName     string `json:"username"`
+	ChName       string `json:"ch_name"`
+	Email        string `json:"email"`
+	Language     string `json:"language"`
+	Owner        string `json:"owner"`
+	RefreshToken string `json:"refresh_token"`
+	// ExpireTime is the unix time that the session expires, it is extended by the refresh token
+	ExpireTime int64 `json:"expire_time"`
+}
+
+// GetLoginUrl generate the authorization request and returns the authorization endpoint url of the provider
+func (m *user) GetLoginUrl(c *gin.Context, config map[string]string, input *metadata.LogoutRequestParams) string {
+	rid := util.GetHTTPCCRequestID(c.Request.Header)
+
+	conf, err := getOidcConfig()
+	if err != nil {
+		blog.Errorf("get oidc config failed, err: %v, rid: %s", err, rid)
+		return ""
+	}
+
+	meta, err := conf.discover()
+	if err != nil {
+		blog.Errorf("discover oidc provider failed, err: %v, rid: %s", err, rid)
+		return ""
+	}
+
+	var siteURL string
+	if common.LogoutHTTPSchemeHTTPS == input.HTTPScheme {
+		siteURL, err = cc.String("webServer.site.httpsDomainUrl")
+	} else {
+		siteURL, err = cc.String("webServer.site.domainUrl")
+	}
+	if err != nil {
+		siteURL = ""
+	}
+
+	state := &authState{RedirectURL: strings.TrimRight(siteURL, "/") + c.Request.URL.String()}
+	for _, value := range []*string{&state.State, &state.Nonce, &state.CodeVerifier} {
+		if *value, err = randomString(); err != nil {
+			blog.Errorf("generate oidc auth state failed, err: %v, rid: %s", err, rid)
+			return ""
+		}
+	}
+	stateJson, err := json.Marshal(state)
+	if err != nil {
+		blog.Errorf("marshal oidc auth state failed, err: %v, rid: %s", err, rid)
+		return ""
+	}
+
+	session := sessions.Default(c)
+	session.Set(authSessionKey, string(stateJson))
+	if err := session.Save(); err != nil {
+		blog.Errorf("save session failed, err: %v, rid: %s", err, rid)
+		return ""
+	}
+
+	challenge := sha256.Sum256([]byte(state.CodeVerifier))
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", conf.clientID)
+	params.Set("redirect_uri", conf.redirectURL)
+	params.Set("scope", strings.Join(conf.scopes, " "))
+	params.Set("state", state.State)
+	params.Set("nonce", state.Nonce)
+	params.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	params.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(meta.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return meta.AuthorizationEndpoint + separator + params.Encode()
+}
+
+// LoginCallback exchange the authorization code for tokens, and save the user of the verified id token in session
+func (m *user) LoginCallback(c *gin.Context) (string, *ccErr.RawErrorInfo) {
+	rid := util.GetHTTPCCRequestID(c.Request.Header)
+	session := sessions.Default(c)
+
+	stateJson, _ := session.Get(authSessionKey).(string)
+	state := new(authState)
+	if err := json.Unmarshal([]byte(stateJson), state); err != nil {
+		blog.Errorf("oidc auth state is not found in session, rid: %s", rid)
+		return "", callbackError(errors.New("authorization request is not found"))
+	}
+	// the authorization request can only be used once
+	session.Delete(authSessionKey)
+
+	if errCode := c.Query("error"); errCode != "" {
+		blog.Errorf("oidc authorization failed, error: %s, description: %s, rid: %s", errCode,
+			c.Query("error_description"), rid)
+		return "", callbackError(fmt.Errorf("authorization failed, error: %s", errCode))
+	}
+
+	if subtle.ConstantTimeCompare([]byte(c.Query("state")), []byte(state.State)) != 1 {
+		blog.Errorf("oidc callback state does not match, rid: %s", rid)
+		return "", callbackError(errors.New("state is invalid"))
+	}
+
+	code := c.Query("code")
+	if code == "" {
+		return "", callbackError(errors.New("authorization code is not set"))
+	}
+
+	conf, err := getOidcConfig()
+	if err != nil {
+		blog.Errorf("get oidc config failed, err: %v, rid: %s", err, rid)
+		return "", callbackError(err)
+	}
+
+	meta, err := conf.discover()
+	if err != nil {
+		blog.Errorf("discover oidc provider failed, err: %v, rid: %s", err, rid)
+		return "", callbackError(err)
+	}
+
+	params := url.Values{}
+	params.Set("grant_type", "authorization_code")
+	params.Set("code", code)
+	params.Set("redirect_uri", conf.redirectURL)
+	params.Set("client_id", conf.clientID)
+	params.Set("code_verifier", state.CodeVerifier)
+	token, err := conf.requestToken(meta.TokenEndpoint, params)
+	if err != nil {
+		blog.Errorf("exchange oidc authorization code failed, err: %v, rid: %s", err, rid)
+		return "", callbackError(err)
+	}
+
+	if token.IDToken == "" {
+		blog.Errorf("oidc token response has no id token, rid: %s", rid)
+		return "", callbackError(errors.New("token response has no id_token"))
+	}
+
+	claims, expireTime, err := conf.verifyIDToken(meta, token.IDToken, state.Nonce)
+	if err != nil {
+		blog.Errorf("verify oidc id token failed, err: %v, rid: %s", err, rid)
+		return "", callbackError(err)
+	}
+
+	user, err := conf.newUser(claims)
+	if err != nil {
+		blog.Errorf("get user from oidc id token claims failed, err: %v, rid: %s", err, rid)
+		return "", callbackError(err)
+	}
+	user.RefreshToken = token.RefreshToken
+	user.ExpireTime = expireTime.Unix()
+
+	if err := saveUser(session, user); err != nil {
+		blog.Errorf("save oidc user %s failed, err: %v, rid: %s", user.UserName, err, rid)
+		return "", callbackError(err)
+	}
+
+	c.SetCookie(common.BKUser, user.UserName, 0, "/", "", false, false)
+	return state.RedirectURL, nil
+}
+
+// LoginUser user login, the session is refreshed with the refresh token when the id token expires
+func (m *user) LoginUser(c *gin.Context, config map[string]string, isMultiOwner bool) (*metadata.LoginUserInfo, bool) {
+	rid := util.GetHTTPCCRequestID(c.Request.Header)
+	session := sessions.Default(c)
+
+	userJson, _ := session.Get(userSessionKey).(string)
+	if userJson == "" {
+		blog.Errorf("oidc login user not found, rid: %s", rid)
+		return nil, false
+	}
+
+	user := new(oidcUser)
+	if err := json.Unmarshal([]byte(userJson), user); err != nil {
+		blog.Errorf("unmarshal oidc user %s failed, err: %v, rid: %s", userJson, err, rid)
+		return nil, false
+	}
+
+	if time.Now().Add(refreshAhead).Unix() >= user.ExpireTime {
+		if err := refreshUser(user); err != nil {
+			blog.Errorf("refresh oidc user %s session failed, err: %v, rid: %s", user.UserName, err, rid)
+			session.Delete(userSessionKey)
+			if err := session.Save(); err != nil {
+				blog.Warnf("save session failed, err: %v, rid: %s", err, rid)
+			}
+			return nil, false
+		}
+
+		if err := saveUser(session, user); err != nil {
+			blog.Warnf("save refreshed oidc user %s failed, err: %v, rid: %s", user.UserName, err, rid)
+		}
+	}
+
+	owner := user.Owner
+	if owner == "" {
+		owner = common.BKDefaultOwnerID
+	}
+	if cookieOwnerID, err := c.Cookie(common.BKHTTPOwnerID); err != nil || cookieOwnerID != owner {
+		c.SetCookie(common.BKHTTPOwnerID, owner, 0, "/", "", false, false)
+	}
+
+	language := user.Language
+	if language == "" {
+		language = webCommon.GetLanguageByHTTPRequest(c)
+	}
+
+	return &metadata.LoginUserInfo{
+		UserName: user.UserName,
+		ChName:   user.ChName,
+		Phone:    "",
+		Email:    user.Email,
+		Role:     "",
+		BkToken:  "",
+		OnwerUin: owner,
+		IsOwner:  false,
+		Language: language,
+	}, true
+}
+
+// GetUserList get user list. the identity provider has no user list api, so returns the login user, or the users
+// to look up exactly if config has exact_lookups, with their usernames as the display names.
+func (m *user) GetUserList(c *gin.Context, config map[string]string) ([]*metadata.LoginSystemUserInfo,
+	*ccErr.RawErrorInfo) {
+
+	users := make([]*metadata.LoginSystemUserInfo, 0)
+	if lookups := config["exact_lookups"]; lookups != "" {
+		for _, userName := range strings.Split(lookups, ",") {
+			if userName = strings.TrimSpace(userName); userName != "" {
+				users = append(users, &metadata.LoginSystemUserInfo{CnName: userName, EnName: userName})
+			}
+		}
+		return users, nil
+	}
+
+	user := new(oidcUser)
+	userJson, _ := sessions.Default(c).Get(userSessionKey).(string)
+	if err := json.Unmarshal([]byte(userJson), user); err == nil && user.UserName != "" {
+		users = append(users, &metadata.LoginSystemUserInfo{CnName: user.ChName, EnName: user.UserName})
+	}
+	return users, nil
+}
+
+// refreshUser refresh the tokens with the refresh token, and update the user info if a new id token is returned
+func refreshUser(user *oidcUser) error {
+	if user.RefreshToken == "" {
+		return errors.New("session is expired and there is no refresh token")
+	}
+
+	conf, err := getOidcConfig()
+	if err != nil {
+		return err
+	}
+
+	meta, err := conf.discover()
+	if err != nil {
+		return err
+	}
+
+	params := url.Values{}
+	params.Set("grant_type", "refresh_token")
+	params.Set("refresh_token", user.RefreshToken)
+	params.Set("client_id", conf.clientID)
+	token, err := conf.requestToken(meta.TokenEndpoint, params)
+	if err != nil {
+		return err
+	}
+
+	// the refresh token may be rotated by the provider
+	if token.RefreshToken != "" {
+		user.RefreshToken = token.RefreshToken
+	}
+
+	if token.IDToken == "" {
+		if token.ExpiresIn <= 0 {
+			return errors.New("refresh token response has neither id_token nor expires_in")
+		}
+		user.ExpireTime = time.Now().Unix() + token.ExpiresIn
+		return nil
+	}
+
+	claims, expireTime, err := conf.verifyIDToken(meta, token.IDToken, "")
+	if err != nil {
+		return err
+	}
+
+	refreshed, err := conf.newUser(claims)
+	if err != nil {
+		return err
+	}
+
+	// the refreshed id token must belong to the same user, see openid connect core 1.0 section 12.2
+	if refreshed.UserName != user.UserName {
+		return fmt.Errorf("refreshed id token user %s does not match", refreshed.UserName)
+	}
+
+	refreshed.RefreshToken = user.RefreshToken
+	refreshed.ExpireTime = expireTime.Unix()
+	*user = *refreshed
+	return nil
+}
+
+// newUser map the id token claims to the user
+func (o *oidcConfig) newUser(claims map[string]interface{}) (*oidcUser, error) {
+	user := &oidcUser{
+		UserName: claimString(claims, o.usernameClaim),
+		ChName:   claimString(claims, o.displayNameClaim),
+		Email:    claimString(claims, o.emailClaim),
+		Language: convertLanguage(claimString(claims, o.languageClaim)),
+	}
+
+	if user.UserName == "" {
+		return nil, fmt.Errorf("id token has no username claim %s", o.usernameClaim)
+	}
+
+	if user.ChName == "" {
+		user.ChName = user.UserName
+	}
+
+	if o.ownerClaim != "" {
+		user.Owner = claimString(claims, o.ownerClaim)
+	}
+	return user, nil
+}
+
+func claimString(claims map[string]interface{}, name string) string {
+	switch value := claims[name].(type) {
+	case string:
+		return value
+	case float64:
+		return fmt.Sprintf("%v", value)
+	default:
+		return ""
+	}
+}
+
+// convertLanguage convert the locale claim like zh-CN or en-US to the language of cmdb
+func convertLanguage(locale string) string {
+	locale = strings.ToLower(locale)
+	switch {
+	case strings.HasPrefix(locale, "zh"):
+		return string(common.Chinese)
+	case strings.HasPrefix(locale, "en"):
+		return string(common.English)
+	default:
+		return ""
+	}
+}
+
+func saveUser(session sessions.Session, user *oidcUser) error {
+	userJson, err := json.Marshal(user)
+	if err != nil {
+		return err
+	}
+
+	session.Set(userSessionKey, string(userJson))
+	return session.Save()
+}
+
+func callbackError(err error) *ccErr.RawErrorInfo {
+	return &ccErr.RawErrorInfo{ErrCode: common.CCErrWebOIDCRequestFail, Args: []interface{}{err.Error()}}
+}
+
+// randomString generate a url safe random string with 256 bits entropy, used as state, nonce and code verifier
+func randomString() (string, error) {
+	data := make([]byte, 32)
+	if _, err := rand.Read(data); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
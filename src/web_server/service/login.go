@@ -13,6 +13,7 @@
 package service
 
 import (
+	"net/http"
 	"strings"
 	"time"
 
@@ -22,6 +23,7 @@ import (
 	"configcenter/src/common/metadata"
 	"configcenter/src/common/util"
 	"configcenter/src/web_server/middleware/user"
+	"configcenter/src/web_server/middleware/user/plugins"
 
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
@@ -56,7 +58,31 @@ func (s *Service) LoginUser(c *gin.Context) {
 		c.HTML(200, "login.html", gin.H{
 			"error": defErr.CCError(common.CCErrWebNeedFillinUsernamePasswd).Error(),
 		})
+		return
+	}
+
+	// the login plugin that has its own user system verifies the username and password itself
+	plugin := plugins.CurrentPlugin(c, s.Config.LoginVersion)
+	if authenticator, ok := plugin.(metadata.LoginUserAuthenticator); ok {
+		loginName, success, rawErr := authenticator.AuthenticateUser(c, userName, password)
+		if rawErr != nil {
+			c.HTML(200, "login.html", gin.H{
+				"error": rawErr.ToCCError(defErr).Error(),
+			})
+			return
+		}
+
+		if success {
+			s.loginSuccess(c, loginName)
+			return
+		}
+
+		c.HTML(200, "login.html", gin.H{
+			"error": defErr.CCError(common.CCErrWebUsernamePasswdWrong).Error(),
+		})
+		return
 	}
+
 	userInfo, err := cc.String("webServer.session.userInfo")
 	if err != nil {
 		c.HTML(200, "login.html", gin.H{
@@ -75,21 +101,7 @@ func (s *Service) LoginUser(c *gin.Context) {
 			return
 		}
 		if userWithPassword[0] == userName && userWithPassword[1] == password {
-			c.SetCookie(common.BKUser, userName, 24*60*60, "/", "", false, false)
-			session := sessions.Default(c)
-			session.Set(userName, time.Now().Unix())
-			if err := session.Save(); err != nil {
-				blog.Warnf("save session failed, err: %s, rid: %s", err.Error(), rid)
-			}
-			userManger := user.NewUser(*s.Config, s.Engine, s.CacheCli)
-			userManger.LoginUser(c)
-			var redirectURL string
-			if c.Query("c_url") != "" {
-				redirectURL = c.Query("c_url")
-			} else {
-				redirectURL = s.Config.Site.DomainUrl
-			}
-			c.Redirect(302, redirectURL)
+			s.loginSuccess(c, userName)
 			return
 		}
 	}
@@ -98,3 +110,62 @@ func (s *Service) LoginUser(c *gin.Context) {
 	})
 	return
 }
+
+// LoginCallback handle the callback of the login plugin that authenticates the user with an external identity provider
+func (s *Service) LoginCallback(c *gin.Context) {
+	rid := util.GetHTTPCCRequestID(c.Request.Header)
+	defErr := s.CCErr.CreateDefaultCCErrorIf(util.GetLanguage(c.Request.Header))
+
+	plugin := plugins.CurrentPlugin(c, s.Config.LoginVersion)
+	handler, ok := plugin.(metadata.LoginCallbackHandler)
+	if !ok {
+		blog.Errorf("login version %s does not support login callback, rid: %s", s.Config.LoginVersion, rid)
+		c.JSON(http.StatusNotFound, metadata.BaseResp{
+			Code:   common.CCErrWebUnknownLoginVersion,
+			ErrMsg: defErr.CCErrorf(common.CCErrWebUnknownLoginVersion, s.Config.LoginVersion).Error(),
+		})
+		return
+	}
+
+	redirectURL, rawErr := handler.LoginCallback(c)
+	if rawErr != nil {
+		ccErr := rawErr.ToCCError(defErr)
+		c.JSON(http.StatusUnauthorized, metadata.BaseResp{Code: ccErr.GetCode(), ErrMsg: ccErr.Error()})
+		return
+	}
+
+	userManger := user.NewUser(*s.Config, s.Engine, s.CacheCli)
+	if !userManger.LoginUser(c) {
+		blog.Errorf("login user after login callback failed, rid: %s", rid)
+		c.JSON(http.StatusUnauthorized, metadata.BaseResp{
+			Code:   common.CCErrWebUsernamePasswdWrong,
+			ErrMsg: defErr.CCError(common.CCErrWebUsernamePasswdWrong).Error(),
+		})
+		return
+	}
+
+	if redirectURL == "" {
+		redirectURL = s.Config.Site.DomainUrl
+	}
+	c.Redirect(http.StatusFound, redirectURL)
+}
+
+// loginSuccess set the login user and redirect to the page before login
+func (s *Service) loginSuccess(c *gin.Context, userName string) {
+	rid := util.GetHTTPCCRequestID(c.Request.Header)
+	c.SetCookie(common.BKUser, userName, 24*60*60, "/", "", false, false)
+	session := sessions.Default(c)
+	session.Set(userName, time.Now().Unix())
+	if err := session.Save(); err != nil {
+		blog.Warnf("save session failed, err: %s, rid: %s", err.Error(), rid)
+	}
+	userManger := user.NewUser(*s.Config, s.Engine, s.CacheCli)
+	userManger.LoginUser(c)
+	var redirectURL string
+	if c.Query("c_url") != "" {
+		redirectURL = c.Query("c_url")
+	} else {
+		redirectURL = s.Config.Site.DomainUrl
+	}
+	c.Redirect(302, redirectURL)
+}
@@ -91,6 +91,7 @@ func (s *Service) WebService() *gin.Engine {
 	ws.POST("/logout", s.LogOutUser)
 	ws.GET("/login", s.Login)
 	ws.POST("/login", s.LoginUser)
+	ws.GET("/login/callback", s.LoginCallback)
 	ws.POST("/object/object/:bk_obj_id/import", s.ImportObject)
 	ws.POST("/object/object/:bk_obj_id/export", s.ExportObject)
 	ws.POST("/object/exportmany", s.BatchExportObject)